@@ -0,0 +1,132 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"appstore/backend/internal/audit"
+	"appstore/backend/internal/rabbitmq"
+	"appstore/backend/pkg/models"
+)
+
+const (
+	// defaultReplayMax is the number of messages replayed when no max is given
+	defaultReplayMax = 100
+	// maxReplayMax limits the number of messages replayed by a single request
+	maxReplayMax = 1000
+)
+
+// Replayer moves dead-lettered messages back to the exchange
+type Replayer interface {
+	ReplayDeadLetters(ctx context.Context, queue string, maxMessages int, dryRun bool) (rabbitmq.ReplayResult, error)
+}
+
+// Handler handles admin HTTP requests
+type Handler struct {
+	replayer        Replayer
+	deadLetterQueue string
+	auditLogger     *audit.Logger
+	logger          *slog.Logger
+}
+
+// NewHandler creates a new admin handler. replayer may be nil if RabbitMQ is unavailable,
+// auditLogger to not audit replays.
+func NewHandler(replayer Replayer, deadLetterQueue string, auditLogger *audit.Logger) *Handler {
+	return &Handler{
+		replayer:        replayer,
+		deadLetterQueue: deadLetterQueue,
+		auditLogger:     auditLogger,
+		logger:          slog.Default().With("component", "admin-handler"),
+	}
+}
+
+// ReplayDeadLetters handles POST /api/v1/admin/dead-letters/replay
+//
+// Republishes up to ?max= (default 100) messages from the dead-letter queue to the
+// appstore exchange. With ?dryRun=true the messages are only listed. Every request is
+// recorded in the audit trail.
+func (h *Handler) ReplayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	// TODO: Get user ID from auth context
+	record := models.AuditRecord{
+		Action:    audit.ActionReplay,
+		UserID:    "anonymous",
+		RequestID: uuid.New().String(),
+	}
+
+	maxMessages := defaultReplayMax
+	if v := r.URL.Query().Get("max"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxReplayMax {
+			msg := fmt.Sprintf("max must be between 1 and %d", maxReplayMax)
+			h.auditLog(r.Context(), record, audit.OutcomeRejected, msg)
+			h.respondError(w, http.StatusBadRequest, msg)
+			return
+		}
+		maxMessages = n
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dryRun"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			h.auditLog(r.Context(), record, audit.OutcomeRejected, "dryRun must be a boolean")
+			h.respondError(w, http.StatusBadRequest, "dryRun must be a boolean")
+			return
+		}
+	}
+
+	if h.replayer == nil {
+		h.auditLog(r.Context(), record, audit.OutcomeFailed, "RabbitMQ not available")
+		h.respondError(w, http.StatusServiceUnavailable, "RabbitMQ not available")
+		return
+	}
+
+	result, err := h.replayer.ReplayDeadLetters(r.Context(), h.deadLetterQueue, maxMessages, dryRun)
+	if err != nil {
+		h.logger.Error("failed to replay dead letters", "error", err, "queue", h.deadLetterQueue, "replayed", result.Replayed)
+		h.auditLog(r.Context(), record, audit.OutcomeFailed,
+			fmt.Sprintf("failed to replay dead letters from %s after %d messages", h.deadLetterQueue, result.Replayed))
+		h.respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error":    "failed to replay dead letters",
+			"replayed": result.Replayed,
+		})
+		return
+	}
+
+	if dryRun {
+		h.auditLog(r.Context(), record, audit.OutcomeAccepted, fmt.Sprintf("dry run of %d messages from %s", result.Replayed, h.deadLetterQueue))
+	} else {
+		h.auditLog(r.Context(), record, audit.OutcomeAccepted, fmt.Sprintf("replayed %d messages from %s", result.Replayed, h.deadLetterQueue))
+	}
+
+	h.logger.Info("dead letters replayed",
+		"queue", h.deadLetterQueue,
+		"replayed", result.Replayed,
+		"dryRun", dryRun,
+	)
+
+	h.respondJSON(w, http.StatusOK, result)
+}
+
+// auditLog records the outcome of a replay in the audit trail
+func (h *Handler) auditLog(ctx context.Context, record models.AuditRecord, outcome, reason string) {
+	record.Outcome = outcome
+	record.Reason = reason
+	h.auditLogger.Log(ctx, record)
+}
+
+func (h *Handler) respondJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *Handler) respondError(w http.ResponseWriter, status int, message string) {
+	h.respondJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,120 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"appstore/backend/internal/audit"
+	"appstore/backend/internal/rabbitmq"
+	"appstore/backend/pkg/models"
+)
+
+type fakeReplayer struct {
+	queue       string
+	maxMessages int
+	dryRun      bool
+}
+
+func (f *fakeReplayer) ReplayDeadLetters(_ context.Context, queue string, maxMessages int, dryRun bool) (rabbitmq.ReplayResult, error) {
+	f.queue, f.maxMessages, f.dryRun = queue, maxMessages, dryRun
+	return rabbitmq.ReplayResult{Replayed: 2, DryRun: dryRun}, nil
+}
+
+func replay(h *Handler, query string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ReplayDeadLetters(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/dead-letters/replay"+query, nil))
+	return rec
+}
+
+func TestReplayDeadLetters(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantMax    int
+		wantDryRun bool
+	}{
+		{"", http.StatusOK, defaultReplayMax, false},
+		{"?max=5&dryRun=true", http.StatusOK, 5, true},
+		{"?max=0", http.StatusBadRequest, 0, false},
+		{"?max=5000", http.StatusBadRequest, 0, false},
+		{"?dryRun=maybe", http.StatusBadRequest, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			replayer := &fakeReplayer{}
+			rec := replay(NewHandler(replayer, "appstore.deployments.dlq", nil), tt.query)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if replayer.queue != "appstore.deployments.dlq" || replayer.maxMessages != tt.wantMax || replayer.dryRun != tt.wantDryRun {
+				t.Errorf("replayed %s max=%d dryRun=%v, want max=%d dryRun=%v",
+					replayer.queue, replayer.maxMessages, replayer.dryRun, tt.wantMax, tt.wantDryRun)
+			}
+			var result rabbitmq.ReplayResult
+			if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+			if result.Replayed != 2 {
+				t.Errorf("replayed = %d, want 2", result.Replayed)
+			}
+		})
+	}
+}
+
+func TestReplayDeadLettersWithoutRabbitMQ(t *testing.T) {
+	if rec := replay(NewHandler(nil, "appstore.deployments.dlq", nil), ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+// auditRecorder collects audit records
+type auditRecorder struct {
+	records []models.AuditRecord
+}
+
+func (a *auditRecorder) PublishAuditRecord(_ context.Context, record models.AuditRecord) error {
+	a.records = append(a.records, record)
+	return nil
+}
+
+func TestReplayDeadLettersAudit(t *testing.T) {
+	tests := []struct {
+		name        string
+		replayer    Replayer
+		query       string
+		wantOutcome string
+		wantReason  string
+	}{
+		{"replay", &fakeReplayer{}, "?max=5", audit.OutcomeAccepted, "replayed 2 messages from appstore.deployments.dlq"},
+		{"dry run", &fakeReplayer{}, "?dryRun=true", audit.OutcomeAccepted, "dry run of 2 messages from appstore.deployments.dlq"},
+		{"invalid max", &fakeReplayer{}, "?max=0", audit.OutcomeRejected, "max must be between 1 and 1000"},
+		{"without RabbitMQ", nil, "", audit.OutcomeFailed, "RabbitMQ not available"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &auditRecorder{}
+			auditLogger := audit.NewLogger(slog.New(slog.NewJSONHandler(io.Discard, nil)), recorder)
+			replay(NewHandler(tt.replayer, "appstore.deployments.dlq", auditLogger), tt.query)
+
+			if len(recorder.records) != 1 {
+				t.Fatalf("got %d audit records, want 1", len(recorder.records))
+			}
+			got := recorder.records[0]
+			if got.Action != audit.ActionReplay || got.Outcome != tt.wantOutcome || got.Reason != tt.wantReason {
+				t.Errorf("audit record = %+v, want action %q, outcome %q and reason %q", got, audit.ActionReplay, tt.wantOutcome, tt.wantReason)
+			}
+			if got.UserID != "anonymous" || got.RequestID == "" {
+				t.Errorf("audit record = %+v, want the user and a request ID", got)
+			}
+		})
+	}
+}
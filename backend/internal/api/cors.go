@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	corsAllowedMethods = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	corsAllowedHeaders = "Content-Type, Authorization, If-Match, Idempotency-Key"
+	// ETag is read by clients to update deployments with If-Match
+	corsExposedHeaders = "ETag"
+)
+
+// CORSConfig decides which browser origins may call the API
+type CORSConfig struct {
+	// AllowedOrigins are allowed exactly, e.g. https://appstore.example.com. "*" allows any
+	// origin.
+	AllowedOrigins []string
+	// AllowedOriginPattern allows the origins it matches in full (optional)
+	AllowedOriginPattern *regexp.Regexp
+	// AllowCredentials lets browsers send cookies and authorization with requests. It
+	// can't be combined with allowing any origin.
+	AllowCredentials bool
+}
+
+// ParseCORSConfig parses comma-separated allowed origins and an origin regex, which must
+// match an origin in full
+func ParseCORSConfig(origins, originRegex string, allowCredentials bool) (*CORSConfig, error) {
+	cfg := &CORSConfig{AllowCredentials: allowCredentials}
+	for _, origin := range strings.Split(origins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			cfg.AllowedOrigins = append(cfg.AllowedOrigins, strings.TrimSuffix(origin, "/"))
+		}
+	}
+	if originRegex != "" {
+		pattern, err := regexp.Compile("^(?:" + originRegex + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid origin regex: %w", err)
+		}
+		cfg.AllowedOriginPattern = pattern
+	}
+	if cfg.allowsAnyOrigin() && allowCredentials {
+		return nil, errors.New("credentials can't be allowed for any origin")
+	}
+	return cfg, nil
+}
+
+// allowsAnyOrigin reports whether every origin is allowed
+func (c *CORSConfig) allowsAnyOrigin() bool {
+	return slices.Contains(c.AllowedOrigins, "*")
+}
+
+// allowed reports whether requests from the origin are allowed
+func (c *CORSConfig) allowed(origin string) bool {
+	if c.allowsAnyOrigin() || slices.Contains(c.AllowedOrigins, origin) {
+		return true
+	}
+	return c.AllowedOriginPattern != nil && c.AllowedOriginPattern.MatchString(origin)
+}
+
+// setHeaders sets the CORS headers of a response and reports whether the request's origin
+// is allowed. Requests without an Origin header aren't cross-origin and always allowed.
+func (c *CORSConfig) setHeaders(w http.ResponseWriter, req *http.Request) bool {
+	header := w.Header()
+	origin := req.Header.Get("Origin")
+
+	// With any origin allowed, responses are the same for every origin
+	if c.allowsAnyOrigin() {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		// Caches must not serve the response of one origin to another
+		header.Add("Vary", "Origin")
+		if origin == "" {
+			return true
+		}
+		if !c.allowed(origin) {
+			return false
+		}
+		header.Set("Access-Control-Allow-Origin", origin)
+		if c.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+	}
+
+	header.Set("Access-Control-Allow-Methods", corsAllowedMethods)
+	header.Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+	header.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+	return true
+}
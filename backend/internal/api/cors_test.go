@@ -0,0 +1,113 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func corsRequest(t *testing.T, router *Router, method, origin string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/healthz", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORSAllowedOrigins(t *testing.T) {
+	cors, err := ParseCORSConfig("https://appstore.example.com, http://localhost:5173/", `https://[a-z0-9-]+\.preview\.example\.com`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := NewRouter(nil, nil, nil, nil, nil, "", "", "", 0, cors)
+
+	tests := []struct {
+		name    string
+		method  string
+		origin  string
+		status  int
+		allowed bool
+	}{
+		{"allowed origin", http.MethodGet, "https://appstore.example.com", http.StatusOK, true},
+		{"allowed origin with trailing slash in the flag", http.MethodGet, "http://localhost:5173", http.StatusOK, true},
+		{"origin matching the regex", http.MethodGet, "https://pr-42.preview.example.com", http.StatusOK, true},
+		{"disallowed origin", http.MethodGet, "https://evil.example.net", http.StatusOK, false},
+		{"regex matches in full only", http.MethodGet, "https://pr-42.preview.example.com.evil.net", http.StatusOK, false},
+		{"same-origin request", http.MethodGet, "", http.StatusOK, false},
+		{"preflight of an allowed origin", http.MethodOptions, "https://appstore.example.com", http.StatusOK, true},
+		{"preflight of a disallowed origin", http.MethodOptions, "https://evil.example.net", http.StatusForbidden, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := corsRequest(t, router, tt.method, tt.origin)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if vary := rec.Header().Values("Vary"); len(vary) != 1 || vary[0] != "Origin" {
+				t.Errorf("Vary = %v, want Origin", vary)
+			}
+
+			allowOrigin := rec.Header().Get("Access-Control-Allow-Origin")
+			if !tt.allowed {
+				if allowOrigin != "" || rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+					t.Errorf("CORS headers set for a disallowed origin: %v", rec.Header())
+				}
+				return
+			}
+			// Allowed origins are reflected, never *
+			if allowOrigin != tt.origin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", allowOrigin, tt.origin)
+			}
+			if rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+				t.Error("credentials not allowed")
+			}
+			if rec.Header().Get("Access-Control-Allow-Methods") == "" || rec.Header().Get("Access-Control-Allow-Headers") == "" {
+				t.Errorf("allowed methods or headers missing: %v", rec.Header())
+			}
+		})
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	// Without a configuration any origin is allowed, without credentials
+	router := NewRouter(nil, nil, nil, nil, nil, "", "", "", 0, nil)
+	for _, method := range []string{http.MethodGet, http.MethodOptions} {
+		rec := corsRequest(t, router, method, "https://anywhere.example.org")
+		if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("%s: status = %d, headers = %v", method, rec.Code, rec.Header())
+		}
+		if rec.Header().Get("Access-Control-Allow-Credentials") != "" || rec.Header().Get("Vary") != "" {
+			t.Errorf("%s: headers = %v, want neither credentials nor Vary", method, rec.Header())
+		}
+	}
+}
+
+func TestParseCORSConfig(t *testing.T) {
+	for _, args := range []struct {
+		origins, regex string
+		credentials    bool
+	}{
+		{"*", "", true},
+		{"https://appstore.example.com,*", "", true},
+		{"", "https://(", false},
+	} {
+		if _, err := ParseCORSConfig(args.origins, args.regex, args.credentials); err == nil {
+			t.Errorf("ParseCORSConfig(%+v) error = nil", args)
+		}
+	}
+
+	// No origins disables cross-origin requests
+	cors, err := ParseCORSConfig("", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cors.allowed("https://appstore.example.com") {
+		t.Error("origin allowed without allowed origins")
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/google/uuid"
+)
+
+// requestIDHeader carries the ID of a request, which is generated if the client doesn't
+// send one
+const requestIDHeader = "X-Request-ID"
+
+// recoveryWriter records whether a response was started, after which a panic can't be
+// turned into a 500
+type recoveryWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *recoveryWriter) WriteHeader(status int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recoveryWriter) Write(data []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *recoveryWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// recoverPanics responds with 500 and the standard error body when next panics, logging
+// the panic with the request's ID. http.ErrAbortHandler is re-panicked, so that the
+// server aborts the response as the handler intended.
+func recoverPanics(next http.Handler, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rw := &recoveryWriter{ResponseWriter: w}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(recovered)
+			}
+
+			requestID := req.Header.Get(requestIDHeader)
+			if requestID == "" {
+				requestID = uuid.New().String()
+			}
+			logger.Error("Handler panicked", "panic", recovered, "requestId", requestID,
+				"method", req.Method, "path", req.URL.Path, "stack", string(debug.Stack()))
+
+			// The status was already sent, so the response can only be cut short
+			if rw.wroteHeader {
+				panic(http.ErrAbortHandler)
+			}
+			w.Header().Set(requestIDHeader, requestID)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "internal server error"})
+		}()
+		next.ServeHTTP(rw, req)
+	})
+}
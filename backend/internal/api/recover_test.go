@@ -0,0 +1,83 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func panicking(value interface{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(value)
+	})
+}
+
+func TestRecoverPanics(t *testing.T) {
+	var logs bytes.Buffer
+	handler := recoverPanics(panicking("nil map"), slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/deployments", nil)
+	req.Header.Set("X-Request-ID", "req-42")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if rec.Header().Get("Content-Type") != "application/json" || rec.Header().Get("X-Request-ID") != "req-42" {
+		t.Errorf("headers = %v", rec.Header())
+	}
+	var body map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body["error"] != "internal server error" {
+		t.Errorf("body = %v", body)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("log %q: %v", logs.String(), err)
+	}
+	if entry["requestId"] != "req-42" || entry["panic"] != "nil map" || !strings.Contains(entry["stack"].(string), "recover_test.go") {
+		t.Errorf("log = %v", entry)
+	}
+
+	// Requests without an ID get one
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("X-Request-ID") == "" {
+		t.Errorf("status = %d, headers = %v", rec.Code, rec.Header())
+	}
+}
+
+// servePanicking serves a request with the handler and returns what it re-panicked with
+func servePanicking(handler http.Handler) (recovered interface{}) {
+	defer func() {
+		recovered = recover()
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	return nil
+}
+
+func TestRecoverPanicsAborts(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+
+	// Handlers aborting on purpose aren't recovered
+	if recovered := servePanicking(recoverPanics(panicking(http.ErrAbortHandler), logger)); recovered != http.ErrAbortHandler {
+		t.Errorf("recovered = %v, want http.ErrAbortHandler", recovered)
+	}
+
+	// Nor are responses that were already started
+	started := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		panic("halfway")
+	})
+	if recovered := servePanicking(recoverPanics(started, logger)); recovered != http.ErrAbortHandler {
+		t.Errorf("recovered = %v, want http.ErrAbortHandler", recovered)
+	}
+}
@@ -1,47 +1,102 @@
 package api
 
 import (
+	"encoding/json"
+	"log/slog"
 	"net/http"
 
+	"appstore/backend/internal/admin"
+	"appstore/backend/internal/audit"
 	"appstore/backend/internal/catalog"
 	"appstore/backend/internal/deployment"
 	"appstore/backend/internal/k8s"
 	"appstore/backend/internal/rabbitmq"
 )
 
+// DefaultMaxBodyBytes is the default limit on the size of request bodies
+const DefaultMaxBodyBytes = 1 << 20
+
 // Router sets up HTTP routes
 type Router struct {
 	mux               *http.ServeMux
 	deploymentHandler *deployment.Handler
 	catalogHandler    *catalog.Handler
+	adminHandler      *admin.Handler
+	publisher         *rabbitmq.Publisher
+	maxBodyBytes      int64
+	cors              *CORSConfig
+
+	// handler serves the routes of mux, recovering from panics
+	handler http.Handler
 }
 
-// NewRouter creates a new router with all handlers
-func NewRouter(publisher *rabbitmq.Publisher, k8sClient *k8s.Client, catalogService *catalog.Service) *Router {
+// NewRouter creates a new router with all handlers. Request bodies larger than maxBodyBytes
+// are rejected with 413 (DefaultMaxBodyBytes if not positive). Browsers may call the API
+// from the origins cors allows, any origin if nil.
+func NewRouter(publisher *rabbitmq.Publisher, k8sClient *k8s.Client, catalogService *catalog.Service, auditLogger *audit.Logger, teamLimits *deployment.TeamLimits, unknownValuesMode deployment.UnknownValuesMode, defaultNamespace, deadLetterQueue string, maxBodyBytes int64, cors *CORSConfig) *Router {
+	// Avoid passing a typed nil pointer as a non-nil interface
+	var deploymentPublisher deployment.Publisher
+	var replayer admin.Replayer
+	if publisher != nil {
+		deploymentPublisher = publisher
+		replayer = publisher
+	}
+
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = DefaultMaxBodyBytes
+	}
+	if cors == nil {
+		cors = &CORSConfig{AllowedOrigins: []string{"*"}}
+	}
+
 	r := &Router{
 		mux:               http.NewServeMux(),
-		deploymentHandler: deployment.NewHandler(publisher, k8sClient),
+		deploymentHandler: deployment.NewHandler(deploymentPublisher, k8sClient, catalogService, auditLogger, teamLimits, unknownValuesMode, defaultNamespace),
 		catalogHandler:    catalog.NewHandler(catalogService),
+		adminHandler:      admin.NewHandler(replayer, deadLetterQueue, auditLogger),
+		publisher:         publisher,
+		maxBodyBytes:      maxBodyBytes,
+		cors:              cors,
 	}
 
 	r.setupRoutes()
+	r.handler = recoverPanics(r.mux, slog.Default().With("component", "router"))
 	return r
 }
 
 func (r *Router) setupRoutes() {
 	// Health check
 	r.mux.HandleFunc("GET /healthz", r.healthz)
+	r.mux.HandleFunc("GET /readyz", r.readyz)
 
 	// Catalog routes
 	r.mux.HandleFunc("GET /api/v1/catalog", r.catalogHandler.List)
 	r.mux.HandleFunc("GET /api/v1/catalog/{appName}", r.catalogHandler.Get)
+	r.mux.HandleFunc("GET /api/v1/catalog/{appName}/readme", r.catalogHandler.Readme)
+	r.mux.HandleFunc("GET /api/v1/catalog/{appName}/values", r.catalogHandler.Values)
+	r.mux.HandleFunc("GET /api/v1/catalog/{appName}/schema", r.catalogHandler.Schema)
+	r.mux.HandleFunc("GET /api/v1/catalog/{appName}/icon", r.catalogHandler.Icon)
 
 	// Deployment routes
 	r.mux.HandleFunc("POST /api/v1/deployments", r.deploymentHandler.Create)
+	r.mux.HandleFunc("POST /api/v1/deployments:batch", r.deploymentHandler.CreateBatch)
+	r.mux.HandleFunc("POST /api/v1/deployments:preview", r.deploymentHandler.Preview)
 	r.mux.HandleFunc("GET /api/v1/deployments", r.deploymentHandler.List)
+	r.mux.HandleFunc("GET /api/v1/deployments/summary", r.deploymentHandler.Summary)
 	r.mux.HandleFunc("GET /api/v1/deployments/{name}", r.deploymentHandler.Get)
+	r.mux.HandleFunc("GET /api/v1/deployments/{name}/events", r.deploymentHandler.Events)
+	r.mux.HandleFunc("GET /api/v1/deployments/{name}/diff", r.deploymentHandler.Diff)
+	r.mux.HandleFunc("GET /api/v1/deployments/{name}/manifest", r.deploymentHandler.Manifest)
+	r.mux.HandleFunc("GET /api/v1/deployments/{name}/wait", r.deploymentHandler.Wait)
+	r.mux.HandleFunc("POST /api/v1/deployments/{name}/cancel", r.deploymentHandler.Cancel)
+	// Wildcards must be whole path segments, so the handler matches the :clone suffix
+	r.mux.HandleFunc("POST /api/v1/deployments/{name}", r.deploymentHandler.Clone)
 	r.mux.HandleFunc("PUT /api/v1/deployments/{name}", r.deploymentHandler.Update)
+	r.mux.HandleFunc("PATCH /api/v1/deployments/{name}", r.deploymentHandler.Patch)
 	r.mux.HandleFunc("DELETE /api/v1/deployments/{name}", r.deploymentHandler.Delete)
+
+	// Admin routes
+	r.mux.HandleFunc("POST /api/v1/admin/dead-letters/replay", r.adminHandler.ReplayDeadLetters)
 }
 
 func (r *Router) healthz(w http.ResponseWriter, req *http.Request) {
@@ -49,18 +104,52 @@ func (r *Router) healthz(w http.ResponseWriter, req *http.Request) {
 	w.Write([]byte("ok"))
 }
 
+// readyz reports the state of the RabbitMQ circuit breaker. It fails with 503 while the
+// breaker is open, since creates, updates and deletes would fail.
+func (r *Router) readyz(w http.ResponseWriter, req *http.Request) {
+	status := http.StatusOK
+	breakerState := "unavailable"
+	if r.publisher != nil {
+		state := r.publisher.BreakerState()
+		breakerState = string(state)
+		if state == rabbitmq.BreakerOpen {
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	ready := "ready"
+	if status != http.StatusOK {
+		ready = "not ready"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   ready,
+		"rabbitmq": map[string]string{"circuitBreaker": breakerState},
+	})
+}
+
 // ServeHTTP implements http.Handler with CORS support
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	// CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	// Other requests of disallowed origins are served without CORS headers, so browsers
+	// don't let the calling page read the response
+	allowed := r.cors.setHeaders(w, req)
 
 	// Handle preflight requests
-	if req.Method == "OPTIONS" {
+	if req.Method == http.MethodOptions {
+		if !allowed {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
-	r.mux.ServeHTTP(w, req)
+	// Bound the memory a single request can take; handlers reading past the limit get an
+	// *http.MaxBytesError and respond with 413
+	if req.Body != nil {
+		req.Body = http.MaxBytesReader(w, req.Body, r.maxBodyBytes)
+	}
+
+	r.handler.ServeHTTP(w, req)
 }
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// bodyOfSize returns a create request of exactly size bytes
+func bodyOfSize(t *testing.T, size int) string {
+	t.Helper()
+	prefix, suffix := `{"appName":"postgresql","namespace":"team-a","values":{"pad":"`, `"}}`
+	padding := size - len(prefix) - len(suffix)
+	if padding < 0 {
+		t.Fatalf("size %d is too small for a create request", size)
+	}
+	return prefix + strings.Repeat("x", padding) + suffix
+}
+
+func TestRouterLimitsBodySize(t *testing.T) {
+	const limit = 1024
+	router := NewRouter(nil, nil, nil, nil, nil, "", "", "", limit, nil)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		// Under the limit, creates are decoded and only fail for lack of RabbitMQ
+		{"create just under the limit", http.MethodPost, "/api/v1/deployments", bodyOfSize(t, limit-1), http.StatusServiceUnavailable},
+		{"create at the limit", http.MethodPost, "/api/v1/deployments", bodyOfSize(t, limit), http.StatusServiceUnavailable},
+		{"create just over the limit", http.MethodPost, "/api/v1/deployments", bodyOfSize(t, limit+1), http.StatusRequestEntityTooLarge},
+		{"batch over the limit", http.MethodPost, "/api/v1/deployments:batch", "[" + bodyOfSize(t, limit) + "]", http.StatusRequestEntityTooLarge},
+		{"preview over the limit", http.MethodPost, "/api/v1/deployments:preview", bodyOfSize(t, limit+1), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus == http.StatusRequestEntityTooLarge && !strings.Contains(rec.Body.String(), "exceeds 1024 bytes") {
+				t.Errorf("body = %s, want the limit", rec.Body)
+			}
+		})
+	}
+}
+
+func TestRouterDefaultBodyLimit(t *testing.T) {
+	router := NewRouter(nil, nil, nil, nil, nil, "", "", "", 0, nil)
+	if router.maxBodyBytes != DefaultMaxBodyBytes {
+		t.Errorf("maxBodyBytes = %d, want %d", router.maxBodyBytes, DefaultMaxBodyBytes)
+	}
+}
@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"appstore/backend/pkg/models"
+)
+
+// Actions that are audited
+const (
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionDelete   = "delete"
+	ActionRollback = "rollback"
+	ActionSuspend  = "suspend"
+	ActionCancel   = "cancel"
+	ActionReplay   = "replay"
+)
+
+// Outcomes of an audited action
+const (
+	// OutcomeAccepted means the request was handed to the operator
+	OutcomeAccepted = "accepted"
+	// OutcomeRejected means the request was refused, e.g. failed validation or a conflict
+	OutcomeRejected = "rejected"
+	// OutcomeFailed means the request could not be processed because of a server error
+	OutcomeFailed = "failed"
+)
+
+// Publisher publishes audit records to the message bus
+type Publisher interface {
+	PublishAuditRecord(ctx context.Context, record models.AuditRecord) error
+}
+
+// Logger writes the audit trail. Records are logged as a separate stream, marked with
+// log=audit, and optionally published to RabbitMQ.
+type Logger struct {
+	logger    *slog.Logger
+	publisher Publisher
+}
+
+// NewLogger creates an audit logger. publisher may be nil to only log records.
+func NewLogger(logger *slog.Logger, publisher Publisher) *Logger {
+	return &Logger{
+		logger:    logger.With("log", "audit"),
+		publisher: publisher,
+	}
+}
+
+// Log records an audited action. It is a no-op on a nil Logger.
+func (l *Logger) Log(ctx context.Context, record models.AuditRecord) {
+	if l == nil {
+		return
+	}
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now().UTC()
+	}
+
+	l.logger.LogAttrs(ctx, slog.LevelInfo, "audit",
+		slog.String("action", record.Action),
+		slog.String("outcome", record.Outcome),
+		slog.String("teamId", record.TeamID),
+		slog.String("userId", record.UserID),
+		slog.String("deployment", record.Deployment),
+		slog.String("namespace", record.Namespace),
+		slog.String("appName", record.AppName),
+		slog.String("requestId", record.RequestID),
+		slog.String("batchId", record.BatchID),
+		slog.String("reason", record.Reason),
+		slog.Time("timestamp", record.Timestamp),
+	)
+
+	if l.publisher != nil {
+		// The audit trail must not fail the request; the log stream is the record of truth
+		if err := l.publisher.PublishAuditRecord(context.WithoutCancel(ctx), record); err != nil {
+			l.logger.Error("failed to publish audit record", "error", err, "action", record.Action, "requestId", record.RequestID)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"appstore/backend/pkg/models"
+)
+
+type fakePublisher struct {
+	records []models.AuditRecord
+	err     error
+}
+
+func (p *fakePublisher) PublishAuditRecord(_ context.Context, record models.AuditRecord) error {
+	p.records = append(p.records, record)
+	return p.err
+}
+
+func TestLogWritesAuditStream(t *testing.T) {
+	var buf bytes.Buffer
+	publisher := &fakePublisher{}
+	l := NewLogger(slog.New(slog.NewJSONHandler(&buf, nil)), publisher)
+
+	l.Log(context.Background(), models.AuditRecord{
+		Action:     ActionDelete,
+		Outcome:    OutcomeAccepted,
+		TeamID:     "team-a",
+		UserID:     "alice",
+		Deployment: "db",
+		Namespace:  "team-a",
+		AppName:    "postgresql",
+		RequestID:  "req-1",
+	})
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log output is not JSON: %v (%s)", err, buf.String())
+	}
+	want := map[string]string{
+		"log":        "audit",
+		"msg":        "audit",
+		"action":     "delete",
+		"outcome":    "accepted",
+		"teamId":     "team-a",
+		"userId":     "alice",
+		"deployment": "db",
+		"namespace":  "team-a",
+		"appName":    "postgresql",
+		"requestId":  "req-1",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %q", key, entry[key], value)
+		}
+	}
+	if _, ok := entry["timestamp"]; !ok {
+		t.Error("timestamp missing")
+	}
+
+	if len(publisher.records) != 1 {
+		t.Fatalf("published %d records, want 1", len(publisher.records))
+	}
+	if got := publisher.records[0]; got.RequestID != "req-1" || got.Timestamp.IsZero() {
+		t.Errorf("published record = %+v", got)
+	}
+}
+
+func TestLogIgnoresPublishErrors(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(slog.New(slog.NewJSONHandler(&buf, nil)), &fakePublisher{err: errors.New("channel closed")})
+
+	l.Log(context.Background(), models.AuditRecord{Action: ActionCreate, Outcome: OutcomeAccepted})
+
+	if !bytes.Contains(buf.Bytes(), []byte(`"msg":"audit"`)) {
+		t.Errorf("audit record not logged: %s", buf.String())
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("failed to publish audit record")) {
+		t.Errorf("publish error not logged: %s", buf.String())
+	}
+}
+
+func TestNilLoggerIsNoop(t *testing.T) {
+	var l *Logger
+	l.Log(context.Background(), models.AuditRecord{Action: ActionCreate})
+}
@@ -0,0 +1,56 @@
+package catalog
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChartMaintainer is a maintainer listed in a chart's Chart.yaml
+type ChartMaintainer struct {
+	Name  string `json:"name" yaml:"name"`
+	Email string `json:"email,omitempty" yaml:"email"`
+	URL   string `json:"url,omitempty" yaml:"url"`
+}
+
+// ChartDependency is a subchart listed in a chart's Chart.yaml
+type ChartDependency struct {
+	Name       string `json:"name" yaml:"name"`
+	Version    string `json:"version,omitempty" yaml:"version"`
+	Repository string `json:"repository,omitempty" yaml:"repository"`
+}
+
+// ChartMetadata is the metadata of an app's chart, as in its Chart.yaml. The field names
+// follow Helm's chart metadata.
+type ChartMetadata struct {
+	Name         string            `json:"name" yaml:"name"`
+	Version      string            `json:"version" yaml:"version"`
+	AppVersion   string            `json:"appVersion,omitempty" yaml:"appVersion"`
+	Description  string            `json:"description,omitempty" yaml:"description"`
+	Home         string            `json:"home,omitempty" yaml:"home"`
+	Deprecated   bool              `json:"deprecated,omitempty" yaml:"deprecated"`
+	Maintainers  []ChartMaintainer `json:"maintainers,omitempty" yaml:"maintainers"`
+	Dependencies []ChartDependency `json:"dependencies,omitempty" yaml:"dependencies"`
+}
+
+// AppDetails is a catalog app together with the metadata of its chart. Chart is nil if
+// the chart isn't available locally.
+type AppDetails struct {
+	App
+	Chart *ChartMetadata `json:"chart,omitempty"`
+}
+
+// ChartMetadata returns the metadata in the app's chart Chart.yaml. ErrChartFileNotFound
+// is returned if the chart isn't available.
+func (s *Service) ChartMetadata(appName string) (*ChartMetadata, error) {
+	data, err := s.ChartFile(appName, "Chart.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata ChartMetadata
+	if err := yaml.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse Chart.yaml for app %s: %w", appName, err)
+	}
+	return &metadata, nil
+}
@@ -0,0 +1,65 @@
+package catalog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func getAppDetails(t *testing.T, h *Handler, appName string) AppDetails {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/catalog/{appName}", h.Get)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/catalog/"+appName, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s status = %d, want %d (body %s)", appName, rec.Code, http.StatusOK, rec.Body)
+	}
+
+	var details AppDetails
+	if err := json.NewDecoder(rec.Body).Decode(&details); err != nil {
+		t.Fatal(err)
+	}
+	return details
+}
+
+func TestGetIncludesChartMetadata(t *testing.T) {
+	h := NewHandler(newServiceWithFiles(t, map[string]string{
+		"catalog.yaml": "apps:\n" +
+			"  - name: postgresql\n    displayName: PostgreSQL\n    chartPath: postgresql\n" +
+			"  - name: valkey\n    displayName: Valkey\n    chartPath: valkey\n" +
+			"  - name: broken\n    chartPath: broken\n",
+		"apps/postgresql/Chart.yaml": "apiVersion: v2\n" +
+			"name: postgresql\n" +
+			"version: 15.2.0\n" +
+			"appVersion: \"16.2\"\n" +
+			"maintainers:\n  - name: Platform\n    email: platform@example.com\n" +
+			"dependencies:\n  - name: common\n    version: 2.x.x\n    repository: oci://registry-1.docker.io/bitnamicharts\n",
+		"apps/broken/Chart.yaml": "version: [\n",
+	}))
+
+	details := getAppDetails(t, h, "postgresql")
+	if details.Name != "postgresql" || details.DisplayName != "PostgreSQL" {
+		t.Errorf("app = %+v, want the catalog's data", details.App)
+	}
+	want := &ChartMetadata{
+		Name:         "postgresql",
+		Version:      "15.2.0",
+		AppVersion:   "16.2",
+		Maintainers:  []ChartMaintainer{{Name: "Platform", Email: "platform@example.com"}},
+		Dependencies: []ChartDependency{{Name: "common", Version: "2.x.x", Repository: "oci://registry-1.docker.io/bitnamicharts"}},
+	}
+	if !reflect.DeepEqual(details.Chart, want) {
+		t.Errorf("chart = %+v, want %+v", details.Chart, want)
+	}
+
+	// Apps without a readable chart are returned with the catalog's data only
+	for _, appName := range []string{"valkey", "broken"} {
+		details := getAppDetails(t, h, appName)
+		if details.Name != appName || details.Chart != nil {
+			t.Errorf("GET %s = %+v, want the app without chart metadata", appName, details)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package catalog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newServiceWithFiles writes files relative to a temp dir containing catalog.yaml and
+// an apps/ charts directory, and loads the catalog
+func newServiceWithFiles(t *testing.T, files map[string]string) *Service {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewService(filepath.Join(dir, "catalog.yaml"), filepath.Join(dir, "apps"))
+	if err := s.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return s
+}
+
+// newChartFixture creates a catalog with a documented chart and a bare chart
+func newChartFixture(t *testing.T) *Handler {
+	t.Helper()
+	return NewHandler(newServiceWithFiles(t, map[string]string{
+		"catalog.yaml": "apps:\n" +
+			"  - name: postgresql\n    chartPath: postgresql\n" +
+			"  - name: valkey\n    chartPath: valkey\n",
+		"apps/postgresql/Chart.yaml":  "name: postgresql\n",
+		"apps/postgresql/README.md":   "# PostgreSQL\n",
+		"apps/postgresql/values.yaml": "replicaCount: 1\n",
+		"apps/valkey/Chart.yaml":      "name: valkey\n",
+	}))
+}
+
+func serveChartFile(h *Handler, handle http.HandlerFunc, appName string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/catalog/{appName}/file", handle)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/catalog/"+appName+"/file", nil))
+	return rec
+}
+
+func TestChartFileEndpoints(t *testing.T) {
+	h := newChartFixture(t)
+
+	tests := []struct {
+		name        string
+		handle      http.HandlerFunc
+		app         string
+		wantStatus  int
+		wantBody    string
+		contentType string
+	}{
+		{"readme", h.Readme, "postgresql", http.StatusOK, "# PostgreSQL\n", "text/markdown; charset=utf-8"},
+		{"values", h.Values, "postgresql", http.StatusOK, "replicaCount: 1\n", "application/yaml"},
+		{"missing readme", h.Readme, "valkey", http.StatusNotFound, "", "application/json"},
+		{"missing values", h.Values, "valkey", http.StatusNotFound, "", "application/json"},
+		{"unknown app", h.Readme, "mysql", http.StatusNotFound, "", "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveChartFile(h, tt.handle, tt.app)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body, tt.wantBody)
+			}
+		})
+	}
+}
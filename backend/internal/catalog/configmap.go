@@ -0,0 +1,105 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// DefaultConfigMapKey is the ConfigMap key holding the catalog unless another is given
+const DefaultConfigMapKey = "catalog.yaml"
+
+// configMapLoadTimeout limits reading the catalog ConfigMap in Load
+const configMapLoadTimeout = 30 * time.Second
+
+// rewatchDelay is how long Watch waits before watching the ConfigMap again after the
+// watch ended
+var rewatchDelay = 5 * time.Second
+
+// ConfigMapClient reads and watches ConfigMaps, see k8s.Client
+type ConfigMapClient interface {
+	GetConfigMapData(ctx context.Context, namespace, name string) (map[string]string, error)
+	WatchConfigMap(ctx context.Context, namespace, name string, onChange func(data map[string]string)) error
+}
+
+// configMapSource is a catalog stored in a ConfigMap key
+type configMapSource struct {
+	client    ConfigMapClient
+	namespace string
+	name      string
+	key       string
+}
+
+func (c *configMapSource) String() string {
+	return fmt.Sprintf("configmap %s/%s key %s", c.namespace, c.name, c.key)
+}
+
+// NewConfigMapService creates a catalog service reading the catalog from a key of a
+// ConfigMap (DefaultConfigMapKey if empty) instead of a file. Watch reloads it when the
+// ConfigMap changes.
+func NewConfigMapService(client ConfigMapClient, namespace, name, key, chartsDir string) *Service {
+	if key == "" {
+		key = DefaultConfigMapKey
+	}
+	s := NewService("", chartsDir)
+	s.configMap = &configMapSource{client: client, namespace: namespace, name: name, key: key}
+	return s
+}
+
+// loadConfigMap reads and parses the catalog ConfigMap
+func (s *Service) loadConfigMap() error {
+	ctx, cancel := context.WithTimeout(context.Background(), configMapLoadTimeout)
+	defer cancel()
+
+	data, err := s.configMap.client.GetConfigMapData(ctx, s.configMap.namespace, s.configMap.name)
+	if err != nil {
+		return fmt.Errorf("failed to read catalog: %w", err)
+	}
+	return s.loadConfigMapData(data)
+}
+
+// loadConfigMapData parses the catalog from the data of the catalog ConfigMap
+func (s *Service) loadConfigMapData(data map[string]string) error {
+	content, ok := data[s.configMap.key]
+	if !ok {
+		return fmt.Errorf("failed to read catalog: %s not found", s.configMap)
+	}
+
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+	return s.parse([]byte(content))
+}
+
+// Watch reloads the catalog whenever its ConfigMap changes, until ctx is done. A catalog
+// that fails to load is logged and the previous one kept. Catalogs read from a file
+// aren't watched and Watch returns right away.
+func (s *Service) Watch(ctx context.Context) {
+	if s.configMap == nil {
+		return
+	}
+
+	onChange := func(data map[string]string) {
+		etag := s.ETag()
+		if err := s.loadConfigMapData(data); err != nil {
+			slog.Error("Failed to reload catalog", "error", err, "source", s.configMap.String())
+			return
+		}
+		if s.ETag() != etag {
+			slog.Info("Catalog reloaded", "source", s.configMap.String(), "apps", len(s.ListApps()))
+		}
+	}
+
+	for {
+		err := s.configMap.client.WatchConfigMap(ctx, s.configMap.namespace, s.configMap.name, onChange)
+		if err != nil {
+			slog.Warn("Failed to watch catalog ConfigMap", "error", err, "source", s.configMap.String())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(rewatchDelay):
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package catalog
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"appstore/backend/internal/k8s"
+)
+
+func newCatalogConfigMap(data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "catalog", Namespace: "appstore"},
+		Data:       data,
+	}
+}
+
+func appNames(s *Service) string {
+	var names []string
+	for _, app := range s.ListApps() {
+		names = append(names, app.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestLoadConfigMap(t *testing.T) {
+	clientset := fake.NewClientset(newCatalogConfigMap(map[string]string{
+		"catalog.yaml": "apps:\n  - name: postgresql\n  - name: valkey\n",
+		"other.yaml":   "apps:\n  - name: mysql\n",
+	}))
+	client := k8s.NewClientFromInterfaces(nil, clientset)
+
+	s := NewConfigMapService(client, "appstore", "catalog", "", "")
+	if err := s.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := appNames(s); got != "postgresql,valkey" {
+		t.Errorf("apps = %s, want postgresql,valkey", got)
+	}
+
+	s = NewConfigMapService(client, "appstore", "catalog", "other.yaml", "")
+	if err := s.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := appNames(s); got != "mysql" {
+		t.Errorf("apps = %s, want mysql", got)
+	}
+
+	for _, s := range []*Service{
+		NewConfigMapService(client, "appstore", "catalog", "missing.yaml", ""),
+		NewConfigMapService(client, "appstore", "missing", "", ""),
+	} {
+		if err := s.Load(); err == nil {
+			t.Errorf("Load(%s) succeeded, want an error", s.configMap)
+		}
+	}
+}
+
+func TestWatchConfigMap(t *testing.T) {
+	configMap := newCatalogConfigMap(map[string]string{"catalog.yaml": "apps:\n  - name: postgresql\n"})
+	clientset := fake.NewClientset(configMap)
+	s := NewConfigMapService(k8s.NewClientFromInterfaces(nil, clientset), "appstore", "catalog", "", "")
+	if err := s.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		s.Watch(ctx)
+		close(done)
+	}()
+
+	// Wait for the watch to be established, so that it sees the updates
+	waitFor(t, "the ConfigMap to be watched", func() bool {
+		for _, action := range clientset.Actions() {
+			if action.GetVerb() == "watch" {
+				return true
+			}
+		}
+		return false
+	})
+
+	update := func(catalog string) {
+		configMap.Data = map[string]string{"catalog.yaml": catalog}
+		if _, err := clientset.CoreV1().ConfigMaps("appstore").Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	update("apps:\n  - name: postgresql\n  - name: mysql\n")
+	waitFor(t, "the catalog to be reloaded", func() bool { return appNames(s) == "mysql,postgresql" })
+
+	// Invalid catalogs are ignored
+	etag := s.ETag()
+	update("apps:\n  - name: postgresql\n    lifecycle: retired\n")
+	update("apps:\n  - name: valkey\n")
+	waitFor(t, "the catalog to be reloaded", func() bool { return appNames(s) == "valkey" })
+	if s.ETag() == etag {
+		t.Error("ETag didn't change")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch didn't return after the context was canceled")
+	}
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
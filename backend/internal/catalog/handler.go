@@ -2,7 +2,11 @@ package catalog
 
 import (
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 // Handler handles catalog HTTP requests
@@ -18,10 +22,36 @@ func NewHandler(service *Service) *Handler {
 }
 
 // List handles GET /api/v1/catalog
+//
+// Deprecated apps are only listed with ?includeDeprecated=true; removed apps are never listed.
+// Apps are ordered by weight, heaviest first, and then by name; ?featured=true only lists
+// apps with a positive weight.
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	// Get optional category filter
 	category := r.URL.Query().Get("category")
 
+	includeDeprecated := false
+	if v := r.URL.Query().Get("includeDeprecated"); v != "" {
+		var err error
+		if includeDeprecated, err = strconv.ParseBool(v); err != nil {
+			h.respondError(w, http.StatusBadRequest, "includeDeprecated must be a boolean")
+			return
+		}
+	}
+
+	featured := false
+	if v := r.URL.Query().Get("featured"); v != "" {
+		var err error
+		if featured, err = strconv.ParseBool(v); err != nil {
+			h.respondError(w, http.StatusBadRequest, "featured must be a boolean")
+			return
+		}
+	}
+
+	if h.notModified(w, r) {
+		return
+	}
+
 	var apps []App
 	if category != "" {
 		apps = h.service.GetAppsByCategory(category)
@@ -29,12 +59,25 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		apps = h.service.ListApps()
 	}
 
+	listed := []App{}
+	for _, app := range apps {
+		if featured && !app.IsFeatured() {
+			continue
+		}
+		if app.IsActive() || (includeDeprecated && app.Lifecycle == LifecycleDeprecated) {
+			listed = append(listed, app)
+		}
+	}
+
 	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"apps": apps,
+		"apps": listed,
 	})
 }
 
 // Get handles GET /api/v1/catalog/{appName}
+//
+// The app is returned with the metadata of its chart, such as its version, appVersion,
+// maintainers and dependencies. Without a readable chart only the catalog's data is returned.
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	appName := r.PathValue("appName")
 	if appName == "" {
@@ -48,7 +91,138 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, app)
+	if h.notModified(w, r) {
+		return
+	}
+
+	details := AppDetails{App: *app}
+	details.Chart, err = h.service.ChartMetadata(appName)
+	if err != nil && !errors.Is(err, ErrChartFileNotFound) {
+		slog.Warn("Failed to read chart metadata", "error", err, "app", appName)
+	}
+
+	h.respondJSON(w, http.StatusOK, details)
+}
+
+// notModified sets the catalog's ETag on the response and reports whether the client's
+// If-None-Match matches it, in which case a 304 Not Modified has been written
+func (h *Handler) notModified(w http.ResponseWriter, r *http.Request) bool {
+	etag := h.service.ETag()
+	if etag == "" {
+		return false
+	}
+
+	// Clients may cache responses but must revalidate them
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// Readme handles GET /api/v1/catalog/{appName}/readme
+func (h *Handler) Readme(w http.ResponseWriter, r *http.Request) {
+	h.serveChartFile(w, r, "README.md", "text/markdown; charset=utf-8")
+}
+
+// Values handles GET /api/v1/catalog/{appName}/values
+func (h *Handler) Values(w http.ResponseWriter, r *http.Request) {
+	h.serveChartFile(w, r, "values.yaml", "application/yaml")
+}
+
+// Schema handles GET /api/v1/catalog/{appName}/schema
+func (h *Handler) Schema(w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("appName")
+	if appName == "" {
+		h.respondError(w, http.StatusBadRequest, "app name is required")
+		return
+	}
+
+	if _, err := h.service.GetApp(appName); err != nil {
+		h.respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	schema, err := h.service.GetValuesSchema(appName)
+	if err != nil {
+		if errors.Is(err, ErrChartFileNotFound) {
+			h.respondError(w, http.StatusNotFound, "no values schema or values.yaml for app "+appName)
+			return
+		}
+		h.respondError(w, http.StatusInternalServerError, "failed to load values schema")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, schema)
+}
+
+// Icon handles GET /api/v1/catalog/{appName}/icon
+//
+// The icon is proxied so the frontend has a same-origin URL even when the catalog
+// points at a registry the browser can't reach.
+func (h *Handler) Icon(w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("appName")
+	if appName == "" {
+		h.respondError(w, http.StatusBadRequest, "app name is required")
+		return
+	}
+
+	if _, err := h.service.GetApp(appName); err != nil {
+		h.respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	icon, err := h.service.Icon(r.Context(), appName)
+	if err != nil {
+		if errors.Is(err, ErrIconNotFound) {
+			h.respondError(w, http.StatusNotFound, "no icon for app "+appName)
+			return
+		}
+		h.respondError(w, http.StatusBadGateway, "failed to load icon")
+		return
+	}
+
+	w.Header().Set("Content-Type", icon.ContentType)
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// SVGs can carry scripts; don't let them run if the icon is opened directly
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	w.WriteHeader(http.StatusOK)
+	w.Write(icon.Data)
+}
+
+// serveChartFile writes a file from the app's chart directory as-is
+func (h *Handler) serveChartFile(w http.ResponseWriter, r *http.Request, fileName, contentType string) {
+	appName := r.PathValue("appName")
+	if appName == "" {
+		h.respondError(w, http.StatusBadRequest, "app name is required")
+		return
+	}
+
+	if _, err := h.service.GetApp(appName); err != nil {
+		h.respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	data, err := h.service.ChartFile(appName, fileName)
+	if err != nil {
+		if errors.Is(err, ErrChartFileNotFound) {
+			h.respondError(w, http.StatusNotFound, fileName+" not found for app "+appName)
+			return
+		}
+		h.respondError(w, http.StatusInternalServerError, "failed to read "+fileName)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
 }
 
 func (h *Handler) respondJSON(w http.ResponseWriter, status int, data interface{}) {
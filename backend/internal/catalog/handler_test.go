@@ -0,0 +1,237 @@
+package catalog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testCatalog = `apps:
+  - name: postgresql
+    category: database
+  - name: mysql
+    category: database
+    lifecycle: deprecated
+  - name: memcached
+    category: cache
+    lifecycle: removed
+  - name: valkey
+    category: cache
+    lifecycle: active
+`
+
+func newTestService(t *testing.T, content string) *Service {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewService(path, "")
+	if err := s.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return s
+}
+
+func listAppNames(t *testing.T, h *Handler, query string) []string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.List(rec, httptest.NewRequest(http.MethodGet, "/api/v1/catalog"+query, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("List(%q) status = %d, body = %s", query, rec.Code, rec.Body)
+	}
+
+	var body struct {
+		Apps []App `json:"apps"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, app := range body.Apps {
+		names = append(names, app.Name)
+	}
+	return names
+}
+
+func TestLoadDefaultsLifecycle(t *testing.T) {
+	s := newTestService(t, testCatalog)
+
+	app, err := s.GetApp("postgresql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Lifecycle != LifecycleActive || !app.IsActive() {
+		t.Errorf("lifecycle = %q, want %q", app.Lifecycle, LifecycleActive)
+	}
+
+	// Removed apps can still be looked up for existing deployments
+	app, err = s.GetApp("memcached")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.IsActive() {
+		t.Error("removed app reported as active")
+	}
+}
+
+func TestLoadRejectsInvalidLifecycle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+	if err := os.WriteFile(path, []byte("apps:\n  - name: postgresql\n    lifecycle: retired\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewService(path, "").Load(); err == nil {
+		t.Fatal("Load() error = nil, want invalid lifecycle error")
+	}
+}
+
+func TestListFiltersLifecycle(t *testing.T) {
+	h := NewHandler(newTestService(t, testCatalog))
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"postgresql", "valkey"}},
+		{"?includeDeprecated=false", []string{"postgresql", "valkey"}},
+		{"?includeDeprecated=true", []string{"mysql", "postgresql", "valkey"}},
+		{"?category=database", []string{"postgresql"}},
+		{"?category=database&includeDeprecated=true", []string{"mysql", "postgresql"}},
+		{"?category=cache&includeDeprecated=true", []string{"valkey"}},
+	}
+	for _, tt := range tests {
+		got := listAppNames(t, h, tt.query)
+		if len(got) != len(tt.want) {
+			t.Errorf("List(%q) = %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("List(%q) = %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestListOrdersByWeight(t *testing.T) {
+	h := NewHandler(newTestService(t, `apps:
+  - name: valkey
+    category: cache
+  - name: postgresql
+    category: database
+    weight: 10
+  - name: mysql
+    category: database
+    weight: 20
+    lifecycle: deprecated
+  - name: mongodb
+    category: database
+  - name: kafka
+    category: messaging
+    weight: 10
+  - name: rabbitmq
+    category: messaging
+    weight: -5
+`))
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"kafka", "postgresql", "mongodb", "valkey", "rabbitmq"}},
+		{"?includeDeprecated=true", []string{"mysql", "kafka", "postgresql", "mongodb", "valkey", "rabbitmq"}},
+		{"?category=database", []string{"postgresql", "mongodb"}},
+		{"?featured=true", []string{"kafka", "postgresql"}},
+		{"?featured=true&includeDeprecated=true", []string{"mysql", "kafka", "postgresql"}},
+		{"?featured=true&category=messaging", []string{"kafka"}},
+		{"?featured=false", []string{"kafka", "postgresql", "mongodb", "valkey", "rabbitmq"}},
+	}
+	for _, tt := range tests {
+		got := listAppNames(t, h, tt.query)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("List(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestListInvalidFeatured(t *testing.T) {
+	h := NewHandler(newTestService(t, testCatalog))
+
+	rec := httptest.NewRecorder()
+	h.List(rec, httptest.NewRequest(http.MethodGet, "/api/v1/catalog?featured=yes", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestListInvalidIncludeDeprecated(t *testing.T) {
+	h := NewHandler(newTestService(t, testCatalog))
+
+	rec := httptest.NewRecorder()
+	h.List(rec, httptest.NewRequest(http.MethodGet, "/api/v1/catalog?includeDeprecated=maybe", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCatalogETag(t *testing.T) {
+	s := newTestService(t, testCatalog)
+	h := NewHandler(s)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/catalog", h.List)
+	mux.HandleFunc("GET /api/v1/catalog/{appName}", h.Get)
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, path := range []string{"/api/v1/catalog", "/api/v1/catalog/postgresql"} {
+		rec := get(path, "")
+		etag := rec.Header().Get("ETag")
+		if rec.Code != http.StatusOK || etag == "" {
+			t.Fatalf("GET %s status = %d, ETag = %q, want 200 with an ETag", path, rec.Code, etag)
+		}
+
+		rec = get(path, etag)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("conditional GET %s status = %d with %d bytes, want 304 without a body", path, rec.Code, rec.Body.Len())
+		}
+		if rec := get(path, `"other", W/`+etag); rec.Code != http.StatusNotModified {
+			t.Errorf("GET %s with a list of ETags status = %d, want 304", path, rec.Code)
+		}
+		if rec := get(path, `"other"`); rec.Code != http.StatusOK {
+			t.Errorf("GET %s with a stale ETag status = %d, want 200", path, rec.Code)
+		}
+	}
+
+	if rec := get("/api/v1/catalog/unknown", s.ETag()); rec.Code != http.StatusNotFound {
+		t.Errorf("conditional GET of an unknown app status = %d, want 404", rec.Code)
+	}
+
+	// Reloading a changed catalog invalidates the ETag
+	oldETag := s.ETag()
+	if err := os.WriteFile(s.catalogPath, []byte(testCatalog+"  - name: redis\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	rec := get("/api/v1/catalog", oldETag)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET after reload status = %d, want 200", rec.Code)
+	}
+	if newETag := rec.Header().Get("ETag"); newETag == "" || newETag == oldETag {
+		t.Errorf("ETag after reload = %q, want a new ETag (was %q)", newETag, oldETag)
+	}
+}
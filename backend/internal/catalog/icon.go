@@ -0,0 +1,120 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// maxIconSize limits how much of a remote icon is read
+	maxIconSize = 1 << 20
+
+	// iconCacheTTL is how long fetched remote icons are kept in memory
+	iconCacheTTL = time.Hour
+)
+
+// ErrIconNotFound is returned when an app has no icon or its icon doesn't exist
+var ErrIconNotFound = errors.New("icon not found")
+
+// Icon is an app icon image
+type Icon struct {
+	Data        []byte
+	ContentType string
+	fetchedAt   time.Time
+}
+
+// Icon returns the app's icon. The catalog icon is either an http(s) URL, which is
+// fetched (and cached), or the name of a file bundled in the app's chart directory.
+func (s *Service) Icon(ctx context.Context, appName string) (*Icon, error) {
+	app, err := s.GetApp(appName)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.Icon == "" {
+		return nil, fmt.Errorf("%w: app %s has no icon", ErrIconNotFound, appName)
+	}
+
+	if strings.HasPrefix(app.Icon, "http://") || strings.HasPrefix(app.Icon, "https://") {
+		return s.fetchIcon(ctx, app.Icon)
+	}
+
+	data, err := s.ChartFile(appName, app.Icon)
+	if err != nil {
+		if errors.Is(err, ErrChartFileNotFound) {
+			return nil, fmt.Errorf("%w: %v", ErrIconNotFound, err)
+		}
+		return nil, err
+	}
+
+	return &Icon{Data: data, ContentType: iconContentType(app.Icon, "", data)}, nil
+}
+
+// fetchIcon downloads a remote icon, serving it from the cache when recently fetched
+func (s *Service) fetchIcon(ctx context.Context, url string) (*Icon, error) {
+	s.iconMu.Lock()
+	cached, ok := s.iconCache[url]
+	s.iconMu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < iconCacheTTL {
+		return cached, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid icon URL: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch icon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrIconNotFound, url)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch icon: %s returned %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIconSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read icon: %w", err)
+	}
+	if len(data) > maxIconSize {
+		return nil, fmt.Errorf("icon %s is larger than %d bytes", url, maxIconSize)
+	}
+
+	icon := &Icon{
+		Data:        data,
+		ContentType: iconContentType(url, resp.Header.Get("Content-Type"), data),
+		fetchedAt:   time.Now(),
+	}
+
+	s.iconMu.Lock()
+	s.iconCache[url] = icon
+	s.iconMu.Unlock()
+
+	return icon, nil
+}
+
+// iconContentType picks the content type from the response header, the file
+// extension or the content, in that order
+func iconContentType(name, header string, data []byte) string {
+	if header != "" {
+		return header
+	}
+	if ext := filepath.Ext(strings.SplitN(name, "?", 2)[0]); ext != "" {
+		if contentType := mime.TypeByExtension(ext); contentType != "" {
+			return contentType
+		}
+	}
+	return http.DetectContentType(data)
+}
@@ -0,0 +1,96 @@
+package catalog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg"/>`
+
+// newIconFixture creates a catalog with a bundled icon, remote icons served by a stub
+// server and an app without an icon. It returns the handler and the number of stub requests.
+func newIconFixture(t *testing.T) (*Handler, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/valkey.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG\r\n\x1a\n"))
+		case "/broken.svg":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(stub.Close)
+
+	return NewHandler(newServiceWithFiles(t, map[string]string{
+		"catalog.yaml": "apps:\n" +
+			"  - name: postgresql\n    icon: postgresql.svg\n" +
+			"  - name: valkey\n    icon: " + stub.URL + "/valkey.png\n" +
+			"  - name: mysql\n    icon: " + stub.URL + "/mysql.svg\n" +
+			"  - name: mongodb\n    icon: " + stub.URL + "/broken.svg\n" +
+			"  - name: redis\n    icon: redis.svg\n" +
+			"  - name: kafka\n",
+		"apps/postgresql/postgresql.svg": testSVG,
+	})), &requests
+}
+
+func TestIcon(t *testing.T) {
+	h, _ := newIconFixture(t)
+
+	tests := []struct {
+		name        string
+		app         string
+		wantStatus  int
+		wantBody    string
+		contentType string
+	}{
+		{"bundled", "postgresql", http.StatusOK, testSVG, "image/svg+xml"},
+		{"remote", "valkey", http.StatusOK, "\x89PNG\r\n\x1a\n", "image/png"},
+		{"remote not found", "mysql", http.StatusNotFound, "", "application/json"},
+		{"remote error", "mongodb", http.StatusBadGateway, "", "application/json"},
+		{"bundled file missing", "redis", http.StatusNotFound, "", "application/json"},
+		{"no icon", "kafka", http.StatusNotFound, "", "application/json"},
+		{"unknown app", "cassandra", http.StatusNotFound, "", "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveChartFile(h, h.Icon, tt.app)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != "public, max-age=3600" {
+				t.Errorf("Cache-Control = %q", got)
+			}
+		})
+	}
+}
+
+func TestIconCachesRemoteIcons(t *testing.T) {
+	h, requests := newIconFixture(t)
+
+	for i := 0; i < 3; i++ {
+		if rec := serveChartFile(h, h.Icon, "valkey"); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+	}
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("icon fetched %d times, want 1", got)
+	}
+}
@@ -0,0 +1,29 @@
+package catalog
+
+import (
+	"fmt"
+)
+
+// CurrentSchemaVersion is the catalog.yaml schema version Catalog represents
+const CurrentSchemaVersion = 1
+
+// catalogFile is catalog.yaml as written, which may use an older schema version
+type catalogFile struct {
+	// SchemaVersion of the file; files without one are version 1
+	SchemaVersion int                    `yaml:"schemaVersion"`
+	Apps          []App                  `yaml:"apps"`
+	DefaultValues map[string]interface{} `yaml:"defaultValues"`
+}
+
+// migrate upgrades a catalog file to the current schema. Files of a newer version than
+// CurrentSchemaVersion are rejected rather than loaded with fields missing.
+func migrate(file catalogFile) (Catalog, error) {
+	version := file.SchemaVersion
+	if version == 0 {
+		version = 1
+	}
+	if version < 1 || version > CurrentSchemaVersion {
+		return Catalog{}, fmt.Errorf("unsupported catalog schemaVersion %d, expected 1 to %d", file.SchemaVersion, CurrentSchemaVersion)
+	}
+	return Catalog{Apps: file.Apps, DefaultValues: file.DefaultValues}, nil
+}
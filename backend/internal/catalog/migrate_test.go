@@ -0,0 +1,40 @@
+package catalog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadSchemaVersions(t *testing.T) {
+	unversioned := `apps:
+  - name: postgresql
+    weight: 1
+  - name: mysql
+    lifecycle: deprecated
+defaultValues:
+  team: platform
+`
+	v1 := "schemaVersion: 1\n" + unversioned
+	want := []App{
+		{Name: "postgresql", Lifecycle: LifecycleActive, Weight: 1},
+		{Name: "mysql", Lifecycle: LifecycleDeprecated},
+	}
+
+	for name, content := range map[string]string{"unversioned": unversioned, "v1": v1} {
+		s := newServiceWithFiles(t, map[string]string{"catalog.yaml": content})
+		if got := s.ListApps(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s apps = %+v, want %+v", name, got, want)
+		}
+		if got := s.DefaultValues(); !reflect.DeepEqual(got, map[string]interface{}{"team": "platform"}) {
+			t.Errorf("%s defaultValues = %v", name, got)
+		}
+	}
+}
+
+func TestMigrateUnsupportedVersion(t *testing.T) {
+	for _, version := range []int{-1, CurrentSchemaVersion + 1} {
+		if _, err := migrate(catalogFile{SchemaVersion: version}); err == nil {
+			t.Errorf("migrate(schemaVersion %d) error = nil", version)
+		}
+	}
+}
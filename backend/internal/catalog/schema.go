@@ -0,0 +1,92 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ValuesField describes a single key of a chart's values.yaml
+type ValuesField struct {
+	Key     string      `json:"key"`
+	Type    string      `json:"type"`
+	Default interface{} `json:"default"`
+}
+
+// ValuesSchema is the form metadata for a chart's values. Schema holds the chart's
+// values.schema.json when present; otherwise Fields is inferred from values.yaml.
+type ValuesSchema struct {
+	Source string          `json:"source"`
+	Schema json.RawMessage `json:"schema,omitempty"`
+	Fields []ValuesField   `json:"fields,omitempty"`
+}
+
+const (
+	// SchemaSourceSchema means the metadata comes from values.schema.json
+	SchemaSourceSchema = "schema"
+	// SchemaSourceValues means the metadata was inferred from values.yaml
+	SchemaSourceValues = "values"
+)
+
+// GetValuesSchema returns form metadata for the app's chart values
+func (s *Service) GetValuesSchema(appName string) (*ValuesSchema, error) {
+	data, err := s.ChartFile(appName, "values.schema.json")
+	if err == nil {
+		if !json.Valid(data) {
+			return nil, fmt.Errorf("invalid values.schema.json for app %s", appName)
+		}
+		return &ValuesSchema{Source: SchemaSourceSchema, Schema: data}, nil
+	}
+	if !errors.Is(err, ErrChartFileNotFound) {
+		return nil, err
+	}
+
+	values, err := s.ChartValues(appName)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []ValuesField{}
+	flattenValues("", values, &fields)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+
+	return &ValuesSchema{Source: SchemaSourceValues, Fields: fields}, nil
+}
+
+// flattenValues appends a field for every leaf of values, using dot-separated keys.
+// Empty maps are kept as object fields.
+func flattenValues(prefix string, values map[string]interface{}, fields *[]ValuesField) {
+	for key, value := range values {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenValues(key, nested, fields)
+			continue
+		}
+		*fields = append(*fields, ValuesField{Key: key, Type: inferType(value), Default: value})
+	}
+}
+
+// inferType returns the JSON Schema type name for a YAML value
+func inferType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "string"
+	}
+}
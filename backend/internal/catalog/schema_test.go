@@ -0,0 +1,106 @@
+package catalog
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+const schemaFixtureValues = `replicaCount: 1
+image:
+  repository: postgres
+  pullPolicy: IfNotPresent
+auth:
+  enabled: true
+  ratio: 0.5
+extraEnv: []
+podAnnotations: {}
+nodeSelector: ~
+`
+
+func newSchemaFixture(t *testing.T) *Handler {
+	t.Helper()
+	return NewHandler(newServiceWithFiles(t, map[string]string{
+		"catalog.yaml": "apps:\n" +
+			"  - name: postgresql\n" +
+			"  - name: valkey\n" +
+			"  - name: empty\n",
+		"apps/postgresql/values.yaml":        schemaFixtureValues,
+		"apps/postgresql/values.schema.json": `{"type":"object","properties":{"replicaCount":{"type":"integer"}}}`,
+		"apps/valkey/values.yaml":            schemaFixtureValues,
+		"apps/empty/Chart.yaml":              "name: empty\n",
+	}))
+}
+
+func TestSchemaFromValuesSchemaJSON(t *testing.T) {
+	h := newSchemaFixture(t)
+	rec := serveChartFile(h, h.Schema, "postgresql")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	var got struct {
+		Source string                 `json:"source"`
+		Schema map[string]interface{} `json:"schema"`
+		Fields []ValuesField          `json:"fields"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Source != SchemaSourceSchema {
+		t.Errorf("source = %q, want %q", got.Source, SchemaSourceSchema)
+	}
+	if got.Schema["type"] != "object" {
+		t.Errorf("schema = %v, want the chart's values.schema.json", got.Schema)
+	}
+	if len(got.Fields) != 0 {
+		t.Errorf("fields = %v, want none", got.Fields)
+	}
+}
+
+func TestSchemaInferredFromValues(t *testing.T) {
+	h := newSchemaFixture(t)
+	schema, err := h.service.GetValuesSchema("valkey")
+	if err != nil {
+		t.Fatalf("GetValuesSchema() error = %v", err)
+	}
+	if schema.Source != SchemaSourceValues {
+		t.Errorf("source = %q, want %q", schema.Source, SchemaSourceValues)
+	}
+
+	want := []struct{ key, typ string }{
+		{"auth.enabled", "boolean"},
+		{"auth.ratio", "number"},
+		{"extraEnv", "array"},
+		{"image.pullPolicy", "string"},
+		{"image.repository", "string"},
+		{"nodeSelector", "null"},
+		{"podAnnotations", "object"},
+		{"replicaCount", "integer"},
+	}
+	if len(schema.Fields) != len(want) {
+		t.Fatalf("fields = %+v, want %d fields", schema.Fields, len(want))
+	}
+	for i, w := range want {
+		if schema.Fields[i].Key != w.key || schema.Fields[i].Type != w.typ {
+			t.Errorf("field %d = %s (%s), want %s (%s)", i, schema.Fields[i].Key, schema.Fields[i].Type, w.key, w.typ)
+		}
+	}
+	if schema.Fields[7].Default != 1 {
+		t.Errorf("replicaCount default = %v, want 1", schema.Fields[7].Default)
+	}
+
+	rec := serveChartFile(h, h.Schema, "valkey")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+}
+
+func TestSchemaMissing(t *testing.T) {
+	h := newSchemaFixture(t)
+	for _, app := range []string{"empty", "unknown"} {
+		if rec := serveChartFile(h, h.Schema, app); rec.Code != http.StatusNotFound {
+			t.Errorf("Schema(%s) status = %d, want %d", app, rec.Code, http.StatusNotFound)
+		}
+	}
+}
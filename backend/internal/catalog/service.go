@@ -1,63 +1,178 @@
 package catalog
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"appstore/shared/values"
+)
+
+// Lifecycle is the lifecycle status of a catalog app
+type Lifecycle string
+
+const (
+	// LifecycleActive apps can be deployed (the default)
+	LifecycleActive Lifecycle = "active"
+	// LifecycleDeprecated apps keep running but accept no new deployments
+	LifecycleDeprecated Lifecycle = "deprecated"
+	// LifecycleRemoved apps are hidden from the catalog and accept no new deployments
+	LifecycleRemoved Lifecycle = "removed"
 )
 
 // App represents an application in the catalog
 type App struct {
-	Name        string   `json:"name" yaml:"name"`
-	DisplayName string   `json:"displayName" yaml:"displayName"`
-	Description string   `json:"description" yaml:"description"`
-	Icon        string   `json:"icon" yaml:"icon"`
-	Category    string   `json:"category" yaml:"category"`
-	ChartPath   string   `json:"chartPath" yaml:"chartPath"`
-	Tags        []string `json:"tags" yaml:"tags"`
+	Name        string    `json:"name" yaml:"name"`
+	DisplayName string    `json:"displayName" yaml:"displayName"`
+	Description string    `json:"description" yaml:"description"`
+	Icon        string    `json:"icon" yaml:"icon"`
+	Category    string    `json:"category" yaml:"category"`
+	ChartPath   string    `json:"chartPath" yaml:"chartPath"`
+	Tags        []string  `json:"tags" yaml:"tags"`
+	Lifecycle   Lifecycle `json:"lifecycle" yaml:"lifecycle"`
+	// Weight orders the apps in the catalog, heaviest first. Apps with a positive weight
+	// are featured.
+	Weight int `json:"weight,omitempty" yaml:"weight"`
+	// DefaultValues are merged under the values of the app's deployments, over the
+	// catalog's default values
+	DefaultValues map[string]interface{} `json:"defaultValues,omitempty" yaml:"defaultValues"`
+	// RequiredValues are dot-separated paths of values every deployment of the app has to
+	// set, e.g. auth.password
+	RequiredValues []string `json:"requiredValues,omitempty" yaml:"requiredValues"`
+}
+
+// IsActive reports whether new deployments of the app are allowed
+func (a App) IsActive() bool {
+	return a.Lifecycle == LifecycleActive
+}
+
+// IsFeatured reports whether the app is featured at the top of the catalog
+func (a App) IsFeatured() bool {
+	return a.Weight > 0
 }
 
 // Catalog represents the full catalog of available apps
 type Catalog struct {
 	Apps []App `json:"apps" yaml:"apps"`
+	// DefaultValues are merged under the values of every deployment
+	DefaultValues map[string]interface{} `json:"defaultValues,omitempty" yaml:"defaultValues"`
 }
 
+// ErrChartFileNotFound is returned when an app's chart doesn't contain the requested file
+var ErrChartFileNotFound = errors.New("chart file not found")
+
 // Service provides access to the app catalog
 type Service struct {
 	catalogPath string
-	catalog     *Catalog
-	mu          sync.RWMutex
+	// configMap is read instead of catalogPath if set
+	configMap *configMapSource
+	chartsDir string
+	catalog   *Catalog
+	etag      string
+	mu        sync.RWMutex
+	// loadMu serializes loads, so that an older file never replaces a newer one
+	loadMu sync.Mutex
+
+	httpClient *http.Client
+	iconCache  map[string]*Icon
+	iconMu     sync.Mutex
 }
 
-// NewService creates a new catalog service
-func NewService(catalogPath string) *Service {
+// NewService creates a new catalog service. chartsDir is the directory containing
+// the charts referenced by each app's ChartPath.
+func NewService(catalogPath, chartsDir string) *Service {
 	return &Service{
 		catalogPath: catalogPath,
+		chartsDir:   chartsDir,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		iconCache:   make(map[string]*Icon),
 	}
 }
 
-// Load reads and parses the catalog file
+// Load reads and parses the catalog file, or the catalog ConfigMap, migrating older schema
+// versions. Readers keep seeing the previous catalog until the new one is parsed, and keep
+// it if loading fails.
 func (s *Service) Load() error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	if s.configMap != nil {
+		return s.loadConfigMap()
+	}
+
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
 
 	data, err := os.ReadFile(s.catalogPath)
 	if err != nil {
 		return fmt.Errorf("failed to read catalog file: %w", err)
 	}
+	return s.parse(data)
+}
 
-	var catalog Catalog
-	if err := yaml.Unmarshal(data, &catalog); err != nil {
+// parse parses and stores a catalog. The caller holds loadMu.
+func (s *Service) parse(data []byte) error {
+	var file catalogFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
 		return fmt.Errorf("failed to parse catalog file: %w", err)
 	}
+	catalog, err := migrate(file)
+	if err != nil {
+		return err
+	}
+
+	for i := range catalog.Apps {
+		switch catalog.Apps[i].Lifecycle {
+		case "":
+			catalog.Apps[i].Lifecycle = LifecycleActive
+		case LifecycleActive, LifecycleDeprecated, LifecycleRemoved:
+		default:
+			return fmt.Errorf("app %s has invalid lifecycle %q", catalog.Apps[i].Name, catalog.Apps[i].Lifecycle)
+		}
+		for _, path := range catalog.Apps[i].RequiredValues {
+			if slices.Contains(strings.Split(path, "."), "") {
+				return fmt.Errorf("app %s has invalid required value %q", catalog.Apps[i].Name, path)
+			}
+		}
+	}
+
+	// Featured apps first, then by name
+	sort.SliceStable(catalog.Apps, func(i, j int) bool {
+		a, b := catalog.Apps[i], catalog.Apps[j]
+		if a.Weight != b.Weight {
+			return a.Weight > b.Weight
+		}
+		return a.Name < b.Name
+	})
+
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.catalog = &catalog
+	s.etag = etag
 	return nil
 }
 
-// ListApps returns all apps in the catalog
+// ETag returns an entity tag identifying the loaded catalog, which changes whenever a
+// different catalog is loaded. It is empty if no catalog is loaded.
+func (s *Service) ETag() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.etag
+}
+
+// ListApps returns all apps in the catalog, ordered by weight and then name
 func (s *Service) ListApps() []App {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -111,3 +226,64 @@ func (s *Service) AppExists(name string) bool {
 	_, err := s.GetApp(name)
 	return err == nil
 }
+
+// DefaultValues returns a copy of the catalog's global default values, which apply to
+// every deployment beneath the deployment's own values
+func (s *Service) DefaultValues() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.catalog == nil {
+		return nil
+	}
+	return values.DeepCopy(s.catalog.DefaultValues)
+}
+
+// AppDefaultValues returns a copy of the default values of the app's deployments: the
+// catalog's global default values with the app's own merged over them
+func (s *Service) AppDefaultValues(appName string) map[string]interface{} {
+	defaults := s.DefaultValues()
+	app, err := s.GetApp(appName)
+	if err != nil || len(app.DefaultValues) == 0 {
+		return defaults
+	}
+	return values.Merge(defaults, values.DeepCopy(app.DefaultValues))
+}
+
+// ChartValues returns the defaults in the app's chart values.yaml. ErrChartFileNotFound is
+// returned if the chart has no values.yaml.
+func (s *Service) ChartValues(appName string) (map[string]interface{}, error) {
+	data, err := s.ChartFile(appName, "values.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	var chartValues map[string]interface{}
+	if err := yaml.Unmarshal(data, &chartValues); err != nil {
+		return nil, fmt.Errorf("failed to parse values.yaml for app %s: %w", appName, err)
+	}
+	return chartValues, nil
+}
+
+// ChartFile reads a file such as README.md from the app's chart directory
+func (s *Service) ChartFile(appName, fileName string) ([]byte, error) {
+	app, err := s.GetApp(appName)
+	if err != nil {
+		return nil, err
+	}
+
+	chartPath := app.ChartPath
+	if chartPath == "" {
+		chartPath = app.Name
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.chartsDir, chartPath, fileName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s/%s", ErrChartFileNotFound, chartPath, fileName)
+		}
+		return nil, fmt.Errorf("failed to read chart file: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,118 @@
+package catalog
+
+import (
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLoadWhileReading(t *testing.T) {
+	catalogs := []string{
+		"apps:\n  - name: postgresql\n  - name: valkey\n",
+		"apps:\n  - name: postgresql\n  - name: valkey\n  - name: mysql\n",
+	}
+	s := newServiceWithFiles(t, map[string]string{"catalog.yaml": catalogs[0]})
+
+	var (
+		stop    atomic.Bool
+		readers sync.WaitGroup
+		bad     atomic.Int32
+	)
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for !stop.Load() {
+				if n := len(s.ListApps()); n != 2 && n != 3 {
+					bad.Add(1)
+				}
+				if _, err := s.GetApp("postgresql"); err != nil {
+					bad.Add(1)
+				}
+				if s.ETag() == "" {
+					bad.Add(1)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		if err := os.WriteFile(s.catalogPath, []byte(catalogs[i%2]), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Load(); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+	}
+	stop.Store(true)
+	readers.Wait()
+
+	if n := bad.Load(); n > 0 {
+		t.Errorf("readers observed %d partial or empty catalogs", n)
+	}
+}
+
+func TestLoadFailureKeepsCatalog(t *testing.T) {
+	s := newServiceWithFiles(t, map[string]string{"catalog.yaml": "apps:\n  - name: postgresql\n"})
+	etag := s.ETag()
+
+	if err := os.WriteFile(s.catalogPath, []byte("apps: ["), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load(); err == nil {
+		t.Fatal("Load() of an invalid catalog error = nil")
+	}
+	if !s.AppExists("postgresql") || s.ETag() != etag {
+		t.Errorf("failed Load() replaced the catalog")
+	}
+}
+
+func TestAppDefaultValues(t *testing.T) {
+	s := newServiceWithFiles(t, map[string]string{"catalog.yaml": `defaultValues:
+  podLabels:
+    managed-by: appstore
+  team: platform
+apps:
+  - name: postgresql
+    defaultValues:
+      podLabels:
+        tier: db
+      team: null
+    requiredValues: [auth.password]
+  - name: valkey
+`})
+
+	// The null is kept, so that Helm also removes the chart's default
+	want := map[string]interface{}{"podLabels": map[string]interface{}{"managed-by": "appstore", "tier": "db"}, "team": nil}
+	if got := s.AppDefaultValues("postgresql"); !reflect.DeepEqual(got, want) {
+		t.Errorf("AppDefaultValues(postgresql) = %v, want %v", got, want)
+	}
+	want = map[string]interface{}{"podLabels": map[string]interface{}{"managed-by": "appstore"}, "team": "platform"}
+	if got := s.AppDefaultValues("valkey"); !reflect.DeepEqual(got, want) {
+		t.Errorf("AppDefaultValues(valkey) = %v, want %v", got, want)
+	}
+
+	// The catalog is not modified
+	if got := s.AppDefaultValues("valkey"); !reflect.DeepEqual(got, want) {
+		t.Errorf("AppDefaultValues(valkey) after AppDefaultValues(postgresql) = %v, want %v", got, want)
+	}
+	if app, _ := s.GetApp("postgresql"); !reflect.DeepEqual(app.RequiredValues, []string{"auth.password"}) {
+		t.Errorf("requiredValues = %v", app.RequiredValues)
+	}
+}
+
+func TestLoadInvalidRequiredValues(t *testing.T) {
+	s := newServiceWithFiles(t, map[string]string{"catalog.yaml": "apps:\n  - name: postgresql\n"})
+
+	for _, path := range []string{"", "auth.", ".password", "auth..password"} {
+		content := "apps:\n  - name: postgresql\n    requiredValues: [\"" + path + "\"]\n"
+		if err := os.WriteFile(s.catalogPath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Load(); err == nil {
+			t.Errorf("Load() with required value %q error = nil", path)
+		}
+	}
+}
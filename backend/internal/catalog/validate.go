@@ -0,0 +1,189 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// schemaNode is the subset of JSON Schema checked by ValidateValues
+type schemaNode struct {
+	Type       interface{}            `json:"type"`
+	Properties map[string]*schemaNode `json:"properties"`
+	Required   []string               `json:"required"`
+	Enum       []interface{}          `json:"enum"`
+}
+
+// ValidateValues checks user-supplied values against the app's chart. With a
+// values.schema.json the type, properties, required and enum keywords are checked; otherwise
+// values are checked against the types of the defaults in values.yaml. Keys the chart
+// doesn't know are reported as warnings, since charts may accept values they don't declare.
+// ErrChartFileNotFound is returned if the chart has neither file.
+func (s *Service) ValidateValues(appName string, values map[string]interface{}) (errs, warnings []string, err error) {
+	schema, err := s.GetValuesSchema(appName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Normalize the values to their JSON representation, e.g. all numbers as float64
+	var normalized interface{}
+	if data, err := json.Marshal(values); err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal values: %w", err)
+	} else if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal values: %w", err)
+	}
+
+	if schema.Source == SchemaSourceSchema {
+		var root schemaNode
+		if err := json.Unmarshal(schema.Schema, &root); err != nil {
+			return nil, nil, fmt.Errorf("invalid values.schema.json for app %s: %w", appName, err)
+		}
+		root.validate("", normalized, &errs, &warnings)
+		return errs, warnings, nil
+	}
+
+	known := make(map[string]string, len(schema.Fields))
+	for _, field := range schema.Fields {
+		known[field.Key] = field.Type
+	}
+	var leaves []ValuesField
+	if m, ok := normalized.(map[string]interface{}); ok {
+		flattenValues("", m, &leaves)
+	}
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].Key < leaves[j].Key })
+	for _, leaf := range leaves {
+		want, ok := known[leaf.Key]
+		got := valueType(leaf.Default)
+		switch {
+		case !ok:
+			warnings = append(warnings, fmt.Sprintf("%s is not in the chart's values.yaml", leaf.Key))
+		case want != "null" && got != "null" && !typeMatches(want, got):
+			errs = append(errs, fmt.Sprintf("%s must be of type %s, got %s", leaf.Key, want, got))
+		}
+	}
+	return errs, warnings, nil
+}
+
+// UnknownValueKeys returns the top-level keys of values that the app's chart doesn't
+// declare in its values.yaml, sorted. Helm's global key is always known.
+// ErrChartFileNotFound is returned if the chart has no values.yaml.
+func (s *Service) UnknownValueKeys(appName string, values map[string]interface{}) ([]string, error) {
+	chartValues, err := s.ChartValues(appName)
+	if err != nil {
+		return nil, err
+	}
+
+	var unknown []string
+	for key := range values {
+		if _, ok := chartValues[key]; !ok && key != "global" {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+// validate checks value against the schema node, appending problems at path
+func (n *schemaNode) validate(path string, value interface{}, errs, warnings *[]string) {
+	label := path
+	if label == "" {
+		label = "values"
+	}
+
+	if types := n.types(); len(types) > 0 {
+		got := valueType(value)
+		matched := false
+		for _, want := range types {
+			if typeMatches(want, got) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			*errs = append(*errs, fmt.Sprintf("%s must be of type %s, got %s", label, strings.Join(types, " or "), got))
+			return
+		}
+	}
+
+	if len(n.Enum) > 0 && !containsValue(n.Enum, value) {
+		*errs = append(*errs, fmt.Sprintf("%s must be one of %v", label, n.Enum))
+	}
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, key := range n.Required {
+		if _, ok := object[key]; !ok {
+			*errs = append(*errs, fmt.Sprintf("%s is required", joinKey(path, key)))
+		}
+	}
+	if n.Properties == nil {
+		return
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		property, ok := n.Properties[key]
+		if !ok {
+			*warnings = append(*warnings, fmt.Sprintf("%s is not in the chart's values schema", joinKey(path, key)))
+			continue
+		}
+		property.validate(joinKey(path, key), object[key], errs, warnings)
+	}
+}
+
+// types returns the allowed types of a node, whose type may be a string or a list
+func (n *schemaNode) types() []string {
+	switch t := n.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// valueType returns the JSON Schema type name of a value decoded from JSON, in which all
+// numbers are float64
+func valueType(value interface{}) string {
+	if f, ok := value.(float64); ok && f == math.Trunc(f) {
+		return "integer"
+	}
+	return inferType(value)
+}
+
+// typeMatches reports whether a value of type got is valid for type want. Whole numbers
+// are valid numbers.
+func typeMatches(want, got string) bool {
+	return want == got || (want == "number" && got == "integer")
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
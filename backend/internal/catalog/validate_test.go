@@ -0,0 +1,87 @@
+package catalog
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateValues(t *testing.T) {
+	s := newServiceWithFiles(t, map[string]string{
+		"catalog.yaml": "apps:\n" +
+			"  - name: postgresql\n" +
+			"  - name: valkey\n" +
+			"  - name: bare\n",
+		"apps/postgresql/values.schema.json": `{
+			"type": "object",
+			"required": ["architecture"],
+			"properties": {
+				"architecture": {"type": "string", "enum": ["standalone", "replication"]},
+				"replicas": {"type": "integer"},
+				"auth": {"type": "object", "properties": {"enabled": {"type": "boolean"}}}
+			}
+		}`,
+		"apps/valkey/values.yaml": "replicaCount: 1\nratio: 0.5\nimage:\n  tag: \"8.0\"\nnodeSelector: {}\n",
+	})
+
+	tests := []struct {
+		name         string
+		app          string
+		values       map[string]interface{}
+		wantErrs     []string
+		wantWarnings []string
+	}{
+		{
+			name:   "valid against schema",
+			app:    "postgresql",
+			values: map[string]interface{}{"architecture": "replication", "replicas": 3, "auth": map[string]interface{}{"enabled": true}},
+		},
+		{
+			name:   "invalid against schema",
+			app:    "postgresql",
+			values: map[string]interface{}{"replicas": 1.5, "auth": map[string]interface{}{"enabled": "yes"}, "extra": 1},
+			wantErrs: []string{
+				"architecture is required",
+				"auth.enabled must be of type boolean, got string",
+				"replicas must be of type integer, got number",
+			},
+			wantWarnings: []string{"extra is not in the chart's values schema"},
+		},
+		{
+			name:     "enum",
+			app:      "postgresql",
+			values:   map[string]interface{}{"architecture": "cluster"},
+			wantErrs: []string{"architecture must be one of [standalone replication]"},
+		},
+		{
+			name:   "valid against values.yaml",
+			app:    "valkey",
+			values: map[string]interface{}{"replicaCount": 2, "ratio": 1, "image": map[string]interface{}{"tag": "8.1"}},
+		},
+		{
+			name:         "invalid against values.yaml",
+			app:          "valkey",
+			values:       map[string]interface{}{"replicaCount": "two", "nodeSelector": map[string]interface{}{"zone": "a"}},
+			wantErrs:     []string{"replicaCount must be of type integer, got string"},
+			wantWarnings: []string{"nodeSelector.zone is not in the chart's values.yaml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, warnings, err := s.ValidateValues(tt.app, tt.values)
+			if err != nil {
+				t.Fatalf("ValidateValues() error = %v", err)
+			}
+			if !reflect.DeepEqual(errs, tt.wantErrs) {
+				t.Errorf("errors = %q, want %q", errs, tt.wantErrs)
+			}
+			if !reflect.DeepEqual(warnings, tt.wantWarnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.wantWarnings)
+			}
+		})
+	}
+
+	if _, _, err := s.ValidateValues("bare", map[string]interface{}{"a": 1}); !errors.Is(err, ErrChartFileNotFound) {
+		t.Errorf("ValidateValues(bare) error = %v, want ErrChartFileNotFound", err)
+	}
+}
@@ -0,0 +1,151 @@
+package deployment
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"appstore/backend/internal/audit"
+	"appstore/backend/pkg/models"
+)
+
+// auditRecorder collects audit records
+type auditRecorder struct {
+	records []models.AuditRecord
+}
+
+func (a *auditRecorder) PublishAuditRecord(_ context.Context, record models.AuditRecord) error {
+	a.records = append(a.records, record)
+	return nil
+}
+
+func newAuditedHandler(t *testing.T, publisher Publisher) (*Handler, *auditRecorder) {
+	t.Helper()
+	recorder := &auditRecorder{}
+	auditLogger := audit.NewLogger(slog.New(slog.NewJSONHandler(io.Discard, nil)), recorder)
+	k8sClient := newTestK8sClient(newAppDeployment("team-a", "db", 42))
+	return NewHandler(publisher, k8sClient, newTestCatalog(t), auditLogger, nil, "", ""), recorder
+}
+
+func deleteDeployment(h *Handler, name string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /api/v1/deployments/{name}", h.Delete)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/deployments/"+name+"?namespace=team-a", nil))
+	return rec
+}
+
+func TestAuditRecordPerAction(t *testing.T) {
+	tests := []struct {
+		name       string
+		do         func(h *Handler) *httptest.ResponseRecorder
+		wantStatus int
+		want       models.AuditRecord
+	}{
+		{
+			name: "create",
+			do: func(h *Handler) *httptest.ResponseRecorder {
+				return create(h, `{"appName":"postgresql","namespace":"team-a"}`)
+			},
+			wantStatus: http.StatusAccepted,
+			want: models.AuditRecord{Action: "create", Outcome: "accepted", TeamID: "default-team", UserID: "anonymous",
+				Namespace: "team-a", AppName: "postgresql"},
+		},
+		{
+			name: "create of a removed app",
+			do: func(h *Handler) *httptest.ResponseRecorder {
+				return create(h, `{"appName":"memcached","namespace":"team-a"}`)
+			},
+			wantStatus: http.StatusBadRequest,
+			want: models.AuditRecord{Action: "create", Outcome: "rejected", TeamID: "default-team", UserID: "anonymous",
+				Namespace: "team-a", AppName: "memcached", Reason: "app memcached is removed and no longer accepts new deployments"},
+		},
+		{
+			name:       "update",
+			do:         func(h *Handler) *httptest.ResponseRecorder { return update(h, "db", "", `{"version":"2.0.0"}`) },
+			wantStatus: http.StatusAccepted,
+			want: models.AuditRecord{Action: "update", Outcome: "accepted", TeamID: "team-a", UserID: "anonymous",
+				Deployment: "db", Namespace: "team-a", AppName: "postgresql"},
+		},
+		{
+			name:       "update conflict",
+			do:         func(h *Handler) *httptest.ResponseRecorder { return update(h, "db", `"41"`, `{"version":"2.0.0"}`) },
+			wantStatus: http.StatusConflict,
+			want: models.AuditRecord{Action: "update", Outcome: "rejected", TeamID: "team-a", UserID: "anonymous",
+				Deployment: "db", Namespace: "team-a", AppName: "postgresql", Reason: "deployment has been modified"},
+		},
+		{
+			name:       "delete",
+			do:         func(h *Handler) *httptest.ResponseRecorder { return deleteDeployment(h, "db") },
+			wantStatus: http.StatusAccepted,
+			want: models.AuditRecord{Action: "delete", Outcome: "accepted", TeamID: "team-a", UserID: "anonymous",
+				Deployment: "db", Namespace: "team-a", AppName: "postgresql"},
+		},
+		{
+			name:       "delete of a missing deployment",
+			do:         func(h *Handler) *httptest.ResponseRecorder { return deleteDeployment(h, "cache") },
+			wantStatus: http.StatusNotFound,
+			want: models.AuditRecord{Action: "delete", Outcome: "rejected", UserID: "anonymous",
+				Deployment: "cache", Namespace: "team-a", Reason: "deployment not found"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, recorder := newAuditedHandler(t, &fakePublisher{})
+
+			rec := tt.do(h)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			if len(recorder.records) != 1 {
+				t.Fatalf("got %d audit records, want 1: %+v", len(recorder.records), recorder.records)
+			}
+			got := recorder.records[0]
+			if got.RequestID == "" || got.Timestamp.IsZero() {
+				t.Errorf("record is missing request ID or timestamp: %+v", got)
+			}
+			got.RequestID, got.Timestamp = "", tt.want.Timestamp
+			if got != tt.want {
+				t.Errorf("record = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditRecordsFailedPublish(t *testing.T) {
+	h, recorder := newAuditedHandler(t, nil)
+
+	if rec := deleteDeployment(h, "db"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if len(recorder.records) != 1 || recorder.records[0].Outcome != audit.OutcomeFailed {
+		t.Errorf("records = %+v, want one failed record", recorder.records)
+	}
+}
+
+func TestAuditRecordPerBatchItem(t *testing.T) {
+	h, recorder := newAuditedHandler(t, &fakePublisher{})
+
+	rec := createBatch(h, `[{"appName":"postgresql","namespace":"team-a"},{"appName":"mysql","namespace":"team-a"}]`)
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusMultiStatus)
+	}
+
+	if len(recorder.records) != 2 {
+		t.Fatalf("got %d audit records, want 2", len(recorder.records))
+	}
+	outcomes := map[string]string{}
+	for _, r := range recorder.records {
+		if r.BatchID == "" {
+			t.Errorf("record %+v has no batch ID", r)
+		}
+		outcomes[r.AppName] = r.Outcome
+	}
+	if outcomes["postgresql"] != audit.OutcomeAccepted || outcomes["mysql"] != audit.OutcomeRejected {
+		t.Errorf("outcomes = %v", outcomes)
+	}
+}
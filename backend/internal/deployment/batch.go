@@ -0,0 +1,142 @@
+package deployment
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"appstore/backend/internal/audit"
+	"appstore/backend/pkg/models"
+)
+
+// maxBatchSize limits the number of deployments in a single batch request
+const maxBatchSize = 50
+
+// teamQuota tracks a team's remaining deployments while a batch is processed
+type teamQuota struct {
+	remaining int
+	limit     int
+	err       error
+}
+
+// BatchItemResult is the outcome of a single item of a batch create
+type BatchItemResult struct {
+	Index     int    `json:"index"`
+	Status    int    `json:"status"`
+	RequestID string `json:"requestId,omitempty"`
+	AppName   string `json:"appName,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Error     string `json:"error,omitempty"`
+	// Warnings are problems that didn't prevent the item from being accepted
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// BatchCreateResponse is the multi-status response of a batch create
+type BatchCreateResponse struct {
+	BatchID string            `json:"batchId"`
+	Results []BatchItemResult `json:"results"`
+}
+
+// CreateBatch handles POST /api/v1/deployments:batch
+//
+// The body is an array of create requests. All items are validated before anything is
+// published; valid items are published as individual requests sharing a batch ID and
+// invalid items are reported with their validation error. The response is 207 Multi-Status
+// with a result per item.
+func (h *Handler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	var reqs []CreateRequest
+	if err := decodeBody(r, &reqs); err != nil {
+		status, message := bodyError(err, "invalid request body: expected an array of deployments")
+		h.respondError(w, status, message)
+		return
+	}
+	if len(reqs) == 0 {
+		h.respondError(w, http.StatusBadRequest, "at least one deployment is required")
+		return
+	}
+	if len(reqs) > maxBatchSize {
+		h.respondError(w, http.StatusBadRequest, fmt.Sprintf("a batch may contain at most %d deployments", maxBatchSize))
+		return
+	}
+
+	if h.publisher == nil {
+		h.respondError(w, http.StatusServiceUnavailable, "RabbitMQ not available")
+		return
+	}
+
+	batchID := uuid.New().String()
+	results := make([]BatchItemResult, len(reqs))
+	payloads := make([]*models.DeploymentRequestPayload, len(reqs))
+
+	// Validate everything up front
+	for i, req := range reqs {
+		results[i] = BatchItemResult{Index: i, AppName: req.AppName, Namespace: req.Namespace}
+		payload := h.newRequestPayload(req)
+		payload.BatchID = batchID
+		if msg := h.validateCreateRequest(req); msg != "" {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = msg
+			h.auditLog(r.Context(), requestAuditRecord(payload), audit.OutcomeRejected, msg)
+			continue
+		}
+		payloads[i] = &payload
+	}
+
+	// Items beyond their team's deployment limit are rejected in order
+	quotas := map[string]*teamQuota{}
+	for i, payload := range payloads {
+		if payload == nil {
+			continue
+		}
+		quota, ok := quotas[payload.TeamID]
+		if !ok {
+			quota = &teamQuota{}
+			quota.remaining, quota.limit, quota.err = h.remainingDeployments(r.Context(), payload.TeamID)
+			if quota.err != nil {
+				h.logger.Error("failed to check team deployment limit", "error", quota.err, "teamId", payload.TeamID)
+			}
+			quotas[payload.TeamID] = quota
+		}
+
+		switch {
+		case quota.err != nil:
+			results[i].Status = http.StatusInternalServerError
+			results[i].Error = "failed to create deployment"
+			h.auditLog(r.Context(), requestAuditRecord(*payload), audit.OutcomeFailed, "failed to check team deployment limit")
+			payloads[i] = nil
+		case quota.limit > 0 && quota.remaining == 0:
+			msg := teamLimitMessage(payload.TeamID, quota.limit)
+			results[i].Status = http.StatusForbidden
+			results[i].Error = msg
+			h.auditLog(r.Context(), requestAuditRecord(*payload), audit.OutcomeRejected, msg)
+			payloads[i] = nil
+		case quota.limit > 0:
+			quota.remaining--
+		}
+	}
+
+	for i, payload := range payloads {
+		if payload == nil {
+			continue
+		}
+		if err := h.publisher.PublishDeploymentRequest(r.Context(), *payload); err != nil {
+			h.logger.Error("failed to publish deployment request", "error", err, "batchId", batchID, "index", i)
+			results[i].Status = publishErrorStatus(err)
+			results[i].Error = "failed to create deployment"
+			h.auditLog(r.Context(), requestAuditRecord(*payload), audit.OutcomeFailed, "failed to publish deployment request")
+			continue
+		}
+		results[i].Status = http.StatusAccepted
+		results[i].RequestID = payload.RequestID
+		results[i].Warnings = h.unknownValuesWarnings(reqs[i])
+		h.auditLog(r.Context(), requestAuditRecord(*payload), audit.OutcomeAccepted, "")
+	}
+
+	h.logger.Info("deployment batch processed", "batchId", batchID, "items", len(reqs))
+
+	h.respondJSON(w, http.StatusMultiStatus, BatchCreateResponse{
+		BatchID: batchID,
+		Results: results,
+	})
+}
@@ -0,0 +1,89 @@
+package deployment
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func createBatch(h *Handler, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/deployments", h.Create)
+	mux.HandleFunc("POST /api/v1/deployments:batch", h.CreateBatch)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/deployments:batch", strings.NewReader(body)))
+	return rec
+}
+
+func TestCreateBatchMixedItems(t *testing.T) {
+	publisher := &fakePublisher{}
+	h := NewHandler(publisher, nil, newTestCatalog(t), nil, nil, "", "")
+
+	rec := createBatch(h, `[
+		{"appName":"postgresql","namespace":"team-a"},
+		{"appName":"postgresql"},
+		{"appName":"mysql","namespace":"team-a"},
+		{"appName":"postgresql","namespace":"team-b","releaseName":"db"}
+	]`)
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusMultiStatus, rec.Body)
+	}
+
+	var resp BatchCreateResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.BatchID == "" {
+		t.Error("batchId is empty")
+	}
+
+	wantStatus := []int{http.StatusAccepted, http.StatusBadRequest, http.StatusBadRequest, http.StatusAccepted}
+	if len(resp.Results) != len(wantStatus) {
+		t.Fatalf("got %d results, want %d", len(resp.Results), len(wantStatus))
+	}
+	for i, want := range wantStatus {
+		got := resp.Results[i]
+		if got.Index != i || got.Status != want {
+			t.Errorf("result %d = %+v, want status %d", i, got, want)
+		}
+		if want == http.StatusAccepted && got.RequestID == "" {
+			t.Errorf("result %d has no requestId", i)
+		}
+		if want == http.StatusBadRequest && got.Error == "" {
+			t.Errorf("result %d has no error", i)
+		}
+	}
+	if resp.Results[1].Error != "namespace is required" {
+		t.Errorf("result 1 error = %q", resp.Results[1].Error)
+	}
+
+	// Only the valid items are published, sharing the batch ID
+	if len(publisher.requests) != 2 {
+		t.Fatalf("published %d requests, want 2", len(publisher.requests))
+	}
+	for i, payload := range publisher.requests {
+		if payload.BatchID != resp.BatchID {
+			t.Errorf("request %d batchId = %q, want %q", i, payload.BatchID, resp.BatchID)
+		}
+	}
+	if publisher.requests[0].RequestID != resp.Results[0].RequestID || publisher.requests[1].RequestID != resp.Results[3].RequestID {
+		t.Error("published request IDs don't match the results")
+	}
+}
+
+func TestCreateBatchInvalidBody(t *testing.T) {
+	h := NewHandler(&fakePublisher{}, nil, nil, nil, nil, "", "")
+
+	for _, body := range []string{`{"appName":"postgresql"}`, `[]`, `not json`} {
+		if rec := createBatch(h, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("CreateBatch(%s) status = %d, want %d", body, rec.Code, http.StatusBadRequest)
+		}
+	}
+
+	tooMany := "[" + strings.Repeat(`{"appName":"postgresql","namespace":"team-a"},`, maxBatchSize) + `{"appName":"postgresql","namespace":"team-a"}]`
+	if rec := createBatch(h, tooMany); rec.Code != http.StatusBadRequest {
+		t.Errorf("CreateBatch(%d items) status = %d, want %d", maxBatchSize+1, rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,88 @@
+package deployment
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"appstore/backend/internal/audit"
+	"appstore/backend/pkg/models"
+)
+
+// Cancel handles POST /api/v1/deployments/{name}/cancel
+//
+// Asks the operator to abort the install or upgrade in progress. The operator cancels the
+// Helm operation and marks the deployment Failed with reason Canceled; it isn't retried
+// until the deployment is updated. Deployments that aren't installing or upgrading are
+// rejected with 409.
+func (h *Handler) Cancel(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" {
+		h.respondError(w, http.StatusBadRequest, "deployment name is required")
+		return
+	}
+
+	namespace := h.requestNamespace(r)
+
+	// TODO: Get team ID and user ID from auth context
+	userID := "anonymous"
+
+	requestID := uuid.New().String()
+	record := models.AuditRecord{
+		Action:     audit.ActionCancel,
+		UserID:     userID,
+		Deployment: name,
+		Namespace:  namespace,
+		RequestID:  requestID,
+	}
+
+	if h.k8sClient == nil || h.publisher == nil {
+		h.auditLog(r.Context(), record, audit.OutcomeFailed, "Kubernetes or RabbitMQ not available")
+		h.respondError(w, http.StatusServiceUnavailable, "Kubernetes or RabbitMQ not available")
+		return
+	}
+
+	deployment, err := h.k8sClient.GetAppDeployment(r.Context(), namespace, name)
+	if err != nil {
+		h.auditLog(r.Context(), record, audit.OutcomeRejected, "deployment not found")
+		h.respondError(w, http.StatusNotFound, "deployment not found")
+		return
+	}
+	record.TeamID = deployment.TeamID
+	record.AppName = deployment.AppName
+
+	if deployment.Phase != "Installing" && deployment.Phase != "Upgrading" {
+		msg := fmt.Sprintf("deployment has no install or upgrade in progress (phase %s)", deployment.Phase)
+		h.auditLog(r.Context(), record, audit.OutcomeRejected, msg)
+		h.respondError(w, http.StatusConflict, msg)
+		return
+	}
+
+	payload := models.DeploymentCancelPayload{
+		RequestID: requestID,
+		TeamID:    deployment.TeamID,
+		UserID:    userID,
+		Name:      name,
+		Namespace: namespace,
+	}
+
+	if err := h.publisher.PublishDeploymentCancel(r.Context(), payload); err != nil {
+		h.logger.Error("failed to publish deployment cancel", "error", err)
+		h.auditLog(r.Context(), record, audit.OutcomeFailed, "failed to publish deployment cancel")
+		h.respondError(w, publishErrorStatus(err), "failed to cancel deployment")
+		return
+	}
+
+	h.auditLog(r.Context(), record, audit.OutcomeAccepted, "")
+
+	h.logger.Info("deployment cancel published",
+		"requestId", requestID,
+		"name", name,
+	)
+
+	h.respondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"requestId": requestID,
+		"message":   "deployment cancel request accepted",
+	})
+}
@@ -0,0 +1,71 @@
+package deployment
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func cancel(h *Handler, name string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/deployments/{name}/cancel", h.Cancel)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/deployments/"+name+"/cancel?namespace=team-a", nil))
+	return rec
+}
+
+func TestCancel(t *testing.T) {
+	tests := []struct {
+		phase      string
+		wantStatus int
+	}{
+		{"Installing", http.StatusAccepted},
+		{"Upgrading", http.StatusAccepted},
+		{"Deployed", http.StatusConflict},
+		{"Failed", http.StatusConflict},
+		{"", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.phase, func(t *testing.T) {
+			ad := newAppDeployment("team-a", "db", 42)
+			ad.Object["status"] = map[string]interface{}{"phase": tt.phase}
+			publisher := &fakePublisher{}
+			h := NewHandler(publisher, newTestK8sClient(ad), nil, nil, nil, "", "")
+
+			rec := cancel(h, "db")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			if tt.wantStatus != http.StatusAccepted {
+				if !strings.Contains(rec.Body.String(), "no install or upgrade in progress") {
+					t.Errorf("body = %s", rec.Body)
+				}
+				if len(publisher.cancels) != 0 {
+					t.Errorf("published %d cancels, want 0", len(publisher.cancels))
+				}
+				return
+			}
+			if len(publisher.cancels) != 1 {
+				t.Fatalf("published %d cancels, want 1", len(publisher.cancels))
+			}
+			payload := publisher.cancels[0]
+			if payload.Name != "db" || payload.Namespace != "team-a" || payload.TeamID != "team-a" || payload.RequestID == "" {
+				t.Errorf("payload = %+v", payload)
+			}
+		})
+	}
+}
+
+func TestCancelNotFound(t *testing.T) {
+	publisher := &fakePublisher{}
+	h := NewHandler(publisher, newTestK8sClient(), nil, nil, nil, "", "")
+
+	if rec := cancel(h, "db"); rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if len(publisher.cancels) != 0 {
+		t.Errorf("published %d cancels, want 0", len(publisher.cancels))
+	}
+}
@@ -0,0 +1,103 @@
+package deployment
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"appstore/backend/internal/audit"
+	"appstore/backend/pkg/models"
+	"appstore/shared/values"
+)
+
+// cloneSuffix marks a POST to a deployment as a clone
+const cloneSuffix = ":clone"
+
+// CloneRequest is the request body for cloning a deployment. Fields left empty are taken
+// from the source deployment.
+type CloneRequest struct {
+	// Name of the new deployment (generated if empty)
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Version   string `json:"version,omitempty"`
+	// Values are merged over the source deployment's values
+	Values map[string]interface{} `json:"values,omitempty"`
+	// Priority and TTLSeconds of the create request, see CreateRequest
+	Priority   int `json:"priority,omitempty"`
+	TTLSeconds int `json:"ttlSeconds,omitempty"`
+}
+
+// Clone handles POST /api/v1/deployments/{name}:clone
+//
+// Publishes a create request for a new deployment of the same app with the source
+// deployment's version, values and secret key references, overridden by the body. The
+// source deployment must belong to the requesting team. The new deployment is validated
+// and limited like any other create, including the Idempotency-Key header.
+func (h *Handler) Clone(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(r.PathValue("name"), cloneSuffix)
+	if !ok || name == "" {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	namespace := h.requestNamespace(r)
+
+	var req CloneRequest
+	if err := decodeBody(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		status, message := bodyError(err, "invalid request body")
+		h.auditLog(r.Context(), models.AuditRecord{Action: audit.ActionCreate, Namespace: namespace}, audit.OutcomeRejected, message)
+		h.respondError(w, status, message)
+		return
+	}
+
+	record := models.AuditRecord{
+		Action:     audit.ActionCreate,
+		TeamID:     requestTeamID,
+		UserID:     requestUserID,
+		Deployment: req.Name,
+		Namespace:  req.Namespace,
+	}
+	if record.Namespace == "" {
+		record.Namespace = namespace
+	}
+
+	if h.k8sClient == nil {
+		h.auditLog(r.Context(), record, audit.OutcomeFailed, "Kubernetes not available")
+		h.respondError(w, http.StatusServiceUnavailable, "Kubernetes not available")
+		return
+	}
+
+	source, err := h.k8sClient.GetAppDeploymentSpec(r.Context(), namespace, name)
+	if err != nil {
+		h.auditLog(r.Context(), record, audit.OutcomeRejected, "source deployment not found")
+		h.respondError(w, http.StatusNotFound, "deployment not found")
+		return
+	}
+	record.AppName = source.AppName
+
+	if source.TeamID != requestTeamID {
+		h.auditLog(r.Context(), record, audit.OutcomeRejected, "source deployment belongs to another team")
+		h.respondError(w, http.StatusForbidden, "deployment belongs to another team")
+		return
+	}
+
+	create := CreateRequest{
+		AppName:       source.AppName,
+		Namespace:     record.Namespace,
+		ReleaseName:   req.Name,
+		Version:       req.Version,
+		Values:        source.Values,
+		SecretKeyRefs: source.SecretKeyRefs,
+		Priority:      req.Priority,
+		TTLSeconds:    req.TTLSeconds,
+	}
+	if create.Version == "" {
+		create.Version = source.ChartVersion
+	}
+	if req.Values != nil {
+		create.Values = values.Merge(source.Values, req.Values)
+	}
+
+	h.create(w, r, create)
+}
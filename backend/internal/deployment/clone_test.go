@@ -0,0 +1,116 @@
+package deployment
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"appstore/backend/pkg/models"
+)
+
+func clone(h *Handler, path, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/deployments/{name}", h.Clone)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/deployments/"+path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func newCloneTestHandler(t *testing.T, publisher *fakePublisher) *Handler {
+	source := newAppDeployment("team-a", "db", 42)
+	spec := source.Object["spec"].(map[string]interface{})
+	spec["teamId"] = requestTeamID
+	spec["chartVersion"] = "15.2.0"
+	spec["values"] = map[string]interface{}{
+		"replicaCount": int64(1),
+		"auth":         map[string]interface{}{"database": "app", "username": "app"},
+	}
+	spec["secretKeyRefs"] = []interface{}{
+		map[string]interface{}{"path": "auth.password", "name": "pg-credentials", "key": "password"},
+	}
+
+	other := newAppDeployment("team-b", "cache", 7)
+	return NewHandler(publisher, newTestK8sClient(source, other), newTestCatalog(t), nil, nil, "", "")
+}
+
+func TestCloneInheritsSource(t *testing.T) {
+	publisher := &fakePublisher{}
+	h := newCloneTestHandler(t, publisher)
+
+	rec := clone(h, "db:clone?namespace=team-a", "")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	if len(publisher.requests) != 1 {
+		t.Fatalf("published %d requests, want 1", len(publisher.requests))
+	}
+
+	got := publisher.requests[0]
+	if got.AppName != "postgresql" || got.Namespace != "team-a" || got.ReleaseName != "" || got.Version != "15.2.0" {
+		t.Errorf("request = %+v", got)
+	}
+	wantValues := map[string]interface{}{
+		"replicaCount": int64(1),
+		"auth":         map[string]interface{}{"database": "app", "username": "app"},
+	}
+	if !reflect.DeepEqual(got.Values, wantValues) {
+		t.Errorf("values = %v, want %v", got.Values, wantValues)
+	}
+	wantRefs := []models.SecretKeyRef{{Path: "auth.password", Name: "pg-credentials", Key: "password"}}
+	if !reflect.DeepEqual(got.SecretKeyRefs, wantRefs) {
+		t.Errorf("secretKeyRefs = %v, want %v", got.SecretKeyRefs, wantRefs)
+	}
+}
+
+func TestCloneAppliesOverrides(t *testing.T) {
+	publisher := &fakePublisher{}
+	h := newCloneTestHandler(t, publisher)
+
+	body := `{"name":"db-staging","namespace":"team-a-staging","version":"15.3.0","values":{"replicaCount":2,"auth":{"username":null}}}`
+	rec := clone(h, "db:clone?namespace=team-a", body)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusAccepted, rec.Body)
+	}
+
+	got := publisher.requests[0]
+	if got.ReleaseName != "db-staging" || got.Namespace != "team-a-staging" || got.Version != "15.3.0" {
+		t.Errorf("request = %+v", got)
+	}
+	wantValues := map[string]interface{}{
+		"replicaCount": float64(2),
+		"auth":         map[string]interface{}{"database": "app", "username": nil},
+	}
+	if !reflect.DeepEqual(got.Values, wantValues) {
+		t.Errorf("values = %v, want %v", got.Values, wantValues)
+	}
+}
+
+func TestCloneErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"unknown deployment", "missing:clone?namespace=team-a", "", http.StatusNotFound},
+		{"other team's deployment", "cache:clone?namespace=team-b", "", http.StatusForbidden},
+		{"no clone suffix", "db?namespace=team-a", "", http.StatusNotFound},
+		{"invalid body", "db:clone?namespace=team-a", `{"name":`, http.StatusBadRequest},
+		{"values not an object", "db:clone?namespace=team-a", `{"values":"replicaCount"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakePublisher{}
+			rec := clone(newCloneTestHandler(t, publisher), tt.path, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if len(publisher.requests) != 0 {
+				t.Errorf("published %d requests, want 0", len(publisher.requests))
+			}
+		})
+	}
+}
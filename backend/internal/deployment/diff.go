@@ -0,0 +1,140 @@
+package deployment
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"appstore/backend/internal/k8s"
+)
+
+// ValuesDiff is the response of GET /api/v1/deployments/{name}/diff
+type ValuesDiff struct {
+	From    int           `json:"from"`
+	To      int           `json:"to"`
+	Added   []ValueChange `json:"added"`
+	Removed []ValueChange `json:"removed"`
+	Changed []ValueChange `json:"changed"`
+}
+
+// ValueChange is a difference at a dot-separated values path. The values themselves are
+// never returned: revisions store the values of Secrets, valuesFrom Secrets and
+// SOPS-encrypted references in plain text, and which values came from them isn't recorded
+// for earlier revisions.
+type ValueChange struct {
+	Path string `json:"path"`
+}
+
+// Diff handles GET /api/v1/deployments/{name}/diff
+//
+// Compares the values of two revisions of the deployment's Helm release, given by the from
+// and to query parameters. By default the latest revision is compared with the one before
+// it. Only the paths of the differences are returned, see ValueChange.
+func (h *Handler) Diff(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		h.respondError(w, http.StatusServiceUnavailable, "Kubernetes not available")
+		return
+	}
+
+	name := r.PathValue("name")
+	if name == "" {
+		h.respondError(w, http.StatusBadRequest, "deployment name is required")
+		return
+	}
+
+	namespace := h.requestNamespace(r)
+
+	var from, to int
+	for param, revision := range map[string]*int{"from": &from, "to": &to} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			h.respondError(w, http.StatusBadRequest, param+" must be a positive revision number")
+			return
+		}
+		*revision = n
+	}
+
+	history, err := h.k8sClient.GetReleaseHistory(r.Context(), namespace, name)
+	if errors.Is(err, k8s.ErrRemoteCluster) {
+		h.logger.Error("failed to read release history from the remote cluster", "error", err, "name", name, "namespace", namespace)
+		h.respondError(w, http.StatusBadGateway, "the deployment's remote cluster can't be read")
+		return
+	}
+	if k8s.IsNotFound(err) {
+		h.respondError(w, http.StatusNotFound, "deployment not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get release history", "error", err, "name", name, "namespace", namespace)
+		h.respondError(w, http.StatusInternalServerError, "failed to get release history")
+		return
+	}
+
+	revisions := history.Revisions
+	if to == 0 && len(revisions) > 0 {
+		to = revisions[len(revisions)-1].Revision
+	}
+	if from == 0 {
+		// The latest stored revision before to
+		for _, rev := range revisions {
+			if rev.Revision < to {
+				from = rev.Revision
+			}
+		}
+	}
+	fromRevision, toRevision := history.Revision(from), history.Revision(to)
+	if fromRevision == nil || toRevision == nil {
+		h.respondError(w, http.StatusNotFound, "revisions to compare not found")
+		return
+	}
+
+	diff := ValuesDiff{From: from, To: to, Added: []ValueChange{}, Removed: []ValueChange{}, Changed: []ValueChange{}}
+	diffValues("", fromRevision.Values, toRevision.Values, &diff)
+
+	h.respondJSON(w, http.StatusOK, diff)
+}
+
+// diffValues appends the differences between two values maps to diff, descending into maps
+// present on both sides. Other values, including lists, are compared as a whole.
+func diffValues(prefix string, from, to map[string]interface{}, diff *ValuesDiff) {
+	keys := make([]string, 0, len(from)+len(to))
+	for key := range from {
+		keys = append(keys, key)
+	}
+	for key := range to {
+		if _, ok := from[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		oldValue, inFrom := from[key]
+		newValue, inTo := to[key]
+		switch {
+		case !inFrom:
+			diff.Added = append(diff.Added, ValueChange{Path: path})
+		case !inTo:
+			diff.Removed = append(diff.Removed, ValueChange{Path: path})
+		default:
+			oldMap, oldIsMap := oldValue.(map[string]interface{})
+			newMap, newIsMap := newValue.(map[string]interface{})
+			if oldIsMap && newIsMap {
+				diffValues(path, oldMap, newMap, diff)
+			} else if !reflect.DeepEqual(oldValue, newValue) {
+				diff.Changed = append(diff.Changed, ValueChange{Path: path})
+			}
+		}
+	}
+}
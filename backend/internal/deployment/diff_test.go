@@ -0,0 +1,167 @@
+package deployment
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"appstore/backend/internal/k8s"
+)
+
+// newReleaseSecret stores an uncompressed release revision like Helm's Secret driver
+func newReleaseSecret(t *testing.T, release string, revision int, config map[string]interface{}) *corev1.Secret {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{"name": release, "version": revision, "config": config})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("sh.helm.release.v1.%s.v%d", release, revision),
+			Namespace: "team-a",
+			Labels:    map[string]string{"owner": "helm", "name": release},
+		},
+		Data: map[string][]byte{"release": []byte(base64.StdEncoding.EncodeToString(data))},
+	}
+}
+
+func newDiffHandler(t *testing.T) *Handler {
+	t.Helper()
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{k8s.AppDeploymentGVR: "AppDeploymentList"},
+		newAppDeployment("team-a", "db", 42),
+	)
+	clientset := fake.NewClientset(
+		newReleaseSecret(t, "db", 1, map[string]interface{}{
+			"replicaCount": 1,
+			"auth":         map[string]interface{}{"database": "app", "password": "old"},
+		}),
+		newReleaseSecret(t, "db", 2, map[string]interface{}{
+			"replicaCount": 2,
+			"image":        map[string]interface{}{"tag": "16.1"},
+			"auth":         map[string]interface{}{"database": "app", "password": "old"},
+		}),
+		newReleaseSecret(t, "db", 3, map[string]interface{}{
+			"replicaCount": 3,
+			"auth":         map[string]interface{}{"username": "app", "password": "new"},
+			"metrics":      map[string]interface{}{"enabled": true},
+		}),
+	)
+	return NewHandler(nil, k8s.NewClientFromInterfaces(dynamicClient, clientset), nil, nil, nil, "", "")
+}
+
+func diff(h *Handler, query string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/deployments/{name}/diff", h.Diff)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/deployments/db/diff?namespace=team-a"+query, nil))
+	return rec
+}
+
+func TestDiffLatestRevisions(t *testing.T) {
+	rec := diff(newDiffHandler(t), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	var got ValuesDiff
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := ValuesDiff{
+		From:    2,
+		To:      3,
+		Added:   []ValueChange{{Path: "auth.username"}, {Path: "metrics"}},
+		Removed: []ValueChange{{Path: "auth.database"}, {Path: "image"}},
+		Changed: []ValueChange{{Path: "auth.password"}, {Path: "replicaCount"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diff = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiffOmitsValues(t *testing.T) {
+	// Values from secretKeyRefs of earlier revisions, valuesFrom Secrets and SOPS-encrypted
+	// references look like any other value in the stored revisions
+	for _, query := range []string{"", "&from=1&to=3", "&from=1&to=2"} {
+		rec := diff(newDiffHandler(t), query)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+		}
+		for _, value := range []string{"old", "new", "16.1", `"app"`} {
+			if strings.Contains(rec.Body.String(), value) {
+				t.Errorf("diff%s = %s, want no values like %s", query, rec.Body, value)
+			}
+		}
+	}
+}
+
+func TestDiffRevisions(t *testing.T) {
+	tests := []struct {
+		query       string
+		wantStatus  int
+		wantChanges int
+	}{
+		{"&from=1&to=2", http.StatusOK, 2},
+		{"&to=2", http.StatusOK, 2},
+		{"&from=1", http.StatusOK, 5},
+		{"&from=2&to=2", http.StatusOK, 0},
+		{"&to=1", http.StatusNotFound, 0},
+		{"&from=4", http.StatusNotFound, 0},
+		{"&from=latest", http.StatusBadRequest, 0},
+		{"&to=0", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := diff(newDiffHandler(t), tt.query)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got ValuesDiff
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if n := len(got.Added) + len(got.Removed) + len(got.Changed); n != tt.wantChanges {
+				t.Errorf("got %d changes, want %d: %+v", n, tt.wantChanges, got)
+			}
+		})
+	}
+}
+
+func TestDiffUnknownDeployment(t *testing.T) {
+	h := NewHandler(nil, newTestK8sClient(), nil, nil, nil, "", "")
+	if rec := diff(h, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestDiffCorruptRelease(t *testing.T) {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{k8s.AppDeploymentGVR: "AppDeploymentList"},
+		newAppDeployment("team-a", "db", 42),
+	)
+	corrupt := newReleaseSecret(t, "db", 1, nil)
+	corrupt.Data["release"] = []byte("not base64!")
+	h := NewHandler(nil, k8s.NewClientFromInterfaces(dynamicClient, fake.NewClientset(corrupt)), nil, nil, nil, "", "")
+
+	if rec := diff(h, ""); rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
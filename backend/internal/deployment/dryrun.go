@@ -0,0 +1,65 @@
+package deployment
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"appstore/backend/internal/catalog"
+)
+
+// DryRunResult is the response of a create with ?dryRun=true
+type DryRunResult struct {
+	Valid    bool     `json:"valid"`
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+}
+
+// dryRunCreate validates a create request without publishing it. Problems that would make
+// the create fail are reported as errors, checks that couldn't be made as warnings.
+func (h *Handler) dryRunCreate(w http.ResponseWriter, r *http.Request, req CreateRequest) {
+	result := DryRunResult{Errors: []string{}, Warnings: []string{}}
+	addError := func(msg string) { result.Errors = append(result.Errors, msg) }
+	addWarning := func(msg string) { result.Warnings = append(result.Warnings, msg) }
+
+	if msg := h.validateCreateRequest(req); msg != "" {
+		addError(msg)
+	}
+
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		if errs := validation.IsValidLabelValue(key); len(errs) > 0 {
+			addError("invalid Idempotency-Key: " + strings.Join(errs, "; "))
+		}
+	}
+
+	if len(req.Values) > 0 && h.catalogService != nil && h.catalogService.AppExists(req.AppName) {
+		errs, warnings, err := h.catalogService.ValidateValues(req.AppName, req.Values)
+		switch {
+		case errors.Is(err, catalog.ErrChartFileNotFound):
+			addWarning("values not validated: the chart has no values.schema.json or values.yaml")
+		case err != nil:
+			addWarning("values not validated: " + err.Error())
+		}
+		for _, msg := range errs {
+			addError("invalid values: " + msg)
+		}
+		for _, msg := range warnings {
+			addWarning(msg)
+		}
+	}
+
+	payload := h.newRequestPayload(req)
+	remaining, limit, err := h.remainingDeployments(r.Context(), payload.TeamID)
+	switch {
+	case err != nil:
+		h.logger.Error("failed to check team deployment limit", "error", err, "teamId", payload.TeamID)
+		addWarning("team deployment limit not checked")
+	case limit > 0 && remaining == 0:
+		addError(teamLimitMessage(payload.TeamID, limit))
+	}
+
+	result.Valid = len(result.Errors) == 0
+	h.respondJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,147 @@
+package deployment
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"appstore/backend/internal/catalog"
+)
+
+// newSchemaCatalog returns a catalog whose postgresql chart has a values schema
+func newSchemaCatalog(t *testing.T) *catalog.Service {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"catalog.yaml":                       "apps:\n  - name: postgresql\n  - name: mysql\n    lifecycle: deprecated\n",
+		"apps/postgresql/values.schema.json": `{"type": "object", "properties": {"replicas": {"type": "integer"}}}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := catalog.NewService(filepath.Join(dir, "catalog.yaml"), filepath.Join(dir, "apps"))
+	if err := s.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return s
+}
+
+func dryRun(h *Handler, query, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.Create(rec, httptest.NewRequest(http.MethodPost, "/api/v1/deployments"+query, strings.NewReader(body)))
+	return rec
+}
+
+func TestCreateDryRun(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantErrs     []string
+		wantWarnings []string
+	}{
+		{
+			name: "valid",
+			body: `{"appName":"postgresql","namespace":"team-a","values":{"replicas":2}}`,
+		},
+		{
+			name:     "missing namespace",
+			body:     `{"appName":"postgresql"}`,
+			wantErrs: []string{"namespace is required"},
+		},
+		{
+			name:     "invalid namespace",
+			body:     `{"appName":"postgresql","namespace":"Team_A"}`,
+			wantErrs: []string{"invalid namespace: a lowercase RFC 1123 label must consist of"},
+		},
+		{
+			name:     "unknown app",
+			body:     `{"appName":"oracle","namespace":"team-a"}`,
+			wantErrs: []string{"app oracle is not in the catalog"},
+		},
+		{
+			name:     "deprecated app",
+			body:     `{"appName":"mysql","namespace":"team-a"}`,
+			wantErrs: []string{"app mysql is deprecated"},
+		},
+		{
+			name:         "invalid values",
+			body:         `{"appName":"postgresql","namespace":"team-a","values":{"replicas":"two","extra":true}}`,
+			wantErrs:     []string{"invalid values: replicas must be of type integer, got string"},
+			wantWarnings: []string{"extra is not in the chart's values schema"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakePublisher{}
+			h := NewHandler(publisher, nil, newSchemaCatalog(t), nil, nil, "", "")
+
+			rec := dryRun(h, "?dryRun=true", tt.body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusOK, rec.Body)
+			}
+			if len(publisher.requests) != 0 {
+				t.Errorf("dry run published %d requests", len(publisher.requests))
+			}
+
+			var result DryRunResult
+			if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+			if result.Valid != (len(tt.wantErrs) == 0) {
+				t.Errorf("valid = %v with errors %q", result.Valid, result.Errors)
+			}
+			if len(result.Errors) != len(tt.wantErrs) {
+				t.Fatalf("errors = %q, want %q", result.Errors, tt.wantErrs)
+			}
+			for i, want := range tt.wantErrs {
+				if !strings.HasPrefix(result.Errors[i], want) {
+					t.Errorf("error %d = %q, want prefix %q", i, result.Errors[i], want)
+				}
+			}
+			if len(result.Warnings)+len(tt.wantWarnings) > 0 && !reflect.DeepEqual(result.Warnings, tt.wantWarnings) {
+				t.Errorf("warnings = %q, want %q", result.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestCreateDryRunTeamLimit(t *testing.T) {
+	h, publisher := newLimitedHandler(&TeamLimits{Default: 1})
+	if rec := create(h, `{"appName":"postgresql","namespace":"team-a"}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("Create() status = %d (body %s)", rec.Code, rec.Body)
+	}
+
+	rec := dryRun(h, "?dryRun=true", `{"appName":"postgresql","namespace":"team-a"}`)
+	if !strings.Contains(rec.Body.String(), `"valid":false`) || !strings.Contains(rec.Body.String(), "limit of 1 deployments") {
+		t.Errorf("dry run body = %s, want the team limit error", rec.Body)
+	}
+	if len(publisher.requests) != 1 {
+		t.Errorf("published %d requests, want only the real create", len(publisher.requests))
+	}
+}
+
+func TestCreateDryRunParameter(t *testing.T) {
+	publisher := &fakePublisher{}
+	h := NewHandler(publisher, nil, newSchemaCatalog(t), nil, nil, "", "")
+
+	if rec := dryRun(h, "?dryRun=maybe", `{"appName":"postgresql","namespace":"team-a"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := dryRun(h, "?dryRun=false", `{"appName":"postgresql","namespace":"team-a"}`); rec.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+	if len(publisher.requests) != 1 {
+		t.Errorf("published %d requests, want 1", len(publisher.requests))
+	}
+}
@@ -1,15 +1,32 @@
 package deployment
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
+	"k8s.io/apimachinery/pkg/util/validation"
 
+	"appstore/backend/internal/audit"
+	"appstore/backend/internal/catalog"
 	"appstore/backend/internal/k8s"
 	"appstore/backend/internal/rabbitmq"
 	"appstore/backend/pkg/models"
+	sharedrabbitmq "appstore/shared/rabbitmq"
+	"appstore/shared/values"
+)
+
+// The team and user of requests
+// TODO: Get team ID and user ID from auth context
+const (
+	requestTeamID = "default-team"
+	requestUserID = "anonymous"
 )
 
 // CreateRequest is the request body for creating a deployment
@@ -19,6 +36,15 @@ type CreateRequest struct {
 	ReleaseName string                 `json:"releaseName,omitempty"`
 	Version     string                 `json:"version,omitempty"`
 	Values      map[string]interface{} `json:"values,omitempty"`
+	// SecretKeyRefs set values from Secret keys in the deployment's namespace, so that
+	// credentials don't have to be sent as plain values
+	SecretKeyRefs []models.SecretKeyRef `json:"secretKeyRefs,omitempty"`
+	// Priority from 0 (default) to sharedrabbitmq.MaxPriority; higher priority requests are
+	// processed before lower priority requests waiting in the queue
+	Priority int `json:"priority,omitempty"`
+	// TTLSeconds drops the request if the operator hasn't picked it up in time, e.g.
+	// during an outage; 0 uses the default message TTL
+	TTLSeconds int `json:"ttlSeconds,omitempty"`
 }
 
 // UpdateRequest is the request body for updating a deployment
@@ -27,81 +53,278 @@ type UpdateRequest struct {
 	Values  map[string]interface{} `json:"values,omitempty"`
 }
 
+// Publisher publishes deployment messages to the operator
+type Publisher interface {
+	PublishDeploymentRequest(ctx context.Context, payload models.DeploymentRequestPayload) error
+	PublishDeploymentUpdate(ctx context.Context, payload models.DeploymentUpdatePayload) error
+	PublishDeploymentDelete(ctx context.Context, payload models.DeploymentDeletePayload) error
+	PublishDeploymentCancel(ctx context.Context, payload models.DeploymentCancelPayload) error
+}
+
 // Handler handles deployment HTTP requests
 type Handler struct {
-	publisher *rabbitmq.Publisher
-	k8sClient *k8s.Client
-	logger    *slog.Logger
+	publisher      Publisher
+	k8sClient      *k8s.Client
+	catalogService *catalog.Service
+	auditLogger    *audit.Logger
+	teamLimits     *TeamLimits
+	logger         *slog.Logger
+
+	unknownValuesMode UnknownValuesMode
+	defaultNamespace  string
 }
 
-// NewHandler creates a new deployment handler
-func NewHandler(publisher *rabbitmq.Publisher, k8sClient *k8s.Client) *Handler {
+// NewHandler creates a new deployment handler. publisher may be nil if RabbitMQ is unavailable,
+// auditLogger may be nil to disable the audit trail and teamLimits may be nil to not limit
+// the number of deployments per team. unknownValuesMode defaults to UnknownValuesWarn and
+// defaultNamespace, the namespace of requests without ?namespace=, to DefaultNamespace.
+func NewHandler(publisher Publisher, k8sClient *k8s.Client, catalogService *catalog.Service, auditLogger *audit.Logger, teamLimits *TeamLimits, unknownValuesMode UnknownValuesMode, defaultNamespace string) *Handler {
+	if unknownValuesMode == "" {
+		unknownValuesMode = UnknownValuesWarn
+	}
+	if defaultNamespace == "" {
+		defaultNamespace = DefaultNamespace
+	}
 	return &Handler{
-		publisher: publisher,
-		k8sClient: k8sClient,
-		logger:    slog.Default().With("component", "deployment-handler"),
+		publisher:         publisher,
+		k8sClient:         k8sClient,
+		catalogService:    catalogService,
+		auditLogger:       auditLogger,
+		teamLimits:        teamLimits,
+		logger:            slog.Default().With("component", "deployment-handler"),
+		unknownValuesMode: unknownValuesMode,
+		defaultNamespace:  defaultNamespace,
 	}
 }
 
 // Create handles POST /api/v1/deployments
+//
+// Clients may send an Idempotency-Key header to make retries safe: a retried request
+// with the same key returns the existing deployment instead of creating a duplicate.
+// With ?dryRun=true the request is only validated and the result returned.
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
-	if h.publisher == nil {
-		h.respondError(w, http.StatusServiceUnavailable, "RabbitMQ not available")
+	var req CreateRequest
+	if err := decodeBody(r, &req); err != nil {
+		status, message := bodyError(err, "invalid request body")
+		h.auditLog(r.Context(), models.AuditRecord{Action: audit.ActionCreate}, audit.OutcomeRejected, message)
+		h.respondError(w, status, message)
 		return
 	}
 
-	var req CreateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid request body")
+	if v := r.URL.Query().Get("dryRun"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "dryRun must be a boolean")
+			return
+		}
+		if dryRun {
+			// Nothing is changed, so dry runs are not audited
+			h.dryRunCreate(w, r, req)
+			return
+		}
+	}
+
+	h.create(w, r, req)
+}
+
+// create validates a create request and publishes it, responding with 202 and the request ID
+func (h *Handler) create(w http.ResponseWriter, r *http.Request, req CreateRequest) {
+	payload := h.newRequestPayload(req)
+	record := requestAuditRecord(payload)
+
+	if msg := h.validateCreateRequest(req); msg != "" {
+		h.auditLog(r.Context(), record, audit.OutcomeRejected, msg)
+		h.respondError(w, http.StatusBadRequest, msg)
 		return
 	}
 
-	// Validate required fields
-	if req.AppName == "" {
-		h.respondError(w, http.StatusBadRequest, "appName is required")
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if idempotencyKey != "" {
+		// The key is stored as a label on the AppDeployment
+		if errs := validation.IsValidLabelValue(idempotencyKey); len(errs) > 0 {
+			msg := "invalid Idempotency-Key: " + strings.Join(errs, "; ")
+			h.auditLog(r.Context(), record, audit.OutcomeRejected, msg)
+			h.respondError(w, http.StatusBadRequest, msg)
+			return
+		}
+
+		if h.k8sClient != nil {
+			existing, err := h.k8sClient.FindAppDeploymentByIdempotencyKey(r.Context(), req.Namespace, idempotencyKey)
+			if err != nil {
+				h.logger.Error("failed to look up idempotency key", "error", err)
+				h.auditLog(r.Context(), record, audit.OutcomeFailed, "failed to look up idempotency key")
+				h.respondError(w, http.StatusInternalServerError, "failed to create deployment")
+				return
+			}
+			if existing != nil {
+				// A retry of a request that was already audited
+				h.respondJSON(w, http.StatusOK, existing)
+				return
+			}
+		}
+	}
+
+	remaining, limit, err := h.remainingDeployments(r.Context(), payload.TeamID)
+	if err != nil {
+		h.logger.Error("failed to check team deployment limit", "error", err, "teamId", payload.TeamID)
+		h.auditLog(r.Context(), record, audit.OutcomeFailed, "failed to check team deployment limit")
+		h.respondError(w, http.StatusInternalServerError, "failed to create deployment")
 		return
 	}
-	if req.Namespace == "" {
-		h.respondError(w, http.StatusBadRequest, "namespace is required")
+	if limit > 0 && remaining == 0 {
+		msg := teamLimitMessage(payload.TeamID, limit)
+		h.auditLog(r.Context(), record, audit.OutcomeRejected, msg)
+		h.respondError(w, http.StatusForbidden, msg)
 		return
 	}
 
-	// TODO: Get team ID and user ID from auth context
-	teamID := "default-team"
-	userID := "anonymous"
-
-	requestID := uuid.New().String()
-
-	payload := models.DeploymentRequestPayload{
-		RequestID:   requestID,
-		TeamID:      teamID,
-		UserID:      userID,
-		AppName:     req.AppName,
-		Namespace:   req.Namespace,
-		ReleaseName: req.ReleaseName,
-		Version:     req.Version,
-		Values:      req.Values,
+	if h.publisher == nil {
+		h.auditLog(r.Context(), record, audit.OutcomeFailed, "RabbitMQ not available")
+		h.respondError(w, http.StatusServiceUnavailable, "RabbitMQ not available")
+		return
 	}
 
+	payload.IdempotencyKey = idempotencyKey
+
 	if err := h.publisher.PublishDeploymentRequest(r.Context(), payload); err != nil {
 		h.logger.Error("failed to publish deployment request", "error", err)
-		h.respondError(w, http.StatusInternalServerError, "failed to create deployment")
+		h.auditLog(r.Context(), record, audit.OutcomeFailed, "failed to publish deployment request")
+		h.respondError(w, publishErrorStatus(err), "failed to create deployment")
 		return
 	}
 
+	h.auditLog(r.Context(), record, audit.OutcomeAccepted, "")
+
 	h.logger.Info("deployment request published",
-		"requestId", requestID,
+		"requestId", payload.RequestID,
 		"appName", req.AppName,
 		"namespace", req.Namespace,
 	)
 
-	h.respondJSON(w, http.StatusAccepted, map[string]interface{}{
-		"requestId": requestID,
+	response := map[string]interface{}{
+		"requestId": payload.RequestID,
 		"message":   "deployment request accepted",
-	})
+	}
+	if warnings := h.unknownValuesWarnings(req); len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	h.respondJSON(w, http.StatusAccepted, response)
+}
+
+// validateCreateRequest returns why a create request is invalid, or "" if it is valid
+func (h *Handler) validateCreateRequest(req CreateRequest) string {
+	// Validate required fields
+	if req.AppName == "" {
+		return "appName is required"
+	}
+	if req.Namespace == "" {
+		return "namespace is required"
+	}
+	// The operator creates the namespace if needed, so it must be a valid name
+	if errs := validation.IsDNS1123Label(req.Namespace); len(errs) > 0 {
+		return "invalid namespace: " + strings.Join(errs, "; ")
+	}
+	if req.Priority < 0 || req.Priority > sharedrabbitmq.MaxPriority {
+		return fmt.Sprintf("priority must be between 0 and %d", sharedrabbitmq.MaxPriority)
+	}
+	if req.TTLSeconds < 0 {
+		return "ttlSeconds must not be negative"
+	}
+
+	if h.catalogService != nil {
+		// Fail fast instead of leaving the operator to reject unknown apps
+		app, err := h.catalogService.GetApp(req.AppName)
+		if err != nil {
+			return fmt.Sprintf("app %s is not in the catalog", req.AppName)
+		}
+
+		// Deprecated and removed apps accept no new deployments
+		if !app.IsActive() {
+			return fmt.Sprintf("app %s is %s and no longer accepts new deployments", app.Name, app.Lifecycle)
+		}
+
+		if missing := missingRequiredValues(app, h.withDefaultValues(req.AppName, req.Values), req.SecretKeyRefs); len(missing) > 0 {
+			return fmt.Sprintf("app %s requires values %s", app.Name, strings.Join(missing, ", "))
+		}
+
+		if h.unknownValuesMode == UnknownValuesReject {
+			if msg := h.unknownValues(req); msg != "" {
+				return msg
+			}
+		}
+	}
+
+	return validateSecretKeyRefs(req.SecretKeyRefs)
+}
+
+// validateSecretKeyRefs returns why a list of secret key references is invalid, or ""
+func validateSecretKeyRefs(refs []models.SecretKeyRef) string {
+	for i, ref := range refs {
+		if ref.Path == "" || ref.Name == "" || ref.Key == "" {
+			return fmt.Sprintf("secretKeyRefs[%d] requires path, name and key", i)
+		}
+	}
+	return ""
+}
+
+// newRequestPayload builds the deployment request message for a create request. The
+// catalog's and the app's default values are merged under the request's values.
+func (h *Handler) newRequestPayload(req CreateRequest) models.DeploymentRequestPayload {
+	return models.DeploymentRequestPayload{
+		RequestID:     uuid.New().String(),
+		TeamID:        requestTeamID,
+		UserID:        requestUserID,
+		AppName:       req.AppName,
+		Namespace:     req.Namespace,
+		ReleaseName:   req.ReleaseName,
+		Version:       req.Version,
+		Values:        h.withDefaultValues(req.AppName, req.Values),
+		SecretKeyRefs: req.SecretKeyRefs,
+		Priority:      uint8(req.Priority),
+		TTLSeconds:    req.TTLSeconds,
+	}
+}
+
+// withDefaultValues merges the default values of the app's deployments under the given
+// values. A request without values stays without values if there are no defaults.
+func (h *Handler) withDefaultValues(appName string, userValues map[string]interface{}) map[string]interface{} {
+	if h.catalogService == nil {
+		return userValues
+	}
+	defaults := h.catalogService.AppDefaultValues(appName)
+	if len(defaults) == 0 {
+		return userValues
+	}
+	return values.Merge(defaults, userValues)
+}
+
+// requestAuditRecord returns the audit record of a deployment request
+func requestAuditRecord(payload models.DeploymentRequestPayload) models.AuditRecord {
+	return models.AuditRecord{
+		Action:     audit.ActionCreate,
+		TeamID:     payload.TeamID,
+		UserID:     payload.UserID,
+		Deployment: payload.ReleaseName,
+		Namespace:  payload.Namespace,
+		AppName:    payload.AppName,
+		RequestID:  payload.RequestID,
+		BatchID:    payload.BatchID,
+	}
+}
+
+// auditLog records the outcome of a mutating request in the audit trail
+func (h *Handler) auditLog(ctx context.Context, record models.AuditRecord, outcome, reason string) {
+	record.Outcome = outcome
+	record.Reason = reason
+	h.auditLogger.Log(ctx, record)
 }
 
 // List handles GET /api/v1/deployments
+//
+// Without ?namespace= deployments of all namespaces are listed. ?app= and ?team= only list
+// deployments of the given app and team, selected by label. ?phase= (repeatable) only lists
+// deployments in the given phases and ?sort= orders them by name, createdAt or
+// lastReconcileTime (the latter two newest first).
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	if h.k8sClient == nil {
 		h.respondError(w, http.StatusServiceUnavailable, "Kubernetes not available")
@@ -110,7 +333,13 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 
 	namespace := r.URL.Query().Get("namespace")
 
-	deployments, err := h.k8sClient.ListAppDeployments(r.Context(), namespace)
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	deployments, err := h.k8sClient.ListAppDeploymentsWithLabels(r.Context(), namespace, opts.labels)
 	if err != nil {
 		h.logger.Error("failed to list deployments", "error", err)
 		h.respondError(w, http.StatusInternalServerError, "failed to list deployments")
@@ -118,7 +347,7 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"deployments": deployments,
+		"deployments": opts.apply(deployments),
 	})
 }
 
@@ -135,11 +364,7 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Default to "default" namespace, can be overridden with query param
-	namespace := r.URL.Query().Get("namespace")
-	if namespace == "" {
-		namespace = "default"
-	}
+	namespace := h.requestNamespace(r)
 
 	deployment, err := h.k8sClient.GetAppDeployment(r.Context(), namespace, name)
 	if err != nil {
@@ -148,13 +373,14 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, deployment)
 	h.respondJSON(w, http.StatusOK, deployment)
 }
 
-// Update handles PUT /api/v1/deployments/{name}
-func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
-	if h.k8sClient == nil || h.publisher == nil {
-		h.respondError(w, http.StatusServiceUnavailable, "Kubernetes or RabbitMQ not available")
+// Events handles GET /api/v1/deployments/{name}/events
+func (h *Handler) Events(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		h.respondError(w, http.StatusServiceUnavailable, "Kubernetes not available")
 		return
 	}
 
@@ -164,47 +390,115 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req UpdateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid request body")
+	namespace := h.requestNamespace(r)
+
+	// Optional event type filter (Normal or Warning)
+	eventType := r.URL.Query().Get("type")
+	if eventType != "" && eventType != "Normal" && eventType != "Warning" {
+		h.respondError(w, http.StatusBadRequest, "type must be Normal or Warning")
 		return
 	}
 
-	// Default to "default" namespace, can be overridden with query param
-	namespace := r.URL.Query().Get("namespace")
-	if namespace == "" {
-		namespace = "default"
+	events, err := h.k8sClient.ListDeploymentEvents(r.Context(), namespace, name, eventType)
+	if k8s.IsNotFound(err) {
+		h.respondError(w, http.StatusNotFound, "deployment not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to list deployment events", "error", err, "name", name, "namespace", namespace)
+		h.respondError(w, http.StatusInternalServerError, "failed to list deployment events")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"events": events,
+	})
+}
+
+// Update handles PUT /api/v1/deployments/{name}
+//
+// An If-Match header with the deployment's generation (as returned in the ETag of GET)
+// makes the update fail with 409 if the deployment's spec has changed in the meantime.
+// Changes after the update is accepted are detected by the operator, which rejects the
+// update with an UpdateConflict Event.
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" {
+		h.respondError(w, http.StatusBadRequest, "deployment name is required")
+		return
+	}
+
+	namespace := h.requestNamespace(r)
+
+	// TODO: Get team ID and user ID from auth context
+	userID := "anonymous"
+
+	requestID := uuid.New().String()
+	record := models.AuditRecord{
+		Action:     audit.ActionUpdate,
+		UserID:     userID,
+		Deployment: name,
+		Namespace:  namespace,
+		RequestID:  requestID,
+	}
+
+	if h.k8sClient == nil || h.publisher == nil {
+		h.auditLog(r.Context(), record, audit.OutcomeFailed, "Kubernetes or RabbitMQ not available")
+		h.respondError(w, http.StatusServiceUnavailable, "Kubernetes or RabbitMQ not available")
+		return
+	}
+
+	var req UpdateRequest
+	if err := decodeBody(r, &req); err != nil {
+		status, message := bodyError(err, "invalid request body")
+		h.auditLog(r.Context(), record, audit.OutcomeRejected, message)
+		h.respondError(w, status, message)
+		return
 	}
 
 	// Verify deployment exists and get its details
 	deployment, err := h.k8sClient.GetAppDeployment(r.Context(), namespace, name)
 	if err != nil {
+		h.auditLog(r.Context(), record, audit.OutcomeRejected, "deployment not found")
 		h.respondError(w, http.StatusNotFound, "deployment not found")
 		return
 	}
 
-	// TODO: Get team ID and user ID from auth context
 	teamID := deployment.TeamID
-	userID := "anonymous"
+	record.TeamID = teamID
+	record.AppName = deployment.AppName
 
-	requestID := uuid.New().String()
+	generation, ok := ifMatchGeneration(r.Header.Get("If-Match"), deployment.Generation())
+	if !ok {
+		h.auditLog(r.Context(), record, audit.OutcomeRejected, "deployment has been modified")
+		h.respondError(w, http.StatusConflict, "deployment has been modified; fetch it again and retry")
+		return
+	}
 
 	payload := models.DeploymentUpdatePayload{
-		RequestID: requestID,
-		TeamID:    teamID,
-		UserID:    userID,
-		Name:      name,
-		Namespace: namespace,
-		Version:   req.Version,
-		Values:    req.Values,
+		RequestID:  requestID,
+		TeamID:     teamID,
+		UserID:     userID,
+		Name:       name,
+		Namespace:  namespace,
+		Version:    req.Version,
+		Values:     req.Values,
+		Generation: generation,
+	}
+	if req.Values != nil {
+		// New values replace the old ones, so the defaults have to be sent again
+		payload.Values = h.withDefaultValues(deployment.AppName, req.Values)
 	}
 
 	if err := h.publisher.PublishDeploymentUpdate(r.Context(), payload); err != nil {
 		h.logger.Error("failed to publish deployment update", "error", err)
-		h.respondError(w, http.StatusInternalServerError, "failed to update deployment")
+		h.auditLog(r.Context(), record, audit.OutcomeFailed, "failed to publish deployment update")
+		h.respondError(w, publishErrorStatus(err), "failed to update deployment")
 		return
 	}
 
+	h.auditLog(r.Context(), record, audit.OutcomeAccepted, "")
+
 	h.logger.Info("deployment update published",
 		"requestId", requestID,
 		"name", name,
@@ -218,35 +512,43 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 
 // Delete handles DELETE /api/v1/deployments/{name}
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
-	if h.k8sClient == nil || h.publisher == nil {
-		h.respondError(w, http.StatusServiceUnavailable, "Kubernetes or RabbitMQ not available")
-		return
-	}
-
 	name := r.PathValue("name")
 	if name == "" {
 		h.respondError(w, http.StatusBadRequest, "deployment name is required")
 		return
 	}
 
-	// Default to "default" namespace, can be overridden with query param
-	namespace := r.URL.Query().Get("namespace")
-	if namespace == "" {
-		namespace = "default"
+	namespace := h.requestNamespace(r)
+
+	// TODO: Get team ID and user ID from auth context
+	userID := "anonymous"
+
+	requestID := uuid.New().String()
+	record := models.AuditRecord{
+		Action:     audit.ActionDelete,
+		UserID:     userID,
+		Deployment: name,
+		Namespace:  namespace,
+		RequestID:  requestID,
+	}
+
+	if h.k8sClient == nil || h.publisher == nil {
+		h.auditLog(r.Context(), record, audit.OutcomeFailed, "Kubernetes or RabbitMQ not available")
+		h.respondError(w, http.StatusServiceUnavailable, "Kubernetes or RabbitMQ not available")
+		return
 	}
 
 	// Verify deployment exists and get its details
 	deployment, err := h.k8sClient.GetAppDeployment(r.Context(), namespace, name)
 	if err != nil {
+		h.auditLog(r.Context(), record, audit.OutcomeRejected, "deployment not found")
 		h.respondError(w, http.StatusNotFound, "deployment not found")
 		return
 	}
 
-	// TODO: Get team ID and user ID from auth context
 	teamID := deployment.TeamID
-	userID := "anonymous"
-
-	requestID := uuid.New().String()
+	record.TeamID = teamID
+	record.AppName = deployment.AppName
 
 	payload := models.DeploymentDeletePayload{
 		RequestID: requestID,
@@ -258,10 +560,13 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	if err := h.publisher.PublishDeploymentDelete(r.Context(), payload); err != nil {
 		h.logger.Error("failed to publish deployment delete", "error", err)
-		h.respondError(w, http.StatusInternalServerError, "failed to delete deployment")
+		h.auditLog(r.Context(), record, audit.OutcomeFailed, "failed to publish deployment delete")
+		h.respondError(w, publishErrorStatus(err), "failed to delete deployment")
 		return
 	}
 
+	h.auditLog(r.Context(), record, audit.OutcomeAccepted, "")
+
 	h.logger.Info("deployment delete published",
 		"requestId", requestID,
 		"name", name,
@@ -273,6 +578,68 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// publishErrorStatus returns the status for a failed publish: 503 if it wasn't attempted
+// because RabbitMQ is known to be down, so that clients retry later
+func publishErrorStatus(err error) int {
+	if errors.Is(err, rabbitmq.ErrCircuitOpen) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// unknownFieldPrefix starts the error of a decoder rejecting an unknown field
+const unknownFieldPrefix = "json: unknown field "
+
+// decodeBody decodes a JSON request body into v, rejecting fields v doesn't have so that
+// misspelled fields aren't silently ignored
+func decodeBody(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// bodyError returns the status and message for a request body that couldn't be read or
+// decoded: 413 if it is larger than the router allows, otherwise 400 with message, naming
+// the field if the body has an unknown one
+func bodyError(err error, message string) (int, string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit)
+	}
+	if field, ok := strings.CutPrefix(err.Error(), unknownFieldPrefix); ok {
+		return http.StatusBadRequest, fmt.Sprintf("%s: unknown field %s", message, field)
+	}
+	return http.StatusBadRequest, message
+}
+
+// setETag sets a deployment's generation as the ETag of the response. It can be sent back
+// in If-Match to update safely; unlike the resourceVersion, it doesn't change when the
+// operator updates the status.
+func setETag(w http.ResponseWriter, deployment *k8s.AppDeployment) {
+	if generation := deployment.Generation(); generation != 0 {
+		w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(generation, 10)))
+	}
+}
+
+// ifMatchGeneration checks an If-Match header against a deployment's generation. It
+// returns the generation to send with the update, zero if the header is missing or "*",
+// and false if the header doesn't match. Both quoted ETags and bare generations are
+// accepted.
+func ifMatchGeneration(header string, generation int64) (int64, bool) {
+	value := strings.TrimSpace(header)
+	if value == "" || value == "*" {
+		return 0, true
+	}
+	value = strings.TrimPrefix(value, "W/")
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	if value != strconv.FormatInt(generation, 10) {
+		return 0, false
+	}
+	return generation, true
+}
+
 func (h *Handler) respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -0,0 +1,371 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"appstore/backend/internal/catalog"
+	"appstore/backend/internal/k8s"
+	"appstore/backend/internal/rabbitmq"
+	"appstore/backend/pkg/models"
+)
+
+func newTestCatalog(t *testing.T) *catalog.Service {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+	content := "apps:\n" +
+		"  - name: postgresql\n" +
+		"  - name: mysql\n    lifecycle: deprecated\n" +
+		"  - name: memcached\n    lifecycle: removed\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := catalog.NewService(path, "")
+	if err := s.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return s
+}
+
+func create(h *Handler, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.Create(rec, httptest.NewRequest(http.MethodPost, "/api/v1/deployments", strings.NewReader(body)))
+	return rec
+}
+
+func TestCreateRejectsInactiveApps(t *testing.T) {
+	h := NewHandler(nil, nil, newTestCatalog(t), nil, nil, "", "")
+
+	for _, app := range []string{"mysql", "memcached"} {
+		rec := create(h, `{"appName":"`+app+`","namespace":"team-a"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Create(%s) status = %d, want %d", app, rec.Code, http.StatusBadRequest)
+		}
+		if !strings.Contains(rec.Body.String(), "no longer accepts new deployments") {
+			t.Errorf("Create(%s) body = %s", app, rec.Body)
+		}
+	}
+}
+
+func TestCreateAllowsActiveApps(t *testing.T) {
+	h := NewHandler(nil, nil, newTestCatalog(t), nil, nil, "", "")
+
+	// Passes validation and fails only because there is no publisher
+	rec := create(h, `{"appName":"postgresql","namespace":"team-a"}`)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Create(postgresql) status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestCreateValidatesAppName(t *testing.T) {
+	tests := []struct {
+		app        string
+		wantStatus int
+	}{
+		{"postgresql", http.StatusAccepted},
+		{"oracle", http.StatusBadRequest},
+		{"PostgreSQL", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.app, func(t *testing.T) {
+			publisher := &fakePublisher{}
+			h := NewHandler(publisher, nil, newTestCatalog(t), nil, nil, "", "")
+
+			rec := create(h, `{"appName":"`+tt.app+`","namespace":"team-a"}`)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Create(%s) status = %d, want %d (body %s)", tt.app, rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusBadRequest {
+				return
+			}
+			if !strings.Contains(rec.Body.String(), "is not in the catalog") {
+				t.Errorf("Create(%s) body = %s", tt.app, rec.Body)
+			}
+			if len(publisher.requests) != 0 {
+				t.Errorf("Create(%s) published %d requests, want 0", tt.app, len(publisher.requests))
+			}
+		})
+	}
+}
+
+func TestCreateValidatesNamespace(t *testing.T) {
+	publisher := &fakePublisher{}
+	h := NewHandler(publisher, nil, newTestCatalog(t), nil, nil, "", "")
+
+	rec := create(h, `{"appName":"postgresql","namespace":"Team_A"}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid namespace") {
+		t.Errorf("Create(Team_A) = %d %s, want %d invalid namespace", rec.Code, rec.Body, http.StatusBadRequest)
+	}
+	if len(publisher.requests) != 0 {
+		t.Errorf("Create(Team_A) published %d requests, want 0", len(publisher.requests))
+	}
+}
+
+// newGetErrorHandler returns a handler whose gets of AppDeployments fail with err
+func newGetErrorHandler(err error) *Handler {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{k8s.AppDeploymentGVR: "AppDeploymentList"},
+	)
+	dynamicClient.PrependReactor("get", "appdeployments", func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, err
+	})
+	return NewHandler(nil, k8s.NewClientFromInterfaces(dynamicClient, fake.NewClientset()), nil, nil, nil, "", "")
+}
+
+func TestEventsErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"not found", apierrors.NewNotFound(k8s.AppDeploymentGVR.GroupResource(), "db"), http.StatusNotFound},
+		{"forbidden", apierrors.NewForbidden(k8s.AppDeploymentGVR.GroupResource(), "db", errors.New("denied")), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /api/v1/deployments/{name}/events", newGetErrorHandler(tt.err).Events)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/deployments/db/events?namespace=team-a", nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+// fakePublisher records published payloads. Deployment requests fail with err if set.
+type fakePublisher struct {
+	requests []models.DeploymentRequestPayload
+	updates  []models.DeploymentUpdatePayload
+	deletes  []models.DeploymentDeletePayload
+	cancels  []models.DeploymentCancelPayload
+	err      error
+}
+
+func (p *fakePublisher) PublishDeploymentRequest(_ context.Context, payload models.DeploymentRequestPayload) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.requests = append(p.requests, payload)
+	return nil
+}
+
+func (p *fakePublisher) PublishDeploymentUpdate(_ context.Context, payload models.DeploymentUpdatePayload) error {
+	p.updates = append(p.updates, payload)
+	return nil
+}
+
+func (p *fakePublisher) PublishDeploymentDelete(_ context.Context, payload models.DeploymentDeletePayload) error {
+	p.deletes = append(p.deletes, payload)
+	return nil
+}
+
+func (p *fakePublisher) PublishDeploymentCancel(_ context.Context, payload models.DeploymentCancelPayload) error {
+	p.cancels = append(p.cancels, payload)
+	return nil
+}
+
+func TestCreatePublishErrors(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+	}{
+		{errors.New("channel closed"), http.StatusInternalServerError},
+		{rabbitmq.ErrCircuitOpen, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			h := NewHandler(&fakePublisher{err: tt.err}, nil, newTestCatalog(t), nil, nil, "", "")
+			if rec := create(h, `{"appName":"postgresql","namespace":"team-a"}`); rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCreatePriority(t *testing.T) {
+	publisher := &fakePublisher{}
+	h := NewHandler(publisher, nil, newTestCatalog(t), nil, nil, "", "")
+
+	if rec := create(h, `{"appName":"postgresql","namespace":"team-a","priority":9}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("Create(priority 9) status = %d, body %s", rec.Code, rec.Body)
+	}
+	if got := publisher.requests[0].Priority; got != 9 {
+		t.Errorf("published priority = %d, want 9", got)
+	}
+
+	for _, priority := range []string{"-1", "10", "256"} {
+		rec := create(h, `{"appName":"postgresql","namespace":"team-a","priority":`+priority+`}`)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "priority must be between 0 and 9") {
+			t.Errorf("Create(priority %s) status = %d, body %s", priority, rec.Code, rec.Body)
+		}
+	}
+	if len(publisher.requests) != 1 {
+		t.Errorf("published %d requests, want 1", len(publisher.requests))
+	}
+}
+
+func TestCreateTTL(t *testing.T) {
+	publisher := &fakePublisher{}
+	h := NewHandler(publisher, nil, newTestCatalog(t), nil, nil, "", "")
+
+	if rec := create(h, `{"appName":"postgresql","namespace":"team-a","ttlSeconds":600}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("Create(ttlSeconds 600) status = %d, body %s", rec.Code, rec.Body)
+	}
+	if got := publisher.requests[0].TTLSeconds; got != 600 {
+		t.Errorf("published ttlSeconds = %d, want 600", got)
+	}
+
+	rec := create(h, `{"appName":"postgresql","namespace":"team-a","ttlSeconds":-1}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "ttlSeconds must not be negative") {
+		t.Errorf("Create(ttlSeconds -1) status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func newTestK8sClient(objects ...runtime.Object) *k8s.Client {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{k8s.AppDeploymentGVR: "AppDeploymentList"},
+		objects...,
+	)
+	return k8s.NewClientFromInterfaces(dynamicClient, fake.NewClientset())
+}
+
+func newAppDeployment(namespace, name string, generation int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "appstore.bitpipe.no/v1alpha1",
+		"kind":       "AppDeployment",
+		"metadata": map[string]interface{}{
+			"name":       name,
+			"namespace":  namespace,
+			"generation": generation,
+		},
+		"spec": map[string]interface{}{
+			"appName": "postgresql",
+			"teamId":  "team-a",
+		},
+	}}
+}
+
+func update(h *Handler, name, ifMatch, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /api/v1/deployments/{name}", h.Update)
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/deployments/"+name+"?namespace=team-a", strings.NewReader(body))
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUpdateIfMatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		ifMatch        string
+		wantStatus     int
+		wantGeneration int64
+	}{
+		{"no precondition", "", http.StatusAccepted, 0},
+		{"matching etag", `"42"`, http.StatusAccepted, 42},
+		{"matching bare generation", "42", http.StatusAccepted, 42},
+		{"wildcard", "*", http.StatusAccepted, 0},
+		{"stale generation", `"41"`, http.StatusConflict, 0},
+		{"not a generation", `"abc"`, http.StatusConflict, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakePublisher{}
+			h := NewHandler(publisher, newTestK8sClient(newAppDeployment("team-a", "db", 42)), nil, nil, nil, "", "")
+
+			rec := update(h, "db", tt.ifMatch, `{"version":"2.0.0"}`)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			if tt.wantStatus == http.StatusConflict {
+				if len(publisher.updates) != 0 {
+					t.Errorf("published %d updates on conflict, want 0", len(publisher.updates))
+				}
+				return
+			}
+			if len(publisher.updates) != 1 {
+				t.Fatalf("published %d updates, want 1", len(publisher.updates))
+			}
+			if got := publisher.updates[0].Generation; got != tt.wantGeneration {
+				t.Errorf("payload generation = %d, want %d", got, tt.wantGeneration)
+			}
+		})
+	}
+}
+
+func TestRejectsUnknownFields(t *testing.T) {
+	publisher := &fakePublisher{}
+	h := NewHandler(publisher, newTestK8sClient(newAppDeployment("team-a", "db", 42)), newTestCatalog(t), nil, nil, "", "")
+
+	tests := []struct {
+		name string
+		rec  *httptest.ResponseRecorder
+	}{
+		{"create", create(h, `{"appName":"postgresql","namespace":"team-a","relaseName":"db"}`)},
+		{"batch", createBatch(h, `[{"appName":"postgresql","namespace":"team-a","relaseName":"db"}]`)},
+		{"preview", preview(h, `{"appName":"postgresql","relaseName":"db"}`)},
+		{"clone", clone(h, "db:clone?namespace=team-a", `{"relaseName":"db"}`)},
+		{"update", update(h, "db", "", `{"version":"2.0.0","relaseName":"db"}`)},
+		{"nested", create(h, `{"appName":"postgresql","secretKeyRefs":[{"path":"auth.password","secret":"pg"}]}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", tt.rec.Code, http.StatusBadRequest)
+			}
+			if !strings.Contains(tt.rec.Body.String(), "unknown field") {
+				t.Errorf("body = %s, want the unknown field named", tt.rec.Body)
+			}
+		})
+	}
+	if !strings.Contains(tests[0].rec.Body.String(), `unknown field \"relaseName\"`) {
+		t.Errorf("create body = %s, want relaseName named", tests[0].rec.Body)
+	}
+	if len(publisher.requests) != 0 || len(publisher.updates) != 0 {
+		t.Errorf("published %d requests and %d updates, want none", len(publisher.requests), len(publisher.updates))
+	}
+
+	// Values are free-form and may have any keys
+	rec := create(h, `{"appName":"postgresql","namespace":"team-a","values":{"anyKey":1}}`)
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("Create with values status = %d, want %d (body %s)", rec.Code, http.StatusAccepted, rec.Body)
+	}
+}
+
+func TestGetSetsETag(t *testing.T) {
+	h := NewHandler(nil, newTestK8sClient(newAppDeployment("default", "db", 42)), nil, nil, nil, "", "")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/deployments/{name}", h.Get)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/deployments/db", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("ETag"); got != `"42"` {
+		t.Errorf("ETag = %q, want %q", got, `"42"`)
+	}
+}
@@ -0,0 +1,67 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TeamLimits caps the number of AppDeployments a team may have
+type TeamLimits struct {
+	// Default is the limit of teams without an override (unlimited if 0)
+	Default int
+	// ConfigMapNamespace and ConfigMapName locate an optional ConfigMap mapping team IDs to
+	// their limit, overriding Default. A limit of 0 makes a team unlimited.
+	ConfigMapNamespace string
+	ConfigMapName      string
+}
+
+// teamLimit returns the deployment limit of a team, 0 meaning unlimited
+func (h *Handler) teamLimit(ctx context.Context, teamID string) (int, error) {
+	limits := h.teamLimits
+	if limits.ConfigMapName == "" {
+		return limits.Default, nil
+	}
+
+	// Read on every request so that overrides apply without a restart
+	data, err := h.k8sClient.GetConfigMapData(ctx, limits.ConfigMapNamespace, limits.ConfigMapName)
+	if err != nil {
+		return 0, err
+	}
+	value, ok := data[teamID]
+	if !ok {
+		return limits.Default, nil
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid deployment limit %q for team %s in ConfigMap %s/%s",
+			value, teamID, limits.ConfigMapNamespace, limits.ConfigMapName)
+	}
+	return limit, nil
+}
+
+// remainingDeployments returns the limit of a team and how many more deployments it may
+// create. The limit is 0 if the team is unlimited. Deployments that are requested but not
+// yet created by the operator are not counted.
+func (h *Handler) remainingDeployments(ctx context.Context, teamID string) (remaining, limit int, err error) {
+	if h.teamLimits == nil || h.k8sClient == nil {
+		return 0, 0, nil
+	}
+
+	limit, err = h.teamLimit(ctx, teamID)
+	if err != nil || limit == 0 {
+		return 0, 0, err
+	}
+
+	count, err := h.k8sClient.CountTeamAppDeployments(ctx, teamID)
+	if err != nil {
+		return 0, 0, err
+	}
+	return max(limit-count, 0), limit, nil
+}
+
+// teamLimitMessage explains why a create was rejected by the team's limit
+func teamLimitMessage(teamID string, limit int) string {
+	return fmt.Sprintf("team %s has reached its limit of %d deployments", teamID, limit)
+}
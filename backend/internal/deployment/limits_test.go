@@ -0,0 +1,164 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"appstore/backend/internal/k8s"
+	"appstore/backend/pkg/models"
+)
+
+// operatorPublisher creates the AppDeployment of each published request, like the operator
+type operatorPublisher struct {
+	fakePublisher
+	dynamicClient dynamic.Interface
+}
+
+func (p *operatorPublisher) PublishDeploymentRequest(ctx context.Context, payload models.DeploymentRequestPayload) error {
+	obj := newAppDeployment(payload.Namespace, payload.AppName+"-"+payload.RequestID[:8], 1)
+	obj.SetLabels(map[string]string{k8s.TeamLabel: payload.TeamID})
+	if _, err := p.dynamicClient.Resource(k8s.AppDeploymentGVR).Namespace(payload.Namespace).
+		Create(ctx, obj, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+	return p.fakePublisher.PublishDeploymentRequest(ctx, payload)
+}
+
+func newLimitedHandler(limits *TeamLimits, coreObjects ...runtime.Object) (*Handler, *operatorPublisher) {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{k8s.AppDeploymentGVR: "AppDeploymentList"},
+	)
+	k8sClient := k8s.NewClientFromInterfaces(dynamicClient, fake.NewClientset(coreObjects...))
+	publisher := &operatorPublisher{dynamicClient: dynamicClient}
+	return NewHandler(publisher, k8sClient, nil, nil, limits, "", ""), publisher
+}
+
+func TestCreateEnforcesTeamLimit(t *testing.T) {
+	h, publisher := newLimitedHandler(&TeamLimits{Default: 2})
+
+	for i := range 2 {
+		if rec := create(h, `{"appName":"postgresql","namespace":"team-a"}`); rec.Code != http.StatusAccepted {
+			t.Fatalf("Create() %d status = %d, want %d (body %s)", i, rec.Code, http.StatusAccepted, rec.Body)
+		}
+	}
+
+	rec := create(h, `{"appName":"postgresql","namespace":"team-b"}`)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Create() beyond limit status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if !strings.Contains(rec.Body.String(), "team default-team has reached its limit of 2 deployments") {
+		t.Errorf("Create() beyond limit body = %s", rec.Body)
+	}
+	if len(publisher.requests) != 2 {
+		t.Errorf("published %d requests, want 2", len(publisher.requests))
+	}
+}
+
+func TestCreateTeamLimitOverrides(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       map[string]string
+		wantStatus []int
+	}{
+		{"no override", map[string]string{"other-team": "5"}, []int{http.StatusAccepted, http.StatusForbidden}},
+		{"higher limit", map[string]string{"default-team": "2"}, []int{http.StatusAccepted, http.StatusAccepted, http.StatusForbidden}},
+		{"unlimited", map[string]string{"default-team": "0"}, []int{http.StatusAccepted, http.StatusAccepted, http.StatusAccepted}},
+		{"invalid limit", map[string]string{"default-team": "many"}, []int{http.StatusInternalServerError}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "team-limits", Namespace: "appstore"},
+				Data:       tt.data,
+			}
+			h, _ := newLimitedHandler(&TeamLimits{
+				Default:            1,
+				ConfigMapNamespace: "appstore",
+				ConfigMapName:      "team-limits",
+			}, configMap)
+
+			for i, want := range tt.wantStatus {
+				if rec := create(h, `{"appName":"postgresql","namespace":"team-a"}`); rec.Code != want {
+					t.Fatalf("Create() %d status = %d, want %d (body %s)", i, rec.Code, want, rec.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateTeamLimitWithoutConfigMap(t *testing.T) {
+	h, _ := newLimitedHandler(&TeamLimits{Default: 1, ConfigMapNamespace: "appstore", ConfigMapName: "missing"})
+
+	if rec := create(h, `{"appName":"postgresql","namespace":"team-a"}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("Create() status = %d, want %d (body %s)", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	if rec := create(h, `{"appName":"postgresql","namespace":"team-a"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Create() beyond limit status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestCreateTeamLimitIgnoresDeletedDeployments(t *testing.T) {
+	h, publisher := newLimitedHandler(&TeamLimits{Default: 1})
+
+	deleting := newAppDeployment("team-a", "old", 1)
+	deleting.SetLabels(map[string]string{k8s.TeamLabel: "default-team"})
+	now := metav1.Now()
+	deleting.SetDeletionTimestamp(&now)
+	if _, err := publisher.dynamicClient.Resource(k8s.AppDeploymentGVR).Namespace("team-a").
+		Create(context.Background(), deleting, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := create(h, `{"appName":"postgresql","namespace":"team-a"}`); rec.Code != http.StatusAccepted {
+		t.Errorf("Create() status = %d, want %d (body %s)", rec.Code, http.StatusAccepted, rec.Body)
+	}
+}
+
+func TestCreateBatchEnforcesTeamLimit(t *testing.T) {
+	h, publisher := newLimitedHandler(&TeamLimits{Default: 3})
+	existing := newAppDeployment("team-a", "db", 1)
+	existing.SetLabels(map[string]string{k8s.TeamLabel: "default-team"})
+	if _, err := publisher.dynamicClient.Resource(k8s.AppDeploymentGVR).Namespace("team-a").
+		Create(context.Background(), existing, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := createBatch(h, `[
+		{"appName":"postgresql","namespace":"team-a"},
+		{"appName":"postgresql"},
+		{"appName":"postgresql","namespace":"team-b"},
+		{"appName":"postgresql","namespace":"team-c"}
+	]`)
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusMultiStatus, rec.Body)
+	}
+
+	var resp BatchCreateResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	wantStatus := []int{http.StatusAccepted, http.StatusBadRequest, http.StatusAccepted, http.StatusForbidden}
+	for i, want := range wantStatus {
+		if got := resp.Results[i].Status; got != want {
+			t.Errorf("result %d status = %d, want %d", i, got, want)
+		}
+	}
+	if !strings.Contains(resp.Results[3].Error, "limit of 3 deployments") {
+		t.Errorf("result 3 error = %q", resp.Results[3].Error)
+	}
+	if len(publisher.requests) != 2 {
+		t.Errorf("published %d requests, want 2", len(publisher.requests))
+	}
+}
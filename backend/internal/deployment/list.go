@@ -0,0 +1,114 @@
+package deployment
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"appstore/backend/internal/k8s"
+)
+
+// phases are the phases an AppDeployment can be in, as set by the operator
+var phases = []string{"Pending", "Installing", "Upgrading", "Deployed", "Failed", "Uninstalling"}
+
+// deploymentSorts orders deployments by the value of the ?sort= parameter
+var deploymentSorts = map[string]func(a, b *k8s.AppDeployment) bool{
+	"name": func(a, b *k8s.AppDeployment) bool {
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Namespace < b.Namespace
+	},
+	// Newest first
+	"createdAt": func(a, b *k8s.AppDeployment) bool {
+		return a.CreatedAt.After(b.CreatedAt)
+	},
+	// Most recently reconciled first, never reconciled last
+	"lastReconcileTime": func(a, b *k8s.AppDeployment) bool {
+		if a.LastReconcileTime == nil || b.LastReconcileTime == nil {
+			return a.LastReconcileTime != nil && b.LastReconcileTime == nil
+		}
+		return a.LastReconcileTime.After(*b.LastReconcileTime)
+	},
+}
+
+// listOptions are the filter and sort parameters of the list endpoint
+type listOptions struct {
+	// labels select deployments by app and team when listing them
+	labels labels.Set
+	phases []string
+	sort   string
+}
+
+// listLabels maps the parameters selecting deployments by label to their labels
+var listLabels = []struct{ param, label string }{
+	{"app", k8s.AppLabel},
+	{"team", k8s.TeamLabel},
+}
+
+// parseListLabels parses the ?app= and ?team= parameters into the labels selecting
+// deployments
+func parseListLabels(query url.Values) (labels.Set, error) {
+	set := labels.Set{}
+	for _, l := range listLabels {
+		value := query.Get(l.param)
+		if value == "" {
+			continue
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid %s %q: %s", l.param, value, strings.Join(errs, "; "))
+		}
+		set[l.label] = value
+	}
+	return set, nil
+}
+
+// parseListOptions parses the ?app=, ?team=, ?phase= (repeatable) and ?sort= parameters
+func parseListOptions(query url.Values) (listOptions, error) {
+	opts := listOptions{sort: query.Get("sort")}
+
+	var err error
+	if opts.labels, err = parseListLabels(query); err != nil {
+		return opts, err
+	}
+
+	for _, phase := range query["phase"] {
+		if !slices.Contains(phases, phase) {
+			return opts, fmt.Errorf("unknown phase %q, must be one of %s", phase, strings.Join(phases, ", "))
+		}
+		opts.phases = append(opts.phases, phase)
+	}
+
+	if _, ok := deploymentSorts[opts.sort]; opts.sort != "" && !ok {
+		sorts := make([]string, 0, len(deploymentSorts))
+		for name := range deploymentSorts {
+			sorts = append(sorts, name)
+		}
+		sort.Strings(sorts)
+		return opts, fmt.Errorf("unknown sort %q, must be one of %s", opts.sort, strings.Join(sorts, ", "))
+	}
+
+	return opts, nil
+}
+
+// apply filters and sorts deployments
+func (opts listOptions) apply(deployments []k8s.AppDeployment) []k8s.AppDeployment {
+	listed := []k8s.AppDeployment{}
+	for _, deployment := range deployments {
+		if len(opts.phases) == 0 || slices.Contains(opts.phases, deployment.Phase) {
+			listed = append(listed, deployment)
+		}
+	}
+
+	if less, ok := deploymentSorts[opts.sort]; ok {
+		sort.SliceStable(listed, func(i, j int) bool {
+			return less(&listed[i], &listed[j])
+		})
+	}
+	return listed
+}
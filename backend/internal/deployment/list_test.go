@@ -0,0 +1,148 @@
+package deployment
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"appstore/backend/internal/k8s"
+)
+
+func newPhasedDeployment(name, phase, lastReconcileTime string) *unstructured.Unstructured {
+	obj := newAppDeployment("team-a", name, 1)
+	status := map[string]interface{}{"phase": phase}
+	if lastReconcileTime != "" {
+		status["lastReconcileTime"] = lastReconcileTime
+	}
+	obj.Object["status"] = status
+	return obj
+}
+
+func listNames(t *testing.T, h *Handler, query string) (int, []string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.List(rec, httptest.NewRequest(http.MethodGet, "/api/v1/deployments"+query, nil))
+	if rec.Code != http.StatusOK {
+		return rec.Code, nil
+	}
+
+	var body struct {
+		Deployments []struct {
+			Name string `json:"name"`
+		} `json:"deployments"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, d := range body.Deployments {
+		names = append(names, d.Name)
+	}
+	return rec.Code, names
+}
+
+func TestListFilterAndSort(t *testing.T) {
+	h := NewHandler(nil, newTestK8sClient([]runtime.Object{
+		newPhasedDeployment("cache", "Deployed", "2026-03-04T10:00:00Z"),
+		newPhasedDeployment("analytics", "Failed", "2026-03-04T12:00:00Z"),
+		newPhasedDeployment("db", "Deployed", "2026-03-04T11:00:00Z"),
+		newPhasedDeployment("queue", "Pending", ""),
+		newPhasedDeployment("broken", "Failed", "2026-03-04T09:00:00Z"),
+	}...), nil, nil, nil, "", "")
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"?sort=name", []string{"analytics", "broken", "cache", "db", "queue"}},
+		{"?phase=Failed&sort=name", []string{"analytics", "broken"}},
+		{"?phase=Failed&phase=Pending&sort=name", []string{"analytics", "broken", "queue"}},
+		{"?phase=Uninstalling", []string{}},
+		{"?sort=lastReconcileTime", []string{"analytics", "db", "cache", "broken", "queue"}},
+		{"?phase=Deployed&sort=lastReconcileTime", []string{"db", "cache"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			code, names := listNames(t, h, tt.query)
+			if code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", code, http.StatusOK)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("deployments = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestListRejectsUnknownParameters(t *testing.T) {
+	h := NewHandler(nil, newTestK8sClient(), nil, nil, nil, "", "")
+
+	for _, query := range []string{"?phase=Broken", "?phase=failed", "?phase=Deployed&phase=", "?sort=age", "?app=post%20gres", "?team=team/a"} {
+		if code, _ := listNames(t, h, query); code != http.StatusBadRequest {
+			t.Errorf("List(%s) status = %d, want %d", query, code, http.StatusBadRequest)
+		}
+	}
+}
+
+func newLabeledDeployment(namespace, name, app, team string) *unstructured.Unstructured {
+	obj := newAppDeployment(namespace, name, 1)
+	obj.SetLabels(map[string]string{k8s.AppLabel: app, k8s.TeamLabel: team})
+	return obj
+}
+
+func TestListByAppAndTeam(t *testing.T) {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{k8s.AppDeploymentGVR: "AppDeploymentList"},
+		newLabeledDeployment("team-a", "db", "postgresql", "team-a"),
+		newLabeledDeployment("team-a", "cache", "redis", "team-a"),
+		newLabeledDeployment("team-b", "db", "postgresql", "team-b"),
+		newLabeledDeployment("team-b", "orders", "postgresql", "team-b"),
+	)
+	h := NewHandler(nil, k8s.NewClientFromInterfaces(dynamicClient, fake.NewClientset()), nil, nil, nil, "", "")
+
+	tests := []struct {
+		query    string
+		selector string
+		want     []string
+	}{
+		{"?sort=name", "", []string{"cache", "db", "db", "orders"}},
+		{"?app=postgresql&sort=name", "appstore.bitpipe.no/app=postgresql", []string{"db", "db", "orders"}},
+		{"?team=team-b&sort=name", "appstore.bitpipe.no/team=team-b", []string{"db", "orders"}},
+		{"?app=postgresql&team=team-a", "appstore.bitpipe.no/app=postgresql,appstore.bitpipe.no/team=team-a", []string{"db"}},
+		{"?app=redis&namespace=team-b", "appstore.bitpipe.no/app=redis", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			dynamicClient.ClearActions()
+			code, names := listNames(t, h, tt.query)
+			if code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", code, http.StatusOK)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("deployments = %v, want %v", names, tt.want)
+			}
+
+			actions := dynamicClient.Actions()
+			if len(actions) != 1 {
+				t.Fatalf("actions = %v, want a single list", actions)
+			}
+			list, ok := actions[0].(k8stesting.ListAction)
+			if !ok {
+				t.Fatalf("action = %v, want a list", actions[0])
+			}
+			if got := list.GetListRestrictions().Labels.String(); got != tt.selector {
+				t.Errorf("label selector = %q, want %q", got, tt.selector)
+			}
+		})
+	}
+}
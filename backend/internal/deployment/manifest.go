@@ -0,0 +1,184 @@
+package deployment
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"appstore/backend/internal/k8s"
+)
+
+// redactedSecretValue replaces the values of Secrets in manifests
+const redactedSecretValue = "<redacted>"
+
+// Manifest handles GET /api/v1/deployments/{name}/manifest
+//
+// Returns the YAML manifest of the latest revision of the deployment's Helm release, the
+// resources as Helm applied them. The data of Secrets in it is redacted. The manifest is
+// written document by document, as it can be large.
+func (h *Handler) Manifest(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		h.respondError(w, http.StatusServiceUnavailable, "Kubernetes not available")
+		return
+	}
+
+	name := r.PathValue("name")
+	if name == "" {
+		h.respondError(w, http.StatusBadRequest, "deployment name is required")
+		return
+	}
+
+	namespace := h.requestNamespace(r)
+
+	manifest, err := h.k8sClient.GetReleaseManifest(r.Context(), namespace, name)
+	if errors.Is(err, k8s.ErrReleaseNotFound) {
+		h.respondError(w, http.StatusNotFound, "deployment has no Helm release yet")
+		return
+	}
+	if errors.Is(err, k8s.ErrRemoteCluster) {
+		h.logger.Error("failed to read release manifest from the remote cluster", "error", err, "name", name, "namespace", namespace)
+		h.respondError(w, http.StatusBadGateway, "the deployment's remote cluster can't be read")
+		return
+	}
+	if k8s.IsNotFound(err) {
+		h.respondError(w, http.StatusNotFound, "deployment not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get release manifest", "error", err, "name", name, "namespace", namespace)
+		h.respondError(w, http.StatusInternalServerError, "failed to get release manifest")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("X-Helm-Release", manifest.ReleaseName)
+	w.Header().Set("X-Helm-Release-Revision", strconv.Itoa(manifest.Revision))
+	w.WriteHeader(http.StatusOK)
+	if err := writeManifest(w, manifest.Manifest); err != nil {
+		h.logger.Warn("failed to write release manifest", "error", err, "name", name, "namespace", namespace)
+	}
+}
+
+// writeManifest writes a manifest to w one document at a time, redacting Secrets
+func writeManifest(w io.Writer, manifest string) error {
+	for _, doc := range manifestDocuments(manifest) {
+		if _, err := io.WriteString(w, redactSecret(doc)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// manifestDocuments splits a manifest into its YAML documents, each starting with its
+// "---" separator line, so that they concatenate to the manifest
+func manifestDocuments(manifest string) []string {
+	var docs []string
+	start := 0
+	for pos := 0; pos < len(manifest); {
+		line, _, _ := strings.Cut(manifest[pos:], "\n")
+		if line == "---" && pos > start {
+			docs = append(docs, manifest[start:pos])
+			start = pos
+		}
+		pos += len(line) + 1
+	}
+	if start < len(manifest) {
+		docs = append(docs, manifest[start:])
+	}
+	return docs
+}
+
+// redactSecret returns a manifest document with the values of the data and stringData of
+// Secrets replaced, whether the document is a Secret or a List holding Secrets. Other
+// documents are returned unchanged. A document that can't be parsed is omitted, as it
+// might be a Secret.
+func redactSecret(doc string) string {
+	separator := ""
+	if strings.HasPrefix(doc, "---\n") {
+		separator = "---\n"
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(doc), &node); err != nil {
+		return separator + "# Document omitted, it could not be parsed\n"
+	}
+	if len(node.Content) == 0 || !redactSecrets(node.Content[0], false) {
+		return doc
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(separator)
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		// Never fall back to the unredacted Secret
+		return separator + "# Secret omitted\n"
+	}
+	encoder.Close()
+	return buf.String()
+}
+
+// redactSecrets replaces the values of the data and stringData of a resource if it is a
+// Secret, or of the Secrets among the items of a list, and reports whether it did. The
+// items of a SecretList are Secrets even without a kind.
+func redactSecrets(resource *yaml.Node, secret bool) bool {
+	if resource.Kind != yaml.MappingNode {
+		return false
+	}
+	kind := ""
+	if node := mappingValue(resource, "kind"); node != nil {
+		kind = node.Value
+	}
+
+	switch {
+	case kind == "Secret" || secret && kind == "":
+		for _, field := range []string{"data", "stringData"} {
+			data := mappingValue(resource, field)
+			switch {
+			case data == nil:
+			case data.Kind == yaml.MappingNode:
+				for i := 1; i < len(data.Content); i += 2 {
+					data.Content[i] = redactedNode()
+				}
+			default:
+				// Not a map of keys, e.g. an alias: replace it whole
+				*data = *redactedNode()
+			}
+		}
+		return true
+
+	case strings.HasSuffix(kind, "List"):
+		items := mappingValue(resource, "items")
+		if items == nil || items.Kind != yaml.SequenceNode {
+			return false
+		}
+		redacted := false
+		for _, item := range items.Content {
+			if redactSecrets(item, kind == "SecretList") {
+				redacted = true
+			}
+		}
+		return redacted
+	}
+	return false
+}
+
+// redactedNode returns the YAML node replacing a value of a Secret
+func redactedNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: redactedSecretValue}
+}
+
+// mappingValue returns the value of a key of a YAML mapping, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
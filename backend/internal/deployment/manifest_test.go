@@ -0,0 +1,206 @@
+package deployment
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"appstore/backend/internal/k8s"
+)
+
+const (
+	secretDocument = "---\n# Source: postgresql/templates/secret.yaml\napiVersion: v1\nkind: Secret\nmetadata:\n  name: db\ndata:\n  password: c2VjcmV0\n"
+	configDocument = "---\n# Source: postgresql/templates/configmap.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: db\ndata:\n  max_connections:   \"100\"\n"
+)
+
+// newManifestSecret stores a gzipped release revision with a manifest like Helm's Secret driver
+func newManifestSecret(t *testing.T, release string, revision int, manifest string) *corev1.Secret {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{"name": release, "version": revision, "manifest": manifest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("sh.helm.release.v1.%s.v%d", release, revision),
+			Namespace: "team-a",
+			Labels:    map[string]string{"owner": "helm", "name": release, "version": fmt.Sprint(revision)},
+		},
+		Data: map[string][]byte{"release": []byte(base64.StdEncoding.EncodeToString(buf.Bytes()))},
+	}
+}
+
+func newManifestHandler(objects ...runtime.Object) *Handler {
+	return newManifestHandlerFor(newAppDeployment("team-a", "db", 42), fake.NewClientset(objects...))
+}
+
+// newManifestHandlerFor returns a handler reading the release of deployment with clientset
+func newManifestHandlerFor(deployment *unstructured.Unstructured, clientset kubernetes.Interface) *Handler {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{k8s.AppDeploymentGVR: "AppDeploymentList"},
+		deployment,
+	)
+	return NewHandler(nil, k8s.NewClientFromInterfaces(dynamicClient, clientset), nil, nil, nil, "", "")
+}
+
+func manifest(h *Handler, name string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/deployments/{name}/manifest", h.Manifest)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/deployments/"+name+"/manifest?namespace=team-a", nil))
+	return rec
+}
+
+func TestManifest(t *testing.T) {
+	h := newManifestHandler(
+		newManifestSecret(t, "db", 1, configDocument),
+		newManifestSecret(t, "db", 2, secretDocument+configDocument),
+	)
+
+	rec := manifest(h, "db")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/yaml" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := rec.Header().Get("X-Helm-Release-Revision"); got != "2" {
+		t.Errorf("X-Helm-Release-Revision = %q, want 2", got)
+	}
+
+	body := rec.Body.String()
+	if strings.Contains(body, "c2VjcmV0") || !strings.Contains(body, "password: "+redactedSecretValue) {
+		t.Errorf("Secret data not redacted:\n%s", body)
+	}
+	if !strings.Contains(body, "# Source: postgresql/templates/secret.yaml\n") {
+		t.Errorf("Secret source comment missing:\n%s", body)
+	}
+	if !strings.HasSuffix(body, "\n"+configDocument) {
+		t.Errorf("ConfigMap not returned as stored:\n%s", body)
+	}
+}
+
+func TestManifestLarge(t *testing.T) {
+	large := strings.Repeat(configDocument, 20000)
+	rec := manifest(newManifestHandler(newManifestSecret(t, "db", 1, large)), "db")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if rec.Body.String() != large {
+		t.Errorf("got %d bytes, want the %d byte manifest", rec.Body.Len(), len(large))
+	}
+}
+
+func TestManifestNotFound(t *testing.T) {
+	h := newManifestHandler(newManifestSecret(t, "other", 1, configDocument))
+	for _, name := range []string{"db", "unknown"} {
+		if rec := manifest(h, name); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", name, rec.Code)
+		}
+	}
+}
+
+func TestManifestErrors(t *testing.T) {
+	clientset := fake.NewClientset()
+	clientset.PrependReactor("list", "secrets", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "", errors.New("denied"))
+	})
+	if rec := manifest(newManifestHandlerFor(newAppDeployment("team-a", "db", 1), clientset), "db"); rec.Code != http.StatusInternalServerError {
+		t.Errorf("failing read: status = %d, want 500", rec.Code)
+	}
+
+	// The release of a clusterRef is in the remote cluster, which isn't allowed
+	remote := newAppDeployment("team-a", "db", 1)
+	remote.Object["spec"].(map[string]interface{})["clusterRef"] = map[string]interface{}{"secretName": "edge"}
+	h := newManifestHandlerFor(remote, fake.NewClientset(newManifestSecret(t, "db", 1, secretDocument)))
+	if rec := manifest(h, "db"); rec.Code != http.StatusBadGateway || strings.Contains(rec.Body.String(), "c2VjcmV0") {
+		t.Errorf("remote deployment: status = %d, body = %s, want 502", rec.Code, rec.Body)
+	}
+}
+
+func TestRedactSecret(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{
+			name: "secret",
+			doc:  "---\nkind: Secret\ndata:\n  password: c2VjcmV0\nstringData:\n  user: admin\n",
+			want: "---\nkind: Secret\ndata:\n  password: <redacted>\nstringData:\n  user: <redacted>\n",
+		},
+		{
+			name: "list",
+			doc:  "kind: List\nitems:\n  - kind: ConfigMap\n    data:\n      key: value\n  - kind: Secret\n    data:\n      password: c2VjcmV0\n",
+			want: "kind: List\nitems:\n  - kind: ConfigMap\n    data:\n      key: value\n  - kind: Secret\n    data:\n      password: <redacted>\n",
+		},
+		{
+			name: "secret list",
+			doc:  "kind: SecretList\nitems:\n  - data:\n      password: c2VjcmV0\n",
+			want: "kind: SecretList\nitems:\n  - data:\n      password: <redacted>\n",
+		},
+		{
+			name: "data not a map",
+			doc:  "kind: Secret\ndata: c2VjcmV0\n",
+			want: "kind: Secret\ndata: <redacted>\n",
+		},
+		{
+			name: "unparseable",
+			doc:  "---\nkind: Secret\ndata:\n  password: c2VjcmV0\n   bad: [\n",
+			want: "---\n# Document omitted, it could not be parsed\n",
+		},
+		{
+			name: "config map",
+			doc:  configDocument,
+			want: configDocument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactSecret(tt.doc); got != tt.want {
+				t.Errorf("redactSecret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManifestDocuments(t *testing.T) {
+	for _, m := range []string{
+		"",
+		configDocument,
+		secretDocument + configDocument,
+		"apiVersion: v1\nkind: ConfigMap\n---\napiVersion: v1\nkind: Service\n",
+		"data:\n  key: |\n    ---not a separator\n",
+	} {
+		docs := manifestDocuments(m)
+		if got := strings.Join(docs, ""); got != m {
+			t.Errorf("manifestDocuments(%q) = %q", m, docs)
+		}
+	}
+	if docs := manifestDocuments(secretDocument + configDocument); len(docs) != 2 {
+		t.Errorf("manifestDocuments() = %d documents, want 2", len(docs))
+	}
+}
@@ -0,0 +1,36 @@
+package deployment
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// DefaultNamespace is the namespace of requests without ?namespace= unless another
+	// default is configured
+	DefaultNamespace = "default"
+	// TeamPlaceholder in the default namespace is replaced by the requesting team's ID,
+	// so that each team defaults to its own namespace
+	TeamPlaceholder = "{team}"
+)
+
+// ValidateDefaultNamespace checks a default namespace given as a flag, which must be a
+// valid namespace once TeamPlaceholder is replaced
+func ValidateDefaultNamespace(s string) error {
+	if errs := validation.IsDNS1123Label(strings.ReplaceAll(s, TeamPlaceholder, "team")); len(errs) > 0 {
+		return fmt.Errorf("default namespace %q is invalid: %s", s, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// requestNamespace returns the namespace of a request on an existing deployment: the
+// ?namespace= query parameter, or the default namespace of the requesting team
+func (h *Handler) requestNamespace(r *http.Request) string {
+	if namespace := r.URL.Query().Get("namespace"); namespace != "" {
+		return namespace
+	}
+	return strings.ReplaceAll(h.defaultNamespace, TeamPlaceholder, requestTeamID)
+}
@@ -0,0 +1,58 @@
+package deployment
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestNamespaceDefault(t *testing.T) {
+	tests := []struct {
+		name             string
+		defaultNamespace string
+		query            string
+		wantNamespace    string
+	}{
+		{"unconfigured", "", "", DefaultNamespace},
+		{"configured", "apps", "", "apps"},
+		{"team namespace", TeamPlaceholder + "-apps", "", requestTeamID + "-apps"},
+		{"query parameter", "apps", "?namespace=team-a", "team-a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakePublisher{}
+			h := NewHandler(publisher, newTestK8sClient(newAppDeployment(tt.wantNamespace, "db", 42)), nil, nil, nil, "", tt.defaultNamespace)
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /api/v1/deployments/{name}", h.Get)
+			mux.HandleFunc("DELETE /api/v1/deployments/{name}", h.Delete)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/deployments/db"+tt.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("Get status = %d, want %d (body %s)", rec.Code, http.StatusOK, rec.Body)
+			}
+
+			rec = httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/deployments/db"+tt.query, nil))
+			if rec.Code != http.StatusAccepted {
+				t.Fatalf("Delete status = %d, want %d (body %s)", rec.Code, http.StatusAccepted, rec.Body)
+			}
+			if len(publisher.deletes) != 1 || publisher.deletes[0].Namespace != tt.wantNamespace {
+				t.Errorf("published deletes = %+v, want one in namespace %s", publisher.deletes, tt.wantNamespace)
+			}
+		})
+	}
+}
+
+func TestValidateDefaultNamespace(t *testing.T) {
+	for _, namespace := range []string{"default", "apps", TeamPlaceholder, "team-" + TeamPlaceholder} {
+		if err := ValidateDefaultNamespace(namespace); err != nil {
+			t.Errorf("ValidateDefaultNamespace(%q) error = %v", namespace, err)
+		}
+	}
+	for _, namespace := range []string{"", "Apps", "apps_ns", "{teams}"} {
+		if err := ValidateDefaultNamespace(namespace); err == nil {
+			t.Errorf("ValidateDefaultNamespace(%q) succeeded, want an error", namespace)
+		}
+	}
+}
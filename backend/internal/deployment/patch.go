@@ -0,0 +1,171 @@
+package deployment
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/google/uuid"
+	jsonpatch "gopkg.in/evanphx/json-patch.v4"
+
+	"appstore/backend/internal/audit"
+	"appstore/backend/pkg/models"
+)
+
+// Content types accepted by Patch
+const (
+	contentTypeJSONPatch  = "application/json-patch+json"
+	contentTypeMergePatch = "application/merge-patch+json"
+)
+
+// Patch handles PATCH /api/v1/deployments/{name}
+//
+// Applies a patch to the deployment's current spec.values and publishes the result as an
+// update, so that a nested value can be changed without resending all of them. The body is
+// an RFC 6902 JSON Patch with Content-Type application/json-patch+json, or an RFC 7386
+// merge patch with Content-Type application/merge-patch+json. Paths are relative to the
+// values. If-Match is honoured as for PUT; without it, values changed between reading and
+// applying the update are overwritten.
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" {
+		h.respondError(w, http.StatusBadRequest, "deployment name is required")
+		return
+	}
+
+	namespace := h.requestNamespace(r)
+
+	// TODO: Get team ID and user ID from auth context
+	userID := "anonymous"
+
+	requestID := uuid.New().String()
+	record := models.AuditRecord{
+		Action:     audit.ActionUpdate,
+		UserID:     userID,
+		Deployment: name,
+		Namespace:  namespace,
+		RequestID:  requestID,
+	}
+
+	if h.k8sClient == nil || h.publisher == nil {
+		h.auditLog(r.Context(), record, audit.OutcomeFailed, "Kubernetes or RabbitMQ not available")
+		h.respondError(w, http.StatusServiceUnavailable, "Kubernetes or RabbitMQ not available")
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != contentTypeJSONPatch && contentType != contentTypeMergePatch {
+		h.auditLog(r.Context(), record, audit.OutcomeRejected, "unsupported patch content type")
+		h.respondError(w, http.StatusUnsupportedMediaType,
+			"Content-Type must be "+contentTypeJSONPatch+" or "+contentTypeMergePatch)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		status, message := bodyError(err, "invalid request body")
+		h.auditLog(r.Context(), record, audit.OutcomeRejected, message)
+		h.respondError(w, status, message)
+		return
+	}
+
+	// Verify deployment exists and get its details
+	deployment, err := h.k8sClient.GetAppDeployment(r.Context(), namespace, name)
+	if err != nil {
+		h.auditLog(r.Context(), record, audit.OutcomeRejected, "deployment not found")
+		h.respondError(w, http.StatusNotFound, "deployment not found")
+		return
+	}
+
+	teamID := deployment.TeamID
+	record.TeamID = teamID
+	record.AppName = deployment.AppName
+
+	current, currentGeneration, err := h.k8sClient.GetAppDeploymentValues(r.Context(), namespace, name)
+	if err != nil {
+		h.logger.Error("failed to get deployment values", "error", err, "name", name, "namespace", namespace)
+		h.auditLog(r.Context(), record, audit.OutcomeFailed, "failed to get deployment values")
+		h.respondError(w, http.StatusInternalServerError, "failed to get deployment values")
+		return
+	}
+
+	generation, ok := ifMatchGeneration(r.Header.Get("If-Match"), currentGeneration)
+	if !ok {
+		h.auditLog(r.Context(), record, audit.OutcomeRejected, "deployment has been modified")
+		h.respondError(w, http.StatusConflict, "deployment has been modified; fetch it again and retry")
+		return
+	}
+
+	patched, err := patchValues(current, body, contentType)
+	if err != nil {
+		h.auditLog(r.Context(), record, audit.OutcomeRejected, "invalid patch")
+		h.respondError(w, http.StatusUnprocessableEntity, "invalid patch: "+err.Error())
+		return
+	}
+
+	payload := models.DeploymentUpdatePayload{
+		RequestID: requestID,
+		TeamID:    teamID,
+		UserID:    userID,
+		Name:      name,
+		Namespace: namespace,
+		Values:    patched,
+		// With If-Match, don't overwrite changes made since the values were read
+		Generation: generation,
+	}
+
+	if err := h.publisher.PublishDeploymentUpdate(r.Context(), payload); err != nil {
+		h.logger.Error("failed to publish deployment update", "error", err)
+		h.auditLog(r.Context(), record, audit.OutcomeFailed, "failed to publish deployment update")
+		h.respondError(w, publishErrorStatus(err), "failed to update deployment")
+		return
+	}
+
+	h.auditLog(r.Context(), record, audit.OutcomeAccepted, "")
+
+	h.logger.Info("deployment patch published",
+		"requestId", requestID,
+		"name", name,
+	)
+
+	h.respondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"requestId": requestID,
+		"message":   "deployment update request accepted",
+		"values":    patched,
+	})
+}
+
+// patchValues applies a JSON Patch or merge patch, by content type, to values
+func patchValues(values map[string]interface{}, patch []byte, contentType string) (map[string]interface{}, error) {
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	doc, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	switch contentType {
+	case contentTypeJSONPatch:
+		ops, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, err
+		}
+		doc, err = ops.Apply(doc)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		doc, err = jsonpatch.MergePatch(doc, patch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	patched := map[string]interface{}{}
+	if err := json.Unmarshal(doc, &patched); err != nil {
+		return nil, err
+	}
+	return patched, nil
+}
@@ -0,0 +1,165 @@
+package deployment
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func patch(h *Handler, name, contentType, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("PATCH /api/v1/deployments/{name}", h.Patch)
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/deployments/"+name+"?namespace=team-a", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func newPatchTestHandler(publisher *fakePublisher) *Handler {
+	ad := newAppDeployment("team-a", "db", 42)
+	ad.Object["spec"].(map[string]interface{})["values"] = map[string]interface{}{
+		"replicas": int64(1),
+		"resources": map[string]interface{}{
+			"limits": map[string]interface{}{"cpu": "500m", "memory": "512Mi"},
+		},
+		"metrics": map[string]interface{}{"enabled": true},
+	}
+	return NewHandler(publisher, newTestK8sClient(ad), nil, nil, nil, "", "")
+}
+
+func TestPatchValues(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        map[string]interface{}
+	}{
+		{
+			name:        "add nested value",
+			contentType: contentTypeJSONPatch,
+			body:        `[{"op":"add","path":"/resources/requests","value":{"cpu":"250m"}}]`,
+			want: map[string]interface{}{
+				"replicas": float64(1),
+				"resources": map[string]interface{}{
+					"limits":   map[string]interface{}{"cpu": "500m", "memory": "512Mi"},
+					"requests": map[string]interface{}{"cpu": "250m"},
+				},
+				"metrics": map[string]interface{}{"enabled": true},
+			},
+		},
+		{
+			name:        "remove nested value",
+			contentType: contentTypeJSONPatch,
+			body:        `[{"op":"remove","path":"/resources/limits/memory"}]`,
+			want: map[string]interface{}{
+				"replicas":  float64(1),
+				"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "500m"}},
+				"metrics":   map[string]interface{}{"enabled": true},
+			},
+		},
+		{
+			name:        "replace nested value",
+			contentType: contentTypeJSONPatch,
+			body:        `[{"op":"replace","path":"/resources/limits/cpu","value":"1"},{"op":"replace","path":"/replicas","value":3}]`,
+			want: map[string]interface{}{
+				"replicas": float64(3),
+				"resources": map[string]interface{}{
+					"limits": map[string]interface{}{"cpu": "1", "memory": "512Mi"},
+				},
+				"metrics": map[string]interface{}{"enabled": true},
+			},
+		},
+		{
+			name:        "merge patch",
+			contentType: contentTypeMergePatch + "; charset=utf-8",
+			body:        `{"resources":{"limits":{"memory":"1Gi"}},"metrics":null}`,
+			want: map[string]interface{}{
+				"replicas": float64(1),
+				"resources": map[string]interface{}{
+					"limits": map[string]interface{}{"cpu": "500m", "memory": "1Gi"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakePublisher{}
+			rec := patch(newPatchTestHandler(publisher), "db", tt.contentType, tt.body)
+			if rec.Code != http.StatusAccepted {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusAccepted, rec.Body)
+			}
+			if len(publisher.updates) != 1 {
+				t.Fatalf("published %d updates, want 1", len(publisher.updates))
+			}
+			update := publisher.updates[0]
+			if !reflect.DeepEqual(update.Values, tt.want) {
+				t.Errorf("values = %v, want %v", update.Values, tt.want)
+			}
+			// Without If-Match the operator applies the update whatever changed since
+			if update.Generation != 0 {
+				t.Errorf("generation = %d, want none", update.Generation)
+			}
+		})
+	}
+}
+
+func TestPatchRejects(t *testing.T) {
+	tests := []struct {
+		name        string
+		deployment  string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{"unsupported content type", "db", "application/json", `{"replicas":2}`, http.StatusUnsupportedMediaType},
+		{"malformed patch", "db", contentTypeJSONPatch, `{"op":"add"}`, http.StatusUnprocessableEntity},
+		{"missing path", "db", contentTypeJSONPatch, `[{"op":"remove","path":"/ingress/host"}]`, http.StatusUnprocessableEntity},
+		{"failed test op", "db", contentTypeJSONPatch, `[{"op":"test","path":"/replicas","value":2}]`, http.StatusUnprocessableEntity},
+		{"missing deployment", "cache", contentTypeMergePatch, `{"replicas":2}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakePublisher{}
+			rec := patch(newPatchTestHandler(publisher), tt.deployment, tt.contentType, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if len(publisher.updates) != 0 {
+				t.Errorf("published %d updates, want 0", len(publisher.updates))
+			}
+		})
+	}
+}
+
+func patchIfMatch(h *Handler, ifMatch string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("PATCH /api/v1/deployments/{name}", h.Patch)
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/deployments/db?namespace=team-a", strings.NewReader(`{"replicas":2}`))
+	req.Header.Set("Content-Type", contentTypeMergePatch)
+	req.Header.Set("If-Match", ifMatch)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPatchIfMatch(t *testing.T) {
+	publisher := &fakePublisher{}
+	rec := patchIfMatch(newPatchTestHandler(publisher), `"41"`)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusConflict, rec.Body)
+	}
+	if len(publisher.updates) != 0 {
+		t.Errorf("published %d updates on conflict, want 0", len(publisher.updates))
+	}
+
+	rec = patchIfMatch(newPatchTestHandler(publisher), `"42"`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	if len(publisher.updates) != 1 || publisher.updates[0].Generation != 42 {
+		t.Errorf("updates = %+v, want one with generation 42", publisher.updates)
+	}
+}
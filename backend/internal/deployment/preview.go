@@ -0,0 +1,87 @@
+package deployment
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"appstore/backend/internal/catalog"
+	"appstore/backend/pkg/models"
+	"appstore/shared/values"
+)
+
+// PreviewResult is the response of POST /api/v1/deployments:preview
+type PreviewResult struct {
+	// Values are the effective Helm values of the deployment
+	Values map[string]interface{} `json:"values"`
+}
+
+// redactedValue is shown in place of a value sourced from a Secret
+func redactedValue(ref models.SecretKeyRef) string {
+	return fmt.Sprintf("<redacted:%s/%s>", ref.Name, ref.Key)
+}
+
+// Preview handles POST /api/v1/deployments:preview
+//
+// Returns the values a create request would deploy with: the chart's values.yaml, overlaid
+// with the catalog's default values and then the request's values, in which null unsets a
+// default value, including the chart's, as in Helm. Values sourced from secretKeyRefs are
+// redacted. Nothing is published.
+func (h *Handler) Preview(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+	if err := decodeBody(r, &req); err != nil {
+		status, message := bodyError(err, "invalid request body")
+		h.respondError(w, status, message)
+		return
+	}
+
+	if req.AppName == "" {
+		h.respondError(w, http.StatusBadRequest, "appName is required")
+		return
+	}
+	if msg := validateSecretKeyRefs(req.SecretKeyRefs); msg != "" {
+		h.respondError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if h.catalogService == nil {
+		h.respondError(w, http.StatusServiceUnavailable, "catalog not available")
+		return
+	}
+	if !h.catalogService.AppExists(req.AppName) {
+		h.respondError(w, http.StatusNotFound, "app not found")
+		return
+	}
+
+	chartValues, err := h.catalogService.ChartValues(req.AppName)
+	if err != nil && !errors.Is(err, catalog.ErrChartFileNotFound) {
+		h.logger.Error("failed to read chart values", "error", err, "appName", req.AppName)
+		h.respondError(w, http.StatusInternalServerError, "failed to read chart values")
+		return
+	}
+
+	merged := removeNulls(values.Merge(chartValues, h.withDefaultValues(req.AppName, req.Values)))
+	for _, ref := range req.SecretKeyRefs {
+		// The operator resolves the Secret when deploying; its value is never exposed here
+		if err := values.SetAtPath(merged, ref.Path, redactedValue(ref)); err != nil {
+			h.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	h.respondJSON(w, http.StatusOK, PreviewResult{Values: merged})
+}
+
+// removeNulls removes the keys set to null from values, as Helm does when it merges the
+// values of a release with the chart's
+func removeNulls(values map[string]interface{}) map[string]interface{} {
+	for key, value := range values {
+		switch v := value.(type) {
+		case nil:
+			delete(values, key)
+		case map[string]interface{}:
+			removeNulls(v)
+		}
+	}
+	return values
+}
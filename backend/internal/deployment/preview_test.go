@@ -0,0 +1,282 @@
+package deployment
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"appstore/backend/internal/catalog"
+)
+
+// newDefaultsCatalog returns a catalog with global default values whose postgresql chart
+// has a values.yaml and whose mysql app has default and required values
+func newDefaultsCatalog(t *testing.T) *catalog.Service {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"catalog.yaml": "defaultValues:\n" +
+			"  resources:\n    limits:\n      memory: 512Mi\n" +
+			"  podLabels:\n    managed-by: appstore\n" +
+			"apps:\n  - name: postgresql\n  - name: valkey\n" +
+			"  - name: mysql\n" +
+			"    defaultValues:\n      architecture: standalone\n      podLabels:\n        tier: db\n" +
+			"    requiredValues: [architecture, auth.rootPassword, auth.database]\n",
+		"apps/postgresql/values.yaml": "replicas: 1\n" +
+			"resources:\n  limits:\n    cpu: 500m\n    memory: 256Mi\n" +
+			"auth:\n  username: postgres\n  password: \"\"\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := catalog.NewService(filepath.Join(dir, "catalog.yaml"), filepath.Join(dir, "apps"))
+	if err := s.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return s
+}
+
+func preview(h *Handler, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.Preview(rec, httptest.NewRequest(http.MethodPost, "/api/v1/deployments:preview", strings.NewReader(body)))
+	return rec
+}
+
+func TestPreviewLayersValues(t *testing.T) {
+	h := NewHandler(nil, nil, newDefaultsCatalog(t), nil, nil, "", "")
+
+	rec := preview(h, `{
+		"appName": "postgresql",
+		"values": {"replicas": 3, "resources": {"limits": {"memory": "1Gi"}}, "auth": {"password": "hunter2"}},
+		"secretKeyRefs": [
+			{"path": "auth.password", "name": "pg-credentials", "key": "password"},
+			{"path": "metrics.token", "name": "pg-metrics", "key": "token"}
+		]
+	}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	var result PreviewResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		// Request values win over defaults and the chart
+		"replicas": float64(3),
+		"resources": map[string]interface{}{"limits": map[string]interface{}{
+			// From the chart
+			"cpu": "500m",
+			// Defaults override the chart, the request overrides defaults
+			"memory": "1Gi",
+		}},
+		// From the defaults
+		"podLabels": map[string]interface{}{"managed-by": "appstore"},
+		"auth": map[string]interface{}{
+			"username": "postgres",
+			// Secret references win over everything and are redacted
+			"password": "<redacted:pg-credentials/password>",
+		},
+		"metrics": map[string]interface{}{"token": "<redacted:pg-metrics/token>"},
+	}
+	if !reflect.DeepEqual(result.Values, want) {
+		t.Errorf("values = %v, want %v", result.Values, want)
+	}
+}
+
+func TestPreviewWithoutChartValues(t *testing.T) {
+	h := NewHandler(nil, nil, newDefaultsCatalog(t), nil, nil, "", "")
+
+	rec := preview(h, `{"appName": "valkey", "values": {"resources": {"limits": {"cpu": "1"}}}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	var result PreviewResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "1", "memory": "512Mi"}},
+		"podLabels": map[string]interface{}{"managed-by": "appstore"},
+	}
+	if !reflect.DeepEqual(result.Values, want) {
+		t.Errorf("values = %v, want %v", result.Values, want)
+	}
+}
+
+func TestPreviewNullValues(t *testing.T) {
+	h := NewHandler(nil, nil, newDefaultsCatalog(t), nil, nil, "", "")
+
+	rec := preview(h, `{"appName": "postgresql", "values": {"podLabels": null, "resources": {"limits": {"memory": null}}}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	var result PreviewResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"replicas": float64(1),
+		// Null unsets the default memory limit and, as in Helm, the chart's
+		"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "500m"}},
+		"auth":      map[string]interface{}{"username": "postgres", "password": ""},
+	}
+	if !reflect.DeepEqual(result.Values, want) {
+		t.Errorf("values = %v, want %v", result.Values, want)
+	}
+}
+
+func TestPreviewRejectsInvalidRequests(t *testing.T) {
+	h := NewHandler(nil, nil, newDefaultsCatalog(t), nil, nil, "", "")
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"invalid body", `{`, http.StatusBadRequest},
+		{"missing appName", `{}`, http.StatusBadRequest},
+		{"unknown app", `{"appName": "oracle"}`, http.StatusNotFound},
+		{"incomplete secretKeyRef", `{"appName": "postgresql", "secretKeyRefs": [{"path": "auth.password"}]}`, http.StatusBadRequest},
+		{"secretKeyRef path through a value", `{"appName": "postgresql", "secretKeyRefs": [{"path": "replicas.count", "name": "s", "key": "k"}]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := preview(h, tt.body); rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestCreateAppliesDefaultValues(t *testing.T) {
+	publisher := &fakePublisher{}
+	catalogService := newDefaultsCatalog(t)
+	h := NewHandler(publisher, nil, catalogService, nil, nil, "", "")
+
+	rec := create(h, `{"appName": "postgresql", "namespace": "team-a",
+		"values": {"podLabels": {"tier": "db"}},
+		"secretKeyRefs": [{"path": "auth.password", "name": "pg-credentials", "key": "password"}]}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	if len(publisher.requests) != 1 {
+		t.Fatalf("published %d requests, want 1", len(publisher.requests))
+	}
+
+	payload := publisher.requests[0]
+	want := map[string]interface{}{
+		"resources": map[string]interface{}{"limits": map[string]interface{}{"memory": "512Mi"}},
+		"podLabels": map[string]interface{}{"managed-by": "appstore", "tier": "db"},
+	}
+	if !reflect.DeepEqual(payload.Values, want) {
+		t.Errorf("values = %v, want %v", payload.Values, want)
+	}
+	if len(payload.SecretKeyRefs) != 1 || payload.SecretKeyRefs[0].Name != "pg-credentials" {
+		t.Errorf("secretKeyRefs = %+v, want the request's", payload.SecretKeyRefs)
+	}
+	if defaults := catalogService.DefaultValues(); !reflect.DeepEqual(defaults["podLabels"], map[string]interface{}{"managed-by": "appstore"}) {
+		t.Errorf("catalog defaults were modified: %v", defaults)
+	}
+}
+
+func TestCreateAppliesAppDefaultValues(t *testing.T) {
+	publisher := &fakePublisher{}
+	h := NewHandler(publisher, nil, newDefaultsCatalog(t), nil, nil, "", "")
+
+	rec := create(h, `{"appName": "mysql", "namespace": "team-a",
+		"values": {"podLabels": {"managed-by": "team-a"}, "auth": {"rootPassword": "hunter2", "database": "app"}}}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	if len(publisher.requests) != 1 {
+		t.Fatalf("published %d requests, want 1", len(publisher.requests))
+	}
+
+	want := map[string]interface{}{
+		// Global defaults
+		"resources": map[string]interface{}{"limits": map[string]interface{}{"memory": "512Mi"}},
+		// The app's defaults are merged over the global ones, the request's over both
+		"podLabels":    map[string]interface{}{"managed-by": "team-a", "tier": "db"},
+		"architecture": "standalone",
+		"auth":         map[string]interface{}{"rootPassword": "hunter2", "database": "app"},
+	}
+	if got := publisher.requests[0].Values; !reflect.DeepEqual(got, want) {
+		t.Errorf("values = %v, want %v", got, want)
+	}
+}
+
+func TestCreateRejectsMissingRequiredValues(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "all set",
+			body:       `{"appName":"mysql","namespace":"team-a","values":{"auth":{"rootPassword":"hunter2","database":"app"}}}`,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "set by secret key references",
+			body:       `{"appName":"mysql","namespace":"team-a","values":{"auth":{"database":"app"}},"secretKeyRefs":[{"path":"auth.rootPassword","name":"db","key":"password"}]}`,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "no values",
+			body:       `{"appName":"mysql","namespace":"team-a"}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "app mysql requires values auth.rootPassword, auth.database",
+		},
+		{
+			name:       "null",
+			body:       `{"appName":"mysql","namespace":"team-a","values":{"auth":{"rootPassword":"hunter2","database":null}}}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "app mysql requires values auth.database",
+		},
+		{
+			name:       "default unset",
+			body:       `{"appName":"mysql","namespace":"team-a","values":{"architecture":null,"auth":{"rootPassword":"hunter2","database":"app"}}}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "app mysql requires values architecture",
+		},
+		{
+			name:       "app without required values",
+			body:       `{"appName":"valkey","namespace":"team-a"}`,
+			wantStatus: http.StatusAccepted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakePublisher{}
+			h := NewHandler(publisher, nil, newDefaultsCatalog(t), nil, nil, "", "")
+
+			rec := create(h, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantError != "" {
+				if !strings.Contains(rec.Body.String(), tt.wantError) {
+					t.Errorf("body = %s, want error %q", rec.Body, tt.wantError)
+				}
+				if len(publisher.requests) != 0 {
+					t.Errorf("published %d requests, want none", len(publisher.requests))
+				}
+			}
+		})
+	}
+}
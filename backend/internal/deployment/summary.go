@@ -0,0 +1,65 @@
+package deployment
+
+import (
+	"net/http"
+
+	"appstore/backend/internal/k8s"
+)
+
+// Summary counts deployments by phase, team and app, e.g. for an overview page
+type Summary struct {
+	Total int `json:"total"`
+	// ByPhase has a count for every phase, including those without deployments
+	ByPhase map[string]int `json:"byPhase"`
+	ByTeam  map[string]int `json:"byTeam"`
+	ByApp   map[string]int `json:"byApp"`
+}
+
+// summarize counts the deployments. Deployments the operator hasn't reconciled yet have no
+// phase and are counted as Pending.
+func summarize(deployments []k8s.AppDeployment) Summary {
+	summary := Summary{
+		Total:   len(deployments),
+		ByPhase: make(map[string]int, len(phases)),
+		ByTeam:  map[string]int{},
+		ByApp:   map[string]int{},
+	}
+	for _, phase := range phases {
+		summary.ByPhase[phase] = 0
+	}
+	for _, deployment := range deployments {
+		phase := deployment.Phase
+		if phase == "" {
+			phase = "Pending"
+		}
+		summary.ByPhase[phase]++
+		summary.ByTeam[deployment.TeamID]++
+		summary.ByApp[deployment.AppName]++
+	}
+	return summary
+}
+
+// Summary handles GET /api/v1/deployments/summary. It counts the deployments the list
+// endpoint would return for the same ?namespace=, ?app= and ?team= parameters, from a
+// single list.
+func (h *Handler) Summary(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		h.respondError(w, http.StatusServiceUnavailable, "Kubernetes not available")
+		return
+	}
+
+	selector, err := parseListLabels(r.URL.Query())
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	deployments, err := h.k8sClient.ListAppDeploymentsWithLabels(r.Context(), r.URL.Query().Get("namespace"), selector)
+	if err != nil {
+		h.logger.Error("failed to list deployments", "error", err)
+		h.respondError(w, http.StatusInternalServerError, "failed to list deployments")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, summarize(deployments))
+}
@@ -0,0 +1,93 @@
+package deployment
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newSummaryDeployment(namespace, name, app, team, phase string) runtime.Object {
+	obj := newLabeledDeployment(namespace, name, app, team)
+	obj.Object["spec"] = map[string]interface{}{"appName": app, "teamId": team}
+	if phase != "" {
+		obj.Object["status"] = map[string]interface{}{"phase": phase}
+	}
+	return obj
+}
+
+func getSummary(t *testing.T, h *Handler, query string) (int, Summary) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.Summary(rec, httptest.NewRequest(http.MethodGet, "/api/v1/deployments/summary"+query, nil))
+	var summary Summary
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&summary); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, summary
+}
+
+func TestSummary(t *testing.T) {
+	h := NewHandler(nil, newTestK8sClient(
+		newSummaryDeployment("team-a", "db", "postgresql", "team-a", "Deployed"),
+		newSummaryDeployment("team-a", "cache", "redis", "team-a", "Failed"),
+		newSummaryDeployment("team-a", "queue", "rabbitmq", "team-a", ""),
+		newSummaryDeployment("team-b", "db", "postgresql", "team-b", "Deployed"),
+		newSummaryDeployment("team-b", "orders", "postgresql", "team-b", "Upgrading"),
+	), nil, nil, nil, "", "")
+
+	tests := []struct {
+		query string
+		want  Summary
+	}{
+		{"", Summary{
+			Total: 5,
+			// Deployments without a phase yet are pending
+			ByPhase: map[string]int{"Pending": 1, "Installing": 0, "Upgrading": 1, "Deployed": 2, "Failed": 1, "Uninstalling": 0},
+			ByTeam:  map[string]int{"team-a": 3, "team-b": 2},
+			ByApp:   map[string]int{"postgresql": 3, "redis": 1, "rabbitmq": 1},
+		}},
+		{"?namespace=team-b", Summary{
+			Total:   2,
+			ByPhase: map[string]int{"Pending": 0, "Installing": 0, "Upgrading": 1, "Deployed": 1, "Failed": 0, "Uninstalling": 0},
+			ByTeam:  map[string]int{"team-b": 2},
+			ByApp:   map[string]int{"postgresql": 2},
+		}},
+		{"?app=postgresql&team=team-a", Summary{
+			Total:   1,
+			ByPhase: map[string]int{"Pending": 0, "Installing": 0, "Upgrading": 0, "Deployed": 1, "Failed": 0, "Uninstalling": 0},
+			ByTeam:  map[string]int{"team-a": 1},
+			ByApp:   map[string]int{"postgresql": 1},
+		}},
+		{"?team=team-c", Summary{
+			ByPhase: map[string]int{"Pending": 0, "Installing": 0, "Upgrading": 0, "Deployed": 0, "Failed": 0, "Uninstalling": 0},
+			ByTeam:  map[string]int{},
+			ByApp:   map[string]int{},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			code, summary := getSummary(t, h, tt.query)
+			if code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", code, http.StatusOK)
+			}
+			if !reflect.DeepEqual(summary, tt.want) {
+				t.Errorf("summary = %+v, want %+v", summary, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryErrors(t *testing.T) {
+	if code, _ := getSummary(t, NewHandler(nil, newTestK8sClient(), nil, nil, nil, "", ""), "?team=team/a"); code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", code, http.StatusBadRequest)
+	}
+	if code, _ := getSummary(t, NewHandler(nil, nil, nil, nil, nil, "", ""), ""); code != http.StatusServiceUnavailable {
+		t.Errorf("status without Kubernetes = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,85 @@
+package deployment
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"appstore/backend/internal/catalog"
+	"appstore/backend/pkg/models"
+	"appstore/shared/values"
+)
+
+// UnknownValuesMode is how creates treat top-level values that the chart doesn't declare in
+// its values.yaml, which are usually misspelled keys the chart silently ignores
+type UnknownValuesMode string
+
+const (
+	// UnknownValuesWarn accepts the create and returns a warning (the default)
+	UnknownValuesWarn UnknownValuesMode = "warn"
+	// UnknownValuesReject rejects the create with 400
+	UnknownValuesReject UnknownValuesMode = "reject"
+	// UnknownValuesIgnore doesn't check the values
+	UnknownValuesIgnore UnknownValuesMode = "ignore"
+)
+
+// ParseUnknownValuesMode parses a mode given as a flag
+func ParseUnknownValuesMode(s string) (UnknownValuesMode, error) {
+	switch mode := UnknownValuesMode(s); mode {
+	case UnknownValuesWarn, UnknownValuesReject, UnknownValuesIgnore:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown values mode must be warn, reject or ignore, got %q", s)
+}
+
+// unknownValues returns a message naming the request's top-level values that the chart
+// doesn't declare, or "" if there are none or they can't be checked
+func (h *Handler) unknownValues(req CreateRequest) string {
+	if h.unknownValuesMode == UnknownValuesIgnore || len(req.Values) == 0 || h.catalogService == nil {
+		return ""
+	}
+
+	keys, err := h.catalogService.UnknownValueKeys(req.AppName, req.Values)
+	if err != nil {
+		if !errors.Is(err, catalog.ErrChartFileNotFound) {
+			h.logger.Warn("failed to check value keys", "error", err, "appName", req.AppName)
+		}
+		return ""
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("values %s are not in the values.yaml of app %s", strings.Join(keys, ", "), req.AppName)
+}
+
+// unknownValuesWarnings returns the warnings to respond to a create with
+func (h *Handler) unknownValuesWarnings(req CreateRequest) []string {
+	if h.unknownValuesMode != UnknownValuesWarn {
+		return nil
+	}
+	msg := h.unknownValues(req)
+	if msg == "" {
+		return nil
+	}
+	h.logger.Warn("deployment request has unknown values", "appName", req.AppName, "namespace", req.Namespace, "warning", msg)
+	return []string{msg}
+}
+
+// missingRequiredValues returns the app's required values that neither the values nor a
+// secret key reference set, in the catalog's order
+func missingRequiredValues(app *catalog.App, vals map[string]interface{}, refs []models.SecretKeyRef) []string {
+	var missing []string
+	for _, path := range app.RequiredValues {
+		if _, ok := values.GetAtPath(vals, path); ok {
+			continue
+		}
+		if slices.ContainsFunc(refs, func(ref models.SecretKeyRef) bool {
+			return ref.Path == path || strings.HasPrefix(ref.Path, path+".")
+		}) {
+			continue
+		}
+		missing = append(missing, path)
+	}
+	return missing
+}
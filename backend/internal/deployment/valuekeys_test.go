@@ -0,0 +1,165 @@
+package deployment
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"appstore/backend/internal/catalog"
+)
+
+// newValuesCatalog returns a catalog whose postgresql chart has a values.yaml and whose
+// redis chart has none
+func newValuesCatalog(t *testing.T) *catalog.Service {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"catalog.yaml":                "apps:\n  - name: postgresql\n  - name: redis\n",
+		"apps/postgresql/values.yaml": "replicaCount: 1\nauth:\n  database: app\npersistence:\n  size: 8Gi\n",
+		"apps/redis/Chart.yaml":       "apiVersion: v2\nname: redis\nversion: 1.0.0\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := catalog.NewService(filepath.Join(dir, "catalog.yaml"), filepath.Join(dir, "apps"))
+	if err := s.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return s
+}
+
+func TestCreateRejectsUnknownValues(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "known keys",
+			body:       `{"appName":"postgresql","namespace":"team-a","values":{"replicaCount":2,"auth":{"anything":"goes"},"global":{"storageClass":"fast"}}}`,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "unknown keys",
+			body:       `{"appName":"postgresql","namespace":"team-a","values":{"replicaCout":2,"persistance":{"size":"1Gi"},"auth":{}}}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "values persistance, replicaCout are not in the values.yaml of app postgresql",
+		},
+		{
+			name:       "chart without values.yaml",
+			body:       `{"appName":"redis","namespace":"team-a","values":{"anything":true}}`,
+			wantStatus: http.StatusAccepted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakePublisher{}
+			h := NewHandler(publisher, nil, newValuesCatalog(t), nil, nil, UnknownValuesReject, "")
+
+			rec := create(h, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantError != "" {
+				if !strings.Contains(rec.Body.String(), tt.wantError) {
+					t.Errorf("body = %s, want %q", rec.Body, tt.wantError)
+				}
+				if len(publisher.requests) != 0 {
+					t.Errorf("published %d requests, want 0", len(publisher.requests))
+				}
+			}
+		})
+	}
+}
+
+func TestCreateWarnsAboutUnknownValues(t *testing.T) {
+	publisher := &fakePublisher{}
+	h := NewHandler(publisher, nil, newValuesCatalog(t), nil, nil, "", "")
+
+	rec := create(h, `{"appName":"postgresql","namespace":"team-a","values":{"replicaCout":2,"auth":{}}}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	if len(publisher.requests) != 1 {
+		t.Fatalf("published %d requests, want 1", len(publisher.requests))
+	}
+
+	var body struct {
+		Warnings []string `json:"warnings"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"values replicaCout are not in the values.yaml of app postgresql"}
+	if !reflect.DeepEqual(body.Warnings, want) {
+		t.Errorf("warnings = %v, want %v", body.Warnings, want)
+	}
+
+	// No warnings for known keys
+	rec = create(h, `{"appName":"postgresql","namespace":"team-a","values":{"replicaCount":2}}`)
+	if strings.Contains(rec.Body.String(), "warnings") {
+		t.Errorf("body = %s, want no warnings", rec.Body)
+	}
+}
+
+func TestCreateIgnoresUnknownValues(t *testing.T) {
+	h := NewHandler(&fakePublisher{}, nil, newValuesCatalog(t), nil, nil, UnknownValuesIgnore, "")
+
+	rec := create(h, `{"appName":"postgresql","namespace":"team-a","values":{"replicaCout":2}}`)
+	if rec.Code != http.StatusAccepted || strings.Contains(rec.Body.String(), "warnings") {
+		t.Errorf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+}
+
+func TestBatchUnknownValues(t *testing.T) {
+	body := `[{"appName":"postgresql","namespace":"team-a","values":{"replicaCout":2}},` +
+		`{"appName":"postgresql","namespace":"team-b","values":{"replicaCount":2}}]`
+
+	for _, tt := range []struct {
+		mode         UnknownValuesMode
+		wantStatuses []int
+		wantWarnings int
+	}{
+		{UnknownValuesWarn, []int{http.StatusAccepted, http.StatusAccepted}, 1},
+		{UnknownValuesReject, []int{http.StatusBadRequest, http.StatusAccepted}, 0},
+	} {
+		h := NewHandler(&fakePublisher{}, nil, newValuesCatalog(t), nil, nil, tt.mode, "")
+		var resp BatchCreateResponse
+		if err := json.NewDecoder(createBatch(h, body).Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+
+		warnings := 0
+		for i, result := range resp.Results {
+			if result.Status != tt.wantStatuses[i] {
+				t.Errorf("%s: item %d status = %d, want %d", tt.mode, i, result.Status, tt.wantStatuses[i])
+			}
+			warnings += len(result.Warnings)
+		}
+		if warnings != tt.wantWarnings {
+			t.Errorf("%s: %d warnings, want %d", tt.mode, warnings, tt.wantWarnings)
+		}
+	}
+}
+
+func TestParseUnknownValuesMode(t *testing.T) {
+	for _, s := range []string{"warn", "reject", "ignore"} {
+		if mode, err := ParseUnknownValuesMode(s); err != nil || string(mode) != s {
+			t.Errorf("ParseUnknownValuesMode(%q) = %q, %v", s, mode, err)
+		}
+	}
+	if _, err := ParseUnknownValuesMode("strict"); err == nil {
+		t.Error("ParseUnknownValuesMode(strict) error = nil")
+	}
+}
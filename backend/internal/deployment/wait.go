@@ -0,0 +1,73 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"appstore/backend/internal/k8s"
+)
+
+const (
+	// defaultWaitTimeout is how long Wait waits without a timeout parameter
+	defaultWaitTimeout = 30 * time.Second
+	// maxWaitTimeout bounds the timeout parameter of Wait
+	maxWaitTimeout = 5 * time.Minute
+)
+
+// Wait handles GET /api/v1/deployments/{name}/wait
+//
+// Long-polls until the deployment is Deployed or Failed for its current spec, or until the
+// timeout parameter (30s by default, at most 5m) elapses, and responds with the deployment
+// as GET does. After a timeout the deployment is returned in the phase it is in then.
+func (h *Handler) Wait(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		h.respondError(w, http.StatusServiceUnavailable, "Kubernetes not available")
+		return
+	}
+
+	name := r.PathValue("name")
+	if name == "" {
+		h.respondError(w, http.StatusBadRequest, "deployment name is required")
+		return
+	}
+
+	namespace := h.requestNamespace(r)
+
+	timeout := defaultWaitTimeout
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		var err error
+		if timeout, err = time.ParseDuration(v); err != nil || timeout <= 0 {
+			h.respondError(w, http.StatusBadRequest, "timeout must be a positive duration such as 30s")
+			return
+		}
+		timeout = min(timeout, maxWaitTimeout)
+	}
+
+	// The server's write timeout is shorter than a long poll
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + 10*time.Second)); err != nil {
+		h.logger.Debug("failed to extend write deadline", "error", err)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	deployment, err := h.k8sClient.WaitForAppDeployment(ctx, namespace, name)
+	if errors.Is(err, k8s.ErrAppDeploymentDeleted) {
+		h.respondError(w, http.StatusNotFound, "deployment was deleted")
+		return
+	}
+	if k8s.IsNotFound(err) {
+		h.respondError(w, http.StatusNotFound, "deployment not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to wait for deployment", "error", err, "name", name, "namespace", namespace)
+		h.respondError(w, http.StatusInternalServerError, "failed to wait for deployment")
+		return
+	}
+
+	setETag(w, deployment)
+	h.respondJSON(w, http.StatusOK, deployment)
+}
@@ -0,0 +1,162 @@
+package deployment
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"appstore/backend/internal/k8s"
+)
+
+// newWaitTestHandler returns a handler with a deployment in the given phase and a channel
+// receiving the watches started on it
+func newWaitTestHandler(phase string, generation, observedGeneration int64) (*Handler, *unstructured.Unstructured, chan *watch.FakeWatcher) {
+	ad := newAppDeployment("team-a", "db", 42)
+	ad.SetGeneration(generation)
+	ad.Object["status"] = map[string]interface{}{"phase": phase, "observedGeneration": observedGeneration}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{k8s.AppDeploymentGVR: "AppDeploymentList"},
+		ad,
+	)
+	watches := make(chan *watch.FakeWatcher, 1)
+	dynamicClient.PrependWatchReactor("appdeployments", func(clienttesting.Action) (bool, watch.Interface, error) {
+		watcher := watch.NewFake()
+		watches <- watcher
+		return true, watcher, nil
+	})
+
+	h := NewHandler(nil, k8s.NewClientFromInterfaces(dynamicClient, fake.NewClientset()), nil, nil, nil, "", "")
+	return h, ad, watches
+}
+
+// withStatus returns a copy of the deployment in a new phase, as observed for its generation
+func withStatus(ad *unstructured.Unstructured, phase string) *unstructured.Unstructured {
+	updated := ad.DeepCopy()
+	updated.Object["status"] = map[string]interface{}{"phase": phase, "observedGeneration": ad.GetGeneration()}
+	return updated
+}
+
+func wait(h *Handler, query string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/deployments/{name}/wait", h.Wait)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/deployments/db/wait?namespace=team-a"+query, nil))
+	return rec
+}
+
+func decodePhase(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var deployment k8s.AppDeployment
+	if err := json.NewDecoder(rec.Body).Decode(&deployment); err != nil {
+		t.Fatal(err)
+	}
+	return deployment.Phase
+}
+
+func TestWaitReturnsOnTransition(t *testing.T) {
+	for _, phase := range []string{"Deployed", "Failed"} {
+		t.Run(phase, func(t *testing.T) {
+			h, ad, watches := newWaitTestHandler("Installing", 1, 1)
+
+			go func() {
+				watcher := <-watches
+				watcher.Modify(withStatus(ad, "Upgrading"))
+				watcher.Modify(withStatus(ad, phase))
+			}()
+
+			if got := decodePhase(t, wait(h, "&timeout=10s")); got != phase {
+				t.Errorf("phase = %q, want %q", got, phase)
+			}
+		})
+	}
+}
+
+func TestWaitReturnsSettledDeploymentImmediately(t *testing.T) {
+	h, _, watches := newWaitTestHandler("Deployed", 2, 2)
+
+	if got := decodePhase(t, wait(h, "")); got != "Deployed" {
+		t.Errorf("phase = %q, want Deployed", got)
+	}
+	if len(watches) != 0 {
+		t.Error("watched a settled deployment")
+	}
+}
+
+func TestWaitIgnoresStatusOfOldSpec(t *testing.T) {
+	// Deployed, but not yet reconciled since the spec changed
+	h, ad, watches := newWaitTestHandler("Deployed", 3, 2)
+
+	go func() {
+		watcher := <-watches
+		watcher.Modify(withStatus(ad, "Failed"))
+	}()
+
+	if got := decodePhase(t, wait(h, "&timeout=10s")); got != "Failed" {
+		t.Errorf("phase = %q, want Failed", got)
+	}
+}
+
+func TestWaitTimesOut(t *testing.T) {
+	h, _, _ := newWaitTestHandler("Installing", 1, 1)
+
+	if got := decodePhase(t, wait(h, "&timeout=50ms")); got != "Installing" {
+		t.Errorf("phase = %q, want Installing", got)
+	}
+}
+
+func TestWaitDeleted(t *testing.T) {
+	h, ad, watches := newWaitTestHandler("Uninstalling", 1, 1)
+
+	go func() {
+		watcher := <-watches
+		watcher.Delete(ad)
+	}()
+
+	if rec := wait(h, "&timeout=10s"); rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestWaitErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"not found", apierrors.NewNotFound(k8s.AppDeploymentGVR.GroupResource(), "db"), http.StatusNotFound},
+		{"forbidden", apierrors.NewForbidden(k8s.AppDeploymentGVR.GroupResource(), "db", errors.New("denied")), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := wait(newGetErrorHandler(tt.err), "&timeout=1s"); rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWaitRejectsInvalidTimeout(t *testing.T) {
+	h, _, _ := newWaitTestHandler("Installing", 1, 1)
+
+	for _, timeout := range []string{"soon", "0s", "-5s"} {
+		if rec := wait(h, "&timeout="+timeout); rec.Code != http.StatusBadRequest {
+			t.Errorf("timeout=%s status = %d, want %d", timeout, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
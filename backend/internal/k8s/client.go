@@ -2,15 +2,25 @@ package k8s
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+
+	"appstore/backend/pkg/models"
 )
 
 // AppDeploymentGVR is the GroupVersionResource for AppDeployment
@@ -20,6 +30,15 @@ var AppDeploymentGVR = schema.GroupVersionResource{
 	Resource: "appdeployments",
 }
 
+const (
+	// IdempotencyKeyLabel is set by the operator on AppDeployments created with an Idempotency-Key
+	IdempotencyKeyLabel = "appstore.bitpipe.no/idempotency-key"
+	// TeamLabel is set by the operator to the team owning an AppDeployment
+	TeamLabel = "appstore.bitpipe.no/team"
+	// AppLabel is set by the operator to the catalog app of an AppDeployment
+	AppLabel = "appstore.bitpipe.no/app"
+)
+
 // Condition represents a Kubernetes condition
 type Condition struct {
 	Type               string    `json:"type"`
@@ -33,6 +52,7 @@ type Condition struct {
 type AppDeployment struct {
 	Name                 string      `json:"name"`
 	Namespace            string      `json:"namespace"`
+	ResourceVersion      string      `json:"resourceVersion,omitempty"`
 	AppName              string      `json:"appName"`
 	ChartVersion         string      `json:"chartVersion,omitempty"`
 	TeamID               string      `json:"teamId"`
@@ -42,14 +62,45 @@ type AppDeployment struct {
 	HelmReleaseRevision  int64       `json:"helmReleaseRevision,omitempty"`
 	DeployedChartVersion string      `json:"deployedChartVersion,omitempty"`
 	Message              string      `json:"message,omitempty"`
+	Progress             int64       `json:"progress"`
 	Conditions           []Condition `json:"conditions,omitempty"`
 	CreatedAt            time.Time   `json:"createdAt"`
 	LastReconcileTime    *time.Time  `json:"lastReconcileTime,omitempty"`
+
+	// generation and observedGeneration tell whether the status is of the current spec
+	generation         int64
+	observedGeneration int64
+}
+
+// Generation returns the generation of the AppDeployment, which only changes with its spec
+func (d *AppDeployment) Generation() int64 {
+	return d.generation
+}
+
+// Event represents a Kubernetes Event related to an AppDeployment
+type Event struct {
+	Type           string    `json:"type"`
+	Reason         string    `json:"reason"`
+	Message        string    `json:"message"`
+	InvolvedKind   string    `json:"involvedKind"`
+	InvolvedName   string    `json:"involvedName"`
+	Count          int32     `json:"count,omitempty"`
+	FirstTimestamp time.Time `json:"firstTimestamp,omitempty"`
+	LastTimestamp  time.Time `json:"lastTimestamp"`
 }
 
 // Client provides access to Kubernetes resources
 type Client struct {
 	dynamicClient dynamic.Interface
+	clientset     kubernetes.Interface
+
+	// backoff bounds the retries of reads failing with transient errors
+	backoff wait.Backoff
+
+	// AllowedClusters are the API servers, normalized with kubeconfig.NormalizeServer of
+	// appstore/shared, of the remote clusters whose releases may be read for AppDeployments
+	// with spec.clusterRef. Releases in remote clusters can't be read if empty.
+	AllowedClusters []string
 }
 
 // NewClient creates a new Kubernetes client
@@ -76,27 +127,57 @@ func NewClient(kubeconfig string) (*Client, error) {
 		return nil, fmt.Errorf("failed to create kubernetes config: %w", err)
 	}
 
+	return newClientForConfig(config)
+}
+
+// newClientForConfig creates the dynamic and typed clients from a shared rest.Config
+func newClientForConfig(config *rest.Config) (*Client, error) {
 	dynamicClient, err := dynamic.NewForConfig(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
 	}
 
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	return NewClientFromInterfaces(dynamicClient, clientset), nil
+}
+
+// NewClientFromInterfaces creates a client from existing dynamic and typed clients,
+// e.g. fakes in tests
+func NewClientFromInterfaces(dynamicClient dynamic.Interface, clientset kubernetes.Interface) *Client {
 	return &Client{
 		dynamicClient: dynamicClient,
-	}, nil
+		clientset:     clientset,
+		backoff:       defaultBackoff,
+	}
+}
+
+// Clientset returns the typed Kubernetes clientset for core resources
+func (c *Client) Clientset() kubernetes.Interface {
+	return c.clientset
 }
 
 // ListAppDeployments returns all AppDeployments in a namespace (or all namespaces if empty)
 func (c *Client) ListAppDeployments(ctx context.Context, namespace string) ([]AppDeployment, error) {
-	var list *unstructured.UnstructuredList
-	var err error
-
-	if namespace != "" {
-		list, err = c.dynamicClient.Resource(AppDeploymentGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
-	} else {
-		list, err = c.dynamicClient.Resource(AppDeploymentGVR).List(ctx, metav1.ListOptions{})
-	}
+	return c.ListAppDeploymentsWithLabels(ctx, namespace, nil)
+}
 
+// ListAppDeploymentsWithLabels returns the AppDeployments in a namespace (or all namespaces
+// if empty) having all the given labels. The labels are selected by the API server.
+func (c *Client) ListAppDeploymentsWithLabels(ctx context.Context, namespace string, set labels.Set) ([]AppDeployment, error) {
+	opts := metav1.ListOptions{LabelSelector: labels.SelectorFromSet(set).String()}
+	var list *unstructured.UnstructuredList
+	err := c.withRetry(ctx, func() (err error) {
+		if namespace != "" {
+			list, err = c.dynamicClient.Resource(AppDeploymentGVR).Namespace(namespace).List(ctx, opts)
+		} else {
+			list, err = c.dynamicClient.Resource(AppDeploymentGVR).List(ctx, opts)
+		}
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list AppDeployments: %w", err)
 	}
@@ -115,7 +196,7 @@ func (c *Client) ListAppDeployments(ctx context.Context, namespace string) ([]Ap
 
 // GetAppDeployment returns a specific AppDeployment
 func (c *Client) GetAppDeployment(ctx context.Context, namespace, name string) (*AppDeployment, error) {
-	item, err := c.dynamicClient.Resource(AppDeploymentGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	item, err := c.getAppDeployment(ctx, namespace, name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get AppDeployment: %w", err)
 	}
@@ -123,11 +204,223 @@ func (c *Client) GetAppDeployment(ctx context.Context, namespace, name string) (
 	return parseAppDeployment(item)
 }
 
+// GetAppDeploymentValues returns the spec.values of an AppDeployment along with its
+// generation, or nil values if it has none
+func (c *Client) GetAppDeploymentValues(ctx context.Context, namespace, name string) (map[string]interface{}, int64, error) {
+	item, err := c.getAppDeployment(ctx, namespace, name)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get AppDeployment: %w", err)
+	}
+
+	values, _, err := unstructured.NestedMap(item.Object, "spec", "values")
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid spec.values: %w", err)
+	}
+	return values, item.GetGeneration(), nil
+}
+
+// AppDeploymentSpec is the part of an AppDeployment's spec that a copy of it is created from
+type AppDeploymentSpec struct {
+	AppName       string
+	ChartVersion  string
+	TeamID        string
+	Values        map[string]interface{}
+	SecretKeyRefs []models.SecretKeyRef
+}
+
+// GetAppDeploymentSpec returns the spec of an AppDeployment
+func (c *Client) GetAppDeploymentSpec(ctx context.Context, namespace, name string) (*AppDeploymentSpec, error) {
+	item, err := c.getAppDeployment(ctx, namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AppDeployment: %w", err)
+	}
+
+	spec := &AppDeploymentSpec{}
+	spec.AppName, _, _ = unstructured.NestedString(item.Object, "spec", "appName")
+	spec.ChartVersion, _, _ = unstructured.NestedString(item.Object, "spec", "chartVersion")
+	spec.TeamID, _, _ = unstructured.NestedString(item.Object, "spec", "teamId")
+
+	spec.Values, _, err = unstructured.NestedMap(item.Object, "spec", "values")
+	if err != nil {
+		return nil, fmt.Errorf("invalid spec.values: %w", err)
+	}
+
+	refs, _, err := unstructured.NestedSlice(item.Object, "spec", "secretKeyRefs")
+	if err != nil {
+		return nil, fmt.Errorf("invalid spec.secretKeyRefs: %w", err)
+	}
+	for _, ref := range refs {
+		refMap, ok := ref.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid spec.secretKeyRefs: %v is not an object", ref)
+		}
+		var secretKeyRef models.SecretKeyRef
+		secretKeyRef.Path, _, _ = unstructured.NestedString(refMap, "path")
+		secretKeyRef.Name, _, _ = unstructured.NestedString(refMap, "name")
+		secretKeyRef.Key, _, _ = unstructured.NestedString(refMap, "key")
+		secretKeyRef.Optional, _, _ = unstructured.NestedBool(refMap, "optional")
+		spec.SecretKeyRefs = append(spec.SecretKeyRefs, secretKeyRef)
+	}
+
+	return spec, nil
+}
+
+// ListDeploymentEvents returns Events involving an AppDeployment and the objects of its
+// Helm release, most recent first. If eventType is set, only events of that type are returned.
+func (c *Client) ListDeploymentEvents(ctx context.Context, namespace, name, eventType string) ([]Event, error) {
+	item, err := c.getAppDeployment(ctx, namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AppDeployment: %w", err)
+	}
+
+	// Resources rendered by the chart are named after the release (Helm "fullname" convention)
+	releaseName, _, _ := unstructured.NestedString(item.Object, "status", "helmReleaseName")
+	if releaseName == "" {
+		releaseName = item.GetName()
+	}
+
+	var list *corev1.EventList
+	err = c.withRetry(ctx, func() (err error) {
+		list, err = c.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+
+	events := []Event{}
+	for _, e := range list.Items {
+		if eventType != "" && e.Type != eventType {
+			continue
+		}
+		if !involvesDeployment(&e, item, releaseName) {
+			continue
+		}
+		events = append(events, toEvent(&e))
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].LastTimestamp.After(events[j].LastTimestamp)
+	})
+
+	return events, nil
+}
+
+// involvesDeployment checks if an event is about the AppDeployment itself or one of its release's objects
+func involvesDeployment(e *corev1.Event, item *unstructured.Unstructured, releaseName string) bool {
+	obj := e.InvolvedObject
+	if obj.Kind == "AppDeployment" {
+		return obj.Name == item.GetName() || (obj.UID != "" && obj.UID == item.GetUID())
+	}
+	return obj.Name == releaseName || strings.HasPrefix(obj.Name, releaseName+"-")
+}
+
+// toEvent converts a core Event, falling back to the series/event time when LastTimestamp is unset
+func toEvent(e *corev1.Event) Event {
+	last := e.LastTimestamp.Time
+	if last.IsZero() {
+		last = e.EventTime.Time
+	}
+	if last.IsZero() {
+		last = e.FirstTimestamp.Time
+	}
+
+	return Event{
+		Type:           e.Type,
+		Reason:         e.Reason,
+		Message:        e.Message,
+		InvolvedKind:   e.InvolvedObject.Kind,
+		InvolvedName:   e.InvolvedObject.Name,
+		Count:          e.Count,
+		FirstTimestamp: e.FirstTimestamp.UTC(),
+		LastTimestamp:  last.UTC(),
+	}
+}
+
+// FindAppDeploymentByIdempotencyKey returns the AppDeployment created with the given
+// Idempotency-Key, or nil if there is none
+func (c *Client) FindAppDeploymentByIdempotencyKey(ctx context.Context, namespace, key string) (*AppDeployment, error) {
+	var list *unstructured.UnstructuredList
+	err := c.withRetry(ctx, func() (err error) {
+		list, err = c.dynamicClient.Resource(AppDeploymentGVR).Namespace(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: labels.SelectorFromSet(labels.Set{IdempotencyKeyLabel: key}).String(),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list AppDeployments: %w", err)
+	}
+
+	for _, item := range list.Items {
+		if deployment, err := parseAppDeployment(&item); err == nil {
+			return deployment, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// CountTeamAppDeployments returns the number of AppDeployments of a team in all namespaces,
+// not counting those being deleted
+func (c *Client) CountTeamAppDeployments(ctx context.Context, teamID string) (int, error) {
+	var list *unstructured.UnstructuredList
+	err := c.withRetry(ctx, func() (err error) {
+		list, err = c.dynamicClient.Resource(AppDeploymentGVR).List(ctx, metav1.ListOptions{
+			LabelSelector: labels.SelectorFromSet(labels.Set{TeamLabel: teamID}).String(),
+		})
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list AppDeployments: %w", err)
+	}
+
+	count := 0
+	for _, item := range list.Items {
+		if item.GetDeletionTimestamp() == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetConfigMapData returns the data of a ConfigMap, or nil if it does not exist
+func (c *Client) GetConfigMapData(ctx context.Context, namespace, name string) (map[string]string, error) {
+	var configMap *corev1.ConfigMap
+	err := c.withRetry(ctx, func() (err error) {
+		configMap, err = c.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ConfigMap %s/%s: %w", namespace, name, err)
+	}
+	return configMap.Data, nil
+}
+
+// IsNotFound reports whether err is caused by a missing AppDeployment or Helm release
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrReleaseNotFound) || apierrors.IsNotFound(err)
+}
+
+// getAppDeployment fetches an AppDeployment, retrying transient errors
+func (c *Client) getAppDeployment(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	var item *unstructured.Unstructured
+	err := c.withRetry(ctx, func() (err error) {
+		item, err = c.dynamicClient.Resource(AppDeploymentGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	return item, err
+}
+
 func parseAppDeployment(item *unstructured.Unstructured) (*AppDeployment, error) {
 	deployment := &AppDeployment{
-		Name:      item.GetName(),
-		Namespace: item.GetNamespace(),
-		CreatedAt: item.GetCreationTimestamp().Time,
+		Name:            item.GetName(),
+		Namespace:       item.GetNamespace(),
+		ResourceVersion: item.GetResourceVersion(),
+		CreatedAt:       item.GetCreationTimestamp().UTC(),
+		generation:      item.GetGeneration(),
 	}
 
 	// Parse spec
@@ -167,10 +460,16 @@ func parseAppDeployment(item *unstructured.Unstructured) (*AppDeployment, error)
 		if message, ok := status["message"].(string); ok {
 			deployment.Message = message
 		}
+		if progress, ok := status["progress"].(int64); ok {
+			deployment.Progress = progress
+		}
+		if observedGeneration, ok := status["observedGeneration"].(int64); ok {
+			deployment.observedGeneration = observedGeneration
+		}
 
 		// Parse lastReconcileTime
 		if lastReconcileTime, ok := status["lastReconcileTime"].(string); ok {
-			if t, err := time.Parse(time.RFC3339, lastReconcileTime); err == nil {
+			if t, err := parseTime(lastReconcileTime); err == nil {
 				deployment.LastReconcileTime = &t
 			}
 		}
@@ -193,7 +492,7 @@ func parseAppDeployment(item *unstructured.Unstructured) (*AppDeployment, error)
 						cond.Message = m
 					}
 					if ltt, ok := condMap["lastTransitionTime"].(string); ok {
-						if t, err := time.Parse(time.RFC3339, ltt); err == nil {
+						if t, err := parseTime(ltt); err == nil {
 							cond.LastTransitionTime = t
 						}
 					}
@@ -0,0 +1,81 @@
+package k8s
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const fakeKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: fake
+  cluster:
+    server: https://127.0.0.1:6443
+    insecure-skip-tls-verify: true
+contexts:
+- name: fake
+  context:
+    cluster: fake
+    user: fake
+current-context: fake
+users:
+- name: fake
+  user:
+    token: fake-token
+`
+
+func TestNewClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(path, []byte(fakeKubeconfig), 0600); err != nil {
+		t.Fatalf("failed to write kubeconfig: %v", err)
+	}
+
+	c, err := NewClient(path)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	if c.dynamicClient == nil {
+		t.Error("expected dynamic client to be set")
+	}
+	if c.Clientset() == nil {
+		t.Error("expected clientset to be set")
+	}
+}
+
+func TestNewClientInvalidKubeconfig(t *testing.T) {
+	if _, err := NewClient(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected error for missing kubeconfig")
+	}
+}
+
+func TestGetAppDeploymentProgress(t *testing.T) {
+	c := newTestClient([]runtime.Object{
+		newAppDeploymentObject("team-a", "my-db", map[string]interface{}{
+			"phase":    "Deployed",
+			"progress": int64(90),
+		}),
+		newAppDeploymentObject("team-a", "new-db", nil),
+	})
+
+	deployment, err := c.GetAppDeployment(context.Background(), "team-a", "my-db")
+	if err != nil {
+		t.Fatalf("GetAppDeployment() error = %v", err)
+	}
+	if deployment.Phase != "Deployed" || deployment.Progress != 90 {
+		t.Errorf("GetAppDeployment() phase = %q, progress = %d, want Deployed, 90", deployment.Phase, deployment.Progress)
+	}
+
+	// Not yet reconciled
+	deployment, err = c.GetAppDeployment(context.Background(), "team-a", "new-db")
+	if err != nil {
+		t.Fatalf("GetAppDeployment() error = %v", err)
+	}
+	if deployment.Progress != 0 {
+		t.Errorf("GetAppDeployment() progress = %d, want 0", deployment.Progress)
+	}
+}
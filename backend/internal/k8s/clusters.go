@@ -0,0 +1,67 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+
+	"appstore/shared/kubeconfig"
+)
+
+// defaultClusterRefKey is the Secret key holding the kubeconfig of a clusterRef without a key
+const defaultClusterRefKey = "kubeconfig"
+
+// ErrRemoteCluster is returned when an AppDeployment's release is installed into the
+// remote cluster of its spec.clusterRef and the cluster can't be read, e.g. because it is
+// not in AllowedClusters or its kubeconfig Secret is missing or invalid
+var ErrRemoteCluster = errors.New("remote cluster can't be read")
+
+// releaseClientset returns the clientset of the cluster an AppDeployment's release is
+// installed into: the remote cluster of its spec.clusterRef, whose kubeconfig is read from
+// the Secret in the AppDeployment's namespace, or else the backend's own cluster. The
+// kubeconfig is checked like the operator checks it.
+func (c *Client) releaseClientset(ctx context.Context, item *unstructured.Unstructured) (kubernetes.Interface, error) {
+	ref, found, err := unstructured.NestedStringMap(item.Object, "spec", "clusterRef")
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid spec.clusterRef: %v", ErrRemoteCluster, err)
+	}
+	if !found {
+		return c.clientset, nil
+	}
+	if len(c.AllowedClusters) == 0 {
+		return nil, fmt.Errorf("%w: no remote clusters are allowed", ErrRemoteCluster)
+	}
+
+	key := ref["key"]
+	if key == "" {
+		key = defaultClusterRefKey
+	}
+	var secret *corev1.Secret
+	err = c.withRetry(ctx, func() (err error) {
+		secret, err = c.clientset.CoreV1().Secrets(item.GetNamespace()).Get(ctx, ref["secretName"], metav1.GetOptions{})
+		return err
+	})
+	if err != nil {
+		// Not wrapped, a missing kubeconfig Secret doesn't mean the deployment is missing
+		return nil, fmt.Errorf("%w: failed to get kubeconfig secret %s: %v", ErrRemoteCluster, ref["secretName"], err)
+	}
+	data, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: kubeconfig secret %s has no key %s", ErrRemoteCluster, ref["secretName"], key)
+	}
+
+	_, restConfig, err := kubeconfig.Load(data, c.AllowedClusters)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRemoteCluster, err)
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to create clientset: %v", ErrRemoteCluster, err)
+	}
+	return clientset, nil
+}
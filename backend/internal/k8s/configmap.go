@@ -0,0 +1,64 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// WatchConfigMap calls onChange with the data of a ConfigMap whenever it is created or
+// changes, until ctx is done or the watch ends, e.g. because it expired. onChange is
+// first called with the current data if the ConfigMap exists. Deleting the ConfigMap
+// doesn't call onChange.
+func (c *Client) WatchConfigMap(ctx context.Context, namespace, name string, onChange func(data map[string]string)) error {
+	var configMap *corev1.ConfigMap
+	err := c.withRetry(ctx, func() (err error) {
+		configMap, err = c.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	resourceVersion := ""
+	switch {
+	case err == nil:
+		onChange(configMap.Data)
+		resourceVersion = configMap.ResourceVersion
+	case !apierrors.IsNotFound(err):
+		return fmt.Errorf("failed to get ConfigMap %s/%s: %w", namespace, name, err)
+	}
+
+	var watcher watch.Interface
+	err = c.withRetry(ctx, func() (err error) {
+		watcher, err = c.clientset.CoreV1().ConfigMaps(namespace).Watch(ctx, metav1.ListOptions{
+			FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+			ResourceVersion: resourceVersion,
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch ConfigMap %s/%s: %w", namespace, name, err)
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				if configMap, ok := event.Object.(*corev1.ConfigMap); ok && configMap.Name == name {
+					onChange(configMap.Data)
+				}
+			case watch.Error:
+				return fmt.Errorf("failed to watch ConfigMap %s/%s: %w", namespace, name, apierrors.FromObject(event.Object))
+			}
+		}
+	}
+}
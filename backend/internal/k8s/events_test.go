@@ -0,0 +1,109 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newAppDeploymentObject(namespace, name string, status map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "appstore.bitpipe.no/v1alpha1",
+		"kind":       "AppDeployment",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+			"uid":       "uid-" + name,
+		},
+		"spec": map[string]interface{}{
+			"appName": "postgresql",
+			"teamId":  "team-a",
+		},
+	}}
+	if status != nil {
+		obj.Object["status"] = status
+	}
+	return obj
+}
+
+func newTestClient(objects []runtime.Object, coreObjects ...runtime.Object) *Client {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{AppDeploymentGVR: "AppDeploymentList"},
+		objects...,
+	)
+	return NewClientFromInterfaces(dynamicClient, fake.NewClientset(coreObjects...))
+}
+
+func newEvent(name, eventType, kind, objName string, last time.Time) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "team-a"},
+		Type:           eventType,
+		Reason:         "Reason-" + name,
+		Message:        "message " + name,
+		InvolvedObject: corev1.ObjectReference{Kind: kind, Name: objName, Namespace: "team-a"},
+		LastTimestamp:  metav1.NewTime(last),
+	}
+}
+
+func TestListDeploymentEvents(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	c := newTestClient(
+		[]runtime.Object{newAppDeploymentObject("team-a", "my-db", map[string]interface{}{
+			"helmReleaseName": "my-db",
+		})},
+		newEvent("ad", corev1.EventTypeNormal, "AppDeployment", "my-db", now.Add(-3*time.Minute)),
+		newEvent("pod", corev1.EventTypeWarning, "Pod", "my-db-postgresql-0", now.Add(-1*time.Minute)),
+		newEvent("sts", corev1.EventTypeNormal, "StatefulSet", "my-db-postgresql", now.Add(-2*time.Minute)),
+		newEvent("other", corev1.EventTypeWarning, "Pod", "my-dbx-0", now),
+		newEvent("unrelated", corev1.EventTypeWarning, "Pod", "cache-0", now),
+	)
+
+	events, err := c.ListDeploymentEvents(context.Background(), "team-a", "my-db", "")
+	if err != nil {
+		t.Fatalf("ListDeploymentEvents() error = %v", err)
+	}
+
+	want := []string{"Reason-pod", "Reason-sts", "Reason-ad"}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, reason := range want {
+		if events[i].Reason != reason {
+			t.Errorf("events[%d].Reason = %s, want %s", i, events[i].Reason, reason)
+		}
+	}
+}
+
+func TestListDeploymentEventsTypeFilter(t *testing.T) {
+	now := time.Now()
+	c := newTestClient(
+		[]runtime.Object{newAppDeploymentObject("team-a", "my-db", nil)},
+		newEvent("ad", corev1.EventTypeNormal, "AppDeployment", "my-db", now),
+		newEvent("pod", corev1.EventTypeWarning, "Pod", "my-db-0", now),
+	)
+
+	events, err := c.ListDeploymentEvents(context.Background(), "team-a", "my-db", corev1.EventTypeWarning)
+	if err != nil {
+		t.Fatalf("ListDeploymentEvents() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Type != corev1.EventTypeWarning {
+		t.Fatalf("expected only the warning event, got %+v", events)
+	}
+}
+
+func TestListDeploymentEventsNotFound(t *testing.T) {
+	c := newTestClient(nil)
+
+	if _, err := c.ListDeploymentEvents(context.Background(), "team-a", "missing", ""); err == nil {
+		t.Error("expected error for missing AppDeployment")
+	}
+}
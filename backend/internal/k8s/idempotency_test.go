@@ -0,0 +1,37 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestFindAppDeploymentByIdempotencyKey(t *testing.T) {
+	labelled := newAppDeploymentObject("team-a", "postgresql-1a2b3c4d", nil)
+	labelled.SetLabels(map[string]string{IdempotencyKeyLabel: "create-db-1"})
+	otherNamespace := newAppDeploymentObject("team-b", "postgresql-1a2b3c4d", nil)
+	otherNamespace.SetLabels(map[string]string{IdempotencyKeyLabel: "create-db-1"})
+
+	c := newTestClient([]runtime.Object{
+		labelled,
+		otherNamespace,
+		newAppDeploymentObject("team-a", "unlabelled", nil),
+	})
+
+	got, err := c.FindAppDeploymentByIdempotencyKey(context.Background(), "team-a", "create-db-1")
+	if err != nil {
+		t.Fatalf("FindAppDeploymentByIdempotencyKey() error = %v", err)
+	}
+	if got == nil || got.Name != "postgresql-1a2b3c4d" || got.Namespace != "team-a" {
+		t.Fatalf("FindAppDeploymentByIdempotencyKey() = %+v, want team-a/postgresql-1a2b3c4d", got)
+	}
+
+	got, err = c.FindAppDeploymentByIdempotencyKey(context.Background(), "team-a", "unknown")
+	if err != nil {
+		t.Fatalf("FindAppDeploymentByIdempotencyKey() error = %v", err)
+	}
+	if got != nil {
+		t.Errorf("FindAppDeploymentByIdempotencyKey() = %+v, want nil", got)
+	}
+}
@@ -0,0 +1,207 @@
+package k8s
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ReleaseRevision is a revision of a Helm release as stored by Helm
+type ReleaseRevision struct {
+	Revision int    `json:"revision"`
+	Status   string `json:"status"`
+	// Values are the values the revision was installed or upgraded with, excluding the
+	// chart's defaults. They include the values of Secrets in plain text.
+	Values map[string]interface{} `json:"-"`
+}
+
+// ReleaseHistory is the stored revisions of an AppDeployment's Helm release
+type ReleaseHistory struct {
+	ReleaseName string
+	// Revisions are ordered oldest first. Helm prunes old revisions, so they may not start at 1.
+	Revisions []ReleaseRevision
+}
+
+// Revision returns the stored revision with the given number, or nil
+func (h *ReleaseHistory) Revision(revision int) *ReleaseRevision {
+	for i := range h.Revisions {
+		if h.Revisions[i].Revision == revision {
+			return &h.Revisions[i]
+		}
+	}
+	return nil
+}
+
+// ReleaseManifest is the manifest of the latest revision of an AppDeployment's Helm release
+type ReleaseManifest struct {
+	ReleaseName string
+	Revision    int
+	// Manifest is the YAML of the resources rendered by the chart, as applied by Helm
+	Manifest string
+}
+
+// ErrReleaseNotFound is returned when an AppDeployment has no stored Helm release
+var ErrReleaseNotFound = errors.New("Helm release not found")
+
+// storedRelease is the part of a release stored by Helm's Secret driver read here
+type storedRelease struct {
+	Version int                    `json:"version"`
+	Config  map[string]interface{} `json:"config"`
+	Info    struct {
+		Status string `json:"status"`
+	} `json:"info"`
+}
+
+// gzipMagic prefixes gzip-compressed releases
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+// GetReleaseHistory returns the revisions of an AppDeployment's Helm release, read from the
+// Secrets in which Helm stores them in the release namespace
+func (c *Client) GetReleaseHistory(ctx context.Context, namespace, name string) (*ReleaseHistory, error) {
+	item, err := c.getAppDeployment(ctx, namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AppDeployment: %w", err)
+	}
+
+	history := &ReleaseHistory{ReleaseName: releaseName(item)}
+
+	secrets, err := c.listReleaseSecrets(ctx, item, history.ReleaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, secret := range secrets {
+		release, err := decodeRelease(secret.Data["release"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode release secret %s: %w", secret.Name, err)
+		}
+		history.Revisions = append(history.Revisions, ReleaseRevision{
+			Revision: release.Version,
+			Status:   release.Info.Status,
+			Values:   release.Config,
+		})
+	}
+	sort.Slice(history.Revisions, func(i, j int) bool {
+		return history.Revisions[i].Revision < history.Revisions[j].Revision
+	})
+
+	return history, nil
+}
+
+// GetReleaseManifest returns the manifest of the latest stored revision of an
+// AppDeployment's Helm release. Only that revision is decoded.
+func (c *Client) GetReleaseManifest(ctx context.Context, namespace, name string) (*ReleaseManifest, error) {
+	item, err := c.getAppDeployment(ctx, namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AppDeployment: %w", err)
+	}
+
+	manifest := &ReleaseManifest{ReleaseName: releaseName(item)}
+	secrets, err := c.listReleaseSecrets(ctx, item, manifest.ReleaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Helm labels the Secret of each revision with its number
+	var latest *corev1.Secret
+	for i := range secrets {
+		revision, err := strconv.Atoi(secrets[i].Labels["version"])
+		if err != nil {
+			continue
+		}
+		if latest == nil || revision > manifest.Revision {
+			latest, manifest.Revision = &secrets[i], revision
+		}
+	}
+	if latest == nil {
+		return nil, ErrReleaseNotFound
+	}
+
+	var release struct {
+		Manifest string `json:"manifest"`
+	}
+	if err := readRelease(latest.Data["release"], &release); err != nil {
+		return nil, fmt.Errorf("failed to decode release secret %s: %w", latest.Name, err)
+	}
+	manifest.Manifest = release.Manifest
+
+	return manifest, nil
+}
+
+// releaseName returns the name of an AppDeployment's Helm release
+func releaseName(item *unstructured.Unstructured) string {
+	if name, _, _ := unstructured.NestedString(item.Object, "status", "helmReleaseName"); name != "" {
+		return name
+	}
+	return item.GetName()
+}
+
+// listReleaseSecrets returns the Secrets in which Helm stores the revisions of an
+// AppDeployment's release, in the namespace of the AppDeployment in the cluster the release
+// is installed into
+func (c *Client) listReleaseSecrets(ctx context.Context, item *unstructured.Unstructured, releaseName string) ([]corev1.Secret, error) {
+	clientset, err := c.releaseClientset(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{"owner": "helm", "name": releaseName})
+	var secrets *corev1.SecretList
+	err = c.withRetry(ctx, func() (err error) {
+		secrets, err = clientset.CoreV1().Secrets(item.GetNamespace()).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list release secrets: %w", err)
+	}
+	return secrets.Items, nil
+}
+
+// decodeRelease decodes a release stored by Helm
+func decodeRelease(data []byte) (*storedRelease, error) {
+	var release storedRelease
+	if err := readRelease(data, &release); err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// readRelease decodes a release stored by Helm, base64-encoded JSON that is usually
+// gzipped, into v. The release is decoded as a stream rather than copied at every step,
+// as manifests can be large.
+func readRelease(data []byte, v interface{}) error {
+	r := bufio.NewReader(base64.NewDecoder(base64.StdEncoding, bytes.NewReader(data)))
+
+	var decoder *json.Decoder
+	if magic, err := r.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("invalid gzip: %w", err)
+		}
+		defer gz.Close()
+		decoder = json.NewDecoder(gz)
+	} else {
+		decoder = json.NewDecoder(r)
+	}
+
+	if err := decoder.Decode(v); err != nil {
+		var corrupt base64.CorruptInputError
+		if errors.As(err, &corrupt) {
+			return fmt.Errorf("invalid base64: %w", err)
+		}
+		return fmt.Errorf("invalid release JSON: %w", err)
+	}
+	return nil
+}
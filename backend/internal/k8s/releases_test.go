@@ -0,0 +1,194 @@
+package k8s
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// newReleaseSecret stores a release revision the way Helm's Secret driver does
+func newReleaseSecret(t *testing.T, namespace, release string, revision int, config map[string]interface{}, compress bool) *corev1.Secret {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"name":     release,
+		"version":  revision,
+		"config":   config,
+		"info":     map[string]interface{}{"status": "deployed"},
+		"manifest": fmt.Sprintf("---\n# Source: %s/templates/configmap.yaml\n# revision %d\n", release, revision),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compress {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(data)
+		w.Close()
+		data = buf.Bytes()
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("sh.helm.release.v1.%s.v%d", release, revision),
+			Namespace: namespace,
+			Labels:    map[string]string{"owner": "helm", "name": release, "version": fmt.Sprint(revision)},
+		},
+		Type: "helm.sh/release.v1",
+		Data: map[string][]byte{"release": []byte(base64.StdEncoding.EncodeToString(data))},
+	}
+}
+
+func TestGetReleaseHistory(t *testing.T) {
+	deployment := newAppDeploymentObject("team-a", "my-db", map[string]interface{}{"helmReleaseName": "db-release"})
+	c := newTestClient(
+		[]runtime.Object{deployment},
+		newReleaseSecret(t, "team-a", "db-release", 2, map[string]interface{}{"replicaCount": 2}, true),
+		newReleaseSecret(t, "team-a", "db-release", 1, map[string]interface{}{"replicaCount": 1}, false),
+		newReleaseSecret(t, "team-a", "other", 1, map[string]interface{}{"other": true}, true),
+	)
+
+	history, err := c.GetReleaseHistory(context.Background(), "team-a", "my-db")
+	if err != nil {
+		t.Fatalf("GetReleaseHistory() error = %v", err)
+	}
+	if history.ReleaseName != "db-release" {
+		t.Errorf("ReleaseName = %q, want db-release", history.ReleaseName)
+	}
+	want := []ReleaseRevision{
+		{Revision: 1, Status: "deployed", Values: map[string]interface{}{"replicaCount": float64(1)}},
+		{Revision: 2, Status: "deployed", Values: map[string]interface{}{"replicaCount": float64(2)}},
+	}
+	if !reflect.DeepEqual(history.Revisions, want) {
+		t.Errorf("Revisions = %+v, want %+v", history.Revisions, want)
+	}
+	if history.Revision(2) == nil || history.Revision(3) != nil {
+		t.Errorf("Revision() found the wrong revisions")
+	}
+}
+
+func TestGetReleaseHistoryCorrupt(t *testing.T) {
+	secret := newReleaseSecret(t, "team-a", "my-db", 1, nil, true)
+	secret.Data["release"] = []byte("not base64!")
+	c := newTestClient([]runtime.Object{newAppDeploymentObject("team-a", "my-db", nil)}, secret)
+
+	if _, err := c.GetReleaseHistory(context.Background(), "team-a", "my-db"); err == nil {
+		t.Error("GetReleaseHistory() succeeded with a corrupt release")
+	}
+}
+
+func TestGetReleaseManifest(t *testing.T) {
+	c := newTestClient(
+		[]runtime.Object{
+			newAppDeploymentObject("team-a", "my-db", map[string]interface{}{"helmReleaseName": "db-release"}),
+			newAppDeploymentObject("team-a", "new-db", nil),
+		},
+		newReleaseSecret(t, "team-a", "db-release", 9, nil, true),
+		newReleaseSecret(t, "team-a", "db-release", 10, nil, true),
+		newReleaseSecret(t, "team-a", "db-release", 2, nil, false),
+	)
+
+	manifest, err := c.GetReleaseManifest(context.Background(), "team-a", "my-db")
+	if err != nil {
+		t.Fatalf("GetReleaseManifest() error = %v", err)
+	}
+	want := &ReleaseManifest{
+		ReleaseName: "db-release",
+		Revision:    10,
+		Manifest:    "---\n# Source: db-release/templates/configmap.yaml\n# revision 10\n",
+	}
+	if !reflect.DeepEqual(manifest, want) {
+		t.Errorf("GetReleaseManifest() = %+v, want %+v", manifest, want)
+	}
+
+	if _, err := c.GetReleaseManifest(context.Background(), "team-a", "new-db"); !errors.Is(err, ErrReleaseNotFound) {
+		t.Errorf("GetReleaseManifest() of a deployment without a release error = %v, want ErrReleaseNotFound", err)
+	}
+}
+
+// remoteKubeConfig returns a kubeconfig for a test API server, trusting its certificate
+func remoteKubeConfig(server *httptest.Server) []byte {
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: remote
+  cluster:
+    server: %s
+    certificate-authority-data: %s
+contexts:
+- name: remote
+  context:
+    cluster: remote
+    user: remote
+current-context: remote
+users:
+- name: remote
+  user:
+    token: remote-token
+`, server.URL, base64.StdEncoding.EncodeToString(ca)))
+}
+
+func TestGetReleaseManifestRemoteCluster(t *testing.T) {
+	remoteSecret := newReleaseSecret(t, "team-a", "my-db", 3, nil, true)
+	var authorization []string
+	apiServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(corev1.SecretList{
+			TypeMeta: metav1.TypeMeta{Kind: "SecretList", APIVersion: "v1"},
+			Items:    []corev1.Secret{*remoteSecret},
+		})
+	}))
+	defer apiServer.Close()
+
+	deployment := newAppDeploymentObject("team-a", "my-db", nil)
+	deployment.Object["spec"].(map[string]interface{})["clusterRef"] = map[string]interface{}{"secretName": "edge"}
+	kubeconfigSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge", Namespace: "team-a"},
+		Data:       map[string][]byte{"kubeconfig": remoteKubeConfig(apiServer)},
+	}
+	// A local release of the same name must not be read
+	local := newReleaseSecret(t, "team-a", "my-db", 7, nil, true)
+	c := newTestClient([]runtime.Object{deployment}, kubeconfigSecret, local)
+
+	if _, err := c.GetReleaseManifest(context.Background(), "team-a", "my-db"); !errors.Is(err, ErrRemoteCluster) {
+		t.Errorf("GetReleaseManifest() without allowed clusters error = %v, want ErrRemoteCluster", err)
+	}
+
+	c.AllowedClusters = []string{"https://other.example.com"}
+	if _, err := c.GetReleaseManifest(context.Background(), "team-a", "my-db"); !errors.Is(err, ErrRemoteCluster) {
+		t.Errorf("GetReleaseManifest() of a cluster not allowed error = %v, want ErrRemoteCluster", err)
+	}
+
+	c.AllowedClusters = []string{apiServer.URL}
+	manifest, err := c.GetReleaseManifest(context.Background(), "team-a", "my-db")
+	if err != nil {
+		t.Fatalf("GetReleaseManifest() error = %v", err)
+	}
+	if manifest.Revision != 3 {
+		t.Errorf("Revision = %d, want 3 from the remote cluster", manifest.Revision)
+	}
+	if len(authorization) != 1 || authorization[0] != "Bearer remote-token" {
+		t.Errorf("remote requests authorized with %v, want the kubeconfig's token", authorization)
+	}
+
+	kubeconfigSecret.Data = map[string][]byte{"config": remoteKubeConfig(apiServer)}
+	c = newTestClient([]runtime.Object{deployment}, kubeconfigSecret)
+	c.AllowedClusters = []string{apiServer.URL}
+	if _, err := c.GetReleaseManifest(context.Background(), "team-a", "my-db"); !errors.Is(err, ErrRemoteCluster) || IsNotFound(err) {
+		t.Errorf("GetReleaseManifest() without the kubeconfig key error = %v, want ErrRemoteCluster", err)
+	}
+}
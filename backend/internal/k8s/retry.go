@@ -0,0 +1,62 @@
+package k8s
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// defaultBackoff retries up to 4 times, waiting 100ms, 200ms, 400ms and 800ms (with jitter)
+var defaultBackoff = wait.Backoff{
+	Duration: 100 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    5,
+	Cap:      2 * time.Second,
+}
+
+// isRetryable reports whether an API error is likely transient
+func isRetryable(err error) bool {
+	return apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) ||
+		apierrors.IsUnexpectedServerError(err) ||
+		utilnet.IsConnectionReset(err) ||
+		utilnet.IsProbableEOF(err)
+}
+
+// withRetry calls fn until it succeeds, fails with a non-retryable error, the backoff
+// is exhausted or ctx is done. The last error is returned. No wait is longer than the
+// backoff's cap, also when the server's Retry-After asks for more.
+func (c *Client) withRetry(ctx context.Context, fn func() error) error {
+	backoff := c.backoff
+	for {
+		err := fn()
+		if err == nil || !isRetryable(err) || backoff.Steps <= 1 {
+			return err
+		}
+
+		// Honor the server's Retry-After if it asks for a longer wait, up to the backoff's
+		// cap so that a request isn't held up for as long as the server likes
+		delay := backoff.Step()
+		if seconds, ok := apierrors.SuggestsClientDelay(err); ok && time.Duration(seconds)*time.Second > delay {
+			delay = time.Duration(seconds) * time.Second
+			if backoff.Cap > 0 && delay > backoff.Cap {
+				delay = backoff.Cap
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var appDeploymentsResource = schema.GroupResource{Group: AppDeploymentGVR.Group, Resource: AppDeploymentGVR.Resource}
+
+// failFirst makes the first n calls of verb on AppDeployments fail with err and counts all calls
+func failFirst(c *Client, verb string, n int, err error) *int {
+	calls := 0
+	c.dynamicClient.(*dynamicfake.FakeDynamicClient).PrependReactor(verb, "appdeployments",
+		func(k8stesting.Action) (bool, runtime.Object, error) {
+			calls++
+			if calls <= n {
+				return true, nil, err
+			}
+			return false, nil, nil
+		})
+	return &calls
+}
+
+func newRetryTestClient() *Client {
+	c := newTestClient([]runtime.Object{newAppDeploymentObject("team-a", "my-db", nil)})
+	c.backoff = wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 3}
+	return c
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"server timeout", apierrors.NewServerTimeout(appDeploymentsResource, "get", 0)},
+		{"too many requests", apierrors.NewTooManyRequests("slow down", 0)},
+		{"service unavailable", apierrors.NewServiceUnavailable("apiserver restarting")},
+		{"internal error", apierrors.NewInternalError(errors.New("etcd leader changed"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newRetryTestClient()
+			calls := failFirst(c, "get", 2, tt.err)
+
+			deployment, err := c.GetAppDeployment(context.Background(), "team-a", "my-db")
+			if err != nil {
+				t.Fatalf("GetAppDeployment() error = %v", err)
+			}
+			if deployment.Name != "my-db" {
+				t.Errorf("Name = %q, want my-db", deployment.Name)
+			}
+			if *calls != 3 {
+				t.Errorf("got %d calls, want 3", *calls)
+			}
+		})
+	}
+}
+
+func TestRetryListAppDeployments(t *testing.T) {
+	c := newRetryTestClient()
+	calls := failFirst(c, "list", 1, apierrors.NewTooManyRequests("slow down", 0))
+
+	deployments, err := c.ListAppDeployments(context.Background(), "team-a")
+	if err != nil {
+		t.Fatalf("ListAppDeployments() error = %v", err)
+	}
+	if len(deployments) != 1 || *calls != 2 {
+		t.Errorf("got %d deployments after %d calls, want 1 after 2", len(deployments), *calls)
+	}
+}
+
+func TestRetryGivesUpAfterBackoff(t *testing.T) {
+	c := newRetryTestClient()
+	calls := failFirst(c, "get", 10, apierrors.NewServiceUnavailable("apiserver restarting"))
+
+	_, err := c.GetAppDeployment(context.Background(), "team-a", "my-db")
+	if !apierrors.IsServiceUnavailable(err) {
+		t.Fatalf("GetAppDeployment() error = %v, want service unavailable", err)
+	}
+	if *calls != 3 {
+		t.Errorf("got %d calls, want 3", *calls)
+	}
+}
+
+func TestNoRetryOnPermanentErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"not found", apierrors.NewNotFound(appDeploymentsResource, "my-db")},
+		{"forbidden", apierrors.NewForbidden(appDeploymentsResource, "my-db", errors.New("no access"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newRetryTestClient()
+			calls := failFirst(c, "get", 1, tt.err)
+
+			if _, err := c.GetAppDeployment(context.Background(), "team-a", "my-db"); err == nil {
+				t.Fatal("GetAppDeployment() error = nil, want error")
+			}
+			if *calls != 1 {
+				t.Errorf("got %d calls, want 1", *calls)
+			}
+		})
+	}
+}
+
+func TestRetryStopsWhenContextDone(t *testing.T) {
+	c := newRetryTestClient()
+	c.backoff = wait.Backoff{Duration: time.Hour, Factor: 2, Steps: 3}
+	calls := failFirst(c, "get", 10, apierrors.NewServiceUnavailable("apiserver restarting"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.GetAppDeployment(ctx, "team-a", "my-db"); !apierrors.IsServiceUnavailable(err) {
+		t.Fatalf("GetAppDeployment() error = %v, want service unavailable", err)
+	}
+	if *calls != 1 {
+		t.Errorf("got %d calls, want 1", *calls)
+	}
+}
+
+func TestRetryAfterIsCapped(t *testing.T) {
+	c := newRetryTestClient()
+	c.backoff.Cap = 10 * time.Millisecond
+	calls := failFirst(c, "get", 1, apierrors.NewTooManyRequests("slow down", 60))
+
+	start := time.Now()
+	if _, err := c.GetAppDeployment(context.Background(), "team-a", "my-db"); err != nil {
+		t.Fatalf("GetAppDeployment() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("retry waited %s, want at most the backoff cap", elapsed)
+	}
+	if *calls != 2 {
+		t.Errorf("got %d calls, want 2", *calls)
+	}
+}
@@ -0,0 +1,18 @@
+package k8s
+
+import (
+	"fmt"
+	"time"
+)
+
+// parseTime parses a timestamp of a Kubernetes resource, with or without fractional
+// seconds, and returns it in UTC so that times from different sources compare and
+// serialize alike
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q, expected RFC 3339", value)
+}
@@ -0,0 +1,68 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestParseTime(t *testing.T) {
+	want := time.Date(2026, 3, 4, 12, 30, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		value string
+		want  time.Time
+	}{
+		{"2026-03-04T12:30:00Z", want},
+		{"2026-03-04T14:30:00+02:00", want},
+		{"2026-03-04T07:30:00-05:00", want},
+		{"2026-03-04T12:30:00.123456789Z", want.Add(123456789 * time.Nanosecond)},
+		{"2026-03-04T13:30:00.5+01:00", want.Add(500 * time.Millisecond)},
+	} {
+		got, err := parseTime(tt.value)
+		if err != nil {
+			t.Errorf("parseTime(%q) error = %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) || got.Location() != time.UTC {
+			t.Errorf("parseTime(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "2026-03-04", "2026-03-04 12:30:00", "yesterday"} {
+		if _, err := parseTime(value); err == nil {
+			t.Errorf("parseTime(%q) succeeded", value)
+		}
+	}
+}
+
+func TestAppDeploymentTimesAreUTC(t *testing.T) {
+	c := newTestClient([]runtime.Object{
+		newAppDeploymentObject("team-a", "my-db", map[string]interface{}{
+			"phase":             "Deployed",
+			"lastReconcileTime": "2026-03-04T14:30:00+02:00",
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "True", "lastTransitionTime": "2026-03-04T07:30:00.25-05:00"},
+			},
+		}),
+	})
+
+	deployment, err := c.GetAppDeployment(context.Background(), "team-a", "my-db")
+	if err != nil {
+		t.Fatalf("GetAppDeployment() error = %v", err)
+	}
+	want := time.Date(2026, 3, 4, 12, 30, 0, 0, time.UTC)
+	if got := deployment.LastReconcileTime; got == nil || !got.Equal(want) || got.Location() != time.UTC {
+		t.Errorf("LastReconcileTime = %v, want %v", got, want)
+	}
+	if len(deployment.Conditions) != 1 {
+		t.Fatalf("Conditions = %+v", deployment.Conditions)
+	}
+	if got := deployment.Conditions[0].LastTransitionTime; !got.Equal(want.Add(250*time.Millisecond)) || got.Location() != time.UTC {
+		t.Errorf("LastTransitionTime = %v, want %v", got, want.Add(250*time.Millisecond))
+	}
+	if got := deployment.CreatedAt; got.Location() != time.UTC {
+		t.Errorf("CreatedAt = %v, want UTC", got)
+	}
+}
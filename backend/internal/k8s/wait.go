@@ -0,0 +1,98 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// ErrAppDeploymentDeleted is returned by WaitForAppDeployment if the AppDeployment is
+// deleted while waiting
+var ErrAppDeploymentDeleted = errors.New("AppDeployment was deleted")
+
+// Settled reports whether the operator has finished reconciling the deployment's current
+// spec, successfully or not
+func (d *AppDeployment) Settled() bool {
+	return (d.Phase == "Deployed" || d.Phase == "Failed") && d.observedGeneration >= d.generation
+}
+
+// WaitForAppDeployment waits until an AppDeployment is settled or ctx is done, and returns
+// the AppDeployment as last seen. Changes are watched rather than polled, so waiting costs
+// the API server a single watch.
+func (c *Client) WaitForAppDeployment(ctx context.Context, namespace, name string) (*AppDeployment, error) {
+	var deployment *AppDeployment
+	for {
+		item, err := c.getAppDeployment(ctx, namespace, name)
+		if err != nil {
+			if deployment != nil && ctx.Err() != nil {
+				return deployment, nil
+			}
+			return nil, fmt.Errorf("failed to get AppDeployment: %w", err)
+		}
+		if deployment, err = parseAppDeployment(item); err != nil {
+			return nil, err
+		}
+		if deployment.Settled() || ctx.Err() != nil {
+			return deployment, nil
+		}
+
+		watcher, err := c.dynamicClient.Resource(AppDeploymentGVR).Namespace(namespace).Watch(ctx, metav1.ListOptions{
+			FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+			ResourceVersion: item.GetResourceVersion(),
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return deployment, nil
+			}
+			return nil, fmt.Errorf("failed to watch AppDeployment: %w", err)
+		}
+
+		done, err := watchUntilSettled(ctx, watcher, name, &deployment)
+		if done || err != nil {
+			return deployment, err
+		}
+		// The watch ended early, e.g. because it expired; start over from the current state
+	}
+}
+
+// watchUntilSettled updates *deployment from the watch's events until it is settled or
+// ctx is done. It returns false if the watch ends first.
+func watchUntilSettled(ctx context.Context, watcher watch.Interface, name string, deployment **AppDeployment) (bool, error) {
+	defer watcher.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return true, nil
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return false, nil
+			}
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				item, ok := event.Object.(*unstructured.Unstructured)
+				if !ok || item.GetName() != name {
+					continue
+				}
+				parsed, err := parseAppDeployment(item)
+				if err != nil {
+					continue
+				}
+				*deployment = parsed
+				if parsed.Settled() {
+					return true, nil
+				}
+			case watch.Deleted:
+				if item, ok := event.Object.(*unstructured.Unstructured); ok && item.GetName() == name {
+					return true, ErrAppDeploymentDeleted
+				}
+			case watch.Error:
+				return false, nil
+			}
+		}
+	}
+}
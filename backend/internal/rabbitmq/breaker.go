@@ -0,0 +1,131 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBreakerThreshold is the number of consecutive failed publishes that opens the
+	// circuit breaker
+	DefaultBreakerThreshold = 5
+	// DefaultBreakerCooldown is how long an open circuit breaker fails publishes before
+	// trying again
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+// BreakerState is the state of the publisher's circuit breaker
+type BreakerState string
+
+const (
+	// BreakerClosed means publishes are attempted (the default)
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen means publishes fail with ErrCircuitOpen without being attempted
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen means the cooldown has passed and a single publish is attempted to
+	// test whether RabbitMQ has recovered
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// ErrCircuitOpen is returned without attempting to publish while RabbitMQ is failing
+var ErrCircuitOpen = errors.New("RabbitMQ circuit breaker is open")
+
+// breaker is a circuit breaker that opens after threshold consecutive failures. After
+// cooldown it half-opens, letting one trial call through: success closes it, failure
+// opens it again. A nil breaker allows everything.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	// trial is set while the half-open trial call is in flight
+	trial bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		threshold = DefaultBreakerThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+	return &breaker{threshold: threshold, cooldown: cooldown, now: time.Now, state: BreakerClosed}
+}
+
+// allow returns ErrCircuitOpen if a call must not be attempted. Otherwise the caller must
+// report the outcome of the call with done.
+func (b *breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+	case BreakerHalfOpen:
+		if b.trial {
+			return ErrCircuitOpen
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+// done records the outcome of an allowed call. Canceled calls say nothing about RabbitMQ
+// and are ignored.
+func (b *breaker) done(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasTrial := b.trial
+	b.trial = false
+	switch {
+	case errors.Is(err, context.Canceled):
+	case err == nil:
+		b.state = BreakerClosed
+		b.failures = 0
+	case b.state == BreakerHalfOpen && wasTrial:
+		b.open()
+	case b.state == BreakerClosed:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open()
+		}
+	}
+}
+
+func (b *breaker) open() {
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+	b.failures = 0
+}
+
+// State returns the breaker's state. An open breaker whose cooldown has passed is
+// reported as half-open, since the next call will be attempted.
+func (b *breaker) State() BreakerState {
+	if b == nil {
+		return BreakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
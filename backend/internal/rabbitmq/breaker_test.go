@@ -0,0 +1,162 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"appstore/backend/pkg/models"
+)
+
+// fakeClock is a manually advanced clock for the breaker
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestBreaker(threshold int, cooldown time.Duration) (*breaker, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b := newBreaker(threshold, cooldown)
+	b.now = clock.Now
+	return b, clock
+}
+
+// call runs a call with the given outcome through the breaker
+func call(b *breaker, err error) error {
+	if allowErr := b.allow(); allowErr != nil {
+		return allowErr
+	}
+	b.done(err)
+	return err
+}
+
+func TestBreakerStates(t *testing.T) {
+	b, clock := newTestBreaker(3, time.Minute)
+	failure := errors.New("connection reset")
+
+	// Closed: calls are attempted until the threshold of consecutive failures
+	call(b, failure)
+	call(b, failure)
+	call(b, nil)
+	if b.State() != BreakerClosed {
+		t.Fatalf("state = %s after a success, want closed", b.State())
+	}
+	for i := 0; i < 3; i++ {
+		if err := call(b, failure); !errors.Is(err, failure) {
+			t.Fatalf("call %d error = %v, want the call's error", i, err)
+		}
+	}
+
+	// Open: calls fail fast until the cooldown has passed
+	if b.State() != BreakerOpen {
+		t.Fatalf("state = %s after 3 failures, want open", b.State())
+	}
+	clock.Advance(59 * time.Second)
+	if err := call(b, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("call during cooldown error = %v, want ErrCircuitOpen", err)
+	}
+
+	// Half-open: a single trial call is attempted, failing opens the breaker again
+	clock.Advance(time.Second)
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("state = %s after the cooldown, want half-open", b.State())
+	}
+	if err := call(b, failure); !errors.Is(err, failure) {
+		t.Fatalf("trial call error = %v, want the call's error", err)
+	}
+	if b.State() != BreakerOpen {
+		t.Fatalf("state = %s after a failed trial, want open", b.State())
+	}
+
+	// Only one call is let through while the trial is in flight
+	clock.Advance(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("trial allow() error = %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow() during the trial error = %v, want ErrCircuitOpen", err)
+	}
+
+	// A successful trial closes the breaker
+	b.done(nil)
+	if b.State() != BreakerClosed {
+		t.Fatalf("state = %s after a successful trial, want closed", b.State())
+	}
+	if err := call(b, nil); err != nil {
+		t.Fatalf("call after closing error = %v", err)
+	}
+}
+
+func TestBreakerIgnoresCanceledCalls(t *testing.T) {
+	b, clock := newTestBreaker(1, time.Minute)
+
+	call(b, context.Canceled)
+	if b.State() != BreakerClosed {
+		t.Fatalf("state = %s after a canceled call, want closed", b.State())
+	}
+
+	call(b, errors.New("connection reset"))
+	clock.Advance(time.Minute)
+	// A canceled trial leaves the breaker half-open for the next call
+	call(b, context.Canceled)
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("state = %s after a canceled trial, want half-open", b.State())
+	}
+	if err := call(b, nil); err != nil {
+		t.Fatalf("next trial error = %v", err)
+	}
+	if b.State() != BreakerClosed {
+		t.Fatalf("state = %s, want closed", b.State())
+	}
+}
+
+// failingChannel fails publishes while err is set and counts the attempts
+type failingChannel struct {
+	err      error
+	attempts int
+}
+
+func (c *failingChannel) PublishWithContext(context.Context, string, string, bool, bool, amqp.Publishing) error {
+	c.attempts++
+	return c.err
+}
+
+func (c *failingChannel) Get(string, bool) (amqp.Delivery, bool, error) {
+	return amqp.Delivery{}, false, nil
+}
+
+func (c *failingChannel) Close() error { return nil }
+
+func TestPublisherFailsFastWhileBreakerIsOpen(t *testing.T) {
+	ch := &failingChannel{err: amqp.ErrClosed}
+	b, clock := newTestBreaker(2, 10*time.Second)
+	p := &Publisher{config: PublisherConfig{Exchange: "appstore"}, channel: ch, breaker: b}
+	publish := func() error {
+		return p.PublishDeploymentRequest(context.Background(), models.DeploymentRequestPayload{RequestID: "1"})
+	}
+
+	for i := 0; i < 4; i++ {
+		publish()
+	}
+	if ch.attempts != 2 {
+		t.Errorf("attempted %d publishes, want 2 before the breaker opened", ch.attempts)
+	}
+	if err := publish(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("publish error = %v, want ErrCircuitOpen", err)
+	}
+	if p.BreakerState() != BreakerOpen {
+		t.Errorf("BreakerState() = %s, want open", p.BreakerState())
+	}
+
+	ch.err = nil
+	clock.Advance(10 * time.Second)
+	if err := publish(); err != nil {
+		t.Fatalf("publish after recovery error = %v", err)
+	}
+	if p.BreakerState() != BreakerClosed || ch.attempts != 3 {
+		t.Errorf("BreakerState() = %s with %d attempts, want closed with 3", p.BreakerState(), ch.attempts)
+	}
+}
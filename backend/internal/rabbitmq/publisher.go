@@ -3,54 +3,97 @@ package rabbitmq
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
+	"appstore/backend/internal/tracing"
 	"appstore/backend/pkg/models"
+	sharedrabbitmq "appstore/shared/rabbitmq"
+	sharedtracing "appstore/shared/tracing"
 )
 
 // PublisherConfig holds the configuration for the RabbitMQ publisher
 type PublisherConfig struct {
 	URL      string
 	Exchange string
+	// TLS configures the connection for amqps:// URLs
+	TLS sharedrabbitmq.TLSConfig
+	// BreakerThreshold is the number of consecutive failed publishes after which publishes
+	// fail fast with ErrCircuitOpen, DefaultBreakerThreshold if zero
+	BreakerThreshold int
+	// BreakerCooldown is how long publishes fail fast before one is attempted again,
+	// DefaultBreakerCooldown if zero
+	BreakerCooldown time.Duration
+	// MessageTTL expires deployment messages that aren't consumed in time, unless a
+	// request sets its own TTL. Zero never expires them.
+	MessageTTL time.Duration
+}
+
+// ErrClosed is returned when publishing after Shutdown was called
+var ErrClosed = errors.New("publisher is shutting down")
+
+// channel is the part of *amqp.Channel used by the publisher
+type channel interface {
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	Get(queue string, autoAck bool) (amqp.Delivery, bool, error)
+	Close() error
 }
 
 // Publisher handles publishing messages to RabbitMQ
 type Publisher struct {
 	config  PublisherConfig
 	conn    *amqp.Connection
-	channel *amqp.Channel
+	channel channel
 	mu      sync.Mutex
+	breaker *breaker
+
+	// stateMu guards closing and additions to inflight
+	stateMu  sync.Mutex
+	closing  bool
+	inflight sync.WaitGroup
 }
 
 // NewPublisher creates a new RabbitMQ publisher
 func NewPublisher(config PublisherConfig) *Publisher {
 	return &Publisher{
-		config: config,
+		config:  config,
+		breaker: newBreaker(config.BreakerThreshold, config.BreakerCooldown),
 	}
 }
 
+// BreakerState returns the state of the circuit breaker around publishes
+func (p *Publisher) BreakerState() BreakerState {
+	return p.breaker.State()
+}
+
 // Connect establishes a connection to RabbitMQ
 func (p *Publisher) Connect() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	var err error
-	p.conn, err = amqp.Dial(p.config.URL)
+	p.conn, err = sharedrabbitmq.Dial(p.config.URL, p.config.TLS)
 	if err != nil {
 		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
 	}
 
-	p.channel, err = p.conn.Channel()
+	ch, err := p.conn.Channel()
 	if err != nil {
 		return fmt.Errorf("failed to open channel: %w", err)
 	}
+	p.channel = ch
 
 	// Declare exchange
-	if err := p.channel.ExchangeDeclare(
+	if err := ch.ExchangeDeclare(
 		p.config.Exchange,
 		"topic",
 		true,  // durable
@@ -83,17 +126,94 @@ func (p *Publisher) Close() error {
 	return nil
 }
 
+// Shutdown stops accepting new publishes, waits for in-flight publishes to complete and
+// closes the connection. If ctx ends first, the connection is closed anyway and an error
+// is returned.
+func (p *Publisher) Shutdown(ctx context.Context) error {
+	p.stateMu.Lock()
+	p.closing = true
+	p.stateMu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		p.inflight.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out waiting for in-flight publishes: %w", ctx.Err())
+	}
+
+	return errors.Join(err, p.Close())
+}
+
+// begin registers an in-flight operation unless the publisher is shutting down. The
+// caller must call p.inflight.Done when the operation is complete.
+func (p *Publisher) begin() error {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+	if p.closing {
+		return ErrClosed
+	}
+	p.inflight.Add(1)
+	return nil
+}
+
+// messageOptions are the AMQP properties a message is published with
+type messageOptions struct {
+	priority uint8
+	// ttl expires the message if it isn't consumed in time; zero never expires it
+	ttl time.Duration
+}
+
+// expiration returns the AMQP expiration property for the TTL
+func (o messageOptions) expiration() string {
+	if o.ttl <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(max(o.ttl.Milliseconds(), 1), 10)
+}
+
 // publish sends a message to RabbitMQ
-func (p *Publisher) publish(ctx context.Context, routingKey string, msg models.Message) error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+func (p *Publisher) publish(ctx context.Context, routingKey string, msg models.Message, opts messageOptions) error {
+	if err := p.begin(); err != nil {
+		return err
+	}
+	defer p.inflight.Done()
+
+	// Name the span after the message type, routing keys include namespaces
+	ctx, span := tracing.Tracer().Start(ctx, "publish "+string(msg.Type),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "rabbitmq"),
+			attribute.String("messaging.destination.name", p.config.Exchange),
+			attribute.String("messaging.rabbitmq.destination.routing_key", routingKey),
+			attribute.String("messaging.message.id", msg.ID),
+		),
+	)
+	defer span.End()
+
+	// The consumer continues the trace from the headers
+	msg.Headers = sharedtracing.Inject(ctx)
 
 	body, err := json.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	return p.channel.PublishWithContext(ctx,
+	// Fail fast instead of queueing up behind publishes to a RabbitMQ that is down
+	if err := p.breaker.allow(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err = p.channel.PublishWithContext(ctx,
 		p.config.Exchange,
 		routingKey,
 		false, // mandatory
@@ -103,9 +223,17 @@ func (p *Publisher) publish(ctx context.Context, routingKey string, msg models.M
 			DeliveryMode: amqp.Persistent,
 			MessageId:    msg.ID,
 			Timestamp:    msg.Timestamp,
+			Priority:     opts.priority,
+			Expiration:   opts.expiration(),
 			Body:         body,
 		},
 	)
+	p.breaker.done(err)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
 }
 
 // PublishDeploymentRequest publishes a deployment request message
@@ -123,7 +251,11 @@ func (p *Publisher) PublishDeploymentRequest(ctx context.Context, payload models
 		Payload:   payloadBytes,
 	}
 
-	return p.publish(ctx, models.RoutingKeyDeploymentRequest, msg)
+	opts := messageOptions{priority: min(payload.Priority, sharedrabbitmq.MaxPriority), ttl: p.config.MessageTTL}
+	if payload.TTLSeconds > 0 {
+		opts.ttl = time.Duration(payload.TTLSeconds) * time.Second
+	}
+	return p.publish(ctx, models.DeploymentRoutingKey(models.RoutingKeyDeploymentRequest, payload.Namespace), msg, opts)
 }
 
 // PublishDeploymentUpdate publishes a deployment update message
@@ -141,7 +273,7 @@ func (p *Publisher) PublishDeploymentUpdate(ctx context.Context, payload models.
 		Payload:   payloadBytes,
 	}
 
-	return p.publish(ctx, models.RoutingKeyDeploymentUpdate, msg)
+	return p.publish(ctx, models.DeploymentRoutingKey(models.RoutingKeyDeploymentUpdate, payload.Namespace), msg, messageOptions{ttl: p.config.MessageTTL})
 }
 
 // PublishDeploymentDelete publishes a deployment delete message
@@ -159,5 +291,41 @@ func (p *Publisher) PublishDeploymentDelete(ctx context.Context, payload models.
 		Payload:   payloadBytes,
 	}
 
-	return p.publish(ctx, models.RoutingKeyDeploymentDelete, msg)
+	return p.publish(ctx, models.DeploymentRoutingKey(models.RoutingKeyDeploymentDelete, payload.Namespace), msg, messageOptions{ttl: p.config.MessageTTL})
+}
+
+// PublishDeploymentCancel publishes a deployment cancel message
+func (p *Publisher) PublishDeploymentCancel(ctx context.Context, payload models.DeploymentCancelPayload) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	msg := models.Message{
+		Type:      models.MessageTypeDeploymentCancel,
+		ID:        payload.RequestID,
+		Timestamp: time.Now().UTC(),
+		Source:    "backend-api",
+		Payload:   payloadBytes,
+	}
+
+	return p.publish(ctx, models.DeploymentRoutingKey(models.RoutingKeyDeploymentCancel, payload.Namespace), msg, messageOptions{ttl: p.config.MessageTTL})
+}
+
+// PublishAuditRecord publishes an audit record with routing key audit.<action>
+func (p *Publisher) PublishAuditRecord(ctx context.Context, record models.AuditRecord) error {
+	payloadBytes, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	msg := models.Message{
+		Type:      models.MessageTypeAuditRecord,
+		ID:        uuid.New().String(),
+		Timestamp: time.Now().UTC(),
+		Source:    "backend-api",
+		Payload:   payloadBytes,
+	}
+
+	return p.publish(ctx, models.RoutingKeyAuditPrefix+record.Action, msg, messageOptions{})
 }
@@ -0,0 +1,219 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"appstore/backend/pkg/models"
+	sharedrabbitmq "appstore/shared/rabbitmq"
+)
+
+// slowChannel blocks publishes until released and records what happened, in order
+type slowChannel struct {
+	started chan struct{}
+	release chan struct{}
+
+	mu     sync.Mutex
+	events []string
+}
+
+func newSlowChannel() *slowChannel {
+	return &slowChannel{started: make(chan struct{}, 10), release: make(chan struct{})}
+}
+
+func (c *slowChannel) PublishWithContext(_ context.Context, _, key string, _, _ bool, _ amqp.Publishing) error {
+	c.started <- struct{}{}
+	<-c.release
+	c.record("published " + key)
+	return nil
+}
+
+func (c *slowChannel) Get(string, bool) (amqp.Delivery, bool, error) {
+	return amqp.Delivery{}, false, nil
+}
+
+func (c *slowChannel) Close() error {
+	c.record("closed")
+	return nil
+}
+
+func (c *slowChannel) record(event string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, event)
+}
+
+func (c *slowChannel) recorded() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.events...)
+}
+
+func (p *Publisher) isClosing() bool {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+	return p.closing
+}
+
+func TestShutdownWaitsForInFlightPublish(t *testing.T) {
+	ch := newSlowChannel()
+	p := &Publisher{config: PublisherConfig{Exchange: "appstore"}, channel: ch}
+
+	published := make(chan error, 1)
+	go func() {
+		published <- p.PublishDeploymentRequest(context.Background(), models.DeploymentRequestPayload{RequestID: "1"})
+	}()
+	<-ch.started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- p.Shutdown(context.Background())
+	}()
+
+	// Wait until Shutdown has stopped accepting publishes
+	for !p.isClosing() {
+		time.Sleep(time.Millisecond)
+	}
+	if err := p.PublishDeploymentDelete(context.Background(), models.DeploymentDeletePayload{RequestID: "2"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("publish during shutdown error = %v, want ErrClosed", err)
+	}
+
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown() returned while a publish was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(ch.release)
+	if err := <-published; err != nil {
+		t.Errorf("in-flight publish error = %v", err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+
+	want := []string{"published " + models.RoutingKeyDeploymentRequest, "closed"}
+	if got := ch.recorded(); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	ch := newSlowChannel()
+	p := &Publisher{config: PublisherConfig{Exchange: "appstore"}, channel: ch}
+
+	go p.PublishDeploymentRequest(context.Background(), models.DeploymentRequestPayload{RequestID: "1"})
+	<-ch.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// Close needs the publish lock, so release the publish once the timeout has passed
+	go func() {
+		<-ctx.Done()
+		close(ch.release)
+	}()
+
+	if err := p.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestPublishDeploymentRequestPriority(t *testing.T) {
+	tests := []struct {
+		priority uint8
+		want     uint8
+	}{
+		{0, 0},
+		{5, 5},
+		{sharedrabbitmq.MaxPriority, sharedrabbitmq.MaxPriority},
+		{200, sharedrabbitmq.MaxPriority},
+	}
+	for _, tt := range tests {
+		ch := &recordingChannel{}
+		p := &Publisher{config: PublisherConfig{Exchange: "appstore"}, channel: ch}
+		payload := models.DeploymentRequestPayload{RequestID: "req-1", Priority: tt.priority}
+		if err := p.PublishDeploymentRequest(context.Background(), payload); err != nil {
+			t.Fatalf("PublishDeploymentRequest() error = %v", err)
+		}
+		if got := ch.published[0].Priority; got != tt.want {
+			t.Errorf("priority %d published as %d, want %d", tt.priority, got, tt.want)
+		}
+	}
+}
+
+func TestPublishMessageTTL(t *testing.T) {
+	ch := &recordingChannel{}
+	p := &Publisher{config: PublisherConfig{Exchange: "appstore", MessageTTL: 10 * time.Minute}, channel: ch}
+	ctx := context.Background()
+
+	if err := p.PublishDeploymentRequest(ctx, models.DeploymentRequestPayload{RequestID: "req-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PublishDeploymentRequest(ctx, models.DeploymentRequestPayload{RequestID: "req-2", TTLSeconds: 30}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PublishDeploymentDelete(ctx, models.DeploymentDeletePayload{RequestID: "req-3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PublishAuditRecord(ctx, models.AuditRecord{Action: "create"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"600000", "30000", "600000", ""}
+	for i, msg := range ch.published {
+		if msg.Expiration != want[i] {
+			t.Errorf("message %d expiration = %q, want %q", i, msg.Expiration, want[i])
+		}
+	}
+
+	ch.published = nil
+	p.config.MessageTTL = 0
+	if err := p.PublishDeploymentRequest(ctx, models.DeploymentRequestPayload{RequestID: "req-4"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ch.published[0].Expiration; got != "" {
+		t.Errorf("expiration without a TTL = %q, want none", got)
+	}
+}
+
+func TestDeploymentRoutingKeysIncludeNamespace(t *testing.T) {
+	ch := newSlowChannel()
+	close(ch.release)
+	p := &Publisher{config: PublisherConfig{Exchange: "appstore"}, channel: ch}
+	ctx := context.Background()
+
+	if err := p.PublishDeploymentRequest(ctx, models.DeploymentRequestPayload{RequestID: "1", Namespace: "team-a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PublishDeploymentUpdate(ctx, models.DeploymentUpdatePayload{RequestID: "2", Namespace: "team-a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PublishDeploymentDelete(ctx, models.DeploymentDeletePayload{RequestID: "3", Namespace: "team-b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PublishDeploymentCancel(ctx, models.DeploymentCancelPayload{RequestID: "4", Namespace: "team-b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"published deployment.request.team-a",
+		"published deployment.update.team-a",
+		"published deployment.delete.team-b",
+		"published deployment.cancel.team-b",
+	}
+	got := ch.recorded()
+	if len(got) != len(want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("events[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
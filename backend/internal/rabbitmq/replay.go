@@ -0,0 +1,116 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ReplayedMessage describes a dead-lettered message that was (or would be) replayed
+type ReplayedMessage struct {
+	MessageID  string `json:"messageId"`
+	RoutingKey string `json:"routingKey"`
+}
+
+// ReplayResult reports the outcome of replaying dead-lettered messages
+type ReplayResult struct {
+	Replayed int               `json:"replayed"`
+	DryRun   bool              `json:"dryRun"`
+	Messages []ReplayedMessage `json:"messages"`
+}
+
+// ReplayDeadLetters moves up to maxMessages messages from a dead-letter queue back to the
+// exchange, using the routing key they were originally published with. With dryRun the
+// messages are only inspected and left in the queue.
+//
+// Each message is acknowledged only after it has been republished, so a failure leaves the
+// remaining messages in the dead-letter queue. The result reports the messages replayed
+// before the failure.
+func (p *Publisher) ReplayDeadLetters(ctx context.Context, queue string, maxMessages int, dryRun bool) (ReplayResult, error) {
+	result := ReplayResult{DryRun: dryRun, Messages: []ReplayedMessage{}}
+
+	if err := p.begin(); err != nil {
+		return result, err
+	}
+	defer p.inflight.Done()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Deliveries are held unacknowledged during a dry run so that Get returns the next
+	// message, and are all returned to the queue at the end
+	var inspected []amqp.Delivery
+	defer func() {
+		for _, d := range inspected {
+			_ = d.Nack(false, true)
+		}
+	}()
+
+	for result.Replayed < maxMessages {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		d, ok, err := p.channel.Get(queue, false)
+		if err != nil {
+			return result, fmt.Errorf("failed to get message from %s: %w", queue, err)
+		}
+		if !ok {
+			break // Queue is empty
+		}
+
+		message := ReplayedMessage{MessageID: d.MessageId, RoutingKey: originalRoutingKey(d)}
+		if dryRun {
+			inspected = append(inspected, d)
+			result.Messages = append(result.Messages, message)
+			result.Replayed++
+			continue
+		}
+
+		// The replay is timestamped anew, as the operator drops messages older than its
+		// maximum message age
+		if err := p.channel.PublishWithContext(ctx,
+			p.config.Exchange,
+			message.RoutingKey,
+			false, // mandatory
+			false, // immediate
+			amqp.Publishing{
+				ContentType:  d.ContentType,
+				DeliveryMode: amqp.Persistent,
+				MessageId:    d.MessageId,
+				Timestamp:    time.Now().UTC(),
+				Priority:     d.Priority,
+				Body:         d.Body,
+			},
+		); err != nil {
+			return result, errors.Join(fmt.Errorf("failed to republish message %s: %w", d.MessageId, err), d.Nack(false, true))
+		}
+		if err := d.Ack(false); err != nil {
+			// The message was republished and may be replayed again later
+			return result, fmt.Errorf("failed to ack message %s: %w", d.MessageId, err)
+		}
+
+		result.Messages = append(result.Messages, message)
+		result.Replayed++
+	}
+
+	return result, nil
+}
+
+// originalRoutingKey returns the routing key a dead-lettered message was published with,
+// taken from the most recent x-death entry
+func originalRoutingKey(d amqp.Delivery) string {
+	if deaths, ok := d.Headers["x-death"].([]interface{}); ok && len(deaths) > 0 {
+		if death, ok := deaths[0].(amqp.Table); ok {
+			if keys, ok := death["routing-keys"].([]interface{}); ok && len(keys) > 0 {
+				if key, ok := keys[0].(string); ok {
+					return key
+				}
+			}
+		}
+	}
+	return d.RoutingKey
+}
@@ -0,0 +1,178 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type publishedMessage struct {
+	exchange   string
+	routingKey string
+	msg        amqp.Publishing
+}
+
+// dlqChannel serves deliveries from an in-memory dead-letter queue and records publishes
+type dlqChannel struct {
+	queue      []amqp.Delivery
+	unacked    map[uint64]amqp.Delivery
+	nextTag    uint64
+	published  []publishedMessage
+	acked      []string
+	publishErr error
+}
+
+func newDLQChannel(deliveries ...amqp.Delivery) *dlqChannel {
+	return &dlqChannel{queue: deliveries, unacked: map[uint64]amqp.Delivery{}}
+}
+
+func (c *dlqChannel) PublishWithContext(_ context.Context, exchange, key string, _, _ bool, msg amqp.Publishing) error {
+	if c.publishErr != nil {
+		return c.publishErr
+	}
+	c.published = append(c.published, publishedMessage{exchange: exchange, routingKey: key, msg: msg})
+	return nil
+}
+
+func (c *dlqChannel) Get(string, bool) (amqp.Delivery, bool, error) {
+	if len(c.queue) == 0 {
+		return amqp.Delivery{}, false, nil
+	}
+	d := c.queue[0]
+	c.queue = c.queue[1:]
+	c.nextTag++
+	d.DeliveryTag = c.nextTag
+	d.Acknowledger = c
+	c.unacked[d.DeliveryTag] = d
+	return d, true, nil
+}
+
+func (c *dlqChannel) Close() error { return nil }
+
+func (c *dlqChannel) Ack(tag uint64, _ bool) error {
+	c.acked = append(c.acked, c.unacked[tag].MessageId)
+	delete(c.unacked, tag)
+	return nil
+}
+
+func (c *dlqChannel) Nack(tag uint64, _ bool, requeue bool) error {
+	if requeue {
+		c.queue = append(c.queue, c.unacked[tag])
+	}
+	delete(c.unacked, tag)
+	return nil
+}
+
+func (c *dlqChannel) Reject(tag uint64, requeue bool) error {
+	return c.Nack(tag, false, requeue)
+}
+
+// deadLetter returns a delivery dead-lettered from the deployments queue
+func deadLetter(id, routingKey string) amqp.Delivery {
+	return amqp.Delivery{
+		MessageId:   id,
+		ContentType: "application/json",
+		RoutingKey:  "appstore.deployments",
+		Body:        []byte(`{"id":"` + id + `"}`),
+		Headers: amqp.Table{"x-death": []interface{}{amqp.Table{
+			"queue":        "appstore.deployments",
+			"reason":       "rejected",
+			"routing-keys": []interface{}{routingKey},
+		}}},
+	}
+}
+
+func TestReplayDeadLetters(t *testing.T) {
+	ch := newDLQChannel(
+		deadLetter("1", "deployment.request"),
+		deadLetter("2", "deployment.delete"),
+		amqp.Delivery{MessageId: "3", RoutingKey: "deployment.update", Body: []byte(`{}`)},
+	)
+	p := &Publisher{config: PublisherConfig{Exchange: "appstore"}, channel: ch}
+
+	result, err := p.ReplayDeadLetters(context.Background(), "appstore.deployments.dlq", 10, false)
+	if err != nil {
+		t.Fatalf("ReplayDeadLetters() error = %v", err)
+	}
+	if result.Replayed != 3 || result.DryRun {
+		t.Errorf("result = %+v, want 3 replayed", result)
+	}
+
+	wantKeys := []string{"deployment.request", "deployment.delete", "deployment.update"}
+	if len(ch.published) != len(wantKeys) {
+		t.Fatalf("published %d messages, want %d", len(ch.published), len(wantKeys))
+	}
+	for i, want := range wantKeys {
+		got := ch.published[i]
+		if got.exchange != "appstore" || got.routingKey != want {
+			t.Errorf("message %d published to %s/%s, want appstore/%s", i, got.exchange, got.routingKey, want)
+		}
+		if result.Messages[i].RoutingKey != want {
+			t.Errorf("result message %d routingKey = %q, want %q", i, result.Messages[i].RoutingKey, want)
+		}
+	}
+	if string(ch.published[0].msg.Body) != `{"id":"1"}` || ch.published[0].msg.MessageId != "1" {
+		t.Errorf("republished message = %+v, want the original body and ID", ch.published[0].msg)
+	}
+	if time.Since(ch.published[0].msg.Timestamp) > time.Minute {
+		t.Errorf("republished message timestamp = %v, want the time of the replay", ch.published[0].msg.Timestamp)
+	}
+	if len(ch.acked) != 3 || len(ch.queue) != 0 {
+		t.Errorf("acked %v with %d left in the queue, want all acked", ch.acked, len(ch.queue))
+	}
+}
+
+func TestReplayDeadLettersMax(t *testing.T) {
+	ch := newDLQChannel(deadLetter("1", "deployment.request"), deadLetter("2", "deployment.request"))
+	p := &Publisher{config: PublisherConfig{Exchange: "appstore"}, channel: ch}
+
+	result, err := p.ReplayDeadLetters(context.Background(), "appstore.deployments.dlq", 1, false)
+	if err != nil {
+		t.Fatalf("ReplayDeadLetters() error = %v", err)
+	}
+	if result.Replayed != 1 || len(ch.published) != 1 {
+		t.Errorf("replayed %d and published %d, want 1", result.Replayed, len(ch.published))
+	}
+	if len(ch.queue) != 1 || ch.queue[0].MessageId != "2" {
+		t.Errorf("queue = %v, want message 2 left", ch.queue)
+	}
+}
+
+func TestReplayDeadLettersDryRun(t *testing.T) {
+	ch := newDLQChannel(deadLetter("1", "deployment.request"), deadLetter("2", "deployment.update"))
+	p := &Publisher{config: PublisherConfig{Exchange: "appstore"}, channel: ch}
+
+	result, err := p.ReplayDeadLetters(context.Background(), "appstore.deployments.dlq", 10, true)
+	if err != nil {
+		t.Fatalf("ReplayDeadLetters() error = %v", err)
+	}
+	if result.Replayed != 2 || !result.DryRun {
+		t.Errorf("result = %+v, want 2 in a dry run", result)
+	}
+	if len(ch.published) != 0 || len(ch.acked) != 0 {
+		t.Errorf("dry run published %d and acked %d messages", len(ch.published), len(ch.acked))
+	}
+	if len(ch.queue) != 2 || len(ch.unacked) != 0 {
+		t.Errorf("queue has %d messages and %d unacked, want all requeued", len(ch.queue), len(ch.unacked))
+	}
+}
+
+func TestReplayDeadLettersPublishError(t *testing.T) {
+	ch := newDLQChannel(deadLetter("1", "deployment.request"))
+	ch.publishErr = errors.New("channel closed")
+	p := &Publisher{config: PublisherConfig{Exchange: "appstore"}, channel: ch}
+
+	result, err := p.ReplayDeadLetters(context.Background(), "appstore.deployments.dlq", 10, false)
+	if err == nil {
+		t.Fatal("ReplayDeadLetters() error = nil, want an error")
+	}
+	if result.Replayed != 0 {
+		t.Errorf("replayed = %d, want 0", result.Replayed)
+	}
+	if len(ch.queue) != 1 || len(ch.acked) != 0 {
+		t.Errorf("message was not returned to the dead-letter queue")
+	}
+}
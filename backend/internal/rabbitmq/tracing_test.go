@@ -0,0 +1,101 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"appstore/backend/pkg/models"
+	sharedtracing "appstore/shared/tracing"
+)
+
+// recordingChannel keeps the published messages
+type recordingChannel struct {
+	published []amqp.Publishing
+}
+
+func (c *recordingChannel) PublishWithContext(_ context.Context, _, _ string, _, _ bool, msg amqp.Publishing) error {
+	c.published = append(c.published, msg)
+	return nil
+}
+
+func (c *recordingChannel) Get(string, bool) (amqp.Delivery, bool, error) {
+	return amqp.Delivery{}, false, nil
+}
+
+func (c *recordingChannel) Close() error { return nil }
+
+func TestPublishPropagatesTraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	ch := &recordingChannel{}
+	p := &Publisher{config: PublisherConfig{Exchange: "appstore"}, channel: ch}
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "POST /api/v1/deployments")
+	err := p.PublishDeploymentRequest(ctx, models.DeploymentRequestPayload{RequestID: "1"})
+	parent.End()
+	if err != nil {
+		t.Fatalf("PublishDeploymentRequest() error = %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	publishSpan := spans[0]
+	if publishSpan.Name() != "publish "+models.RoutingKeyDeploymentRequest || publishSpan.SpanKind() != trace.SpanKindProducer {
+		t.Errorf("span = %s (%s), want a producer span", publishSpan.Name(), publishSpan.SpanKind())
+	}
+	if publishSpan.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("publish span is not a child of the request span")
+	}
+
+	var msg models.Message
+	if err := json.Unmarshal(ch.published[0].Body, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Headers["traceparent"] == "" {
+		t.Fatalf("message headers = %v, want a traceparent", msg.Headers)
+	}
+
+	// A consumer continues the trace as a child of the publish span
+	got := trace.SpanContextFromContext(sharedtracing.Extract(context.Background(), msg.Headers))
+	if got.TraceID() != parent.SpanContext().TraceID() || got.SpanID() != publishSpan.SpanContext().SpanID() {
+		t.Errorf("extracted span context = %v/%v, want %v/%v", got.TraceID(), got.SpanID(),
+			parent.SpanContext().TraceID(), publishSpan.SpanContext().SpanID())
+	}
+	if !got.IsRemote() {
+		t.Error("extracted span context is not remote")
+	}
+}
+
+func TestPublishWithoutTraceHasNoHeaders(t *testing.T) {
+	ch := &recordingChannel{}
+	p := &Publisher{config: PublisherConfig{Exchange: "appstore"}, channel: ch}
+
+	if err := p.PublishDeploymentDelete(context.Background(), models.DeploymentDeletePayload{RequestID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	var msg models.Message
+	if err := json.Unmarshal(ch.published[0].Body, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Headers != nil {
+		t.Errorf("message headers = %v, want none", msg.Headers)
+	}
+}
@@ -0,0 +1,151 @@
+// Package tlsconfig builds the TLS configuration of the backend's HTTP server.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// CertReloader serves a certificate loaded from a cert and key file, reloading it when
+// either file changes so that renewed certificates are used without a restart
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	certMod  time.Time
+	keyMod   time.Time
+	lastStat time.Time
+}
+
+// statInterval limits how often the files are checked for changes
+const statInterval = time.Second
+
+// NewCertReloader loads the certificate from the given files
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		logger:   slog.Default().With("component", "tls"),
+	}
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(certMod, keyMod); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate. If the files have changed since the
+// certificate was loaded it is reloaded; if that fails, the previous certificate is kept.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.lastStat) < statInterval {
+		return r.cert, nil
+	}
+	r.lastStat = time.Now()
+
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		r.logger.Error("failed to check TLS certificate for changes", "error", err)
+		return r.cert, nil
+	}
+	if certMod.Equal(r.certMod) && keyMod.Equal(r.keyMod) {
+		return r.cert, nil
+	}
+	if err := r.load(certMod, keyMod); err != nil {
+		// The cert and key may be replaced one at a time, so this is retried next time
+		r.logger.Error("failed to reload TLS certificate", "error", err)
+		return r.cert, nil
+	}
+	r.logger.Info("TLS certificate reloaded", "certFile", r.certFile)
+	return r.cert, nil
+}
+
+func (r *CertReloader) modTimes() (certMod, keyMod time.Time, err error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to stat TLS certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to stat TLS key: %w", err)
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// load reads the certificate; the caller must hold r.mu unless r isn't shared yet
+func (r *CertReloader) load(certMod, keyMod time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	r.cert = &cert
+	r.certMod, r.keyMod = certMod, keyMod
+	return nil
+}
+
+// ServerConfig returns the TLS configuration for serving with the certificate in certFile
+// and keyFile, which is reloaded when the files change. If clientCAFile is set, client
+// certificates are verified against its CAs. Clients without a certificate can still
+// connect, e.g. the kubelet's probes; wrap the handler with RequireClientCert to reject
+// their other requests.
+func ServerConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("both a TLS certificate and key are required")
+	}
+
+	reloader, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if clientCAFile != "" {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return config, nil
+}
+
+// RequireClientCert rejects requests without a verified client certificate with 401,
+// except for the given paths, e.g. the health probes
+func RequireClientCert(next http.Handler, publicPaths ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) && !slices.Contains(publicPaths, r.URL.Path) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "client certificate required"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
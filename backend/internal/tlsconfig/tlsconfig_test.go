@@ -0,0 +1,264 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a generated certificate with its PEM encoding
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert generates a certificate for localhost, signed by parent or self-signed if
+// parent is nil
+func newTestCert(t *testing.T, serial int64, isCA bool, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// writeCert writes the certificate and key to cert.pem and key.pem in dir
+func writeCert(t *testing.T, dir string, c *testCert, modTime time.Time) (certFile, keyFile string) {
+	t.Helper()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	for file, data := range map[string][]byte{certFile: c.certPEM, keyFile: c.keyPEM} {
+		if err := os.WriteFile(file, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return certFile, keyFile
+}
+
+// okHandler responds with "ok"
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+})
+
+// serve starts an HTTPS server with config and returns its URL
+func serve(t *testing.T, config *tls.Config) string {
+	t.Helper()
+	return serveHandler(t, config, okHandler)
+}
+
+// serveHandler starts an HTTPS server with config serving handler and returns its URL
+func serveHandler(t *testing.T, config *tls.Config, handler http.Handler) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{
+		Handler:   handler,
+		TLSConfig: config,
+	}
+	go server.ServeTLS(ln, "", "")
+	t.Cleanup(func() { server.Close() })
+	return "https://" + ln.Addr().String()
+}
+
+// client returns an HTTP client trusting roots and presenting clientCert if not nil
+func client(roots *x509.Certificate, clientCert *testCert) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(roots)
+	config := &tls.Config{RootCAs: pool}
+	if clientCert != nil {
+		config.Certificates = []tls.Certificate{{
+			Certificate: [][]byte{clientCert.cert.Raw},
+			PrivateKey:  clientCert.key,
+		}}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config}, Timeout: 5 * time.Second}
+}
+
+func TestServerConfigHandshake(t *testing.T) {
+	serverCert := newTestCert(t, 1, true, nil)
+	certFile, keyFile := writeCert(t, t.TempDir(), serverCert, time.Now())
+
+	config, err := ServerConfig(certFile, keyFile, "")
+	if err != nil {
+		t.Fatalf("ServerConfig() error = %v", err)
+	}
+	url := serve(t, config)
+
+	resp, err := client(serverCert.cert, nil).Get(url)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if len(resp.TLS.PeerCertificates) == 0 || resp.TLS.PeerCertificates[0].SerialNumber.Int64() != 1 {
+		t.Error("server did not present the configured certificate")
+	}
+}
+
+func TestServerConfigClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	serverCert := newTestCert(t, 1, true, nil)
+	certFile, keyFile := writeCert(t, dir, serverCert, time.Now())
+	clientCA := newTestCert(t, 2, true, nil)
+	caFile := filepath.Join(dir, "client-ca.pem")
+	if err := os.WriteFile(caFile, clientCA.certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ServerConfig(certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatalf("ServerConfig() error = %v", err)
+	}
+	url := serveHandler(t, config, RequireClientCert(okHandler, "/healthz"))
+
+	get := func(clientCert *testCert, path string) (int, error) {
+		t.Helper()
+		resp, err := client(serverCert.cert, clientCert).Get(url + path)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	// The probes connect without a client certificate
+	if status, err := get(nil, "/healthz"); err != nil || status != http.StatusOK {
+		t.Errorf("GET /healthz without a client certificate = %d, %v, want %d", status, err, http.StatusOK)
+	}
+	if status, err := get(nil, "/api"); err != nil || status != http.StatusUnauthorized {
+		t.Errorf("GET /api without a client certificate = %d, %v, want %d", status, err, http.StatusUnauthorized)
+	}
+
+	untrusted := newTestCert(t, 3, false, nil)
+	if _, err := get(untrusted, "/healthz"); err == nil {
+		t.Error("GET with an untrusted client certificate succeeded, want a handshake failure")
+	}
+
+	if status, err := get(newTestCert(t, 4, false, clientCA), "/api"); err != nil || status != http.StatusOK {
+		t.Errorf("GET /api with a trusted client certificate = %d, %v, want %d", status, err, http.StatusOK)
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, newTestCert(t, 1, true, nil), time.Now().Add(-time.Minute))
+
+	reloader, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewCertReloader() error = %v", err)
+	}
+	serial := func() int64 {
+		t.Helper()
+		// Skip the stat interval
+		reloader.lastStat = time.Time{}
+		cert, err := reloader.GetCertificate(nil)
+		if err != nil {
+			t.Fatalf("GetCertificate() error = %v", err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaf.SerialNumber.Int64()
+	}
+
+	if got := serial(); got != 1 {
+		t.Fatalf("serial = %d, want 1", got)
+	}
+
+	writeCert(t, dir, newTestCert(t, 2, true, nil), time.Now())
+	if got := serial(); got != 2 {
+		t.Errorf("serial = %d after renewal, want 2", got)
+	}
+
+	// A broken renewal keeps the previous certificate
+	if err := os.WriteFile(keyFile, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(keyFile, time.Now().Add(time.Minute), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if got := serial(); got != 2 {
+		t.Errorf("serial = %d after a broken renewal, want 2", got)
+	}
+}
+
+func TestServerConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, newTestCert(t, 1, true, nil), time.Now())
+	notPEM := filepath.Join(dir, "not.pem")
+	if err := os.WriteFile(notPEM, []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name                  string
+		certFile, keyFile, ca string
+	}{
+		{"missing key", certFile, "", ""},
+		{"nonexistent cert", filepath.Join(dir, "missing.pem"), keyFile, ""},
+		{"invalid key", certFile, notPEM, ""},
+		{"nonexistent client CA", certFile, keyFile, filepath.Join(dir, "missing.pem")},
+		{"client CA without certificates", certFile, keyFile, notPEM},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ServerConfig(tt.certFile, tt.keyFile, tt.ca); err == nil {
+				t.Error("ServerConfig() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+// Package tracing provides the backend's OpenTelemetry tracer. Tracing is set up with
+// the tracing package of appstore/shared.
+package tracing
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation scope of the spans created by the backend
+const TracerName = "appstore/backend"
+
+// Tracer returns the backend's tracer from the global TracerProvider
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
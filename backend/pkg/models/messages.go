@@ -13,9 +13,13 @@ const (
 	MessageTypeDeploymentRequest MessageType = "deployment.request"
 	MessageTypeDeploymentUpdate  MessageType = "deployment.update"
 	MessageTypeDeploymentDelete  MessageType = "deployment.delete"
+	MessageTypeDeploymentCancel  MessageType = "deployment.cancel"
 
 	// Status update messages (operator -> backend)
 	MessageTypeStatusUpdate MessageType = "status.update"
+
+	// Audit trail messages
+	MessageTypeAuditRecord MessageType = "audit.record"
 )
 
 // Message is the envelope for all RabbitMQ messages
@@ -25,6 +29,8 @@ type Message struct {
 	Timestamp time.Time       `json:"timestamp"`
 	Source    string          `json:"source"`
 	Payload   json.RawMessage `json:"payload"`
+	// Headers carry the trace context of the publisher, as W3C traceparent and tracestate
+	Headers map[string]string `json:"headers,omitempty"`
 }
 
 // DeploymentRequestPayload contains the data for a deployment request
@@ -37,6 +43,26 @@ type DeploymentRequestPayload struct {
 	ReleaseName string                 `json:"releaseName,omitempty"`
 	Version     string                 `json:"version,omitempty"`
 	Values      map[string]interface{} `json:"values,omitempty"`
+	// IdempotencyKey makes retried requests map to the same AppDeployment
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+	// BatchID is shared by requests created together through the batch endpoint
+	BatchID string `json:"batchId,omitempty"`
+	// SecretKeyRefs inject Secret keys in the deployment's namespace into the values
+	SecretKeyRefs []SecretKeyRef `json:"secretKeyRefs,omitempty"`
+	// Priority (0 to MaxPriority of appstore/shared/rabbitmq) lets urgent requests overtake
+	// others waiting in the queue
+	Priority uint8 `json:"priority,omitempty"`
+	// TTLSeconds expires the request if it isn't consumed in time, overriding the
+	// publisher's default message TTL
+	TTLSeconds int `json:"ttlSeconds,omitempty"`
+}
+
+// SecretKeyRef injects a single Secret key into the Helm values
+type SecretKeyRef struct {
+	Path     string `json:"path"`
+	Name     string `json:"name"`
+	Key      string `json:"key"`
+	Optional bool   `json:"optional,omitempty"`
 }
 
 // DeploymentUpdatePayload contains the data for updating an existing deployment
@@ -48,6 +74,9 @@ type DeploymentUpdatePayload struct {
 	Namespace string                 `json:"namespace"`
 	Version   string                 `json:"version,omitempty"`
 	Values    map[string]interface{} `json:"values,omitempty"`
+	// Generation, if set, makes the update fail when the AppDeployment's spec has changed
+	// since
+	Generation int64 `json:"generation,omitempty"`
 }
 
 // DeploymentDeletePayload contains the data for deleting a deployment
@@ -59,6 +88,16 @@ type DeploymentDeletePayload struct {
 	Namespace string `json:"namespace"`
 }
 
+// DeploymentCancelPayload contains the data for canceling the install or upgrade of a
+// deployment that is in progress
+type DeploymentCancelPayload struct {
+	RequestID string `json:"requestId"`
+	TeamID    string `json:"teamId"`
+	UserID    string `json:"userId"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
 // StatusUpdatePayload contains status updates from the operator
 type StatusUpdatePayload struct {
 	Name                 string    `json:"name"`
@@ -71,10 +110,29 @@ type StatusUpdatePayload struct {
 	UpdatedAt            time.Time `json:"updatedAt"`
 }
 
+// AuditRecord describes a mutating API operation and its outcome
+type AuditRecord struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Action     string    `json:"action"`
+	Outcome    string    `json:"outcome"`
+	TeamID     string    `json:"teamId,omitempty"`
+	UserID     string    `json:"userId,omitempty"`
+	Deployment string    `json:"deployment,omitempty"`
+	Namespace  string    `json:"namespace,omitempty"`
+	AppName    string    `json:"appName,omitempty"`
+	RequestID  string    `json:"requestId,omitempty"`
+	BatchID    string    `json:"batchId,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+}
+
 // Queue names
 const (
 	QueueDeploymentRequests = "appstore.deployments"
 	QueueStatusUpdates      = "appstore.status"
+
+	// QueueDeploymentDeadLetters receives deployment messages rejected by the operator when
+	// dead-lettering is configured for QueueDeploymentRequests
+	QueueDeploymentDeadLetters = "appstore.deployments.dlq"
 )
 
 // Exchange names
@@ -87,5 +145,19 @@ const (
 	RoutingKeyDeploymentRequest = "deployment.request"
 	RoutingKeyDeploymentUpdate  = "deployment.update"
 	RoutingKeyDeploymentDelete  = "deployment.delete"
+	RoutingKeyDeploymentCancel  = "deployment.cancel"
 	RoutingKeyStatusUpdate      = "status.update"
+
+	// RoutingKeyAuditPrefix is followed by the audited action, e.g. audit.create
+	RoutingKeyAuditPrefix = "audit."
 )
+
+// DeploymentRoutingKey returns the routing key of a deployment message for a namespace,
+// e.g. deployment.request.team-a, so that namespace-scoped operators only receive the
+// messages for their namespaces
+func DeploymentRoutingKey(key, namespace string) string {
+	if namespace == "" {
+		return key
+	}
+	return key + "." + namespace
+}
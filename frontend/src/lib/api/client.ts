@@ -10,6 +10,7 @@ export interface App {
 	category: string;
 	chartPath: string;
 	tags: string[];
+	lifecycle: 'active' | 'deprecated' | 'removed';
 }
 
 export interface Condition {
@@ -33,26 +33,149 @@ const (
 	PhaseUninstalling AppDeploymentPhase = "Uninstalling"
 )
 
-// ValuesReference references a ConfigMap or Secret for Helm values
+// CancelRequestedAnnotation asks the operator to cancel the install or upgrade in progress.
+// Its value is the ID of the cancel request. The operator removes it once handled.
+const CancelRequestedAnnotation = "appstore.bitpipe.no/cancel-requested"
+
+// DeploymentStrategy defines how upgrades are rolled out
+// +kubebuilder:validation:Enum=immediate;canary
+type DeploymentStrategy string
+
+const (
+	// StrategyImmediate upgrades the release in a single step
+	StrategyImmediate DeploymentStrategy = "immediate"
+	// StrategyCanary first upgrades with the canary values overlay and waits for the
+	// release to be ready, then promotes it with the full values
+	StrategyCanary DeploymentStrategy = "canary"
+)
+
+// ValuesStrategy defines which values an upgrade starts from
+// +kubebuilder:validation:Enum=reset;reuse
+type ValuesStrategy string
+
+const (
+	// ValuesStrategyReset upgrades with the chart's default values and the AppDeployment's
+	// values, dropping values set on the release outside the AppDeployment
+	ValuesStrategyReset ValuesStrategy = "reset"
+	// ValuesStrategyReuse merges the AppDeployment's values over those of the current
+	// release, keeping values set outside the AppDeployment
+	ValuesStrategyReuse ValuesStrategy = "reuse"
+)
+
+// ValuesReference references a ConfigMap, Secret or URL for Helm values
 type ValuesReference struct {
-	// Kind of the values referent (ConfigMap or Secret)
-	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// Kind of the values referent (ConfigMap, Secret or URL)
+	// +kubebuilder:validation:Enum=ConfigMap;Secret;URL
 	Kind string `json:"kind"`
 
-	// Name of the referent
-	Name string `json:"name"`
+	// Name of the referent (required for ConfigMap and Secret)
+	// +optional
+	Name string `json:"name,omitempty"`
 
 	// ValuesKey is the key in the referent to read
 	// +kubebuilder:default=values.yaml
 	// +optional
 	ValuesKey string `json:"valuesKey,omitempty"`
 
+	// ValuesKeys are keys in the referent to read instead of ValuesKey, e.g. to split large
+	// values across keys. Each key holds values YAML, merged over the previous keys in order.
+	// +optional
+	ValuesKeys []string `json:"valuesKeys,omitempty"`
+
+	// AllKeys reads every key of the referent instead of ValuesKey, merged in the order of
+	// their names. It may not be combined with ValuesKeys.
+	// +optional
+	AllKeys bool `json:"allKeys,omitempty"`
+
+	// URL is the http(s) address of a values YAML file (required for URL)
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// AuthSecretRef names a Secret with credentials for URL: either a "token" key
+	// for bearer auth, or "username" and "password" keys for basic auth
+	// +optional
+	AuthSecretRef string `json:"authSecretRef,omitempty"`
+
+	// Optional marks this reference as optional
+	// +kubebuilder:default=false
+	// +optional
+	Optional bool `json:"optional,omitempty"`
+}
+
+// SecretKeyRef injects a single Secret key into the Helm values
+type SecretKeyRef struct {
+	// Path is the dot-separated values path to set, e.g. database.password
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+
+	// Name of the Secret
+	Name string `json:"name"`
+
+	// Key in the Secret to read
+	Key string `json:"key"`
+
 	// Optional marks this reference as optional
 	// +kubebuilder:default=false
 	// +optional
 	Optional bool `json:"optional,omitempty"`
 }
 
+// UpgradeWindow is a recurring time range in which upgrades may run
+type UpgradeWindow struct {
+	// Days the window opens on (Mon, Tue, Wed, Thu, Fri, Sat, Sun). Every day if empty.
+	// +optional
+	Days []string `json:"days,omitempty"`
+
+	// Start is the time of day the window opens, in HH:MM
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// End is the time of day the window closes, in HH:MM. A window that ends
+	// before it starts runs past midnight into the next day.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+
+	// Timezone is the IANA time zone of Start and End
+	// +kubebuilder:default=UTC
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// InstallOptions tune the Helm installs and upgrades of a deployment. All options are off
+// by default.
+type InstallOptions struct {
+	// Atomic rolls a failed upgrade back and uninstalls a failed install, waiting for
+	// resources to become ready before an operation counts as successful
+	// +optional
+	Atomic bool `json:"atomic,omitempty"`
+
+	// CleanupOnFail deletes the resources created by a failed upgrade
+	// +optional
+	CleanupOnFail bool `json:"cleanupOnFail,omitempty"`
+
+	// DisableHooks skips the chart's hooks
+	// +optional
+	DisableHooks bool `json:"disableHooks,omitempty"`
+
+	// SkipCRDs skips installing the CRDs in the chart's crds directory
+	// +optional
+	SkipCRDs bool `json:"skipCRDs,omitempty"`
+}
+
+// ClusterRef references a Secret holding the kubeconfig of the cluster a deployment is
+// installed into
+type ClusterRef struct {
+	// SecretName is the name of the Secret in the AppDeployment's namespace
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+
+	// Key is the Secret key holding the kubeconfig
+	// +kubebuilder:default=kubeconfig
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
 // AppDeploymentSpec defines the desired state of AppDeployment
 type AppDeploymentSpec struct {
 	// AppName is the name of the application from the catalog (validated at runtime against available charts)
@@ -76,7 +199,8 @@ type AppDeploymentSpec struct {
 	// +optional
 	ReleaseName string `json:"releaseName,omitempty"`
 
-	// Values are custom Helm values to override defaults
+	// Values are custom Helm values to override defaults. A null value unsets
+	// the key, whether set through ValuesFrom or by the chart.
 	// +kubebuilder:pruning:PreserveUnknownFields
 	// +optional
 	Values *apiextensionsv1.JSON `json:"values,omitempty"`
@@ -85,6 +209,27 @@ type AppDeploymentSpec struct {
 	// +optional
 	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
 
+	// Environment selects environment-specific overlay values, e.g. dev, staging or prod:
+	// the chart's values-<environment>.yaml and the values.yaml key of the ConfigMap
+	// <appName>-values-<environment> in the deployment's namespace, if they exist. They
+	// are applied over ValuesFrom, the ConfigMap's over the chart's, and below Values.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +optional
+	Environment string `json:"environment,omitempty"`
+
+	// SecretKeyRefs inject individual Secret keys at specific values paths.
+	// They are applied after Values and ValuesFrom and take precedence over both.
+	// +optional
+	SecretKeyRefs []SecretKeyRef `json:"secretKeyRefs,omitempty"`
+
+	// ValuesStrategy controls which values an upgrade starts from: reset uses the chart's
+	// defaults, reuse the values of the current release. With reuse, values removed from
+	// the AppDeployment and values set with e.g. helm upgrade --set are kept.
+	// +kubebuilder:default=reset
+	// +optional
+	ValuesStrategy ValuesStrategy `json:"valuesStrategy,omitempty"`
+
 	// AutoUpgrade enables automatic upgrades to new chart versions
 	// +kubebuilder:default=false
 	// +optional
@@ -94,6 +239,113 @@ type AppDeploymentSpec struct {
 	// +kubebuilder:default=false
 	// +optional
 	Suspend bool `json:"suspend,omitempty"`
+
+	// PreflightCheck verifies that the namespace ResourceQuotas can fit the chart's
+	// resource requests before installing
+	// +kubebuilder:default=false
+	// +optional
+	PreflightCheck bool `json:"preflightCheck,omitempty"`
+
+	// Strategy controls how upgrades are rolled out
+	// +kubebuilder:default=immediate
+	// +optional
+	Strategy DeploymentStrategy `json:"strategy,omitempty"`
+
+	// CanaryValues are merged over the values for the canary step of a canary
+	// upgrade, typically to scale workloads down to a subset of replicas. Defaults to
+	// {"replicaCount": 1}.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	CanaryValues *apiextensionsv1.JSON `json:"canaryValues,omitempty"`
+
+	// AutoRollback rolls an upgrade back to the previous revision when the new
+	// release does not become healthy within HealthCheckGracePeriod
+	// +kubebuilder:default=false
+	// +optional
+	AutoRollback bool `json:"autoRollback,omitempty"`
+
+	// HealthCheckGracePeriod is how long an upgraded release may take to become
+	// healthy before it is rolled back (requires AutoRollback)
+	// +kubebuilder:default="5m"
+	// +optional
+	HealthCheckGracePeriod *metav1.Duration `json:"healthCheckGracePeriod,omitempty"`
+
+	// UpgradeWindow restricts upgrades to a recurring maintenance window. Upgrades
+	// outside the window are deferred until it opens; initial installs are not affected.
+	// +optional
+	UpgradeWindow *UpgradeWindow `json:"upgradeWindow,omitempty"`
+
+	// AllowMajorUpgrade allows upgrades to a chart version with a new major version,
+	// a changed kubeVersion constraint or breaking changes declared in its annotations,
+	// which otherwise fail
+	// +kubebuilder:default=false
+	// +optional
+	AllowMajorUpgrade bool `json:"allowMajorUpgrade,omitempty"`
+
+	// InstallOptions tune how the release is installed and upgraded
+	// +optional
+	InstallOptions *InstallOptions `json:"installOptions,omitempty"`
+
+	// CommonLabels are added to the metadata of every resource the chart renders, e.g. a
+	// cost center or owner. They take precedence over labels of the same name set by the chart.
+	// +optional
+	CommonLabels map[string]string `json:"commonLabels,omitempty"`
+
+	// CommonAnnotations are added to the metadata of every resource the chart renders.
+	// They take precedence over annotations of the same name set by the chart.
+	// +optional
+	CommonAnnotations map[string]string `json:"commonAnnotations,omitempty"`
+
+	// Timeout bounds each Helm install, upgrade, rollback and uninstall of the deployment,
+	// including waiting for resources. Defaults to the operator's --helm-timeout.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// DeletionTimeout is how long the controller retries a failing Helm uninstall
+	// before it gives up and removes the finalizer, orphaning the release's resources.
+	// Defaults to the operator's --deletion-timeout; zero retries forever.
+	// +optional
+	DeletionTimeout *metav1.Duration `json:"deletionTimeout,omitempty"`
+
+	// ClusterRef installs the release into the remote cluster of a kubeconfig, in the
+	// namespace of the same name as the AppDeployment's. Defaults to the operator's cluster.
+	// +optional
+	ClusterRef *ClusterRef `json:"clusterRef,omitempty"`
+
+	// NotifyURL receives a POST with the deployment's status as JSON when it is deployed
+	// or fails
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	NotifyURL string `json:"notifyURL,omitempty"`
+
+	// NotifySecretRef names a Secret whose "secret" key signs the notifications sent to
+	// NotifyURL with HMAC-SHA256, in the X-Appstore-Signature header
+	// +optional
+	NotifySecretRef string `json:"notifySecretRef,omitempty"`
+}
+
+// HookStatus is the result of a Helm hook run by an install or upgrade
+type HookStatus struct {
+	// Name of the hook resource
+	Name string `json:"name"`
+
+	// Kind of the hook resource, e.g. Job
+	Kind string `json:"kind"`
+
+	// Events the hook runs on, e.g. pre-install
+	// +optional
+	Events []string `json:"events,omitempty"`
+
+	// Phase is Running, Succeeded, Failed or Unknown
+	Phase string `json:"phase"`
+
+	// StartedAt is when the hook started
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+
+	// CompletedAt is when the hook completed
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
 }
 
 // AppDeploymentStatus defines the observed state of AppDeployment
@@ -114,9 +366,62 @@ type AppDeploymentStatus struct {
 	// LastAttemptedChartVersion is the version last attempted
 	LastAttemptedChartVersion string `json:"lastAttemptedChartVersion,omitempty"`
 
-	// LastAppliedValuesHash is a hash of the last applied values
+	// AvailableChartVersion is the version of the chart found when spec.chartVersion is
+	// pinned to a version that isn't available. It is cleared once the pin resolves.
+	// +optional
+	AvailableChartVersion string `json:"availableChartVersion,omitempty"`
+
+	// RolledBackChartVersion is the chart version of the last upgrade that was
+	// automatically rolled back
+	// +optional
+	RolledBackChartVersion string `json:"rolledBackChartVersion,omitempty"`
+
+	// RolledBackValuesHash is the values hash of the last upgrade that was
+	// automatically rolled back. The same upgrade is not retried until the spec changes.
+	// +optional
+	RolledBackValuesHash string `json:"rolledBackValuesHash,omitempty"`
+
+	// LastAppliedValuesHash is a hash of the last applied values, including the common
+	// labels and annotations if set
 	LastAppliedValuesHash string `json:"lastAppliedValuesHash,omitempty"`
 
+	// LastAppliedValues are the values of the last successful install or upgrade, with
+	// values from Secrets replaced by a marker
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	LastAppliedValues *apiextensionsv1.JSON `json:"lastAppliedValues,omitempty"`
+
+	// LastAttemptedValues are the values last reconciled, whether or not applying them
+	// succeeded, with values from Secrets replaced by a marker
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	LastAttemptedValues *apiextensionsv1.JSON `json:"lastAttemptedValues,omitempty"`
+
+	// LastAttemptedValuesHash is a hash of the values last reconciled
+	// +optional
+	LastAttemptedValuesHash string `json:"lastAttemptedValuesHash,omitempty"`
+
+	// HealthyReplicas is the number of ready replicas across the release's
+	// Deployments and StatefulSets
+	// +optional
+	HealthyReplicas int32 `json:"healthyReplicas,omitempty"`
+
+	// DesiredReplicas is the number of desired replicas across the release's
+	// Deployments and StatefulSets
+	// +optional
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
+
+	// Progress is a rough indication of how far the deployment has come, from 0 to 100,
+	// derived from the phase and the readiness of the release's workloads
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	Progress int32 `json:"progress,omitempty"`
+
+	// Hooks are the results of the chart hooks run by the last install or upgrade
+	// +optional
+	Hooks []HookStatus `json:"hooks,omitempty"`
+
 	// Conditions represent the latest available observations
 	// +listType=map
 	// +listMapKey=type
@@ -141,6 +446,7 @@ type AppDeploymentStatus struct {
 // +kubebuilder:printcolumn:name="App",type=string,JSONPath=`.spec.appName`
 // +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.chartVersion`
 // +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Healthy",type=string,JSONPath=`.status.conditions[?(@.type=="Healthy")].status`
 // +kubebuilder:printcolumn:name="Release",type=string,JSONPath=`.status.helmReleaseName`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
@@ -0,0 +1,140 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartsync
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// ChartSource provides the charts a CatalogValidator restricts, such as a Syncer
+type ChartSource interface {
+	ChartExists(chartName string) bool
+	ListCharts() ([]string, error)
+}
+
+// catalogStatInterval limits how often the catalog file is checked for changes
+const catalogStatInterval = time.Second
+
+// catalogFile is the part of the backend's catalog.yaml the operator needs
+type catalogFile struct {
+	Apps []struct {
+		Name string `json:"name"`
+	} `json:"apps"`
+}
+
+// CatalogValidator only accepts charts that are both available from its chart source and
+// listed as an app in the catalog file, as the backend does. The catalog is reloaded when
+// the file changes, e.g. when the Syncer pulls a new version.
+type CatalogValidator struct {
+	catalogPath string
+	charts      ChartSource
+	logger      logr.Logger
+
+	mu       sync.Mutex
+	apps     map[string]bool
+	modTime  time.Time
+	lastStat time.Time
+}
+
+// NewCatalogValidator loads the catalog file at catalogPath
+func NewCatalogValidator(catalogPath string, charts ChartSource) (*CatalogValidator, error) {
+	v := &CatalogValidator{
+		catalogPath: catalogPath,
+		charts:      charts,
+		logger:      ctrl.Log.WithName("catalog"),
+	}
+	info, err := os.Stat(catalogPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat catalog: %w", err)
+	}
+	if err := v.load(info.ModTime()); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ChartExists reports whether the chart is in the catalog and available
+func (v *CatalogValidator) ChartExists(chartName string) bool {
+	return v.inCatalog(chartName) && v.charts.ChartExists(chartName)
+}
+
+// ListCharts returns the available charts that are in the catalog
+func (v *CatalogValidator) ListCharts() ([]string, error) {
+	charts, err := v.charts.ListCharts()
+	if err != nil {
+		return nil, err
+	}
+
+	var listed []string
+	for _, chart := range charts {
+		if v.inCatalog(chart) {
+			listed = append(listed, chart)
+		}
+	}
+	return listed, nil
+}
+
+// inCatalog reports whether the catalog lists the app, reloading the catalog first if the
+// file has changed. If reloading fails, the previous catalog is kept.
+func (v *CatalogValidator) inCatalog(appName string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if time.Since(v.lastStat) >= catalogStatInterval {
+		v.lastStat = time.Now()
+		info, err := os.Stat(v.catalogPath)
+		switch {
+		case err != nil:
+			v.logger.Error(err, "Failed to check catalog for changes", "path", v.catalogPath)
+		case !info.ModTime().Equal(v.modTime):
+			if err := v.load(info.ModTime()); err != nil {
+				v.logger.Error(err, "Failed to reload catalog", "path", v.catalogPath)
+			} else {
+				v.logger.Info("Catalog reloaded", "path", v.catalogPath, "apps", len(v.apps))
+			}
+		}
+	}
+
+	return v.apps[appName]
+}
+
+// load reads the catalog; the caller must hold v.mu unless v isn't shared yet
+func (v *CatalogValidator) load(modTime time.Time) error {
+	data, err := os.ReadFile(v.catalogPath)
+	if err != nil {
+		return fmt.Errorf("failed to read catalog: %w", err)
+	}
+	var catalog catalogFile
+	if err := yaml.Unmarshal(data, &catalog); err != nil {
+		return fmt.Errorf("failed to parse catalog: %w", err)
+	}
+
+	apps := make(map[string]bool, len(catalog.Apps))
+	for _, app := range catalog.Apps {
+		apps[app.Name] = true
+	}
+	v.apps = apps
+	v.modTime = modTime
+	return nil
+}
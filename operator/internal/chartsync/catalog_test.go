@@ -0,0 +1,106 @@
+package chartsync
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeCatalogCharts creates charts postgresql, valkey and experimental in chartsDir and a
+// catalog listing the given apps, returning the catalog's path
+func writeCatalogCharts(t *testing.T, chartsDir string, catalog string) string {
+	t.Helper()
+	for _, chart := range []string{"postgresql", "valkey", "experimental"} {
+		if err := os.MkdirAll(filepath.Join(chartsDir, chart), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		chartfile := "apiVersion: v2\nname: " + chart + "\nversion: 1.0.0\n"
+		if err := os.WriteFile(filepath.Join(chartsDir, chart, "Chart.yaml"), []byte(chartfile), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	catalogPath := filepath.Join(chartsDir, "catalog.yaml")
+	if err := os.WriteFile(catalogPath, []byte(catalog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return catalogPath
+}
+
+func TestCatalogValidator(t *testing.T) {
+	chartsDir := t.TempDir()
+	catalogPath := writeCatalogCharts(t, chartsDir, "apps:\n  - name: postgresql\n  - name: valkey\n  - name: mysql\n")
+
+	v, err := NewCatalogValidator(catalogPath, NewSyncer("", "", chartsDir, time.Minute))
+	if err != nil {
+		t.Fatalf("NewCatalogValidator() error = %v", err)
+	}
+
+	for chart, want := range map[string]bool{
+		"postgresql":   true,
+		"valkey":       true,
+		"experimental": false, // chart without a catalog entry
+		"mysql":        false, // catalog entry without a chart
+	} {
+		if got := v.ChartExists(chart); got != want {
+			t.Errorf("ChartExists(%q) = %v, want %v", chart, got, want)
+		}
+	}
+
+	charts, err := v.ListCharts()
+	if err != nil {
+		t.Fatalf("ListCharts() error = %v", err)
+	}
+	if want := []string{"postgresql", "valkey"}; !reflect.DeepEqual(charts, want) {
+		t.Errorf("ListCharts() = %v, want %v", charts, want)
+	}
+}
+
+func TestCatalogValidatorReload(t *testing.T) {
+	chartsDir := t.TempDir()
+	catalogPath := writeCatalogCharts(t, chartsDir, "apps:\n  - name: postgresql\n")
+
+	v, err := NewCatalogValidator(catalogPath, NewSyncer("", "", chartsDir, time.Minute))
+	if err != nil {
+		t.Fatalf("NewCatalogValidator() error = %v", err)
+	}
+	if v.ChartExists("experimental") {
+		t.Fatal("ChartExists(experimental) = true before it was added to the catalog")
+	}
+
+	update := func(catalog string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(catalogPath, []byte(catalog), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(catalogPath, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		v.lastStat = time.Time{}
+	}
+
+	update("apps:\n  - name: postgresql\n  - name: experimental\n", time.Now().Add(time.Minute))
+	if !v.ChartExists("experimental") {
+		t.Error("ChartExists(experimental) = false after it was added to the catalog")
+	}
+
+	// An invalid catalog keeps the previous one
+	update("apps: [", time.Now().Add(2*time.Minute))
+	if !v.ChartExists("postgresql") || !v.ChartExists("experimental") {
+		t.Error("invalid catalog replaced the previous one")
+	}
+}
+
+func TestNewCatalogValidatorErrors(t *testing.T) {
+	chartsDir := t.TempDir()
+	syncer := NewSyncer("", "", chartsDir, time.Minute)
+
+	if _, err := NewCatalogValidator(filepath.Join(chartsDir, "missing.yaml"), syncer); err == nil {
+		t.Error("NewCatalogValidator() of a missing catalog error = nil")
+	}
+	invalid := writeCatalogCharts(t, chartsDir, "apps: [")
+	if _, err := NewCatalogValidator(invalid, syncer); err == nil {
+		t.Error("NewCatalogValidator() of an invalid catalog error = nil")
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartsync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ChecksumMismatchError is returned when the files of a chart differ from the synced commit,
+// e.g. because they were modified or only partially synced
+type ChecksumMismatchError struct {
+	Chart    string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("chart %s has checksum %s, the synced commit has %s", e.Chart, e.Actual, e.Expected)
+}
+
+// fileDigests are the SHA-256 digests of the files of a chart, by their slash-separated
+// path relative to the chart directory
+type fileDigests map[string][sha256.Size]byte
+
+// add adds the digest of a file's content
+func (d fileDigests) add(path string, content io.Reader) error {
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	d[path] = [sha256.Size]byte(h.Sum(nil))
+	return nil
+}
+
+// checksum returns the checksum of all files as sha256:<hex>, which changes with the path
+// or content of any file
+func (d fileDigests) checksum() string {
+	paths := make([]string, 0, len(d))
+	for path := range d {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		fmt.Fprintf(h, "%x  %s\n", d[path], path)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// treeChecksums returns the checksums of the charts of a commit's tree, by chart name.
+// Files outside of a chart directory, such as the catalog, are ignored.
+func treeChecksums(tree *object.Tree) (map[string]string, error) {
+	charts := make(map[string]fileDigests)
+	err := tree.Files().ForEach(func(f *object.File) error {
+		chart, path, ok := strings.Cut(f.Name, "/")
+		if !ok || chart[0] == '.' {
+			return nil
+		}
+		if charts[chart] == nil {
+			charts[chart] = make(fileDigests)
+		}
+		// The content of symlinks is their target, like dirChecksum reads them
+		r, err := f.Reader()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		defer r.Close()
+		return charts[chart].add(path, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[string]string, len(charts))
+	for chart, digests := range charts {
+		checksums[chart] = digests.checksum()
+	}
+	return checksums, nil
+}
+
+// dirChecksum returns the checksum of the files of a chart directory, comparable to those of
+// treeChecksums. Directories only count through their files, like in Git.
+func dirChecksum(dir string) (string, error) {
+	digests := make(fileDigests)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		switch {
+		case entry.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return digests.add(rel, strings.NewReader(target))
+		case entry.Type().IsRegular():
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return digests.add(rel, f)
+		default:
+			return nil
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	return digests.checksum(), nil
+}
@@ -18,14 +18,19 @@ package chartsync
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-logr/logr"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
@@ -39,8 +44,23 @@ type Syncer struct {
 	repo         *git.Repository
 	mu           sync.RWMutex
 	logger       logr.Logger
+
+	// changes receives the names of the charts changed by a sync, see Changes
+	changes chan []string
+	// checksums are the checksums of the charts of the synced commit, by chart name
+	checksums map[string]string
+	// verified holds the result of checking the local files of each chart against its
+	// checksum, by chart name. Charts are checked after every sync, see verifyCharts.
+	verified map[string]error
+
+	// SubPath is the slash-separated directory of the repository holding the charts, e.g. in
+	// a monorepo. If set, only this directory is checked out. Set it before Start (optional).
+	SubPath string
 }
 
+// changesBufferSize is how many syncs with changed charts are buffered for a slow receiver
+const changesBufferSize = 16
+
 // NewSyncer creates a new chart syncer
 func NewSyncer(repoURL, branch, localPath string, syncInterval time.Duration) *Syncer {
 	return &Syncer{
@@ -54,6 +74,12 @@ func NewSyncer(repoURL, branch, localPath string, syncInterval time.Duration) *S
 
 // Start begins the periodic sync process
 func (s *Syncer) Start(ctx context.Context) error {
+	subPath, err := cleanSubPath(s.SubPath)
+	if err != nil {
+		return err
+	}
+	s.SubPath = subPath
+
 	// Initial clone or open
 	if err := s.initialSync(); err != nil {
 		return fmt.Errorf("initial sync failed: %w", err)
@@ -65,6 +91,22 @@ func (s *Syncer) Start(ctx context.Context) error {
 	return nil
 }
 
+// cleanSubPath cleans a sub path, which must stay within the repository
+func cleanSubPath(subPath string) (string, error) {
+	if subPath == "" {
+		return "", nil
+	}
+	cleaned := path.Clean(subPath)
+	first, _, _ := strings.Cut(cleaned, "/")
+	if path.IsAbs(cleaned) || first == ".." || first == ".git" {
+		return "", fmt.Errorf("invalid sub path %q, it must be a directory of the repository", subPath)
+	}
+	if cleaned == "." {
+		return "", nil
+	}
+	return cleaned, nil
+}
+
 // initialSync clones the repo or opens existing one
 func (s *Syncer) initialSync() error {
 	s.mu.Lock()
@@ -79,13 +121,21 @@ func (s *Syncer) initialSync() error {
 		if err != nil {
 			s.logger.Error(err, "Failed to open existing repo, will re-clone")
 			os.RemoveAll(s.localPath)
+		} else if cloned, err := clonedSubPath(repo); err != nil || cloned != s.SubPath {
+			// A clone of another sub path lacks the charts, or checks out the whole monorepo
+			s.logger.Info("Charts sub path changed, will re-clone", "clonedSubPath", cloned, "subPath", s.SubPath)
+			os.RemoveAll(s.localPath)
 		} else {
 			s.repo = repo
 			// Pull latest changes
-			if err := s.pull(); err != nil {
+			if _, err := s.pull(); err != nil {
 				s.logger.Error(err, "Failed to pull, will re-clone")
 				os.RemoveAll(s.localPath)
 				s.repo = nil
+			} else if err := s.updateChecksums(); err != nil {
+				s.logger.Error(err, "Failed to compute chart checksums, will re-clone")
+				os.RemoveAll(s.localPath)
+				s.repo = nil
 			} else {
 				s.logger.Info("Opened existing repo and pulled latest changes")
 				return nil
@@ -93,46 +143,334 @@ func (s *Syncer) initialSync() error {
 		}
 	}
 
-	// Clone fresh
-	s.logger.Info("Cloning charts repository")
+	// Clone fresh, checking out only the sub path if set
+	s.logger.Info("Cloning charts repository", "subPath", s.SubPath)
 	repo, err := git.PlainClone(s.localPath, false, &git.CloneOptions{
 		URL:           s.repoURL,
 		ReferenceName: plumbing.NewBranchReferenceName(s.branch),
 		SingleBranch:  true,
 		Depth:         1,
+		NoCheckout:    s.SubPath != "",
 	})
 	if err != nil {
 		return fmt.Errorf("failed to clone repo: %w", err)
 	}
+	if s.SubPath != "" {
+		w, err := repo.Worktree()
+		if err != nil {
+			return fmt.Errorf("failed to get worktree: %w", err)
+		}
+		err = w.Checkout(&git.CheckoutOptions{
+			Branch:                    plumbing.NewBranchReferenceName(s.branch),
+			SparseCheckoutDirectories: []string{s.SubPath},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to check out %s: %w", s.SubPath, err)
+		}
+	}
+	if err := recordSubPath(repo, s.SubPath); err != nil {
+		return err
+	}
 
 	s.repo = repo
+	if err := s.updateChecksums(); err != nil {
+		return err
+	}
+	s.verifyCharts()
 	s.logger.Info("Charts repository cloned successfully")
 	return nil
 }
 
-// pull fetches and merges latest changes
-func (s *Syncer) pull() error {
+// subPathSection and subPathOption locate the sub path a clone was made with in its git
+// config, so that a clone is only reused for the same sub path
+const (
+	subPathSection = "appstore"
+	subPathOption  = "chartsSubPath"
+)
+
+// clonedSubPath returns the sub path repo was cloned with
+func clonedSubPath(repo *git.Repository) (string, error) {
+	cfg, err := repo.Config()
+	if err != nil {
+		return "", fmt.Errorf("failed to read repository config: %w", err)
+	}
+	return cfg.Raw.Section(subPathSection).Option(subPathOption), nil
+}
+
+// recordSubPath stores the sub path repo was cloned with in its config
+func recordSubPath(repo *git.Repository, subPath string) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read repository config: %w", err)
+	}
+	cfg.Raw.Section(subPathSection).SetOption(subPathOption, subPath)
+	if err := repo.SetConfig(cfg); err != nil {
+		return fmt.Errorf("failed to record sub path: %w", err)
+	}
+	return nil
+}
+
+// Changes returns a channel receiving the names of the charts changed by each sync that
+// pulled new commits. Syncs are dropped while the channel's buffer is full, so receivers
+// shouldn't rely on it alone.
+func (s *Syncer) Changes() <-chan []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.changes == nil {
+		s.changes = make(chan []string, changesBufferSize)
+	}
+	return s.changes
+}
+
+// notifyChanges sends changed charts to the Changes channel, if any. Callers hold s.mu.
+func (s *Syncer) notifyChanges(charts []string) {
+	if len(charts) == 0 || s.changes == nil {
+		return
+	}
+	select {
+	case s.changes <- charts:
+	default:
+		s.logger.Info("Dropping chart change notification, receiver is not keeping up", "charts", charts)
+	}
+}
+
+// pull fetches and merges latest changes, returning the charts that changed
+func (s *Syncer) pull() ([]string, error) {
 	if s.repo == nil {
-		return fmt.Errorf("repository not initialized")
+		return nil, fmt.Errorf("repository not initialized")
 	}
 
 	w, err := s.repo.Worktree()
 	if err != nil {
-		return fmt.Errorf("failed to get worktree: %w", err)
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
 	}
 
-	err = w.Pull(&git.PullOptions{
-		RemoteName:    "origin",
-		ReferenceName: plumbing.NewBranchReferenceName(s.branch),
-		SingleBranch:  true,
-		Force:         true,
-	})
+	head, err := s.repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	if s.SubPath != "" {
+		err = s.pullSparse(w, head.Hash())
+	} else {
+		opts := &git.PullOptions{
+			RemoteName:    "origin",
+			ReferenceName: plumbing.NewBranchReferenceName(s.branch),
+			SingleBranch:  true,
+			Force:         true,
+		}
+		err = w.Pull(opts)
+		if errors.Is(err, git.ErrUnstagedChanges) {
+			// Local changes to the charts would otherwise block every pull
+			s.logger.Info("Checkout has local changes, resetting it before pulling")
+			if err = s.resetWorktree(); err == nil {
+				err = w.Pull(opts)
+			}
+		}
+	}
 
 	if err == git.NoErrAlreadyUpToDate {
-		return nil
+		s.verifyCharts()
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.updateChecksums(); err != nil {
+		return nil, err
+	}
+	s.verifyCharts()
+
+	return s.changedCharts(head.Hash())
+}
+
+// pullSparse fetches the latest commit of the branch and checks out its sub path. Unlike
+// Worktree.Pull, which checks out every file, it keeps the checkout sparse and the clone
+// shallow.
+func (s *Syncer) pullSparse(w *git.Worktree, head plumbing.Hash) error {
+	err := s.repo.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		Depth:      1,
+		Force:      true,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+
+	// Compared with HEAD rather than relying on the fetch, so that a failed checkout is retried
+	remote, err := s.repo.Reference(plumbing.NewRemoteReferenceName("origin", s.branch), true)
+	if err != nil {
+		return fmt.Errorf("failed to get remote branch: %w", err)
+	}
+	if remote.Hash() == head {
+		return git.NoErrAlreadyUpToDate
+	}
+
+	return w.ResetSparsely(&git.ResetOptions{Commit: remote.Hash(), Mode: git.MergeReset}, []string{s.SubPath})
+}
+
+// updateChecksums computes the checksums of the charts of HEAD. Callers hold s.mu.
+func (s *Syncer) updateChecksums() error {
+	head, err := s.repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	tree, err := s.commitTree(head.Hash())
+	if err != nil {
+		return err
+	}
+	if tree, err = s.chartsTree(tree); err != nil {
+		return err
+	}
+	checksums, err := treeChecksums(tree)
+	if err != nil {
+		return fmt.Errorf("failed to compute chart checksums: %w", err)
+	}
+	s.checksums = checksums
+	return nil
+}
+
+// verifyCharts checks the local files of every chart against its checksum. If any were
+// modified, the checkout is reset to HEAD and checked again, so that a corrupted chart is
+// repaired by the next sync. Callers hold s.mu.
+func (s *Syncer) verifyCharts() {
+	verified := s.checkCharts()
+	for _, err := range verified {
+		var mismatch *ChecksumMismatchError
+		if !errors.As(err, &mismatch) {
+			continue
+		}
+		s.logger.Info("Chart files don't match the synced commit, resetting the checkout", "chart", mismatch.Chart)
+		if err := s.resetWorktree(); err != nil {
+			s.logger.Error(err, "Failed to reset the checkout")
+		} else {
+			verified = s.checkCharts()
+		}
+		break
+	}
+	s.verified = verified
+}
+
+// checkCharts returns the result of checking the local files of each chart against its
+// checksum, by chart name. Callers hold s.mu.
+func (s *Syncer) checkCharts() map[string]error {
+	verified := make(map[string]error, len(s.checksums))
+	for chartName, expected := range s.checksums {
+		actual, err := dirChecksum(s.GetChartPath(chartName))
+		switch {
+		case err != nil:
+			verified[chartName] = fmt.Errorf("failed to compute checksum of chart %s: %w", chartName, err)
+		case actual != expected:
+			verified[chartName] = &ChecksumMismatchError{Chart: chartName, Expected: expected, Actual: actual}
+		default:
+			verified[chartName] = nil
+		}
+	}
+	return verified
+}
+
+// resetWorktree discards local changes to the checkout, restoring the files of HEAD and
+// removing untracked ones. Callers hold s.mu.
+func (s *Syncer) resetWorktree() error {
+	w, err := s.repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	head, err := s.repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	opts := &git.ResetOptions{Commit: head.Hash(), Mode: git.HardReset}
+	if s.SubPath != "" {
+		err = w.ResetSparsely(opts, []string{s.SubPath})
+	} else {
+		err = w.Reset(opts)
+	}
+	if err != nil {
+		return err
+	}
+	return w.Clean(&git.CleanOptions{Dir: true})
+}
+
+// changedCharts returns the sorted names of the charts with files that differ between the
+// given commit and HEAD. Files outside of a chart directory, such as the catalog, are ignored.
+func (s *Syncer) changedCharts(from plumbing.Hash) ([]string, error) {
+	head, err := s.repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	if head.Hash() == from {
+		return nil, nil
+	}
+
+	fromTree, err := s.commitTree(from)
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := s.commitTree(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff commits: %w", err)
 	}
 
-	return err
+	seen := make(map[string]bool)
+	var charts []string
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			rel, ok := s.chartsRelative(name)
+			if !ok {
+				continue
+			}
+			chart, _, ok := strings.Cut(rel, "/")
+			if !ok || chart[0] == '.' || seen[chart] {
+				continue
+			}
+			seen[chart] = true
+			charts = append(charts, chart)
+		}
+	}
+	sort.Strings(charts)
+
+	return charts, nil
+}
+
+// chartsRelative returns a repository path relative to the charts directory, and whether
+// the path is within it
+func (s *Syncer) chartsRelative(name string) (string, bool) {
+	if s.SubPath == "" {
+		return name, true
+	}
+	return strings.CutPrefix(name, s.SubPath+"/")
+}
+
+// chartsTree returns the tree of the charts directory of a commit's tree
+func (s *Syncer) chartsTree(tree *object.Tree) (*object.Tree, error) {
+	if s.SubPath == "" {
+		return tree, nil
+	}
+	subTree, err := tree.Tree(s.SubPath)
+	if err != nil {
+		return nil, fmt.Errorf("charts directory %s not found in the repository: %w", s.SubPath, err)
+	}
+	return subTree, nil
+}
+
+// commitTree returns the tree of a commit
+func (s *Syncer) commitTree(hash plumbing.Hash) (*object.Tree, error) {
+	commit, err := s.repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", hash, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of commit %s: %w", hash, err)
+	}
+	return tree, nil
 }
 
 // periodicSync runs sync on interval
@@ -147,19 +485,25 @@ func (s *Syncer) periodicSync(ctx context.Context) {
 			return
 		case <-ticker.C:
 			s.mu.Lock()
-			if err := s.pull(); err != nil {
+			if charts, err := s.pull(); err != nil {
 				s.logger.Error(err, "Periodic sync failed")
 			} else {
-				s.logger.V(1).Info("Periodic sync completed")
+				s.logger.V(1).Info("Periodic sync completed", "changedCharts", charts)
+				s.notifyChanges(charts)
 			}
 			s.mu.Unlock()
 		}
 	}
 }
 
+// ChartsPath returns the local path to the charts directory, the sub path of the clone if set
+func (s *Syncer) ChartsPath() string {
+	return filepath.Join(s.localPath, filepath.FromSlash(s.SubPath))
+}
+
 // GetChartPath returns the local path to a chart
 func (s *Syncer) GetChartPath(chartName string) string {
-	return filepath.Join(s.localPath, chartName)
+	return filepath.Join(s.ChartsPath(), chartName)
 }
 
 // ChartExists checks if a chart exists locally
@@ -174,12 +518,36 @@ func (s *Syncer) ChartExists(chartName string) bool {
 	return err == nil
 }
 
+// ChartChecksum returns the checksum of a chart's files in the synced commit, and whether
+// the commit has the chart
+func (s *Syncer) ChartChecksum(chartName string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	checksum, ok := s.checksums[chartName]
+	return checksum, ok
+}
+
+// VerifyChart returns whether the local files of a chart matched its checksum in the synced
+// commit when last checked, after the latest sync. It returns a *ChecksumMismatchError if
+// they were corrupted or only partially synced and resetting the checkout didn't help.
+func (s *Syncer) VerifyChart(chartName string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.checksums[chartName]; !ok {
+		return fmt.Errorf("chart %s is not part of the synced commit", chartName)
+	}
+	return s.verified[chartName]
+}
+
 // ListCharts returns all available charts
 func (s *Syncer) ListCharts() ([]string, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	entries, err := os.ReadDir(s.localPath)
+	chartsPath := s.ChartsPath()
+	entries, err := os.ReadDir(chartsPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read charts directory: %w", err)
 	}
@@ -194,7 +562,7 @@ func (s *Syncer) ListCharts() ([]string, error) {
 			continue
 		}
 		// Check if it's a valid chart (has Chart.yaml)
-		chartYaml := filepath.Join(s.localPath, entry.Name(), "Chart.yaml")
+		chartYaml := filepath.Join(chartsPath, entry.Name(), "Chart.yaml")
 		if _, err := os.Stat(chartYaml); err == nil {
 			charts = append(charts, entry.Name())
 		}
@@ -209,5 +577,10 @@ func (s *Syncer) ForceSync() error {
 	defer s.mu.Unlock()
 
 	s.logger.Info("Force sync triggered")
-	return s.pull()
+	charts, err := s.pull()
+	if err != nil {
+		return err
+	}
+	s.notifyChanges(charts)
+	return nil
 }
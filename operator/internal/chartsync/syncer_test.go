@@ -0,0 +1,399 @@
+package chartsync
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitFiles writes files into the work tree of repo at dir and commits them
+func commitFiles(t *testing.T, repo *git.Repository, dir string, files map[string]string) {
+	t.Helper()
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = w.Commit("update charts", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncerChanges(t *testing.T) {
+	remoteDir := t.TempDir()
+	remote, err := git.PlainInit(remoteDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, remote, remoteDir, map[string]string{
+		"catalog.yaml":          "apps: []\n",
+		"postgresql/Chart.yaml": "apiVersion: v2\nname: postgresql\nversion: 1.0.0\n",
+		"valkey/Chart.yaml":     "apiVersion: v2\nname: valkey\nversion: 1.0.0\n",
+	})
+	head, err := remote.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSyncer(remoteDir, head.Name().Short(), filepath.Join(t.TempDir(), "charts"), time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	changes := s.Changes()
+
+	// Nothing to pull
+	if err := s.ForceSync(); err != nil {
+		t.Fatalf("ForceSync() error = %v", err)
+	}
+	select {
+	case charts := <-changes:
+		t.Fatalf("unchanged sync sent %v", charts)
+	default:
+	}
+
+	// Only charts with changed files are sent, not the catalog
+	commitFiles(t, remote, remoteDir, map[string]string{
+		"catalog.yaml":          "apps:\n  - name: postgresql\n",
+		"postgresql/Chart.yaml": "apiVersion: v2\nname: postgresql\nversion: 1.1.0\n",
+	})
+	if err := s.ForceSync(); err != nil {
+		t.Fatalf("ForceSync() error = %v", err)
+	}
+	select {
+	case charts := <-changes:
+		if want := []string{"postgresql"}; !reflect.DeepEqual(charts, want) {
+			t.Errorf("changed charts = %v, want %v", charts, want)
+		}
+	default:
+		t.Fatal("no changes sent")
+	}
+}
+
+func TestSyncerVerifyChart(t *testing.T) {
+	remoteDir := t.TempDir()
+	remote, err := git.PlainInit(remoteDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, remote, remoteDir, map[string]string{
+		"catalog.yaml":                     "apps: []\n",
+		"postgresql/Chart.yaml":            "apiVersion: v2\nname: postgresql\nversion: 1.0.0\n",
+		"postgresql/values.yaml":           "replicas: 1\n",
+		"postgresql/templates/secret.yaml": "kind: Secret\n",
+		"valkey/Chart.yaml":                "apiVersion: v2\nname: valkey\nversion: 1.0.0\n",
+	})
+	head, err := remote.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localPath := filepath.Join(t.TempDir(), "charts")
+	s := NewSyncer(remoteDir, head.Name().Short(), localPath, time.Hour)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	for _, chart := range []string{"postgresql", "valkey"} {
+		if err := s.VerifyChart(chart); err != nil {
+			t.Errorf("VerifyChart(%q) error = %v", chart, err)
+		}
+	}
+	if _, ok := s.ChartChecksum("catalog.yaml"); ok {
+		t.Error("ChartChecksum(catalog.yaml) ok = true, want files outside of charts ignored")
+	}
+	if err := s.VerifyChart("mysql"); err == nil {
+		t.Error("VerifyChart(mysql) error = nil, want an error for a chart that isn't synced")
+	}
+
+	// A new commit changes the checksum
+	checksum, _ := s.ChartChecksum("postgresql")
+	commitFiles(t, remote, remoteDir, map[string]string{"postgresql/values.yaml": "replicas: 3\n"})
+	if err := s.ForceSync(); err != nil {
+		t.Fatalf("ForceSync() error = %v", err)
+	}
+	if updated, ok := s.ChartChecksum("postgresql"); !ok || updated == checksum {
+		t.Errorf("ChartChecksum(postgresql) = %q, %v after a change, want a new checksum", updated, ok)
+	}
+	if err := s.VerifyChart("postgresql"); err != nil {
+		t.Errorf("VerifyChart(postgresql) error = %v", err)
+	}
+
+	for name, corrupt := range map[string]func(chartDir string) error{
+		"modified file": func(chartDir string) error {
+			return os.WriteFile(filepath.Join(chartDir, "values.yaml"), []byte("replicas: 0\n"), 0o644)
+		},
+		"missing file": func(chartDir string) error {
+			return os.Remove(filepath.Join(chartDir, "templates", "secret.yaml"))
+		},
+		"extra file": func(chartDir string) error {
+			return os.WriteFile(filepath.Join(chartDir, "templates", "job.yaml"), []byte("kind: Job\n"), 0o644)
+		},
+		"truncated file": func(chartDir string) error {
+			return os.Truncate(filepath.Join(chartDir, "Chart.yaml"), 10)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			chartDir := filepath.Join(t.TempDir(), "postgresql")
+			if err := os.CopyFS(chartDir, os.DirFS(s.GetChartPath("postgresql"))); err != nil {
+				t.Fatal(err)
+			}
+			if err := corrupt(chartDir); err != nil {
+				t.Fatal(err)
+			}
+			got, err := dirChecksum(chartDir)
+			if err != nil {
+				t.Fatalf("dirChecksum() error = %v", err)
+			}
+			if want, _ := s.ChartChecksum("postgresql"); got == want {
+				t.Errorf("dirChecksum() = %s, want it to differ from the synced commit", got)
+			}
+		})
+	}
+
+	// Charts are checked once per sync, and a corrupted checkout is reset by the next
+	valuesPath := filepath.Join(localPath, "postgresql", "values.yaml")
+	if err := os.WriteFile(valuesPath, []byte("replicas: 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	extraPath := filepath.Join(localPath, "postgresql", "templates", "job.yaml")
+	if err := os.WriteFile(extraPath, []byte("kind: Job\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.VerifyChart("postgresql"); err != nil {
+		t.Errorf("VerifyChart(postgresql) error = %v before the next sync, want the last result", err)
+	}
+	if err := s.ForceSync(); err != nil {
+		t.Fatalf("ForceSync() error = %v", err)
+	}
+	if err := s.VerifyChart("postgresql"); err != nil {
+		t.Errorf("VerifyChart(postgresql) error = %v after the reset", err)
+	}
+	if values, err := os.ReadFile(valuesPath); err != nil || string(values) != "replicas: 3\n" {
+		t.Errorf("values.yaml = %q, %v, want the synced file restored", values, err)
+	}
+	if _, err := os.Stat(extraPath); !os.IsNotExist(err) {
+		t.Errorf("extra file wasn't removed, stat error = %v", err)
+	}
+
+	// A chart that can't be restored fails its verification
+	s.mu.Lock()
+	s.checksums["valkey"] = "corrupted"
+	s.verifyCharts()
+	s.mu.Unlock()
+	var mismatch *ChecksumMismatchError
+	if err := s.VerifyChart("valkey"); !errors.As(err, &mismatch) || mismatch.Chart != "valkey" {
+		t.Errorf("VerifyChart(valkey) error = %v, want a ChecksumMismatchError", err)
+	}
+	if err := s.VerifyChart("postgresql"); err != nil {
+		t.Errorf("VerifyChart(postgresql) error = %v", err)
+	}
+}
+
+func TestSyncerSubPath(t *testing.T) {
+	remoteDir := t.TempDir()
+	remote, err := git.PlainInit(remoteDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, remote, remoteDir, map[string]string{"README.md": "# monorepo\n"})
+	commitFiles(t, remote, remoteDir, map[string]string{
+		"services/api/main.go":                "package main\n",
+		"deploy/charts/catalog.yaml":          "apps: []\n",
+		"deploy/charts/postgresql/Chart.yaml": "apiVersion: v2\nname: postgresql\nversion: 1.0.0\n",
+		"deploy/charts/valkey/Chart.yaml":     "apiVersion: v2\nname: valkey\nversion: 1.0.0\n",
+	})
+	head, err := remote.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localPath := filepath.Join(t.TempDir(), "charts")
+	s := NewSyncer(remoteDir, head.Name().Short(), localPath, time.Hour)
+	s.SubPath = "./deploy/charts/"
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	changes := s.Changes()
+
+	if want := filepath.Join(localPath, "deploy", "charts", "postgresql"); s.GetChartPath("postgresql") != want {
+		t.Errorf("GetChartPath(postgresql) = %s, want %s", s.GetChartPath("postgresql"), want)
+	}
+	charts, err := s.ListCharts()
+	if err != nil {
+		t.Fatalf("ListCharts() error = %v", err)
+	}
+	if want := []string{"postgresql", "valkey"}; !reflect.DeepEqual(charts, want) {
+		t.Errorf("ListCharts() = %v, want %v", charts, want)
+	}
+	if err := s.VerifyChart("postgresql"); err != nil {
+		t.Errorf("VerifyChart(postgresql) error = %v", err)
+	}
+
+	// Only the sub path is checked out, of the latest commit only
+	assertSparse := func() {
+		t.Helper()
+		for _, name := range []string{"README.md", "services"} {
+			if _, err := os.Stat(filepath.Join(localPath, name)); !os.IsNotExist(err) {
+				t.Errorf("%s is checked out, want only the sub path", name)
+			}
+		}
+		shallow, err := s.repo.Storer.Shallow()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(shallow) == 0 {
+			t.Error("clone isn't shallow")
+		}
+	}
+	assertSparse()
+
+	// Changes are relative to the sub path and keep the checkout sparse
+	commitFiles(t, remote, remoteDir, map[string]string{
+		"README.md":                           "# monorepo, updated\n",
+		"services/api/main.go":                "package main\n\nfunc main() {}\n",
+		"deploy/charts/catalog.yaml":          "apps:\n  - name: postgresql\n",
+		"deploy/charts/postgresql/Chart.yaml": "apiVersion: v2\nname: postgresql\nversion: 1.1.0\n",
+	})
+	if err := s.ForceSync(); err != nil {
+		t.Fatalf("ForceSync() error = %v", err)
+	}
+	select {
+	case charts := <-changes:
+		if want := []string{"postgresql"}; !reflect.DeepEqual(charts, want) {
+			t.Errorf("changed charts = %v, want %v", charts, want)
+		}
+	default:
+		t.Fatal("no changes sent")
+	}
+	chartfile, err := os.ReadFile(filepath.Join(s.GetChartPath("postgresql"), "Chart.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(chartfile), "version: 1.1.0") {
+		t.Errorf("Chart.yaml = %q, want the pulled version", chartfile)
+	}
+	if err := s.VerifyChart("postgresql"); err != nil {
+		t.Errorf("VerifyChart(postgresql) error = %v", err)
+	}
+	assertSparse()
+
+	// Nothing more to pull
+	if err := s.ForceSync(); err != nil {
+		t.Fatalf("ForceSync() error = %v", err)
+	}
+	select {
+	case charts := <-changes:
+		t.Fatalf("unchanged sync sent %v", charts)
+	default:
+	}
+
+	// A corrupted chart is restored without checking out the rest of the repository
+	chartfilePath := filepath.Join(s.GetChartPath("postgresql"), "Chart.yaml")
+	if err := os.WriteFile(chartfilePath, []byte("corrupted\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ForceSync(); err != nil {
+		t.Fatalf("ForceSync() error = %v", err)
+	}
+	if restored, err := os.ReadFile(chartfilePath); err != nil || string(restored) != string(chartfile) {
+		t.Errorf("Chart.yaml = %q, %v, want the synced file restored", restored, err)
+	}
+	if err := s.VerifyChart("postgresql"); err != nil {
+		t.Errorf("VerifyChart(postgresql) error = %v", err)
+	}
+	assertSparse()
+}
+
+func TestSyncerSubPathChanged(t *testing.T) {
+	remoteDir := t.TempDir()
+	remote, err := git.PlainInit(remoteDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, remote, remoteDir, map[string]string{
+		"README.md":                       "# monorepo\n",
+		"deploy/charts/valkey/Chart.yaml": "apiVersion: v2\nname: valkey\nversion: 1.0.0\n",
+		"charts/postgresql/Chart.yaml":    "apiVersion: v2\nname: postgresql\nversion: 1.0.0\n",
+		"redis/Chart.yaml":                "apiVersion: v2\nname: redis\nversion: 1.0.0\n",
+	})
+	head, err := remote.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Restarts reuse the clone of the same sub path only
+	localPath := filepath.Join(t.TempDir(), "charts")
+	for _, tt := range []struct {
+		subPath string
+		charts  []string
+		readme  bool
+	}{
+		{"deploy/charts", []string{"valkey"}, false},
+		{"charts", []string{"postgresql"}, false},
+		{"", []string{"redis"}, true},
+		{"deploy/charts", []string{"valkey"}, false},
+	} {
+		s := NewSyncer(remoteDir, head.Name().Short(), localPath, time.Hour)
+		s.SubPath = tt.subPath
+		if err := s.Start(context.Background()); err != nil {
+			t.Fatalf("Start() with sub path %q error = %v", tt.subPath, err)
+		}
+		charts, err := s.ListCharts()
+		if err != nil {
+			t.Fatalf("ListCharts() error = %v", err)
+		}
+		if !reflect.DeepEqual(charts, tt.charts) {
+			t.Errorf("ListCharts() with sub path %q = %v, want %v", tt.subPath, charts, tt.charts)
+		}
+		if _, err := os.Stat(filepath.Join(localPath, "README.md")); (err == nil) != tt.readme {
+			t.Errorf("README.md checked out = %v with sub path %q, want %v", err == nil, tt.subPath, tt.readme)
+		}
+	}
+}
+
+func TestSyncerInvalidSubPath(t *testing.T) {
+	remoteDir := t.TempDir()
+	remote, err := git.PlainInit(remoteDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, remote, remoteDir, map[string]string{"charts/valkey/Chart.yaml": "apiVersion: v2\nname: valkey\nversion: 1.0.0\n"})
+	head, err := remote.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, subPath := range []string{"../charts", "/charts", ".git", "missing"} {
+		s := NewSyncer(remoteDir, head.Name().Short(), filepath.Join(t.TempDir(), "charts"), time.Hour)
+		s.SubPath = subPath
+		if err := s.Start(context.Background()); err == nil {
+			t.Errorf("Start() with sub path %q error = nil", subPath)
+		}
+	}
+}
@@ -18,24 +18,38 @@ package controller
 
 import (
 	"context"
-	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"helm.sh/helm/v3/pkg/chart"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	appstorev1alpha1 "appstore/operator/api/v1alpha1"
 	"appstore/operator/internal/helm"
+	"appstore/operator/internal/sops"
+	"appstore/operator/internal/tracing"
+	helmvalues "appstore/shared/values"
 )
 
 const (
@@ -44,6 +58,8 @@ const (
 	// Condition types
 	ConditionTypeReady       = "Ready"
 	ConditionTypeReconciling = "Reconciling"
+	ConditionTypeRolledBack  = "RolledBack"
+	ConditionTypeHealthy     = "Healthy"
 
 	// Requeue intervals
 	requeueAfterSuccess = 5 * time.Minute
@@ -56,12 +72,129 @@ type ChartValidator interface {
 	ListCharts() ([]string, error)
 }
 
+// HelmClient manages Helm releases
+type HelmClient interface {
+	Install(ctx context.Context, releaseName, chartName, namespace string, values map[string]interface{}, version string, opts helm.ActionOptions) (*helm.ReleaseInfo, error)
+	Upgrade(ctx context.Context, releaseName, chartName, namespace string, values map[string]interface{}, version string, opts helm.ActionOptions) (*helm.ReleaseInfo, error)
+	Rollback(ctx context.Context, releaseName, namespace string, revision int) error
+	Uninstall(ctx context.Context, releaseName, namespace string) error
+	Render(ctx context.Context, releaseName, chartName, namespace string, values map[string]interface{}, version string) (string, error)
+	GetRelease(ctx context.Context, releaseName, namespace string) (*helm.ReleaseInfo, error)
+	ReleaseExists(ctx context.Context, releaseName, namespace string) (bool, error)
+	GetChartMetadata(ctx context.Context, chartName, version string) (*chart.Metadata, error)
+	GetChartFile(ctx context.Context, chartName, version, fileName string) ([]byte, error)
+	ClearPendingRelease(ctx context.Context, releaseName, namespace string) error
+}
+
 // AppDeploymentReconciler reconciles a AppDeployment object
 type AppDeploymentReconciler struct {
 	client.Client
 	Scheme         *runtime.Scheme
-	HelmClient     *helm.Client
+	HelmClient     HelmClient
 	ChartValidator ChartValidator
+
+	// ChartPolicy restricts which charts may be deployed (all charts if nil)
+	ChartPolicy *ChartPolicy
+
+	// WatchNamespaces limits reconciliation to these namespaces (all namespaces if empty).
+	// The manager cache should be scoped to the same namespaces, see CacheOptions.
+	WatchNamespaces []string
+
+	// Clock is used to check upgrade windows and deletion timeouts (the real clock if nil)
+	Clock clock.PassiveClock
+
+	// Recorder emits Kubernetes Events for the AppDeployment (optional)
+	Recorder record.EventRecorder
+
+	// HTTPClient fetches values from URL references (if nil, a client with a 30s timeout
+	// that only connects to public addresses)
+	HTTPClient *http.Client
+
+	// ValuesURLHosts are the hosts, as path.Match globs, that valuesFrom URL references may
+	// fetch from. URL references fail if empty.
+	ValuesURLHosts []string
+
+	// NotifyHosts are the hosts, as path.Match globs, that spec.notifyURL may point to. No
+	// notifications are sent if empty.
+	NotifyHosts []string
+
+	// DeletionTimeout is the default time to retry a failing uninstall before the
+	// finalizer is removed anyway, see spec.deletionTimeout. Zero retries forever.
+	DeletionTimeout time.Duration
+
+	// ImagePullSecret is a registry pull secret copied into every deployment's namespace
+	// and added to its service accounts (disabled if nil)
+	ImagePullSecret *types.NamespacedName
+
+	// PendingReleaseTimeout is how long a release may stay locked by a pending Helm operation
+	// before it is marked failed so that it can be upgraded again. Zero waits forever.
+	PendingReleaseTimeout time.Duration
+
+	// NewClusterClient creates the client for the remote cluster of a spec.clusterRef
+	// (client.New if nil)
+	NewClusterClient func(config *rest.Config) (client.Client, error)
+
+	// AllowedClusters are the API servers, normalized with kubeconfig.NormalizeServer, that
+	// the kubeconfigs of spec.clusterRef may target. A clusterRef fails if empty.
+	AllowedClusters []string
+
+	// CancelPollInterval is how often a running install or upgrade checks whether it was
+	// asked to cancel (2s if zero)
+	CancelPollInterval time.Duration
+
+	// StartupSplay spreads the first reconciles of existing AppDeployments after startup
+	// randomly over this window (not spread if zero)
+	StartupSplay time.Duration
+
+	// Paused stops reconciling all AppDeployments, e.g. during incidents. They report a
+	// Paused condition and are checked again every 30 seconds.
+	Paused bool
+
+	// PauseConfigMap pauses reconciliation like Paused while its "paused" key is "true"
+	// (optional)
+	PauseConfigMap *types.NamespacedName
+
+	// SOPSIdentities decrypt valuesFrom references encrypted with SOPS for age recipients
+	// (optional; encrypted references fail without them)
+	SOPSIdentities []*sops.Identity
+
+	// ChartChanges receives the names of charts that changed, such as from chartsync, to
+	// reconcile the AppDeployments with spec.autoUpgrade right away and read the charts'
+	// environment values files again (optional)
+	ChartChanges <-chan []string
+
+	// observed holds the UIDs of the AppDeployments reconciled since startup, until they're
+	// deleted
+	observed sync.Map
+
+	// autoUpgradeChecks holds when AppDeployments with spec.autoUpgrade last checked for a
+	// new chart version, by UID
+	autoUpgradeChecks sync.Map
+
+	// chartFiles caches the files read from charts, by chartFileKey
+	chartFiles sync.Map
+
+	// clusters caches the remote clusters of spec.clusterRef Secrets
+	clusters clusterCache
+
+	// valuesURLs caches the values files fetched from valuesFrom URLs
+	valuesURLs valuesURLCache
+
+	valuesURLClientOnce sync.Once
+	valuesURLClient     *http.Client
+
+	// notifications queues the status notifications of spec.notifyURL
+	notifications notifier
+
+	notifyClientOnce sync.Once
+	notifyHTTPClient *http.Client
+
+	// pauseCache watches only the PauseConfigMap, whatever namespaces are watched
+	pauseCache cache.Cache
+
+	// pullSecretReader reads the ImagePullSecret from a cache holding only that secret,
+	// whatever namespaces are watched
+	pullSecretReader client.Reader
 }
 
 // +kubebuilder:rbac:groups=appstore.bitpipe.no,resources=appdeployments,verbs=get;list;watch;create;update;patch;delete
@@ -70,15 +203,20 @@ type AppDeploymentReconciler struct {
 // +kubebuilder:rbac:groups="",resources=secrets;configmaps;serviceaccounts;services;persistentvolumeclaims;pods;endpoints,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets;replicasets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=resourcequotas,verbs=get;list;watch
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses;networkpolicies,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is the main reconciliation loop for AppDeployment resources
-func (r *AppDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *AppDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
 	logger := log.FromContext(ctx)
 	logger.Info("Reconciling AppDeployment")
 
+	// Reflect phase changes made by this reconcile, including deletions
+	defer r.updatePhaseMetrics(ctx)
+
 	// Fetch the AppDeployment instance
 	appDeployment := &appstorev1alpha1.AppDeployment{}
 	if err := r.Get(ctx, req.NamespacedName, appDeployment); err != nil {
@@ -90,6 +228,49 @@ func (r *AppDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, err
 	}
 
+	start, previousFailures := time.Now(), appDeployment.Status.FailureCount
+	defer func() {
+		r.observeReconcile(appDeployment, previousFailures, time.Since(start), err)
+	}()
+
+	// Each reconcile starts its own trace, linked to the trace of the message that last
+	// created or changed the AppDeployment. Joining that trace would grow it with every
+	// requeue and resync for as long as the AppDeployment exists.
+	link := trace.LinkFromContext(tracing.ExtractAnnotations(ctx, appDeployment.Annotations))
+	ctx, span := tracing.Tracer().Start(ctx, "reconcile AppDeployment",
+		trace.WithNewRoot(),
+		trace.WithLinks(link),
+		trace.WithAttributes(
+			attribute.String("k8s.namespace.name", req.Namespace),
+			attribute.String("appstore.appdeployment.name", req.Name),
+			attribute.String("appstore.app.name", appDeployment.Spec.AppName),
+		),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	ctx = withHelmTimeout(ctx, appDeployment)
+
+	// Do nothing while reconciliation is paused operator-wide
+	paused, err := r.paused(ctx)
+	if err != nil {
+		logger.Error(err, "Failed to check whether reconciliation is paused")
+		return ctrl.Result{}, err
+	}
+	if paused {
+		logger.Info("Reconciliation is paused, skipping")
+		return r.updateStatusPaused(ctx, appDeployment)
+	}
+	if err := r.clearStatusPaused(ctx, appDeployment); err != nil {
+		logger.Error(err, "Failed to clear the Paused condition")
+		return ctrl.Result{}, err
+	}
+
 	// Check if the resource is being deleted
 	if !appDeployment.DeletionTimestamp.IsZero() {
 		return r.reconcileDelete(ctx, appDeployment)
@@ -98,6 +279,7 @@ func (r *AppDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	// Add finalizer if not present
 	if !controllerutil.ContainsFinalizer(appDeployment, finalizerName) {
 		controllerutil.AddFinalizer(appDeployment, finalizerName)
+		setSpecLabels(appDeployment)
 		if err := r.Update(ctx, appDeployment); err != nil {
 			logger.Error(err, "Failed to add finalizer")
 			return ctrl.Result{}, err
@@ -105,20 +287,54 @@ func (r *AppDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{Requeue: true}, nil
 	}
 
+	// Label the deployment with its app and team, also if it wasn't created by the
+	// operator's handler, so that it can be listed by them
+	if setSpecLabels(appDeployment) {
+		if err := r.Update(ctx, appDeployment); err != nil {
+			logger.Error(err, "Failed to set app and team labels")
+			return ctrl.Result{}, err
+		}
+	}
+
 	// Check if suspended
 	if appDeployment.Spec.Suspend {
 		logger.Info("AppDeployment is suspended, skipping reconciliation")
 		return ctrl.Result{}, nil
 	}
 
-	// Reconcile the Helm release
-	return r.reconcileHelm(ctx, appDeployment)
+	// Don't reconcile every deployment at once after a restart
+	if splay := r.startupSplay(appDeployment); splay > 0 {
+		logger.Info("Delaying the first reconcile after startup", "after", splay)
+		return ctrl.Result{RequeueAfter: splay}, nil
+	}
+
+	// Reconcile the Helm release, notifying the deployment's receiver when it finishes
+	previousPhase, previousGeneration := appDeployment.Status.Phase, appDeployment.Status.ObservedGeneration
+	result, err := r.reconcileHelm(ctx, appDeployment)
+	r.notifyStatus(ctx, appDeployment, previousPhase, previousGeneration)
+	return result, err
 }
 
 // reconcileHelm handles the Helm release installation/upgrade
 func (r *AppDeploymentReconciler) reconcileHelm(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
+	// A cancel request stops the install or upgrade, which isn't retried until the spec
+	// changes. Requests arriving after it completed are dropped.
+	if cancelRequested(appDeployment) {
+		if appDeployment.Status.Phase != appstorev1alpha1.PhaseDeployed {
+			logger.Info("Canceling deployment on request")
+			return r.updateStatusCanceled(ctx, appDeployment, "Deployment")
+		}
+		if err := r.clearCancelRequest(ctx, appDeployment); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if deploymentCanceled(appDeployment) {
+		logger.Info("Deployment was canceled, waiting for the spec to change")
+		return ctrl.Result{}, nil
+	}
+
 	// Validate that the requested chart exists
 	if r.ChartValidator != nil && !r.ChartValidator.ChartExists(appDeployment.Spec.AppName) {
 		availableCharts, _ := r.ChartValidator.ListCharts()
@@ -127,20 +343,74 @@ func (r *AppDeploymentReconciler) reconcileHelm(ctx context.Context, appDeployme
 		return r.updateStatusFailed(ctx, appDeployment, msg)
 	}
 
+	// Enforce the operator's chart allow/deny lists
+	if err := r.ChartPolicy.Check(appDeployment.Spec.AppName); err != nil {
+		logger.Info("Chart not allowed", "chart", appDeployment.Spec.AppName, "reason", err.Error())
+		return r.updateStatusFailedWithReason(ctx, appDeployment, "ChartNotAllowed", err.Error())
+	}
+
+	// Labels and annotations Kubernetes would reject fail every install or upgrade
+	if err := validateCommonMetadata(appDeployment); err != nil {
+		return r.updateStatusFailedWithReason(ctx, appDeployment, "InvalidCommonMetadata", err.Error())
+	}
+
+	// Install into the cluster of spec.clusterRef
+	ctx, err := r.targetCluster(ctx, appDeployment)
+	if err != nil {
+		return r.updateStatusFailedWithReason(ctx, appDeployment, "ClusterRefFailed", err.Error())
+	}
+
+	// Fail rather than deploy another version than the pinned one. Other errors loading the
+	// chart are reported by the install or upgrade.
+	if appDeployment.Spec.ChartVersion != "" {
+		_, err := r.HelmClient.GetChartMetadata(ctx, appDeployment.Spec.AppName, appDeployment.Spec.ChartVersion)
+		var mismatch *helm.VersionMismatchError
+		if errors.As(err, &mismatch) {
+			logger.Info("Pinned chart version not available", "chart", mismatch.Chart, "requested", mismatch.Requested, "available", mismatch.Available)
+			appDeployment.Status.AvailableChartVersion = mismatch.Available
+			return r.updateStatusFailedWithReason(ctx, appDeployment, "ChartVersionUnavailable", err.Error())
+		}
+	}
+	appDeployment.Status.AvailableChartVersion = ""
+
 	// Determine the release name
 	releaseName := appDeployment.Spec.ReleaseName
 	if releaseName == "" {
 		releaseName = appDeployment.Name
 	}
 
-	// Get values from spec and valuesFrom
-	values, err := r.getValues(ctx, appDeployment)
+	// Get values from spec, valuesFrom and secretKeyRefs
+	resolved, err := r.getValues(ctx, appDeployment)
 	if err != nil {
+		var valuesFromErr *valuesFromError
+		if errors.As(err, &valuesFromErr) {
+			return r.updateStatusFailedWithReason(ctx, appDeployment, "ValuesFromFailed", fmt.Sprintf("Failed to get values: %v", err))
+		}
 		return r.updateStatusFailed(ctx, appDeployment, fmt.Sprintf("Failed to get values: %v", err))
 	}
+	values := resolved.values
+
+	// Calculate values hash for change detection (secret values are redacted). It covers the
+	// common labels and annotations too.
+	valuesHash := releaseHash(appDeployment, resolved.redacted)
+
+	// Recorded with the next status update, whether the values are applied or fail
+	snapshot, err := json.Marshal(resolved.snapshot)
+	if err != nil {
+		return r.updateStatusFailed(ctx, appDeployment, fmt.Sprintf("Failed to marshal values: %v", err))
+	}
+	appDeployment.Status.LastAttemptedValues = &apiextensionsv1.JSON{Raw: snapshot}
+	appDeployment.Status.LastAttemptedValuesHash = valuesHash
 
-	// Calculate values hash for change detection
-	valuesHash := hashValues(values)
+	// Helm operations in a namespace that is being deleted fail
+	terminating, err := r.namespaceTerminating(ctx, appDeployment.Namespace)
+	if err != nil {
+		return r.updateStatusFailed(ctx, appDeployment, fmt.Sprintf("Failed to check the namespace: %v", err))
+	}
+	if terminating {
+		logger.Info("Namespace is terminating, not installing or upgrading", "namespace", appDeployment.Namespace)
+		return r.updateStatusNamespaceTerminating(ctx, appDeployment)
+	}
 
 	// Check if release exists
 	existingRelease, err := r.HelmClient.GetRelease(ctx, releaseName, appDeployment.Namespace)
@@ -148,49 +418,127 @@ func (r *AppDeploymentReconciler) reconcileHelm(ctx context.Context, appDeployme
 		return r.updateStatusFailed(ctx, appDeployment, fmt.Sprintf("Failed to check existing release: %v", err))
 	}
 
+	// Never take over the release of another AppDeployment with the same release name
+	if existingRelease != nil {
+		owner, err := r.releaseOwner(ctx, appDeployment, existingRelease)
+		if err != nil {
+			return r.updateStatusFailed(ctx, appDeployment, fmt.Sprintf("Failed to check the release owner: %v", err))
+		}
+		if owner != "" {
+			logger.Info("Release belongs to another AppDeployment", "release", releaseName, "owner", owner)
+			return r.updateStatusFailedWithReason(ctx, appDeployment, "ReleaseNameConflict",
+				releaseConflictMessage(releaseName, appDeployment.Namespace, owner))
+		}
+	}
+
 	var releaseInfo *helm.ReleaseInfo
 
 	if existingRelease == nil {
+		// Fail fast if the namespace quota can't fit the chart
+		if appDeployment.Spec.PreflightCheck {
+			if err := r.preflightCheck(ctx, appDeployment, releaseName, values); err != nil {
+				logger.Info("Preflight check failed", "reason", err.Error())
+				return r.updateStatusFailed(ctx, appDeployment, fmt.Sprintf("Preflight check failed: %v", err))
+			}
+		}
+
 		// Install new release
 		logger.Info("Installing new Helm release", "release", releaseName, "chart", appDeployment.Spec.AppName)
 
+		// The chart's pods may use the default service account as soon as they are created
+		r.injectImagePullSecret(ctx, appDeployment, releaseName)
+
 		if err := r.updateStatusPhase(ctx, appDeployment, appstorev1alpha1.PhaseInstalling, "Installing Helm chart"); err != nil {
 			return ctrl.Result{}, err
 		}
 
+		installCtx, stop := r.withCancelRequest(ctx, appDeployment)
 		releaseInfo, err = r.HelmClient.Install(
-			ctx,
+			installCtx,
 			releaseName,
 			appDeployment.Spec.AppName,
 			appDeployment.Namespace,
 			values,
 			appDeployment.Spec.ChartVersion,
+			withInstallOptions(appDeployment, helm.ActionOptions{}),
 		)
+		canceled := operationCanceled(installCtx)
+		stop()
 		if err != nil {
+			if canceled {
+				logger.Info("Install canceled on request", "release", releaseName)
+				return r.updateStatusCanceled(ctx, appDeployment, "Install")
+			}
+			var inProgress *helm.OperationInProgressError
+			if errors.As(err, &inProgress) {
+				return r.handleOperationInProgress(ctx, appDeployment, releaseName)
+			}
 			logger.Error(err, "Failed to install Helm chart")
+			r.recordFailedReleaseHooks(ctx, appDeployment, releaseName)
 			return r.updateStatusFailed(ctx, appDeployment, fmt.Sprintf("Failed to install: %v", err))
 		}
 	} else {
+		// Don't retry an upgrade that was rolled back until the spec or values change
+		if upgradeRolledBack(appDeployment, valuesHash) {
+			logger.Info("Skipping upgrade that was rolled back", "release", releaseName, "failedVersion", appDeployment.Status.RolledBackChartVersion)
+			return ctrl.Result{RequeueAfter: requeueAfterSuccess}, nil
+		}
+
 		// Check if upgrade is needed
 		needsUpgrade := r.needsUpgrade(appDeployment, existingRelease, valuesHash)
+		if !needsUpgrade {
+			needsUpgrade = r.autoUpgradeAvailable(ctx, appDeployment, existingRelease)
+		}
 
 		if needsUpgrade {
+			// Defer the upgrade until the maintenance window opens
+			wait, err := upgradeWindowWait(appDeployment.Spec.UpgradeWindow, r.now())
+			if err != nil {
+				return r.updateStatusFailed(ctx, appDeployment, err.Error())
+			}
+			if wait > 0 {
+				logger.Info("Deferring upgrade until the upgrade window opens", "release", releaseName, "wait", wait)
+				return r.updateStatusWaitingForWindow(ctx, appDeployment, wait)
+			}
+
+			// Don't cross major versions or declared breaking changes unless allowed
+			if !appDeployment.Spec.AllowMajorUpgrade {
+				target, err := r.HelmClient.GetChartMetadata(ctx, appDeployment.Spec.AppName, appDeployment.Spec.ChartVersion)
+				if err != nil {
+					return r.updateStatusFailed(ctx, appDeployment, fmt.Sprintf("Failed to load chart metadata: %v", err))
+				}
+				if err := checkUpgradeCompatibility(existingRelease, target); err != nil {
+					logger.Info("Upgrade blocked", "release", releaseName, "reason", err.Error())
+					return r.updateStatusFailedWithReason(ctx, appDeployment, "BreakingUpgrade", err.Error())
+				}
+			}
+
 			logger.Info("Upgrading Helm release", "release", releaseName, "chart", appDeployment.Spec.AppName)
 
 			if err := r.updateStatusPhase(ctx, appDeployment, appstorev1alpha1.PhaseUpgrading, "Upgrading Helm chart"); err != nil {
 				return ctrl.Result{}, err
 			}
 
-			releaseInfo, err = r.HelmClient.Upgrade(
-				ctx,
-				releaseName,
-				appDeployment.Spec.AppName,
-				appDeployment.Namespace,
-				values,
-				appDeployment.Spec.ChartVersion,
-			)
+			upgradeCtx, stop := r.withCancelRequest(ctx, appDeployment)
+			releaseInfo, err = r.upgradeRelease(upgradeCtx, appDeployment, releaseName, existingRelease, values)
+			canceled := operationCanceled(upgradeCtx)
+			stop()
 			if err != nil {
+				if canceled {
+					logger.Info("Upgrade canceled on request", "release", releaseName)
+					return r.updateStatusCanceled(ctx, appDeployment, "Upgrade")
+				}
+				var rolledBack *rolledBackError
+				if errors.As(err, &rolledBack) {
+					logger.Info("Upgrade was rolled back", "release", releaseName, "revision", rolledBack.Revision, "reason", err.Error())
+					return r.updateStatusRolledBack(ctx, appDeployment, rolledBack, valuesHash)
+				}
+				var inProgress *helm.OperationInProgressError
+				if errors.As(err, &inProgress) {
+					return r.handleOperationInProgress(ctx, appDeployment, releaseName)
+				}
 				logger.Error(err, "Failed to upgrade Helm chart")
+				r.recordFailedReleaseHooks(ctx, appDeployment, releaseName)
 				return r.updateStatusFailed(ctx, appDeployment, fmt.Sprintf("Failed to upgrade: %v", err))
 			}
 		} else {
@@ -199,6 +547,9 @@ func (r *AppDeploymentReconciler) reconcileHelm(ctx context.Context, appDeployme
 		}
 	}
 
+	// Also covers the service accounts created by the release
+	r.injectImagePullSecret(ctx, appDeployment, releaseName)
+
 	// Update status to deployed
 	return r.updateStatusDeployed(ctx, appDeployment, releaseInfo, valuesHash)
 }
@@ -219,19 +570,18 @@ func (r *AppDeploymentReconciler) reconcileDelete(ctx context.Context, appDeploy
 			return ctrl.Result{}, err
 		}
 
-		// Check if release exists before trying to uninstall
-		exists, err := r.HelmClient.ReleaseExists(ctx, releaseName, appDeployment.Namespace)
-		if err != nil {
-			logger.Error(err, "Failed to check if release exists")
-			return ctrl.Result{RequeueAfter: requeueAfterFailure}, err
-		}
-
-		if exists {
-			logger.Info("Uninstalling Helm release", "release", releaseName)
-			if err := r.HelmClient.Uninstall(ctx, releaseName, appDeployment.Namespace); err != nil {
-				logger.Error(err, "Failed to uninstall Helm release")
+		if err := r.uninstallRelease(ctx, appDeployment, releaseName); err != nil {
+			timeout := r.deletionTimeout(appDeployment)
+			if timeout == 0 || r.now().Sub(appDeployment.DeletionTimestamp.Time) < timeout {
 				return ctrl.Result{RequeueAfter: requeueAfterFailure}, err
 			}
+
+			// Give up rather than block deletion of the AppDeployment forever
+			message := fmt.Sprintf("Gave up uninstalling Helm release %s after %s, its resources may be orphaned: %v", releaseName, timeout, err)
+			logger.Info("Deletion timeout exceeded, removing finalizer", "release", releaseName, "timeout", timeout)
+			if err := r.updateStatusUninstallAbandoned(ctx, appDeployment, message); err != nil {
+				return ctrl.Result{}, err
+			}
 		}
 
 		// Remove finalizer
@@ -240,25 +590,118 @@ func (r *AppDeploymentReconciler) reconcileDelete(ctx context.Context, appDeploy
 			logger.Error(err, "Failed to remove finalizer")
 			return ctrl.Result{}, err
 		}
+		r.autoUpgradeChecks.Delete(appDeployment.UID)
+		r.observed.Delete(appDeployment.UID)
 	}
 
 	return ctrl.Result{}, nil
 }
 
-// getValues retrieves and merges values from spec and valuesFrom references
-func (r *AppDeploymentReconciler) getValues(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment) (map[string]interface{}, error) {
+// uninstallRelease uninstalls the Helm release if it exists and doesn't belong to another
+// AppDeployment
+func (r *AppDeploymentReconciler) uninstallRelease(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, releaseName string) error {
+	logger := log.FromContext(ctx)
+	namespace := appDeployment.Namespace
+
+	ctx, err := r.targetCluster(ctx, appDeployment)
+	if err != nil {
+		return err
+	}
+
+	// Check if release exists before trying to uninstall
+	release, err := r.HelmClient.GetRelease(ctx, releaseName, namespace)
+	if err != nil {
+		logger.Error(err, "Failed to check if release exists")
+		return err
+	}
+	if release == nil {
+		return nil
+	}
+
+	owner, err := r.releaseOwner(ctx, appDeployment, release)
+	if err != nil {
+		return err
+	}
+	if owner != "" {
+		logger.Info("Not uninstalling Helm release of another AppDeployment", "release", releaseName, "owner", owner)
+		return nil
+	}
+
+	logger.Info("Uninstalling Helm release", "release", releaseName)
+	if err := r.HelmClient.Uninstall(ctx, releaseName, namespace); err != nil {
+		logger.Error(err, "Failed to uninstall Helm release")
+		return err
+	}
+
+	return nil
+}
+
+// deletionTimeout returns how long a failing uninstall is retried, zero meaning forever
+func (r *AppDeploymentReconciler) deletionTimeout(appDeployment *appstorev1alpha1.AppDeployment) time.Duration {
+	if appDeployment.Spec.DeletionTimeout != nil {
+		return appDeployment.Spec.DeletionTimeout.Duration
+	}
+	return r.DeletionTimeout
+}
+
+// withHelmTimeout applies spec.timeout to the Helm actions run with the returned context
+func withHelmTimeout(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment) context.Context {
+	if appDeployment.Spec.Timeout == nil || appDeployment.Spec.Timeout.Duration <= 0 {
+		return ctx
+	}
+	return helm.WithActionTimeout(ctx, appDeployment.Spec.Timeout.Duration)
+}
+
+// deploymentValues are the merged values of an AppDeployment
+type deploymentValues struct {
+	// values are passed to Helm
+	values map[string]interface{}
+	// redacted has the values of secretKeyRefs, valuesFrom Secrets and SOPS-encrypted
+	// references replaced by a marker with the version of their source, so that it is safe
+	// to hash and log and its hash still changes when they do
+	redacted map[string]interface{}
+	// snapshot has the same values replaced by a marker, which is safe to store in the
+	// status
+	snapshot map[string]interface{}
+}
+
+// getValues retrieves and merges values from spec, valuesFrom and secretKeyRefs references
+func (r *AppDeploymentReconciler) getValues(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment) (*deploymentValues, error) {
 	values := make(map[string]interface{})
+	redacted := make(map[string]interface{})
+	snapshot := make(map[string]interface{})
 
-	// Get values from valuesFrom references first
+	// Get values from valuesFrom references first. All of them are resolved before failing,
+	// so that the error lists every one that failed.
+	outcomes := make([]valuesFromOutcome, 0, len(appDeployment.Spec.ValuesFrom))
 	for _, ref := range appDeployment.Spec.ValuesFrom {
-		refValues, err := r.getValuesFromReference(ctx, appDeployment.Namespace, ref)
+		refValues, version, encrypted, err := r.getValuesFromReference(ctx, appDeployment.Namespace, ref)
+		outcomes = append(outcomes, valuesFromOutcome{ref: describeValuesReference(ref), optional: ref.Optional, err: err})
 		if err != nil {
-			if ref.Optional {
-				continue
-			}
-			return nil, fmt.Errorf("failed to get values from %s/%s: %w", ref.Kind, ref.Name, err)
+			continue
+		}
+		values = helmvalues.Merge(values, refValues)
+		if ref.Kind == "Secret" || encrypted {
+			redacted = helmvalues.Merge(redacted, helmvalues.Redact(refValues, fmt.Sprintf("<redacted:%s@%s>", ref.Name, version)))
+			snapshot = helmvalues.Merge(snapshot, helmvalues.Redact(refValues, fmt.Sprintf("<redacted:%s>", ref.Name)))
+		} else {
+			redacted = helmvalues.Merge(redacted, refValues)
+			snapshot = helmvalues.Merge(snapshot, refValues)
+		}
+	}
+	if err := newValuesFromError(outcomes); err != nil {
+		return nil, err
+	}
+
+	// Layer the environment's overlay over the base values
+	if appDeployment.Spec.Environment != "" {
+		envValues, err := r.environmentValues(ctx, appDeployment)
+		if err != nil {
+			return nil, err
 		}
-		values = mergeMaps(values, refValues)
+		values = helmvalues.Merge(values, envValues)
+		redacted = helmvalues.Merge(redacted, envValues)
+		snapshot = helmvalues.Merge(snapshot, envValues)
 	}
 
 	// Merge spec values (these take precedence)
@@ -267,54 +710,92 @@ func (r *AppDeploymentReconciler) getValues(ctx context.Context, appDeployment *
 		if err := json.Unmarshal(appDeployment.Spec.Values.Raw, &specValues); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal spec values: %w", err)
 		}
-		values = mergeMaps(values, specValues)
+		values = helmvalues.Merge(values, specValues)
+		redacted = helmvalues.Merge(redacted, specValues)
+		snapshot = helmvalues.Merge(snapshot, specValues)
+	}
+
+	// Inject individual secret keys last
+	for _, ref := range appDeployment.Spec.SecretKeyRefs {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: appDeployment.Namespace}, secret); err != nil {
+			if ref.Optional && apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get Secret %s for values path %s: %w", ref.Name, ref.Path, err)
+		}
+		data, ok := secret.Data[ref.Key]
+		if !ok {
+			if ref.Optional {
+				continue
+			}
+			return nil, fmt.Errorf("key %s not found in Secret %s for values path %s", ref.Key, ref.Name, ref.Path)
+		}
+
+		if err := helmvalues.SetAtPath(values, ref.Path, string(data)); err != nil {
+			return nil, err
+		}
+		// The resource version makes secret rotations change the values hash
+		marker := fmt.Sprintf("<redacted:%s/%s@%s>", ref.Name, ref.Key, secret.ResourceVersion)
+		if err := helmvalues.SetAtPath(redacted, ref.Path, marker); err != nil {
+			return nil, err
+		}
+		if err := helmvalues.SetAtPath(snapshot, ref.Path, marker); err != nil {
+			return nil, err
+		}
 	}
 
-	return values, nil
+	return &deploymentValues{values: values, redacted: redacted, snapshot: snapshot}, nil
 }
 
-// getValuesFromReference retrieves values from a ConfigMap or Secret
-func (r *AppDeploymentReconciler) getValuesFromReference(ctx context.Context, namespace string, ref appstorev1alpha1.ValuesReference) (map[string]interface{}, error) {
-	key := ref.ValuesKey
-	if key == "" {
-		key = "values.yaml"
+// getValuesFromReference retrieves values from a ConfigMap, Secret or URL. It returns the
+// version of the source, which changes whenever the values do, and reports whether the
+// values were decrypted, so that they are kept out of the status.
+func (r *AppDeploymentReconciler) getValuesFromReference(ctx context.Context, namespace string, ref appstorev1alpha1.ValuesReference) (map[string]interface{}, string, bool, error) {
+	if ref.Kind == "URL" {
+		return r.getValuesFromURL(ctx, namespace, ref)
 	}
 
-	var data string
+	var data map[string][]byte
+	var version string
 
 	switch ref.Kind {
 	case "ConfigMap":
 		cm := &corev1.ConfigMap{}
 		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace}, cm); err != nil {
-			return nil, err
+			return nil, "", false, err
 		}
-		var ok bool
-		data, ok = cm.Data[key]
-		if !ok {
-			return nil, fmt.Errorf("key %s not found in ConfigMap %s", key, ref.Name)
+		data = make(map[string][]byte, len(cm.Data)+len(cm.BinaryData))
+		for key, value := range cm.Data {
+			data[key] = []byte(value)
+		}
+		for key, value := range cm.BinaryData {
+			data[key] = value
 		}
+		version = cm.ResourceVersion
 
 	case "Secret":
 		secret := &corev1.Secret{}
 		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace}, secret); err != nil {
-			return nil, err
-		}
-		dataBytes, ok := secret.Data[key]
-		if !ok {
-			return nil, fmt.Errorf("key %s not found in Secret %s", key, ref.Name)
+			return nil, "", false, err
 		}
-		data = string(dataBytes)
+		data = secret.Data
+		version = secret.ResourceVersion
 
 	default:
-		return nil, fmt.Errorf("unsupported kind: %s", ref.Kind)
+		return nil, "", false, fmt.Errorf("unsupported kind: %s", ref.Kind)
 	}
 
-	var values map[string]interface{}
-	if err := json.Unmarshal([]byte(data), &values); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal values: %w", err)
-	}
+	values, encrypted, err := valuesFromKeys(ref, data, r.SOPSIdentities)
+	return values, version, encrypted, err
+}
 
-	return values, nil
+// describeValuesReference returns a short description of a values reference for messages
+func describeValuesReference(ref appstorev1alpha1.ValuesReference) string {
+	if ref.Kind == "URL" {
+		return ref.URL
+	}
+	return ref.Kind + "/" + ref.Name
 }
 
 // needsUpgrade determines if the Helm release needs to be upgraded
@@ -332,12 +813,21 @@ func (r *AppDeploymentReconciler) needsUpgrade(appDeployment *appstorev1alpha1.A
 	return false
 }
 
+// upgradeRolledBack reports whether the desired state is the upgrade that was last rolled back
+func upgradeRolledBack(appDeployment *appstorev1alpha1.AppDeployment, valuesHash string) bool {
+	status := appDeployment.Status
+	return meta.IsStatusConditionTrue(status.Conditions, ConditionTypeRolledBack) &&
+		status.ObservedGeneration == appDeployment.Generation &&
+		status.RolledBackValuesHash == valuesHash
+}
+
 // updateStatusPhase updates the status phase
 func (r *AppDeploymentReconciler) updateStatusPhase(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, phase appstorev1alpha1.AppDeploymentPhase, message string) error {
 	appDeployment.Status.Phase = phase
 	appDeployment.Status.Message = message
 	appDeployment.Status.LastReconcileTime = &metav1.Time{Time: time.Now()}
 	appDeployment.Status.ObservedGeneration = appDeployment.Generation
+	appDeployment.Status.Progress = deploymentProgress(&appDeployment.Status)
 
 	meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
 		Type:               ConditionTypeReconciling,
@@ -352,12 +842,18 @@ func (r *AppDeploymentReconciler) updateStatusPhase(ctx context.Context, appDepl
 
 // updateStatusDeployed updates the status after successful deployment
 func (r *AppDeploymentReconciler) updateStatusDeployed(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, releaseInfo *helm.ReleaseInfo, valuesHash string) (ctrl.Result, error) {
+	// Hooks only run for a new revision
+	if releaseInfo.Revision != appDeployment.Status.HelmReleaseRevision {
+		r.recordHooks(appDeployment, releaseInfo)
+	}
+
 	appDeployment.Status.Phase = appstorev1alpha1.PhaseDeployed
 	appDeployment.Status.Message = "Helm release deployed successfully"
 	appDeployment.Status.HelmReleaseName = releaseInfo.Name
 	appDeployment.Status.HelmReleaseRevision = releaseInfo.Revision
 	appDeployment.Status.DeployedChartVersion = releaseInfo.ChartVersion
 	appDeployment.Status.LastAppliedValuesHash = valuesHash
+	appDeployment.Status.LastAppliedValues = appDeployment.Status.LastAttemptedValues.DeepCopy()
 	appDeployment.Status.LastReconcileTime = &metav1.Time{Time: time.Now()}
 	appDeployment.Status.ObservedGeneration = appDeployment.Generation
 	appDeployment.Status.FailureCount = 0
@@ -378,6 +874,69 @@ func (r *AppDeploymentReconciler) updateStatusDeployed(ctx context.Context, appD
 		LastTransitionTime: metav1.Now(),
 	})
 
+	r.setHealthStatus(ctx, appDeployment, releaseInfo.Name)
+	appDeployment.Status.Progress = deploymentProgress(&appDeployment.Status)
+
+	if meta.FindStatusCondition(appDeployment.Status.Conditions, ConditionTypeRolledBack) != nil {
+		meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+			Type:               ConditionTypeRolledBack,
+			Status:             metav1.ConditionFalse,
+			Reason:             "Deployed",
+			Message:            "Helm release is deployed",
+			LastTransitionTime: metav1.Now(),
+		})
+	}
+
+	if err := r.Status().Update(ctx, appDeployment); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Check back sooner until the workloads are ready
+	if !meta.IsStatusConditionTrue(appDeployment.Status.Conditions, ConditionTypeHealthy) {
+		return ctrl.Result{RequeueAfter: requeueAfterFailure}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfterSuccess}, nil
+}
+
+// updateStatusRolledBack updates the status after an upgrade was automatically rolled back
+func (r *AppDeploymentReconciler) updateStatusRolledBack(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, rolledBack *rolledBackError, valuesHash string) (ctrl.Result, error) {
+	message := fmt.Sprintf("Upgrade rolled back: %v", rolledBack)
+
+	appDeployment.Status.Phase = appstorev1alpha1.PhaseFailed
+	appDeployment.Status.Message = message
+	appDeployment.Status.LastAttemptedChartVersion = rolledBack.FailedVersion
+	appDeployment.Status.RolledBackChartVersion = rolledBack.FailedVersion
+	appDeployment.Status.RolledBackValuesHash = valuesHash
+	appDeployment.Status.LastReconcileTime = &metav1.Time{Time: time.Now()}
+	appDeployment.Status.ObservedGeneration = appDeployment.Generation
+	appDeployment.Status.FailureCount++
+	appDeployment.Status.Progress = deploymentProgress(&appDeployment.Status)
+
+	meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeRolledBack,
+		Status:             metav1.ConditionTrue,
+		Reason:             "HealthCheckFailed",
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	})
+
+	meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             "RolledBack",
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	})
+
+	meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeReconciling,
+		Status:             metav1.ConditionFalse,
+		Reason:             "RolledBack",
+		Message:            "Reconciliation complete",
+		LastTransitionTime: metav1.Now(),
+	})
+
 	if err := r.Status().Update(ctx, appDeployment); err != nil {
 		return ctrl.Result{}, err
 	}
@@ -385,18 +944,67 @@ func (r *AppDeploymentReconciler) updateStatusDeployed(ctx context.Context, appD
 	return ctrl.Result{RequeueAfter: requeueAfterSuccess}, nil
 }
 
+// updateStatusWaitingForWindow records a deferred upgrade and requeues when the upgrade window opens
+func (r *AppDeploymentReconciler) updateStatusWaitingForWindow(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, wait time.Duration) (ctrl.Result, error) {
+	message := fmt.Sprintf("Upgrade deferred until the upgrade window opens in %s", wait.Round(time.Second))
+
+	appDeployment.Status.Message = message
+	appDeployment.Status.LastReconcileTime = &metav1.Time{Time: time.Now()}
+
+	meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeReconciling,
+		Status:             metav1.ConditionTrue,
+		Reason:             "WaitingForUpgradeWindow",
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	})
+
+	if err := r.Status().Update(ctx, appDeployment); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: wait}, nil
+}
+
+// updateStatusUninstallAbandoned records that the uninstall was given up on and emits a warning Event
+func (r *AppDeploymentReconciler) updateStatusUninstallAbandoned(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, message string) error {
+	if r.Recorder != nil {
+		r.Recorder.Event(appDeployment, corev1.EventTypeWarning, "UninstallAbandoned", message)
+	}
+
+	now := metav1.NewTime(r.now())
+	appDeployment.Status.Message = message
+	appDeployment.Status.LastReconcileTime = &now
+
+	meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             "UninstallAbandoned",
+		Message:            message,
+		LastTransitionTime: now,
+	})
+
+	return r.Status().Update(ctx, appDeployment)
+}
+
 // updateStatusFailed updates the status after a failure
 func (r *AppDeploymentReconciler) updateStatusFailed(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, message string) (ctrl.Result, error) {
+	return r.updateStatusFailedWithReason(ctx, appDeployment, "Failed", message)
+}
+
+// updateStatusFailedWithReason updates the status after a failure, with a specific Ready condition reason
+func (r *AppDeploymentReconciler) updateStatusFailedWithReason(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, reason, message string) (ctrl.Result, error) {
 	appDeployment.Status.Phase = appstorev1alpha1.PhaseFailed
 	appDeployment.Status.Message = message
 	appDeployment.Status.LastReconcileTime = &metav1.Time{Time: time.Now()}
 	appDeployment.Status.ObservedGeneration = appDeployment.Generation
 	appDeployment.Status.FailureCount++
+	appDeployment.Status.Progress = deploymentProgress(&appDeployment.Status)
 
 	meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
 		Type:               ConditionTypeReady,
 		Status:             metav1.ConditionFalse,
-		Reason:             "Failed",
+		Reason:             reason,
 		Message:            message,
 		LastTransitionTime: metav1.Now(),
 	})
@@ -408,33 +1016,46 @@ func (r *AppDeploymentReconciler) updateStatusFailed(ctx context.Context, appDep
 	return ctrl.Result{RequeueAfter: requeueAfterFailure}, nil
 }
 
-// hashValues creates a SHA256 hash of the values map
-func hashValues(values map[string]interface{}) string {
-	data, _ := json.Marshal(values)
-	hash := sha256.Sum256(data)
-	return fmt.Sprintf("%x", hash[:8])
-}
-
-// mergeMaps recursively merges src into dst
-func mergeMaps(dst, src map[string]interface{}) map[string]interface{} {
-	for key, srcVal := range src {
-		if dstVal, exists := dst[key]; exists {
-			srcMap, srcOk := srcVal.(map[string]interface{})
-			dstMap, dstOk := dstVal.(map[string]interface{})
-			if srcOk && dstOk {
-				dst[key] = mergeMaps(dstMap, srcMap)
-				continue
-			}
-		}
-		dst[key] = srcVal
+// now returns the current time from the reconciler's clock
+func (r *AppDeploymentReconciler) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
 	}
-	return dst
+	return r.Clock.Now()
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *AppDeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&appstorev1alpha1.AppDeployment{}).
-		Named("appdeployment").
-		Complete(r)
+		Watches(&corev1.ConfigMap{}, r.valuesFromHandler("ConfigMap")).
+		Watches(&corev1.Secret{}, r.valuesFromHandler("Secret")).
+		Named("appdeployment")
+
+	if len(r.WatchNamespaces) > 0 {
+		b = b.WithEventFilter(namespacePredicate(r.WatchNamespaces))
+	}
+
+	if r.ChartChanges != nil {
+		b = b.WatchesRawSource(r.chartChangeSource())
+	}
+
+	if r.ImagePullSecret != nil {
+		pullSecretCache, err := newObjectCache(mgr, &corev1.Secret{}, *r.ImagePullSecret)
+		if err != nil {
+			return fmt.Errorf("failed to create the image pull secret cache: %w", err)
+		}
+		r.pullSecretReader = pullSecretCache
+	}
+
+	if r.PauseConfigMap != nil {
+		// A raw source isn't filtered by the namespace predicate
+		src, err := r.pauseSource(mgr)
+		if err != nil {
+			return err
+		}
+		b = b.WatchesRawSource(src)
+	}
+
+	return b.Complete(r)
 }
@@ -0,0 +1,127 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+// chartChangeSource enqueues the AppDeployments with spec.autoUpgrade of the charts
+// received from ChartChanges, so that they are upgraded as soon as the charts are synced
+// rather than on their next periodic reconcile.
+func (r *AppDeploymentReconciler) chartChangeSource() source.Source {
+	return source.Func(func(ctx context.Context, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) error {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case charts, ok := <-r.ChartChanges:
+					if !ok {
+						return
+					}
+					r.forgetChartFiles(charts)
+					for _, req := range r.autoUpgradeRequests(ctx, charts) {
+						queue.Add(req)
+					}
+				}
+			}
+		}()
+		return nil
+	})
+}
+
+// autoUpgradeRequests returns reconcile requests for the AppDeployments in the watched
+// namespaces that deploy one of the given charts with spec.autoUpgrade
+func (r *AppDeploymentReconciler) autoUpgradeRequests(ctx context.Context, charts []string) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	changed := make(map[string]bool, len(charts))
+	for _, chart := range charts {
+		changed[chart] = true
+	}
+	watched := make(map[string]bool, len(r.WatchNamespaces))
+	for _, ns := range r.WatchNamespaces {
+		watched[ns] = true
+	}
+
+	var list appstorev1alpha1.AppDeploymentList
+	if err := r.List(ctx, &list); err != nil {
+		logger.Error(err, "Failed to list AppDeployments for changed charts", "charts", charts)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, ad := range list.Items {
+		if !ad.Spec.AutoUpgrade || !changed[ad.Spec.AppName] {
+			continue
+		}
+		if len(watched) > 0 && !watched[ad.Namespace] {
+			continue
+		}
+		// Check the changed chart right away
+		r.autoUpgradeChecks.Delete(ad.UID)
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: ad.Namespace, Name: ad.Name}})
+	}
+	if len(requests) > 0 {
+		logger.Info("Charts changed, reconciling AppDeployments with autoUpgrade", "charts", charts, "deployments", len(requests))
+	}
+	return requests
+}
+
+// autoUpgradeCheckInterval limits how often an AppDeployment with spec.autoUpgrade checks
+// for a new chart version. It is a little shorter than requeueAfterSuccess, so that the
+// periodic reconciles check; reconciles caused by status updates in between don't.
+const autoUpgradeCheckInterval = 4 * time.Minute
+
+// autoUpgradeAvailable reports whether an AppDeployment with spec.autoUpgrade should be
+// upgraded to a new version of its chart. It checks at most every
+// autoUpgradeCheckInterval, unless the chart changed. Failing to check, e.g. while a
+// repository is unavailable, doesn't affect the deployed release, so it is only reported
+// with an Event and retried on the next reconcile.
+func (r *AppDeploymentReconciler) autoUpgradeAvailable(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, release *helm.ReleaseInfo) bool {
+	if !appDeployment.Spec.AutoUpgrade {
+		return false
+	}
+	now := r.now()
+	if last, ok := r.autoUpgradeChecks.Load(appDeployment.UID); ok && now.Sub(last.(time.Time)) < autoUpgradeCheckInterval {
+		return false
+	}
+
+	available, err := r.HelmClient.GetChartMetadata(ctx, appDeployment.Spec.AppName, appDeployment.Spec.ChartVersion)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to check for a new chart version")
+		if r.Recorder != nil {
+			r.Recorder.Eventf(appDeployment, corev1.EventTypeWarning, "AutoUpgradeCheckFailed",
+				"Failed to check for a new version of chart %s: %v", appDeployment.Spec.AppName, err)
+		}
+		return false
+	}
+	r.autoUpgradeChecks.Store(appDeployment.UID, now)
+	return available.Version != release.ChartVersion
+}
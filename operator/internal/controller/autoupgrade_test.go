@@ -0,0 +1,191 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+var _ = Describe("Auto upgrades", func() {
+	var (
+		ctx      context.Context
+		fakeHelm *fakeHelmClient
+	)
+
+	newAppDeployment := func(namespace, name, appName string, autoUpgrade bool) *appstorev1alpha1.AppDeployment {
+		return &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       name,
+				Namespace:  namespace,
+				Finalizers: []string{finalizerName},
+			},
+			Spec: appstorev1alpha1.AppDeploymentSpec{AppName: appName, TeamID: "team-a", AutoUpgrade: autoUpgrade},
+		}
+	}
+
+	newReconciler := func(objects ...client.Object) *AppDeploymentReconciler {
+		return &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(objects...).
+				WithStatusSubresource(&appstorev1alpha1.AppDeployment{}).
+				Build(),
+			HelmClient: fakeHelm,
+		}
+	}
+
+	request := func(namespace, name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{}
+	})
+
+	It("enqueues the deployments with autoUpgrade of changed charts", func() {
+		reconciler := newReconciler(
+			newAppDeployment("default", "db", "postgresql", true),
+			newAppDeployment("team-b", "db", "postgresql", true),
+			newAppDeployment("default", "pinned-db", "postgresql", false),
+			newAppDeployment("default", "cache", "valkey", true),
+		)
+		changes := make(chan []string, 1)
+		reconciler.ChartChanges = changes
+
+		queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+		defer queue.ShutDown()
+		sourceCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		Expect(reconciler.chartChangeSource().Start(sourceCtx, queue)).To(Succeed())
+
+		changes <- []string{"postgresql", "mysql"}
+		Eventually(queue.Len).Should(Equal(2))
+		Consistently(queue.Len).Should(Equal(2))
+
+		var queued []reconcile.Request
+		for range 2 {
+			req, _ := queue.Get()
+			queued = append(queued, req)
+			queue.Done(req)
+		}
+		Expect(queued).To(ConsistOf(request("default", "db"), request("team-b", "db")))
+	})
+
+	It("only enqueues deployments in the watched namespaces", func() {
+		reconciler := newReconciler(
+			newAppDeployment("default", "db", "postgresql", true),
+			newAppDeployment("team-b", "db", "postgresql", true),
+		)
+		reconciler.WatchNamespaces = []string{"team-b"}
+
+		Expect(reconciler.autoUpgradeRequests(ctx, []string{"postgresql"})).To(ConsistOf(request("team-b", "db")))
+		Expect(reconciler.autoUpgradeRequests(ctx, []string{"valkey"})).To(BeEmpty())
+	})
+
+	It("upgrades deployments with autoUpgrade to a new chart version", func() {
+		for autoUpgrade, upgrades := range map[bool]int{true: 1, false: 0} {
+			fakeHelm = &fakeHelmClient{Charts: map[string]*chart.Metadata{"": {Name: "postgresql", Version: "1.0.0"}}}
+			ad := newAppDeployment("default", "db", "postgresql", autoUpgrade)
+			reconciler := newReconciler(ad)
+			fakeClock := clocktesting.NewFakePassiveClock(time.Now())
+			reconciler.Clock = fakeClock
+			reconcile := func() {
+				_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ad)})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			reconcile()
+			Expect(fakeHelm.callsTo("Install")).To(HaveLen(1))
+			fakeHelm.Releases["db"].ChartVersion = "1.0.0"
+			reconcile()
+			Expect(fakeHelm.callsTo("Upgrade")).To(BeEmpty())
+
+			// A new version of the chart is synced, which is only checked for after a while
+			fakeHelm.Charts[""] = &chart.Metadata{Name: "postgresql", Version: "1.1.0"}
+			reconcile()
+			Expect(fakeHelm.callsTo("Upgrade")).To(BeEmpty())
+
+			fakeClock.SetTime(fakeClock.Now().Add(autoUpgradeCheckInterval))
+			reconcile()
+			Expect(fakeHelm.callsTo("Upgrade")).To(HaveLen(upgrades), "autoUpgrade: %v", autoUpgrade)
+		}
+	})
+
+	It("checks a changed chart right away", func() {
+		fakeHelm = &fakeHelmClient{Charts: map[string]*chart.Metadata{"": {Name: "postgresql", Version: "1.0.0"}}}
+		ad := newAppDeployment("default", "db", "postgresql", true)
+		reconciler := newReconciler(ad)
+		reconcile := func() {
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ad)})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		reconcile()
+		fakeHelm.Releases["db"].ChartVersion = "1.0.0"
+		reconcile()
+
+		fakeHelm.Charts[""] = &chart.Metadata{Name: "postgresql", Version: "1.1.0"}
+		Expect(reconciler.autoUpgradeRequests(ctx, []string{"postgresql"})).To(HaveLen(1))
+		reconcile()
+		Expect(fakeHelm.callsTo("Upgrade")).To(HaveLen(1))
+	})
+
+	It("keeps the deployment deployed when checking for a new version fails", func() {
+		fakeHelm = &fakeHelmClient{Charts: map[string]*chart.Metadata{"": {Name: "postgresql", Version: "1.0.0"}}}
+		ad := newAppDeployment("default", "db", "postgresql", true)
+		reconciler := newReconciler(ad)
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ad)})
+		Expect(err).NotTo(HaveOccurred())
+		fakeHelm.Releases["db"].ChartVersion = "1.0.0"
+
+		fakeHelm.ChartErr = errors.New("repository unavailable")
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ad)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &appstorev1alpha1.AppDeployment{}
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), updated)).To(Succeed())
+		Expect(updated.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+		Expect(updated.Status.FailureCount).To(BeZero())
+		var events []string
+		for len(recorder.Events) > 0 {
+			events = append(events, <-recorder.Events)
+		}
+		Expect(events).To(ContainElement(ContainSubstring("AutoUpgradeCheckFailed")))
+	})
+})
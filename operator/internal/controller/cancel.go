@@ -0,0 +1,140 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+// ReasonCanceled is the Ready condition reason of a deployment whose install or upgrade
+// was canceled on request
+const ReasonCanceled = "Canceled"
+
+// defaultCancelPollInterval is used when CancelPollInterval is not set
+const defaultCancelPollInterval = 2 * time.Second
+
+// errCancelRequested is the cause of a Helm operation canceled through the cancel annotation
+var errCancelRequested = errors.New("canceled on request")
+
+// cancelRequested reports whether the AppDeployment carries a cancel request
+func cancelRequested(appDeployment *appstorev1alpha1.AppDeployment) bool {
+	_, ok := appDeployment.Annotations[appstorev1alpha1.CancelRequestedAnnotation]
+	return ok
+}
+
+// deploymentCanceled reports whether the install or upgrade of the current spec was canceled.
+// It isn't retried until the spec changes.
+func deploymentCanceled(appDeployment *appstorev1alpha1.AppDeployment) bool {
+	cond := meta.FindStatusCondition(appDeployment.Status.Conditions, ConditionTypeReady)
+	return cond != nil && cond.Reason == ReasonCanceled &&
+		appDeployment.Status.ObservedGeneration == appDeployment.Generation
+}
+
+// withCancelRequest returns a context for a Helm operation that is canceled when the
+// AppDeployment is asked to cancel, and a function releasing it. The AppDeployment is
+// checked every CancelPollInterval while the operation runs.
+func (r *AppDeploymentReconciler) withCancelRequest(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment) (context.Context, context.CancelFunc) {
+	opCtx, cancel := context.WithCancelCause(ctx)
+	interval := r.CancelPollInterval
+	if interval <= 0 {
+		interval = defaultCancelPollInterval
+	}
+
+	key := client.ObjectKeyFromObject(appDeployment)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-opCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			current := &appstorev1alpha1.AppDeployment{}
+			if err := r.Get(opCtx, key, current); err == nil && cancelRequested(current) {
+				cancel(errCancelRequested)
+				return
+			}
+		}
+	}()
+
+	return opCtx, func() { cancel(context.Canceled) }
+}
+
+// operationCanceled reports whether a context from withCancelRequest was canceled on request
+func operationCanceled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errCancelRequested)
+}
+
+// updateStatusCanceled marks the deployment as failed with reason Canceled, emits an Event
+// and removes the cancel request. operation is what was canceled, e.g. Install.
+func (r *AppDeploymentReconciler) updateStatusCanceled(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, operation string) (ctrl.Result, error) {
+	// The cancel request has usually been added since the AppDeployment was read
+	latest := &appstorev1alpha1.AppDeployment{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(appDeployment), latest); err != nil {
+		return ctrl.Result{}, err
+	}
+	appDeployment.ResourceVersion = latest.ResourceVersion
+	appDeployment.Annotations = latest.Annotations
+
+	message := fmt.Sprintf("%s canceled by request %s; it is retried when the AppDeployment is updated",
+		operation, appDeployment.Annotations[appstorev1alpha1.CancelRequestedAnnotation])
+	if r.Recorder != nil {
+		r.Recorder.Event(appDeployment, corev1.EventTypeWarning, ReasonCanceled, message)
+	}
+
+	meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeReconciling,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonCanceled,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	})
+
+	// The status is recorded first, so that the operation isn't retried if removing the
+	// request fails
+	if _, err := r.updateStatusFailedWithReason(ctx, appDeployment, ReasonCanceled, message); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.clearCancelRequest(ctx, appDeployment); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// clearCancelRequest removes the cancel annotation from the AppDeployment. Only the
+// annotation is patched, so a spec changed in the meantime is kept.
+func (r *AppDeploymentReconciler) clearCancelRequest(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment) error {
+	if !cancelRequested(appDeployment) {
+		return nil
+	}
+	patch := client.MergeFrom(appDeployment.DeepCopy())
+	delete(appDeployment.Annotations, appstorev1alpha1.CancelRequestedAnnotation)
+	return r.Patch(ctx, appDeployment, patch)
+}
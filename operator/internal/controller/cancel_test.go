@@ -0,0 +1,180 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+// cancelingHelmClient requests a cancel of the AppDeployment when an install or upgrade
+// starts, then blocks like a Helm operation waiting for its resources until the context
+// is canceled
+type cancelingHelmClient struct {
+	*fakeHelmClient
+	requestCancel func(ctx context.Context)
+}
+
+func (c *cancelingHelmClient) block(ctx context.Context) error {
+	c.requestCancel(ctx)
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("release db failed: %w", ctx.Err())
+	case <-time.After(5 * time.Second):
+		return errors.New("operation was not canceled")
+	}
+}
+
+func (c *cancelingHelmClient) Install(ctx context.Context, releaseName, chartName, namespace string, values map[string]interface{}, version string, opts helm.ActionOptions) (*helm.ReleaseInfo, error) {
+	c.Calls = append(c.Calls, helmCall{Method: "Install", Values: values, Options: opts})
+	return nil, c.block(ctx)
+}
+
+func (c *cancelingHelmClient) Upgrade(ctx context.Context, releaseName, chartName, namespace string, values map[string]interface{}, version string, opts helm.ActionOptions) (*helm.ReleaseInfo, error) {
+	c.Calls = append(c.Calls, helmCall{Method: "Upgrade", Values: values, Options: opts})
+	return nil, c.block(ctx)
+}
+
+var _ = Describe("Canceling deployments", func() {
+	var (
+		ctx        context.Context
+		fakeHelm   *fakeHelmClient
+		recorder   *record.FakeRecorder
+		reconciler *AppDeploymentReconciler
+		ad         *appstorev1alpha1.AppDeployment
+	)
+
+	// requestCancel adds the cancel annotation like the RabbitMQ handler does
+	requestCancel := func(ctx context.Context) {
+		current := &appstorev1alpha1.AppDeployment{}
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), current)).To(Succeed())
+		current.Annotations = map[string]string{appstorev1alpha1.CancelRequestedAnnotation: "req-1"}
+		Expect(reconciler.Update(ctx, current)).To(Succeed())
+	}
+
+	reconcileHelm := func() {
+		_, err := reconciler.reconcileHelm(ctx, ad)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+	}
+
+	expectCanceled := func(message string) {
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseFailed))
+		cond := meta.FindStatusCondition(ad.Status.Conditions, ConditionTypeReady)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Reason).To(Equal(ReasonCanceled))
+		Expect(cond.Message).To(ContainSubstring(message))
+		Expect(meta.IsStatusConditionTrue(ad.Status.Conditions, ConditionTypeReconciling)).To(BeFalse())
+		Expect(ad.Annotations).NotTo(HaveKey(appstorev1alpha1.CancelRequestedAnnotation))
+		Expect(recorder.Events).To(Receive(ContainSubstring("Warning Canceled " + message)))
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{}
+		recorder = record.NewFakeRecorder(10)
+		ad = &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Generation: 1},
+			Spec:       appstorev1alpha1.AppDeploymentSpec{AppName: "postgresql", TeamID: "team-a"},
+		}
+		reconciler = &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(ad).
+				WithStatusSubresource(ad).
+				Build(),
+			HelmClient:         &cancelingHelmClient{fakeHelmClient: fakeHelm, requestCancel: requestCancel},
+			Recorder:           recorder,
+			CancelPollInterval: 10 * time.Millisecond,
+		}
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+	})
+
+	It("cancels an install in progress", func() {
+		reconcileHelm()
+
+		Expect(fakeHelm.callsTo("Install")).To(HaveLen(1))
+		expectCanceled("Install canceled by request req-1")
+	})
+
+	It("cancels an upgrade in progress", func() {
+		fakeHelm.setRelease(&helm.ReleaseInfo{Name: "db", Namespace: "default", Revision: 1, Status: releaseStatusDeployed, ChartName: "postgresql", ChartVersion: "15.2.0"})
+		ad.Spec.ChartVersion = "15.3.0"
+		Expect(reconciler.Update(ctx, ad)).To(Succeed())
+
+		reconcileHelm()
+
+		Expect(fakeHelm.callsTo("Upgrade")).To(HaveLen(1))
+		expectCanceled("Upgrade canceled by request req-1")
+		Expect(ad.Spec.ChartVersion).To(Equal("15.3.0"))
+	})
+
+	It("doesn't retry a canceled deployment until the spec changes", func() {
+		reconcileHelm()
+		expectCanceled("Install canceled by request req-1")
+
+		reconcileHelm()
+		Expect(fakeHelm.callsTo("Install")).To(HaveLen(1))
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseFailed))
+
+		// A new generation of the spec is installed again
+		reconciler.HelmClient = fakeHelm
+		ad.Generation = 2
+		reconcileHelm()
+		Expect(fakeHelm.callsTo("Install")).To(HaveLen(2))
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+	})
+
+	It("cancels a deployment that is waiting to be retried", func() {
+		reconciler.HelmClient = fakeHelm
+		requestCancel(ctx)
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+
+		reconcileHelm()
+
+		Expect(fakeHelm.Calls).To(BeEmpty())
+		expectCanceled("Deployment canceled by request req-1")
+	})
+
+	It("drops a cancel request that arrives after the deployment completed", func() {
+		reconciler.HelmClient = fakeHelm
+		reconcileHelm()
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+
+		requestCancel(ctx)
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		reconcileHelm()
+
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+		Expect(ad.Annotations).NotTo(HaveKey(appstorev1alpha1.CancelRequestedAnnotation))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
@@ -0,0 +1,97 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ChartPolicy restricts which catalog charts may be deployed. Patterns are globs
+// as in path.Match, e.g. "postgres*".
+type ChartPolicy struct {
+	// Allow lists the charts that may be deployed (all charts if empty)
+	Allow []string
+	// Deny lists charts that may not be deployed, even if allowed
+	Deny []string
+}
+
+// NewChartPolicy parses comma-separated allow and deny lists of chart name patterns.
+// It returns nil, allowing every chart, if both lists are empty.
+func NewChartPolicy(allow, deny string) (*ChartPolicy, error) {
+	allowPatterns, err := parseChartPatterns(allow)
+	if err != nil {
+		return nil, err
+	}
+	denyPatterns, err := parseChartPatterns(deny)
+	if err != nil {
+		return nil, err
+	}
+	if len(allowPatterns) == 0 && len(denyPatterns) == 0 {
+		return nil, nil
+	}
+	return &ChartPolicy{Allow: allowPatterns, Deny: denyPatterns}, nil
+}
+
+// parseChartPatterns splits a comma-separated list of glob patterns and validates them
+func parseChartPatterns(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return nil, fmt.Errorf("empty chart pattern in %q", value)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid chart pattern %q: %w", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// Check returns an error if the chart may not be deployed. A nil policy allows all charts.
+func (p *ChartPolicy) Check(chartName string) error {
+	if p == nil {
+		return nil
+	}
+
+	if pattern, ok := matchChart(p.Deny, chartName); ok {
+		return fmt.Errorf("chart %q is denied by the operator policy (%s)", chartName, pattern)
+	}
+	if len(p.Allow) > 0 {
+		if _, ok := matchChart(p.Allow, chartName); !ok {
+			return fmt.Errorf("chart %q is not in the operator's allowed charts %v", chartName, p.Allow)
+		}
+	}
+	return nil
+}
+
+// matchChart returns the first pattern matching the chart name
+func matchChart(patterns []string, chartName string) (string, bool) {
+	for _, pattern := range patterns {
+		// Patterns are validated when the policy is created
+		if ok, _ := path.Match(pattern, chartName); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
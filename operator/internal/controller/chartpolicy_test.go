@@ -0,0 +1,111 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+var _ = Describe("Chart policy", func() {
+	mustPolicy := func(allow, deny string) *ChartPolicy {
+		policy, err := NewChartPolicy(allow, deny)
+		Expect(err).NotTo(HaveOccurred())
+		return policy
+	}
+
+	It("allows every chart without lists", func() {
+		policy := mustPolicy("", " ")
+		Expect(policy).To(BeNil())
+		Expect(policy.Check("postgresql")).To(Succeed())
+	})
+
+	It("only allows charts on the allowlist", func() {
+		policy := mustPolicy("postgresql, valkey", "")
+		Expect(policy.Check("postgresql")).To(Succeed())
+		Expect(policy.Check("valkey")).To(Succeed())
+		Expect(policy.Check("mysql")).To(MatchError(ContainSubstring(`chart "mysql" is not in the operator's allowed charts`)))
+	})
+
+	It("rejects charts on the denylist", func() {
+		policy := mustPolicy("", "mysql")
+		Expect(policy.Check("postgresql")).To(Succeed())
+		Expect(policy.Check("mysql")).To(MatchError(ContainSubstring(`chart "mysql" is denied`)))
+	})
+
+	It("matches globs", func() {
+		policy := mustPolicy("postgres*,valkey", "*-experimental")
+		Expect(policy.Check("postgresql")).To(Succeed())
+		Expect(policy.Check("postgres-ha")).To(Succeed())
+		Expect(policy.Check("postgres-experimental")).To(MatchError(ContainSubstring("(*-experimental)")))
+		Expect(policy.Check("mongodb")).NotTo(Succeed())
+	})
+
+	It("gives the denylist precedence over the allowlist", func() {
+		policy := mustPolicy("*", "mysql")
+		Expect(policy.Check("valkey")).To(Succeed())
+		Expect(policy.Check("mysql")).NotTo(Succeed())
+	})
+
+	It("rejects invalid patterns", func() {
+		_, err := NewChartPolicy("postgres[", "")
+		Expect(err).To(MatchError(ContainSubstring(`invalid chart pattern "postgres["`)))
+		_, err = NewChartPolicy("", "mysql,,valkey")
+		Expect(err).To(MatchError(ContainSubstring("empty chart pattern")))
+	})
+
+	Context("when reconciling", func() {
+		It("fails a deployment of a disallowed chart without installing it", func() {
+			ctx := context.Background()
+			ad := &appstorev1alpha1.AppDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+				Spec:       appstorev1alpha1.AppDeploymentSpec{AppName: "mysql", TeamID: "team-a"},
+			}
+			fakeHelm := &fakeHelmClient{}
+			reconciler := &AppDeploymentReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(ad).
+					WithStatusSubresource(ad).
+					Build(),
+				HelmClient:  fakeHelm,
+				ChartPolicy: mustPolicy("postgres*", ""),
+			}
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+
+			result, err := reconciler.reconcileHelm(ctx, ad)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(requeueAfterFailure))
+			Expect(fakeHelm.Calls).To(BeEmpty())
+
+			Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseFailed))
+			cond := meta.FindStatusCondition(ad.Status.Conditions, ConditionTypeReady)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Reason).To(Equal("ChartNotAllowed"))
+			Expect(cond.Message).To(ContainSubstring(`chart "mysql"`))
+		})
+	})
+})
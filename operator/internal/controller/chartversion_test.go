@@ -0,0 +1,113 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+var _ = Describe("Pinned chart versions", func() {
+	var (
+		ctx        context.Context
+		fakeHelm   *fakeHelmClient
+		reconciler *AppDeploymentReconciler
+		ad         *appstorev1alpha1.AppDeployment
+	)
+
+	reconcileHelm := func() {
+		_, err := reconciler.reconcileHelm(ctx, ad)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{}
+		ad = &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec: appstorev1alpha1.AppDeploymentSpec{
+				AppName:      "postgresql",
+				TeamID:       "team-a",
+				ChartVersion: "15.2.0",
+			},
+		}
+		reconciler = &AppDeploymentReconciler{
+			Client:     fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ad).WithStatusSubresource(ad).Build(),
+			HelmClient: fakeHelm,
+		}
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+	})
+
+	It("installs the pinned version when it is available", func() {
+		reconcileHelm()
+
+		Expect(fakeHelm.callsTo("Install")).To(HaveLen(1))
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+		Expect(ad.Status.DeployedChartVersion).To(Equal("15.2.0"))
+		Expect(ad.Status.AvailableChartVersion).To(BeEmpty())
+	})
+
+	It("fails without installing when the pinned version is missing", func() {
+		fakeHelm.ChartErr = &helm.VersionMismatchError{Chart: "postgresql", Requested: "15.2.0", Available: "16.0.1"}
+		reconcileHelm()
+
+		Expect(fakeHelm.callsTo("Install")).To(BeEmpty())
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseFailed))
+		Expect(ad.Status.AvailableChartVersion).To(Equal("16.0.1"))
+		ready := meta.FindStatusCondition(ad.Status.Conditions, ConditionTypeReady)
+		Expect(ready).NotTo(BeNil())
+		Expect(ready.Reason).To(Equal("ChartVersionUnavailable"))
+		Expect(ready.Message).To(ContainSubstring("version 15.2.0 is not available"))
+		Expect(ready.Message).To(ContainSubstring("16.0.1"))
+	})
+
+	It("does not upgrade a deployed release to a missing pinned version", func() {
+		fakeHelm.setRelease(&helm.ReleaseInfo{
+			Name: "db", Namespace: "default", Revision: 1, Status: releaseStatusDeployed, ChartName: "postgresql", ChartVersion: "15.1.0",
+		})
+		fakeHelm.ChartErr = &helm.VersionMismatchError{Chart: "postgresql", Requested: "15.2.0", Available: "15.1.0"}
+		reconcileHelm()
+
+		Expect(fakeHelm.callsTo("Upgrade")).To(BeEmpty())
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseFailed))
+		Expect(ad.Status.AvailableChartVersion).To(Equal("15.1.0"))
+	})
+
+	It("clears the available version once the pin resolves", func() {
+		fakeHelm.ChartErr = &helm.VersionMismatchError{Chart: "postgresql", Requested: "15.2.0", Available: "16.0.1"}
+		reconcileHelm()
+		Expect(ad.Status.AvailableChartVersion).To(Equal("16.0.1"))
+
+		fakeHelm.ChartErr = nil
+		reconcileHelm()
+
+		Expect(fakeHelm.callsTo("Install")).To(HaveLen(1))
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+		Expect(ad.Status.AvailableChartVersion).To(BeEmpty())
+	})
+})
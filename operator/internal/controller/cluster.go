@@ -0,0 +1,138 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+// defaultKubeConfigKey is the Secret key of the kubeconfig if spec.clusterRef.key is empty
+const defaultKubeConfigKey = "kubeconfig"
+
+// clusterClientKey is the context key of the client for the target cluster
+type clusterClientKey struct{}
+
+// cachedCluster is the cluster of a spec.clusterRef with its client
+type cachedCluster struct {
+	kubeconfig []byte
+	cluster    *helm.Cluster
+	client     client.Client
+}
+
+// clusterCache caches the clusters of clusterRef Secrets, so that clients and their
+// discovery information are reused until the kubeconfig changes
+type clusterCache struct {
+	mu       sync.Mutex
+	clusters map[types.NamespacedName]*cachedCluster
+}
+
+// get returns the cached cluster of a Secret if its kubeconfig is unchanged
+func (c *clusterCache) get(secret types.NamespacedName, kubeconfig []byte) *cachedCluster {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached := c.clusters[secret]; cached != nil && bytes.Equal(cached.kubeconfig, kubeconfig) {
+		return cached
+	}
+	return nil
+}
+
+// set caches the cluster of a Secret, replacing the one of an earlier kubeconfig
+func (c *clusterCache) set(secret types.NamespacedName, cluster *cachedCluster) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.clusters == nil {
+		c.clusters = make(map[types.NamespacedName]*cachedCluster)
+	}
+	c.clusters[secret] = cluster
+}
+
+// targetCluster returns a context in which Helm operations and the release's workloads go
+// to the cluster of spec.clusterRef. Without a clusterRef the context is returned unchanged.
+func (r *AppDeploymentReconciler) targetCluster(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment) (context.Context, error) {
+	ref := appDeployment.Spec.ClusterRef
+	if ref == nil {
+		return ctx, nil
+	}
+	if len(r.AllowedClusters) == 0 {
+		return ctx, errors.New("remote clusters are not enabled, see --allowed-clusters")
+	}
+
+	key := ref.Key
+	if key == "" {
+		key = defaultKubeConfigKey
+	}
+	secretName := types.NamespacedName{Name: ref.SecretName, Namespace: appDeployment.Namespace}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, secretName, secret); err != nil {
+		return ctx, fmt.Errorf("failed to get cluster secret %s: %w", ref.SecretName, err)
+	}
+	kubeconfig := secret.Data[key]
+	if len(kubeconfig) == 0 {
+		return ctx, fmt.Errorf("cluster secret %s has no key %s", ref.SecretName, key)
+	}
+
+	cached := r.clusters.get(secretName, kubeconfig)
+	if cached == nil {
+		cluster, err := helm.NewCluster(kubeconfig, r.AllowedClusters)
+		if err != nil {
+			return ctx, fmt.Errorf("invalid kubeconfig in cluster secret %s: %w", ref.SecretName, err)
+		}
+		newClient := r.NewClusterClient
+		if newClient == nil {
+			newClient = func(config *rest.Config) (client.Client, error) {
+				return client.New(config, client.Options{Scheme: r.Scheme})
+			}
+		}
+		clusterClient, err := newClient(cluster.RESTConfig())
+		if err != nil {
+			return ctx, fmt.Errorf("failed to create client for cluster secret %s: %w", ref.SecretName, err)
+		}
+		cached = &cachedCluster{kubeconfig: bytes.Clone(kubeconfig), cluster: cluster, client: clusterClient}
+		r.clusters.set(secretName, cached)
+	}
+
+	ctx = helm.WithCluster(ctx, cached.cluster)
+	return context.WithValue(ctx, clusterClientKey{}, cached.client), nil
+}
+
+// clusterClient returns the client for the cluster the release is installed into
+func (r *AppDeploymentReconciler) clusterClient(ctx context.Context) client.Client {
+	if c, ok := ctx.Value(clusterClientKey{}).(client.Client); ok {
+		return c
+	}
+	return r.Client
+}
+
+// remoteCluster reports whether the context targets another cluster than the operator's
+func remoteCluster(ctx context.Context) bool {
+	_, ok := ctx.Value(clusterClientKey{}).(client.Client)
+	return ok
+}
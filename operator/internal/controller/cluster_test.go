@@ -0,0 +1,271 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+// remoteKubeConfig is a kubeconfig for a cluster that is never contacted
+const remoteKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: remote
+  cluster:
+    server: https://remote.example.com:6443
+users:
+- name: remote
+  user:
+    token: remote-token
+contexts:
+- name: remote
+  context:
+    cluster: remote
+    user: remote
+current-context: remote
+`
+
+var _ = Describe("Remote clusters", func() {
+	var (
+		ctx            context.Context
+		fakeHelm       *fakeHelmClient
+		reconciler     *AppDeploymentReconciler
+		remote         client.Client
+		remoteHost     string
+		clientsCreated int
+		appDeployment  *appstorev1alpha1.AppDeployment
+		secret         *corev1.Secret
+	)
+
+	reconcileHelm := func() {
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(appDeployment), appDeployment)).To(Succeed())
+		_, err := reconciler.reconcileHelm(ctx, appDeployment)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	expectClusterRefFailed := func(message string) {
+		Expect(appDeployment.Status.Phase).To(Equal(appstorev1alpha1.PhaseFailed))
+		cond := meta.FindStatusCondition(appDeployment.Status.Conditions, ConditionTypeReady)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Reason).To(Equal("ClusterRefFailed"))
+		Expect(cond.Message).To(ContainSubstring(message))
+		Expect(fakeHelm.callsTo("Install")).To(BeEmpty())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{}
+		remoteHost = ""
+		clientsCreated = 0
+
+		replicas := int32(2)
+		remote = fake.NewClientBuilder().
+			WithScheme(scheme.Scheme).
+			WithObjects(&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "db-postgresql",
+					Namespace:   "default",
+					Annotations: map[string]string{helmReleaseNameAnnotation: "db"},
+				},
+				Spec:   appsv1.DeploymentSpec{Replicas: &replicas},
+				Status: appsv1.DeploymentStatus{ReadyReplicas: 2},
+			}).
+			Build()
+
+		appDeployment = &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec: appstorev1alpha1.AppDeploymentSpec{
+				AppName:    "postgresql",
+				TeamID:     "team-a",
+				ClusterRef: &appstorev1alpha1.ClusterRef{SecretName: "edge-cluster"},
+			},
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "edge-cluster", Namespace: "default"},
+			Data:       map[string][]byte{"kubeconfig": []byte(remoteKubeConfig)},
+		}
+	})
+
+	JustBeforeEach(func() {
+		reconciler = &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(appDeployment, secret).
+				WithStatusSubresource(appDeployment).
+				Build(),
+			HelmClient:      fakeHelm,
+			AllowedClusters: []string{"https://remote.example.com:6443"},
+			NewClusterClient: func(config *rest.Config) (client.Client, error) {
+				remoteHost = config.Host
+				clientsCreated++
+				return remote, nil
+			},
+		}
+	})
+
+	It("installs the release into the cluster of the kubeconfig", func() {
+		reconcileHelm()
+
+		Expect(remoteHost).To(Equal("https://remote.example.com:6443"))
+		install := fakeHelm.callsTo("Install")
+		Expect(install).To(HaveLen(1))
+		Expect(install[0].KubeConfig).To(Equal(remoteKubeConfig))
+
+		// Health comes from the workloads in the remote cluster
+		Expect(appDeployment.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+		Expect(appDeployment.Status.HealthyReplicas).To(Equal(int32(2)))
+		Expect(appDeployment.Status.DesiredReplicas).To(Equal(int32(2)))
+	})
+
+	// The objects are copied into the fake client in JustBeforeEach, so changes to them
+	// go in BeforeEach
+
+	Context("with the kubeconfig under another key", func() {
+		BeforeEach(func() {
+			appDeployment.Spec.ClusterRef.Key = "config"
+			secret.Data = map[string][]byte{"config": []byte(remoteKubeConfig)}
+		})
+
+		It("reads the kubeconfig from spec.clusterRef.key", func() {
+			reconcileHelm()
+
+			Expect(remoteHost).To(Equal("https://remote.example.com:6443"))
+			Expect(fakeHelm.callsTo("Install")[0].KubeConfig).To(Equal(remoteKubeConfig))
+		})
+	})
+
+	Context("without the Secret", func() {
+		BeforeEach(func() {
+			secret.Name = "other"
+		})
+
+		It("fails when the Secret doesn't exist", func() {
+			reconcileHelm()
+
+			expectClusterRefFailed("failed to get cluster secret edge-cluster")
+		})
+	})
+
+	Context("with a Secret without a kubeconfig", func() {
+		BeforeEach(func() {
+			secret.Data = map[string][]byte{"token": []byte("remote-token")}
+		})
+
+		It("fails when the Secret has no kubeconfig", func() {
+			reconcileHelm()
+
+			expectClusterRefFailed("cluster secret edge-cluster has no key kubeconfig")
+		})
+	})
+
+	Context("with an invalid kubeconfig", func() {
+		BeforeEach(func() {
+			secret.Data = map[string][]byte{"kubeconfig": []byte("not: [a kubeconfig")}
+		})
+
+		It("fails when the kubeconfig is invalid", func() {
+			reconcileHelm()
+
+			expectClusterRefFailed("invalid kubeconfig in cluster secret edge-cluster")
+		})
+	})
+
+	It("fails when remote clusters aren't enabled", func() {
+		reconciler.AllowedClusters = nil
+
+		reconcileHelm()
+
+		expectClusterRefFailed("remote clusters are not enabled")
+	})
+
+	It("fails when the cluster isn't allowed", func() {
+		reconciler.AllowedClusters = []string{"https://other.example.com:6443"}
+
+		reconcileHelm()
+
+		expectClusterRefFailed("cluster https://remote.example.com:6443 is not allowed")
+	})
+
+	Context("with a kubeconfig that runs an exec plugin", func() {
+		BeforeEach(func() {
+			secret.Data = map[string][]byte{"kubeconfig": []byte(strings.Replace(remoteKubeConfig, "    token: remote-token",
+				"    exec:\n      apiVersion: client.authentication.k8s.io/v1\n      command: sh", 1))}
+		})
+
+		It("fails when the kubeconfig runs an exec plugin", func() {
+			reconcileHelm()
+
+			expectClusterRefFailed("exec plugins are not allowed")
+			Expect(clientsCreated).To(BeZero())
+		})
+	})
+
+	It("reuses the client until the kubeconfig changes", func() {
+		reconcileHelm()
+		reconcileHelm()
+		Expect(clientsCreated).To(Equal(1))
+
+		secret.Data = map[string][]byte{"kubeconfig": []byte(strings.Replace(remoteKubeConfig, "remote-token", "rotated-token", 1))}
+		Expect(reconciler.Update(ctx, secret)).To(Succeed())
+		reconcileHelm()
+		Expect(clientsCreated).To(Equal(2))
+	})
+
+	Context("without a clusterRef", func() {
+		BeforeEach(func() {
+			appDeployment.Spec.ClusterRef = nil
+		})
+
+		It("uses the operator's cluster without a clusterRef", func() {
+			reconcileHelm()
+
+			Expect(remoteHost).To(BeEmpty())
+			Expect(fakeHelm.callsTo("Install")[0].KubeConfig).To(BeEmpty())
+			Expect(appDeployment.Status.DesiredReplicas).To(BeZero())
+		})
+	})
+
+	It("uninstalls the release from the remote cluster on deletion", func() {
+		reconcileHelm()
+
+		appDeployment.Finalizers = []string{finalizerName}
+		Expect(reconciler.Update(ctx, appDeployment)).To(Succeed())
+		Expect(reconciler.Delete(ctx, appDeployment)).To(Succeed())
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(appDeployment)})
+		Expect(err).NotTo(HaveOccurred())
+
+		uninstall := fakeHelm.callsTo("Uninstall")
+		Expect(uninstall).To(HaveLen(1))
+		Expect(uninstall[0].KubeConfig).To(Equal(remoteKubeConfig))
+	})
+})
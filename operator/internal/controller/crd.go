@@ -0,0 +1,75 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+// appDeploymentResource is the resource of the AppDeployment CRD
+const appDeploymentResource = "appdeployments"
+
+// WaitForCRD waits until the API server serves AppDeployments, which it does once their
+// CRD is established. It polls discovery every interval and gives up after timeout,
+// since the manager's cache fails obscurely without the CRD.
+func WaitForCRD(ctx context.Context, client discovery.DiscoveryInterface, interval, timeout time.Duration) error {
+	logger := log.FromContext(ctx)
+	crd := appDeploymentResource + "." + appstorev1alpha1.GroupVersion.Group
+
+	var lastErr error
+	waiting := false
+	err := wait.PollUntilContextTimeout(ctx, interval, timeout, true, func(context.Context) (bool, error) {
+		resources, err := client.ServerResourcesForGroupVersion(appstorev1alpha1.GroupVersion.String())
+		if err != nil && !apierrors.IsNotFound(err) {
+			// Keep trying, the API server may not be reachable yet
+			lastErr = err
+			return false, nil
+		}
+		if resources != nil {
+			for _, resource := range resources.APIResources {
+				if resource.Name == appDeploymentResource {
+					return true, nil
+				}
+			}
+		}
+		if !waiting {
+			logger.Info("Waiting for the CRD to be established", "crd", crd, "timeout", timeout)
+			waiting = true
+		}
+		lastErr = nil
+		return false, nil
+	})
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if lastErr != nil {
+		return fmt.Errorf("CRD %s is not available after %s, discovery failed: %w", crd, timeout, lastErr)
+	}
+	return fmt.Errorf("CRD %s is not established after %s; install it with make install or the release manifests", crd, timeout)
+}
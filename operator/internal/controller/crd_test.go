@@ -0,0 +1,77 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+var _ = Describe("Waiting for the CRD", func() {
+	// newDiscovery returns a discovery client that serves AppDeployments from the given
+	// lookup on, failing earlier lookups with err if set
+	newDiscovery := func(servedFrom int, err error) (*fakediscovery.FakeDiscovery, *int) {
+		discovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}
+		lookups := 0
+		discovery.AddReactor("get", "resource", func(clienttesting.Action) (bool, runtime.Object, error) {
+			lookups++
+			if lookups < servedFrom {
+				return err != nil, nil, err
+			}
+			discovery.Resources = []*metav1.APIResourceList{{
+				GroupVersion: appstorev1alpha1.GroupVersion.String(),
+				APIResources: []metav1.APIResource{{Name: "appdeployments", Kind: "AppDeployment", Namespaced: true}},
+			}}
+			return false, nil, nil
+		})
+		return discovery, &lookups
+	}
+
+	It("returns once the CRD is established", func() {
+		discovery, lookups := newDiscovery(3, nil)
+		Expect(WaitForCRD(context.Background(), discovery, 10*time.Millisecond, 5*time.Second)).To(Succeed())
+		Expect(*lookups).To(Equal(3))
+	})
+
+	It("keeps trying while discovery fails", func() {
+		discovery, lookups := newDiscovery(3, errors.New("connection refused"))
+		Expect(WaitForCRD(context.Background(), discovery, 10*time.Millisecond, 5*time.Second)).To(Succeed())
+		Expect(*lookups).To(Equal(3))
+	})
+
+	It("fails with a clear message if the CRD never appears", func() {
+		discovery, _ := newDiscovery(1000, nil)
+		err := WaitForCRD(context.Background(), discovery, 10*time.Millisecond, 100*time.Millisecond)
+		Expect(err).To(MatchError(ContainSubstring("CRD appdeployments.appstore.bitpipe.no is not established after 100ms")))
+	})
+
+	It("reports the discovery error if the API server never answers", func() {
+		discovery, _ := newDiscovery(1000, errors.New("connection refused"))
+		err := WaitForCRD(context.Background(), discovery, 10*time.Millisecond, 100*time.Millisecond)
+		Expect(err).To(MatchError(ContainSubstring("discovery failed: connection refused")))
+	})
+})
@@ -0,0 +1,174 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+var _ = Describe("Deletion timeout", func() {
+	var (
+		ctx        context.Context
+		fakeHelm   *fakeHelmClient
+		fakeClock  *clocktesting.FakePassiveClock
+		recorder   *record.FakeRecorder
+		reconciler *AppDeploymentReconciler
+		deletedAt  time.Time
+	)
+
+	key := client.ObjectKey{Name: "db", Namespace: "default"}
+
+	// setup stores an AppDeployment that is being deleted
+	setup := func(deletionTimeout *metav1.Duration) {
+		ad := &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              key.Name,
+				Namespace:         key.Namespace,
+				Finalizers:        []string{finalizerName},
+				DeletionTimestamp: &metav1.Time{Time: deletedAt},
+			},
+			Spec: appstorev1alpha1.AppDeploymentSpec{
+				AppName:         "postgresql",
+				TeamID:          "team-a",
+				DeletionTimeout: deletionTimeout,
+			},
+		}
+		reconciler.Client = fake.NewClientBuilder().
+			WithScheme(scheme.Scheme).
+			WithObjects(ad).
+			WithStatusSubresource(ad).
+			Build()
+	}
+
+	reconcileAfter := func(elapsed time.Duration) error {
+		fakeClock.SetTime(deletedAt.Add(elapsed))
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		return err
+	}
+
+	exists := func() bool {
+		err := reconciler.Get(ctx, key, &appstorev1alpha1.AppDeployment{})
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return true
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		deletedAt = time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+		fakeHelm = &fakeHelmClient{
+			Releases:     releasesOf(&helm.ReleaseInfo{Name: "db", Namespace: "default", Revision: 1, Status: releaseStatusDeployed}),
+			UninstallErr: errors.New("uninstall: timed out waiting for the condition"),
+		}
+		fakeClock = clocktesting.NewFakePassiveClock(deletedAt)
+		recorder = record.NewFakeRecorder(10)
+		reconciler = &AppDeploymentReconciler{
+			HelmClient:      fakeHelm,
+			Clock:           fakeClock,
+			Recorder:        recorder,
+			DeletionTimeout: 10 * time.Minute,
+		}
+	})
+
+	It("keeps the finalizer while uninstall fails within the timeout", func() {
+		setup(nil)
+
+		Expect(reconcileAfter(time.Minute)).To(MatchError(ContainSubstring("timed out")))
+		Expect(reconcileAfter(9 * time.Minute)).To(MatchError(ContainSubstring("timed out")))
+
+		Expect(fakeHelm.callsTo("Uninstall")).To(HaveLen(2))
+		Expect(exists()).To(BeTrue())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("removes the finalizer and records a warning after the timeout", func() {
+		setup(nil)
+
+		Expect(reconcileAfter(time.Minute)).To(HaveOccurred())
+		Expect(reconcileAfter(5 * time.Minute)).To(HaveOccurred())
+		Expect(reconcileAfter(10 * time.Minute)).To(Succeed())
+
+		Expect(fakeHelm.callsTo("Uninstall")).To(HaveLen(3))
+		Expect(exists()).To(BeFalse())
+		Expect(recorder.Events).To(Receive(And(
+			HavePrefix("Warning UninstallAbandoned"),
+			ContainSubstring("timed out waiting for the condition"),
+		)))
+	})
+
+	It("records when it gave up with the reconciler's clock", func() {
+		setup(nil)
+		fakeClock.SetTime(deletedAt.Add(10 * time.Minute))
+
+		ad := &appstorev1alpha1.AppDeployment{}
+		Expect(reconciler.Get(ctx, key, ad)).To(Succeed())
+		Expect(reconciler.updateStatusUninstallAbandoned(ctx, ad, "gave up")).To(Succeed())
+
+		Expect(reconciler.Get(ctx, key, ad)).To(Succeed())
+		Expect(ad.Status.LastReconcileTime.Time).To(BeTemporally("==", deletedAt.Add(10*time.Minute)))
+		ready := meta.FindStatusCondition(ad.Status.Conditions, ConditionTypeReady)
+		Expect(ready.LastTransitionTime.Time).To(BeTemporally("==", deletedAt.Add(10*time.Minute)))
+	})
+
+	It("prefers the deployment's own timeout", func() {
+		setup(&metav1.Duration{Duration: time.Hour})
+
+		Expect(reconcileAfter(30 * time.Minute)).To(HaveOccurred())
+		Expect(exists()).To(BeTrue())
+
+		Expect(reconcileAfter(time.Hour)).To(Succeed())
+		Expect(exists()).To(BeFalse())
+	})
+
+	It("retries forever without a timeout", func() {
+		reconciler.DeletionTimeout = 0
+		setup(nil)
+
+		Expect(reconcileAfter(30 * 24 * time.Hour)).To(HaveOccurred())
+		Expect(exists()).To(BeTrue())
+	})
+
+	It("removes the finalizer once uninstall succeeds", func() {
+		setup(nil)
+
+		Expect(reconcileAfter(time.Minute)).To(HaveOccurred())
+		fakeHelm.UninstallErr = nil
+		Expect(reconcileAfter(2 * time.Minute)).To(Succeed())
+
+		Expect(exists()).To(BeFalse())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+})
@@ -0,0 +1,130 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	helmvalues "appstore/shared/values"
+)
+
+// chartFileTTL limits how long a file read from a chart is reused. Like
+// autoUpgradeCheckInterval it is a little shorter than requeueAfterSuccess, so that periodic
+// reconciles read the file again; reconciles caused by status updates in between don't.
+const chartFileTTL = autoUpgradeCheckInterval
+
+// chartFileKey identifies a file of a chart version in the chart files cache
+type chartFileKey struct {
+	chart   string
+	version string
+	file    string
+}
+
+// cachedChartFile is a file read from a chart, nil if the chart has no such file
+type cachedChartFile struct {
+	data []byte
+	read time.Time
+}
+
+// environmentChartFile is the chart's values file of an environment
+func environmentChartFile(environment string) string {
+	return fmt.Sprintf("values-%s.yaml", environment)
+}
+
+// environmentConfigMapName is the ConfigMap holding an app's values for an environment
+func environmentConfigMapName(appName, environment string) string {
+	return fmt.Sprintf("%s-values-%s", appName, environment)
+}
+
+// environmentValues returns the overlay values of the deployment's spec.environment: the
+// chart's values-<environment>.yaml with the values.yaml key of the ConfigMap
+// <appName>-values-<environment> merged over it. Either may be missing.
+func (r *AppDeploymentReconciler) environmentValues(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment) (map[string]interface{}, error) {
+	environment := appDeployment.Spec.Environment
+	values := make(map[string]interface{})
+
+	fileName := environmentChartFile(environment)
+	data, err := r.chartFile(ctx, appDeployment.Spec.AppName, appDeployment.Spec.ChartVersion, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s from the chart: %w", fileName, err)
+	}
+	if data != nil {
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s from the chart: %w", fileName, err)
+		}
+	}
+
+	cmName := environmentConfigMapName(appDeployment.Spec.AppName, environment)
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{Name: cmName, Namespace: appDeployment.Namespace}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return values, nil
+		}
+		return nil, fmt.Errorf("failed to get ConfigMap %s: %w", cmName, err)
+	}
+	raw, ok := cm.Data[defaultValuesKey]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found in ConfigMap %s", defaultValuesKey, cmName)
+	}
+	var cmValues map[string]interface{}
+	if err := yaml.Unmarshal([]byte(raw), &cmValues); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal values from ConfigMap %s: %w", cmName, err)
+	}
+
+	return helmvalues.Merge(values, cmValues), nil
+}
+
+// chartFile returns a file from the root of a chart like HelmClient.GetChartFile, which
+// locates the chart and may pull it, reusing the file for chartFileTTL unless the chart
+// changes in the meantime
+func (r *AppDeploymentReconciler) chartFile(ctx context.Context, chartName, version, fileName string) ([]byte, error) {
+	key := chartFileKey{chart: chartName, version: version, file: fileName}
+	now := r.now()
+	if cached, ok := r.chartFiles.Load(key); ok && now.Sub(cached.(cachedChartFile).read) < chartFileTTL {
+		return cached.(cachedChartFile).data, nil
+	}
+
+	data, err := r.HelmClient.GetChartFile(ctx, chartName, version, fileName)
+	if err != nil {
+		return nil, err
+	}
+	r.chartFiles.Store(key, cachedChartFile{data: data, read: now})
+	return data, nil
+}
+
+// forgetChartFiles drops the cached files of the given charts, so that they're read again
+// after a chart sync changed them
+func (r *AppDeploymentReconciler) forgetChartFiles(charts []string) {
+	changed := make(map[string]bool, len(charts))
+	for _, chart := range charts {
+		changed[chart] = true
+	}
+	r.chartFiles.Range(func(key, _ interface{}) bool {
+		if changed[key.(chartFileKey).chart] {
+			r.chartFiles.Delete(key)
+		}
+		return true
+	})
+}
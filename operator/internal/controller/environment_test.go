@@ -0,0 +1,155 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+var _ = Describe("Environment values", func() {
+	var (
+		ctx      context.Context
+		fakeHelm *fakeHelmClient
+	)
+
+	configMap := func(name, values string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Data:       map[string]string{"values.yaml": values},
+		}
+	}
+
+	newDeployment := func(environment string) *appstorev1alpha1.AppDeployment {
+		return &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec: appstorev1alpha1.AppDeploymentSpec{
+				AppName:     "postgresql",
+				TeamID:      "team-a",
+				Environment: environment,
+				ValuesFrom:  []appstorev1alpha1.ValuesReference{{Kind: "ConfigMap", Name: "base"}},
+				Values:      &apiextensionsv1.JSON{Raw: []byte(`{"spec":"values"}`)},
+			},
+		}
+	}
+
+	getValues := func(ad *appstorev1alpha1.AppDeployment, objects ...client.Object) map[string]interface{} {
+		objects = append(objects, configMap("base", "base: from\nchart: base\nconfigMap: base\nspec: base\n"))
+		reconciler := &AppDeploymentReconciler{
+			Client:     fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build(),
+			HelmClient: fakeHelm,
+		}
+		resolved, err := reconciler.getValues(ctx, ad)
+		Expect(err).NotTo(HaveOccurred())
+		return resolved.values
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{ChartFiles: map[string]string{
+			"values-prod.yaml": "chart: prod\nconfigMap: chart\nspec: chart\n",
+		}}
+	})
+
+	It("layers the environment's values above the base values and below spec.values", func() {
+		values := getValues(newDeployment("prod"), configMap("postgresql-values-prod", "configMap: prod\nspec: configMap\n"))
+		Expect(values).To(Equal(map[string]interface{}{
+			"base":      "from",
+			"chart":     "prod",
+			"configMap": "prod",
+			"spec":      "values",
+		}))
+	})
+
+	It("applies the chart's values file without a ConfigMap", func() {
+		values := getValues(newDeployment("prod"))
+		Expect(values).To(HaveKeyWithValue("chart", "prod"))
+		Expect(values).To(HaveKeyWithValue("configMap", "chart"))
+	})
+
+	It("applies the ConfigMap without a values file in the chart", func() {
+		values := getValues(newDeployment("staging"), configMap("postgresql-values-staging", "configMap: staging\n"))
+		Expect(values).To(HaveKeyWithValue("chart", "base"))
+		Expect(values).To(HaveKeyWithValue("configMap", "staging"))
+	})
+
+	It("unsets lower values with a null in the overlay", func() {
+		values := getValues(newDeployment("prod"), configMap("postgresql-values-prod", "base: null\nchart: null\n"))
+		Expect(values).To(HaveKeyWithValue("base", BeNil()))
+		Expect(values).To(HaveKeyWithValue("chart", BeNil()))
+		Expect(values).To(HaveKeyWithValue("configMap", "chart"))
+	})
+
+	It("reads the chart's values file again after chartFileTTL or a chart change", func() {
+		fakeClock := clocktesting.NewFakePassiveClock(time.Now())
+		reconciler := &AppDeploymentReconciler{
+			Client:     fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(configMap("base", "")).Build(),
+			HelmClient: fakeHelm,
+			Clock:      fakeClock,
+		}
+		reads := func() int {
+			resolved, err := reconciler.getValues(ctx, newDeployment("prod"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resolved.values).To(HaveKeyWithValue("chart", "prod"))
+			return len(fakeHelm.callsTo("GetChartFile"))
+		}
+
+		Expect(reads()).To(Equal(1))
+		Expect(reads()).To(Equal(1))
+
+		fakeClock.SetTime(fakeClock.Now().Add(chartFileTTL))
+		Expect(reads()).To(Equal(2))
+
+		reconciler.forgetChartFiles([]string{"redis"})
+		Expect(reads()).To(Equal(2))
+		reconciler.forgetChartFiles([]string{"postgresql"})
+		Expect(reads()).To(Equal(3))
+	})
+
+	It("applies no overlay without an environment", func() {
+		values := getValues(newDeployment(""), configMap("postgresql-values-prod", "configMap: prod\n"))
+		Expect(values).To(Equal(map[string]interface{}{
+			"base":      "from",
+			"chart":     "base",
+			"configMap": "base",
+			"spec":      "values",
+		}))
+	})
+
+	It("fails if the ConfigMap has no values.yaml", func() {
+		cm := configMap("postgresql-values-prod", "")
+		cm.Data = map[string]string{"other.yaml": "configMap: prod\n"}
+		reconciler := &AppDeploymentReconciler{
+			Client:     fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(cm, configMap("base", "")).Build(),
+			HelmClient: fakeHelm,
+		}
+		_, err := reconciler.getValues(ctx, newDeployment("prod"))
+		Expect(err).To(MatchError(ContainSubstring("key values.yaml not found in ConfigMap postgresql-values-prod")))
+	})
+})
@@ -0,0 +1,199 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+
+	"appstore/operator/internal/helm"
+)
+
+// helmCall records a single call made to fakeHelmClient
+type helmCall struct {
+	Method   string
+	Values   map[string]interface{}
+	Options  helm.ActionOptions
+	Revision int
+	// KubeConfig is the kubeconfig of the target cluster, empty for the operator's cluster
+	KubeConfig string
+	// Timeout is the AppDeployment's timeout from the context, zero if not set
+	Timeout time.Duration
+}
+
+// fakeHelmClient is an in-memory HelmClient that records calls
+type fakeHelmClient struct {
+	Calls []helmCall
+
+	// Releases are returned by GetRelease by release name and updated by Install, Upgrade
+	// and Uninstall
+	Releases map[string]*helm.ReleaseInfo
+
+	// UpgradeErrs are returned by successive Upgrade calls
+	UpgradeErrs []error
+
+	// Hooks are recorded on the releases created by Install and Upgrade
+	Hooks []helm.HookInfo
+
+	InstallErr   error
+	RollbackErr  error
+	UninstallErr error
+	ClearErr     error
+	Manifest     string
+
+	// Charts are returned by GetChartMetadata by version; other versions have no kubeVersion
+	// or annotations
+	Charts map[string]*chart.Metadata
+	// ChartErr is returned by GetChartMetadata if set
+	ChartErr error
+	// ChartFiles are returned by GetChartFile by file name
+	ChartFiles map[string]string
+}
+
+func (f *fakeHelmClient) Install(ctx context.Context, releaseName, chartName, namespace string, values map[string]interface{}, version string, opts helm.ActionOptions) (*helm.ReleaseInfo, error) {
+	f.Calls = append(f.Calls, helmCall{Method: "Install", Values: values, Options: opts, KubeConfig: string(helm.KubeConfigFromContext(ctx)), Timeout: helm.ActionTimeoutFromContext(ctx)})
+	if f.InstallErr != nil {
+		return nil, f.InstallErr
+	}
+	return f.setRelease(&helm.ReleaseInfo{
+		Name:         releaseName,
+		Namespace:    namespace,
+		Revision:     1,
+		Status:       releaseStatusDeployed,
+		ChartName:    chartName,
+		ChartVersion: version,
+		Hooks:        f.Hooks,
+		Labels:       opts.ReleaseLabels,
+	}), nil
+}
+
+func (f *fakeHelmClient) Upgrade(ctx context.Context, releaseName, chartName, namespace string, values map[string]interface{}, version string, opts helm.ActionOptions) (*helm.ReleaseInfo, error) {
+	n := len(f.callsTo("Upgrade"))
+	f.Calls = append(f.Calls, helmCall{Method: "Upgrade", Values: values, Options: opts, KubeConfig: string(helm.KubeConfigFromContext(ctx)), Timeout: helm.ActionTimeoutFromContext(ctx)})
+	if n < len(f.UpgradeErrs) && f.UpgradeErrs[n] != nil {
+		return nil, f.UpgradeErrs[n]
+	}
+
+	revision := 1
+	// Like Helm, upgrades keep the release labels they don't set
+	labels := map[string]string{}
+	if current := f.Releases[releaseName]; current != nil {
+		revision = current.Revision + 1
+		for key, value := range current.Labels {
+			labels[key] = value
+		}
+	}
+	for key, value := range opts.ReleaseLabels {
+		labels[key] = value
+	}
+	return f.setRelease(&helm.ReleaseInfo{
+		Name:         releaseName,
+		Namespace:    namespace,
+		Revision:     revision,
+		Status:       releaseStatusDeployed,
+		ChartName:    chartName,
+		ChartVersion: version,
+		Hooks:        f.Hooks,
+		Labels:       labels,
+	}), nil
+}
+
+func (f *fakeHelmClient) Rollback(ctx context.Context, _, _ string, revision int) error {
+	f.Calls = append(f.Calls, helmCall{Method: "Rollback", Revision: revision, Timeout: helm.ActionTimeoutFromContext(ctx)})
+	return f.RollbackErr
+}
+
+func (f *fakeHelmClient) Uninstall(ctx context.Context, releaseName, _ string) error {
+	f.Calls = append(f.Calls, helmCall{Method: "Uninstall", KubeConfig: string(helm.KubeConfigFromContext(ctx)), Timeout: helm.ActionTimeoutFromContext(ctx)})
+	if f.UninstallErr == nil {
+		delete(f.Releases, releaseName)
+	}
+	return f.UninstallErr
+}
+
+func (f *fakeHelmClient) Render(_ context.Context, _, _, _ string, values map[string]interface{}, _ string) (string, error) {
+	f.Calls = append(f.Calls, helmCall{Method: "Render", Values: values})
+	return f.Manifest, nil
+}
+
+func (f *fakeHelmClient) GetRelease(_ context.Context, releaseName, _ string) (*helm.ReleaseInfo, error) {
+	return f.Releases[releaseName], nil
+}
+
+func (f *fakeHelmClient) ReleaseExists(_ context.Context, releaseName, _ string) (bool, error) {
+	return f.Releases[releaseName] != nil, nil
+}
+
+func (f *fakeHelmClient) GetChartMetadata(_ context.Context, chartName, version string) (*chart.Metadata, error) {
+	if f.ChartErr != nil {
+		return nil, f.ChartErr
+	}
+	if metadata, ok := f.Charts[version]; ok {
+		return metadata, nil
+	}
+	return &chart.Metadata{Name: chartName, Version: version}, nil
+}
+
+func (f *fakeHelmClient) GetChartFile(_ context.Context, _, _, fileName string) ([]byte, error) {
+	f.Calls = append(f.Calls, helmCall{Method: "GetChartFile"})
+	if data, ok := f.ChartFiles[fileName]; ok {
+		return []byte(data), nil
+	}
+	return nil, nil
+}
+
+func (f *fakeHelmClient) ClearPendingRelease(_ context.Context, releaseName, _ string) error {
+	f.Calls = append(f.Calls, helmCall{Method: "ClearPendingRelease"})
+	if f.ClearErr != nil {
+		return f.ClearErr
+	}
+	if release := f.Releases[releaseName]; release != nil && release.Pending() {
+		release.Status = "failed"
+	}
+	return nil
+}
+
+// setRelease stores release under its name and returns it
+func (f *fakeHelmClient) setRelease(release *helm.ReleaseInfo) *helm.ReleaseInfo {
+	if f.Releases == nil {
+		f.Releases = map[string]*helm.ReleaseInfo{}
+	}
+	f.Releases[release.Name] = release
+	return release
+}
+
+// releasesOf returns releases keyed by name, for fakeHelmClient.Releases
+func releasesOf(releases ...*helm.ReleaseInfo) map[string]*helm.ReleaseInfo {
+	byName := map[string]*helm.ReleaseInfo{}
+	for _, release := range releases {
+		byName[release.Name] = release
+	}
+	return byName
+}
+
+// callsTo returns the recorded calls to the given method
+func (f *fakeHelmClient) callsTo(method string) []helmCall {
+	var calls []helmCall
+	for _, c := range f.Calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+// helmReleaseNameAnnotation is set by Helm on every object it creates
+const helmReleaseNameAnnotation = "meta.helm.sh/release-name"
+
+// releaseHealth sums the ready and desired replicas of the Deployments and StatefulSets
+// owned by a Helm release, in the cluster it is installed into
+func (r *AppDeploymentReconciler) releaseHealth(ctx context.Context, namespace, releaseName string) (ready, desired int32, err error) {
+	deployments := &appsv1.DeploymentList{}
+	if err := r.clusterClient(ctx).List(ctx, deployments, client.InNamespace(namespace)); err != nil {
+		return 0, 0, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, d := range deployments.Items {
+		if d.Annotations[helmReleaseNameAnnotation] != releaseName {
+			continue
+		}
+		ready, desired = addReplicas(ready, desired, d.Spec.Replicas, d.Status.ReadyReplicas, d.Status.ObservedGeneration < d.Generation)
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.clusterClient(ctx).List(ctx, statefulSets, client.InNamespace(namespace)); err != nil {
+		return 0, 0, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for _, s := range statefulSets.Items {
+		if s.Annotations[helmReleaseNameAnnotation] != releaseName {
+			continue
+		}
+		ready, desired = addReplicas(ready, desired, s.Spec.Replicas, s.Status.ReadyReplicas, s.Status.ObservedGeneration < s.Generation)
+	}
+
+	return ready, desired, nil
+}
+
+// addReplicas adds a workload's replicas to the totals. A workload whose latest spec
+// has not been observed by its controller counts as having no ready replicas.
+func addReplicas(ready, desired int32, specReplicas *int32, readyReplicas int32, stale bool) (int32, int32) {
+	want := int32(1)
+	if specReplicas != nil {
+		want = *specReplicas
+	}
+	if stale {
+		readyReplicas = 0
+	}
+	return ready + min(readyReplicas, want), desired + want
+}
+
+// setHealthStatus records the readiness of the release's workloads in the status
+func (r *AppDeploymentReconciler) setHealthStatus(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, releaseName string) {
+	ready, desired, err := r.releaseHealth(ctx, appDeployment.Namespace, releaseName)
+	if err != nil {
+		meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+			Type:               ConditionTypeHealthy,
+			Status:             metav1.ConditionUnknown,
+			Reason:             "HealthCheckError",
+			Message:            err.Error(),
+			LastTransitionTime: metav1.Now(),
+		})
+		return
+	}
+
+	appDeployment.Status.HealthyReplicas = ready
+	appDeployment.Status.DesiredReplicas = desired
+
+	condition := metav1.Condition{
+		Type:               ConditionTypeHealthy,
+		Status:             metav1.ConditionTrue,
+		Reason:             "WorkloadsReady",
+		Message:            fmt.Sprintf("%d/%d replicas ready", ready, desired),
+		LastTransitionTime: metav1.Now(),
+	}
+	if ready < desired {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "WorkloadsNotReady"
+	}
+	meta.SetStatusCondition(&appDeployment.Status.Conditions, condition)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+var _ = Describe("Release health", func() {
+	var (
+		ctx        context.Context
+		reconciler *AppDeploymentReconciler
+		ad         *appstorev1alpha1.AppDeployment
+	)
+
+	objectMeta := func(name, release string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Generation:  1,
+			Annotations: map[string]string{helmReleaseNameAnnotation: release},
+		}
+	}
+
+	deployment := func(name, release string, replicas, ready int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: objectMeta(name, release),
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(replicas)},
+			Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, ReadyReplicas: ready},
+		}
+	}
+
+	statefulSet := func(name, release string, replicas, ready int32) *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{
+			ObjectMeta: objectMeta(name, release),
+			Spec:       appsv1.StatefulSetSpec{Replicas: ptr.To(replicas)},
+			Status:     appsv1.StatefulSetStatus{ObservedGeneration: 1, ReadyReplicas: ready},
+		}
+	}
+
+	// updateStatusDeployed reports the release as deployed with the given workloads in the namespace
+	updateStatusDeployed := func(workloads ...client.Object) time.Duration {
+		reconciler.Client = fake.NewClientBuilder().
+			WithScheme(scheme.Scheme).
+			WithObjects(append(workloads, ad)...).
+			WithStatusSubresource(ad).
+			Build()
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+
+		release := &helm.ReleaseInfo{Name: "db", Namespace: "default", Revision: 1, Status: releaseStatusDeployed}
+		result, err := reconciler.updateStatusDeployed(ctx, ad, release, "hash")
+		Expect(err).NotTo(HaveOccurred())
+		return result.RequeueAfter
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		reconciler = &AppDeploymentReconciler{}
+		ad = &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec:       appstorev1alpha1.AppDeploymentSpec{AppName: "postgresql", TeamID: "team-a"},
+		}
+	})
+
+	It("is healthy when all workloads of the release are ready", func() {
+		requeueAfter := updateStatusDeployed(
+			deployment("db-api", "db", 2, 2),
+			statefulSet("db-postgresql", "db", 3, 3),
+		)
+
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+		Expect(ad.Status.HealthyReplicas).To(Equal(int32(5)))
+		Expect(ad.Status.DesiredReplicas).To(Equal(int32(5)))
+		Expect(meta.IsStatusConditionTrue(ad.Status.Conditions, ConditionTypeHealthy)).To(BeTrue())
+		Expect(requeueAfter).To(Equal(requeueAfterSuccess))
+	})
+
+	It("is not healthy while replicas are not ready", func() {
+		requeueAfter := updateStatusDeployed(
+			deployment("db-api", "db", 2, 2),
+			statefulSet("db-postgresql", "db", 3, 1),
+		)
+
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+		Expect(ad.Status.HealthyReplicas).To(Equal(int32(3)))
+		Expect(ad.Status.DesiredReplicas).To(Equal(int32(5)))
+
+		cond := meta.FindStatusCondition(ad.Status.Conditions, ConditionTypeHealthy)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal("WorkloadsNotReady"))
+		Expect(cond.Message).To(Equal("3/5 replicas ready"))
+		Expect(requeueAfter).To(Equal(requeueAfterFailure))
+	})
+
+	It("does not count ready replicas of a spec its controller has not observed", func() {
+		stale := deployment("db-api", "db", 2, 2)
+		stale.Generation = 2
+
+		updateStatusDeployed(stale)
+
+		Expect(ad.Status.HealthyReplicas).To(Equal(int32(0)))
+		Expect(ad.Status.DesiredReplicas).To(Equal(int32(2)))
+		Expect(meta.IsStatusConditionTrue(ad.Status.Conditions, ConditionTypeHealthy)).To(BeFalse())
+	})
+
+	It("ignores workloads of other releases", func() {
+		updateStatusDeployed(
+			deployment("db-api", "db", 1, 1),
+			deployment("cache", "cache", 3, 0),
+		)
+
+		Expect(ad.Status.HealthyReplicas).To(Equal(int32(1)))
+		Expect(ad.Status.DesiredReplicas).To(Equal(int32(1)))
+		Expect(meta.IsStatusConditionTrue(ad.Status.Conditions, ConditionTypeHealthy)).To(BeTrue())
+	})
+})
@@ -0,0 +1,73 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+// hookPhaseFailed is the phase Helm records for a hook that failed
+const hookPhaseFailed = "Failed"
+
+// recordHooks stores the results of the hooks run for a release revision in the status and
+// emits a Warning event for each hook that failed, which would otherwise go unnoticed
+func (r *AppDeploymentReconciler) recordHooks(appDeployment *appstorev1alpha1.AppDeployment, releaseInfo *helm.ReleaseInfo) {
+	appDeployment.Status.Hooks = nil
+	for _, hook := range releaseInfo.Hooks {
+		status := appstorev1alpha1.HookStatus{
+			Name:   hook.Name,
+			Kind:   hook.Kind,
+			Events: hook.Events,
+			Phase:  hook.Phase,
+		}
+		if !hook.StartedAt.IsZero() {
+			status.StartedAt = &metav1.Time{Time: hook.StartedAt}
+		}
+		if !hook.CompletedAt.IsZero() {
+			status.CompletedAt = &metav1.Time{Time: hook.CompletedAt}
+		}
+		appDeployment.Status.Hooks = append(appDeployment.Status.Hooks, status)
+
+		if hook.Phase == hookPhaseFailed && r.Recorder != nil {
+			r.Recorder.Event(appDeployment, corev1.EventTypeWarning, "HookFailed",
+				fmt.Sprintf("%s hook %s/%s of revision %d failed", strings.Join(hook.Events, ","), hook.Kind, hook.Name, releaseInfo.Revision))
+		}
+	}
+}
+
+// recordFailedReleaseHooks records the hooks of the revision created by a failed install or
+// upgrade, if it created one
+func (r *AppDeploymentReconciler) recordFailedReleaseHooks(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, releaseName string) {
+	failed, err := r.HelmClient.GetRelease(ctx, releaseName, appDeployment.Namespace)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to get release to record its hooks", "release", releaseName)
+		return
+	}
+	if failed == nil || failed.Revision == appDeployment.Status.HelmReleaseRevision {
+		return
+	}
+	r.recordHooks(appDeployment, failed)
+}
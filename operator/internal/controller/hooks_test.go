@@ -0,0 +1,149 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+var _ = Describe("Hook status", func() {
+	var (
+		ctx        context.Context
+		fakeHelm   *fakeHelmClient
+		recorder   *record.FakeRecorder
+		reconciler *AppDeploymentReconciler
+		ad         *appstorev1alpha1.AppDeployment
+	)
+
+	started := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	mixedHooks := []helm.HookInfo{
+		{
+			Name: "db-migrate", Kind: "Job", Events: []string{"pre-upgrade"}, Phase: "Succeeded",
+			StartedAt: started, CompletedAt: started.Add(time.Minute),
+		},
+		{
+			Name: "db-smoke-test", Kind: "Pod", Events: []string{"post-install", "post-upgrade"}, Phase: "Failed",
+			StartedAt: started.Add(2 * time.Minute), CompletedAt: started.Add(3 * time.Minute),
+		},
+	}
+
+	reconcileHelm := func() {
+		reconciler = &AppDeploymentReconciler{
+			Client:     fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ad).WithStatusSubresource(ad).Build(),
+			HelmClient: fakeHelm,
+			Recorder:   recorder,
+		}
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+
+		_, err := reconciler.reconcileHelm(ctx, ad)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{}
+		recorder = record.NewFakeRecorder(10)
+		ad = &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec: appstorev1alpha1.AppDeploymentSpec{
+				AppName: "postgresql",
+				TeamID:  "team-a",
+			},
+		}
+	})
+
+	It("records the hooks of an install and warns about failed ones", func() {
+		fakeHelm.Hooks = mixedHooks
+		reconcileHelm()
+
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+		Expect(ad.Status.Hooks).To(HaveLen(2))
+		Expect(ad.Status.Hooks[0].Name).To(Equal("db-migrate"))
+		Expect(ad.Status.Hooks[0].Kind).To(Equal("Job"))
+		Expect(ad.Status.Hooks[0].Events).To(Equal([]string{"pre-upgrade"}))
+		Expect(ad.Status.Hooks[0].Phase).To(Equal("Succeeded"))
+		Expect(ad.Status.Hooks[0].StartedAt.Time).To(BeTemporally("==", started))
+		Expect(ad.Status.Hooks[0].CompletedAt.Time).To(BeTemporally("==", started.Add(time.Minute)))
+		Expect(ad.Status.Hooks[1].Name).To(Equal("db-smoke-test"))
+		Expect(ad.Status.Hooks[1].Phase).To(Equal("Failed"))
+
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(Equal("Warning HookFailed post-install,post-upgrade hook Pod/db-smoke-test of revision 1 failed"))
+	})
+
+	It("does not repeat hook events for an unchanged revision", func() {
+		fakeHelm.Hooks = mixedHooks
+		reconcileHelm()
+		Expect(recorder.Events).To(HaveLen(1))
+		<-recorder.Events
+
+		reconcileHelm()
+		Expect(fakeHelm.callsTo("Upgrade")).To(BeEmpty())
+		Expect(ad.Status.Hooks).To(HaveLen(2))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("replaces the hooks with those of the latest revision", func() {
+		fakeHelm.Hooks = mixedHooks
+		reconcileHelm()
+		<-recorder.Events
+
+		fakeHelm.Hooks = mixedHooks[:1]
+		ad.Spec.ChartVersion = "1.1.0"
+		reconcileHelm()
+
+		Expect(fakeHelm.callsTo("Upgrade")).To(HaveLen(1))
+		Expect(ad.Status.HelmReleaseRevision).To(Equal(2))
+		Expect(ad.Status.Hooks).To(HaveLen(1))
+		Expect(ad.Status.Hooks[0].Name).To(Equal("db-migrate"))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("records the hooks of a failed upgrade", func() {
+		reconcileHelm()
+		Expect(ad.Status.HelmReleaseRevision).To(Equal(1))
+
+		// Helm stores the failed revision along with the hooks it ran
+		fakeHelm.UpgradeErrs = []error{errors.New("post-upgrade hooks failed")}
+		fakeHelm.setRelease(&helm.ReleaseInfo{
+			Name: "db", Namespace: "default", Revision: 2, Status: "failed", ChartName: "postgresql", Hooks: mixedHooks,
+		})
+		ad.Spec.ChartVersion = "1.1.0"
+		reconcileHelm()
+
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseFailed))
+		Expect(ad.Status.HelmReleaseRevision).To(Equal(1))
+		Expect(ad.Status.Hooks).To(HaveLen(2))
+		Expect(ad.Status.Hooks[1].Phase).To(Equal("Failed"))
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(ContainSubstring("HookFailed post-install,post-upgrade hook Pod/db-smoke-test of revision 2 failed"))
+	})
+})
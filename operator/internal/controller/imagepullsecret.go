@@ -0,0 +1,119 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+// defaultServiceAccount is the service account of pods that don't name one
+const defaultServiceAccount = "default"
+
+// ParseImagePullSecret parses the namespace/name of the registry pull secret shared with
+// every deployment's namespace. An empty value returns nil, disabling the injection.
+func ParseImagePullSecret(value string) (*types.NamespacedName, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	namespace, name, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok || namespace == "" || name == "" {
+		return nil, fmt.Errorf("invalid image pull secret %q, expected namespace/name", value)
+	}
+	return &types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+// ensureImagePullSecret copies the shared pull secret into the deployment's namespace and
+// adds it to the imagePullSecrets of the namespace's default service account and of the
+// service accounts created by the release. Service accounts that don't exist yet are
+// patched by a later reconcile. For remote clusters the secret is copied from the
+// operator's cluster.
+func (r *AppDeploymentReconciler) ensureImagePullSecret(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, releaseName string) error {
+	if r.ImagePullSecret == nil {
+		return nil
+	}
+	namespace := appDeployment.Namespace
+	cluster := r.clusterClient(ctx)
+
+	if namespace != r.ImagePullSecret.Namespace || remoteCluster(ctx) {
+		// Without SetupWithManager, e.g. in tests, the secret is read with the client
+		var reader client.Reader = r.Client
+		if r.pullSecretReader != nil {
+			reader = r.pullSecretReader
+		}
+		source := &corev1.Secret{}
+		if err := reader.Get(ctx, *r.ImagePullSecret, source); err != nil {
+			return fmt.Errorf("failed to get image pull secret %s: %w", r.ImagePullSecret, err)
+		}
+
+		target := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: r.ImagePullSecret.Name, Namespace: namespace}}
+		result, err := controllerutil.CreateOrUpdate(ctx, cluster, target, func() error {
+			target.Type = source.Type
+			target.Data = source.Data
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to copy image pull secret to namespace %s: %w", namespace, err)
+		}
+		if result != controllerutil.OperationResultNone {
+			log.FromContext(ctx).Info("Copied image pull secret", "secret", r.ImagePullSecret.Name, "namespace", namespace, "result", result)
+		}
+	}
+
+	serviceAccounts := &corev1.ServiceAccountList{}
+	if err := cluster.List(ctx, serviceAccounts, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list service accounts: %w", err)
+	}
+	ref := corev1.LocalObjectReference{Name: r.ImagePullSecret.Name}
+	for i := range serviceAccounts.Items {
+		sa := &serviceAccounts.Items[i]
+		if sa.Name != defaultServiceAccount && sa.Annotations[helmReleaseNameAnnotation] != releaseName {
+			continue
+		}
+		if slices.Contains(sa.ImagePullSecrets, ref) {
+			continue
+		}
+		sa.ImagePullSecrets = append(sa.ImagePullSecrets, ref)
+		if err := cluster.Update(ctx, sa); err != nil {
+			return fmt.Errorf("failed to add image pull secret to service account %s: %w", sa.Name, err)
+		}
+		log.FromContext(ctx).Info("Added image pull secret to service account", "serviceAccount", sa.Name, "secret", ref.Name)
+	}
+	return nil
+}
+
+// injectImagePullSecret runs ensureImagePullSecret, reporting failures with a Warning event
+// rather than failing the deployment, whose release may well not need the secret
+func (r *AppDeploymentReconciler) injectImagePullSecret(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, releaseName string) {
+	if err := r.ensureImagePullSecret(ctx, appDeployment, releaseName); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to inject image pull secret")
+		if r.Recorder != nil {
+			r.Recorder.Event(appDeployment, corev1.EventTypeWarning, "ImagePullSecretFailed", err.Error())
+		}
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+var _ = Describe("Image pull secret", func() {
+	var (
+		ctx        context.Context
+		recorder   *record.FakeRecorder
+		reconciler *AppDeploymentReconciler
+		ad         *appstorev1alpha1.AppDeployment
+		source     *corev1.Secret
+	)
+
+	serviceAccount := func(name, release string) *corev1.ServiceAccount {
+		sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a"}}
+		if release != "" {
+			sa.Annotations = map[string]string{helmReleaseNameAnnotation: release}
+		}
+		return sa
+	}
+
+	pullSecretsOf := func(name string) []corev1.LocalObjectReference {
+		sa := &corev1.ServiceAccount{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Namespace: "team-a", Name: name}, sa)).To(Succeed())
+		return sa.ImagePullSecrets
+	}
+
+	reconcileHelm := func() {
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		_, err := reconciler.reconcileHelm(ctx, ad)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		recorder = record.NewFakeRecorder(10)
+		ad = &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "team-a"},
+			Spec: appstorev1alpha1.AppDeploymentSpec{
+				AppName: "postgresql",
+				TeamID:  "team-a",
+			},
+		}
+		source = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "appstore-system"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)},
+		}
+		reconciler = &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).
+				WithObjects(ad, source,
+					serviceAccount("default", ""),
+					serviceAccount("db-postgresql", "db"),
+					serviceAccount("other-app", "other"),
+				).
+				WithStatusSubresource(ad).Build(),
+			HelmClient:      &fakeHelmClient{},
+			Recorder:        recorder,
+			ImagePullSecret: &types.NamespacedName{Namespace: "appstore-system", Name: "registry"},
+		}
+	})
+
+	It("copies the secret and adds it to the default and the release's service accounts", func() {
+		reconcileHelm()
+
+		copied := &corev1.Secret{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Namespace: "team-a", Name: "registry"}, copied)).To(Succeed())
+		Expect(copied.Type).To(Equal(corev1.SecretTypeDockerConfigJson))
+		Expect(copied.Data).To(Equal(source.Data))
+
+		registry := []corev1.LocalObjectReference{{Name: "registry"}}
+		Expect(pullSecretsOf("default")).To(Equal(registry))
+		Expect(pullSecretsOf("db-postgresql")).To(Equal(registry))
+		Expect(pullSecretsOf("other-app")).To(BeEmpty())
+
+		By("not adding the secret twice")
+		reconcileHelm()
+		Expect(pullSecretsOf("default")).To(Equal(registry))
+		Expect(pullSecretsOf("db-postgresql")).To(Equal(registry))
+	})
+
+	It("keeps existing pull secrets of a service account", func() {
+		sa := serviceAccount("default", "")
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(sa), sa)).To(Succeed())
+		sa.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "team-registry"}}
+		Expect(reconciler.Update(ctx, sa)).To(Succeed())
+
+		reconcileHelm()
+		Expect(pullSecretsOf("default")).To(Equal([]corev1.LocalObjectReference{{Name: "team-registry"}, {Name: "registry"}}))
+	})
+
+	It("updates the copy when the source secret changes", func() {
+		reconcileHelm()
+
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(source), source)).To(Succeed())
+		source.Data = map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"registry.example.com":{}}}`)}
+		Expect(reconciler.Update(ctx, source)).To(Succeed())
+
+		reconcileHelm()
+		copied := &corev1.Secret{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Namespace: "team-a", Name: "registry"}, copied)).To(Succeed())
+		Expect(copied.Data).To(Equal(source.Data))
+	})
+
+	It("does nothing when disabled", func() {
+		reconciler.ImagePullSecret = nil
+		reconcileHelm()
+
+		err := reconciler.Get(ctx, types.NamespacedName{Namespace: "team-a", Name: "registry"}, &corev1.Secret{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(pullSecretsOf("default")).To(BeEmpty())
+	})
+
+	It("emits a warning but deploys when the source secret is missing", func() {
+		reconciler.ImagePullSecret = &types.NamespacedName{Namespace: "appstore-system", Name: "missing"}
+		reconcileHelm()
+
+		Expect(recorder.Events).To(Receive(ContainSubstring("ImagePullSecretFailed")))
+		Expect(pullSecretsOf("default")).To(BeEmpty())
+	})
+
+	It("reads the source secret outside the watched namespaces from its own cache", func() {
+		// Like the manager's cache, the client only reads the watched namespaces
+		reconciler.WatchNamespaces = []string{"team-a"}
+		unwatched := reconciler.Client.(client.WithWatch)
+		reconciler.Client = interceptor.NewClient(unwatched, interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if key.Namespace != "" && !slices.Contains(reconciler.WatchNamespaces, key.Namespace) {
+					return fmt.Errorf("unable to get: %s because of unknown namespace for the cache", key)
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+		})
+
+		By("failing to copy the secret through the manager's cache")
+		reconcileHelm()
+		Expect(recorder.Events).To(Receive(ContainSubstring("unknown namespace for the cache")))
+		Expect(pullSecretsOf("default")).To(BeEmpty())
+
+		By("copying it with the pull secret's own reader")
+		reconciler.pullSecretReader = unwatched
+		reconcileHelm()
+		copied := &corev1.Secret{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Namespace: "team-a", Name: "registry"}, copied)).To(Succeed())
+		Expect(copied.Data).To(Equal(source.Data))
+		Expect(pullSecretsOf("default")).To(Equal([]corev1.LocalObjectReference{{Name: "registry"}}))
+	})
+
+	It("parses the flag", func() {
+		secret, err := ParseImagePullSecret("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secret).To(BeNil())
+
+		secret, err = ParseImagePullSecret(" appstore-system/registry ")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*secret).To(Equal(types.NamespacedName{Namespace: "appstore-system", Name: "registry"}))
+
+		for _, value := range []string{"registry", "/registry", "appstore-system/"} {
+			_, err := ParseImagePullSecret(value)
+			Expect(err).To(HaveOccurred(), value)
+		}
+	})
+})
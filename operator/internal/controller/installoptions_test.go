@@ -0,0 +1,134 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+var _ = Describe("Install options", func() {
+	var (
+		ctx      context.Context
+		fakeHelm *fakeHelmClient
+	)
+
+	newDeployment := func(opts *appstorev1alpha1.InstallOptions) *appstorev1alpha1.AppDeployment {
+		return &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec: appstorev1alpha1.AppDeploymentSpec{
+				AppName:        "postgresql",
+				TeamID:         "team-a",
+				InstallOptions: opts,
+			},
+		}
+	}
+
+	reconcileHelm := func(ad *appstorev1alpha1.AppDeployment) {
+		reconciler := &AppDeploymentReconciler{
+			Client:     fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ad).WithStatusSubresource(ad).Build(),
+			HelmClient: fakeHelm,
+		}
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+
+		_, err := reconciler.reconcileHelm(ctx, ad)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{}
+	})
+
+	It("installs with the default options when none are set", func() {
+		reconcileHelm(newDeployment(nil))
+
+		installs := fakeHelm.callsTo("Install")
+		Expect(installs).To(HaveLen(1))
+		Expect(installs[0].Options).To(Equal(helm.ActionOptions{}))
+	})
+
+	It("passes each option to installs", func() {
+		for _, opts := range []helm.ActionOptions{
+			{Atomic: true},
+			{CleanupOnFail: true},
+			{DisableHooks: true},
+			{SkipCRDs: true},
+		} {
+			fakeHelm = &fakeHelmClient{}
+			reconcileHelm(newDeployment(&appstorev1alpha1.InstallOptions{
+				Atomic:        opts.Atomic,
+				CleanupOnFail: opts.CleanupOnFail,
+				DisableHooks:  opts.DisableHooks,
+				SkipCRDs:      opts.SkipCRDs,
+			}))
+
+			installs := fakeHelm.callsTo("Install")
+			Expect(installs).To(HaveLen(1))
+			Expect(installs[0].Options).To(Equal(opts))
+		}
+	})
+
+	It("passes the options to upgrades", func() {
+		fakeHelm.setRelease(&helm.ReleaseInfo{
+			Name: "db", Namespace: "default", Revision: 1, Status: releaseStatusDeployed, ChartName: "postgresql",
+		})
+		ad := newDeployment(&appstorev1alpha1.InstallOptions{Atomic: true, CleanupOnFail: true, DisableHooks: true, SkipCRDs: true})
+		reconcileHelm(ad)
+
+		upgrades := fakeHelm.callsTo("Upgrade")
+		Expect(upgrades).To(HaveLen(1))
+		Expect(upgrades[0].Options).To(Equal(helm.ActionOptions{Atomic: true, CleanupOnFail: true, DisableHooks: true, SkipCRDs: true}))
+	})
+
+	It("reuses the release's values on upgrades with the reuse values strategy", func() {
+		for strategy, reuse := range map[appstorev1alpha1.ValuesStrategy]bool{
+			"":                                   false,
+			appstorev1alpha1.ValuesStrategyReset: false,
+			appstorev1alpha1.ValuesStrategyReuse: true,
+		} {
+			fakeHelm = &fakeHelmClient{Releases: releasesOf(&helm.ReleaseInfo{
+				Name: "db", Namespace: "default", Revision: 1, Status: releaseStatusDeployed, ChartName: "postgresql",
+			})}
+			ad := newDeployment(nil)
+			ad.Spec.ValuesStrategy = strategy
+			reconcileHelm(ad)
+
+			upgrades := fakeHelm.callsTo("Upgrade")
+			Expect(upgrades).To(HaveLen(1))
+			Expect(upgrades[0].Options.ReuseValues).To(Equal(reuse), "values strategy %q", strategy)
+		}
+	})
+
+	It("keeps the options a strategy needs", func() {
+		ad := newDeployment(&appstorev1alpha1.InstallOptions{DisableHooks: true})
+		ad.Spec.Strategy = appstorev1alpha1.StrategyCanary
+
+		Expect(withInstallOptions(ad, helm.ActionOptions{Wait: true, Atomic: true})).To(Equal(
+			helm.ActionOptions{Wait: true, Atomic: true, DisableHooks: true},
+		))
+	})
+})
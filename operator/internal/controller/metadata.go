@@ -0,0 +1,115 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	helmvalues "appstore/shared/values"
+)
+
+const (
+	// deploymentAppLabel and deploymentTeamLabel label AppDeployments with their
+	// spec.appName and spec.teamID, so that they can be listed by app or team however they
+	// were created
+	deploymentAppLabel  = "appstore.bitpipe.no/app"
+	deploymentTeamLabel = "appstore.bitpipe.no/team"
+)
+
+// setSpecLabels sets the app and team labels of the AppDeployment to its spec and reports
+// whether they changed. A value that isn't a valid label value removes the label instead,
+// so that the deployment isn't listed under a stale value.
+func setSpecLabels(appDeployment *appstorev1alpha1.AppDeployment) bool {
+	labels := appDeployment.GetLabels()
+	changed := false
+	for key, value := range map[string]string{
+		deploymentAppLabel:  appDeployment.Spec.AppName,
+		deploymentTeamLabel: appDeployment.Spec.TeamID,
+	} {
+		current, ok := labels[key]
+		if value == "" || len(validation.IsValidLabelValue(value)) > 0 {
+			if ok {
+				delete(labels, key)
+				changed = true
+			}
+			continue
+		}
+		if !ok || current != value {
+			if labels == nil {
+				labels = make(map[string]string, 2)
+			}
+			labels[key] = value
+			changed = true
+		}
+	}
+	appDeployment.SetLabels(labels)
+	return changed
+}
+
+// releaseHash hashes the values together with the common labels and annotations, so that
+// changing either upgrades the release. Without common labels or annotations it is the
+// hash of the values alone.
+func releaseHash(appDeployment *appstorev1alpha1.AppDeployment, values map[string]interface{}) string {
+	spec := appDeployment.Spec
+	if len(spec.CommonLabels) == 0 && len(spec.CommonAnnotations) == 0 {
+		return helmvalues.Hash(values)
+	}
+	return helmvalues.Hash(map[string]interface{}{
+		"values":            values,
+		"commonLabels":      spec.CommonLabels,
+		"commonAnnotations": spec.CommonAnnotations,
+	})
+}
+
+// validateCommonMetadata checks that spec.commonLabels are valid label keys and values
+// and spec.commonAnnotations valid annotation keys
+func validateCommonMetadata(appDeployment *appstorev1alpha1.AppDeployment) error {
+	var problems []string
+	for _, key := range sortedKeys(appDeployment.Spec.CommonLabels) {
+		for _, msg := range validation.IsQualifiedName(key) {
+			problems = append(problems, fmt.Sprintf("label key %q: %s", key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(appDeployment.Spec.CommonLabels[key]) {
+			problems = append(problems, fmt.Sprintf("label %q value: %s", key, msg))
+		}
+	}
+	for _, key := range sortedKeys(appDeployment.Spec.CommonAnnotations) {
+		// Annotation keys follow the label key syntax, case-insensitively
+		for _, msg := range validation.IsQualifiedName(strings.ToLower(key)) {
+			problems = append(problems, fmt.Sprintf("annotation key %q: %s", key, msg))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid common metadata: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+	helmvalues "appstore/shared/values"
+)
+
+var _ = Describe("Common labels and annotations", func() {
+	var (
+		ctx        context.Context
+		fakeHelm   *fakeHelmClient
+		reconciler *AppDeploymentReconciler
+		ad         *appstorev1alpha1.AppDeployment
+	)
+
+	reconcileHelm := func() {
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		_, err := reconciler.reconcileHelm(ctx, ad)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	// update changes the stored deployment's spec
+	update := func(mutate func(*appstorev1alpha1.AppDeploymentSpec)) {
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		mutate(&ad.Spec)
+		Expect(reconciler.Update(ctx, ad)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{}
+		ad = &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec: appstorev1alpha1.AppDeploymentSpec{
+				AppName:           "postgresql",
+				TeamID:            "team-a",
+				CommonLabels:      map[string]string{"cost-center": "cc-42"},
+				CommonAnnotations: map[string]string{"example.com/owner": "alice@example.com"},
+			},
+		}
+		reconciler = &AppDeploymentReconciler{
+			Client:     fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ad).WithStatusSubresource(ad).Build(),
+			HelmClient: fakeHelm,
+		}
+	})
+
+	It("passes them to installs", func() {
+		reconcileHelm()
+
+		installs := fakeHelm.callsTo("Install")
+		Expect(installs).To(HaveLen(1))
+		Expect(installs[0].Options.Labels).To(Equal(map[string]string{"cost-center": "cc-42"}))
+		Expect(installs[0].Options.Annotations).To(Equal(map[string]string{"example.com/owner": "alice@example.com"}))
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+	})
+
+	It("upgrades the release when they change", func() {
+		reconcileHelm()
+		reconcileHelm()
+		Expect(fakeHelm.callsTo("Upgrade")).To(BeEmpty())
+
+		update(func(spec *appstorev1alpha1.AppDeploymentSpec) {
+			spec.CommonLabels["cost-center"] = "cc-7"
+		})
+		reconcileHelm()
+
+		upgrades := fakeHelm.callsTo("Upgrade")
+		Expect(upgrades).To(HaveLen(1))
+		Expect(upgrades[0].Options.Labels).To(Equal(map[string]string{"cost-center": "cc-7"}))
+
+		update(func(spec *appstorev1alpha1.AppDeploymentSpec) {
+			spec.CommonAnnotations = nil
+		})
+		reconcileHelm()
+
+		upgrades = fakeHelm.callsTo("Upgrade")
+		Expect(upgrades).To(HaveLen(2))
+		Expect(upgrades[1].Options.Annotations).To(BeEmpty())
+	})
+
+	It("keeps the values hash of deployments without them", func() {
+		values := map[string]interface{}{"replicas": 2}
+		ad.Spec.CommonLabels = nil
+		ad.Spec.CommonAnnotations = nil
+
+		Expect(releaseHash(ad, values)).To(Equal(helmvalues.Hash(values)))
+
+		ad.Spec.CommonLabels = map[string]string{"cost-center": "cc-42"}
+		Expect(releaseHash(ad, values)).NotTo(Equal(helmvalues.Hash(values)))
+	})
+
+	It("fails on invalid labels and annotations without installing", func() {
+		update(func(spec *appstorev1alpha1.AppDeploymentSpec) {
+			spec.CommonLabels = map[string]string{"cost center": "cc-42", "owner": "alice@example.com"}
+			spec.CommonAnnotations = map[string]string{"-owner": "alice"}
+		})
+		reconcileHelm()
+
+		Expect(fakeHelm.Calls).To(BeEmpty())
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseFailed))
+		cond := meta.FindStatusCondition(ad.Status.Conditions, ConditionTypeReady)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Reason).To(Equal("InvalidCommonMetadata"))
+		Expect(cond.Message).To(ContainSubstring(`label key "cost center"`))
+		Expect(cond.Message).To(ContainSubstring(`label "owner" value`))
+		Expect(cond.Message).To(ContainSubstring(`annotation key "-owner"`))
+	})
+
+	It("are kept by strategies", func() {
+		Expect(withInstallOptions(ad, helm.ActionOptions{Wait: true})).To(Equal(helm.ActionOptions{
+			Wait:        true,
+			Labels:      map[string]string{"cost-center": "cc-42"},
+			Annotations: map[string]string{"example.com/owner": "alice@example.com"},
+		}))
+	})
+})
+
+var _ = Describe("App and team labels", func() {
+	It("labels deployments with their app and team however they were created", func() {
+		ctx := context.Background()
+		ad := &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "db",
+				Namespace:  "default",
+				Labels:     map[string]string{"owner": "gitops", deploymentTeamLabel: "team-old"},
+				Finalizers: []string{finalizerName},
+			},
+			Spec: appstorev1alpha1.AppDeploymentSpec{AppName: "postgresql", TeamID: "team-a"},
+		}
+		reconciler := &AppDeploymentReconciler{
+			Client:     fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ad).WithStatusSubresource(ad).Build(),
+			HelmClient: &fakeHelmClient{},
+		}
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ad)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		Expect(ad.Labels).To(Equal(map[string]string{
+			"owner":             "gitops",
+			deploymentAppLabel:  "postgresql",
+			deploymentTeamLabel: "team-a",
+		}))
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+	})
+
+	It("reports whether the labels changed", func() {
+		ad := &appstorev1alpha1.AppDeployment{Spec: appstorev1alpha1.AppDeploymentSpec{AppName: "postgresql", TeamID: "team-a"}}
+		Expect(setSpecLabels(ad)).To(BeTrue())
+		Expect(setSpecLabels(ad)).To(BeFalse())
+
+		By("removing a label whose spec field isn't a valid label value")
+		ad.Spec.TeamID = strings.Repeat("a", 64)
+		Expect(setSpecLabels(ad)).To(BeTrue())
+		Expect(ad.Labels).To(Equal(map[string]string{deploymentAppLabel: "postgresql"}))
+	})
+})
@@ -0,0 +1,121 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+// phases are the values of the phase label of deploymentsByPhase. Labelling only with this
+// fixed set, rather than names or teams, bounds the gauge to one series per phase.
+var phases = []appstorev1alpha1.AppDeploymentPhase{
+	appstorev1alpha1.PhasePending,
+	appstorev1alpha1.PhaseInstalling,
+	appstorev1alpha1.PhaseUpgrading,
+	appstorev1alpha1.PhaseDeployed,
+	appstorev1alpha1.PhaseFailed,
+	appstorev1alpha1.PhaseUninstalling,
+}
+
+// deploymentsByPhase counts the AppDeployments in the cluster by phase
+var deploymentsByPhase = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "appstore_deployments_by_phase",
+		Help: "Number of AppDeployments in each phase.",
+	},
+	[]string{"phase"},
+)
+
+// otherApp is the app label of the metrics of charts missing from the catalog
+const otherApp = "other"
+
+// reconcileDuration observes how long reconciles take by app
+var reconcileDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "appstore_reconcile_duration_seconds",
+		Help:    "Duration of AppDeployment reconciles by app.",
+		Buckets: []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	},
+	[]string{"appName"},
+)
+
+// reconcileFailures counts the reconciles that returned an error or recorded a new failure
+// of the AppDeployment, by app
+var reconcileFailures = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "appstore_reconcile_failures_total",
+		Help: "Number of AppDeployment reconciles that failed, by app.",
+	},
+	[]string{"appName"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(deploymentsByPhase, reconcileDuration, reconcileFailures)
+}
+
+// observeReconcile records the duration and outcome of a reconcile of appDeployment.
+// previousFailures is the failure count before the reconcile, so re-reconciling a
+// deployment that is already failed is not counted again.
+func (r *AppDeploymentReconciler) observeReconcile(appDeployment *appstorev1alpha1.AppDeployment, previousFailures int, duration time.Duration, err error) {
+	app := r.appLabel(appDeployment.Spec.AppName)
+	reconcileDuration.WithLabelValues(app).Observe(duration.Seconds())
+	if err != nil || appDeployment.Status.FailureCount > previousFailures {
+		reconcileFailures.WithLabelValues(app).Inc()
+	}
+}
+
+// appLabel returns the app label for appName. AppDeployments can name any chart, so only
+// charts in the catalog get their own series; the rest share one, bounding the label set
+// by the size of the catalog.
+func (r *AppDeploymentReconciler) appLabel(appName string) string {
+	if r.ChartValidator == nil || !r.ChartValidator.ChartExists(appName) {
+		return otherApp
+	}
+	return appName
+}
+
+// updatePhaseMetrics recounts the AppDeployments in each phase across all namespaces. The
+// list is served from the manager's cache, so this is cheap to do on every reconcile.
+func (r *AppDeploymentReconciler) updatePhaseMetrics(ctx context.Context) {
+	list := &appstorev1alpha1.AppDeploymentList{}
+	if err := r.List(ctx, list); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list AppDeployments for metrics")
+		return
+	}
+
+	counts := make(map[appstorev1alpha1.AppDeploymentPhase]int, len(phases))
+	for _, ad := range list.Items {
+		phase := ad.Status.Phase
+		if phase == "" {
+			// Not yet reconciled
+			phase = appstorev1alpha1.PhasePending
+		}
+		counts[phase]++
+	}
+
+	// Phases outside the known set are dropped rather than exported as new series
+	for _, phase := range phases {
+		deploymentsByPhase.WithLabelValues(string(phase)).Set(float64(counts[phase]))
+	}
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+var _ = Describe("Phase metrics", func() {
+	var (
+		ctx        context.Context
+		reconciler *AppDeploymentReconciler
+	)
+
+	newDeployment := func(namespace, name string, phase appstorev1alpha1.AppDeploymentPhase) *appstorev1alpha1.AppDeployment {
+		return &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       appstorev1alpha1.AppDeploymentSpec{AppName: "postgresql", TeamID: "team-a"},
+			Status:     appstorev1alpha1.AppDeploymentStatus{Phase: phase},
+		}
+	}
+
+	reconcile := func(namespace, name string) {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	gauge := func(phase appstorev1alpha1.AppDeploymentPhase) float64 {
+		return testutil.ToFloat64(deploymentsByPhase.WithLabelValues(string(phase)))
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		objects := []client.Object{
+			newDeployment("team-a", "db", appstorev1alpha1.PhaseDeployed),
+			newDeployment("team-a", "cache", appstorev1alpha1.PhaseDeployed),
+			newDeployment("team-b", "db", appstorev1alpha1.PhaseFailed),
+			newDeployment("team-b", "queue", appstorev1alpha1.PhaseUpgrading),
+			newDeployment("team-c", "new", ""),
+		}
+		reconciler = &AppDeploymentReconciler{
+			Client:     fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).WithStatusSubresource(objects...).Build(),
+			HelmClient: &fakeHelmClient{},
+		}
+	})
+
+	It("counts the deployments in each phase across namespaces", func() {
+		for _, key := range [][2]string{{"team-a", "db"}, {"team-a", "cache"}, {"team-b", "db"}, {"team-b", "queue"}, {"team-c", "new"}} {
+			reconcile(key[0], key[1])
+		}
+
+		Expect(gauge(appstorev1alpha1.PhaseDeployed)).To(Equal(2.0))
+		Expect(gauge(appstorev1alpha1.PhaseFailed)).To(Equal(1.0))
+		Expect(gauge(appstorev1alpha1.PhaseUpgrading)).To(Equal(1.0))
+		Expect(gauge(appstorev1alpha1.PhasePending)).To(Equal(1.0))
+		Expect(gauge(appstorev1alpha1.PhaseInstalling)).To(Equal(0.0))
+		Expect(gauge(appstorev1alpha1.PhaseUninstalling)).To(Equal(0.0))
+	})
+
+	It("follows phase changes and deletions", func() {
+		reconcile("team-a", "db")
+		Expect(gauge(appstorev1alpha1.PhaseFailed)).To(Equal(1.0))
+
+		ad := &appstorev1alpha1.AppDeployment{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Namespace: "team-b", Name: "db"}, ad)).To(Succeed())
+		ad.Status.Phase = appstorev1alpha1.PhaseDeployed
+		Expect(reconciler.Status().Update(ctx, ad)).To(Succeed())
+		reconcile("team-b", "db")
+
+		Expect(gauge(appstorev1alpha1.PhaseFailed)).To(Equal(0.0))
+		Expect(gauge(appstorev1alpha1.PhaseDeployed)).To(Equal(3.0))
+
+		Expect(gauge(appstorev1alpha1.PhasePending)).To(Equal(1.0))
+		Expect(reconciler.Delete(ctx, newDeployment("team-c", "new", ""))).To(Succeed())
+		reconcile("team-c", "new")
+
+		Expect(gauge(appstorev1alpha1.PhasePending)).To(Equal(0.0))
+	})
+})
+
+// fakeChartValidator is a catalog of charts
+type fakeChartValidator []string
+
+func (v fakeChartValidator) ChartExists(chartName string) bool {
+	return slices.Contains(v, chartName)
+}
+
+func (v fakeChartValidator) ListCharts() ([]string, error) {
+	return v, nil
+}
+
+var _ = Describe("Reconcile metrics", func() {
+	var (
+		ctx      context.Context
+		fakeHelm *fakeHelmClient
+	)
+
+	// reconcile reconciles a new AppDeployment of the app, which already has its finalizer
+	reconcile := func(name, appName string) {
+		ad := &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Finalizers: []string{finalizerName}},
+			Spec:       appstorev1alpha1.AppDeploymentSpec{AppName: appName, TeamID: "team-a"},
+		}
+		reconciler := &AppDeploymentReconciler{
+			Client:         fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ad).WithStatusSubresource(ad).Build(),
+			HelmClient:     fakeHelm,
+			ChartValidator: fakeChartValidator{"postgresql", "redis"},
+		}
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ad)})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	observations := func(appName string) uint64 {
+		metric := &dto.Metric{}
+		Expect(reconcileDuration.WithLabelValues(appName).(prometheus.Histogram).Write(metric)).To(Succeed())
+		return metric.GetHistogram().GetSampleCount()
+	}
+
+	failures := func(appName string) float64 {
+		return testutil.ToFloat64(reconcileFailures.WithLabelValues(appName))
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{}
+	})
+
+	It("observes reconciles by chart", func() {
+		postgresql, redis := observations("postgresql"), observations("redis")
+		postgresqlFailures, redisFailures := failures("postgresql"), failures("redis")
+
+		reconcile("db", "postgresql")
+		fakeHelm.InstallErr = errors.New("timed out waiting for the condition")
+		reconcile("cache", "redis")
+		reconcile("cache-2", "redis")
+
+		Expect(observations("postgresql")).To(Equal(postgresql + 1))
+		Expect(observations("redis")).To(Equal(redis + 2))
+		Expect(failures("postgresql")).To(Equal(postgresqlFailures))
+		Expect(failures("redis")).To(Equal(redisFailures + 2))
+	})
+
+	It("groups charts missing from the catalog", func() {
+		other, otherFailures := observations(otherApp), failures(otherApp)
+
+		reconcile("one", "not-in-catalog")
+		reconcile("two", "also-not-in-catalog")
+
+		Expect(observations(otherApp)).To(Equal(other + 2))
+		Expect(failures(otherApp)).To(Equal(otherFailures + 2))
+		Expect(testutil.CollectAndCount(reconcileFailures)).To(BeNumerically("<=", 3))
+	})
+
+	It("does not count re-reconciles of a failed deployment", func() {
+		ad := &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "rolled-back", Namespace: "default", Finalizers: []string{finalizerName}},
+			Spec:       appstorev1alpha1.AppDeploymentSpec{AppName: "postgresql", TeamID: "team-a", AutoRollback: true},
+		}
+		reconciler := &AppDeploymentReconciler{
+			Client:         fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ad).WithStatusSubresource(ad).Build(),
+			HelmClient:     fakeHelm,
+			ChartValidator: fakeChartValidator{"postgresql"},
+		}
+		reconcileRolledBack := func() {
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ad)})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		reconcileRolledBack()
+
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		ad.Spec.ChartVersion = "2.0.0"
+		Expect(reconciler.Update(ctx, ad)).To(Succeed())
+		fakeHelm.UpgradeErrs = []error{errors.New("timed out waiting for the condition")}
+		before := failures("postgresql")
+
+		reconcileRolledBack()
+		Expect(failures("postgresql")).To(Equal(before + 1))
+
+		// The rolled back upgrade isn't retried, so the deployment stays failed
+		reconcileRolledBack()
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseFailed))
+		Expect(fakeHelm.callsTo("Upgrade")).To(HaveLen(1))
+		Expect(failures("postgresql")).To(Equal(before + 1))
+	})
+})
@@ -0,0 +1,85 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+// ReasonNamespaceTerminating is the condition reason while the release's namespace is being deleted
+const ReasonNamespaceTerminating = "NamespaceTerminating"
+
+// namespaceTerminating reports whether the namespace is being deleted in the cluster the
+// release is installed into. A namespace that doesn't exist yet is created by the install.
+// A namespace-scoped operator may not be allowed to read namespaces, in which case the
+// Helm operation goes ahead and fails on its own if the namespace is terminating.
+func (r *AppDeploymentReconciler) namespaceTerminating(ctx context.Context, namespace string) (bool, error) {
+	ns := &corev1.Namespace{}
+	if err := r.clusterClient(ctx).Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		if apierrors.IsForbidden(err) {
+			log.FromContext(ctx).V(1).Info("Not allowed to check whether the namespace is terminating", "namespace", namespace)
+			return false, nil
+		}
+		return false, err
+	}
+	return ns.Status.Phase == corev1.NamespaceTerminating || !ns.DeletionTimestamp.IsZero(), nil
+}
+
+// updateStatusNamespaceTerminating records that the release's namespace is being deleted
+// and requeues to check again, as Helm operations in it would fail
+func (r *AppDeploymentReconciler) updateStatusNamespaceTerminating(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment) (ctrl.Result, error) {
+	message := fmt.Sprintf("Namespace %s is terminating, retrying in %s", appDeployment.Namespace, requeueAfterFailure)
+
+	appDeployment.Status.Message = message
+	appDeployment.Status.LastReconcileTime = &metav1.Time{Time: time.Now()}
+
+	meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonNamespaceTerminating,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	})
+	meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeReconciling,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonNamespaceTerminating,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	})
+
+	if err := r.Status().Update(ctx, appDeployment); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfterFailure}, nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+var _ = Describe("Namespace termination", func() {
+	var (
+		ctx        context.Context
+		fakeHelm   *fakeHelmClient
+		reconciler *AppDeploymentReconciler
+	)
+
+	key := client.ObjectKey{Name: "db", Namespace: "team-a"}
+
+	setup := func(phase corev1.NamespacePhase) {
+		ns := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Namespace},
+			Status:     corev1.NamespaceStatus{Phase: phase},
+		}
+		ad := &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       key.Name,
+				Namespace:  key.Namespace,
+				Finalizers: []string{finalizerName},
+			},
+			Spec: appstorev1alpha1.AppDeploymentSpec{AppName: "postgresql", TeamID: "team-a"},
+		}
+		reconciler = &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(ns, ad).
+				WithStatusSubresource(ad).
+				Build(),
+			HelmClient: fakeHelm,
+		}
+	}
+
+	reconcile := func() (ctrl.Result, *appstorev1alpha1.AppDeployment) {
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		ad := &appstorev1alpha1.AppDeployment{}
+		Expect(reconciler.Get(ctx, key, ad)).To(Succeed())
+		return result, ad
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{}
+	})
+
+	It("installs into an active namespace", func() {
+		setup(corev1.NamespaceActive)
+
+		_, ad := reconcile()
+		Expect(fakeHelm.callsTo("Install")).To(HaveLen(1))
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+	})
+
+	It("installs when it isn't allowed to read the namespace", func() {
+		setup(corev1.NamespaceTerminating)
+		// Like a namespace-scoped operator bound with RoleBindings only
+		reconciler.Client = interceptor.NewClient(reconciler.Client.(client.WithWatch), interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if _, ok := obj.(*corev1.Namespace); ok {
+					return apierrors.NewForbidden(corev1.Resource("namespaces"), key.Name, errors.New("cluster-scoped access denied"))
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+		})
+
+		_, ad := reconcile()
+		Expect(fakeHelm.callsTo("Install")).To(HaveLen(1))
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+	})
+
+	It("waits instead of installing into a terminating namespace", func() {
+		setup(corev1.NamespaceTerminating)
+
+		result, ad := reconcile()
+		Expect(fakeHelm.Calls).To(BeEmpty())
+		Expect(result.RequeueAfter).To(Equal(requeueAfterFailure))
+
+		ready := meta.FindStatusCondition(ad.Status.Conditions, ConditionTypeReady)
+		Expect(ready).NotTo(BeNil())
+		Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+		Expect(ready.Reason).To(Equal(ReasonNamespaceTerminating))
+		Expect(ready.Message).To(ContainSubstring("Namespace team-a is terminating"))
+	})
+})
@@ -0,0 +1,112 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// ParseWatchNamespaces parses a comma-separated list of namespaces to watch.
+// An empty value means all namespaces (cluster-wide mode).
+func ParseWatchNamespaces(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	var namespaces []string
+	for _, ns := range strings.Split(value, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns == "" {
+			return nil, fmt.Errorf("empty namespace in watch list %q", value)
+		}
+		if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid namespace %q: %s", ns, strings.Join(errs, ", "))
+		}
+		if seen[ns] {
+			return nil, fmt.Errorf("duplicate namespace %q in watch list", ns)
+		}
+		seen[ns] = true
+		namespaces = append(namespaces, ns)
+	}
+
+	return namespaces, nil
+}
+
+// CacheOptions returns manager cache options restricted to the given namespaces.
+// No namespaces leaves the cache cluster-wide.
+func CacheOptions(namespaces []string) cache.Options {
+	opts := cache.Options{}
+	if len(namespaces) == 0 {
+		return opts
+	}
+
+	opts.DefaultNamespaces = make(map[string]cache.Config, len(namespaces))
+	for _, ns := range namespaces {
+		opts.DefaultNamespaces[ns] = cache.Config{}
+	}
+	return opts
+}
+
+// ClientOptions returns manager client options. Namespaces are read uncached: they are
+// cluster-scoped, and a namespace-scoped operator may not be allowed to watch them.
+func ClientOptions() client.Options {
+	return client.Options{
+		Cache: &client.CacheOptions{DisableFor: []client.Object{&corev1.Namespace{}}},
+	}
+}
+
+// newObjectCache returns a cache, run by mgr, holding only the object of obj's kind at key.
+// The manager's cache is limited to the watched namespaces, which needn't include key's.
+func newObjectCache(mgr ctrl.Manager, obj client.Object, key types.NamespacedName) (cache.Cache, error) {
+	objectCache, err := cache.New(mgr.GetConfig(), cache.Options{
+		Scheme:            mgr.GetScheme(),
+		Mapper:            mgr.GetRESTMapper(),
+		DefaultNamespaces: map[string]cache.Config{key.Namespace: {}},
+		ByObject: map[client.Object]cache.ByObject{
+			obj: {Field: fields.OneTermEqualSelector("metadata.name", key.Name)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := mgr.Add(objectCache); err != nil {
+		return nil, err
+	}
+	return objectCache, nil
+}
+
+// namespacePredicate filters out objects outside the watched namespaces
+func namespacePredicate(namespaces []string) predicate.Predicate {
+	allowed := make(map[string]bool, len(namespaces))
+	for _, ns := range namespaces {
+		allowed[ns] = true
+	}
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return allowed[obj.GetNamespace()]
+	})
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+var _ = Describe("Watch namespaces", func() {
+	It("parses the flag", func() {
+		namespaces, err := ParseWatchNamespaces("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(namespaces).To(BeNil())
+
+		namespaces, err = ParseWatchNamespaces(" team-a, team-b ")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(namespaces).To(Equal([]string{"team-a", "team-b"}))
+
+		for _, value := range []string{"team-a,", "team-a,team-a", "Team_A"} {
+			_, err := ParseWatchNamespaces(value)
+			Expect(err).To(HaveOccurred(), value)
+		}
+	})
+
+	It("leaves the cache cluster-wide without namespaces", func() {
+		Expect(CacheOptions(nil).DefaultNamespaces).To(BeNil())
+	})
+
+	It("limits the cache to the watched namespaces", func() {
+		opts := CacheOptions([]string{"team-a", "team-b"})
+		Expect(opts.DefaultNamespaces).To(Equal(map[string]cache.Config{
+			"team-a": {},
+			"team-b": {},
+		}))
+	})
+
+	It("reads namespaces uncached", func() {
+		Expect(ClientOptions().Cache.DisableFor).To(ConsistOf(&corev1.Namespace{}))
+	})
+
+	It("ignores events outside the watched namespaces", func() {
+		p := namespacePredicate([]string{"team-a"})
+		inNamespace := func(ns string) *appstorev1alpha1.AppDeployment {
+			return &appstorev1alpha1.AppDeployment{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: ns}}
+		}
+		Expect(p.Create(event.CreateEvent{Object: inNamespace("team-a")})).To(BeTrue())
+		Expect(p.Create(event.CreateEvent{Object: inNamespace("team-b")})).To(BeFalse())
+	})
+})
@@ -0,0 +1,277 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+const (
+	// NotifySignatureHeader holds the HMAC-SHA256 of a notification's body, as
+	// sha256=<hex>, if the deployment has a spec.notifySecretRef
+	NotifySignatureHeader = "X-Appstore-Signature"
+
+	// notifySecretKey is the key of the signing secret in the spec.notifySecretRef Secret
+	notifySecretKey = "secret"
+
+	// notifyTimeout bounds each attempt to deliver a notification
+	notifyTimeout = 10 * time.Second
+	// notifyAttempts is how often a notification is tried before it is dropped
+	notifyAttempts = 5
+
+	// notifyWorkers is how many notifications are delivered concurrently
+	notifyWorkers = 4
+	// notifyQueueSize bounds the notifications waiting for delivery; further ones are
+	// dropped
+	notifyQueueSize = 100
+
+	// notifyUndelivered is the NotifyFailed event message of notifications that the
+	// receiver didn't accept. The details are only logged, so that events can't be used to
+	// probe the receiver.
+	notifyUndelivered = "The status notification could not be delivered, see the operator logs"
+)
+
+// notifyRetryDelay is the delay before the first retry of a notification, doubling with
+// each further retry
+var notifyRetryDelay = 2 * time.Second
+
+// StatusNotification is the payload POSTed to spec.notifyURL
+type StatusNotification struct {
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace"`
+	AppName      string `json:"appName"`
+	TeamID       string `json:"teamId"`
+	Generation   int64  `json:"generation"`
+	Phase        string `json:"phase"`
+	Reason       string `json:"reason,omitempty"`
+	Message      string `json:"message,omitempty"`
+	ReleaseName  string `json:"releaseName,omitempty"`
+	Revision     int    `json:"revision,omitempty"`
+	ChartVersion string `json:"chartVersion,omitempty"`
+	Time         string `json:"time"`
+}
+
+// pendingNotification is a status notification waiting for delivery
+type pendingNotification struct {
+	ctx           context.Context
+	appDeployment *appstorev1alpha1.AppDeployment
+	body          []byte
+	secret        []byte
+}
+
+// notifier delivers notifications from a bounded queue with notifyWorkers workers, which
+// are started with the first notification
+type notifier struct {
+	once  sync.Once
+	queue chan pendingNotification
+}
+
+// notifyStatus sends the deployment's status to its spec.notifyURL in the background if
+// the reconcile, which started in previousPhase for previousGeneration, ended it in a
+// terminal phase. Failures to deliver are logged and emit a NotifyFailed event, but never
+// fail the reconcile.
+func (r *AppDeploymentReconciler) notifyStatus(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, previousPhase appstorev1alpha1.AppDeploymentPhase, previousGeneration int64) {
+	if appDeployment.Spec.NotifyURL == "" {
+		return
+	}
+	status := appDeployment.Status
+	if status.Phase != appstorev1alpha1.PhaseDeployed && status.Phase != appstorev1alpha1.PhaseFailed {
+		return
+	}
+	// Retries ending in the same phase for the same spec were already notified
+	if status.Phase == previousPhase && status.ObservedGeneration == previousGeneration {
+		return
+	}
+
+	notification := StatusNotification{
+		Name:         appDeployment.Name,
+		Namespace:    appDeployment.Namespace,
+		AppName:      appDeployment.Spec.AppName,
+		TeamID:       appDeployment.Spec.TeamID,
+		Generation:   status.ObservedGeneration,
+		Phase:        string(status.Phase),
+		Message:      status.Message,
+		ReleaseName:  status.HelmReleaseName,
+		Revision:     status.HelmReleaseRevision,
+		ChartVersion: status.DeployedChartVersion,
+		Time:         r.now().UTC().Format(time.RFC3339),
+	}
+	if ready := meta.FindStatusCondition(status.Conditions, ConditionTypeReady); ready != nil {
+		notification.Reason = ready.Reason
+	}
+	if len(r.NotifyHosts) == 0 {
+		r.notifyFailed(ctx, appDeployment, errors.New("status notifications are not enabled, see --notify-hosts"), "")
+		return
+	}
+	if _, err := checkOutboundURL(appDeployment.Spec.NotifyURL, r.NotifyHosts); err != nil {
+		r.notifyFailed(ctx, appDeployment, err, "")
+		return
+	}
+	body, err := json.Marshal(notification)
+	if err != nil {
+		r.notifyFailed(ctx, appDeployment, err, "")
+		return
+	}
+
+	var secret []byte
+	if name := appDeployment.Spec.NotifySecretRef; name != "" {
+		if secret, err = r.notifySecret(ctx, appDeployment.Namespace, name); err != nil {
+			r.notifyFailed(ctx, appDeployment, err, "")
+			return
+		}
+	}
+
+	// The reconcile doesn't wait for the receiver, nor is the delivery canceled with it
+	r.notifications.once.Do(func() {
+		r.notifications.queue = make(chan pendingNotification, notifyQueueSize)
+		for range notifyWorkers {
+			go r.deliverNotifications()
+		}
+	})
+	select {
+	case r.notifications.queue <- pendingNotification{
+		ctx:           context.WithoutCancel(ctx),
+		appDeployment: appDeployment.DeepCopy(),
+		body:          body,
+		secret:        secret,
+	}:
+	default:
+		r.notifyFailed(ctx, appDeployment, errors.New("too many status notifications waiting for delivery"), "")
+	}
+}
+
+// deliverNotifications sends the queued notifications until the queue is closed
+func (r *AppDeploymentReconciler) deliverNotifications() {
+	for n := range r.notifications.queue {
+		if err := r.sendNotification(n.ctx, n.appDeployment.Spec.NotifyURL, n.body, n.secret); err != nil {
+			r.notifyFailed(n.ctx, n.appDeployment, err, notifyUndelivered)
+		}
+	}
+}
+
+// notifySecret returns the signing secret of a spec.notifySecretRef
+func (r *AppDeploymentReconciler) notifySecret(ctx context.Context, namespace, name string) ([]byte, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get notify Secret %s: %w", name, err)
+	}
+	key, ok := secret.Data[notifySecretKey]
+	if !ok || len(key) == 0 {
+		return nil, fmt.Errorf("notify Secret %s has no %q key", name, notifySecretKey)
+	}
+	return key, nil
+}
+
+// sendNotification POSTs a notification, retrying with backoff until the receiver accepts
+// it with a 2xx status, rejects it with a 4xx status other than 429, or the attempts run out
+func (r *AppDeploymentReconciler) sendNotification(ctx context.Context, url string, body, secret []byte) error {
+	delay := notifyRetryDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		var retry bool
+		if retry, err = r.postNotification(ctx, url, body, secret); err == nil || !retry || attempt == notifyAttempts {
+			return err
+		}
+		log.FromContext(ctx).Info("Retrying status notification", "url", url, "attempt", attempt, "error", err.Error())
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// postNotification makes a single attempt to deliver a notification and reports whether
+// a failed attempt should be retried
+func (r *AppDeploymentReconciler) postNotification(ctx context.Context, url string, body, secret []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("invalid notify URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != nil {
+		req.Header.Set(NotifySignatureHeader, SignNotification(body, secret))
+	}
+
+	resp, err := r.notifyClient().Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to send status notification: %w", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests:
+		return false, fmt.Errorf("status notification rejected: %s returned %s", url, resp.Status)
+	default:
+		return true, fmt.Errorf("status notification failed: %s returned %s", url, resp.Status)
+	}
+}
+
+// notifyClient returns the client used to send notifications
+func (r *AppDeploymentReconciler) notifyClient() *http.Client {
+	if r.HTTPClient != nil {
+		return r.HTTPClient
+	}
+	r.notifyClientOnce.Do(func() {
+		r.notifyHTTPClient = newOutboundClient(notifyTimeout, r.NotifyHosts)
+	})
+	return r.notifyHTTPClient
+}
+
+// notifyFailed logs a notification that couldn't be sent and emits a NotifyFailed event
+// with message, or the error if message is empty
+func (r *AppDeploymentReconciler) notifyFailed(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, err error, message string) {
+	log.FromContext(ctx).Error(err, "Failed to notify the deployment status", "url", appDeployment.Spec.NotifyURL)
+	if message == "" {
+		message = err.Error()
+	}
+	if r.Recorder != nil {
+		r.Recorder.Event(appDeployment, corev1.EventTypeWarning, "NotifyFailed", message)
+	}
+}
+
+// SignNotification returns the value of the NotifySignatureHeader of a notification body
+// signed with secret, for receivers to compare with the header using hmac.Equal
+func SignNotification(body, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+// notifyRequest is a notification received by the stub receiver
+type notifyRequest struct {
+	Body      []byte
+	Signature string
+}
+
+var _ = Describe("Status notifications", func() {
+	var (
+		ctx        context.Context
+		fakeHelm   *fakeHelmClient
+		recorder   *record.FakeRecorder
+		reconciler *AppDeploymentReconciler
+		ad         *appstorev1alpha1.AppDeployment
+		server     *httptest.Server
+		received   chan notifyRequest
+		statuses   chan int
+		retryDelay time.Duration
+	)
+
+	reconcile := func() {
+		_, _ = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ad)})
+	}
+
+	receive := func() (notifyRequest, StatusNotification) {
+		var req notifyRequest
+		Eventually(received).WithTimeout(5 * time.Second).Should(Receive(&req))
+		var notification StatusNotification
+		Expect(json.Unmarshal(req.Body, &notification)).To(Succeed())
+		return req, notification
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{}
+		recorder = record.NewFakeRecorder(10)
+		retryDelay, notifyRetryDelay = notifyRetryDelay, 10*time.Millisecond
+
+		received = make(chan notifyRequest, 10)
+		// Statuses the receiver responds with in order, then 204
+		statuses = make(chan int, 10)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, _ := io.ReadAll(req.Body)
+			received <- notifyRequest{Body: body, Signature: req.Header.Get(NotifySignatureHeader)}
+			select {
+			case status := <-statuses:
+				w.WriteHeader(status)
+			default:
+				w.WriteHeader(http.StatusNoContent)
+			}
+		}))
+
+		ad = &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "db", Namespace: "default", Generation: 1, Finalizers: []string{finalizerName},
+			},
+			Spec: appstorev1alpha1.AppDeploymentSpec{
+				AppName:         "postgresql",
+				TeamID:          "team-a",
+				NotifyURL:       server.URL,
+				NotifySecretRef: "notify",
+			},
+		}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "notify", Namespace: "default"},
+			Data:       map[string][]byte{"secret": []byte("s3cret")},
+		}
+		reconciler = &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(ad, secret).
+				WithStatusSubresource(ad).
+				Build(),
+			HelmClient:  fakeHelm,
+			Recorder:    recorder,
+			HTTPClient:  server.Client(),
+			NotifyHosts: []string{"127.0.0.1"},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		notifyRetryDelay = retryDelay
+	})
+
+	It("sends a signed notification when the deployment is deployed", func() {
+		reconcile()
+
+		req, notification := receive()
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(req.Body)
+		Expect(req.Signature).To(Equal("sha256=" + hex.EncodeToString(mac.Sum(nil))))
+
+		Expect(notification.Name).To(Equal("db"))
+		Expect(notification.Namespace).To(Equal("default"))
+		Expect(notification.AppName).To(Equal("postgresql"))
+		Expect(notification.TeamID).To(Equal("team-a"))
+		Expect(notification.Phase).To(Equal(string(appstorev1alpha1.PhaseDeployed)))
+		Expect(notification.Generation).To(Equal(int64(1)))
+		Expect(notification.Revision).To(Equal(1))
+	})
+
+	It("sends a signed notification when the deployment fails", func() {
+		fakeHelm.InstallErr = errors.New("timed out waiting for the condition")
+		reconcile()
+
+		req, notification := receive()
+		Expect(req.Signature).To(Equal(SignNotification(req.Body, []byte("s3cret"))))
+		Expect(notification.Phase).To(Equal(string(appstorev1alpha1.PhaseFailed)))
+		Expect(notification.Message).To(ContainSubstring("timed out waiting for the condition"))
+	})
+
+	It("notifies once per terminal phase of a spec", func() {
+		reconcile()
+		receive()
+
+		// Periodic reconciles of the deployed release are not notified
+		reconcile()
+		Consistently(received).WithTimeout(200 * time.Millisecond).ShouldNot(Receive())
+
+		// Nor are failed retries of the same spec
+		fakeHelm.InstallErr = errors.New("timed out waiting for the condition")
+		delete(fakeHelm.Releases, "db")
+		reconcile()
+		_, notification := receive()
+		Expect(notification.Phase).To(Equal(string(appstorev1alpha1.PhaseFailed)))
+		reconcile()
+		Consistently(received).WithTimeout(200 * time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("retries failed deliveries", func() {
+		statuses <- http.StatusServiceUnavailable
+		statuses <- http.StatusInternalServerError
+		reconcile()
+
+		first, _ := receive()
+		second, _ := receive()
+		third, notification := receive()
+		Expect(second.Body).To(Equal(first.Body))
+		Expect(third.Body).To(Equal(first.Body))
+		Expect(notification.Phase).To(Equal(string(appstorev1alpha1.PhaseDeployed)))
+		Consistently(received).WithTimeout(200 * time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("doesn't retry rejected notifications", func() {
+		statuses <- http.StatusBadRequest
+		reconcile()
+
+		receive()
+		Consistently(received).WithTimeout(200 * time.Millisecond).ShouldNot(Receive())
+		// The receiver's response isn't echoed into the event
+		var event string
+		Eventually(recorder.Events).Should(Receive(&event))
+		Expect(event).To(ContainSubstring("NotifyFailed"))
+		Expect(event).NotTo(ContainSubstring("400"))
+	})
+
+	It("only notifies allowed hosts", func() {
+		reconciler.NotifyHosts = []string{"*.example.com"}
+		reconcile()
+
+		Consistently(received).WithTimeout(200 * time.Millisecond).ShouldNot(Receive())
+		Eventually(recorder.Events).Should(Receive(ContainSubstring(`host "127.0.0.1" is not allowed`)))
+	})
+
+	It("sends unsigned notifications without a notify Secret", func() {
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		ad.Spec.NotifySecretRef = ""
+		Expect(reconciler.Update(ctx, ad)).To(Succeed())
+		reconcile()
+
+		req, _ := receive()
+		Expect(req.Signature).To(BeEmpty())
+	})
+})
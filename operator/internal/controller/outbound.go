@@ -0,0 +1,129 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which some clusters use
+// for pods and services
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// ParseHostPatterns splits a comma-separated list of host name globs as in path.Match,
+// e.g. "*.example.com", and validates them
+func ParseHostPatterns(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			return nil, fmt.Errorf("empty host pattern in %q", value)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid host pattern %q: %w", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// checkOutboundURL returns an error unless rawURL is an http(s) URL whose host matches
+// one of the patterns
+func checkOutboundURL(rawURL string, patterns []string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("url %s must use http or https", rawURL)
+	}
+	if err := checkOutboundHost(u.Hostname(), patterns); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// checkOutboundHost returns an error unless host matches one of the patterns
+func checkOutboundHost(host string, patterns []string) error {
+	host = strings.ToLower(host)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, host); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %q is not allowed", host)
+}
+
+// blockedAddress reports whether addr is loopback, link-local (including cloud metadata
+// endpoints), private or otherwise not a public address, and so likely to belong to the
+// cluster or its network
+func blockedAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsGlobalUnicast() || addr.IsPrivate() || sharedAddressSpace.Contains(addr)
+}
+
+// newOutboundClient returns an HTTP client for requests to URLs of AppDeployments. It only
+// connects to public addresses, checked after DNS resolution so that a host can't resolve
+// to an internal address, and only follows redirects to hosts matching the patterns.
+// Proxies aren't used, since they would connect on the client's behalf.
+func newOutboundClient(timeout time.Duration, patterns []string) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if blockedAddress(addr) {
+				return fmt.Errorf("connecting to %s is not allowed", addr)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			_, err := checkOutboundURL(req.URL.String(), patterns)
+			return err
+		},
+	}
+}
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"net/netip"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Outbound requests", func() {
+	It("parses host patterns", func() {
+		patterns, err := ParseHostPatterns(" Values.example.com,*.git.example.com ")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patterns).To(Equal([]string{"values.example.com", "*.git.example.com"}))
+
+		Expect(ParseHostPatterns("")).To(BeEmpty())
+		_, err = ParseHostPatterns("a.example.com,,b.example.com")
+		Expect(err).To(HaveOccurred())
+		_, err = ParseHostPatterns("[a")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("only allows http(s) URLs of matching hosts", func() {
+		patterns := []string{"*.example.com"}
+		_, err := checkOutboundURL("https://values.EXAMPLE.com:8443/values.yaml", patterns)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, url := range []string{
+			"https://example.com/values.yaml",
+			"https://values.example.com.evil.io/values.yaml",
+			"http://169.254.169.254/latest/meta-data",
+			"file:///etc/passwd",
+		} {
+			_, err := checkOutboundURL(url, patterns)
+			Expect(err).To(HaveOccurred(), url)
+		}
+	})
+
+	It("blocks internal addresses", func() {
+		for _, addr := range []string{
+			"127.0.0.1", "::1", "169.254.169.254", "fe80::1", "10.96.0.1", "172.16.0.1",
+			"192.168.1.1", "100.64.0.10", "fd00::1", "0.0.0.0", "::ffff:127.0.0.1", "224.0.0.1",
+		} {
+			Expect(blockedAddress(netip.MustParseAddr(addr))).To(BeTrue(), addr)
+		}
+		for _, addr := range []string{"140.82.112.3", "2606:4700::1111"} {
+			Expect(blockedAddress(netip.MustParseAddr(addr))).To(BeFalse(), addr)
+		}
+	})
+})
@@ -0,0 +1,140 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+const (
+	// releaseOwnerLabel is the Helm release label holding the UID of the AppDeployment that
+	// owns the release
+	releaseOwnerLabel = "appstore.bitpipe.no/owner-uid"
+	// releaseOwnerNameLabel and releaseOwnerNamespaceLabel identify the owning AppDeployment
+	releaseOwnerNameLabel      = "appstore.bitpipe.no/owner-name"
+	releaseOwnerNamespaceLabel = "appstore.bitpipe.no/owner-namespace"
+	// releaseTeamLabel is the team of the owning AppDeployment
+	releaseTeamLabel = "appstore.bitpipe.no/team"
+)
+
+// releaseOwnerLabels returns the release labels marking the AppDeployment as its owner.
+// Helm stores them as labels of the release's storage Secret, so values that aren't valid
+// label values, such as names longer than 63 characters, are left out.
+func releaseOwnerLabels(appDeployment *appstorev1alpha1.AppDeployment) map[string]string {
+	if appDeployment.UID == "" {
+		return nil
+	}
+	labels := map[string]string{releaseOwnerLabel: string(appDeployment.UID)}
+	for key, value := range map[string]string{
+		releaseOwnerNameLabel:      appDeployment.Name,
+		releaseOwnerNamespaceLabel: appDeployment.Namespace,
+		releaseTeamLabel:           appDeployment.Spec.TeamID,
+	} {
+		if value != "" && len(validation.IsValidLabelValue(value)) == 0 {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// releaseOwnerKey returns the AppDeployment a release is labeled as owned by. The owner's
+// namespace defaults to the release's.
+func releaseOwnerKey(release *helm.ReleaseInfo) (types.NamespacedName, bool) {
+	name := release.Labels[releaseOwnerNameLabel]
+	if name == "" {
+		return types.NamespacedName{}, false
+	}
+	namespace := release.Labels[releaseOwnerNamespaceLabel]
+	if namespace == "" {
+		namespace = release.Namespace
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, true
+}
+
+// releaseOwnerDeployment returns the AppDeployment owning a release according to its
+// labels, or nil if the release isn't labeled with its owner or the owner no longer
+// exists. An AppDeployment recreated with the owner's name doesn't own the release.
+func (r *AppDeploymentReconciler) releaseOwnerDeployment(ctx context.Context, release *helm.ReleaseInfo) (*appstorev1alpha1.AppDeployment, error) {
+	key, ok := releaseOwnerKey(release)
+	if !ok {
+		return nil, nil
+	}
+	owner := &appstorev1alpha1.AppDeployment{}
+	if err := r.Get(ctx, key, owner); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get AppDeployment %s: %w", key, err)
+	}
+	if string(owner.UID) != release.Labels[releaseOwnerLabel] {
+		return nil, nil
+	}
+	return owner, nil
+}
+
+// releaseOwner returns the name of another AppDeployment in the namespace that owns the
+// release, or "" if the release may be managed by this one. Releases labeled with the
+// UID of an AppDeployment that no longer exists are adopted. Releases without the label,
+// installed before releases were labeled, belong to the AppDeployment that deployed them
+// according to its status.
+func (r *AppDeploymentReconciler) releaseOwner(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, release *helm.ReleaseInfo) (string, error) {
+	ownerUID, labeled := release.Labels[releaseOwnerLabel]
+	if labeled && ownerUID == string(appDeployment.UID) {
+		return "", nil
+	}
+
+	// Releases labeled with their owner's name are looked up directly
+	owner, err := r.releaseOwnerDeployment(ctx, release)
+	if err != nil {
+		return "", err
+	}
+	if owner != nil {
+		return owner.Name, nil
+	}
+
+	deployments := &appstorev1alpha1.AppDeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(appDeployment.Namespace)); err != nil {
+		return "", fmt.Errorf("failed to list AppDeployments: %w", err)
+	}
+	for _, other := range deployments.Items {
+		if other.UID == appDeployment.UID {
+			continue
+		}
+		if labeled && string(other.UID) == ownerUID {
+			return other.Name, nil
+		}
+		if !labeled && other.Status.HelmReleaseName == release.Name {
+			return other.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// releaseConflictMessage explains that the release name is taken by another AppDeployment
+func releaseConflictMessage(releaseName, namespace, owner string) string {
+	return fmt.Sprintf("Helm release %s in namespace %s belongs to AppDeployment %s; set a different spec.releaseName",
+		releaseName, namespace, owner)
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+var _ = Describe("Release ownership", func() {
+	var (
+		ctx        context.Context
+		fakeHelm   *fakeHelmClient
+		reconciler *AppDeploymentReconciler
+		first      *appstorev1alpha1.AppDeployment
+		second     *appstorev1alpha1.AppDeployment
+	)
+
+	// newDeployment returns an AppDeployment using the release name db
+	newDeployment := func(name string, uid types.UID) *appstorev1alpha1.AppDeployment {
+		return &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: uid},
+			Spec: appstorev1alpha1.AppDeploymentSpec{
+				AppName:     "postgresql",
+				TeamID:      "team-a",
+				ReleaseName: "db",
+			},
+		}
+	}
+
+	reconcileHelm := func(ad *appstorev1alpha1.AppDeployment) {
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		_, err := reconciler.reconcileHelm(ctx, ad)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	expectConflict := func(ad *appstorev1alpha1.AppDeployment, owner string) {
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseFailed))
+		cond := meta.FindStatusCondition(ad.Status.Conditions, ConditionTypeReady)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Reason).To(Equal("ReleaseNameConflict"))
+		Expect(cond.Message).To(ContainSubstring("belongs to AppDeployment " + owner))
+		Expect(cond.Message).To(ContainSubstring("spec.releaseName"))
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{}
+		first = newDeployment("db-first", "uid-first")
+		second = newDeployment("db-second", "uid-second")
+		reconciler = &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(first, second).
+				WithStatusSubresource(first, second).
+				Build(),
+			HelmClient: fakeHelm,
+		}
+	})
+
+	It("labels the release with its owner", func() {
+		reconcileHelm(first)
+
+		owner := map[string]string{
+			releaseOwnerLabel:          "uid-first",
+			releaseOwnerNameLabel:      "db-first",
+			releaseOwnerNamespaceLabel: "default",
+			releaseTeamLabel:           "team-a",
+		}
+		Expect(fakeHelm.callsTo("Install")[0].Options.ReleaseLabels).To(Equal(owner))
+		Expect(fakeHelm.Releases["db"].Labels).To(Equal(owner))
+
+		key, ok := releaseOwnerKey(fakeHelm.Releases["db"])
+		Expect(ok).To(BeTrue())
+		Expect(key).To(Equal(client.ObjectKeyFromObject(first)))
+		found, err := reconciler.releaseOwnerDeployment(ctx, fakeHelm.Releases["db"])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).NotTo(BeNil())
+		Expect(found.UID).To(Equal(types.UID("uid-first")))
+
+		// The owner keeps upgrading it
+		first.Spec.ChartVersion = "15.3.0"
+		Expect(reconciler.Update(ctx, first)).To(Succeed())
+		reconcileHelm(first)
+		Expect(fakeHelm.callsTo("Upgrade")).To(HaveLen(1))
+		Expect(first.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+	})
+
+	It("leaves out owner labels that aren't valid label values", func() {
+		ad := newDeployment(strings.Repeat("a", 64), "uid-long")
+		Expect(releaseOwnerLabels(ad)).To(Equal(map[string]string{
+			releaseOwnerLabel:          "uid-long",
+			releaseOwnerNamespaceLabel: "default",
+			releaseTeamLabel:           "team-a",
+		}))
+		Expect(releaseOwnerLabels(newDeployment("db", ""))).To(BeNil())
+	})
+
+	It("doesn't look up owners of releases without owner names or recreated under the name", func() {
+		release := &helm.ReleaseInfo{Name: "db", Namespace: "default", Labels: map[string]string{releaseOwnerLabel: "uid-first"}}
+		_, ok := releaseOwnerKey(release)
+		Expect(ok).To(BeFalse())
+		Expect(reconciler.releaseOwnerDeployment(ctx, release)).To(BeNil())
+
+		release.Labels[releaseOwnerNameLabel] = "db-first"
+		Expect(reconciler.releaseOwnerDeployment(ctx, release)).NotTo(BeNil())
+		release.Labels[releaseOwnerLabel] = "uid-deleted"
+		Expect(reconciler.releaseOwnerDeployment(ctx, release)).To(BeNil())
+		release.Labels[releaseOwnerNameLabel] = "db-deleted"
+		Expect(reconciler.releaseOwnerDeployment(ctx, release)).To(BeNil())
+	})
+
+	It("rejects a second AppDeployment with the same release name", func() {
+		reconcileHelm(first)
+		reconcileHelm(second)
+
+		Expect(fakeHelm.callsTo("Install")).To(HaveLen(1))
+		Expect(fakeHelm.callsTo("Upgrade")).To(BeEmpty())
+		Expect(fakeHelm.Releases["db"].Labels).To(HaveKeyWithValue(releaseOwnerLabel, "uid-first"))
+		expectConflict(second, "db-first")
+
+		// The first one is unaffected
+		reconcileHelm(first)
+		Expect(first.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+	})
+
+	It("rejects a second AppDeployment for a release installed before releases were labeled", func() {
+		fakeHelm.setRelease(&helm.ReleaseInfo{Name: "db", Namespace: "default", Revision: 1, Status: releaseStatusDeployed, ChartName: "postgresql"})
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(first), first)).To(Succeed())
+		first.Status.HelmReleaseName = "db"
+		Expect(reconciler.Status().Update(ctx, first)).To(Succeed())
+
+		reconcileHelm(second)
+
+		Expect(fakeHelm.callsTo("Upgrade")).To(BeEmpty())
+		expectConflict(second, "db-first")
+
+		// The first one labels the release on its next upgrade
+		reconcileHelm(first)
+		Expect(fakeHelm.callsTo("Upgrade")).To(HaveLen(1))
+		Expect(fakeHelm.Releases["db"].Labels).To(HaveKeyWithValue(releaseOwnerLabel, "uid-first"))
+	})
+
+	It("adopts a release whose owner no longer exists", func() {
+		fakeHelm.setRelease(&helm.ReleaseInfo{
+			Name: "db", Namespace: "default", Revision: 1, Status: releaseStatusDeployed, ChartName: "postgresql",
+			Labels: map[string]string{releaseOwnerLabel: "uid-deleted"},
+		})
+
+		reconcileHelm(second)
+
+		Expect(fakeHelm.callsTo("Upgrade")).To(HaveLen(1))
+		Expect(fakeHelm.Releases["db"].Labels).To(HaveKeyWithValue(releaseOwnerLabel, "uid-second"))
+		Expect(second.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+	})
+
+	It("doesn't uninstall the release of another AppDeployment when deleted", func() {
+		reconcileHelm(first)
+
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(second), second)).To(Succeed())
+		second.Finalizers = []string{finalizerName}
+		Expect(reconciler.Update(ctx, second)).To(Succeed())
+		Expect(reconciler.Delete(ctx, second)).To(Succeed())
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(second)})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(fakeHelm.callsTo("Uninstall")).To(BeEmpty())
+		Expect(fakeHelm.Releases["db"]).NotTo(BeNil())
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, client.ObjectKeyFromObject(second), second))).To(BeTrue())
+	})
+})
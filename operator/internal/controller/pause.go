@@ -0,0 +1,147 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+const (
+	// ConditionTypePaused is True while reconciliation of all AppDeployments is paused
+	ConditionTypePaused = "Paused"
+	// ReasonPaused is the reason of the Paused condition
+	ReasonPaused = "Paused"
+
+	// pauseConfigMapKey is the key of the pause ConfigMap that pauses reconciliation when "true"
+	pauseConfigMapKey = "paused"
+)
+
+// ParsePauseConfigMap parses the namespace/name of the ConfigMap pausing reconciliation
+func ParsePauseConfigMap(value string) (*types.NamespacedName, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	namespace, name, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok || namespace == "" || name == "" {
+		return nil, fmt.Errorf("invalid pause ConfigMap %q, expected namespace/name", value)
+	}
+	return &types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+// paused reports whether reconciliation of all AppDeployments is paused, by Paused or by
+// the "paused" key of the PauseConfigMap. A missing ConfigMap doesn't pause.
+func (r *AppDeploymentReconciler) paused(ctx context.Context) (bool, error) {
+	if r.Paused {
+		return true, nil
+	}
+	if r.PauseConfigMap == nil {
+		return false, nil
+	}
+
+	// Without SetupWithManager, e.g. in tests, the ConfigMap is read with the client
+	var reader client.Reader = r.Client
+	if r.pauseCache != nil {
+		reader = r.pauseCache
+	}
+	cm := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, *r.PauseConfigMap, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get pause ConfigMap %s: %w", r.PauseConfigMap, err)
+	}
+	paused, _ := strconv.ParseBool(strings.TrimSpace(cm.Data[pauseConfigMapKey]))
+	return paused, nil
+}
+
+// updateStatusPaused reports that reconciliation is paused and checks again later. Nothing
+// else is done, not even for deletions.
+func (r *AppDeploymentReconciler) updateStatusPaused(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment) (ctrl.Result, error) {
+	changed := meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypePaused,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonPaused,
+		Message:            "Reconciliation of all AppDeployments is paused by the operator",
+		ObservedGeneration: appDeployment.Generation,
+		LastTransitionTime: metav1.Now(),
+	})
+	if changed {
+		if err := r.Status().Update(ctx, appDeployment); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfterFailure}, nil
+}
+
+// clearStatusPaused removes the Paused condition once reconciliation is resumed
+func (r *AppDeploymentReconciler) clearStatusPaused(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment) error {
+	if !meta.RemoveStatusCondition(&appDeployment.Status.Conditions, ConditionTypePaused) {
+		return nil
+	}
+	return r.Status().Update(ctx, appDeployment)
+}
+
+// pauseSource watches the pause ConfigMap and enqueues all AppDeployments when it changes.
+// The ConfigMap is watched by a cache of its own, since the manager's cache is limited to
+// the watched namespaces, which needn't include the ConfigMap's.
+func (r *AppDeploymentReconciler) pauseSource(mgr ctrl.Manager) (source.Source, error) {
+	pauseCache, err := newObjectCache(mgr, &corev1.ConfigMap{}, *r.PauseConfigMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the pause ConfigMap cache: %w", err)
+	}
+	r.pauseCache = pauseCache
+
+	return source.Kind[client.Object](pauseCache, &corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.pauseRequests)), nil
+}
+
+// pauseRequests returns reconcile requests for all AppDeployments in the watched
+// namespaces, so that they report a pause and resume as soon as the pause ConfigMap changes
+func (r *AppDeploymentReconciler) pauseRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	if r.PauseConfigMap == nil || obj.GetNamespace() != r.PauseConfigMap.Namespace || obj.GetName() != r.PauseConfigMap.Name {
+		return nil
+	}
+
+	var list appstorev1alpha1.AppDeploymentList
+	if err := r.List(ctx, &list); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list AppDeployments for the pause ConfigMap")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, ad := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: ad.Namespace, Name: ad.Name}})
+	}
+	return requests
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+var _ = Describe("Paused reconciliation", func() {
+	var (
+		ctx        context.Context
+		fakeHelm   *fakeHelmClient
+		reconciler *AppDeploymentReconciler
+		ad         *appstorev1alpha1.AppDeployment
+		pause      *corev1.ConfigMap
+	)
+
+	reconcile := func() ctrl.Result {
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ad)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		return result
+	}
+
+	setPaused := func(value string) {
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(pause), pause)).To(Succeed())
+		pause.Data = map[string]string{pauseConfigMapKey: value}
+		Expect(reconciler.Update(ctx, pause)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{}
+		ad = &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Finalizers: []string{finalizerName}},
+			Spec:       appstorev1alpha1.AppDeploymentSpec{AppName: "postgresql", TeamID: "team-a"},
+		}
+		pause = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "appstore-pause", Namespace: "appstore-system"},
+			Data:       map[string]string{pauseConfigMapKey: "true"},
+		}
+		reconciler = &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(ad, pause).
+				WithStatusSubresource(&appstorev1alpha1.AppDeployment{}).
+				Build(),
+			HelmClient:     fakeHelm,
+			PauseConfigMap: &types.NamespacedName{Namespace: "appstore-system", Name: "appstore-pause"},
+		}
+	})
+
+	It("does nothing while the pause ConfigMap is set and resumes when it is cleared", func() {
+		Expect(reconcile().RequeueAfter).To(Equal(requeueAfterFailure))
+		Expect(fakeHelm.Calls).To(BeEmpty())
+		cond := meta.FindStatusCondition(ad.Status.Conditions, ConditionTypePaused)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+		Expect(cond.Reason).To(Equal(ReasonPaused))
+
+		// Reconciling again changes nothing
+		resourceVersion := ad.ResourceVersion
+		reconcile()
+		Expect(fakeHelm.Calls).To(BeEmpty())
+		Expect(ad.ResourceVersion).To(Equal(resourceVersion))
+
+		setPaused("false")
+		reconcile()
+		Expect(fakeHelm.callsTo("Install")).To(HaveLen(1))
+		Expect(meta.FindStatusCondition(ad.Status.Conditions, ConditionTypePaused)).To(BeNil())
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+
+		setPaused("true")
+		ad.Spec.ChartVersion = "15.3.0"
+		Expect(reconciler.Update(ctx, ad)).To(Succeed())
+		reconcile()
+		Expect(fakeHelm.callsTo("Upgrade")).To(BeEmpty())
+		Expect(meta.IsStatusConditionTrue(ad.Status.Conditions, ConditionTypePaused)).To(BeTrue())
+	})
+
+	It("doesn't uninstall deleted deployments while paused", func() {
+		Expect(reconciler.Delete(ctx, ad)).To(Succeed())
+		reconcile()
+		Expect(fakeHelm.callsTo("Uninstall")).To(BeEmpty())
+		Expect(ad.Finalizers).To(ContainElement(finalizerName))
+	})
+
+	It("is paused by the operator flag regardless of the ConfigMap", func() {
+		setPaused("false")
+		reconciler.Paused = true
+		reconcile()
+		Expect(fakeHelm.Calls).To(BeEmpty())
+		Expect(meta.IsStatusConditionTrue(ad.Status.Conditions, ConditionTypePaused)).To(BeTrue())
+	})
+
+	It("isn't paused without the ConfigMap or by other values", func() {
+		for _, value := range []string{"", "no", "maybe"} {
+			setPaused(value)
+			Expect(reconciler.paused(ctx)).To(BeFalse(), "paused: %q", value)
+		}
+		Expect(reconciler.Delete(ctx, pause)).To(Succeed())
+		Expect(reconciler.paused(ctx)).To(BeFalse())
+	})
+
+	It("enqueues all deployments when the pause ConfigMap changes", func() {
+		Expect(reconciler.pauseRequests(ctx, pause)).To(HaveLen(1))
+		other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "appstore-system"}}
+		Expect(reconciler.pauseRequests(ctx, other)).To(BeEmpty())
+	})
+
+	It("parses the pause ConfigMap reference", func() {
+		ref, err := ParsePauseConfigMap("appstore-system/appstore-pause")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*ref).To(Equal(types.NamespacedName{Namespace: "appstore-system", Name: "appstore-pause"}))
+		Expect(ParsePauseConfigMap("")).To(BeNil())
+		_, err = ParsePauseConfigMap("appstore-pause")
+		Expect(err).To(HaveOccurred())
+	})
+})
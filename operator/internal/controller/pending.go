@@ -0,0 +1,105 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+// ReasonOperationInProgress is the condition reason while another Helm operation holds the release
+const ReasonOperationInProgress = "OperationInProgress"
+
+// handleOperationInProgress handles an install or upgrade that failed because another Helm
+// operation holds the release. A release that has been pending for longer than the
+// PendingReleaseTimeout is marked failed and retried; otherwise the operation is waited for.
+func (r *AppDeploymentReconciler) handleOperationInProgress(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, releaseName string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithValues("release", releaseName)
+
+	current, err := r.HelmClient.GetRelease(ctx, releaseName, appDeployment.Namespace)
+	if err != nil {
+		return r.updateStatusFailed(ctx, appDeployment, fmt.Sprintf("Failed to check existing release: %v", err))
+	}
+	if current == nil || !current.Pending() {
+		logger.Info("Helm operation finished, retrying")
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	pendingFor := r.now().Sub(current.Updated)
+	if r.PendingReleaseTimeout > 0 && !current.Updated.IsZero() && pendingFor >= r.PendingReleaseTimeout {
+		logger.Info("Marking stuck Helm release failed", "status", current.Status, "pendingFor", pendingFor)
+		if err := r.HelmClient.ClearPendingRelease(ctx, releaseName, appDeployment.Namespace); err != nil {
+			return r.updateStatusFailedWithReason(ctx, appDeployment, ReasonOperationInProgress,
+				fmt.Sprintf("Failed to clear pending release: %v", err))
+		}
+		if r.Recorder != nil {
+			r.Recorder.Eventf(appDeployment, corev1.EventTypeWarning, "PendingReleaseCleared",
+				"Release %s was stuck in %s for %s and was marked failed", releaseName, current.Status, pendingFor.Round(time.Second))
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	logger.Info("Release is locked by another Helm operation", "status", current.Status, "pendingFor", pendingFor)
+	return r.updateStatusOperationInProgress(ctx, appDeployment, releaseName, current, pendingFor)
+}
+
+// updateStatusOperationInProgress records that the release is locked by another Helm
+// operation and requeues to retry
+func (r *AppDeploymentReconciler) updateStatusOperationInProgress(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, releaseName string, current *helm.ReleaseInfo, pendingFor time.Duration) (ctrl.Result, error) {
+	message := fmt.Sprintf("Release %s is locked by another Helm operation (%s", releaseName, current.Status)
+	if !current.Updated.IsZero() {
+		message += fmt.Sprintf(" for %s", pendingFor.Round(time.Second))
+	}
+	message += fmt.Sprintf("), retrying in %s. If no operation is running, run `helm rollback %s -n %s`", requeueAfterFailure, releaseName, appDeployment.Namespace)
+	if r.PendingReleaseTimeout > 0 {
+		message += fmt.Sprintf("; the release is marked failed after %s", r.PendingReleaseTimeout)
+	}
+
+	appDeployment.Status.Message = message
+	appDeployment.Status.LastReconcileTime = &metav1.Time{Time: time.Now()}
+
+	meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonOperationInProgress,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	})
+	meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeReconciling,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonOperationInProgress,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	})
+
+	if err := r.Status().Update(ctx, appDeployment); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfterFailure}, nil
+}
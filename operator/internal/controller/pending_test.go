@@ -0,0 +1,189 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+var _ = Describe("Pending Helm operations", func() {
+	var (
+		ctx        context.Context
+		fakeHelm   *fakeHelmClient
+		recorder   *record.FakeRecorder
+		reconciler *AppDeploymentReconciler
+		ad         *appstorev1alpha1.AppDeployment
+		now        time.Time
+	)
+
+	errPending := &helm.OperationInProgressError{
+		Release: "db",
+		Err:     errors.New("another operation (install/upgrade/rollback) is in progress"),
+	}
+
+	// pendingFor leaves the release pending an upgrade that started the given time ago
+	pendingFor := func(d time.Duration) {
+		fakeHelm.setRelease(&helm.ReleaseInfo{
+			Name: "db", Namespace: "default", Revision: 2, Status: "pending-upgrade",
+			ChartName: "postgresql", ChartVersion: "15.2.0", Updated: now.Add(-d),
+		})
+	}
+
+	reconcileHelm := func() ctrl.Result {
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		result, err := reconciler.reconcileHelm(ctx, ad)
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+		fakeHelm = &fakeHelmClient{UpgradeErrs: []error{errPending}}
+		recorder = record.NewFakeRecorder(10)
+		ad = &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec:       appstorev1alpha1.AppDeploymentSpec{AppName: "postgresql", TeamID: "team-a", ChartVersion: "15.3.0"},
+		}
+		reconciler = &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(ad).
+				WithStatusSubresource(ad).
+				Build(),
+			HelmClient: fakeHelm,
+			Clock:      clocktesting.NewFakePassiveClock(now),
+			Recorder:   recorder,
+		}
+	})
+
+	It("waits for the operation with an actionable condition", func() {
+		pendingFor(2 * time.Minute)
+
+		result := reconcileHelm()
+
+		Expect(result.RequeueAfter).To(Equal(requeueAfterFailure))
+		Expect(fakeHelm.callsTo("ClearPendingRelease")).To(BeEmpty())
+		Expect(ad.Status.Phase).NotTo(Equal(appstorev1alpha1.PhaseFailed))
+		Expect(ad.Status.FailureCount).To(BeZero())
+
+		cond := meta.FindStatusCondition(ad.Status.Conditions, ConditionTypeReady)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal(ReasonOperationInProgress))
+		Expect(cond.Message).To(ContainSubstring("pending-upgrade for 2m0s"))
+		Expect(cond.Message).To(ContainSubstring("helm rollback db -n default"))
+		Expect(meta.IsStatusConditionTrue(ad.Status.Conditions, ConditionTypeReconciling)).To(BeTrue())
+	})
+
+	It("waits for an operation pending for less than the timeout", func() {
+		reconciler.PendingReleaseTimeout = 10 * time.Minute
+		pendingFor(5 * time.Minute)
+
+		reconcileHelm()
+
+		Expect(fakeHelm.callsTo("ClearPendingRelease")).To(BeEmpty())
+		Expect(fakeHelm.Releases["db"].Status).To(Equal("pending-upgrade"))
+		Expect(ad.Status.Message).To(ContainSubstring("the release is marked failed after 10m0s"))
+	})
+
+	It("marks a release stuck for longer than the timeout failed and upgrades it", func() {
+		reconciler.PendingReleaseTimeout = 10 * time.Minute
+		pendingFor(15 * time.Minute)
+
+		result := reconcileHelm()
+
+		Expect(result.Requeue).To(BeTrue())
+		Expect(fakeHelm.callsTo("ClearPendingRelease")).To(HaveLen(1))
+		Expect(fakeHelm.Releases["db"].Status).To(Equal("failed"))
+		Expect(recorder.Events).To(Receive(ContainSubstring("PendingReleaseCleared")))
+
+		// The next reconcile upgrades the failed release
+		reconcileHelm()
+
+		Expect(fakeHelm.callsTo("Upgrade")).To(HaveLen(2))
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+		Expect(ad.Status.DeployedChartVersion).To(Equal("15.3.0"))
+	})
+
+	It("reports a failure to clear the release", func() {
+		reconciler.PendingReleaseTimeout = 10 * time.Minute
+		fakeHelm.ClearErr = errors.New("failed to update release: conflict")
+		pendingFor(15 * time.Minute)
+
+		reconcileHelm()
+
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseFailed))
+		cond := meta.FindStatusCondition(ad.Status.Conditions, ConditionTypeReady)
+		Expect(cond.Reason).To(Equal(ReasonOperationInProgress))
+		Expect(cond.Message).To(ContainSubstring("conflict"))
+	})
+
+	It("retries right away if the operation finished in the meantime", func() {
+		fakeHelm.setRelease(&helm.ReleaseInfo{
+			Name: "db", Namespace: "default", Revision: 2, Status: releaseStatusDeployed,
+			ChartName: "postgresql", ChartVersion: "15.2.0",
+		})
+
+		result := reconcileHelm()
+
+		Expect(result.Requeue).To(BeTrue())
+		Expect(fakeHelm.callsTo("ClearPendingRelease")).To(BeEmpty())
+	})
+
+	It("handles a failed install the same way", func() {
+		fakeHelm.InstallErr = errPending
+
+		// A concurrent install left the release pending after it was checked
+		reconciler.HelmClient = &installRaceHelmClient{fakeHelmClient: fakeHelm, now: now}
+		result := reconcileHelm()
+
+		Expect(result.RequeueAfter).To(Equal(requeueAfterFailure))
+		cond := meta.FindStatusCondition(ad.Status.Conditions, ConditionTypeReady)
+		Expect(cond.Reason).To(Equal(ReasonOperationInProgress))
+		Expect(cond.Message).To(ContainSubstring("pending-install"))
+	})
+})
+
+// installRaceHelmClient simulates another operator instance installing the release between
+// the release lookup and the install
+type installRaceHelmClient struct {
+	*fakeHelmClient
+	now time.Time
+}
+
+func (f *installRaceHelmClient) Install(ctx context.Context, releaseName, chartName, namespace string, values map[string]interface{}, version string, opts helm.ActionOptions) (*helm.ReleaseInfo, error) {
+	_, err := f.fakeHelmClient.Install(ctx, releaseName, chartName, namespace, values, version, opts)
+	f.setRelease(&helm.ReleaseInfo{Name: releaseName, Namespace: namespace, Revision: 1, Status: "pending-install", Updated: f.now})
+	return nil, err
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+// renderedObject holds the parts of a rendered manifest needed to compute resource usage
+type renderedObject struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Replicas       *int32                  `json:"replicas,omitempty"`
+		Template       *corev1.PodTemplateSpec `json:"template,omitempty"`
+		Containers     []corev1.Container      `json:"containers,omitempty"`
+		InitContainers []corev1.Container      `json:"initContainers,omitempty"`
+		// JobTemplate is the template of the jobs of a CronJob
+		JobTemplate *struct {
+			Spec struct {
+				Template *corev1.PodTemplateSpec `json:"template,omitempty"`
+			} `json:"spec"`
+		} `json:"jobTemplate,omitempty"`
+	} `json:"spec"`
+}
+
+// preflightCheck renders the chart and verifies the namespace quotas can accommodate it
+func (r *AppDeploymentReconciler) preflightCheck(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, releaseName string, values map[string]interface{}) error {
+	manifest, err := r.HelmClient.Render(
+		ctx,
+		releaseName,
+		appDeployment.Spec.AppName,
+		appDeployment.Namespace,
+		values,
+		appDeployment.Spec.ChartVersion,
+	)
+	if err != nil {
+		return err
+	}
+
+	usage, err := manifestResourceUsage(manifest)
+	if err != nil {
+		return err
+	}
+
+	quotas := &corev1.ResourceQuotaList{}
+	if err := r.clusterClient(ctx).List(ctx, quotas, client.InNamespace(appDeployment.Namespace)); err != nil {
+		return fmt.Errorf("failed to list resource quotas: %w", err)
+	}
+
+	return checkQuotas(usage, quotas.Items)
+}
+
+// manifestResourceUsage sums the pod count and container requests/limits of all
+// workloads in a rendered manifest, in ResourceQuota terms. A CronJob counts as one
+// run of its job.
+func manifestResourceUsage(manifest string) (corev1.ResourceList, error) {
+	usage := corev1.ResourceList{}
+	decoder := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096)
+
+	for {
+		var obj renderedObject
+		if err := decoder.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse rendered manifest: %w", err)
+		}
+
+		var podSpec corev1.PodSpec
+		switch {
+		case obj.Kind == "Pod":
+			podSpec.Containers = obj.Spec.Containers
+			podSpec.InitContainers = obj.Spec.InitContainers
+		case obj.Spec.Template != nil:
+			podSpec = obj.Spec.Template.Spec
+		case obj.Spec.JobTemplate != nil && obj.Spec.JobTemplate.Spec.Template != nil:
+			podSpec = obj.Spec.JobTemplate.Spec.Template.Spec
+		default:
+			continue
+		}
+
+		replicas := int64(1)
+		if obj.Spec.Replicas != nil {
+			replicas = int64(*obj.Spec.Replicas)
+		}
+
+		addUsage(usage, corev1.ResourcePods, *resource.NewQuantity(replicas, resource.DecimalSI))
+		for name, qty := range podUsage(&podSpec) {
+			for i := int64(0); i < replicas; i++ {
+				addUsage(usage, name, qty)
+			}
+		}
+	}
+
+	return usage, nil
+}
+
+// podUsage computes the effective requests/limits of a pod: the sum of its containers,
+// or the largest init container if that is higher
+func podUsage(spec *corev1.PodSpec) corev1.ResourceList {
+	usage := corev1.ResourceList{}
+	for _, c := range spec.Containers {
+		addContainerUsage(usage, c, addUsage)
+	}
+	for _, c := range spec.InitContainers {
+		addContainerUsage(usage, c, maxUsage)
+	}
+	return usage
+}
+
+func addContainerUsage(usage corev1.ResourceList, c corev1.Container, combine func(corev1.ResourceList, corev1.ResourceName, resource.Quantity)) {
+	if qty, ok := c.Resources.Requests[corev1.ResourceCPU]; ok {
+		combine(usage, corev1.ResourceRequestsCPU, qty)
+	}
+	if qty, ok := c.Resources.Requests[corev1.ResourceMemory]; ok {
+		combine(usage, corev1.ResourceRequestsMemory, qty)
+	}
+	if qty, ok := c.Resources.Limits[corev1.ResourceCPU]; ok {
+		combine(usage, corev1.ResourceLimitsCPU, qty)
+	}
+	if qty, ok := c.Resources.Limits[corev1.ResourceMemory]; ok {
+		combine(usage, corev1.ResourceLimitsMemory, qty)
+	}
+}
+
+func addUsage(usage corev1.ResourceList, name corev1.ResourceName, qty resource.Quantity) {
+	total := usage[name]
+	total.Add(qty)
+	usage[name] = total
+}
+
+func maxUsage(usage corev1.ResourceList, name corev1.ResourceName, qty resource.Quantity) {
+	if current, ok := usage[name]; !ok || qty.Cmp(current) > 0 {
+		usage[name] = qty.DeepCopy()
+	}
+}
+
+// checkQuotas compares the required usage against the remaining capacity of each quota
+func checkQuotas(usage corev1.ResourceList, quotas []corev1.ResourceQuota) error {
+	var shortfalls []string
+
+	for _, quota := range quotas {
+		hardLimits := quota.Status.Hard
+		if len(hardLimits) == 0 {
+			hardLimits = quota.Spec.Hard
+		}
+
+		for name, hard := range hardLimits {
+			needed, ok := usage[quotaUsageName(name)]
+			if !ok {
+				continue
+			}
+
+			available := hard.DeepCopy()
+			available.Sub(quota.Status.Used[name])
+			if needed.Cmp(available) > 0 {
+				shortfalls = append(shortfalls, fmt.Sprintf("%s: requires %s, only %s available in quota %s",
+					name, needed.String(), available.String(), quota.Name))
+			}
+		}
+	}
+
+	if len(shortfalls) > 0 {
+		sort.Strings(shortfalls)
+		return fmt.Errorf("insufficient resource quota: %s", strings.Join(shortfalls, "; "))
+	}
+
+	return nil
+}
+
+// quotaUsageName maps quota resource names to the usage key they constrain
+func quotaUsageName(name corev1.ResourceName) corev1.ResourceName {
+	switch name {
+	case corev1.ResourceCPU:
+		return corev1.ResourceRequestsCPU
+	case corev1.ResourceMemory:
+		return corev1.ResourceRequestsMemory
+	}
+	return name
+}
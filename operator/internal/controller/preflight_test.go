@@ -0,0 +1,216 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Preflight quota check", func() {
+	// expectUsage checks usage against quantities by resource name
+	expectUsage := func(usage corev1.ResourceList, want map[corev1.ResourceName]string) {
+		Expect(usage).To(HaveLen(len(want)))
+		for name, qty := range want {
+			Expect(usage).To(HaveKey(name))
+			got := usage[name]
+			Expect(got.Cmp(resource.MustParse(qty))).To(BeZero(), "%s is %s, want %s", name, got.String(), qty)
+		}
+	}
+
+	container := func(requestCPU, requestMemory, limitCPU, limitMemory string) corev1.Container {
+		resources := corev1.ResourceRequirements{Requests: corev1.ResourceList{}, Limits: corev1.ResourceList{}}
+		for name, qty := range map[corev1.ResourceName]string{corev1.ResourceCPU: requestCPU, corev1.ResourceMemory: requestMemory} {
+			if qty != "" {
+				resources.Requests[name] = resource.MustParse(qty)
+			}
+		}
+		for name, qty := range map[corev1.ResourceName]string{corev1.ResourceCPU: limitCPU, corev1.ResourceMemory: limitMemory} {
+			if qty != "" {
+				resources.Limits[name] = resource.MustParse(qty)
+			}
+		}
+		return corev1.Container{Name: "c", Resources: resources}
+	}
+
+	Describe("manifestResourceUsage", func() {
+		It("sums requests and limits across workloads and replicas", func() {
+			usage, err := manifestResourceUsage(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: web
+        resources:
+          requests: {cpu: 100m, memory: 128Mi}
+          limits: {cpu: 200m, memory: 256Mi}
+      - name: sidecar
+        resources:
+          requests: {cpu: 50m}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  template:
+    spec:
+      containers:
+      - name: db
+        resources:
+          requests: {cpu: "1", memory: 1Gi}
+`)
+			Expect(err).NotTo(HaveOccurred())
+			expectUsage(usage, map[corev1.ResourceName]string{
+				corev1.ResourcePods:           "4",
+				corev1.ResourceRequestsCPU:    "1450m",
+				corev1.ResourceRequestsMemory: "1408Mi",
+				corev1.ResourceLimitsCPU:      "600m",
+				corev1.ResourceLimitsMemory:   "768Mi",
+			})
+		})
+
+		It("counts bare pods and one run of a CronJob's job", func() {
+			usage, err := manifestResourceUsage(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: test
+spec:
+  containers:
+  - name: test
+    resources:
+      requests: {cpu: 10m}
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  schedule: "0 * * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: backup
+            resources:
+              requests: {cpu: "2", memory: 4Gi}
+`)
+			Expect(err).NotTo(HaveOccurred())
+			expectUsage(usage, map[corev1.ResourceName]string{
+				corev1.ResourcePods:           "2",
+				corev1.ResourceRequestsCPU:    "2010m",
+				corev1.ResourceRequestsMemory: "4Gi",
+			})
+		})
+
+		It("fails on a manifest that isn't YAML", func() {
+			_, err := manifestResourceUsage("kind: [Deployment")
+			Expect(err).To(MatchError(ContainSubstring("failed to parse rendered manifest")))
+		})
+	})
+
+	Describe("podUsage", func() {
+		It("uses the largest init container when it needs more than the containers", func() {
+			usage := podUsage(&corev1.PodSpec{
+				Containers: []corev1.Container{
+					container("100m", "64Mi", "", ""),
+					container("100m", "64Mi", "", ""),
+				},
+				InitContainers: []corev1.Container{
+					container("500m", "32Mi", "1", ""),
+					container("300m", "", "", ""),
+				},
+			})
+			expectUsage(usage, map[corev1.ResourceName]string{
+				corev1.ResourceRequestsCPU:    "500m",
+				corev1.ResourceRequestsMemory: "128Mi",
+				corev1.ResourceLimitsCPU:      "1",
+			})
+		})
+	})
+
+	Describe("checkQuotas", func() {
+		usage := corev1.ResourceList{
+			corev1.ResourcePods:           resource.MustParse("3"),
+			corev1.ResourceRequestsCPU:    resource.MustParse("1500m"),
+			corev1.ResourceRequestsMemory: resource.MustParse("1Gi"),
+		}
+
+		quota := func(name string, hard, used corev1.ResourceList) corev1.ResourceQuota {
+			return corev1.ResourceQuota{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Status:     corev1.ResourceQuotaStatus{Hard: hard, Used: used},
+			}
+		}
+
+		It("passes when every quota has room", func() {
+			Expect(checkQuotas(usage, []corev1.ResourceQuota{
+				quota("compute", corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("8Gi"),
+				}, corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("2500m"),
+				}),
+				quota("objects", corev1.ResourceList{
+					corev1.ResourcePods:     resource.MustParse("10"),
+					corev1.ResourceServices: resource.MustParse("0"),
+				}, nil),
+			})).To(Succeed())
+		})
+
+		It("reports every resource that doesn't fit", func() {
+			err := checkQuotas(usage, []corev1.ResourceQuota{
+				quota("compute", corev1.ResourceList{
+					corev1.ResourceRequestsCPU: resource.MustParse("2"),
+					corev1.ResourcePods:        resource.MustParse("5"),
+				}, corev1.ResourceList{
+					corev1.ResourceRequestsCPU: resource.MustParse("1"),
+					corev1.ResourcePods:        resource.MustParse("3"),
+				}),
+			})
+			Expect(err).To(MatchError("insufficient resource quota: " +
+				"pods: requires 3, only 2 available in quota compute; " +
+				"requests.cpu: requires 1500m, only 1 available in quota compute"))
+		})
+
+		It("falls back to the spec when the quota has no status yet", func() {
+			q := corev1.ResourceQuota{
+				ObjectMeta: metav1.ObjectMeta{Name: "new"},
+				Spec:       corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")}},
+			}
+			Expect(checkQuotas(usage, []corev1.ResourceQuota{q})).To(MatchError(ContainSubstring("memory: requires 1Gi, only 512Mi available in quota new")))
+		})
+	})
+})
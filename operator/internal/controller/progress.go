@@ -0,0 +1,55 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+// status.progress of the steps of a deployment
+const (
+	progressPending         int32 = 10
+	progressInstalling      int32 = 50
+	progressWaitingForReady int32 = 80
+	progressDeployed        int32 = 100
+)
+
+// deploymentProgress derives status.progress from the phase and the readiness of the
+// release's workloads. A deployed release counts from 80 towards 100 as its replicas
+// become ready. Failed and uninstalling deployments report no progress.
+func deploymentProgress(status *appstorev1alpha1.AppDeploymentStatus) int32 {
+	switch status.Phase {
+	case "", appstorev1alpha1.PhasePending:
+		return progressPending
+	case appstorev1alpha1.PhaseInstalling, appstorev1alpha1.PhaseUpgrading:
+		return progressInstalling
+	case appstorev1alpha1.PhaseDeployed:
+		if meta.IsStatusConditionTrue(status.Conditions, ConditionTypeHealthy) {
+			return progressDeployed
+		}
+		if status.DesiredReplicas <= 0 {
+			return progressWaitingForReady
+		}
+		ready := min(status.HealthyReplicas, status.DesiredReplicas)
+		progress := progressWaitingForReady + (progressDeployed-progressWaitingForReady)*ready/status.DesiredReplicas
+		return min(progress, progressDeployed-1)
+	default:
+		return 0
+	}
+}
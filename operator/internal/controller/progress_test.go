@@ -0,0 +1,105 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+var _ = Describe("Deployment progress", func() {
+	// deployed returns the status of a deployed release with the given replicas ready
+	deployed := func(ready, desired int32, healthy metav1.ConditionStatus) *appstorev1alpha1.AppDeploymentStatus {
+		status := &appstorev1alpha1.AppDeploymentStatus{
+			Phase:           appstorev1alpha1.PhaseDeployed,
+			HealthyReplicas: ready,
+			DesiredReplicas: desired,
+		}
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: ConditionTypeHealthy, Status: healthy, Reason: "Test"})
+		return status
+	}
+
+	It("maps phases to progress", func() {
+		for phase, want := range map[appstorev1alpha1.AppDeploymentPhase]int32{
+			"":                                 10,
+			appstorev1alpha1.PhasePending:      10,
+			appstorev1alpha1.PhaseInstalling:   50,
+			appstorev1alpha1.PhaseUpgrading:    50,
+			appstorev1alpha1.PhaseFailed:       0,
+			appstorev1alpha1.PhaseUninstalling: 0,
+		} {
+			status := &appstorev1alpha1.AppDeploymentStatus{Phase: phase}
+			Expect(deploymentProgress(status)).To(Equal(want), "phase %q", phase)
+		}
+	})
+
+	It("counts a deployed release from 80 to 100 as its replicas become ready", func() {
+		Expect(deploymentProgress(deployed(0, 4, metav1.ConditionFalse))).To(Equal(int32(80)))
+		Expect(deploymentProgress(deployed(2, 4, metav1.ConditionFalse))).To(Equal(int32(90)))
+		Expect(deploymentProgress(deployed(4, 4, metav1.ConditionTrue))).To(Equal(int32(100)))
+	})
+
+	It("stays below 100 until the release is healthy", func() {
+		Expect(deploymentProgress(deployed(99, 100, metav1.ConditionFalse))).To(Equal(int32(99)))
+		Expect(deploymentProgress(deployed(2, 2, metav1.ConditionUnknown))).To(Equal(int32(99)))
+		Expect(deploymentProgress(deployed(0, 0, metav1.ConditionUnknown))).To(Equal(int32(80)))
+	})
+
+	It("is 100 for a healthy release without workloads", func() {
+		Expect(deploymentProgress(deployed(0, 0, metav1.ConditionTrue))).To(Equal(int32(100)))
+	})
+
+	It("is recorded in the status", func() {
+		ctx := context.Background()
+		ad := &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec:       appstorev1alpha1.AppDeploymentSpec{AppName: "postgresql", TeamID: "team-a"},
+		}
+		reconciler := &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(ad).
+				WithStatusSubresource(ad).
+				Build(),
+		}
+
+		Expect(reconciler.updateStatusPhase(ctx, ad, appstorev1alpha1.PhaseInstalling, "Installing Helm chart")).To(Succeed())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		Expect(ad.Status.Progress).To(Equal(int32(50)))
+
+		release := &helm.ReleaseInfo{Name: "db", Namespace: "default", Revision: 1, Status: releaseStatusDeployed}
+		_, err := reconciler.updateStatusDeployed(ctx, ad, release, "hash")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		Expect(ad.Status.Progress).To(Equal(int32(100)))
+
+		_, err = reconciler.updateStatusFailed(ctx, ad, "upgrade failed")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		Expect(ad.Status.Progress).To(BeZero())
+	})
+})
@@ -0,0 +1,41 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"math/rand/v2"
+	"time"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+// startupSplay returns how long to delay the first reconcile of an AppDeployment after
+// the operator started, spreading the reconciles of all existing deployments over
+// StartupSplay instead of running them at once. Deployments that were never reconciled
+// or whose spec changed since are not delayed, nor are later reconciles.
+func (r *AppDeploymentReconciler) startupSplay(appDeployment *appstorev1alpha1.AppDeployment) time.Duration {
+	if r.StartupSplay <= 0 {
+		return 0
+	}
+	if _, seen := r.observed.LoadOrStore(appDeployment.UID, struct{}{}); seen {
+		return 0
+	}
+	if appDeployment.Status.Phase == "" || appDeployment.Status.ObservedGeneration != appDeployment.Generation {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(r.StartupSplay)))
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+var _ = Describe("Startup splay", func() {
+	const window = time.Minute
+
+	var (
+		ctx      context.Context
+		fakeHelm *fakeHelmClient
+	)
+
+	// newDeployed returns an AppDeployment that was deployed before the operator started
+	newDeployed := func(i int) *appstorev1alpha1.AppDeployment {
+		return &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       fmt.Sprintf("db-%d", i),
+				Namespace:  "default",
+				UID:        types.UID(fmt.Sprintf("uid-%d", i)),
+				Generation: 1,
+				Finalizers: []string{finalizerName},
+			},
+			Spec: appstorev1alpha1.AppDeploymentSpec{AppName: "postgresql", TeamID: "team-a"},
+			Status: appstorev1alpha1.AppDeploymentStatus{
+				Phase:              appstorev1alpha1.PhaseDeployed,
+				ObservedGeneration: 1,
+			},
+		}
+	}
+
+	newReconciler := func(splay time.Duration, objects ...client.Object) *AppDeploymentReconciler {
+		return &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(objects...).
+				WithStatusSubresource(&appstorev1alpha1.AppDeployment{}).
+				Build(),
+			HelmClient:   fakeHelm,
+			StartupSplay: splay,
+		}
+	}
+
+	reconcile := func(reconciler *AppDeploymentReconciler, ad *appstorev1alpha1.AppDeployment) ctrl.Result {
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ad)})
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{}
+	})
+
+	It("spreads the first reconciles of existing deployments over the window", func() {
+		var deployments []*appstorev1alpha1.AppDeployment
+		var objects []client.Object
+		for i := range 50 {
+			ad := newDeployed(i)
+			deployments = append(deployments, ad)
+			objects = append(objects, ad)
+		}
+		reconciler := newReconciler(window, objects...)
+
+		delays := map[time.Duration]bool{}
+		early, late := 0, 0
+		for _, ad := range deployments {
+			delay := reconcile(reconciler, ad).RequeueAfter
+			Expect(delay).To(BeNumerically(">=", 0))
+			Expect(delay).To(BeNumerically("<", window))
+			delays[delay] = true
+			if delay < window/2 {
+				early++
+			} else {
+				late++
+			}
+		}
+		Expect(fakeHelm.Calls).To(BeEmpty())
+		Expect(len(delays)).To(BeNumerically(">", 40))
+		Expect(early).To(BeNumerically(">", 5))
+		Expect(late).To(BeNumerically(">", 5))
+
+		// The requeued reconcile isn't delayed again
+		reconcile(reconciler, deployments[0])
+		Expect(fakeHelm.Calls).NotTo(BeEmpty())
+	})
+
+	It("doesn't delay deployments with pending changes", func() {
+		changed := newDeployed(0)
+		changed.Generation = 2
+		created := newDeployed(1)
+		created.Status = appstorev1alpha1.AppDeploymentStatus{}
+		reconciler := newReconciler(window, changed, created)
+
+		Expect(reconcile(reconciler, changed).RequeueAfter).To(Equal(requeueAfterSuccess))
+		Expect(reconcile(reconciler, created).RequeueAfter).To(Equal(requeueAfterSuccess))
+		Expect(fakeHelm.callsTo("Upgrade")).To(HaveLen(0))
+		Expect(fakeHelm.callsTo("Install")).To(HaveLen(2))
+		Expect(fakeHelm.Releases).To(HaveKey("db-0"))
+		Expect(fakeHelm.Releases).To(HaveKey("db-1"))
+	})
+
+	It("doesn't delay reconciles without a splay", func() {
+		ad := newDeployed(0)
+		reconciler := newReconciler(0, ad)
+
+		reconcile(reconciler, ad)
+		Expect(fakeHelm.callsTo("Install")).To(HaveLen(1))
+	})
+	It("forgets deleted deployments", func() {
+		ad := newDeployed(0)
+		reconciler := newReconciler(window, ad)
+
+		reconcile(reconciler, ad)
+		_, seen := reconciler.observed.Load(ad.UID)
+		Expect(seen).To(BeTrue())
+
+		Expect(reconciler.Delete(ctx, ad)).To(Succeed())
+		reconcile(reconciler, ad)
+		_, seen = reconciler.observed.Load(ad.UID)
+		Expect(seen).To(BeFalse())
+	})
+})
@@ -0,0 +1,204 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+	helmvalues "appstore/shared/values"
+)
+
+const (
+	// releaseStatusDeployed is the Helm status of a successfully deployed release
+	releaseStatusDeployed = "deployed"
+
+	// defaultHealthCheckGracePeriod is used when auto-rollback is enabled without a grace period
+	defaultHealthCheckGracePeriod = 5 * time.Minute
+)
+
+// rolledBackError reports an upgrade that failed and was rolled back to the previous revision
+type rolledBackError struct {
+	// Revision is the revision the release was rolled back to
+	Revision int
+	// FailedVersion is the chart version of the upgrade that failed
+	FailedVersion string
+
+	err error
+}
+
+func (e *rolledBackError) Error() string {
+	return fmt.Sprintf("%v (rolled back to revision %d)", e.err, e.Revision)
+}
+
+func (e *rolledBackError) Unwrap() error {
+	return e.err
+}
+
+// upgradeRelease upgrades the release according to the deployment's strategy. With
+// auto-rollback, a release that doesn't become healthy within the deployment's grace period
+// is rolled back to the revision that was running before the upgrade and a
+// *rolledBackError is returned.
+func (r *AppDeploymentReconciler) upgradeRelease(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, releaseName string, current *helm.ReleaseInfo, values map[string]interface{}) (*helm.ReleaseInfo, error) {
+	if !appDeployment.Spec.AutoRollback {
+		return r.rollOut(ctx, appDeployment, releaseName, values, helm.ActionOptions{})
+	}
+
+	grace := healthCheckGracePeriod(appDeployment)
+	releaseInfo, err := r.rollOut(ctx, appDeployment, releaseName, values, helm.ActionOptions{Wait: true, Timeout: grace})
+	if err != nil {
+		return nil, r.rollBack(ctx, appDeployment, releaseName, current, fmt.Errorf("release did not become healthy within %s: %w", grace, err))
+	}
+	return releaseInfo, nil
+}
+
+// rollOut upgrades the release with the deployment's strategy, on top of opts
+func (r *AppDeploymentReconciler) rollOut(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, releaseName string, values map[string]interface{}, opts helm.ActionOptions) (*helm.ReleaseInfo, error) {
+	if appDeployment.Spec.Strategy == appstorev1alpha1.StrategyCanary {
+		return r.canaryUpgrade(ctx, appDeployment, releaseName, values, opts)
+	}
+	return r.HelmClient.Upgrade(
+		ctx,
+		releaseName,
+		appDeployment.Spec.AppName,
+		appDeployment.Namespace,
+		values,
+		appDeployment.Spec.ChartVersion,
+		withInstallOptions(appDeployment, opts),
+	)
+}
+
+// withInstallOptions adds the deployment's spec.installOptions, values strategy, common
+// labels, common annotations and owner label to the options a strategy needs. Options the
+// strategy turns on stay on.
+func withInstallOptions(appDeployment *appstorev1alpha1.AppDeployment, opts helm.ActionOptions) helm.ActionOptions {
+	opts.Labels = appDeployment.Spec.CommonLabels
+	opts.Annotations = appDeployment.Spec.CommonAnnotations
+	opts.ReleaseLabels = releaseOwnerLabels(appDeployment)
+	opts.ReuseValues = appDeployment.Spec.ValuesStrategy == appstorev1alpha1.ValuesStrategyReuse
+	if spec := appDeployment.Spec.InstallOptions; spec != nil {
+		opts.Atomic = opts.Atomic || spec.Atomic
+		opts.CleanupOnFail = opts.CleanupOnFail || spec.CleanupOnFail
+		opts.DisableHooks = opts.DisableHooks || spec.DisableHooks
+		opts.SkipCRDs = opts.SkipCRDs || spec.SkipCRDs
+	}
+	return opts
+}
+
+// rollBack rolls the release back to the revision that was running before a failed
+// upgrade and returns a *rolledBackError wrapping err
+func (r *AppDeploymentReconciler) rollBack(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, releaseName string, current *helm.ReleaseInfo, err error) error {
+	logger := log.FromContext(ctx).WithValues("release", releaseName)
+
+	failedVersion := appDeployment.Spec.ChartVersion
+	if failed, getErr := r.HelmClient.GetRelease(ctx, releaseName, appDeployment.Namespace); getErr == nil && failed != nil && failed.Revision > current.Revision {
+		failedVersion = failed.ChartVersion
+	}
+
+	logger.Info("Upgrade failed health check, rolling back", "revision", current.Revision, "failedVersion", failedVersion)
+	if rbErr := r.HelmClient.Rollback(ctx, releaseName, appDeployment.Namespace, current.Revision); rbErr != nil {
+		return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+	}
+
+	return &rolledBackError{Revision: current.Revision, FailedVersion: failedVersion, err: err}
+}
+
+// healthCheckGracePeriod returns the deployment's grace period or the default
+func healthCheckGracePeriod(appDeployment *appstorev1alpha1.AppDeployment) time.Duration {
+	if appDeployment.Spec.HealthCheckGracePeriod != nil && appDeployment.Spec.HealthCheckGracePeriod.Duration > 0 {
+		return appDeployment.Spec.HealthCheckGracePeriod.Duration
+	}
+	return defaultHealthCheckGracePeriod
+}
+
+// canaryUpgrade performs a gated upgrade: the release is first upgraded with the canary
+// values overlay, then promoted with the full values. Both steps use --wait, so the release
+// is only promoted once the canary is ready, and --atomic unless the deployment rolls back
+// automatically, so a failing step is rolled back by Helm. The promotion is skipped if the
+// overlay doesn't change the values.
+func (r *AppDeploymentReconciler) canaryUpgrade(ctx context.Context, appDeployment *appstorev1alpha1.AppDeployment, releaseName string, values map[string]interface{}, opts helm.ActionOptions) (*helm.ReleaseInfo, error) {
+	logger := log.FromContext(ctx).WithValues("release", releaseName, "strategy", appstorev1alpha1.StrategyCanary)
+	opts.Wait = true
+	opts.Atomic = !appDeployment.Spec.AutoRollback
+	gated := withInstallOptions(appDeployment, opts)
+
+	canaryValues, err := canaryValues(appDeployment, values)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Starting canary upgrade")
+	releaseInfo, err := r.HelmClient.Upgrade(
+		ctx,
+		releaseName,
+		appDeployment.Spec.AppName,
+		appDeployment.Namespace,
+		canaryValues,
+		appDeployment.Spec.ChartVersion,
+		gated,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("canary step failed: %w", err)
+	}
+	if equality.Semantic.DeepEqual(canaryValues, values) {
+		logger.Info("Canary values are the full values, skipping promotion")
+		return releaseInfo, nil
+	}
+
+	logger.Info("Canary ready, promoting release")
+	releaseInfo, err = r.HelmClient.Upgrade(
+		ctx,
+		releaseName,
+		appDeployment.Spec.AppName,
+		appDeployment.Namespace,
+		values,
+		appDeployment.Spec.ChartVersion,
+		gated,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("canary promotion failed: %w", err)
+	}
+
+	return releaseInfo, nil
+}
+
+// canaryValues returns a copy of values with the deployment's canary overlay merged on
+// top, or defaultCanaryValues if it has none
+func canaryValues(appDeployment *appstorev1alpha1.AppDeployment, values map[string]interface{}) (map[string]interface{}, error) {
+	var overlay map[string]interface{}
+	if appDeployment.Spec.CanaryValues == nil {
+		overlay = defaultCanaryValues()
+	} else if err := json.Unmarshal(appDeployment.Spec.CanaryValues.Raw, &overlay); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal canary values: %w", err)
+	}
+
+	return helmvalues.Merge(runtime.DeepCopyJSON(values), overlay), nil
+}
+
+// defaultCanaryValues scales the canary down to a single replica, for charts that follow
+// the replicaCount convention of helm create. Numbers are float64 like in decoded values.
+func defaultCanaryValues() map[string]interface{} {
+	return map[string]interface{}{"replicaCount": float64(1)}
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+var _ = Describe("Upgrade strategies", func() {
+	var (
+		ctx        context.Context
+		fakeHelm   *fakeHelmClient
+		reconciler *AppDeploymentReconciler
+		current    *helm.ReleaseInfo
+		values     map[string]interface{}
+	)
+
+	newDeployment := func(strategy appstorev1alpha1.DeploymentStrategy, canaryValues string) *appstorev1alpha1.AppDeployment {
+		ad := &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec: appstorev1alpha1.AppDeploymentSpec{
+				AppName:  "postgresql",
+				TeamID:   "team-a",
+				Strategy: strategy,
+			},
+		}
+		if canaryValues != "" {
+			ad.Spec.CanaryValues = &apiextensionsv1.JSON{Raw: []byte(canaryValues)}
+		}
+		return ad
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		current = &helm.ReleaseInfo{Name: "db", Namespace: "default", Revision: 3, Status: releaseStatusDeployed}
+		fakeHelm = &fakeHelmClient{Releases: releasesOf(current)}
+		reconciler = &AppDeploymentReconciler{HelmClient: fakeHelm}
+		values = map[string]interface{}{
+			"replicaCount": float64(3),
+			"auth":         map[string]interface{}{"database": "app"},
+		}
+	})
+
+	It("upgrades in a single ungated step for the immediate strategy", func() {
+		info, err := reconciler.upgradeRelease(ctx, newDeployment(appstorev1alpha1.StrategyImmediate, ""), "db", current, values)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Revision).To(Equal(4))
+
+		upgrades := fakeHelm.callsTo("Upgrade")
+		Expect(upgrades).To(HaveLen(1))
+		Expect(upgrades[0].Options).To(Equal(helm.ActionOptions{}))
+		Expect(upgrades[0].Values).To(Equal(values))
+	})
+
+	It("treats an unset strategy as immediate", func() {
+		_, err := reconciler.upgradeRelease(ctx, newDeployment("", ""), "db", current, values)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeHelm.callsTo("Upgrade")).To(HaveLen(1))
+	})
+
+	It("runs a gated canary step with the overlay and then promotes", func() {
+		ad := newDeployment(appstorev1alpha1.StrategyCanary, `{"replicaCount":1}`)
+
+		info, err := reconciler.upgradeRelease(ctx, ad, "db", current, values)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Revision).To(Equal(5))
+
+		upgrades := fakeHelm.callsTo("Upgrade")
+		Expect(upgrades).To(HaveLen(2))
+		gated := helm.ActionOptions{Wait: true, Atomic: true}
+		Expect(upgrades[0].Options).To(Equal(gated))
+		Expect(upgrades[0].Values).To(HaveKeyWithValue("replicaCount", float64(1)))
+		Expect(upgrades[0].Values).To(HaveKey("auth"))
+		Expect(upgrades[1].Options).To(Equal(gated))
+		Expect(upgrades[1].Values).To(HaveKeyWithValue("replicaCount", float64(3)))
+
+		By("leaving the caller's values untouched")
+		Expect(values).To(HaveKeyWithValue("replicaCount", float64(3)))
+		Expect(fakeHelm.callsTo("Rollback")).To(BeEmpty())
+	})
+
+	It("scales the canary down to a single replica without canary values", func() {
+		_, err := reconciler.upgradeRelease(ctx, newDeployment(appstorev1alpha1.StrategyCanary, ""), "db", current, values)
+		Expect(err).NotTo(HaveOccurred())
+
+		upgrades := fakeHelm.callsTo("Upgrade")
+		Expect(upgrades).To(HaveLen(2))
+		Expect(upgrades[0].Values).To(HaveKeyWithValue("replicaCount", float64(1)))
+		Expect(upgrades[1].Values).To(Equal(values))
+	})
+
+	It("skips the promotion when the canary values are the full values", func() {
+		values["replicaCount"] = float64(1)
+
+		info, err := reconciler.upgradeRelease(ctx, newDeployment(appstorev1alpha1.StrategyCanary, ""), "db", current, values)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Revision).To(Equal(4))
+		Expect(fakeHelm.callsTo("Upgrade")).To(HaveLen(1))
+	})
+
+	It("does not promote when the canary step fails", func() {
+		fakeHelm.UpgradeErrs = []error{errors.New("timed out waiting for the condition")}
+
+		_, err := reconciler.upgradeRelease(ctx, newDeployment(appstorev1alpha1.StrategyCanary, ""), "db", current, values)
+		Expect(err).To(MatchError(ContainSubstring("canary step failed")))
+		Expect(fakeHelm.callsTo("Upgrade")).To(HaveLen(1))
+	})
+
+	Context("with auto-rollback", func() {
+		var ad *appstorev1alpha1.AppDeployment
+
+		BeforeEach(func() {
+			ad = newDeployment(appstorev1alpha1.StrategyImmediate, "")
+			ad.Spec.AutoRollback = true
+			ad.Spec.ChartVersion = "2.0.0"
+			ad.Spec.HealthCheckGracePeriod = &metav1.Duration{Duration: 2 * time.Minute}
+		})
+
+		It("waits for the release to become healthy within the grace period", func() {
+			info, err := reconciler.upgradeRelease(ctx, ad, "db", current, values)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Revision).To(Equal(4))
+
+			upgrades := fakeHelm.callsTo("Upgrade")
+			Expect(upgrades).To(HaveLen(1))
+			Expect(upgrades[0].Options).To(Equal(helm.ActionOptions{Wait: true, Timeout: 2 * time.Minute}))
+			Expect(fakeHelm.callsTo("Rollback")).To(BeEmpty())
+		})
+
+		It("uses the default grace period when none is set", func() {
+			ad.Spec.HealthCheckGracePeriod = nil
+
+			_, err := reconciler.upgradeRelease(ctx, ad, "db", current, values)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeHelm.callsTo("Upgrade")[0].Options.Timeout).To(Equal(defaultHealthCheckGracePeriod))
+		})
+
+		It("rolls back and reports the failed version when the release is unhealthy", func() {
+			fakeHelm.UpgradeErrs = []error{errors.New("timed out waiting for the condition")}
+
+			_, err := reconciler.upgradeRelease(ctx, ad, "db", current, values)
+			var rolledBack *rolledBackError
+			Expect(errors.As(err, &rolledBack)).To(BeTrue())
+			Expect(rolledBack.Revision).To(Equal(3))
+			Expect(rolledBack.FailedVersion).To(Equal("2.0.0"))
+			Expect(err).To(MatchError(ContainSubstring("did not become healthy within 2m0s")))
+
+			rollbacks := fakeHelm.callsTo("Rollback")
+			Expect(rollbacks).To(HaveLen(1))
+			Expect(rollbacks[0].Revision).To(Equal(3))
+		})
+
+		It("rolls a failed canary promotion back to the revision before the canary", func() {
+			ad.Spec.Strategy = appstorev1alpha1.StrategyCanary
+			fakeHelm.UpgradeErrs = []error{nil, errors.New("timed out waiting for the condition")}
+
+			_, err := reconciler.upgradeRelease(ctx, ad, "db", current, values)
+			var rolledBack *rolledBackError
+			Expect(errors.As(err, &rolledBack)).To(BeTrue())
+			Expect(rolledBack.Revision).To(Equal(3))
+			Expect(err).To(MatchError(ContainSubstring("canary promotion failed")))
+
+			upgrades := fakeHelm.callsTo("Upgrade")
+			Expect(upgrades).To(HaveLen(2))
+			By("bounding each step by the grace period and leaving the rollback to the operator")
+			for _, upgrade := range upgrades {
+				Expect(upgrade.Options).To(Equal(helm.ActionOptions{Wait: true, Timeout: 2 * time.Minute}))
+			}
+			rollbacks := fakeHelm.callsTo("Rollback")
+			Expect(rollbacks).To(HaveLen(1))
+			Expect(rollbacks[0].Revision).To(Equal(3))
+		})
+
+		It("returns a plain error when the rollback itself fails", func() {
+			fakeHelm.UpgradeErrs = []error{errors.New("timed out waiting for the condition")}
+			fakeHelm.RollbackErr = errors.New("release locked")
+
+			_, err := reconciler.upgradeRelease(ctx, ad, "db", current, values)
+			Expect(err).To(MatchError(ContainSubstring("rollback failed: release locked")))
+			var rolledBack *rolledBackError
+			Expect(errors.As(err, &rolledBack)).To(BeFalse())
+		})
+
+		It("skips the rolled back upgrade until the spec or values change", func() {
+			ad.Generation = 2
+			ad.Status.ObservedGeneration = 2
+			ad.Status.RolledBackValuesHash = "abc"
+			ad.Status.Conditions = []metav1.Condition{{Type: ConditionTypeRolledBack, Status: metav1.ConditionTrue}}
+			Expect(upgradeRolledBack(ad, "abc")).To(BeTrue())
+			Expect(upgradeRolledBack(ad, "def")).To(BeFalse())
+
+			ad.Generation = 3
+			Expect(upgradeRolledBack(ad, "abc")).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,97 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+var _ = Describe("Helm timeout", func() {
+	var (
+		ctx      context.Context
+		fakeHelm *fakeHelmClient
+	)
+
+	newAppDeployment := func(timeout *metav1.Duration) *appstorev1alpha1.AppDeployment {
+		return &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "db",
+				Namespace:  "default",
+				Finalizers: []string{finalizerName},
+			},
+			Spec: appstorev1alpha1.AppDeploymentSpec{AppName: "postgresql", TeamID: "team-a", Timeout: timeout},
+		}
+	}
+
+	reconcile := func(ad *appstorev1alpha1.AppDeployment) {
+		reconciler := &AppDeploymentReconciler{
+			Client:     fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ad).WithStatusSubresource(ad).Build(),
+			HelmClient: fakeHelm,
+		}
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ad)})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{}
+	})
+
+	It("leaves the timeout to the Helm client when spec.timeout is not set", func() {
+		reconcile(newAppDeployment(nil))
+
+		installs := fakeHelm.callsTo("Install")
+		Expect(installs).To(HaveLen(1))
+		Expect(installs[0].Timeout).To(BeZero())
+	})
+
+	It("applies spec.timeout to installs and upgrades", func() {
+		reconcile(newAppDeployment(&metav1.Duration{Duration: 15 * time.Minute}))
+		installs := fakeHelm.callsTo("Install")
+		Expect(installs).To(HaveLen(1))
+		Expect(installs[0].Timeout).To(Equal(15 * time.Minute))
+
+		// The release exists and its values were never applied
+		reconcile(newAppDeployment(&metav1.Duration{Duration: 15 * time.Minute}))
+		upgrades := fakeHelm.callsTo("Upgrade")
+		Expect(upgrades).To(HaveLen(1))
+		Expect(upgrades[0].Timeout).To(Equal(15 * time.Minute))
+	})
+
+	It("applies spec.timeout to uninstalls", func() {
+		ad := newAppDeployment(&metav1.Duration{Duration: 15 * time.Minute})
+		ad.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		fakeHelm.setRelease(&helm.ReleaseInfo{Name: "db", Namespace: "default", Status: releaseStatusDeployed})
+		reconcile(ad)
+
+		uninstalls := fakeHelm.callsTo("Uninstall")
+		Expect(uninstalls).To(HaveLen(1))
+		Expect(uninstalls[0].Timeout).To(Equal(15 * time.Minute))
+	})
+})
@@ -0,0 +1,59 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"helm.sh/helm/v3/pkg/chart"
+
+	"appstore/operator/internal/helm"
+)
+
+// breakingChangesAnnotation on a chart version describes changes that break upgrades
+// from earlier versions, e.g. a required data migration
+const breakingChangesAnnotation = "appstore.bitpipe.no/breaking-changes"
+
+// checkUpgradeCompatibility returns why upgrading the deployed release to the target chart
+// may break it, or nil. Upgrades are unsafe when the chart's major version or kubeVersion
+// constraint changes, or when the target declares breaking changes in its annotations.
+func checkUpgradeCompatibility(current *helm.ReleaseInfo, target *chart.Metadata) error {
+	if target.Version == current.ChartVersion {
+		return nil
+	}
+
+	var reasons []string
+	from, fromErr := semver.NewVersion(current.ChartVersion)
+	to, toErr := semver.NewVersion(target.Version)
+	if fromErr == nil && toErr == nil && from.Major() != to.Major() {
+		reasons = append(reasons, fmt.Sprintf("the major version changes from %d to %d", from.Major(), to.Major()))
+	}
+	if target.KubeVersion != current.KubeVersion {
+		reasons = append(reasons, fmt.Sprintf("the kubeVersion constraint changes from %q to %q", current.KubeVersion, target.KubeVersion))
+	}
+	if changes := target.Annotations[breakingChangesAnnotation]; changes != "" {
+		reasons = append(reasons, "the chart declares breaking changes: "+changes)
+	}
+
+	if len(reasons) == 0 {
+		return nil
+	}
+	return fmt.Errorf("upgrading chart %s from %s to %s may break the release: %s. Set spec.allowMajorUpgrade to upgrade anyway",
+		current.ChartName, current.ChartVersion, target.Version, strings.Join(reasons, "; "))
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+var _ = Describe("Upgrade compatibility", func() {
+	current := &helm.ReleaseInfo{ChartName: "postgresql", ChartVersion: "15.2.0", KubeVersion: ">=1.23.0-0"}
+
+	It("allows upgrades within the major version", func() {
+		Expect(checkUpgradeCompatibility(current, &chart.Metadata{Version: "15.5.1", KubeVersion: ">=1.23.0-0"})).To(Succeed())
+		// Changes are ignored if the version stays the same
+		Expect(checkUpgradeCompatibility(current, &chart.Metadata{Version: "15.2.0", KubeVersion: ">=1.25.0-0"})).To(Succeed())
+	})
+
+	It("rejects upgrades across a major version", func() {
+		err := checkUpgradeCompatibility(current, &chart.Metadata{Version: "16.0.0", KubeVersion: ">=1.23.0-0"})
+		Expect(err).To(MatchError(ContainSubstring("the major version changes from 15 to 16")))
+		Expect(err).To(MatchError(ContainSubstring("spec.allowMajorUpgrade")))
+
+		err = checkUpgradeCompatibility(current, &chart.Metadata{Version: "14.3.0", KubeVersion: ">=1.23.0-0"})
+		Expect(err).To(MatchError(ContainSubstring("the major version changes from 15 to 14")))
+	})
+
+	It("rejects upgrades changing the kubeVersion constraint", func() {
+		err := checkUpgradeCompatibility(current, &chart.Metadata{Version: "15.3.0", KubeVersion: ">=1.27.0-0"})
+		Expect(err).To(MatchError(ContainSubstring(`the kubeVersion constraint changes from ">=1.23.0-0" to ">=1.27.0-0"`)))
+	})
+
+	It("rejects upgrades to a version declaring breaking changes", func() {
+		err := checkUpgradeCompatibility(current, &chart.Metadata{Version: "15.3.0", KubeVersion: ">=1.23.0-0", Annotations: map[string]string{
+			breakingChangesAnnotation: "the data directory moved; see UPGRADING.md",
+		}})
+		Expect(err).To(MatchError(ContainSubstring("the chart declares breaking changes: the data directory moved")))
+	})
+
+	It("ignores versions that aren't semantic versions", func() {
+		Expect(checkUpgradeCompatibility(
+			&helm.ReleaseInfo{ChartVersion: "latest"},
+			&chart.Metadata{Version: "16.0.0"},
+		)).To(Succeed())
+	})
+
+	Context("when reconciling", func() {
+		var (
+			ctx      context.Context
+			fakeHelm *fakeHelmClient
+		)
+
+		reconcileHelm := func(ad *appstorev1alpha1.AppDeployment) {
+			reconciler := &AppDeploymentReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(ad).
+					WithStatusSubresource(ad).
+					Build(),
+				HelmClient: fakeHelm,
+			}
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+
+			_, err := reconciler.reconcileHelm(ctx, ad)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		newDeployment := func(version string) *appstorev1alpha1.AppDeployment {
+			return &appstorev1alpha1.AppDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+				Spec:       appstorev1alpha1.AppDeploymentSpec{AppName: "postgresql", TeamID: "team-a", ChartVersion: version},
+			}
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			fakeHelm = &fakeHelmClient{Releases: releasesOf(&helm.ReleaseInfo{
+				Name: "db", Namespace: "default", Revision: 1, Status: releaseStatusDeployed,
+				ChartName: "postgresql", ChartVersion: "15.2.0",
+			})}
+		})
+
+		It("fails an upgrade across a major version without upgrading", func() {
+			ad := newDeployment("16.0.0")
+			reconcileHelm(ad)
+
+			Expect(fakeHelm.callsTo("Upgrade")).To(BeEmpty())
+			Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseFailed))
+			cond := meta.FindStatusCondition(ad.Status.Conditions, ConditionTypeReady)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Reason).To(Equal("BreakingUpgrade"))
+			Expect(cond.Message).To(ContainSubstring("from 15.2.0 to 16.0.0"))
+		})
+
+		It("fails an upgrade to a version declaring breaking changes", func() {
+			fakeHelm.Charts = map[string]*chart.Metadata{"15.3.0": {
+				Name: "postgresql", Version: "15.3.0",
+				Annotations: map[string]string{breakingChangesAnnotation: "requires a dump and restore"},
+			}}
+			ad := newDeployment("15.3.0")
+			reconcileHelm(ad)
+
+			Expect(fakeHelm.callsTo("Upgrade")).To(BeEmpty())
+			Expect(ad.Status.Message).To(ContainSubstring("requires a dump and restore"))
+		})
+
+		It("upgrades across a major version when allowed", func() {
+			ad := newDeployment("16.0.0")
+			ad.Spec.AllowMajorUpgrade = true
+			reconcileHelm(ad)
+
+			Expect(fakeHelm.callsTo("Upgrade")).To(HaveLen(1))
+			Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+			Expect(ad.Status.DeployedChartVersion).To(Equal("16.0.0"))
+		})
+
+		It("upgrades within a major version", func() {
+			ad := newDeployment("15.3.0")
+			reconcileHelm(ad)
+
+			Expect(fakeHelm.callsTo("Upgrade")).To(HaveLen(1))
+			Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+		})
+	})
+})
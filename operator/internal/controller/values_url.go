@@ -0,0 +1,229 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/sops"
+)
+
+const (
+	// maxValuesURLSize limits the size of a values file fetched from a URL
+	maxValuesURLSize = 1 << 20
+
+	valuesURLTimeout = 30 * time.Second
+
+	// maxValuesURLCacheSize limits the total size of the cached values files
+	maxValuesURLCacheSize = 32 << 20
+)
+
+// valuesURLContentTypes are the accepted content types of values fetched from a URL.
+// Anything else (e.g. an HTML login page) is rejected.
+var valuesURLContentTypes = map[string]bool{
+	"application/yaml":         true,
+	"application/x-yaml":       true,
+	"text/yaml":                true,
+	"text/x-yaml":              true,
+	"application/json":         true,
+	"text/plain":               true,
+	"application/octet-stream": true,
+}
+
+// cachedValuesURL is a values file fetched from a URL, with the validators of the response
+type cachedValuesURL struct {
+	etag         string
+	lastModified string
+	data         []byte
+}
+
+// valuesURLCache caches values files fetched from URLs whose responses have an ETag or
+// Last-Modified header, so that they are only downloaded again when they changed. When
+// the cache is full, arbitrary entries are dropped.
+type valuesURLCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedValuesURL
+	size    int
+}
+
+func (c *valuesURLCache) get(key string) *cachedValuesURL {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries[key]
+}
+
+func (c *valuesURLCache) set(key string, entry *cachedValuesURL) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*cachedValuesURL)
+	}
+	if previous, ok := c.entries[key]; ok {
+		c.size -= len(previous.data)
+		delete(c.entries, key)
+	}
+	for other, cached := range c.entries {
+		if c.size+len(entry.data) <= maxValuesURLCacheSize {
+			break
+		}
+		c.size -= len(cached.data)
+		delete(c.entries, other)
+	}
+	c.entries[key] = entry
+	c.size += len(entry.data)
+}
+
+// getValuesFromURL fetches and parses a values YAML file over HTTP(S), decrypting it if it
+// is encrypted with SOPS, and reports whether it was. The version of the values is a
+// digest of the file as fetched. Only hosts in ValuesURLHosts are fetched from, and a file
+// is only downloaded again if its ETag or Last-Modified changed.
+func (r *AppDeploymentReconciler) getValuesFromURL(ctx context.Context, namespace string, ref appstorev1alpha1.ValuesReference) (map[string]interface{}, string, bool, error) {
+	if ref.URL == "" {
+		return nil, "", false, fmt.Errorf("url is required for kind URL")
+	}
+	if len(r.ValuesURLHosts) == 0 {
+		return nil, "", false, fmt.Errorf("values from URLs are not enabled, see --values-url-hosts")
+	}
+	if _, err := checkOutboundURL(ref.URL, r.ValuesURLHosts); err != nil {
+		return nil, "", false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ref.URL, nil)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("invalid url: %w", err)
+	}
+	req.Header.Set("Accept", "application/yaml, text/yaml, text/plain, */*;q=0.1")
+
+	// Responses depend on the credentials, so they are cached per auth Secret
+	cacheKey := namespace + "/" + ref.AuthSecretRef + " " + ref.URL
+	cached := r.valuesURLs.get(cacheKey)
+	if cached != nil {
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	if ref.AuthSecretRef != "" {
+		if err := r.setValuesURLAuth(ctx, req, namespace, ref.AuthSecretRef); err != nil {
+			return nil, "", false, err
+		}
+	}
+
+	resp, err := r.httpClient().Do(req)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to fetch values: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var data []byte
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		data = cached.data
+	} else if data, err = readValuesURLResponse(resp, ref.URL); err != nil {
+		return nil, "", false, err
+	} else if etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"); etag != "" || lastModified != "" {
+		r.valuesURLs.set(cacheKey, &cachedValuesURL{etag: etag, lastModified: lastModified, data: data})
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, "", false, fmt.Errorf("failed to parse values from %s: %w", ref.URL, err)
+	}
+	// The file changes whenever its values do, even if they are encrypted
+	digest := sha256.Sum256(data)
+	version := hex.EncodeToString(digest[:8])
+	if !sops.IsEncrypted(values) {
+		return values, version, false, nil
+	}
+
+	if values, err = decryptValues(data, r.SOPSIdentities); err != nil {
+		return nil, "", false, fmt.Errorf("values from %s: %w", ref.URL, err)
+	}
+	return values, version, true, nil
+}
+
+// readValuesURLResponse checks the status and content type of a values file response and
+// reads its body
+func readValuesURLResponse(resp *http.Response, url string) ([]byte, error) {
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch values: %s returned %s", url, resp.Status)
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !valuesURLContentTypes[mediaType] {
+			return nil, fmt.Errorf("unexpected content type %q from %s", contentType, url)
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxValuesURLSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values: %w", err)
+	}
+	if len(data) > maxValuesURLSize {
+		return nil, fmt.Errorf("values from %s exceed %d bytes", url, maxValuesURLSize)
+	}
+	return data, nil
+}
+
+// setValuesURLAuth adds bearer or basic auth from a Secret to the request
+func (r *AppDeploymentReconciler) setValuesURLAuth(ctx context.Context, req *http.Request, namespace, secretName string) error {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: namespace}, secret); err != nil {
+		return fmt.Errorf("failed to get auth Secret %s: %w", secretName, err)
+	}
+
+	if token, ok := secret.Data["token"]; ok {
+		req.Header.Set("Authorization", "Bearer "+string(token))
+		return nil
+	}
+
+	username, hasUsername := secret.Data["username"]
+	password, hasPassword := secret.Data["password"]
+	if hasUsername && hasPassword {
+		req.SetBasicAuth(string(username), string(password))
+		return nil
+	}
+
+	return fmt.Errorf("auth Secret %s must contain a token key or username and password keys", secretName)
+}
+
+// httpClient returns the client used to fetch values from URLs
+func (r *AppDeploymentReconciler) httpClient() *http.Client {
+	if r.HTTPClient != nil {
+		return r.HTTPClient
+	}
+	r.valuesURLClientOnce.Do(func() {
+		r.valuesURLClient = newOutboundClient(valuesURLTimeout, r.ValuesURLHosts)
+	})
+	return r.valuesURLClient
+}
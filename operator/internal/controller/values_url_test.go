@@ -0,0 +1,190 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+var _ = Describe("Values from URL", func() {
+	var (
+		ctx        context.Context
+		server     *httptest.Server
+		reconciler *AppDeploymentReconciler
+		requests   int
+	)
+
+	newDeployment := func(refs ...appstorev1alpha1.ValuesReference) *appstorev1alpha1.AppDeployment {
+		return &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec: appstorev1alpha1.AppDeploymentSpec{
+				AppName:    "postgresql",
+				TeamID:     "team-a",
+				ValuesFrom: refs,
+			},
+		}
+	}
+
+	urlRef := func(path string) appstorev1alpha1.ValuesReference {
+		return appstorev1alpha1.ValuesReference{Kind: "URL", URL: server.URL + path}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		requests = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/values.yaml":
+				w.Header().Set("Content-Type", "application/yaml")
+				w.Write([]byte("replicaCount: 2\nauth:\n  database: app\n"))
+			case "/private.yaml":
+				if r.Header.Get("Authorization") != "Bearer s3cret" {
+					if user, pass, ok := r.BasicAuth(); !ok || user != "ci" || pass != "hunter2" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+				}
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.Write([]byte("private: true\n"))
+			case "/login":
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte("<html>please log in</html>"))
+			case "/cached.yaml":
+				requests++
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("ETag", `"v1"`)
+				w.Header().Set("Content-Type", "application/yaml")
+				w.Write([]byte("replicaCount: 4\n"))
+			case "/huge.yaml":
+				w.Header().Set("Content-Type", "application/yaml")
+				w.Write([]byte("blob: " + strings.Repeat("x", maxValuesURLSize) + "\n"))
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		DeferCleanup(server.Close)
+
+		reconciler = &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "values-token", Namespace: "default"},
+					Data:       map[string][]byte{"token": []byte("s3cret")},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "values-basic", Namespace: "default"},
+					Data:       map[string][]byte{"username": []byte("ci"), "password": []byte("hunter2")},
+				},
+			).Build(),
+			HTTPClient:     server.Client(),
+			ValuesURLHosts: []string{"127.0.0.1"},
+		}
+	})
+
+	It("merges YAML values fetched from a URL below the spec values", func() {
+		ad := newDeployment(urlRef("/values.yaml"))
+		ad.Spec.Values = &apiextensionsv1.JSON{Raw: []byte(`{"replicaCount":3}`)}
+
+		resolved, err := reconciler.getValues(ctx, ad)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved.values).To(HaveKeyWithValue("replicaCount", float64(3)))
+		Expect(resolved.values).To(HaveKeyWithValue("auth", map[string]interface{}{"database": "app"}))
+	})
+
+	It("authenticates with a bearer token from a Secret", func() {
+		ref := urlRef("/private.yaml")
+		ref.AuthSecretRef = "values-token"
+
+		resolved, err := reconciler.getValues(ctx, newDeployment(ref))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved.values).To(HaveKeyWithValue("private", true))
+	})
+
+	It("authenticates with basic auth from a Secret", func() {
+		ref := urlRef("/private.yaml")
+		ref.AuthSecretRef = "values-basic"
+
+		resolved, err := reconciler.getValues(ctx, newDeployment(ref))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved.values).To(HaveKeyWithValue("private", true))
+	})
+
+	It("fails on an unsuccessful response", func() {
+		_, err := reconciler.getValues(ctx, newDeployment(urlRef("/private.yaml")))
+		Expect(err).To(MatchError(ContainSubstring("401 Unauthorized")))
+	})
+
+	It("rejects unexpected content types", func() {
+		_, err := reconciler.getValues(ctx, newDeployment(urlRef("/login")))
+		Expect(err).To(MatchError(ContainSubstring(`unexpected content type "text/html"`)))
+	})
+
+	It("rejects values larger than the size limit", func() {
+		_, err := reconciler.getValues(ctx, newDeployment(urlRef("/huge.yaml")))
+		Expect(err).To(MatchError(ContainSubstring("exceed")))
+	})
+
+	It("skips an optional URL that cannot be fetched", func() {
+		missing := urlRef("/missing.yaml")
+		missing.Optional = true
+
+		resolved, err := reconciler.getValues(ctx, newDeployment(missing, urlRef("/values.yaml")))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved.values).To(HaveKeyWithValue("replicaCount", float64(2)))
+	})
+
+	It("only downloads values again when their ETag changed", func() {
+		for range 2 {
+			resolved, err := reconciler.getValues(ctx, newDeployment(urlRef("/cached.yaml")))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resolved.values).To(HaveKeyWithValue("replicaCount", float64(4)))
+		}
+		Expect(requests).To(Equal(2))
+		Expect(reconciler.valuesURLs.entries).To(HaveLen(1))
+	})
+
+	It("rejects hosts that aren't allowed", func() {
+		reconciler.ValuesURLHosts = []string{"*.example.com"}
+		_, err := reconciler.getValues(ctx, newDeployment(urlRef("/values.yaml")))
+		Expect(err).To(MatchError(ContainSubstring(`host "127.0.0.1" is not allowed`)))
+
+		reconciler.ValuesURLHosts = nil
+		_, err = reconciler.getValues(ctx, newDeployment(urlRef("/values.yaml")))
+		Expect(err).To(MatchError(ContainSubstring("values from URLs are not enabled")))
+	})
+
+	It("doesn't connect to internal addresses by default", func() {
+		reconciler.HTTPClient = nil
+		_, err := reconciler.getValues(ctx, newDeployment(urlRef("/values.yaml")))
+		Expect(err).To(MatchError(ContainSubstring("connecting to 127.0.0.1 is not allowed")))
+	})
+})
@@ -0,0 +1,146 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/sops"
+	helmvalues "appstore/shared/values"
+)
+
+// defaultValuesKey is read from a ConfigMap or Secret reference without keys
+const defaultValuesKey = "values.yaml"
+
+// valuesFromOutcome is the result of resolving a single ValuesFrom reference
+type valuesFromOutcome struct {
+	// ref describes the reference, see describeValuesReference
+	ref      string
+	optional bool
+	// err is why the reference couldn't be resolved (nil if it was)
+	err error
+}
+
+// valuesFromError is returned when required ValuesFrom references can't be resolved. It
+// lists the outcome of every reference, so that users can tell which ones to fix.
+type valuesFromError struct {
+	outcomes []valuesFromOutcome
+}
+
+// newValuesFromError returns a valuesFromError if any required reference failed, or nil
+func newValuesFromError(outcomes []valuesFromOutcome) error {
+	for _, outcome := range outcomes {
+		if outcome.err != nil && !outcome.optional {
+			return &valuesFromError{outcomes: outcomes}
+		}
+	}
+	return nil
+}
+
+func (e *valuesFromError) Error() string {
+	var failed, resolved, skipped []string
+	for _, outcome := range e.outcomes {
+		switch {
+		case outcome.err == nil:
+			resolved = append(resolved, outcome.ref)
+		case outcome.optional:
+			skipped = append(skipped, fmt.Sprintf("%s (%v)", outcome.ref, outcome.err))
+		default:
+			failed = append(failed, fmt.Sprintf("%s (%v)", outcome.ref, outcome.err))
+		}
+	}
+
+	msg := "failed to get values from " + strings.Join(failed, ", ")
+	if len(resolved) > 0 {
+		msg += "; resolved " + strings.Join(resolved, ", ")
+	}
+	if len(skipped) > 0 {
+		msg += "; skipped optional " + strings.Join(skipped, ", ")
+	}
+	return msg
+}
+
+// valuesReferenceKeys returns the keys of a ConfigMap or Secret reference to read, in order
+func valuesReferenceKeys(ref appstorev1alpha1.ValuesReference, data map[string][]byte) ([]string, error) {
+	switch {
+	case ref.AllKeys && len(ref.ValuesKeys) > 0:
+		return nil, errors.New("allKeys and valuesKeys are mutually exclusive")
+	case ref.AllKeys:
+		keys := make([]string, 0, len(data))
+		for key := range data {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		return keys, nil
+	case len(ref.ValuesKeys) > 0:
+		return ref.ValuesKeys, nil
+	case ref.ValuesKey != "":
+		return []string{ref.ValuesKey}, nil
+	default:
+		return []string{defaultValuesKey}, nil
+	}
+}
+
+// valuesFromKeys parses the values YAML in the referenced keys of a ConfigMap's or Secret's
+// data, decrypting SOPS-encrypted keys with identities, and merges each key's values over
+// those of the previous keys. It reports whether any key was encrypted.
+func valuesFromKeys(ref appstorev1alpha1.ValuesReference, data map[string][]byte, identities []*sops.Identity) (map[string]interface{}, bool, error) {
+	keys, err := valuesReferenceKeys(ref, data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	values := make(map[string]interface{})
+	encrypted := false
+	for _, key := range keys {
+		raw, ok := data[key]
+		if !ok {
+			return nil, false, fmt.Errorf("key %s not found in %s %s", key, ref.Kind, ref.Name)
+		}
+		var keyValues map[string]interface{}
+		if err := yaml.Unmarshal(raw, &keyValues); err != nil {
+			return nil, false, fmt.Errorf("failed to unmarshal values from key %s: %w", key, err)
+		}
+		if sops.IsEncrypted(keyValues) {
+			if keyValues, err = decryptValues(raw, identities); err != nil {
+				return nil, false, fmt.Errorf("key %s: %w", key, err)
+			}
+			encrypted = true
+		}
+		values = helmvalues.Merge(values, keyValues)
+	}
+	return values, encrypted, nil
+}
+
+// decryptValues decrypts a SOPS-encrypted values document. Its errors never include
+// decrypted values.
+func decryptValues(data []byte, identities []*sops.Identity) (map[string]interface{}, error) {
+	if len(identities) == 0 {
+		return nil, errors.New("values are encrypted with SOPS, but no age key is configured")
+	}
+	decrypted, err := sops.Decrypt(data, identities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt values: %w", err)
+	}
+	return decrypted, nil
+}
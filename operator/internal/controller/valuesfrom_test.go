@@ -0,0 +1,349 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/sops"
+)
+
+var _ = Describe("ValuesFrom outcomes", func() {
+	var (
+		ctx        context.Context
+		fakeHelm   *fakeHelmClient
+		reconciler *AppDeploymentReconciler
+	)
+
+	present := appstorev1alpha1.ValuesReference{Kind: "ConfigMap", Name: "base"}
+	missingOptional := appstorev1alpha1.ValuesReference{Kind: "ConfigMap", Name: "overrides", Optional: true}
+	missingRequired := appstorev1alpha1.ValuesReference{Kind: "ConfigMap", Name: "app-config"}
+	missingKey := appstorev1alpha1.ValuesReference{Kind: "Secret", Name: "credentials", ValuesKey: "extra.yaml"}
+
+	reconcileHelm := func(refs ...appstorev1alpha1.ValuesReference) *appstorev1alpha1.AppDeployment {
+		ad := &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec: appstorev1alpha1.AppDeploymentSpec{
+				AppName:    "postgresql",
+				TeamID:     "team-a",
+				ValuesFrom: refs,
+			},
+		}
+		reconciler = &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).
+				WithObjects(ad,
+					&corev1.ConfigMap{
+						ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"},
+						Data:       map[string]string{"values.yaml": `{"replicaCount":2}`},
+					},
+					&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "default"},
+						Data:       map[string][]byte{"values.yaml": []byte(`{}`)},
+					},
+				).
+				WithStatusSubresource(ad).Build(),
+			HelmClient: fakeHelm,
+		}
+
+		_, err := reconciler.reconcileHelm(ctx, ad)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		return ad
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{}
+	})
+
+	It("deploys when only optional references are missing", func() {
+		ad := reconcileHelm(present, missingOptional)
+
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+		Expect(fakeHelm.callsTo("Install")).To(HaveLen(1))
+		Expect(fakeHelm.Calls[0].Values).To(HaveKeyWithValue("replicaCount", float64(2)))
+	})
+
+	It("lists the outcome of every reference when required ones fail", func() {
+		ad := reconcileHelm(present, missingOptional, missingRequired, missingKey)
+
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseFailed))
+		Expect(fakeHelm.Calls).To(BeEmpty())
+
+		ready := meta.FindStatusCondition(ad.Status.Conditions, ConditionTypeReady)
+		Expect(ready).NotTo(BeNil())
+		Expect(ready.Reason).To(Equal("ValuesFromFailed"))
+		Expect(ready.Message).To(Equal(
+			`Failed to get values: failed to get values from ConfigMap/app-config (configmaps "app-config" not found), ` +
+				`Secret/credentials (key extra.yaml not found in Secret credentials); ` +
+				`resolved ConfigMap/base; ` +
+				`skipped optional ConfigMap/overrides (configmaps "overrides" not found)`))
+		Expect(ad.Status.Message).To(Equal(ready.Message))
+	})
+
+	It("omits empty groups from the message", func() {
+		ad := reconcileHelm(missingRequired)
+
+		Expect(ad.Status.Message).To(Equal(`Failed to get values: failed to get values from ConfigMap/app-config (configmaps "app-config" not found)`))
+	})
+})
+
+var _ = Describe("Values from Secrets", func() {
+	var (
+		ctx        context.Context
+		reconciler *AppDeploymentReconciler
+		secret     *corev1.Secret
+	)
+
+	ad := &appstorev1alpha1.AppDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec: appstorev1alpha1.AppDeploymentSpec{
+			AppName: "postgresql",
+			TeamID:  "team-a",
+			ValuesFrom: []appstorev1alpha1.ValuesReference{
+				{Kind: "ConfigMap", Name: "pg-config"},
+				{Kind: "Secret", Name: "pg-values"},
+			},
+			SecretKeyRefs: []appstorev1alpha1.SecretKeyRef{
+				{Path: "metrics.token", Name: "pg-token", Key: "token"},
+			},
+		},
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "pg-values", Namespace: "default"},
+			Data:       map[string][]byte{"values.yaml": []byte("auth:\n  password: s3cr3t\n")},
+		}
+		reconciler = &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "pg-config", Namespace: "default"},
+					Data:       map[string]string{"values.yaml": "auth:\n  username: app\n"},
+				},
+				secret,
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "pg-token", Namespace: "default"},
+					Data:       map[string][]byte{"token": []byte("t0k3n")},
+				},
+			).Build(),
+		}
+	})
+
+	It("passes them to Helm and keeps them out of the hashed values", func() {
+		resolved, err := reconciler.getValues(ctx, ad)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved.values).To(Equal(map[string]interface{}{
+			"auth":    map[string]interface{}{"username": "app", "password": "s3cr3t"},
+			"metrics": map[string]interface{}{"token": "t0k3n"},
+		}))
+
+		Expect(resolved.redacted).To(Equal(map[string]interface{}{
+			"auth": map[string]interface{}{
+				"username": "app",
+				"password": "<redacted:pg-values@" + secretVersion(ctx, reconciler.Client, "pg-values") + ">",
+			},
+			"metrics": map[string]interface{}{
+				"token": "<redacted:pg-token/token@" + secretVersion(ctx, reconciler.Client, "pg-token") + ">",
+			},
+		}))
+		for _, redacted := range []map[string]interface{}{resolved.redacted, resolved.snapshot} {
+			data, err := json.Marshal(redacted)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).NotTo(ContainSubstring("s3cr3t"))
+			Expect(string(data)).NotTo(ContainSubstring("t0k3n"))
+		}
+	})
+
+	It("changes the values hash when a Secret changes", func() {
+		before, err := reconciler.getValues(ctx, ad)
+		Expect(err).NotTo(HaveOccurred())
+
+		secret.Data["values.yaml"] = []byte("auth:\n  password: rotated\n")
+		Expect(reconciler.Update(ctx, secret)).To(Succeed())
+
+		after, err := reconciler.getValues(ctx, ad)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(after.values["auth"]).To(HaveKeyWithValue("password", "rotated"))
+		Expect(releaseHash(ad, after.redacted)).NotTo(Equal(releaseHash(ad, before.redacted)))
+		Expect(after.snapshot).To(Equal(before.snapshot))
+	})
+})
+
+var _ = Describe("ValuesFrom keys", func() {
+	data := map[string][]byte{
+		"values.yaml":  []byte("replicaCount: 1\nauth:\n  username: app\n"),
+		"10-tls.yaml":  []byte("tls:\n  cert: |\n    -----BEGIN CERTIFICATE-----\n"),
+		"20-prod.yaml": []byte("replicaCount: 3\nauth:\n  username: prod\n  database: prod\n"),
+		"30-trim.json": []byte(`{"auth":{"database":null}}`),
+	}
+	ref := func(mutate func(*appstorev1alpha1.ValuesReference)) appstorev1alpha1.ValuesReference {
+		r := appstorev1alpha1.ValuesReference{Kind: "Secret", Name: "pg-values", ValuesKey: "values.yaml"}
+		mutate(&r)
+		return r
+	}
+
+	It("reads the values key by default", func() {
+		values, _, err := valuesFromKeys(ref(func(r *appstorev1alpha1.ValuesReference) { r.ValuesKey = "" }), data, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(Equal(map[string]interface{}{
+			"replicaCount": float64(1),
+			"auth":         map[string]interface{}{"username": "app"},
+		}))
+	})
+
+	It("merges valuesKeys in the given order", func() {
+		values, _, err := valuesFromKeys(ref(func(r *appstorev1alpha1.ValuesReference) {
+			r.ValuesKeys = []string{"20-prod.yaml", "values.yaml", "10-tls.yaml"}
+		}), data, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(Equal(map[string]interface{}{
+			"replicaCount": float64(1),
+			"auth":         map[string]interface{}{"username": "app", "database": "prod"},
+			"tls":          map[string]interface{}{"cert": "-----BEGIN CERTIFICATE-----\n"},
+		}))
+	})
+
+	It("merges all keys in the order of their names", func() {
+		values, _, err := valuesFromKeys(ref(func(r *appstorev1alpha1.ValuesReference) { r.AllKeys = true }), data, nil)
+		Expect(err).NotTo(HaveOccurred())
+		// values.yaml sorts last, after 30-trim.json unset the database
+		Expect(values).To(Equal(map[string]interface{}{
+			"replicaCount": float64(1),
+			"auth":         map[string]interface{}{"username": "app", "database": nil},
+			"tls":          map[string]interface{}{"cert": "-----BEGIN CERTIFICATE-----\n"},
+		}))
+	})
+
+	It("fails on a missing or invalid key", func() {
+		_, _, err := valuesFromKeys(ref(func(r *appstorev1alpha1.ValuesReference) {
+			r.ValuesKeys = []string{"values.yaml", "missing.yaml"}
+		}), data, nil)
+		Expect(err).To(MatchError("key missing.yaml not found in Secret pg-values"))
+
+		_, _, err = valuesFromKeys(ref(func(r *appstorev1alpha1.ValuesReference) { r.AllKeys = true }),
+			map[string][]byte{"a.yaml": []byte("a: 1"), "b.yaml": []byte("- not a map")}, nil)
+		Expect(err).To(MatchError(ContainSubstring("failed to unmarshal values from key b.yaml")))
+	})
+
+	It("rejects allKeys combined with valuesKeys", func() {
+		_, _, err := valuesFromKeys(ref(func(r *appstorev1alpha1.ValuesReference) {
+			r.AllKeys = true
+			r.ValuesKeys = []string{"values.yaml"}
+		}), data, nil)
+		Expect(err).To(MatchError(ContainSubstring("mutually exclusive")))
+	})
+
+	It("reads binary data of a ConfigMap", func() {
+		ad := &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec: appstorev1alpha1.AppDeploymentSpec{
+				AppName: "postgresql",
+				TeamID:  "team-a",
+				ValuesFrom: []appstorev1alpha1.ValuesReference{
+					{Kind: "ConfigMap", Name: "pg-config", ValuesKeys: []string{"values.yaml", "tls.yaml"}},
+				},
+			},
+		}
+		reconciler := &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg-config", Namespace: "default"},
+				Data:       map[string]string{"values.yaml": "replicaCount: 2\n"},
+				BinaryData: map[string][]byte{"tls.yaml": []byte("tls:\n  enabled: true\n")},
+			}).Build(),
+		}
+
+		resolved, err := reconciler.getValues(context.Background(), ad)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved.values).To(Equal(map[string]interface{}{
+			"replicaCount": float64(2),
+			"tls":          map[string]interface{}{"enabled": true},
+		}))
+	})
+})
+
+var _ = Describe("SOPS-encrypted valuesFrom", func() {
+	readTestdata := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join("..", "sops", "testdata", name))
+		Expect(err).NotTo(HaveOccurred())
+		return data
+	}
+
+	getValues := func(identities []*sops.Identity) (*deploymentValues, error) {
+		ad := &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec: appstorev1alpha1.AppDeploymentSpec{
+				AppName: "postgresql",
+				TeamID:  "team-a",
+				ValuesFrom: []appstorev1alpha1.ValuesReference{
+					{Kind: "ConfigMap", Name: "pg-config", ValuesKeys: []string{"values.yaml", "secrets.enc.yaml"}},
+				},
+			},
+		}
+		reconciler := &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg-config", Namespace: "default"},
+				Data: map[string]string{
+					"values.yaml":      "replicaCount: 1\nauth:\n  database: app\n",
+					"secrets.enc.yaml": string(readTestdata("values.enc.yaml")),
+				},
+			}).Build(),
+			SOPSIdentities: identities,
+		}
+		return reconciler.getValues(context.Background(), ad)
+	}
+
+	It("decrypts encrypted keys before merging them", func() {
+		identities, err := sops.ParseIdentities(readTestdata("age.key"))
+		Expect(err).NotTo(HaveOccurred())
+
+		resolved, err := getValues(identities)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved.values).To(Equal(map[string]interface{}{
+			"replicaCount":        float64(2),
+			"auth":                map[string]interface{}{"username": "app", "password": "s3cr3t", "enabled": true, "database": "app"},
+			"hosts":               []interface{}{"db-0.example.com", "db-1.example.com"},
+			"metrics_unencrypted": true,
+		}))
+		// Decrypted values are kept out of the status
+		Expect(resolved.snapshot["auth"]).To(HaveKeyWithValue("password", "<redacted:pg-config>"))
+	})
+
+	It("fails without a matching age key", func() {
+		_, err := getValues(nil)
+		Expect(err).To(MatchError(ContainSubstring("key secrets.enc.yaml: values are encrypted with SOPS, but no age key is configured")))
+
+		other, err := sops.ParseIdentities([]byte("AGE-SECRET-KEY-19M0JVCD6KKWPH2848FJ6TGJFJSPLLX3WNPNFVT28R07ZNXW0MEWQGAMTEW"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = getValues(other)
+		Expect(err).To(MatchError(ContainSubstring("no age identity can decrypt the data key")))
+	})
+})
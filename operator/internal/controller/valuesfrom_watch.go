@@ -0,0 +1,77 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+// valuesFromHandler enqueues the AppDeployments referencing a changed ConfigMap or Secret
+// of the given kind in spec.valuesFrom, or using a changed ConfigMap as the values of their
+// spec.environment, so that they are reconciled with the new values right away rather than
+// on their next periodic reconcile.
+func (r *AppDeploymentReconciler) valuesFromHandler(kind string) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		return r.valuesFromRequests(ctx, kind, obj.GetNamespace(), obj.GetName())
+	})
+}
+
+// valuesFromRequests returns reconcile requests for the AppDeployments in a namespace
+// that reference the named ConfigMap or Secret in spec.valuesFrom or, for a ConfigMap,
+// whose environment's values it holds
+func (r *AppDeploymentReconciler) valuesFromRequests(ctx context.Context, kind, namespace, name string) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	var list appstorev1alpha1.AppDeploymentList
+	if err := r.List(ctx, &list, client.InNamespace(namespace)); err != nil {
+		logger.Error(err, "Failed to list AppDeployments for changed values", "kind", kind, "namespace", namespace, "name", name)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, ad := range list.Items {
+		if usesValues(&ad, kind, name) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: ad.Namespace, Name: ad.Name}})
+		}
+	}
+	if len(requests) > 0 {
+		logger.Info("Values changed, reconciling referencing AppDeployments", "kind", kind, "namespace", namespace, "name", name, "deployments", len(requests))
+	}
+	return requests
+}
+
+// usesValues reports whether the AppDeployment gets values from the named ConfigMap or
+// Secret in its namespace
+func usesValues(ad *appstorev1alpha1.AppDeployment, kind, name string) bool {
+	if kind == "ConfigMap" && ad.Spec.Environment != "" && environmentConfigMapName(ad.Spec.AppName, ad.Spec.Environment) == name {
+		return true
+	}
+	for _, ref := range ad.Spec.ValuesFrom {
+		if ref.Kind == kind && ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
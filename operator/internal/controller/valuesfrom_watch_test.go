@@ -0,0 +1,117 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+var _ = Describe("ValuesFrom watches", func() {
+	var (
+		ctx        context.Context
+		reconciler *AppDeploymentReconciler
+		queue      workqueue.TypedRateLimitingInterface[reconcile.Request]
+	)
+
+	newAppDeployment := func(namespace, name string, refs ...appstorev1alpha1.ValuesReference) *appstorev1alpha1.AppDeployment {
+		return &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       appstorev1alpha1.AppDeploymentSpec{AppName: "postgresql", TeamID: "team-a", ValuesFrom: refs},
+		}
+	}
+
+	request := func(namespace, name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}
+	}
+
+	queued := func() []reconcile.Request {
+		var requests []reconcile.Request
+		for queue.Len() > 0 {
+			req, _ := queue.Get()
+			requests = append(requests, req)
+			queue.Done(req)
+		}
+		return requests
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		shared := appstorev1alpha1.ValuesReference{Kind: "ConfigMap", Name: "shared-values"}
+		reconciler = &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(
+					newAppDeployment("default", "db", shared),
+					newAppDeployment("default", "cache",
+						appstorev1alpha1.ValuesReference{Kind: "Secret", Name: "shared-values"},
+						appstorev1alpha1.ValuesReference{Kind: "URL", URL: "https://example.com/values.yaml"}),
+					newAppDeployment("default", "queue"),
+					&appstorev1alpha1.AppDeployment{
+						ObjectMeta: metav1.ObjectMeta{Name: "search", Namespace: "default"},
+						Spec:       appstorev1alpha1.AppDeploymentSpec{AppName: "elasticsearch", TeamID: "team-a", Environment: "prod"},
+					},
+					newAppDeployment("team-b", "db", shared),
+				).
+				Build(),
+		}
+		queue = workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+		DeferCleanup(queue.ShutDown)
+	})
+
+	It("enqueues the deployments referencing an updated ConfigMap", func() {
+		old := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared-values", Namespace: "default", ResourceVersion: "1"},
+			Data:       map[string]string{"values.yaml": "replicaCount: 1\n"},
+		}
+		updated := old.DeepCopy()
+		updated.ResourceVersion = "2"
+		updated.Data["values.yaml"] = "replicaCount: 3\n"
+
+		reconciler.valuesFromHandler("ConfigMap").Update(ctx, event.UpdateEvent{ObjectOld: old, ObjectNew: updated}, queue)
+		Expect(queued()).To(ConsistOf(request("default", "db")))
+	})
+
+	It("enqueues the deployments referencing a Secret by kind", func() {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "shared-values", Namespace: "default"}}
+		reconciler.valuesFromHandler("Secret").Create(ctx, event.CreateEvent{Object: secret}, queue)
+		Expect(queued()).To(ConsistOf(request("default", "cache")))
+	})
+
+	It("enqueues the deployments using an updated environment ConfigMap", func() {
+		Expect(reconciler.valuesFromRequests(ctx, "ConfigMap", "default", "elasticsearch-values-prod")).To(ConsistOf(request("default", "search")))
+		Expect(reconciler.valuesFromRequests(ctx, "Secret", "default", "elasticsearch-values-prod")).To(BeEmpty())
+		Expect(reconciler.valuesFromRequests(ctx, "ConfigMap", "default", "elasticsearch-values-dev")).To(BeEmpty())
+	})
+
+	It("ignores ConfigMaps no deployment references", func() {
+		Expect(reconciler.valuesFromRequests(ctx, "ConfigMap", "default", "other-values")).To(BeEmpty())
+		Expect(reconciler.valuesFromRequests(ctx, "ConfigMap", "team-c", "shared-values")).To(BeEmpty())
+	})
+})
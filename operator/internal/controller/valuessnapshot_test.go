@@ -0,0 +1,140 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+var _ = Describe("Values snapshots", func() {
+	var (
+		ctx        context.Context
+		fakeHelm   *fakeHelmClient
+		reconciler *AppDeploymentReconciler
+		ad         *appstorev1alpha1.AppDeployment
+	)
+
+	reconcileHelm := func() {
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		_, err := reconciler.reconcileHelm(ctx, ad)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+	}
+
+	setValues := func(values string) {
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+		ad.Spec.Values = &apiextensionsv1.JSON{Raw: []byte(values)}
+		Expect(reconciler.Update(ctx, ad)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeHelm = &fakeHelmClient{}
+		ad = &appstorev1alpha1.AppDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec: appstorev1alpha1.AppDeploymentSpec{
+				AppName: "postgresql",
+				TeamID:  "team-a",
+				Values:  &apiextensionsv1.JSON{Raw: []byte(`{"replicaCount":1}`)},
+				ValuesFrom: []appstorev1alpha1.ValuesReference{
+					{Kind: "Secret", Name: "pg-values"},
+				},
+				SecretKeyRefs: []appstorev1alpha1.SecretKeyRef{
+					{Path: "auth.postgresPassword", Name: "pg-credentials", Key: "password"},
+				},
+			},
+		}
+		reconciler = &AppDeploymentReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).
+				WithObjects(ad,
+					&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{Name: "pg-values", Namespace: "default"},
+						Data:       map[string][]byte{"values.yaml": []byte(`{"auth":{"username":"app","password":"from-values"}}`)},
+					},
+					&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{Name: "pg-credentials", Namespace: "default"},
+						Data:       map[string][]byte{"password": []byte("from-key")},
+					},
+				).
+				WithStatusSubresource(ad).Build(),
+			HelmClient: fakeHelm,
+		}
+	})
+
+	It("records the applied and attempted values on success, with secret values redacted", func() {
+		reconcileHelm()
+
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+		Expect(ad.Status.LastAppliedValues).NotTo(BeNil())
+		Expect(ad.Status.LastAppliedValues.Raw).To(MatchJSON(`{
+			"replicaCount": 1,
+			"auth": {
+				"username": "<redacted:pg-values>",
+				"password": "<redacted:pg-values>",
+				"postgresPassword": "<redacted:pg-credentials/password@` + secretVersion(ctx, reconciler.Client, "pg-credentials") + `>"
+			}
+		}`))
+		Expect(ad.Status.LastAttemptedValues).To(Equal(ad.Status.LastAppliedValues))
+		Expect(ad.Status.LastAttemptedValuesHash).To(Equal(ad.Status.LastAppliedValuesHash))
+		Expect(string(ad.Status.LastAppliedValues.Raw)).NotTo(ContainSubstring("from-"))
+
+		Expect(fakeHelm.Calls[0].Values).To(HaveKeyWithValue("auth", map[string]interface{}{
+			"username": "app", "password": "from-values", "postgresPassword": "from-key",
+		}))
+	})
+
+	It("only records the attempted values when applying them fails", func() {
+		reconcileHelm()
+		applied := ad.Status.LastAppliedValues.DeepCopy()
+		appliedHash := ad.Status.LastAppliedValuesHash
+
+		fakeHelm.UpgradeErrs = []error{errors.New("upgrade failed")}
+		setValues(`{"replicaCount":3}`)
+		reconcileHelm()
+
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseFailed))
+		Expect(ad.Status.LastAppliedValues).To(Equal(applied))
+		Expect(ad.Status.LastAppliedValuesHash).To(Equal(appliedHash))
+		Expect(ad.Status.LastAttemptedValues.Raw).To(ContainSubstring(`"replicaCount":3`))
+		Expect(ad.Status.LastAttemptedValuesHash).NotTo(Equal(appliedHash))
+
+		By("recording both once the values apply")
+		reconcileHelm()
+		Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+		Expect(ad.Status.LastAppliedValues).To(Equal(ad.Status.LastAttemptedValues))
+		Expect(ad.Status.LastAppliedValuesHash).To(Equal(ad.Status.LastAttemptedValuesHash))
+	})
+})
+
+// secretVersion returns the resource version of a Secret in the default namespace
+func secretVersion(ctx context.Context, c client.Client, name string) string {
+	secret := &corev1.Secret{}
+	Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, secret)).To(Succeed())
+	return secret.ResourceVersion
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// upgradeWindowWait returns how long until the upgrade window opens, or zero if it is open now
+func upgradeWindowWait(window *appstorev1alpha1.UpgradeWindow, now time.Time) (time.Duration, error) {
+	if window == nil {
+		return 0, nil
+	}
+
+	loc := time.UTC
+	if window.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(window.Timezone); err != nil {
+			return 0, fmt.Errorf("invalid upgrade window timezone %q: %w", window.Timezone, err)
+		}
+	}
+
+	start, err := parseTimeOfDay(window.Start)
+	if err != nil {
+		return 0, fmt.Errorf("invalid upgrade window start: %w", err)
+	}
+	end, err := parseTimeOfDay(window.End)
+	if err != nil {
+		return 0, fmt.Errorf("invalid upgrade window end: %w", err)
+	}
+
+	days := make(map[time.Weekday]bool, len(window.Days))
+	for _, d := range window.Days {
+		weekday, ok := weekdays[strings.ToLower(d)]
+		if !ok {
+			return 0, fmt.Errorf("invalid upgrade window day %q", d)
+		}
+		days[weekday] = true
+	}
+
+	// Check the windows opening from yesterday (which may run past midnight) through next week
+	now = now.In(loc)
+	var wait time.Duration
+	for offset := -1; offset <= 7; offset++ {
+		opens := time.Date(now.Year(), now.Month(), now.Day()+offset, 0, start, 0, 0, loc)
+		if len(days) > 0 && !days[opens.Weekday()] {
+			continue
+		}
+
+		closes := time.Date(opens.Year(), opens.Month(), opens.Day(), 0, end, 0, 0, loc)
+		if end <= start {
+			closes = closes.AddDate(0, 0, 1)
+		}
+
+		if !now.Before(opens) && now.Before(closes) {
+			return 0, nil
+		}
+		if opens.After(now) && (wait == 0 || opens.Sub(now) < wait) {
+			wait = opens.Sub(now)
+		}
+	}
+
+	return wait, nil
+}
+
+// parseTimeOfDay parses an HH:MM time of day into minutes since midnight
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not in HH:MM format", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstorev1alpha1 "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/helm"
+)
+
+var _ = Describe("Upgrade windows", func() {
+	// 2026-03-04 is a Wednesday
+	at := func(value string) time.Time {
+		t, err := time.Parse(time.RFC3339, value)
+		Expect(err).NotTo(HaveOccurred())
+		return t
+	}
+
+	Context("upgradeWindowWait", func() {
+		It("is always open without a window", func() {
+			Expect(upgradeWindowWait(nil, at("2026-03-04T12:00:00Z"))).To(BeZero())
+		})
+
+		It("is open inside a daily window", func() {
+			window := &appstorev1alpha1.UpgradeWindow{Start: "02:00", End: "04:00"}
+			Expect(upgradeWindowWait(window, at("2026-03-04T02:00:00Z"))).To(BeZero())
+			Expect(upgradeWindowWait(window, at("2026-03-04T03:59:00Z"))).To(BeZero())
+		})
+
+		It("waits for the next opening outside a daily window", func() {
+			window := &appstorev1alpha1.UpgradeWindow{Start: "02:00", End: "04:00"}
+			Expect(upgradeWindowWait(window, at("2026-03-04T01:30:00Z"))).To(Equal(30 * time.Minute))
+			Expect(upgradeWindowWait(window, at("2026-03-04T04:00:00Z"))).To(Equal(22 * time.Hour))
+		})
+
+		It("handles windows that run past midnight", func() {
+			window := &appstorev1alpha1.UpgradeWindow{Days: []string{"Sat"}, Start: "22:00", End: "02:00"}
+			Expect(upgradeWindowWait(window, at("2026-03-08T01:00:00Z"))).To(BeZero())
+			Expect(upgradeWindowWait(window, at("2026-03-08T02:00:00Z"))).To(Equal(6*24*time.Hour + 20*time.Hour))
+		})
+
+		It("only opens on the listed days", func() {
+			window := &appstorev1alpha1.UpgradeWindow{Days: []string{"Sat", "sun"}, Start: "02:00", End: "04:00"}
+			Expect(upgradeWindowWait(window, at("2026-03-04T03:00:00Z"))).To(Equal(2*24*time.Hour + 23*time.Hour))
+			Expect(upgradeWindowWait(window, at("2026-03-08T03:00:00Z"))).To(BeZero())
+		})
+
+		It("evaluates the window in its timezone", func() {
+			window := &appstorev1alpha1.UpgradeWindow{Start: "02:00", End: "04:00", Timezone: "Europe/Oslo"}
+			Expect(upgradeWindowWait(window, at("2026-03-04T01:30:00Z"))).To(BeZero())
+			Expect(upgradeWindowWait(window, at("2026-03-04T03:30:00Z"))).To(Equal(21*time.Hour + 30*time.Minute))
+		})
+
+		It("rejects invalid windows", func() {
+			now := at("2026-03-04T12:00:00Z")
+			_, err := upgradeWindowWait(&appstorev1alpha1.UpgradeWindow{Start: "2am", End: "04:00"}, now)
+			Expect(err).To(MatchError(ContainSubstring("invalid upgrade window start")))
+			_, err = upgradeWindowWait(&appstorev1alpha1.UpgradeWindow{Days: []string{"Funday"}, Start: "02:00", End: "04:00"}, now)
+			Expect(err).To(MatchError(ContainSubstring("invalid upgrade window day")))
+			_, err = upgradeWindowWait(&appstorev1alpha1.UpgradeWindow{Start: "02:00", End: "04:00", Timezone: "Mars/Base"}, now)
+			Expect(err).To(MatchError(ContainSubstring("invalid upgrade window timezone")))
+		})
+	})
+
+	Context("when reconciling", func() {
+		var (
+			ctx        context.Context
+			fakeHelm   *fakeHelmClient
+			fakeClock  *clocktesting.FakePassiveClock
+			reconciler *AppDeploymentReconciler
+		)
+
+		newDeployment := func(window *appstorev1alpha1.UpgradeWindow) *appstorev1alpha1.AppDeployment {
+			return &appstorev1alpha1.AppDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+				Spec: appstorev1alpha1.AppDeploymentSpec{
+					AppName:       "postgresql",
+					TeamID:        "team-a",
+					ChartVersion:  "1.1.0",
+					UpgradeWindow: window,
+				},
+			}
+		}
+
+		reconcileHelm := func(ad *appstorev1alpha1.AppDeployment) time.Duration {
+			reconciler.Client = fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(ad).
+				WithStatusSubresource(ad).
+				Build()
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(ad), ad)).To(Succeed())
+
+			result, err := reconciler.reconcileHelm(ctx, ad)
+			Expect(err).NotTo(HaveOccurred())
+			return result.RequeueAfter
+		}
+
+		window := &appstorev1alpha1.UpgradeWindow{Start: "02:00", End: "04:00"}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			fakeHelm = &fakeHelmClient{Releases: releasesOf(&helm.ReleaseInfo{
+				Name: "db", Namespace: "default", Revision: 1, Status: releaseStatusDeployed, ChartVersion: "1.0.0",
+			})}
+			fakeClock = clocktesting.NewFakePassiveClock(at("2026-03-04T12:00:00Z"))
+			reconciler = &AppDeploymentReconciler{HelmClient: fakeHelm, Clock: fakeClock}
+		})
+
+		It("defers an upgrade outside the window", func() {
+			ad := newDeployment(window)
+
+			Expect(reconcileHelm(ad)).To(Equal(14 * time.Hour))
+			Expect(fakeHelm.callsTo("Upgrade")).To(BeEmpty())
+
+			cond := meta.FindStatusCondition(ad.Status.Conditions, ConditionTypeReconciling)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Reason).To(Equal("WaitingForUpgradeWindow"))
+		})
+
+		It("upgrades inside the window", func() {
+			fakeClock.SetTime(at("2026-03-04T03:00:00Z"))
+			ad := newDeployment(window)
+
+			Expect(reconcileHelm(ad)).To(Equal(requeueAfterSuccess))
+			Expect(fakeHelm.callsTo("Upgrade")).To(HaveLen(1))
+			Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+		})
+
+		It("installs outside the window", func() {
+			delete(fakeHelm.Releases, "db")
+			ad := newDeployment(window)
+
+			Expect(reconcileHelm(ad)).To(Equal(requeueAfterSuccess))
+			Expect(fakeHelm.callsTo("Install")).To(HaveLen(1))
+			Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseDeployed))
+		})
+
+		It("fails on an invalid window", func() {
+			ad := newDeployment(&appstorev1alpha1.UpgradeWindow{Start: "02:00", End: "25:00"})
+
+			Expect(reconcileHelm(ad)).To(Equal(requeueAfterFailure))
+			Expect(fakeHelm.callsTo("Upgrade")).To(BeEmpty())
+			Expect(ad.Status.Phase).To(Equal(appstorev1alpha1.PhaseFailed))
+		})
+	})
+})
@@ -18,17 +18,23 @@ package helm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/go-logr/logr"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/repo"
 	"helm.sh/helm/v3/pkg/storage/driver"
@@ -37,10 +43,35 @@ import (
 
 // Client wraps Helm SDK operations
 type Client struct {
-	settings   *cli.EnvSettings
-	chartsPath string
-	repoURL    string
-	mu         sync.Mutex
+	settings     *cli.EnvSettings
+	chartsPath   string
+	repositories []Repository
+	// pulledPath caches the charts pulled from repositories
+	pulledPath string
+	// releaseLocks holds a *sync.Mutex per release, see lockRelease
+	releaseLocks sync.Map
+	// pullMu serializes pulls
+	pullMu sync.Mutex
+
+	// PostRenderer modifies the manifests of every install, upgrade and render, before the
+	// deployment's common labels and annotations are added (optional)
+	PostRenderer postrender.PostRenderer
+
+	// Resources checks, or sets defaults for, the resource requests and limits of every
+	// container that installs, upgrades and renders produce, after PostRenderer (optional)
+	Resources *ResourcesPolicy
+
+	// Timeout bounds installs, upgrades, rollbacks and uninstalls, including waiting, unless
+	// their options or context set another (DefaultTimeout if zero)
+	Timeout time.Duration
+
+	// Verifier checks the integrity of local charts before they're used (optional)
+	Verifier ChartVerifier
+}
+
+// ChartVerifier verifies that local charts aren't corrupted, see chartsync.Syncer.VerifyChart
+type ChartVerifier interface {
+	VerifyChart(chartName string) error
 }
 
 // ReleaseInfo contains information about a Helm release
@@ -52,27 +83,100 @@ type ReleaseInfo struct {
 	ChartName    string
 	ChartVersion string
 	AppVersion   string
+	KubeVersion  string
 	Updated      time.Time
+	// Labels are the release's custom labels, see ActionOptions.ReleaseLabels
+	Labels map[string]string
+	// Hooks are the release's hooks that ran, in the order Helm ran them
+	Hooks []HookInfo
+}
+
+// HookInfo is the result of the last run of a release hook
+type HookInfo struct {
+	Name   string
+	Kind   string
+	Events []string
+	// Phase is Running, Succeeded, Failed or Unknown
+	Phase       string
+	StartedAt   time.Time
+	CompletedAt time.Time
+}
+
+// ActionOptions tunes how an install or upgrade is performed
+type ActionOptions struct {
+	// Wait waits until all resources are ready before marking the release as successful
+	Wait bool
+	// Atomic rolls back (or uninstalls) the release if the operation fails. Implies Wait.
+	Atomic bool
+	// Timeout bounds the operation, including waiting (defaults to the context's timeout,
+	// see WithActionTimeout, or else the client's)
+	Timeout time.Duration
+	// CleanupOnFail deletes the resources created by a failed upgrade. Installs ignore it.
+	CleanupOnFail bool
+	// DisableHooks skips the chart's hooks
+	DisableHooks bool
+	// SkipCRDs skips installing the CRDs in the chart's crds directory
+	SkipCRDs bool
+	// Labels and Annotations are added to the metadata of every resource the chart renders
+	Labels      map[string]string
+	Annotations map[string]string
+	// ReleaseLabels are stored on the Helm release itself. Upgrades keep labels not set here.
+	ReleaseLabels map[string]string
+	// ReuseValues merges the values of an upgrade over those of the current release instead
+	// of the chart's defaults. Installs ignore it.
+	ReuseValues bool
+}
+
+// timeout returns the configured timeout or the default
+func (o ActionOptions) timeout() time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+	return DefaultTimeout
 }
 
-// NewClient creates a new Helm client
-func NewClient(chartsPath, repoURL string) *Client {
+// NewClient creates a new Helm client using the charts in chartsPath. Charts that aren't
+// there, or not in the requested version, are pulled from the repositories in order.
+func NewClient(chartsPath string, repositories []Repository) *Client {
 	settings := cli.New()
 	return &Client{
-		settings:   settings,
-		chartsPath: chartsPath,
-		repoURL:    repoURL,
+		settings:     settings,
+		chartsPath:   chartsPath,
+		repositories: repositories,
+		pulledPath:   filepath.Join(os.TempDir(), "appstore-pulled-charts"),
 	}
 }
 
-// getActionConfig creates a Helm action configuration for the given namespace
+// releaseKey identifies a release; Cluster is nil for the operator's own cluster
+type releaseKey struct {
+	cluster   *Cluster
+	namespace string
+	name      string
+}
+
+// lockRelease serializes the operations that change a release and returns the function
+// that unlocks it. Operations on other releases, including waiting for them, aren't blocked.
+func (c *Client) lockRelease(ctx context.Context, releaseName, namespace string) func() {
+	key := releaseKey{cluster: ClusterFromContext(ctx), namespace: namespace, name: releaseName}
+	mu, _ := c.releaseLocks.LoadOrStore(key, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// getActionConfig creates a Helm action configuration for the given namespace, in the
+// context's cluster if it has one
 func (c *Client) getActionConfig(ctx context.Context, namespace string) (*action.Configuration, error) {
 	logger := log.FromContext(ctx)
 	actionConfig := new(action.Configuration)
 
 	// Use the in-cluster config by default
+	getter := c.settings.RESTClientGetter()
+	if cluster := ClusterFromContext(ctx); cluster != nil {
+		getter = cluster.getter(namespace)
+	}
+
 	if err := actionConfig.Init(
-		c.settings.RESTClientGetter(),
+		getter,
 		namespace,
 		os.Getenv("HELM_DRIVER"),
 		func(format string, v ...interface{}) {
@@ -86,9 +190,8 @@ func (c *Client) getActionConfig(ctx context.Context, namespace string) (*action
 }
 
 // Install installs a Helm chart
-func (c *Client) Install(ctx context.Context, releaseName, chartName, namespace string, values map[string]interface{}, version string) (*ReleaseInfo, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (c *Client) Install(ctx context.Context, releaseName, chartName, namespace string, values map[string]interface{}, version string, opts ActionOptions) (*ReleaseInfo, error) {
+	defer c.lockRelease(ctx, releaseName, namespace)()
 
 	logger := log.FromContext(ctx).WithValues("release", releaseName, "chart", chartName, "namespace", namespace)
 	logger.Info("Installing Helm chart")
@@ -98,16 +201,9 @@ func (c *Client) Install(ctx context.Context, releaseName, chartName, namespace
 		return nil, err
 	}
 
-	installAction := action.NewInstall(actionConfig)
-	installAction.Namespace = namespace
-	installAction.ReleaseName = releaseName
-	installAction.CreateNamespace = true
-	installAction.Wait = false
-	installAction.Timeout = 5 * time.Minute
-
-	if version != "" {
-		installAction.Version = version
-	}
+	opts.Timeout = c.timeout(ctx, opts.Timeout)
+	installAction := newInstallAction(actionConfig, releaseName, namespace, version, opts)
+	installAction.PostRenderer = chainPostRenderers(c.PostRenderer, c.Resources.postRenderer(logger), installAction.PostRenderer)
 
 	chartPath, err := c.locateChart(ctx, chartName, version, logger)
 	if err != nil {
@@ -121,17 +217,36 @@ func (c *Client) Install(ctx context.Context, releaseName, chartName, namespace
 
 	rel, err := installAction.RunWithContext(ctx, chart, values)
 	if err != nil {
-		return nil, fmt.Errorf("failed to install chart: %w", err)
+		return nil, fmt.Errorf("failed to install chart: %w", operationInProgress(releaseName, err))
 	}
 
 	logger.Info("Chart installed successfully", "revision", rel.Version)
 	return releaseToInfo(rel), nil
 }
 
+// newInstallAction configures an install action
+func newInstallAction(actionConfig *action.Configuration, releaseName, namespace, version string, opts ActionOptions) *action.Install {
+	installAction := action.NewInstall(actionConfig)
+	installAction.Namespace = namespace
+	installAction.ReleaseName = releaseName
+	installAction.CreateNamespace = true
+	installAction.Wait = opts.Wait
+	installAction.Atomic = opts.Atomic
+	installAction.Timeout = opts.timeout()
+	installAction.DisableHooks = opts.DisableHooks
+	installAction.SkipCRDs = opts.SkipCRDs
+	installAction.PostRenderer = opts.postRenderer()
+	installAction.Labels = opts.ReleaseLabels
+
+	if version != "" {
+		installAction.Version = version
+	}
+	return installAction
+}
+
 // Upgrade upgrades an existing Helm release
-func (c *Client) Upgrade(ctx context.Context, releaseName, chartName, namespace string, values map[string]interface{}, version string) (*ReleaseInfo, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (c *Client) Upgrade(ctx context.Context, releaseName, chartName, namespace string, values map[string]interface{}, version string, opts ActionOptions) (*ReleaseInfo, error) {
+	defer c.lockRelease(ctx, releaseName, namespace)()
 
 	logger := log.FromContext(ctx).WithValues("release", releaseName, "chart", chartName, "namespace", namespace)
 	logger.Info("Upgrading Helm chart")
@@ -141,39 +256,125 @@ func (c *Client) Upgrade(ctx context.Context, releaseName, chartName, namespace
 		return nil, err
 	}
 
+	opts.Timeout = c.timeout(ctx, opts.Timeout)
+	upgradeAction := newUpgradeAction(actionConfig, namespace, version, opts)
+	upgradeAction.PostRenderer = chainPostRenderers(c.PostRenderer, c.Resources.postRenderer(logger), upgradeAction.PostRenderer)
+
+	chartPath, err := c.locateChart(ctx, chartName, version, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate chart: %w", err)
+	}
+
+	chart, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+
+	rel, err := upgradeAction.RunWithContext(ctx, releaseName, chart, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upgrade chart: %w", operationInProgress(releaseName, err))
+	}
+
+	logger.Info("Chart upgraded successfully", "revision", rel.Version)
+	return releaseToInfo(rel), nil
+}
+
+// newUpgradeAction configures an upgrade action
+func newUpgradeAction(actionConfig *action.Configuration, namespace, version string, opts ActionOptions) *action.Upgrade {
 	upgradeAction := action.NewUpgrade(actionConfig)
 	upgradeAction.Namespace = namespace
-	upgradeAction.Wait = false
-	upgradeAction.Timeout = 5 * time.Minute
-	upgradeAction.ReuseValues = false
+	upgradeAction.Wait = opts.Wait
+	upgradeAction.Atomic = opts.Atomic
+	upgradeAction.Timeout = opts.timeout()
+	upgradeAction.ReuseValues = opts.ReuseValues
+	upgradeAction.ResetValues = !opts.ReuseValues
+	upgradeAction.CleanupOnFail = opts.CleanupOnFail
+	upgradeAction.DisableHooks = opts.DisableHooks
+	upgradeAction.SkipCRDs = opts.SkipCRDs
+	upgradeAction.PostRenderer = opts.postRenderer()
+	upgradeAction.Labels = opts.ReleaseLabels
 
 	if version != "" {
 		upgradeAction.Version = version
 	}
+	return upgradeAction
+}
+
+// Render renders a chart's manifests client-side without installing it
+func (c *Client) Render(ctx context.Context, releaseName, chartName, namespace string, values map[string]interface{}, version string) (string, error) {
+	logger := log.FromContext(ctx).WithValues("release", releaseName, "chart", chartName, "namespace", namespace)
+	logger.V(1).Info("Rendering Helm chart")
+
+	actionConfig, err := c.getActionConfig(ctx, namespace)
+	if err != nil {
+		return "", err
+	}
+
+	installAction := action.NewInstall(actionConfig)
+	installAction.Namespace = namespace
+	installAction.ReleaseName = releaseName
+	installAction.DryRun = true
+	installAction.ClientOnly = true
+	installAction.Replace = true
+	// Resources the post-renderer injects, and injected resource defaults, count towards
+	// preflight checks
+	installAction.PostRenderer = chainPostRenderers(c.PostRenderer, c.Resources.postRenderer(logger))
+
+	if version != "" {
+		installAction.Version = version
+	}
 
 	chartPath, err := c.locateChart(ctx, chartName, version, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to locate chart: %w", err)
+		return "", fmt.Errorf("failed to locate chart: %w", err)
 	}
 
 	chart, err := loader.Load(chartPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load chart: %w", err)
+		return "", fmt.Errorf("failed to load chart: %w", err)
 	}
 
-	rel, err := upgradeAction.RunWithContext(ctx, releaseName, chart, values)
+	rel, err := installAction.RunWithContext(ctx, chart, values)
 	if err != nil {
-		return nil, fmt.Errorf("failed to upgrade chart: %w", err)
+		return "", fmt.Errorf("failed to render chart: %w", err)
 	}
 
-	logger.Info("Chart upgraded successfully", "revision", rel.Version)
-	return releaseToInfo(rel), nil
+	return rel.Manifest, nil
+}
+
+// Rollback rolls a release back to the given revision
+func (c *Client) Rollback(ctx context.Context, releaseName, namespace string, revision int) error {
+	defer c.lockRelease(ctx, releaseName, namespace)()
+
+	logger := log.FromContext(ctx).WithValues("release", releaseName, "namespace", namespace, "revision", revision)
+	logger.Info("Rolling back Helm release")
+
+	actionConfig, err := c.getActionConfig(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	rollbackAction := newRollbackAction(actionConfig, revision, c.timeout(ctx, 0))
+	if err := rollbackAction.Run(releaseName); err != nil {
+		return fmt.Errorf("failed to roll back release: %w", operationInProgress(releaseName, err))
+	}
+
+	logger.Info("Release rolled back successfully")
+	return nil
+}
+
+// newRollbackAction configures a rollback action
+func newRollbackAction(actionConfig *action.Configuration, revision int, timeout time.Duration) *action.Rollback {
+	rollbackAction := action.NewRollback(actionConfig)
+	rollbackAction.Version = revision
+	rollbackAction.Timeout = timeout
+	rollbackAction.Wait = false
+	return rollbackAction
 }
 
 // Uninstall removes a Helm release
 func (c *Client) Uninstall(ctx context.Context, releaseName, namespace string) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.lockRelease(ctx, releaseName, namespace)()
 
 	logger := log.FromContext(ctx).WithValues("release", releaseName, "namespace", namespace)
 	logger.Info("Uninstalling Helm release")
@@ -183,10 +384,7 @@ func (c *Client) Uninstall(ctx context.Context, releaseName, namespace string) e
 		return err
 	}
 
-	uninstallAction := action.NewUninstall(actionConfig)
-	uninstallAction.Timeout = 5 * time.Minute
-	uninstallAction.Wait = false
-
+	uninstallAction := newUninstallAction(actionConfig, c.timeout(ctx, 0))
 	_, err = uninstallAction.Run(releaseName)
 	if err != nil {
 		return fmt.Errorf("failed to uninstall release: %w", err)
@@ -196,6 +394,14 @@ func (c *Client) Uninstall(ctx context.Context, releaseName, namespace string) e
 	return nil
 }
 
+// newUninstallAction configures an uninstall action
+func newUninstallAction(actionConfig *action.Configuration, timeout time.Duration) *action.Uninstall {
+	uninstallAction := action.NewUninstall(actionConfig)
+	uninstallAction.Timeout = timeout
+	uninstallAction.Wait = false
+	return uninstallAction
+}
+
 // GetRelease retrieves information about a Helm release
 func (c *Client) GetRelease(ctx context.Context, releaseName, namespace string) (*ReleaseInfo, error) {
 	actionConfig, err := c.getActionConfig(ctx, namespace)
@@ -224,42 +430,88 @@ func (c *Client) ReleaseExists(ctx context.Context, releaseName, namespace strin
 	return rel != nil, nil
 }
 
-// locateChart finds the chart either locally or pulls it from a repository
+// VersionMismatchError is returned when a chart is pinned to a version that isn't available
+type VersionMismatchError struct {
+	Chart     string
+	Requested string
+	Available string
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("chart %s version %s is not available, the synced chart has version %s", e.Chart, e.Requested, e.Available)
+}
+
+// locateChart finds the chart either locally or pulls it from a repository. A local chart
+// is only used if it matches the requested version; Helm would otherwise install it
+// whatever its version. It must pass the Verifier too, if any. Charts named after a
+// repository, e.g. bitnami/postgresql, are only pulled from that repository.
 func (c *Client) locateChart(ctx context.Context, chartName, version string, logger logr.Logger) (string, error) {
+	if repository, name, ok := c.repositoryChart(chartName); ok {
+		return c.pullChart(ctx, repository, name, version, logger)
+	}
+
 	// First, check if the chart exists locally
+	localErr := fmt.Errorf("chart %s not found locally", chartName)
 	localPath := filepath.Join(c.chartsPath, chartName)
 	if _, err := os.Stat(localPath); err == nil {
-		logger.V(1).Info("Using local chart", "path", localPath)
-		return localPath, nil
+		localErr = nil
+		if version != "" {
+			metadata, err := chartutil.LoadChartfile(filepath.Join(localPath, chartutil.ChartfileName))
+			if err != nil {
+				return "", fmt.Errorf("failed to load chart: %w", err)
+			}
+			if !versionMatches(version, metadata.Version) {
+				localErr = &VersionMismatchError{Chart: chartName, Requested: version, Available: metadata.Version}
+			}
+		}
+		if localErr == nil {
+			// A corrupted chart is never deployed, nor replaced by one from a repository
+			if c.Verifier != nil {
+				if err := c.Verifier.VerifyChart(chartName); err != nil {
+					return "", fmt.Errorf("failed to verify local chart: %w", err)
+				}
+			}
+			logger.V(1).Info("Using local chart", "path", localPath)
+			return localPath, nil
+		}
 	}
 
-	// If repo URL is configured, try to pull the chart
-	if c.repoURL != "" {
-		return c.pullChart(ctx, chartName, version, logger)
+	if len(c.repositories) == 0 {
+		var mismatch *VersionMismatchError
+		if errors.As(localErr, &mismatch) {
+			return "", localErr
+		}
+		return "", fmt.Errorf("chart %s not found locally and no repository configured", chartName)
 	}
 
-	return "", fmt.Errorf("chart %s not found locally and no repository configured", chartName)
+	// Fall back to the repositories in order
+	errs := []error{localErr}
+	for _, repository := range c.repositories {
+		path, err := c.pullChart(ctx, repository, chartName, version, logger)
+		if err == nil {
+			return path, nil
+		}
+		logger.V(1).Info("Chart not available from repository", "repo", repository.Name, "error", err.Error())
+		errs = append(errs, err)
+	}
+	return "", errors.Join(errs...)
 }
 
-// pullChart pulls a chart from the configured repository
-func (c *Client) pullChart(ctx context.Context, chartName, version string, logger logr.Logger) (string, error) {
-	logger.Info("Pulling chart from repository", "repo", c.repoURL)
-
-	pullAction := action.NewPullWithOpts(action.WithConfig(new(action.Configuration)))
-	pullAction.RepoURL = c.repoURL
-	pullAction.Version = version
-	pullAction.DestDir = c.chartsPath
-	pullAction.Untar = true
-	pullAction.UntarDir = c.chartsPath
-
-	chartRef := chartName
-	output, err := pullAction.Run(chartRef)
+// versionMatches reports whether a chart version satisfies the requested one, an exact
+// version or a semver constraint like Helm accepts for --version
+func versionMatches(requested, available string) bool {
+	if requested == available {
+		return true
+	}
+	constraint, err := semver.NewConstraint(requested)
 	if err != nil {
-		return "", fmt.Errorf("failed to pull chart: %w", err)
+		return false
 	}
-	logger.V(1).Info("Pull output", "output", output)
-
-	return filepath.Join(c.chartsPath, chartName), nil
+	v, err := semver.NewVersion(available)
+	if err != nil {
+		return false
+	}
+	return constraint.Check(v)
 }
 
 // AddRepository adds a Helm repository
@@ -304,16 +556,41 @@ func (c *Client) AddRepository(ctx context.Context, name, url string) error {
 	return nil
 }
 
-// GetChartMetadata returns metadata for a chart
-func (c *Client) GetChartMetadata(ctx context.Context, chartName string) (*chart.Metadata, error) {
-	localPath := filepath.Join(c.chartsPath, chartName)
-	ch, err := loader.Load(localPath)
+// GetChartMetadata returns metadata for the given version of a chart, which is located
+// like for an install
+func (c *Client) GetChartMetadata(ctx context.Context, chartName, version string) (*chart.Metadata, error) {
+	logger := log.FromContext(ctx).WithValues("chart", chartName, "version", version)
+
+	chartPath, err := c.locateChart(ctx, chartName, version, logger)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := loader.Load(chartPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load chart: %w", err)
 	}
 	return ch.Metadata, nil
 }
 
+// GetChartFile returns a file from the root of the chart, or nil if the chart has no such
+// file. The chart is located like for installs, but only the file is read.
+func (c *Client) GetChartFile(ctx context.Context, chartName, version, fileName string) ([]byte, error) {
+	logger := log.FromContext(ctx).WithValues("chart", chartName, "version", version)
+
+	chartPath, err := c.locateChart(ctx, chartName, version, logger)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(chartPath, filepath.Base(fileName)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chart file %s: %w", fileName, err)
+	}
+	return data, nil
+}
+
 // releaseToInfo converts a Helm release to ReleaseInfo
 func releaseToInfo(rel *release.Release) *ReleaseInfo {
 	if rel == nil {
@@ -325,17 +602,45 @@ func releaseToInfo(rel *release.Release) *ReleaseInfo {
 		Namespace: rel.Namespace,
 		Revision:  rel.Version,
 		Status:    string(rel.Info.Status),
+		Labels:    rel.Labels,
 	}
 
 	if rel.Chart != nil && rel.Chart.Metadata != nil {
 		info.ChartName = rel.Chart.Metadata.Name
 		info.ChartVersion = rel.Chart.Metadata.Version
 		info.AppVersion = rel.Chart.Metadata.AppVersion
+		info.KubeVersion = rel.Chart.Metadata.KubeVersion
 	}
 
 	if rel.Info != nil && !rel.Info.LastDeployed.IsZero() {
 		info.Updated = rel.Info.LastDeployed.Time
 	}
 
+	info.Hooks = hooksToInfo(rel.Hooks)
 	return info
 }
+
+// hooksToInfo returns the results of the hooks that have run, ordered by start time
+func hooksToInfo(hooks []*release.Hook) []HookInfo {
+	var infos []HookInfo
+	for _, hook := range hooks {
+		if hook == nil || hook.LastRun.StartedAt.IsZero() {
+			continue
+		}
+		info := HookInfo{
+			Name:        hook.Name,
+			Kind:        hook.Kind,
+			Phase:       string(hook.LastRun.Phase),
+			StartedAt:   hook.LastRun.StartedAt.Time,
+			CompletedAt: hook.LastRun.CompletedAt.Time,
+		}
+		for _, event := range hook.Events {
+			info.Events = append(info.Events, string(event))
+		}
+		infos = append(infos, info)
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].StartedAt.Before(infos[j].StartedAt)
+	})
+	return infos
+}
@@ -0,0 +1,262 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+func TestNewInstallAction(t *testing.T) {
+	install := newInstallAction(&action.Configuration{}, "db", "team-a", "15.2.0", ActionOptions{})
+	if install.ReleaseName != "db" || install.Namespace != "team-a" || install.Version != "15.2.0" || !install.CreateNamespace {
+		t.Errorf("install = %+v", install)
+	}
+	if install.Atomic || install.Wait || install.DisableHooks || install.SkipCRDs || install.Timeout != 5*time.Minute {
+		t.Errorf("default install options changed: %+v", install)
+	}
+
+	install = newInstallAction(&action.Configuration{}, "db", "team-a", "", ActionOptions{
+		Wait: true, Atomic: true, Timeout: time.Minute, DisableHooks: true, SkipCRDs: true,
+	})
+	if !install.Wait || !install.Atomic || install.Timeout != time.Minute || !install.DisableHooks || !install.SkipCRDs {
+		t.Errorf("install options not applied: %+v", install)
+	}
+}
+
+func TestNewUpgradeAction(t *testing.T) {
+	upgrade := newUpgradeAction(&action.Configuration{}, "team-a", "15.2.0", ActionOptions{})
+	if upgrade.Namespace != "team-a" || upgrade.Version != "15.2.0" || upgrade.ReuseValues || !upgrade.ResetValues {
+		t.Errorf("upgrade = %+v", upgrade)
+	}
+	if upgrade.Atomic || upgrade.Wait || upgrade.CleanupOnFail || upgrade.DisableHooks || upgrade.SkipCRDs {
+		t.Errorf("default upgrade options changed: %+v", upgrade)
+	}
+
+	for name, tt := range map[string]struct {
+		opts  ActionOptions
+		check func(*action.Upgrade) bool
+	}{
+		"atomic":        {ActionOptions{Atomic: true}, func(u *action.Upgrade) bool { return u.Atomic }},
+		"cleanupOnFail": {ActionOptions{CleanupOnFail: true}, func(u *action.Upgrade) bool { return u.CleanupOnFail }},
+		"disableHooks":  {ActionOptions{DisableHooks: true}, func(u *action.Upgrade) bool { return u.DisableHooks }},
+		"skipCRDs":      {ActionOptions{SkipCRDs: true}, func(u *action.Upgrade) bool { return u.SkipCRDs }},
+		"reuseValues":   {ActionOptions{ReuseValues: true}, func(u *action.Upgrade) bool { return u.ReuseValues && !u.ResetValues }},
+	} {
+		if upgrade := newUpgradeAction(&action.Configuration{}, "team-a", "", tt.opts); !tt.check(upgrade) {
+			t.Errorf("%s not applied: %+v", name, upgrade)
+		}
+	}
+}
+
+func TestLocateChartPinnedVersion(t *testing.T) {
+	chartsPath := t.TempDir()
+	chartDir := filepath.Join(chartsPath, "postgresql")
+	if err := os.MkdirAll(chartDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	chartfile := "apiVersion: v2\nname: postgresql\nversion: 15.2.0\n"
+	if err := os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte(chartfile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(chartsPath, nil)
+
+	for _, version := range []string{"", "15.2.0", "^15.0.0", "15.x", ">=15.2.0 <16.0.0"} {
+		path, err := c.locateChart(context.Background(), "postgresql", version, logr.Discard())
+		if err != nil {
+			t.Errorf("locateChart(%q) error = %v", version, err)
+		}
+		if path != chartDir {
+			t.Errorf("locateChart(%q) = %q, want %q", version, path, chartDir)
+		}
+	}
+
+	for _, version := range []string{"15.1.0", "16.0.0", "^14.0.0"} {
+		_, err := c.locateChart(context.Background(), "postgresql", version, logr.Discard())
+		var mismatch *VersionMismatchError
+		if !errors.As(err, &mismatch) {
+			t.Fatalf("locateChart(%q) error = %v, want a VersionMismatchError", version, err)
+		}
+		if mismatch.Requested != version || mismatch.Available != "15.2.0" {
+			t.Errorf("locateChart(%q) mismatch = %+v", version, mismatch)
+		}
+	}
+}
+
+// chartVerifierFunc adapts a function to a ChartVerifier
+type chartVerifierFunc func(chartName string) error
+
+func (f chartVerifierFunc) VerifyChart(chartName string) error {
+	return f(chartName)
+}
+
+func TestLocateChartVerifier(t *testing.T) {
+	chartsPath := t.TempDir()
+	chartDir := filepath.Join(chartsPath, "postgresql")
+	if err := os.MkdirAll(chartDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	chartfile := "apiVersion: v2\nname: postgresql\nversion: 15.2.0\n"
+	if err := os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte(chartfile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(chartsPath, nil)
+
+	var verified []string
+	c.Verifier = chartVerifierFunc(func(chartName string) error {
+		verified = append(verified, chartName)
+		return nil
+	})
+	if path, err := c.locateChart(context.Background(), "postgresql", "", logr.Discard()); err != nil || path != chartDir {
+		t.Errorf("locateChart() = %q, %v, want %q", path, err, chartDir)
+	}
+	if len(verified) != 1 || verified[0] != "postgresql" {
+		t.Errorf("verified charts = %v, want [postgresql]", verified)
+	}
+
+	corrupted := errors.New("checksum mismatch")
+	c.Verifier = chartVerifierFunc(func(string) error { return corrupted })
+	if _, err := c.locateChart(context.Background(), "postgresql", "", logr.Discard()); !errors.Is(err, corrupted) {
+		t.Errorf("locateChart() error = %v, want the verification error", err)
+	}
+}
+
+func TestReleaseLabels(t *testing.T) {
+	cfg := &action.Configuration{
+		Releases:     storage.Init(driver.NewMemory()),
+		KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+		Capabilities: chartutil.DefaultCapabilities,
+		Log:          func(string, ...interface{}) {},
+	}
+	ch := &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "postgresql", Version: "15.2.0"}}
+
+	rel, err := newInstallAction(cfg, "db", "team-a", "", ActionOptions{
+		ReleaseLabels: map[string]string{"appstore.bitpipe.no/owner-uid": "uid-1"},
+	}).Run(ch, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := releaseToInfo(rel); info.Labels["appstore.bitpipe.no/owner-uid"] != "uid-1" {
+		t.Errorf("installed release labels = %v", info.Labels)
+	}
+
+	// Upgrades without labels keep them
+	rel, err = newUpgradeAction(cfg, "team-a", "", ActionOptions{}).Run("db", ch, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := releaseToInfo(rel); info.Labels["appstore.bitpipe.no/owner-uid"] != "uid-1" {
+		t.Errorf("upgraded release labels = %v", info.Labels)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	c := NewClient(t.TempDir(), nil)
+	ctx := context.Background()
+	if got := c.timeout(ctx, 0); got != DefaultTimeout {
+		t.Errorf("default timeout = %v, want %v", got, DefaultTimeout)
+	}
+
+	c.Timeout = 10 * time.Minute
+	if got := c.timeout(ctx, 0); got != 10*time.Minute {
+		t.Errorf("client timeout = %v, want 10m", got)
+	}
+
+	// A deployment's timeout overrides the client's, an action's both
+	ctx = WithActionTimeout(ctx, time.Minute)
+	if got := c.timeout(ctx, 0); got != time.Minute {
+		t.Errorf("context timeout = %v, want 1m", got)
+	}
+	if got := c.timeout(ctx, 30*time.Second); got != 30*time.Second {
+		t.Errorf("action timeout = %v, want 30s", got)
+	}
+}
+
+func TestNewRollbackAndUninstallAction(t *testing.T) {
+	rollback := newRollbackAction(&action.Configuration{}, 3, 7*time.Minute)
+	if rollback.Version != 3 || rollback.Timeout != 7*time.Minute || rollback.Wait {
+		t.Errorf("rollback = %+v", rollback)
+	}
+
+	uninstall := newUninstallAction(&action.Configuration{}, 7*time.Minute)
+	if uninstall.Timeout != 7*time.Minute || uninstall.Wait {
+		t.Errorf("uninstall = %+v", uninstall)
+	}
+}
+
+func TestGetChartFile(t *testing.T) {
+	chartsPath := t.TempDir()
+	chartDir := filepath.Join(chartsPath, "postgresql")
+	if err := os.MkdirAll(chartDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"Chart.yaml":       "apiVersion: v2\nname: postgresql\nversion: 15.2.0\n",
+		"values.yaml":      "replicaCount: 1\n",
+		"values-prod.yaml": "replicaCount: 3\n",
+	} {
+		if err := os.WriteFile(filepath.Join(chartDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := NewClient(chartsPath, nil)
+
+	data, err := c.GetChartFile(context.Background(), "postgresql", "", "values-prod.yaml")
+	if err != nil || string(data) != "replicaCount: 3\n" {
+		t.Errorf("GetChartFile(values-prod.yaml) = %q, %v", data, err)
+	}
+	data, err = c.GetChartFile(context.Background(), "postgresql", "", "values-dev.yaml")
+	if err != nil || data != nil {
+		t.Errorf("GetChartFile(values-dev.yaml) = %q, %v, want no file", data, err)
+	}
+}
+
+func TestLockRelease(t *testing.T) {
+	c := &Client{}
+	ctx := context.Background()
+
+	unlock := c.lockRelease(ctx, "db", "team-a")
+
+	// Other releases, including one with the same name in another cluster, aren't blocked
+	done := make(chan struct{})
+	go func() {
+		c.lockRelease(ctx, "db", "team-b")()
+		c.lockRelease(ctx, "cache", "team-a")()
+		c.lockRelease(WithCluster(ctx, &Cluster{}), "db", "team-a")()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("locking another release blocked")
+	}
+
+	locked := make(chan struct{})
+	go func() {
+		c.lockRelease(ctx, "db", "team-a")()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("the same release was locked twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("the release wasn't locked after it was unlocked")
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"appstore/shared/kubeconfig"
+)
+
+// Cluster is a remote cluster of a kubeconfig. Its discovery information is cached, so a
+// Cluster should be reused for all operations on the cluster.
+type Cluster struct {
+	kubeconfig []byte
+	config     clientcmdapi.Config
+	restConfig *rest.Config
+	discovery  discovery.CachedDiscoveryInterface
+}
+
+// NewCluster parses a kubeconfig using its current context. The kubeconfig must pass the
+// checks of kubeconfig.Load: only embedded credentials, and an API server from
+// allowedServers.
+func NewCluster(data []byte, allowedServers []string) (*Cluster, error) {
+	config, restConfig, err := kubeconfig.Load(data, allowedServers)
+	if err != nil {
+		return nil, err
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery client: %w", err)
+	}
+	return &Cluster{
+		kubeconfig: data,
+		config:     *config,
+		restConfig: restConfig,
+		discovery:  memory.NewMemCacheClient(discoveryClient),
+	}, nil
+}
+
+// RESTConfig returns a copy of the cluster's REST config
+func (c *Cluster) RESTConfig() *rest.Config {
+	return rest.CopyConfig(c.restConfig)
+}
+
+// clusterKey is the context key of the target cluster
+type clusterKey struct{}
+
+// WithCluster returns a context in which the client operates on the remote cluster instead
+// of the operator's own cluster
+func WithCluster(ctx context.Context, cluster *Cluster) context.Context {
+	return context.WithValue(ctx, clusterKey{}, cluster)
+}
+
+// ClusterFromContext returns the cluster set with WithCluster, or nil
+func ClusterFromContext(ctx context.Context) *Cluster {
+	cluster, _ := ctx.Value(clusterKey{}).(*Cluster)
+	return cluster
+}
+
+// KubeConfigFromContext returns the kubeconfig of the cluster set with WithCluster, or nil
+func KubeConfigFromContext(ctx context.Context) []byte {
+	if cluster := ClusterFromContext(ctx); cluster != nil {
+		return cluster.kubeconfig
+	}
+	return nil
+}
+
+// kubeConfigGetter is a RESTClientGetter for a remote cluster, defaulting to the given
+// namespace
+type kubeConfigGetter struct {
+	clientConfig clientcmd.ClientConfig
+	discovery    discovery.CachedDiscoveryInterface
+}
+
+// getter returns a RESTClientGetter for the cluster defaulting to namespace, sharing the
+// cluster's discovery cache
+func (c *Cluster) getter(namespace string) *kubeConfigGetter {
+	clientConfig := clientcmd.NewDefaultClientConfig(c.config, &clientcmd.ConfigOverrides{
+		Context: clientcmdapi.Context{Namespace: namespace},
+	})
+	return &kubeConfigGetter{clientConfig: clientConfig, discovery: c.discovery}
+}
+
+func (g *kubeConfigGetter) ToRESTConfig() (*rest.Config, error) {
+	return g.clientConfig.ClientConfig()
+}
+
+func (g *kubeConfigGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	return g.discovery, nil
+}
+
+func (g *kubeConfigGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(g.discovery)
+	return restmapper.NewShortcutExpander(mapper, g.discovery, nil), nil
+}
+
+func (g *kubeConfigGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	return g.clientConfig
+}
@@ -0,0 +1,108 @@
+package helm
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// remoteKubeConfig returns a kubeconfig for the API server at url
+func remoteKubeConfig(url string) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: remote
+  cluster:
+    server: %s
+contexts:
+- name: remote
+  context:
+    cluster: remote
+    user: remote
+current-context: remote
+users:
+- name: remote
+  user:
+    token: remote-token
+`, url))
+}
+
+func TestNewCluster(t *testing.T) {
+	allowed := []string{"https://remote.example.com:6443"}
+	cluster, err := NewCluster(remoteKubeConfig("https://Remote.example.com:6443/"), allowed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restConfig, err := cluster.getter("team-a").ToRESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restConfig.Host != "https://Remote.example.com:6443/" || restConfig.BearerToken != "remote-token" {
+		t.Errorf("rest config = %s, token %q", restConfig.Host, restConfig.BearerToken)
+	}
+	if namespace, _, _ := cluster.getter("team-a").ToRawKubeConfigLoader().Namespace(); namespace != "team-a" {
+		t.Errorf("namespace = %q, want team-a", namespace)
+	}
+	if got := string(KubeConfigFromContext(WithCluster(context.Background(), cluster))); got != string(cluster.kubeconfig) {
+		t.Errorf("KubeConfigFromContext() = %q", got)
+	}
+	if KubeConfigFromContext(context.Background()) != nil {
+		t.Error("kubeconfig of a plain context isn't nil")
+	}
+
+	for _, kubeconfig := range []string{"not: [yaml", "apiVersion: v1\nkind: Config\n"} {
+		if _, err := NewCluster([]byte(kubeconfig), allowed); err == nil {
+			t.Errorf("NewCluster(%q) succeeded", kubeconfig)
+		}
+	}
+}
+
+func TestGetReleaseRemoteCluster(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	// Clients only send credentials over TLS
+	apiServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("Authorization"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"kind":"SecretList","apiVersion":"v1","metadata":{},"items":[]}`)
+	}))
+	defer apiServer.Close()
+
+	c := NewClient(t.TempDir(), nil)
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: apiServer.Certificate().Raw})
+	kubeconfig := strings.Replace(string(remoteKubeConfig(apiServer.URL)), "    server:",
+		"    certificate-authority-data: "+base64.StdEncoding.EncodeToString(ca)+"\n    server:", 1)
+	cluster, err := NewCluster([]byte(kubeconfig), []string{apiServer.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithCluster(context.Background(), cluster)
+
+	release, err := c.GetRelease(ctx, "db", "team-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release != nil {
+		t.Errorf("GetRelease() = %+v, want nil", release)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := "GET /api/v1/namespaces/team-a/secrets Bearer remote-token"
+	for _, request := range requests {
+		if request == want {
+			return
+		}
+	}
+	t.Errorf("release storage wasn't read from the remote cluster, requests: %v", requests)
+}
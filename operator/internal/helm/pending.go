@@ -0,0 +1,101 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// pendingMessage is the error Helm returns for a release that is locked by another operation.
+// Helm doesn't export the error, so it is matched by message.
+const pendingMessage = "another operation (install/upgrade/rollback) is in progress"
+
+// OperationInProgressError is returned when a release is locked by another Helm operation,
+// either one that is still running or one that was interrupted and left the release pending
+type OperationInProgressError struct {
+	Release string
+	Err     error
+}
+
+func (e *OperationInProgressError) Error() string {
+	return fmt.Sprintf("release %s is locked by another Helm operation: %v", e.Release, e.Err)
+}
+
+func (e *OperationInProgressError) Unwrap() error {
+	return e.Err
+}
+
+// operationInProgress wraps Helm's pending operation error in an *OperationInProgressError
+func operationInProgress(releaseName string, err error) error {
+	if err != nil && strings.Contains(err.Error(), pendingMessage) {
+		return &OperationInProgressError{Release: releaseName, Err: err}
+	}
+	return err
+}
+
+// Pending reports whether the release is locked by an install, upgrade or rollback
+func (r *ReleaseInfo) Pending() bool {
+	return release.Status(r.Status).IsPending()
+}
+
+// ClearPendingRelease marks the latest revision of a release failed if it is stuck in a
+// pending state, so that it can be upgraded again. It must only be used once the operation
+// that left the release pending is known to be gone.
+func (c *Client) ClearPendingRelease(ctx context.Context, releaseName, namespace string) error {
+	defer c.lockRelease(ctx, releaseName, namespace)()
+
+	logger := log.FromContext(ctx).WithValues("release", releaseName, "namespace", namespace)
+
+	actionConfig, err := c.getActionConfig(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	status, err := clearPendingRelease(actionConfig, releaseName)
+	if err != nil {
+		return err
+	}
+	if status.IsPending() {
+		logger.Info("Marked pending Helm release failed", "previousStatus", status)
+	}
+	return nil
+}
+
+// clearPendingRelease marks the latest revision failed if it is pending and returns the
+// status it had
+func clearPendingRelease(actionConfig *action.Configuration, releaseName string) (release.Status, error) {
+	rel, err := actionConfig.Releases.Last(releaseName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get release: %w", err)
+	}
+	status := rel.Info.Status
+	if !status.IsPending() {
+		return status, nil
+	}
+
+	rel.SetStatus(release.StatusFailed, fmt.Sprintf("Marked failed by appstore-operator after being stuck in %s", status))
+	if err := actionConfig.Releases.Update(rel); err != nil {
+		return "", fmt.Errorf("failed to update release: %w", err)
+	}
+	return status, nil
+}
@@ -0,0 +1,125 @@
+package helm
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// pendingReleaseConfig returns an in-memory action configuration holding a deployed
+// revision of release db and a second revision in the given status
+func pendingReleaseConfig(t *testing.T, status release.Status) (*action.Configuration, *chart.Chart) {
+	t.Helper()
+	cfg := &action.Configuration{
+		Releases:     storage.Init(driver.NewMemory()),
+		KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+		Capabilities: chartutil.DefaultCapabilities,
+		Log:          func(string, ...interface{}) {},
+	}
+	ch := &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "postgresql", Version: "15.2.0"}}
+
+	for i, s := range []release.Status{release.StatusSuperseded, status} {
+		rel := &release.Release{
+			Name:      "db",
+			Namespace: "team-a",
+			Version:   i + 1,
+			Chart:     ch,
+			Info:      &release.Info{Status: s},
+		}
+		if err := cfg.Releases.Create(rel); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg, ch
+}
+
+func TestUpgradePendingRelease(t *testing.T) {
+	cfg, ch := pendingReleaseConfig(t, release.StatusPendingUpgrade)
+
+	_, err := newUpgradeAction(cfg, "team-a", "", ActionOptions{}).Run("db", ch, nil)
+	err = operationInProgress("db", err)
+
+	var inProgress *OperationInProgressError
+	if !errors.As(err, &inProgress) {
+		t.Fatalf("upgrade error = %v, want an OperationInProgressError", err)
+	}
+	if inProgress.Release != "db" {
+		t.Errorf("Release = %q, want db", inProgress.Release)
+	}
+
+	if err := operationInProgress("db", errors.New("timed out waiting for the condition")); errors.As(err, &inProgress) {
+		t.Errorf("other errors must not be wrapped, got %v", err)
+	}
+}
+
+func TestClearPendingRelease(t *testing.T) {
+	for _, status := range []release.Status{release.StatusPendingInstall, release.StatusPendingUpgrade, release.StatusPendingRollback} {
+		cfg, ch := pendingReleaseConfig(t, status)
+
+		previous, err := clearPendingRelease(cfg, "db")
+		if err != nil {
+			t.Fatalf("%s: clearPendingRelease() error = %v", status, err)
+		}
+		if previous != status {
+			t.Errorf("%s: clearPendingRelease() = %q", status, previous)
+		}
+		last, err := cfg.Releases.Last("db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last.Info.Status != release.StatusFailed {
+			t.Errorf("%s: status after clearing = %q, want failed", status, last.Info.Status)
+		}
+
+		// The release can be upgraded again
+		rel, err := newUpgradeAction(cfg, "team-a", "", ActionOptions{}).Run("db", ch, nil)
+		if err != nil {
+			t.Fatalf("%s: upgrade after clearing error = %v", status, err)
+		}
+		if rel.Version != 3 || rel.Info.Status != release.StatusDeployed {
+			t.Errorf("%s: upgraded release = revision %d, %s", status, rel.Version, rel.Info.Status)
+		}
+	}
+}
+
+func TestClearPendingReleaseLeavesOtherStatuses(t *testing.T) {
+	cfg, _ := pendingReleaseConfig(t, release.StatusDeployed)
+
+	previous, err := clearPendingRelease(cfg, "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != release.StatusDeployed {
+		t.Errorf("clearPendingRelease() = %q", previous)
+	}
+	last, _ := cfg.Releases.Last("db")
+	if last.Info.Status != release.StatusDeployed {
+		t.Errorf("status = %q, want deployed", last.Info.Status)
+	}
+
+	if _, err := clearPendingRelease(cfg, "missing"); err == nil {
+		t.Error("clearing a missing release succeeded")
+	}
+}
+
+func TestReleaseInfoPending(t *testing.T) {
+	for status, want := range map[string]bool{
+		"pending-install":  true,
+		"pending-upgrade":  true,
+		"pending-rollback": true,
+		"deployed":         false,
+		"failed":           false,
+	} {
+		if got := (&ReleaseInfo{Status: status}).Pending(); got != want {
+			t.Errorf("Pending() for %s = %v, want %v", status, got, want)
+		}
+	}
+}
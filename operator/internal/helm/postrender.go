@@ -0,0 +1,181 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/postrender"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// PostRenderFunc adapts a function to a postrender.PostRenderer
+type PostRenderFunc func(rendered *bytes.Buffer) (*bytes.Buffer, error)
+
+// Run implements postrender.PostRenderer
+func (f PostRenderFunc) Run(rendered *bytes.Buffer) (*bytes.Buffer, error) {
+	return f(rendered)
+}
+
+// postRendererChain runs post-renderers in order, each on the output of the previous one
+type postRendererChain []postrender.PostRenderer
+
+// Run implements postrender.PostRenderer
+func (c postRendererChain) Run(rendered *bytes.Buffer) (*bytes.Buffer, error) {
+	for _, renderer := range c {
+		var err error
+		if rendered, err = renderer.Run(rendered); err != nil {
+			return nil, err
+		}
+	}
+	return rendered, nil
+}
+
+// chainPostRenderers combines the non-nil post-renderers into one, or returns nil if there
+// are none
+func chainPostRenderers(renderers ...postrender.PostRenderer) postrender.PostRenderer {
+	var chain postRendererChain
+	for _, renderer := range renderers {
+		if renderer != nil {
+			chain = append(chain, renderer)
+		}
+	}
+	switch len(chain) {
+	case 0:
+		return nil
+	case 1:
+		return chain[0]
+	}
+	return chain
+}
+
+// metadataPostRenderer adds labels and annotations to the metadata of every rendered
+// resource, including the items of lists
+type metadataPostRenderer struct {
+	labels      map[string]string
+	annotations map[string]string
+}
+
+// postRenderer returns a post-renderer applying the options' labels and annotations, or
+// nil if there are none
+func (o ActionOptions) postRenderer() postrender.PostRenderer {
+	if len(o.Labels) == 0 && len(o.Annotations) == 0 {
+		return nil
+	}
+	return &metadataPostRenderer{labels: o.Labels, annotations: o.Annotations}
+}
+
+// Run implements postrender.PostRenderer
+func (p *metadataPostRenderer) Run(rendered *bytes.Buffer) (*bytes.Buffer, error) {
+	return transformManifest(rendered, func(obj map[string]interface{}) error {
+		p.apply(obj)
+		return nil
+	})
+}
+
+// transformManifest calls transform with every resource of a rendered manifest, including
+// the items of lists, and returns the manifest of the transformed resources
+func transformManifest(rendered *bytes.Buffer, transform func(obj map[string]interface{}) error) (*bytes.Buffer, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(rendered))
+	out := &bytes.Buffer{}
+
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rendered manifest: %w", err)
+		}
+
+		data, err := yaml.YAMLToJSON(doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse rendered manifest: %w", err)
+		}
+		// Numbers are kept as they are rather than converted to floats
+		var obj map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&obj); err != nil {
+			return nil, fmt.Errorf("failed to parse rendered manifest: %w", err)
+		}
+		if obj == nil {
+			// Documents holding only comments
+			continue
+		}
+
+		if err := transform(obj); err != nil {
+			return nil, err
+		}
+		if kind, _ := obj["kind"].(string); strings.HasSuffix(kind, "List") {
+			items, _ := obj["items"].([]interface{})
+			for _, item := range items {
+				if item, ok := item.(map[string]interface{}); ok {
+					if err := transform(item); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+
+		data, err = json.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode manifest: %w", err)
+		}
+		doc, err = yaml.JSONToYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode manifest: %w", err)
+		}
+		out.WriteString("---\n")
+		out.Write(doc)
+	}
+
+	return out, nil
+}
+
+// apply sets the labels and annotations on a single object
+func (p *metadataPostRenderer) apply(obj map[string]interface{}) {
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+		obj["metadata"] = metadata
+	}
+	setMetadataField(metadata, "labels", p.labels)
+	setMetadataField(metadata, "annotations", p.annotations)
+}
+
+// setMetadataField merges values into the metadata's labels or annotations
+func setMetadataField(metadata map[string]interface{}, field string, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+	existing, _ := metadata[field].(map[string]interface{})
+	if existing == nil {
+		existing = map[string]interface{}{}
+		metadata[field] = existing
+	}
+	for key, value := range values {
+		existing[key] = value
+	}
+}
@@ -0,0 +1,267 @@
+package helm
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/postrender"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"sigs.k8s.io/yaml"
+)
+
+const renderedManifest = `---
+# Source: postgresql/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: db
+  labels:
+    app: postgresql
+    team: chart-default
+spec:
+  ports:
+  - port: 5432
+---
+# Source: postgresql/templates/empty.yaml
+---
+# Source: postgresql/templates/configmaps.yaml
+apiVersion: v1
+kind: ConfigMapList
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: db-config
+    annotations:
+      checksum: abc
+  data:
+    size: "1024"
+`
+
+// renderedObjects parses a post-rendered manifest
+func renderedObjects(t *testing.T, manifest string) []map[string]interface{} {
+	t.Helper()
+	var objs []map[string]interface{}
+	for _, doc := range strings.Split(manifest, "---\n") {
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			t.Fatalf("invalid manifest %q: %v", doc, err)
+		}
+		if obj != nil {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
+
+// metadataField returns an object's labels or annotations
+func metadataField(obj map[string]interface{}, field string) map[string]interface{} {
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	values, _ := metadata[field].(map[string]interface{})
+	return values
+}
+
+func TestMetadataPostRenderer(t *testing.T) {
+	renderer := ActionOptions{
+		Labels:      map[string]string{"team": "payments", "cost-center": "cc-42"},
+		Annotations: map[string]string{"owner": "alice@example.com"},
+	}.postRenderer()
+
+	out, err := renderer.Run(bytes.NewBufferString(renderedManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs := renderedObjects(t, out.String())
+	if len(objs) != 2 {
+		t.Fatalf("got %d objects, want 2:\n%s", len(objs), out)
+	}
+
+	service := objs[0]
+	labels := metadataField(service, "labels")
+	if labels["app"] != "postgresql" || labels["team"] != "payments" || labels["cost-center"] != "cc-42" {
+		t.Errorf("service labels = %v", labels)
+	}
+	if annotations := metadataField(service, "annotations"); annotations["owner"] != "alice@example.com" {
+		t.Errorf("service annotations = %v", annotations)
+	}
+	if !strings.Contains(out.String(), "port: 5432") {
+		t.Errorf("spec not preserved:\n%s", out)
+	}
+
+	list := objs[1]
+	items, _ := list["items"].([]interface{})
+	if len(items) != 1 {
+		t.Fatalf("list items = %v", list["items"])
+	}
+	configMap := items[0].(map[string]interface{})
+	if labels := metadataField(configMap, "labels"); labels["cost-center"] != "cc-42" {
+		t.Errorf("list item labels = %v", labels)
+	}
+	annotations := metadataField(configMap, "annotations")
+	if annotations["checksum"] != "abc" || annotations["owner"] != "alice@example.com" {
+		t.Errorf("list item annotations = %v", annotations)
+	}
+	if data, _ := configMap["data"].(map[string]interface{}); data["size"] != "1024" {
+		t.Errorf("list item data = %v", configMap["data"])
+	}
+}
+
+func TestPostRendererDisabled(t *testing.T) {
+	if renderer := (ActionOptions{}).postRenderer(); renderer != nil {
+		t.Errorf("postRenderer() = %v, want nil without labels or annotations", renderer)
+	}
+	if install := newInstallAction(&action.Configuration{}, "db", "team-a", "", ActionOptions{}); install.PostRenderer != nil {
+		t.Error("install has a post-renderer without labels or annotations")
+	}
+}
+
+func TestInstallCommonMetadata(t *testing.T) {
+	cfg := &action.Configuration{
+		Releases:     storage.Init(driver.NewMemory()),
+		KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+		Capabilities: chartutil.DefaultCapabilities,
+		Log:          func(string, ...interface{}) {},
+	}
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "postgresql", Version: "15.2.0"},
+		Templates: []*chart.File{{
+			Name: "templates/deployment.yaml",
+			Data: []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: {{ .Release.Name }}\n  labels:\n    app: postgresql\nspec:\n  replicas: 2\n"),
+		}},
+	}
+	opts := ActionOptions{
+		Labels:      map[string]string{"cost-center": "cc-42"},
+		Annotations: map[string]string{"owner": "alice@example.com"},
+	}
+
+	rel, err := newInstallAction(cfg, "db", "team-a", "", opts).Run(ch, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs := renderedObjects(t, rel.Manifest)
+	if len(objs) != 1 {
+		t.Fatalf("manifest = %s", rel.Manifest)
+	}
+	if labels := metadataField(objs[0], "labels"); labels["app"] != "postgresql" || labels["cost-center"] != "cc-42" {
+		t.Errorf("installed labels = %v", labels)
+	}
+	if annotations := metadataField(objs[0], "annotations"); annotations["owner"] != "alice@example.com" {
+		t.Errorf("installed annotations = %v", annotations)
+	}
+
+	// Upgrades apply them too
+	opts.Labels["cost-center"] = "cc-7"
+	rel, err = newUpgradeAction(cfg, "team-a", "", opts).Run("db", ch, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels := metadataField(renderedObjects(t, rel.Manifest)[0], "labels"); labels["cost-center"] != "cc-7" {
+		t.Errorf("upgraded labels = %v", labels)
+	}
+}
+
+// addLabel is a post-renderer adding a label to every resource, like a platform team's
+func addLabel(key, value string) PostRenderFunc {
+	return func(rendered *bytes.Buffer) (*bytes.Buffer, error) {
+		return (&metadataPostRenderer{labels: map[string]string{key: value}}).Run(rendered)
+	}
+}
+
+func TestChainPostRenderers(t *testing.T) {
+	if chainPostRenderers(nil, nil) != nil {
+		t.Error("chain of nil post-renderers isn't nil")
+	}
+	single := addLabel("a", "1")
+	if _, ok := chainPostRenderers(nil, single).(PostRenderFunc); !ok {
+		t.Error("a single post-renderer is wrapped in a chain")
+	}
+
+	// Later post-renderers see and override the output of earlier ones
+	chain := chainPostRenderers(addLabel("team", "platform"), addLabel("team", "payments"), addLabel("mesh", "on"))
+	out, err := chain.Run(bytes.NewBufferString(renderedManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels := metadataField(renderedObjects(t, out.String())[0], "labels"); labels["team"] != "payments" || labels["mesh"] != "on" {
+		t.Errorf("labels = %v", labels)
+	}
+
+	failing := PostRenderFunc(func(*bytes.Buffer) (*bytes.Buffer, error) { return nil, errors.New("kustomize failed") })
+	if _, err := chainPostRenderers(single, failing).Run(bytes.NewBufferString(renderedManifest)); err == nil || err.Error() != "kustomize failed" {
+		t.Errorf("chain error = %v", err)
+	}
+}
+
+func TestOperatorPostRenderer(t *testing.T) {
+	cfg := &action.Configuration{
+		Releases:     storage.Init(driver.NewMemory()),
+		KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+		Capabilities: chartutil.DefaultCapabilities,
+		Log:          func(string, ...interface{}) {},
+	}
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "postgresql", Version: "15.2.0"},
+		Templates: []*chart.File{{
+			Name: "templates/service.yaml",
+			Data: []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: {{ .Release.Name }}\n"),
+		}},
+	}
+	c := &Client{PostRenderer: addLabel("platform.example.com/managed", "true")}
+
+	// The operator's post-renderer runs first; the deployment's labels override it
+	install := newInstallAction(cfg, "db", "team-a", "", ActionOptions{
+		Labels: map[string]string{"platform.example.com/managed": "by-team", "cost-center": "cc-42"},
+	})
+	install.PostRenderer = chainPostRenderers(c.PostRenderer, install.PostRenderer)
+	rel, err := install.Run(ch, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := metadataField(renderedObjects(t, rel.Manifest)[0], "labels")
+	if labels["platform.example.com/managed"] != "by-team" || labels["cost-center"] != "cc-42" {
+		t.Errorf("installed labels = %v", labels)
+	}
+
+	// Without deployment labels only the operator's post-renderer runs
+	upgrade := newUpgradeAction(cfg, "team-a", "", ActionOptions{})
+	upgrade.PostRenderer = chainPostRenderers(c.PostRenderer, upgrade.PostRenderer)
+	rel, err = upgrade.Run("db", ch, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels = metadataField(renderedObjects(t, rel.Manifest)[0], "labels")
+	if labels["platform.example.com/managed"] != "true" || len(labels) != 1 {
+		t.Errorf("upgraded labels = %v", labels)
+	}
+}
+
+func TestExecPostRenderer(t *testing.T) {
+	// A post-renderer script, as configured with --post-renderer
+	script := filepath.Join(t.TempDir(), "add-label.sh")
+	contents := "#!/bin/sh\nsed 's/^  name: db$/  name: db\\n  labels:\\n    injected: \"true\"/'\n"
+	if err := os.WriteFile(script, []byte(contents), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	renderer, err := postrender.NewExec(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := chainPostRenderers(renderer).Run(bytes.NewBufferString("apiVersion: v1\nkind: Service\nmetadata:\n  name: db\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels := metadataField(renderedObjects(t, out.String())[0], "labels"); labels["injected"] != "true" {
+		t.Errorf("labels = %v, manifest:\n%s", labels, out)
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Repository is a Helm chart repository charts are pulled from
+type Repository struct {
+	// Name selects the repository for charts prefixed with it, e.g. bitnami/postgresql
+	Name string
+	URL  string
+	// Username and Password authenticate to the repository if set
+	Username string
+	Password string
+}
+
+// ParseRepositories parses a comma-separated list of name=url repositories. The
+// credentials of a repository are read with getenv from HELM_REPO_<NAME>_USERNAME and
+// HELM_REPO_<NAME>_PASSWORD, the name upper-cased with dashes replaced by underscores.
+func ParseRepositories(value string, getenv func(string) string) ([]Repository, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	var repositories []Repository
+	for _, entry := range strings.Split(value, ",") {
+		name, url, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || name == "" || url == "" {
+			return nil, fmt.Errorf("invalid repository %q, expected name=url", entry)
+		}
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid repository name %q: %s", name, strings.Join(errs, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate repository %q", name)
+		}
+		seen[name] = true
+
+		env := "HELM_REPO_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		repositories = append(repositories, Repository{
+			Name:     name,
+			URL:      url,
+			Username: getenv(env + "_USERNAME"),
+			Password: getenv(env + "_PASSWORD"),
+		})
+	}
+	return repositories, nil
+}
+
+// repositoryChart splits a chart name prefixed with the name of a configured repository
+func (c *Client) repositoryChart(chartName string) (Repository, string, bool) {
+	prefix, name, ok := strings.Cut(chartName, "/")
+	if !ok {
+		return Repository{}, "", false
+	}
+	for _, repository := range c.repositories {
+		if repository.Name == prefix {
+			return repository, name, true
+		}
+	}
+	return Repository{}, "", false
+}
+
+// ChartValidator validates chart availability, like controller.ChartValidator
+type ChartValidator interface {
+	ChartExists(chartName string) bool
+	ListCharts() ([]string, error)
+}
+
+// repositoryValidator accepts the charts of its ChartValidator and the charts of named
+// repositories, which are only known once pulled
+type repositoryValidator struct {
+	ChartValidator
+	client *Client
+}
+
+// ChartExists reports whether the chart is available locally or named after a repository
+func (v repositoryValidator) ChartExists(chartName string) bool {
+	if _, _, ok := v.client.repositoryChart(chartName); ok {
+		return true
+	}
+	return v.ChartValidator.ChartExists(chartName)
+}
+
+// WithRepositoryCharts extends a chart validator to accept charts prefixed with the name
+// of a configured repository
+func (c *Client) WithRepositoryCharts(validator ChartValidator) ChartValidator {
+	if len(c.repositories) == 0 {
+		return validator
+	}
+	return repositoryValidator{ChartValidator: validator, client: c}
+}
+
+// pulledChartPath returns the path of a chart in the pulled charts cache, by repository
+// and version, so that pulls don't overwrite charts of other repositories or versions
+func (c *Client) pulledChartPath(repository Repository, chartName, version string) string {
+	return filepath.Join(c.pulledPath, repository.Name, chartName+"-"+version)
+}
+
+// cachedChart returns the path of a chart in the pulled charts cache if version is an
+// exact version that has been pulled before
+func (c *Client) cachedChart(repository Repository, chartName, version string) (string, bool) {
+	if _, err := semver.StrictNewVersion(version); err != nil {
+		return "", false
+	}
+	path := c.pulledChartPath(repository, chartName, version)
+	if _, err := os.Stat(filepath.Join(path, chartutil.ChartfileName)); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// pullChart pulls a chart from a repository into the pulled charts cache and returns its
+// path there. Exact versions that are cached aren't pulled again. Cached charts are never
+// replaced, since installs may be reading them while other charts are pulled; a pulled
+// chart is only moved into the cache if its version isn't there yet.
+func (c *Client) pullChart(ctx context.Context, repository Repository, chartName, version string, logger logr.Logger) (string, error) {
+	c.pullMu.Lock()
+	defer c.pullMu.Unlock()
+
+	if path, ok := c.cachedChart(repository, chartName, version); ok {
+		logger.V(1).Info("Using cached chart", "repo", repository.Name, "path", path)
+		return path, nil
+	}
+
+	logger.Info("Pulling chart from repository", "repo", repository.Name, "url", repository.URL)
+
+	if err := os.MkdirAll(c.pulledPath, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create pulled charts directory: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(c.pulledPath, "pull-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create pull directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	pullAction := action.NewPullWithOpts(action.WithConfig(new(action.Configuration)))
+	pullAction.Settings = c.settings
+	pullAction.RepoURL = repository.URL
+	pullAction.Username = repository.Username
+	pullAction.Password = repository.Password
+	pullAction.Version = version
+	pullAction.DestDir = tmpDir
+	pullAction.Untar = true
+	pullAction.UntarDir = tmpDir
+
+	output, err := pullAction.Run(chartName)
+	if err != nil {
+		return "", fmt.Errorf("failed to pull chart %s from repository %s: %w", chartName, repository.Name, err)
+	}
+	logger.V(1).Info("Pull output", "output", output)
+
+	pulled := filepath.Join(tmpDir, chartName)
+	metadata, err := chartutil.LoadChartfile(filepath.Join(pulled, chartutil.ChartfileName))
+	if err != nil {
+		return "", fmt.Errorf("failed to load pulled chart: %w", err)
+	}
+
+	// A version range may resolve to a version that is cached already
+	if path, ok := c.cachedChart(repository, chartName, metadata.Version); ok {
+		return path, nil
+	}
+	path := c.pulledChartPath(repository, chartName, metadata.Version)
+	// Charts are moved into the cache whole, so a directory without a Chart.yaml is left
+	// over from an earlier failure and nobody reads it
+	if err := os.RemoveAll(path); err != nil {
+		return "", fmt.Errorf("failed to remove incomplete pulled chart: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create pulled charts directory: %w", err)
+	}
+	if err := os.Rename(pulled, path); err != nil {
+		return "", fmt.Errorf("failed to store pulled chart: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,221 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// newChartRepository serves the given charts (name to versions) as a Helm repository,
+// requiring basic auth if username is set
+func newChartRepository(t *testing.T, username, password string, charts map[string][]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	index := repo.NewIndexFile()
+	for name, versions := range charts {
+		for _, version := range versions {
+			metadata := &chart.Metadata{APIVersion: chart.APIVersionV2, Name: name, Version: version}
+			archive, err := chartutil.Save(&chart.Chart{Metadata: metadata}, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := index.MustAdd(metadata, filepath.Base(archive), "", "sha256:test"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := index.WriteFile(filepath.Join(dir, "index.yaml"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := http.FileServer(http.Dir(dir))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); username != "" && (user != username || pass != password) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// newRepositoryClient returns a client with a local postgresql 15.0.0 chart
+func newRepositoryClient(t *testing.T, repositories []Repository) *Client {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HELM_REPOSITORY_CONFIG", filepath.Join(home, "repositories.yaml"))
+	t.Setenv("HELM_REPOSITORY_CACHE", filepath.Join(home, "cache"))
+
+	chartsPath := t.TempDir()
+	chartDir := filepath.Join(chartsPath, "postgresql")
+	if err := os.MkdirAll(chartDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	chartfile := "apiVersion: v2\nname: postgresql\nversion: 15.0.0\n"
+	if err := os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte(chartfile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClient(chartsPath, repositories)
+	c.pulledPath = filepath.Join(home, "pulled")
+	return c
+}
+
+func TestLocateChartRepositories(t *testing.T) {
+	stable := newChartRepository(t, "alice", "secret", map[string][]string{"postgresql": {"15.2.0"}})
+	extra := newChartRepository(t, "", "", map[string][]string{"postgresql": {"16.0.0"}, "valkey": {"8.0.0"}})
+	c := newRepositoryClient(t, []Repository{
+		{Name: "stable", URL: stable, Username: "alice", Password: "secret"},
+		{Name: "extra", URL: extra},
+	})
+
+	tests := []struct {
+		chart, version string
+		wantVersion    string
+		wantLocal      bool
+	}{
+		// Local charts come first
+		{"postgresql", "", "15.0.0", true},
+		// A version the local chart doesn't have is pulled from the first repository with it
+		{"postgresql", "15.2.0", "15.2.0", false},
+		{"postgresql", "^16.0.0", "16.0.0", false},
+		// So are charts that aren't local
+		{"valkey", "", "8.0.0", false},
+		// A repository prefix skips the local charts and other repositories
+		{"extra/postgresql", "", "16.0.0", false},
+	}
+	for _, tt := range tests {
+		path, err := c.locateChart(context.Background(), tt.chart, tt.version, logr.Discard())
+		if err != nil {
+			t.Errorf("locateChart(%q, %q) error = %v", tt.chart, tt.version, err)
+			continue
+		}
+		metadata, err := chartutil.LoadChartfile(filepath.Join(path, chartutil.ChartfileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if metadata.Version != tt.wantVersion {
+			t.Errorf("locateChart(%q, %q) version = %s, want %s", tt.chart, tt.version, metadata.Version, tt.wantVersion)
+		}
+		if local := filepath.Dir(path) == c.chartsPath; local != tt.wantLocal {
+			t.Errorf("locateChart(%q, %q) = %s, want local %v", tt.chart, tt.version, path, tt.wantLocal)
+		}
+	}
+
+	// A version no source has reports the local chart's version
+	_, err := c.locateChart(context.Background(), "postgresql", "17.0.0", logr.Discard())
+	var mismatch *VersionMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Available != "15.0.0" {
+		t.Errorf("locateChart(postgresql, 17.0.0) error = %v, want a VersionMismatchError", err)
+	}
+	if _, err := c.locateChart(context.Background(), "stable/valkey", "", logr.Discard()); err == nil {
+		t.Error("locateChart(stable/valkey) error = nil, want valkey missing from stable")
+	}
+}
+
+func TestLocateChartRepositoryCredentials(t *testing.T) {
+	url := newChartRepository(t, "alice", "secret", map[string][]string{"valkey": {"8.0.0"}})
+
+	c := newRepositoryClient(t, []Repository{{Name: "stable", URL: url, Username: "alice", Password: "wrong"}})
+	if _, err := c.locateChart(context.Background(), "valkey", "", logr.Discard()); err == nil {
+		t.Error("locateChart() with wrong credentials error = nil")
+	}
+
+	c = newRepositoryClient(t, []Repository{{Name: "stable", URL: url, Username: "alice", Password: "secret"}})
+	if _, err := c.locateChart(context.Background(), "valkey", "", logr.Discard()); err != nil {
+		t.Errorf("locateChart() error = %v", err)
+	}
+}
+
+func TestPullChartCache(t *testing.T) {
+	url := newChartRepository(t, "", "", map[string][]string{"valkey": {"8.0.0"}})
+	c := newRepositoryClient(t, []Repository{{Name: "stable", URL: url}})
+
+	path, err := c.locateChart(context.Background(), "valkey", "8.0.0", logr.Discard())
+	if err != nil {
+		t.Fatalf("locateChart() error = %v", err)
+	}
+	marker := filepath.Join(path, "marker")
+	if err := os.WriteFile(marker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A range resolving to the cached version doesn't replace it
+	if got, err := c.locateChart(context.Background(), "valkey", "^8.0.0", logr.Discard()); err != nil || got != path {
+		t.Errorf("locateChart(^8.0.0) = %s, %v, want %s", got, err, path)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("cached chart was replaced: %v", err)
+	}
+
+	// An exact version is served from the cache without the repository
+	c.repositories[0].URL = "http://127.0.0.1:1"
+	if got, err := c.locateChart(context.Background(), "valkey", "8.0.0", logr.Discard()); err != nil || got != path {
+		t.Errorf("locateChart(8.0.0) without the repository = %s, %v, want %s", got, err, path)
+	}
+	if _, err := c.locateChart(context.Background(), "valkey", "", logr.Discard()); err == nil {
+		t.Error("locateChart() of the latest version without the repository error = nil")
+	}
+}
+
+func TestParseRepositories(t *testing.T) {
+	env := map[string]string{
+		"HELM_REPO_INTERNAL_CHARTS_USERNAME": "alice",
+		"HELM_REPO_INTERNAL_CHARTS_PASSWORD": "secret",
+	}
+	repositories, err := ParseRepositories("bitnami=https://charts.bitnami.com/bitnami, internal-charts=https://charts.example.com",
+		func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatalf("ParseRepositories() error = %v", err)
+	}
+	want := []Repository{
+		{Name: "bitnami", URL: "https://charts.bitnami.com/bitnami"},
+		{Name: "internal-charts", URL: "https://charts.example.com", Username: "alice", Password: "secret"},
+	}
+	if len(repositories) != len(want) || repositories[0] != want[0] || repositories[1] != want[1] {
+		t.Errorf("ParseRepositories() = %+v, want %+v", repositories, want)
+	}
+
+	for _, value := range []string{"bitnami", "=https://example.com", "Bitnami=https://example.com", "a=https://x,a=https://y"} {
+		if _, err := ParseRepositories(value, os.Getenv); err == nil {
+			t.Errorf("ParseRepositories(%q) error = nil", value)
+		}
+	}
+}
+
+func TestWithRepositoryCharts(t *testing.T) {
+	c := NewClient(t.TempDir(), []Repository{{Name: "extra", URL: "https://charts.example.com"}})
+	validator := c.WithRepositoryCharts(staticCharts{"postgresql"})
+
+	for chart, want := range map[string]bool{"postgresql": true, "extra/valkey": true, "valkey": false, "other/valkey": false} {
+		if got := validator.ChartExists(chart); got != want {
+			t.Errorf("ChartExists(%q) = %v, want %v", chart, got, want)
+		}
+	}
+}
+
+// staticCharts is a ChartValidator of a fixed list of charts
+type staticCharts []string
+
+func (s staticCharts) ChartExists(chartName string) bool {
+	for _, name := range s {
+		if name == chartName {
+			return true
+		}
+	}
+	return false
+}
+
+func (s staticCharts) ListCharts() ([]string, error) {
+	return s, nil
+}
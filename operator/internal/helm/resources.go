@@ -0,0 +1,249 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/postrender"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ResourcesMode is how rendered containers without resource requests or limits are handled
+type ResourcesMode string
+
+const (
+	// ResourcesWarn logs the containers without requests or limits
+	ResourcesWarn ResourcesMode = "warn"
+	// ResourcesEnforce fails installs and upgrades rendering containers without requests
+	// or limits
+	ResourcesEnforce ResourcesMode = "enforce"
+	// ResourcesInject sets the missing requests and limits to the policy's defaults
+	ResourcesInject ResourcesMode = "inject"
+)
+
+// checkedResources are the resources every container must request and be limited in
+var checkedResources = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory}
+
+// parseResourceList parses resources in the form cpu=100m,memory=128Mi
+func parseResourceList(s string) (corev1.ResourceList, error) {
+	list := corev1.ResourceList{}
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid resource %q, expected name=quantity", pair)
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity for %s: %w", name, err)
+		}
+		list[corev1.ResourceName(name)] = quantity
+	}
+	return list, nil
+}
+
+// ResourcesPolicy ensures every rendered container requests and is limited in CPU and
+// memory. Kubernetes defaults a container's requests to its limits, so a limit is enough.
+type ResourcesPolicy struct {
+	Mode ResourcesMode
+	// Defaults are the requests and limits injected by ResourcesInject
+	Defaults corev1.ResourceRequirements
+}
+
+// postRenderer returns a post-renderer applying the policy, or nil if there is none
+func (p *ResourcesPolicy) postRenderer(logger logr.Logger) postrender.PostRenderer {
+	if p == nil || p.Mode == "" {
+		return nil
+	}
+	return PostRenderFunc(func(rendered *bytes.Buffer) (*bytes.Buffer, error) {
+		var missing []string
+		out, err := transformManifest(rendered, func(obj map[string]interface{}) error {
+			missing = append(missing, p.apply(obj)...)
+			return nil
+		})
+		if err != nil || len(missing) == 0 {
+			return out, err
+		}
+		if p.Mode == ResourcesEnforce {
+			return nil, fmt.Errorf("containers without resource requests or limits: %s", strings.Join(missing, ", "))
+		}
+		logger.Info("Rendered containers without resource requests or limits", "mode", p.Mode, "missing", missing)
+		return out, nil
+	})
+}
+
+// apply checks, or with ResourcesInject sets, the resources of an object's containers. It
+// returns the requests and limits that are still missing, as Kind/name container: field.
+func (p *ResourcesPolicy) apply(obj map[string]interface{}) []string {
+	podSpec := renderedPodSpec(obj)
+	if podSpec == nil {
+		return nil
+	}
+	kind, _ := obj["kind"].(string)
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+
+	var missing []string
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _ := podSpec[field].([]interface{})
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			containerName, _ := container["name"].(string)
+			for _, field := range p.applyContainer(container) {
+				missing = append(missing, fmt.Sprintf("%s/%s container %s: %s", kind, name, containerName, field))
+			}
+		}
+	}
+	return missing
+}
+
+// applyContainer checks, or injects, the resources of a single container and returns the
+// missing ones, e.g. limits.memory
+func (p *ResourcesPolicy) applyContainer(container map[string]interface{}) []string {
+	resources, _ := container["resources"].(map[string]interface{})
+	requests, _ := resources["requests"].(map[string]interface{})
+	limits, _ := resources["limits"].(map[string]interface{})
+
+	var missing []string
+	for _, name := range checkedResources {
+		_, hasRequest := requests[string(name)]
+		_, hasLimit := limits[string(name)]
+		if hasRequest || hasLimit {
+			continue
+		}
+		defaultRequest, hasDefault := p.Defaults.Requests[name]
+		if p.Mode != ResourcesInject || !hasDefault {
+			missing = append(missing, "requests."+string(name))
+			continue
+		}
+		requests = setRenderedQuantity(requests, name, defaultRequest)
+	}
+	for _, name := range checkedResources {
+		if _, hasLimit := limits[string(name)]; hasLimit {
+			continue
+		}
+		defaultLimit, hasDefault := p.Defaults.Limits[name]
+		if p.Mode != ResourcesInject || !hasDefault {
+			missing = append(missing, "limits."+string(name))
+			continue
+		}
+		// A limit below the container's request would be rejected
+		if request, ok := renderedQuantity(requests, name); ok && request.Cmp(defaultLimit) > 0 {
+			defaultLimit = request
+		}
+		limits = setRenderedQuantity(limits, name, defaultLimit)
+	}
+
+	if p.Mode == ResourcesInject && (len(requests) > 0 || len(limits) > 0) {
+		if resources == nil {
+			resources = map[string]interface{}{}
+			container["resources"] = resources
+		}
+		if len(requests) > 0 {
+			resources["requests"] = requests
+		}
+		if len(limits) > 0 {
+			resources["limits"] = limits
+		}
+	}
+	return missing
+}
+
+// renderedPodSpec returns the pod spec of a rendered pod or workload, or nil
+func renderedPodSpec(obj map[string]interface{}) map[string]interface{} {
+	var path []string
+	switch kind, _ := obj["kind"].(string); kind {
+	case "Pod":
+		path = []string{"spec"}
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController", "Job":
+		path = []string{"spec", "template", "spec"}
+	case "CronJob":
+		path = []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	default:
+		return nil
+	}
+	for _, field := range path {
+		var ok bool
+		if obj, ok = obj[field].(map[string]interface{}); !ok {
+			return nil
+		}
+	}
+	return obj
+}
+
+// renderedQuantity parses a rendered quantity, which may be a string or a number
+func renderedQuantity(list map[string]interface{}, name corev1.ResourceName) (resource.Quantity, bool) {
+	value, ok := list[string(name)]
+	if !ok {
+		return resource.Quantity{}, false
+	}
+	quantity, err := resource.ParseQuantity(fmt.Sprint(value))
+	if err != nil {
+		return resource.Quantity{}, false
+	}
+	return quantity, true
+}
+
+// setRenderedQuantity sets a quantity in a rendered resource list, which is allocated if nil
+func setRenderedQuantity(list map[string]interface{}, name corev1.ResourceName, quantity resource.Quantity) map[string]interface{} {
+	if list == nil {
+		list = map[string]interface{}{}
+	}
+	list[string(name)] = quantity.String()
+	return list
+}
+
+// ParseResourcesPolicy parses a ResourcesMode and the default requests and limits, in the
+// form cpu=100m,memory=128Mi. It returns nil if mode is empty.
+func ParseResourcesPolicy(mode, requests, limits string) (*ResourcesPolicy, error) {
+	policy := &ResourcesPolicy{Mode: ResourcesMode(mode)}
+	switch policy.Mode {
+	case "":
+		return nil, nil
+	case ResourcesWarn, ResourcesEnforce, ResourcesInject:
+	default:
+		return nil, fmt.Errorf("unknown mode %q, expected warn, enforce or inject", mode)
+	}
+
+	var err error
+	if policy.Defaults.Requests, err = parseResourceList(requests); err != nil {
+		return nil, fmt.Errorf("invalid default requests: %w", err)
+	}
+	if policy.Defaults.Limits, err = parseResourceList(limits); err != nil {
+		return nil, fmt.Errorf("invalid default limits: %w", err)
+	}
+	if policy.Mode == ResourcesInject && len(policy.Defaults.Requests) == 0 && len(policy.Defaults.Limits) == 0 {
+		return nil, errors.New("inject requires default requests or limits")
+	}
+	for name, request := range policy.Defaults.Requests {
+		if limit, ok := policy.Defaults.Limits[name]; ok && request.Cmp(limit) > 0 {
+			return nil, fmt.Errorf("default %s request %s exceeds its limit %s", name, request.String(), limit.String())
+		}
+	}
+	return policy, nil
+}
@@ -0,0 +1,199 @@
+package helm
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// limitedDeployment sets requests and limits for its only container
+const limitedDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}
+spec:
+  template:
+    spec:
+      containers:
+      - name: postgres
+        resources:
+          requests:
+            cpu: 250m
+            memory: 256Mi
+          limits:
+            cpu: 1
+            memory: 1Gi
+`
+
+// unlimitedCronJob sets a memory request for its init container only
+const unlimitedCronJob = `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: {{ .Release.Name }}-backup
+spec:
+  schedule: "0 3 * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          initContainers:
+          - name: wait
+            resources:
+              requests:
+                memory: 1Gi
+          containers:
+          - name: backup
+`
+
+// installResourcesChart installs a chart of the templates with the policy's post-renderer
+func installResourcesChart(t *testing.T, policy *ResourcesPolicy, templates ...string) (string, error) {
+	t.Helper()
+	cfg := &action.Configuration{
+		Releases:     storage.Init(driver.NewMemory()),
+		KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+		Capabilities: chartutil.DefaultCapabilities,
+		Log:          func(string, ...interface{}) {},
+	}
+	ch := &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "postgresql", Version: "15.2.0"}}
+	for i, template := range templates {
+		ch.Templates = append(ch.Templates, &chart.File{Name: "templates/" + string(rune('a'+i)) + ".yaml", Data: []byte(template)})
+	}
+
+	install := newInstallAction(cfg, "db", "team-a", "", ActionOptions{})
+	install.PostRenderer = policy.postRenderer(logr.Discard())
+	rel, err := install.Run(ch, nil)
+	if err != nil {
+		return "", err
+	}
+	return rel.Manifest, nil
+}
+
+// containerResources returns the resources of the named container of a rendered object
+func containerResources(t *testing.T, obj map[string]interface{}, name string) map[string]interface{} {
+	t.Helper()
+	spec := renderedPodSpec(obj)
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _ := spec[field].([]interface{})
+		for _, c := range containers {
+			if container := c.(map[string]interface{}); container["name"] == name {
+				resources, _ := container["resources"].(map[string]interface{})
+				return resources
+			}
+		}
+	}
+	t.Fatalf("container %s not found in %v", name, obj)
+	return nil
+}
+
+func TestResourcesPolicyEnforce(t *testing.T) {
+	policy := &ResourcesPolicy{Mode: ResourcesEnforce}
+	if _, err := installResourcesChart(t, policy, limitedDeployment); err != nil {
+		t.Errorf("install of a chart with limits error = %v", err)
+	}
+
+	_, err := installResourcesChart(t, policy, limitedDeployment, unlimitedCronJob)
+	if err == nil {
+		t.Fatal("install of a chart without limits error = nil")
+	}
+	for _, want := range []string{
+		"CronJob/db-backup container wait: requests.cpu",
+		"CronJob/db-backup container wait: limits.memory",
+		"CronJob/db-backup container backup: requests.memory",
+		"CronJob/db-backup container backup: limits.cpu",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %q, want it to contain %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "Deployment") || strings.Contains(err.Error(), "wait: requests.memory") {
+		t.Errorf("error = %q, includes set resources", err)
+	}
+}
+
+func TestResourcesPolicyWarn(t *testing.T) {
+	manifest, err := installResourcesChart(t, &ResourcesPolicy{Mode: ResourcesWarn}, unlimitedCronJob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resources := containerResources(t, renderedObjects(t, manifest)[0], "backup"); resources != nil {
+		t.Errorf("warn changed resources to %v", resources)
+	}
+}
+
+func TestResourcesPolicyInject(t *testing.T) {
+	policy, err := ParseResourcesPolicy("inject", "cpu=100m,memory=128Mi", "memory=512Mi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := installResourcesChart(t, policy, limitedDeployment, unlimitedCronJob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs := renderedObjects(t, manifest)
+	if len(objs) != 2 {
+		t.Fatalf("manifest = %s", manifest)
+	}
+
+	// Set resources are kept
+	var deployment, cronJob map[string]interface{}
+	for _, obj := range objs {
+		if obj["kind"] == "Deployment" {
+			deployment = obj
+		} else {
+			cronJob = obj
+		}
+	}
+	if limits := containerResources(t, deployment, "postgres")["limits"].(map[string]interface{}); limits["memory"] != "1Gi" {
+		t.Errorf("postgres limits = %v", limits)
+	}
+
+	backup := containerResources(t, cronJob, "backup")
+	if requests := backup["requests"].(map[string]interface{}); requests["cpu"] != "100m" || requests["memory"] != "128Mi" {
+		t.Errorf("backup requests = %v", requests)
+	}
+	if limits := backup["limits"].(map[string]interface{}); limits["memory"] != "512Mi" || limits["cpu"] != nil {
+		t.Errorf("backup limits = %v", limits)
+	}
+
+	// A default limit below the request is raised to it
+	wait := containerResources(t, cronJob, "wait")
+	if limits := wait["limits"].(map[string]interface{}); limits["memory"] != "1Gi" {
+		t.Errorf("wait limits = %v", limits)
+	}
+	if requests := wait["requests"].(map[string]interface{}); requests["cpu"] != "100m" || requests["memory"] != "1Gi" {
+		t.Errorf("wait requests = %v", requests)
+	}
+}
+
+func TestParseResourcesPolicy(t *testing.T) {
+	if policy, err := ParseResourcesPolicy("", "", ""); policy != nil || err != nil {
+		t.Errorf("ParseResourcesPolicy(\"\") = %v, %v, want no policy", policy, err)
+	}
+	policy, err := ParseResourcesPolicy("enforce", "", "cpu=1, memory=1Gi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.Mode != ResourcesEnforce || policy.Defaults.Limits.Memory().String() != "1Gi" {
+		t.Errorf("policy = %+v", policy)
+	}
+
+	for _, args := range [][3]string{
+		{"reject", "", ""},
+		{"inject", "", ""},
+		{"warn", "cpu", ""},
+		{"warn", "", "memory=lots"},
+		{"inject", "memory=1Gi", "memory=512Mi"},
+	} {
+		if _, err := ParseResourcesPolicy(args[0], args[1], args[2]); err == nil {
+			t.Errorf("ParseResourcesPolicy(%q) error = nil", args)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultTimeout bounds installs, upgrades, rollbacks and uninstalls unless a timeout is
+// configured
+const DefaultTimeout = 5 * time.Minute
+
+// actionTimeoutKey is the context key of the timeout of the client's actions
+type actionTimeoutKey struct{}
+
+// WithActionTimeout returns a context in which the client's installs, upgrades, rollbacks
+// and uninstalls time out after timeout instead of the client's Timeout, unless their
+// ActionOptions set one
+func WithActionTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, actionTimeoutKey{}, timeout)
+}
+
+// ActionTimeoutFromContext returns the timeout set with WithActionTimeout, or zero
+func ActionTimeoutFromContext(ctx context.Context) time.Duration {
+	timeout, _ := ctx.Value(actionTimeoutKey{}).(time.Duration)
+	return timeout
+}
+
+// timeout returns the first positive one of the given timeout, the context's and the
+// client's, or DefaultTimeout
+func (c *Client) timeout(ctx context.Context, timeout time.Duration) time.Duration {
+	for _, t := range []time.Duration{timeout, ActionTimeoutFromContext(ctx), c.Timeout} {
+		if t > 0 {
+			return t
+		}
+	}
+	return DefaultTimeout
+}
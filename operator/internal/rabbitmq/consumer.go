@@ -3,11 +3,21 @@ package rabbitmq
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"appstore/operator/internal/tracing"
+	sharedrabbitmq "appstore/shared/rabbitmq"
+	sharedtracing "appstore/shared/tracing"
 )
 
 // MessageType defines the type of message being sent
@@ -17,6 +27,7 @@ const (
 	MessageTypeDeploymentRequest MessageType = "deployment.request"
 	MessageTypeDeploymentUpdate  MessageType = "deployment.update"
 	MessageTypeDeploymentDelete  MessageType = "deployment.delete"
+	MessageTypeDeploymentCancel  MessageType = "deployment.cancel"
 )
 
 // Message is the envelope for all RabbitMQ messages
@@ -26,6 +37,8 @@ type Message struct {
 	Timestamp time.Time       `json:"timestamp"`
 	Source    string          `json:"source"`
 	Payload   json.RawMessage `json:"payload"`
+	// Headers carry the trace context of the publisher, as W3C traceparent and tracestate
+	Headers map[string]string `json:"headers,omitempty"`
 }
 
 // DeploymentRequestPayload contains the data for a deployment request
@@ -38,6 +51,22 @@ type DeploymentRequestPayload struct {
 	ReleaseName string                 `json:"releaseName,omitempty"`
 	Version     string                 `json:"version,omitempty"`
 	Values      map[string]interface{} `json:"values,omitempty"`
+	// IdempotencyKey makes retried requests map to the same AppDeployment
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+	// BatchID is shared by requests created together through the batch endpoint
+	BatchID string `json:"batchId,omitempty"`
+	// SecretKeyRefs inject Secret keys in the deployment's namespace into the values
+	SecretKeyRefs []SecretKeyRef `json:"secretKeyRefs,omitempty"`
+	// Priority of the request, also set as the message's AMQP priority
+	Priority uint8 `json:"priority,omitempty"`
+}
+
+// SecretKeyRef injects a single Secret key into the Helm values
+type SecretKeyRef struct {
+	Path     string `json:"path"`
+	Name     string `json:"name"`
+	Key      string `json:"key"`
+	Optional bool   `json:"optional,omitempty"`
 }
 
 // DeploymentUpdatePayload contains the data for updating an existing deployment
@@ -49,6 +78,9 @@ type DeploymentUpdatePayload struct {
 	Namespace string                 `json:"namespace"`
 	Version   string                 `json:"version,omitempty"`
 	Values    map[string]interface{} `json:"values,omitempty"`
+	// Generation, if set, makes the update fail when the AppDeployment's spec has changed
+	// since
+	Generation int64 `json:"generation,omitempty"`
 }
 
 // DeploymentDeletePayload contains the data for deleting a deployment
@@ -60,11 +92,22 @@ type DeploymentDeletePayload struct {
 	Namespace string `json:"namespace"`
 }
 
+// DeploymentCancelPayload contains the data for canceling the install or upgrade of a
+// deployment that is in progress
+type DeploymentCancelPayload struct {
+	RequestID string `json:"requestId"`
+	TeamID    string `json:"teamId"`
+	UserID    string `json:"userId"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
 // MessageHandler is the interface for handling incoming messages
 type MessageHandler interface {
 	HandleDeploymentRequest(ctx context.Context, payload DeploymentRequestPayload) error
 	HandleDeploymentUpdate(ctx context.Context, payload DeploymentUpdatePayload) error
 	HandleDeploymentDelete(ctx context.Context, payload DeploymentDeletePayload) error
+	HandleDeploymentCancel(ctx context.Context, payload DeploymentCancelPayload) error
 }
 
 // ConsumerConfig holds the configuration for the RabbitMQ consumer
@@ -75,8 +118,62 @@ type ConsumerConfig struct {
 	RoutingKeys   []string
 	ConsumerTag   string
 	PrefetchCount int
+	// Concurrency is the number of deliveries handled in parallel (defaults to 1).
+	// Messages for the same deployment are always handled one at a time, in order.
+	// PrefetchCount should be at least Concurrency for all workers to be used.
+	Concurrency int
+	// ShutdownTimeout bounds how long shutdown waits for in-flight messages (defaults to 30s)
+	ShutdownTimeout time.Duration
+	// TLS configures the connection for amqps:// URLs
+	TLS sharedrabbitmq.TLSConfig
+	// MaxPriority declares the queue as a priority queue with x-max-priority, so that
+	// higher priority messages are delivered first. 0 declares a plain queue.
+	MaxPriority uint8
+	// MaxMessageAge drops messages published longer ago, e.g. requests that sat in the
+	// queue during an outage. Zero handles messages of any age.
+	MaxMessageAge time.Duration
+	// MaxAttempts dead-letters a message once handling it has failed this many times,
+	// instead of requeueing it forever, e.g. requests for an app that doesn't exist. Zero
+	// retries forever.
+	MaxAttempts int
+	// RetryDelay delays handling a failed message again. The message is republished to the
+	// <Queue>.retry queue, which routes it back through Exchange once it expires there. Zero
+	// requeues failed messages right away.
+	RetryDelay time.Duration
 }
 
+// ErrMessageExpired is returned for messages older than MaxMessageAge. Such messages are
+// not requeued.
+var ErrMessageExpired = errors.New("message expired")
+
+// ErrMalformedMessage is returned for messages that can't be parsed or have an unknown
+// type. Handling them again can't succeed, so they are not requeued.
+var ErrMalformedMessage = errors.New("malformed message")
+
+// ErrRetryBudgetExhausted is returned for messages whose handling failed MaxAttempts
+// times. They are not requeued.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// Headers of messages republished to the retry queue
+const (
+	// attemptsHeader counts the failed attempts to handle the message
+	attemptsHeader = "x-appstore-attempts"
+	// failureReasonHeader holds the error of the last failed attempt
+	failureReasonHeader = "x-appstore-failure-reason"
+)
+
+// maxFailureReasonLength truncates failure reasons to keep the message headers small
+const maxFailureReasonLength = 1024
+
+// publisher is the part of *amqp.Channel used to republish failed messages
+type publisher interface {
+	PublishWithDeferredConfirmWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool,
+		msg amqp.Publishing) (*amqp.DeferredConfirmation, error)
+}
+
+// defaultShutdownTimeout is used when ConsumerConfig.ShutdownTimeout is not set
+const defaultShutdownTimeout = 30 * time.Second
+
 // Consumer handles consuming messages from RabbitMQ
 type Consumer struct {
 	config    ConsumerConfig
@@ -85,6 +182,13 @@ type Consumer struct {
 	handler   MessageHandler
 	done      chan struct{}
 	reconnect chan struct{}
+
+	// mu guards the connection, stopping and additions to inFlight
+	mu       sync.Mutex
+	stopping bool
+	inFlight sync.WaitGroup
+	// retries republishes failed messages to the retry queue, see RetryDelay
+	retries publisher
 }
 
 // NewConsumer creates a new RabbitMQ consumer
@@ -97,22 +201,29 @@ func NewConsumer(config ConsumerConfig, handler MessageHandler) *Consumer {
 	}
 }
 
-// Start begins consuming messages from RabbitMQ
+// Start begins consuming messages from RabbitMQ. It blocks until ctx is cancelled or the
+// consumer is stopped, and returns only after the in-flight message has been handled, so
+// the consumer can be added to the manager as a Runnable.
 func (c *Consumer) Start(ctx context.Context) error {
 	logger := log.FromContext(ctx).WithName("rabbitmq")
 
 	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return c.cleanup()
+		case <-c.done:
+			return nil
 		default:
 		}
 
 		if err := c.connect(ctx); err != nil {
 			logger.Error(err, "Failed to connect to RabbitMQ, retrying in 5 seconds")
+			c.cleanup()
 			select {
 			case <-ctx.Done():
-				return ctx.Err()
+				return nil
+			case <-c.done:
+				return nil
 			case <-time.After(5 * time.Second):
 				continue
 			}
@@ -120,36 +231,92 @@ func (c *Consumer) Start(ctx context.Context) error {
 
 		logger.Info("Connected to RabbitMQ", "url", c.config.URL)
 
-		if err := c.consume(ctx); err != nil {
-			logger.Error(err, "Consumer error, reconnecting")
-			c.cleanup()
-			continue
+		err := c.consume(ctx)
+		if ctx.Err() != nil || c.isStopping() {
+			logger.Info("RabbitMQ consumer stopped")
+			return c.cleanup()
 		}
 
-		return nil
+		logger.Error(err, "Consumer error, reconnecting")
+		c.cleanup()
 	}
 }
 
-// Stop gracefully stops the consumer
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Only the elected leader
+// consumes deployment messages, so replicas don't race creating AppDeployments; the others
+// start consuming once they are elected. Without leader election the consumer starts right
+// away.
+func (c *Consumer) NeedLeaderElection() bool {
+	return true
+}
+
+// Stop gracefully stops the consumer, waiting up to ShutdownTimeout for in-flight messages
 func (c *Consumer) Stop() error {
-	close(c.done)
-	return c.cleanup()
+	ctx, cancel := context.WithTimeout(context.Background(), c.shutdownTimeout())
+	defer cancel()
+	return c.Shutdown(ctx)
+}
+
+// Shutdown stops accepting new deliveries, waits for in-flight messages to be handled
+// (or ctx to expire) and then closes the connection. Unacknowledged deliveries are
+// redelivered by the broker.
+func (c *Consumer) Shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.stopping {
+		c.stopping = true
+		close(c.done)
+		if c.channel != nil {
+			// Tell the broker to stop delivering to this consumer
+			_ = c.channel.Cancel(c.config.ConsumerTag, false)
+		}
+	}
+	c.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		c.inFlight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return c.cleanup()
+	case <-ctx.Done():
+		return errors.Join(fmt.Errorf("timed out waiting for in-flight messages: %w", ctx.Err()), c.cleanup())
+	}
+}
+
+func (c *Consumer) isStopping() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stopping
+}
+
+func (c *Consumer) shutdownTimeout() time.Duration {
+	if c.config.ShutdownTimeout > 0 {
+		return c.config.ShutdownTimeout
+	}
+	return defaultShutdownTimeout
 }
 
 func (c *Consumer) connect(ctx context.Context) error {
 	logger := log.FromContext(ctx).WithName("rabbitmq")
 
-	var err error
-	c.conn, err = amqp.Dial(c.config.URL)
+	conn, err := sharedrabbitmq.Dial(c.config.URL, c.config.TLS)
 	if err != nil {
 		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
 	}
 
-	c.channel, err = c.conn.Channel()
+	channel, err := conn.Channel()
 	if err != nil {
+		conn.Close()
 		return fmt.Errorf("failed to open channel: %w", err)
 	}
 
+	c.mu.Lock()
+	c.conn, c.channel = conn, channel
+	c.mu.Unlock()
+
 	if err := c.channel.Qos(c.config.PrefetchCount, 0, false); err != nil {
 		return fmt.Errorf("failed to set QoS: %w", err)
 	}
@@ -174,7 +341,7 @@ func (c *Consumer) connect(ctx context.Context) error {
 		false, // delete when unused
 		false, // exclusive
 		false, // no-wait
-		nil,   // arguments
+		c.queueArguments(),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to declare queue: %w", err)
@@ -194,12 +361,62 @@ func (c *Consumer) connect(ctx context.Context) error {
 		logger.Info("Bound queue to exchange", "queue", queue.Name, "routingKey", key)
 	}
 
+	if c.config.RetryDelay > 0 {
+		if err := c.declareRetryQueue(); err != nil {
+			return err
+		}
+		// Retried messages are acked only once the broker confirmed their republish
+		retries, err := conn.Channel()
+		if err != nil {
+			return fmt.Errorf("failed to open retry channel: %w", err)
+		}
+		if err := retries.Confirm(false); err != nil {
+			return fmt.Errorf("failed to enable publisher confirms: %w", err)
+		}
+		c.mu.Lock()
+		c.retries = retries
+		c.mu.Unlock()
+	}
+
 	return nil
 }
 
-func (c *Consumer) consume(ctx context.Context) error {
-	logger := log.FromContext(ctx).WithName("rabbitmq")
+// retryQueue returns the name of the retry queue and of the fanout exchange routing to it
+func (c *Consumer) retryQueue() string {
+	return c.config.Queue + ".retry"
+}
+
+// declareRetryQueue declares the queue that failed messages wait in for RetryDelay. Expired
+// messages are dead-lettered to Exchange with their original routing key, which routes them
+// back to the queue. The queue's own dead letter exchange overrides any policy.
+func (c *Consumer) declareRetryQueue() error {
+	name := c.retryQueue()
+	if err := c.channel.ExchangeDeclare(name, "fanout", true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare retry exchange: %w", err)
+	}
+	if _, err := c.channel.QueueDeclare(name, true, false, false, false, c.retryQueueArguments()); err != nil {
+		return fmt.Errorf("failed to declare retry queue: %w", err)
+	}
+	if err := c.channel.QueueBind(name, "", name, false, nil); err != nil {
+		return fmt.Errorf("failed to bind retry queue: %w", err)
+	}
+	return nil
+}
+
+// retryQueueArguments returns the arguments the retry queue is declared with
+func (c *Consumer) retryQueueArguments() amqp.Table {
+	return amqp.Table{"x-dead-letter-exchange": c.config.Exchange}
+}
+
+// queueArguments returns the arguments the queue is declared with
+func (c *Consumer) queueArguments() amqp.Table {
+	if c.config.MaxPriority == 0 {
+		return nil
+	}
+	return amqp.Table{"x-max-priority": int32(c.config.MaxPriority)}
+}
 
+func (c *Consumer) consume(ctx context.Context) error {
 	msgs, err := c.channel.Consume(
 		c.config.Queue,
 		c.config.ConsumerTag,
@@ -216,6 +433,27 @@ func (c *Consumer) consume(ctx context.Context) error {
 	// Monitor connection close
 	connClose := c.conn.NotifyClose(make(chan *amqp.Error, 1))
 
+	return c.dispatch(ctx, msgs, connClose)
+}
+
+// dispatch hands deliveries to up to Concurrency workers until ctx is cancelled, the
+// consumer is stopped or the connection closes. Deliveries for the same deployment are
+// handled sequentially in arrival order. Deliveries that are being handled are always
+// finished before dispatch returns.
+func (c *Consumer) dispatch(ctx context.Context, msgs <-chan amqp.Delivery, connClose <-chan *amqp.Error) error {
+	logger := log.FromContext(ctx).WithName("rabbitmq")
+
+	slots := make(chan struct{}, c.concurrency())
+	keys := newKeyQueue()
+	defer c.inFlight.Wait()
+
+	// requeue hands a delivery that won't be handled back to the broker
+	requeue := func(msg amqp.Delivery) {
+		if nackErr := msg.Nack(false, true); nackErr != nil {
+			logger.Error(nackErr, "Failed to nack message")
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -229,66 +467,362 @@ func (c *Consumer) consume(ctx context.Context) error {
 				return fmt.Errorf("message channel closed")
 			}
 
-			if err := c.handleMessage(ctx, msg); err != nil {
-				logger.Error(err, "Failed to handle message", "messageId", msg.MessageId)
-				// Nack and requeue on failure
-				if nackErr := msg.Nack(false, true); nackErr != nil {
-					logger.Error(nackErr, "Failed to nack message")
-				}
-			} else {
-				if ackErr := msg.Ack(false); ackErr != nil {
-					logger.Error(ackErr, "Failed to ack message")
+			// Wait for a free worker
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				requeue(msg)
+				return ctx.Err()
+			case <-c.done:
+				requeue(msg)
+				return nil
+			}
+
+			if !c.beginMessage() {
+				<-slots
+				requeue(msg)
+				return nil
+			}
+
+			wait, finish := keys.enqueue(messageKey(msg))
+			go func() {
+				if wait != nil {
+					<-wait
 				}
+				c.processMessage(ctx, msg)
+				finish()
+				<-slots
+				c.inFlight.Done()
+			}()
+		}
+	}
+}
+
+func (c *Consumer) concurrency() int {
+	if c.config.Concurrency > 0 {
+		return c.config.Concurrency
+	}
+	return 1
+}
+
+// messageKey returns the namespace/name of the deployment a message operates on, or ""
+// if the name is generated by the handler and no ordering is needed
+func messageKey(msg amqp.Delivery) string {
+	var envelope Message
+	if err := json.Unmarshal(msg.Body, &envelope); err != nil {
+		return ""
+	}
+
+	var target struct {
+		Namespace   string `json:"namespace"`
+		Name        string `json:"name"`
+		ReleaseName string `json:"releaseName"`
+	}
+	if err := json.Unmarshal(envelope.Payload, &target); err != nil {
+		return ""
+	}
+
+	name := target.Name
+	if name == "" {
+		name = target.ReleaseName
+	}
+	if name == "" {
+		return ""
+	}
+	return target.Namespace + "/" + name
+}
+
+// keyQueue orders work per key. For each key it tracks a channel that is closed when
+// the latest work registered for that key is done.
+type keyQueue struct {
+	mu    sync.Mutex
+	tails map[string]chan struct{}
+}
+
+func newKeyQueue() *keyQueue {
+	return &keyQueue{tails: make(map[string]chan struct{})}
+}
+
+// enqueue registers work for key. It returns a channel to wait on before starting the
+// work (nil if nothing is pending for the key) and a function to call when it is done.
+func (q *keyQueue) enqueue(key string) (<-chan struct{}, func()) {
+	if key == "" {
+		return nil, func() {}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	prev := q.tails[key]
+	tail := make(chan struct{})
+	q.tails[key] = tail
+
+	return prev, func() {
+		close(tail)
+		q.mu.Lock()
+		if q.tails[key] == tail {
+			delete(q.tails, key)
+		}
+		q.mu.Unlock()
+	}
+}
+
+// beginMessage registers an in-flight message unless the consumer is shutting down
+func (c *Consumer) beginMessage() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopping {
+		return false
+	}
+	c.inFlight.Add(1)
+	return true
+}
+
+// processMessage handles and acknowledges a delivery. The handler runs with a context
+// that outlives ctx so shutdown doesn't abort it halfway; it is only cancelled if the
+// handler is still running ShutdownTimeout after shutdown began.
+func (c *Consumer) processMessage(ctx context.Context, msg amqp.Delivery) {
+	logger := log.FromContext(ctx).WithName("rabbitmq")
+
+	handlerCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	go func() {
+		select {
+		case <-handlerCtx.Done():
+			return
+		case <-ctx.Done():
+		case <-c.done:
+		}
+		select {
+		case <-handlerCtx.Done():
+		case <-time.After(c.shutdownTimeout()):
+			cancel()
+		}
+	}()
+
+	msgType := messageTypeLabel(msg)
+	messagesReceived.WithLabelValues(msgType).Inc()
+	start := time.Now()
+	err := c.handleMessage(handlerCtx, msg)
+	messageDuration.WithLabelValues(msgType).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		if errors.Is(err, ErrMalformedMessage) {
+			// Poison messages are dead-lettered rather than redelivered forever. The body isn't
+			// logged as it can hold values; it can be inspected in the dead-letter queue.
+			messagesMalformed.WithLabelValues(msgType).Inc()
+			logger.Error(err, "Dropping malformed message", "type", msgType, "messageId", msg.MessageId,
+				"routingKey", msg.RoutingKey, "bodyLength", len(msg.Body))
+		} else {
+			logger.Error(err, "Failed to handle message", "messageId", msg.MessageId)
+		}
+		// Retry on failure, unless retrying can't succeed or the message has used up its
+		// attempts
+		requeue := !errors.Is(err, ErrConflict) && !errors.Is(err, ErrNamespaceNotWatched) &&
+			!errors.Is(err, ErrMessageExpired) && !errors.Is(err, ErrMalformedMessage)
+		attempts := failedAttempts(msg)
+		if requeue && c.config.MaxAttempts > 0 && attempts >= c.config.MaxAttempts {
+			requeue = false
+			messagesGivenUp.WithLabelValues(msgType).Inc()
+			logger.Error(fmt.Errorf("%w after %d attempts: %w", ErrRetryBudgetExhausted, attempts, err),
+				"Giving up on message", "messageId", msg.MessageId, "routingKey", msg.RoutingKey, "attempts", attempts)
+		}
+		if requeue && c.retry(ctx, msg, attempts, err) {
+			if ackErr := msg.Ack(false); ackErr != nil {
+				logger.Error(ackErr, "Failed to ack retried message")
 			}
+			messagesHandled.WithLabelValues(msgType, outcomeRetried).Inc()
+			return
+		}
+		if nackErr := msg.Nack(false, requeue); nackErr != nil {
+			logger.Error(nackErr, "Failed to nack message")
 		}
+		outcome := outcomeNacked
+		if !requeue {
+			outcome = outcomeDeadLettered
+		}
+		messagesHandled.WithLabelValues(msgType, outcome).Inc()
+	} else {
+		if ackErr := msg.Ack(false); ackErr != nil {
+			logger.Error(ackErr, "Failed to ack message")
+		}
+		messagesHandled.WithLabelValues(msgType, outcomeAcked).Inc()
 	}
 }
 
+// failedAttempts returns the number of failed attempts to handle a message, counting the
+// one that just failed. Messages from the retry queue carry the count in attemptsHeader;
+// quorum queues count immediate redeliveries in the x-delivery-count header.
+func failedAttempts(msg amqp.Delivery) int {
+	return max(headerInt(msg.Headers, attemptsHeader), headerInt(msg.Headers, "x-delivery-count")) + 1
+}
+
+// headerInt returns an integer header, or 0 if it is missing
+func headerInt(headers amqp.Table, name string) int {
+	switch value := headers[name].(type) {
+	case int64:
+		return int(value)
+	case int32:
+		return int(value)
+	case int:
+		return value
+	}
+	return 0
+}
+
+// retry republishes a failed message to the retry queue, to be handled again after
+// RetryDelay, with the number of attempts so far and the error. It returns false if there
+// is no retry queue or publishing fails, in which case the message is requeued instead.
+func (c *Consumer) retry(ctx context.Context, msg amqp.Delivery, attempts int, cause error) bool {
+	c.mu.Lock()
+	retries := c.retries
+	c.mu.Unlock()
+	if retries == nil {
+		return false
+	}
+
+	headers := amqp.Table{}
+	for name, value := range msg.Headers {
+		headers[name] = value
+	}
+	delete(headers, "x-delivery-count")
+	headers[attemptsHeader] = int32(attempts)
+	reason := cause.Error()
+	if len(reason) > maxFailureReasonLength {
+		reason = reason[:maxFailureReasonLength]
+	}
+	headers[failureReasonHeader] = reason
+
+	confirmation, err := retries.PublishWithDeferredConfirmWithContext(ctx, c.retryQueue(), msg.RoutingKey, false, false, amqp.Publishing{
+		Headers:       headers,
+		ContentType:   msg.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		Priority:      msg.Priority,
+		CorrelationId: msg.CorrelationId,
+		Expiration:    strconv.FormatInt(c.config.RetryDelay.Milliseconds(), 10),
+		MessageId:     msg.MessageId,
+		Timestamp:     msg.Timestamp,
+		Type:          msg.Type,
+		AppId:         msg.AppId,
+		Body:          msg.Body,
+	})
+	if err == nil && confirmation != nil {
+		var acked bool
+		if acked, err = confirmation.WaitContext(ctx); err == nil && !acked {
+			err = errors.New("broker rejected the message")
+		}
+	}
+	if err != nil {
+		log.FromContext(ctx).WithName("rabbitmq").Error(err, "Failed to republish message for retry, requeueing it",
+			"messageId", msg.MessageId)
+		return false
+	}
+	return true
+}
+
 func (c *Consumer) handleMessage(ctx context.Context, msg amqp.Delivery) error {
 	logger := log.FromContext(ctx).WithName("rabbitmq")
 
 	var envelope Message
 	if err := json.Unmarshal(msg.Body, &envelope); err != nil {
-		return fmt.Errorf("failed to unmarshal message: %w", err)
+		return fmt.Errorf("%w: failed to unmarshal message: %w", ErrMalformedMessage, err)
 	}
 
 	logger.Info("Received message", "type", envelope.Type, "id", envelope.ID)
 
+	if err := c.checkAge(envelope, msg); err != nil {
+		return err
+	}
+
+	// Continue the trace of the publisher; the handler passes it on to the reconciles
+	ctx, span := tracing.Tracer().Start(sharedtracing.Extract(ctx, envelope.Headers), "process "+string(envelope.Type),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "rabbitmq"),
+			attribute.String("messaging.rabbitmq.destination.routing_key", msg.RoutingKey),
+			attribute.String("messaging.message.id", envelope.ID),
+		),
+	)
+	defer span.End()
+
+	err := c.dispatchMessage(ctx, envelope)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// checkAge returns ErrMessageExpired if the message is older than MaxMessageAge. The age
+// is measured from the envelope's timestamp, or from the delivery's if that is later, as
+// it is for replayed dead letters.
+func (c *Consumer) checkAge(envelope Message, msg amqp.Delivery) error {
+	if c.config.MaxMessageAge <= 0 {
+		return nil
+	}
+	published := envelope.Timestamp
+	if msg.Timestamp.After(published) {
+		published = msg.Timestamp
+	}
+	if published.IsZero() {
+		return nil
+	}
+	if age := time.Since(published); age > c.config.MaxMessageAge {
+		return fmt.Errorf("%w: published %s ago, maximum age is %s", ErrMessageExpired,
+			age.Round(time.Second), c.config.MaxMessageAge)
+	}
+	return nil
+}
+
+// dispatchMessage passes the payload of a message to the handler method for its type
+func (c *Consumer) dispatchMessage(ctx context.Context, envelope Message) error {
 	switch envelope.Type {
 	case MessageTypeDeploymentRequest:
 		var payload DeploymentRequestPayload
 		if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
-			return fmt.Errorf("failed to unmarshal deployment request payload: %w", err)
+			return fmt.Errorf("%w: failed to unmarshal deployment request payload: %w", ErrMalformedMessage, err)
 		}
 		return c.handler.HandleDeploymentRequest(ctx, payload)
 
 	case MessageTypeDeploymentUpdate:
 		var payload DeploymentUpdatePayload
 		if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
-			return fmt.Errorf("failed to unmarshal deployment update payload: %w", err)
+			return fmt.Errorf("%w: failed to unmarshal deployment update payload: %w", ErrMalformedMessage, err)
 		}
 		return c.handler.HandleDeploymentUpdate(ctx, payload)
 
 	case MessageTypeDeploymentDelete:
 		var payload DeploymentDeletePayload
 		if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
-			return fmt.Errorf("failed to unmarshal deployment delete payload: %w", err)
+			return fmt.Errorf("%w: failed to unmarshal deployment delete payload: %w", ErrMalformedMessage, err)
 		}
 		return c.handler.HandleDeploymentDelete(ctx, payload)
 
+	case MessageTypeDeploymentCancel:
+		var payload DeploymentCancelPayload
+		if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
+			return fmt.Errorf("%w: failed to unmarshal deployment cancel payload: %w", ErrMalformedMessage, err)
+		}
+		return c.handler.HandleDeploymentCancel(ctx, payload)
+
 	default:
-		return fmt.Errorf("unknown message type: %s", envelope.Type)
+		return fmt.Errorf("%w: unknown message type: %s", ErrMalformedMessage, envelope.Type)
 	}
 }
 
 func (c *Consumer) cleanup() error {
-	if c.channel != nil {
-		if err := c.channel.Close(); err != nil {
+	c.mu.Lock()
+	conn, channel := c.conn, c.channel
+	c.conn, c.channel, c.retries = nil, nil, nil
+	c.mu.Unlock()
+
+	if channel != nil {
+		if err := channel.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
 			return err
 		}
 	}
-	if c.conn != nil {
-		if err := c.conn.Close(); err != nil {
+	if conn != nil {
+		if err := conn.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
 			return err
 		}
 	}
@@ -0,0 +1,683 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	sharedrabbitmq "appstore/shared/rabbitmq"
+)
+
+// fakeAcknowledger records acks and nacks by delivery tag
+type fakeAcknowledger struct {
+	mu      sync.Mutex
+	acked   []uint64
+	nacked  []uint64
+	dropped []uint64
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, _ bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acked = append(a.acked, tag)
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, _ bool, requeue bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nacked = append(a.nacked, tag)
+	if !requeue {
+		a.dropped = append(a.dropped, tag)
+	}
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func (a *fakeAcknowledger) counts() (acked, nacked int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.acked), len(a.nacked)
+}
+
+// blockingHandler blocks deployment requests until release is closed
+type blockingHandler struct {
+	started  chan struct{}
+	release  chan struct{}
+	finished chan struct{}
+	ctxErr   error
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{
+		started:  make(chan struct{}, 1),
+		release:  make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+}
+
+func (h *blockingHandler) HandleDeploymentRequest(ctx context.Context, _ DeploymentRequestPayload) error {
+	h.started <- struct{}{}
+	select {
+	case <-h.release:
+	case <-ctx.Done():
+	}
+	h.ctxErr = ctx.Err()
+	close(h.finished)
+	return h.ctxErr
+}
+
+func (h *blockingHandler) HandleDeploymentUpdate(context.Context, DeploymentUpdatePayload) error {
+	return nil
+}
+
+func (h *blockingHandler) HandleDeploymentDelete(context.Context, DeploymentDeletePayload) error {
+	return nil
+}
+
+func (h *blockingHandler) HandleDeploymentCancel(context.Context, DeploymentCancelPayload) error {
+	return nil
+}
+
+func newDelivery(t *testing.T, ack amqp.Acknowledger, tag uint64) amqp.Delivery {
+	t.Helper()
+	payload, err := json.Marshal(DeploymentRequestPayload{RequestID: "req", AppName: "postgresql", Namespace: "team-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(Message{Type: MessageTypeDeploymentRequest, ID: "msg", Payload: payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return amqp.Delivery{Acknowledger: ack, DeliveryTag: tag, Body: body}
+}
+
+func waitFor(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func TestShutdownWaitsForInFlightMessage(t *testing.T) {
+	handler := newBlockingHandler()
+	c := NewConsumer(ConsumerConfig{ShutdownTimeout: 5 * time.Second}, handler)
+	ack := &fakeAcknowledger{}
+	msgs := make(chan amqp.Delivery, 2)
+
+	dispatched := make(chan error, 1)
+	go func() { dispatched <- c.dispatch(context.Background(), msgs, nil) }()
+
+	msgs <- newDelivery(t, ack, 1)
+	waitFor(t, handler.started, "handler to start")
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- c.Shutdown(context.Background()) }()
+
+	// A delivery arriving during shutdown must not be handled
+	msgs <- newDelivery(t, ack, 2)
+
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown() returned %v before the in-flight handler finished", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(handler.release)
+
+	if err := <-shutdown; err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	select {
+	case <-handler.finished:
+	default:
+		t.Fatal("Shutdown() returned before the in-flight handler finished")
+	}
+	if handler.ctxErr != nil {
+		t.Errorf("handler context was cancelled: %v", handler.ctxErr)
+	}
+	if err := <-dispatched; err != nil {
+		t.Errorf("dispatch() error = %v", err)
+	}
+
+	ack.mu.Lock()
+	defer ack.mu.Unlock()
+	if len(ack.acked) != 1 || ack.acked[0] != 1 {
+		t.Errorf("acked = %v, want [1]", ack.acked)
+	}
+	for _, tag := range ack.nacked {
+		if tag == 1 {
+			t.Errorf("in-flight delivery was nacked")
+		}
+	}
+}
+
+func TestContextCancelDoesNotAbortInFlightMessage(t *testing.T) {
+	handler := newBlockingHandler()
+	c := NewConsumer(ConsumerConfig{ShutdownTimeout: 5 * time.Second}, handler)
+	ack := &fakeAcknowledger{}
+	msgs := make(chan amqp.Delivery, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dispatched := make(chan error, 1)
+	go func() { dispatched <- c.dispatch(ctx, msgs, nil) }()
+
+	msgs <- newDelivery(t, ack, 1)
+	waitFor(t, handler.started, "handler to start")
+	cancel()
+
+	select {
+	case <-dispatched:
+		t.Fatal("dispatch() returned before the in-flight handler finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(handler.release)
+	<-dispatched
+
+	if handler.ctxErr != nil {
+		t.Errorf("handler context was cancelled: %v", handler.ctxErr)
+	}
+	if acked, _ := ack.counts(); acked != 1 {
+		t.Errorf("acked %d deliveries, want 1", acked)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	handler := newBlockingHandler()
+	c := NewConsumer(ConsumerConfig{ShutdownTimeout: 50 * time.Millisecond}, handler)
+	ack := &fakeAcknowledger{}
+	msgs := make(chan amqp.Delivery, 1)
+
+	go func() { _ = c.dispatch(context.Background(), msgs, nil) }()
+
+	msgs <- newDelivery(t, ack, 1)
+	waitFor(t, handler.started, "handler to start")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := c.Shutdown(ctx); err == nil {
+		t.Fatal("Shutdown() error = nil, want timeout")
+	}
+
+	// The handler is cancelled once the shutdown timeout has passed and the delivery requeued
+	waitFor(t, handler.finished, "handler to be cancelled")
+	if handler.ctxErr == nil {
+		t.Error("handler context was not cancelled after the shutdown timeout")
+	}
+	if err := c.Stop(); err != nil {
+		t.Errorf("Stop() after Shutdown() error = %v", err)
+	}
+}
+
+// trackingHandler records the order of update handling and the peak concurrency
+type trackingHandler struct {
+	mu      sync.Mutex
+	running int
+	peak    int
+	events  []string
+
+	started chan string
+	release chan struct{}
+}
+
+func newTrackingHandler() *trackingHandler {
+	return &trackingHandler{started: make(chan string, 10), release: make(chan struct{})}
+}
+
+func (h *trackingHandler) HandleDeploymentRequest(context.Context, DeploymentRequestPayload) error {
+	return nil
+}
+
+func (h *trackingHandler) HandleDeploymentUpdate(_ context.Context, payload DeploymentUpdatePayload) error {
+	h.mu.Lock()
+	h.running++
+	h.peak = max(h.peak, h.running)
+	h.events = append(h.events, "start "+payload.RequestID)
+	h.mu.Unlock()
+
+	h.started <- payload.RequestID
+	<-h.release
+
+	h.mu.Lock()
+	h.running--
+	h.events = append(h.events, "end "+payload.RequestID)
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *trackingHandler) HandleDeploymentDelete(context.Context, DeploymentDeletePayload) error {
+	return nil
+}
+
+func (h *trackingHandler) HandleDeploymentCancel(context.Context, DeploymentCancelPayload) error {
+	return nil
+}
+
+func newUpdateDelivery(t *testing.T, ack amqp.Acknowledger, tag uint64, requestID, name string) amqp.Delivery {
+	t.Helper()
+	payload, err := json.Marshal(DeploymentUpdatePayload{RequestID: requestID, Name: name, Namespace: "team-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(Message{Type: MessageTypeDeploymentUpdate, ID: requestID, Payload: payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return amqp.Delivery{Acknowledger: ack, DeliveryTag: tag, Body: body}
+}
+
+func TestConcurrentHandling(t *testing.T) {
+	handler := newTrackingHandler()
+	c := NewConsumer(ConsumerConfig{Concurrency: 3}, handler)
+	ack := &fakeAcknowledger{}
+	msgs := make(chan amqp.Delivery, 3)
+
+	dispatched := make(chan error, 1)
+	go func() { dispatched <- c.dispatch(context.Background(), msgs, nil) }()
+
+	for i, name := range []string{"db", "cache", "queue"} {
+		msgs <- newUpdateDelivery(t, ack, uint64(i+1), name, name)
+	}
+
+	// All three handlers run at the same time
+	for range 3 {
+		select {
+		case <-handler.started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for concurrent handlers to start")
+		}
+	}
+	close(handler.release)
+
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	<-dispatched
+
+	if handler.peak != 3 {
+		t.Errorf("peak concurrency = %d, want 3", handler.peak)
+	}
+	if acked, nacked := ack.counts(); acked != 3 || nacked != 0 {
+		t.Errorf("acked %d, nacked %d; want 3 acked", acked, nacked)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	handler := newTrackingHandler()
+	c := NewConsumer(ConsumerConfig{Concurrency: 2}, handler)
+	ack := &fakeAcknowledger{}
+	msgs := make(chan amqp.Delivery, 3)
+
+	go func() { _ = c.dispatch(context.Background(), msgs, nil) }()
+
+	for i, name := range []string{"db", "cache", "queue"} {
+		msgs <- newUpdateDelivery(t, ack, uint64(i+1), name, name)
+	}
+
+	<-handler.started
+	<-handler.started
+	select {
+	case id := <-handler.started:
+		t.Fatalf("handler for %s started while two workers were busy", id)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(handler.release)
+	<-handler.started
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if handler.peak != 2 {
+		t.Errorf("peak concurrency = %d, want 2", handler.peak)
+	}
+}
+
+func TestSameDeploymentIsSerialized(t *testing.T) {
+	handler := newTrackingHandler()
+	c := NewConsumer(ConsumerConfig{Concurrency: 4}, handler)
+	ack := &fakeAcknowledger{}
+	msgs := make(chan amqp.Delivery, 3)
+
+	go func() { _ = c.dispatch(context.Background(), msgs, nil) }()
+
+	msgs <- newUpdateDelivery(t, ack, 1, "first", "db")
+	msgs <- newUpdateDelivery(t, ack, 2, "second", "db")
+	msgs <- newUpdateDelivery(t, ack, 3, "other", "cache")
+
+	// The other deployment isn't blocked by the busy one
+	got := map[string]bool{}
+	for range 2 {
+		select {
+		case id := <-handler.started:
+			got[id] = true
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for handlers to start")
+		}
+	}
+	if !got["first"] || !got["other"] {
+		t.Fatalf("started %v, want first and other", got)
+	}
+	select {
+	case id := <-handler.started:
+		t.Fatalf("%s started while an earlier message for the same deployment was in flight", id)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(handler.release)
+	<-handler.started
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	var order []string
+	for _, e := range handler.events {
+		if e == "end first" || e == "start second" {
+			order = append(order, e)
+		}
+	}
+	if len(order) != 2 || order[0] != "end first" || order[1] != "start second" {
+		t.Errorf("events for db = %v, want first to end before second starts", order)
+	}
+}
+
+func TestMessageKey(t *testing.T) {
+	ack := &fakeAcknowledger{}
+	if got := messageKey(newUpdateDelivery(t, ack, 1, "req", "db")); got != "team-a/db" {
+		t.Errorf("messageKey(update) = %q, want %q", got, "team-a/db")
+	}
+	if got := messageKey(newDelivery(t, ack, 1)); got != "" {
+		t.Errorf("messageKey(request without release name) = %q, want empty", got)
+	}
+	if got := messageKey(amqp.Delivery{Body: []byte("not json")}); got != "" {
+		t.Errorf("messageKey(malformed) = %q, want empty", got)
+	}
+}
+
+// conflictHandler fails every update with ErrConflict
+type conflictHandler struct{ trackingHandler }
+
+func (h *conflictHandler) HandleDeploymentUpdate(context.Context, DeploymentUpdatePayload) error {
+	return ErrConflict
+}
+
+func TestConflictIsNotRequeued(t *testing.T) {
+	c := NewConsumer(ConsumerConfig{}, &conflictHandler{})
+	ack := &fakeAcknowledger{}
+
+	c.processMessage(context.Background(), newUpdateDelivery(t, ack, 1, "req", "db"))
+
+	if len(ack.dropped) != 1 || ack.dropped[0] != 1 {
+		t.Errorf("dropped = %v, want [1]", ack.dropped)
+	}
+}
+
+func TestMalformedMessagesAreNotRequeued(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		msgType string
+	}{
+		{"invalid JSON", "not json", unknownMessageType},
+		{"unknown type", `{"type":"deployment.unknown","id":"msg","payload":{}}`, unknownMessageType},
+		{"invalid payload", `{"type":"deployment.request","id":"msg","payload":"not an object"}`, string(MessageTypeDeploymentRequest)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConsumer(ConsumerConfig{}, newTrackingHandler())
+			ack := &fakeAcknowledger{}
+			malformed := testutil.ToFloat64(messagesMalformed.WithLabelValues(tt.msgType))
+
+			c.processMessage(context.Background(), amqp.Delivery{Acknowledger: ack, DeliveryTag: 1, Body: []byte(tt.body)})
+
+			if len(ack.dropped) != 1 || len(ack.acked) != 0 {
+				t.Errorf("acked = %v, dropped = %v, want only dropped", ack.acked, ack.dropped)
+			}
+			if got := testutil.ToFloat64(messagesMalformed.WithLabelValues(tt.msgType)) - malformed; got != 1 {
+				t.Errorf("malformed messages counted %v times, want 1", got)
+			}
+		})
+	}
+}
+
+func TestHandlerErrorsAreRequeued(t *testing.T) {
+	c := NewConsumer(ConsumerConfig{}, &failingHandler{})
+	ack := &fakeAcknowledger{}
+	malformed := testutil.ToFloat64(messagesMalformed.WithLabelValues(string(MessageTypeDeploymentRequest)))
+
+	c.processMessage(context.Background(), newDelivery(t, ack, 1))
+
+	if len(ack.nacked) != 1 || len(ack.dropped) != 0 {
+		t.Errorf("nacked = %v, dropped = %v, want the message requeued", ack.nacked, ack.dropped)
+	}
+	if got := testutil.ToFloat64(messagesMalformed.WithLabelValues(string(MessageTypeDeploymentRequest))); got != malformed {
+		t.Error("a handler error was counted as a malformed message")
+	}
+}
+
+func TestQueueArguments(t *testing.T) {
+	if args := NewConsumer(ConsumerConfig{}, nil).queueArguments(); args != nil {
+		t.Errorf("queueArguments() without priority = %v, want nil", args)
+	}
+
+	args := NewConsumer(ConsumerConfig{MaxPriority: sharedrabbitmq.MaxPriority}, nil).queueArguments()
+	if got, ok := args["x-max-priority"].(int32); !ok || got != sharedrabbitmq.MaxPriority {
+		t.Errorf("x-max-priority = %v, want %d", args["x-max-priority"], sharedrabbitmq.MaxPriority)
+	}
+	if err := args.Validate(); err != nil {
+		t.Errorf("queue arguments are invalid: %v", err)
+	}
+}
+
+func newTimestampedDelivery(t *testing.T, ack amqp.Acknowledger, tag uint64, published time.Time) amqp.Delivery {
+	t.Helper()
+	d := newDelivery(t, ack, tag)
+	var envelope Message
+	if err := json.Unmarshal(d.Body, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	envelope.Timestamp = published
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Body = body
+	return d
+}
+
+func TestMaxMessageAge(t *testing.T) {
+	handler := newTrackingHandler()
+	c := NewConsumer(ConsumerConfig{MaxMessageAge: time.Hour}, handler)
+	ack := &fakeAcknowledger{}
+
+	c.processMessage(context.Background(), newTimestampedDelivery(t, ack, 1, time.Now().Add(-time.Minute)))
+	c.processMessage(context.Background(), newTimestampedDelivery(t, ack, 2, time.Now().Add(-2*time.Hour)))
+
+	// A replayed dead letter is as old as its replay
+	replayed := newTimestampedDelivery(t, ack, 3, time.Now().Add(-2*time.Hour))
+	replayed.Timestamp = time.Now()
+	c.processMessage(context.Background(), replayed)
+
+	if len(ack.acked) != 2 || ack.acked[0] != 1 || ack.acked[1] != 3 {
+		t.Errorf("acked = %v, want [1 3]", ack.acked)
+	}
+	if len(ack.dropped) != 1 || ack.dropped[0] != 2 {
+		t.Errorf("dropped = %v, want [2]", ack.dropped)
+	}
+}
+
+// flakyHandler fails deployment requests until failures is used up
+type flakyHandler struct {
+	trackingHandler
+	failures int
+}
+
+func (h *flakyHandler) HandleDeploymentRequest(context.Context, DeploymentRequestPayload) error {
+	if h.failures > 0 {
+		h.failures--
+		return errors.New("API server unavailable")
+	}
+	return nil
+}
+
+// recordingPublisher records republished messages
+type recordingPublisher struct {
+	exchanges []string
+	keys      []string
+	published []amqp.Publishing
+	err       error
+}
+
+func (p *recordingPublisher) PublishWithDeferredConfirmWithContext(_ context.Context, exchange, key string, _, _ bool,
+	msg amqp.Publishing) (*amqp.DeferredConfirmation, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.exchanges = append(p.exchanges, exchange)
+	p.keys = append(p.keys, key)
+	p.published = append(p.published, msg)
+	return nil, nil
+}
+
+// redeliver returns the delivery of a message republished to the retry queue once it
+// expired there
+func (p *recordingPublisher) redeliver(ack amqp.Acknowledger, tag uint64) amqp.Delivery {
+	msg := p.published[len(p.published)-1]
+	return amqp.Delivery{Acknowledger: ack, DeliveryTag: tag, Headers: msg.Headers, Body: msg.Body,
+		RoutingKey: p.keys[len(p.keys)-1], MessageId: msg.MessageId}
+}
+
+func newRetryingConsumer(config ConsumerConfig, handler MessageHandler) (*Consumer, *recordingPublisher) {
+	config.Queue = "appstore.deployments"
+	config.RetryDelay = 5 * time.Second
+	c := NewConsumer(config, handler)
+	retries := &recordingPublisher{}
+	c.retries = retries
+	return c, retries
+}
+
+func TestRetryQueue(t *testing.T) {
+	c, retries := newRetryingConsumer(ConsumerConfig{}, &failingHandler{})
+	ack := &fakeAcknowledger{}
+
+	msg := newDelivery(t, ack, 1)
+	msg.RoutingKey = "deployment.request.team-a"
+	msg.MessageId = "msg"
+	c.processMessage(context.Background(), msg)
+	c.processMessage(context.Background(), retries.redeliver(ack, 2))
+
+	if len(ack.acked) != 2 || len(ack.nacked) != 0 {
+		t.Fatalf("acked = %v, nacked = %v, want both attempts acked after republishing", ack.acked, ack.nacked)
+	}
+	if len(retries.published) != 2 {
+		t.Fatalf("republished %d messages, want 2", len(retries.published))
+	}
+	for i, got := range retries.published {
+		if retries.exchanges[i] != "appstore.deployments.retry" || retries.keys[i] != "deployment.request.team-a" {
+			t.Errorf("republish %d went to %q with key %q, want the retry exchange with the original key",
+				i, retries.exchanges[i], retries.keys[i])
+		}
+		if got.Expiration != "5000" {
+			t.Errorf("republish %d expiration = %q, want 5000", i, got.Expiration)
+		}
+		if got.Headers[attemptsHeader] != int32(i+1) {
+			t.Errorf("republish %d %s = %v, want %d", i, attemptsHeader, got.Headers[attemptsHeader], i+1)
+		}
+		if got.Headers[failureReasonHeader] != errHandlerFailed.Error() {
+			t.Errorf("republish %d %s = %v, want %q", i, failureReasonHeader, got.Headers[failureReasonHeader], errHandlerFailed)
+		}
+		if got.MessageId != "msg" || string(got.Body) != string(msg.Body) {
+			t.Errorf("republish %d = %+v, want the original message", i, got)
+		}
+	}
+	if err := c.retryQueueArguments().Validate(); err != nil {
+		t.Errorf("retry queue arguments are invalid: %v", err)
+	}
+}
+
+func TestRetryQueuePublishFailureRequeues(t *testing.T) {
+	c, retries := newRetryingConsumer(ConsumerConfig{}, &failingHandler{})
+	retries.err = amqp.ErrClosed
+	ack := &fakeAcknowledger{}
+
+	c.processMessage(context.Background(), newDelivery(t, ack, 1))
+
+	if len(ack.acked) != 0 || len(ack.nacked) != 1 || len(ack.dropped) != 0 {
+		t.Errorf("acked = %v, nacked = %v, dropped = %v, want the message requeued", ack.acked, ack.nacked, ack.dropped)
+	}
+}
+
+func TestMaxAttempts(t *testing.T) {
+	c, retries := newRetryingConsumer(ConsumerConfig{MaxAttempts: 3}, &failingHandler{})
+	ack := &fakeAcknowledger{}
+	givenUp := testutil.ToFloat64(messagesGivenUp.WithLabelValues(string(MessageTypeDeploymentRequest)))
+
+	c.processMessage(context.Background(), newDelivery(t, ack, 1))
+	c.processMessage(context.Background(), retries.redeliver(ack, 2))
+	c.processMessage(context.Background(), retries.redeliver(ack, 3))
+
+	if len(retries.published) != 2 || len(ack.dropped) != 1 || ack.dropped[0] != 3 {
+		t.Errorf("republished %d, dropped = %v, want the third attempt dead-lettered", len(retries.published), ack.dropped)
+	}
+	if got := testutil.ToFloat64(messagesGivenUp.WithLabelValues(string(MessageTypeDeploymentRequest))) - givenUp; got != 1 {
+		t.Errorf("messages given up = %v, want 1", got)
+	}
+
+	// A replayed dead letter is republished without the headers and gets a new budget
+	c.processMessage(context.Background(), newDelivery(t, ack, 4))
+	if len(ack.dropped) != 1 {
+		t.Errorf("dropped = %v, want the replayed message retried", ack.dropped)
+	}
+}
+
+func TestMaxAttemptsResetOnSuccess(t *testing.T) {
+	handler := &flakyHandler{trackingHandler: *newTrackingHandler(), failures: 2}
+	c, retries := newRetryingConsumer(ConsumerConfig{MaxAttempts: 3}, handler)
+	ack := &fakeAcknowledger{}
+
+	c.processMessage(context.Background(), newDelivery(t, ack, 1))
+	c.processMessage(context.Background(), retries.redeliver(ack, 2))
+	c.processMessage(context.Background(), retries.redeliver(ack, 3))
+	if len(ack.dropped) != 0 || len(ack.acked) != 3 || len(retries.published) != 2 {
+		t.Fatalf("acked = %v, dropped = %v, want the third attempt to succeed", ack.acked, ack.dropped)
+	}
+}
+
+func TestMaxAttemptsDeliveryCount(t *testing.T) {
+	c := NewConsumer(ConsumerConfig{MaxAttempts: 3}, &failingHandler{})
+	ack := &fakeAcknowledger{}
+
+	// A quorum queue already redelivered the message twice, e.g. to another replica
+	msg := newDelivery(t, ack, 1)
+	msg.Headers = amqp.Table{"x-delivery-count": int64(2)}
+	c.processMessage(context.Background(), msg)
+
+	if len(ack.dropped) != 1 {
+		t.Errorf("dropped = %v, want the message dead-lettered", ack.dropped)
+	}
+}
+
+func TestNoMaxAttemptsRequeuesForever(t *testing.T) {
+	c := NewConsumer(ConsumerConfig{}, &failingHandler{})
+	ack := &fakeAcknowledger{}
+
+	for tag := uint64(1); tag <= 10; tag++ {
+		c.processMessage(context.Background(), newDelivery(t, ack, tag))
+	}
+	if len(ack.nacked) != 10 || len(ack.dropped) != 0 {
+		t.Errorf("nacked = %v, dropped = %v, want every attempt requeued", ack.nacked, ack.dropped)
+	}
+}
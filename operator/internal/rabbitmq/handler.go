@@ -2,29 +2,116 @@ package rabbitmq
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	appstore "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/tracing"
 )
 
+const (
+	// IdempotencyKeyLabel stores the Idempotency-Key of the request that created an AppDeployment
+	IdempotencyKeyLabel = "appstore.bitpipe.no/idempotency-key"
+	// BatchIDLabel groups AppDeployments created by the same batch request
+	BatchIDLabel = "appstore.bitpipe.no/batch-id"
+)
+
+// ErrConflict is returned when an update was requested against a generation that is no
+// longer current. Such messages are not requeued.
+var ErrConflict = errors.New("AppDeployment has been modified")
+
+// ErrNamespaceNotWatched is returned for messages targeting a namespace the operator doesn't
+// watch. A namespace-scoped operator's queue is only bound to the routing keys of its
+// namespaces, so such messages were published with a routing key that doesn't match their
+// namespace. They are not requeued.
+var ErrNamespaceNotWatched = errors.New("namespace is not watched by this operator")
+
+// DeploymentQueue is the queue consumed by an operator watching all namespaces
+const DeploymentQueue = "appstore.deployments"
+
+// deploymentMessageTypes are the message types the backend publishes for AppDeployments.
+// Their routing keys are the type followed by the deployment's namespace.
+var deploymentMessageTypes = []MessageType{
+	MessageTypeDeploymentRequest,
+	MessageTypeDeploymentUpdate,
+	MessageTypeDeploymentDelete,
+	MessageTypeDeploymentCancel,
+}
+
+// DeploymentQueueFor returns the queue an operator watching namespaces consumes. Each set
+// of namespaces gets its own queue, so that namespace-scoped operators don't take each
+// other's messages off a shared queue.
+func DeploymentQueueFor(namespaces []string) string {
+	if len(namespaces) == 0 {
+		return DeploymentQueue
+	}
+	// Namespace names can't contain dots; sorting keeps the name independent of the order
+	// the namespaces were given in
+	sorted := slices.Sorted(slices.Values(namespaces))
+	return DeploymentQueue + ".ns." + strings.Join(sorted, ".")
+}
+
+// DeploymentRoutingKeys returns the routing keys to bind the queue of an operator watching
+// namespaces to. Watching all namespaces also binds the routing keys without a namespace,
+// which older backends publish with.
+func DeploymentRoutingKeys(namespaces []string) []string {
+	var keys []string
+	for _, msgType := range deploymentMessageTypes {
+		if len(namespaces) == 0 {
+			keys = append(keys, string(msgType)+".#")
+			continue
+		}
+		for _, ns := range namespaces {
+			keys = append(keys, string(msgType)+"."+ns)
+		}
+	}
+	return keys
+}
+
 // DeploymentHandler handles deployment messages by creating/updating/deleting AppDeployment CRs
 type DeploymentHandler struct {
 	client client.Client
+	// recorder records Events for updates rejected after the backend accepted them
+	recorder record.EventRecorder
+	// namespaces the handler may act in; nil allows all namespaces
+	namespaces map[string]bool
+}
+
+// NewDeploymentHandler creates a new deployment handler. With watch namespaces, messages
+// for other namespaces are rejected, since no AppDeployment there would be reconciled.
+func NewDeploymentHandler(c client.Client, recorder record.EventRecorder, watchNamespaces []string) *DeploymentHandler {
+	h := &DeploymentHandler{
+		client:   c,
+		recorder: recorder,
+	}
+	if len(watchNamespaces) > 0 {
+		h.namespaces = make(map[string]bool, len(watchNamespaces))
+		for _, ns := range watchNamespaces {
+			h.namespaces[ns] = true
+		}
+	}
+	return h
 }
 
-// NewDeploymentHandler creates a new deployment handler
-func NewDeploymentHandler(c client.Client) *DeploymentHandler {
-	return &DeploymentHandler{
-		client: c,
+// checkNamespace returns ErrNamespaceNotWatched if namespace is outside the watched namespaces
+func (h *DeploymentHandler) checkNamespace(namespace string) error {
+	if h.namespaces != nil && !h.namespaces[namespace] {
+		return fmt.Errorf("%w: %q", ErrNamespaceNotWatched, namespace)
 	}
+	return nil
 }
 
 // HandleDeploymentRequest creates a new AppDeployment CR
@@ -33,14 +120,24 @@ func (h *DeploymentHandler) HandleDeploymentRequest(ctx context.Context, payload
 		"requestId", payload.RequestID,
 		"appName", payload.AppName,
 		"namespace", payload.Namespace,
+		"priority", payload.Priority,
 	)
 
 	logger.Info("Handling deployment request")
 
-	// Generate name if not provided
+	if err := h.checkNamespace(payload.Namespace); err != nil {
+		return err
+	}
+
+	// Generate name if not provided. With an idempotency key the name is derived from
+	// the key so that retried requests map to the same AppDeployment.
 	name := payload.ReleaseName
 	if name == "" {
-		name = fmt.Sprintf("%s-%s", payload.AppName, payload.RequestID[:8])
+		if payload.IdempotencyKey != "" {
+			name = idempotentName(payload.AppName, payload.IdempotencyKey)
+		} else {
+			name = fmt.Sprintf("%s-%s", payload.AppName, payload.RequestID[:8])
+		}
 	}
 
 	// Convert values to JSON
@@ -77,6 +174,23 @@ func (h *DeploymentHandler) HandleDeploymentRequest(ctx context.Context, payload
 		},
 	}
 
+	for _, ref := range payload.SecretKeyRefs {
+		appDeployment.Spec.SecretKeyRefs = append(appDeployment.Spec.SecretKeyRefs, appstore.SecretKeyRef{
+			Path:     ref.Path,
+			Name:     ref.Name,
+			Key:      ref.Key,
+			Optional: ref.Optional,
+		})
+	}
+
+	if payload.IdempotencyKey != "" {
+		appDeployment.Labels[IdempotencyKeyLabel] = payload.IdempotencyKey
+	}
+	if payload.BatchID != "" {
+		appDeployment.Labels[BatchIDLabel] = payload.BatchID
+	}
+	tracing.InjectAnnotations(ctx, appDeployment.Annotations)
+
 	// Check if namespace exists, create if needed
 	if err := h.ensureNamespace(ctx, payload.Namespace); err != nil {
 		return fmt.Errorf("failed to ensure namespace: %w", err)
@@ -84,7 +198,7 @@ func (h *DeploymentHandler) HandleDeploymentRequest(ctx context.Context, payload
 
 	// Create the AppDeployment
 	if err := h.client.Create(ctx, appDeployment); err != nil {
-		if errors.IsAlreadyExists(err) {
+		if apierrors.IsAlreadyExists(err) {
 			logger.Info("AppDeployment already exists", "name", name)
 			return nil
 		}
@@ -105,13 +219,17 @@ func (h *DeploymentHandler) HandleDeploymentUpdate(ctx context.Context, payload
 
 	logger.Info("Handling deployment update")
 
+	if err := h.checkNamespace(payload.Namespace); err != nil {
+		return err
+	}
+
 	// Get existing AppDeployment
 	appDeployment := &appstore.AppDeployment{}
 	if err := h.client.Get(ctx, types.NamespacedName{
 		Name:      payload.Name,
 		Namespace: payload.Namespace,
 	}, appDeployment); err != nil {
-		if errors.IsNotFound(err) {
+		if apierrors.IsNotFound(err) {
 			return fmt.Errorf("AppDeployment not found: %s/%s", payload.Namespace, payload.Name)
 		}
 		return fmt.Errorf("failed to get AppDeployment: %w", err)
@@ -122,6 +240,14 @@ func (h *DeploymentHandler) HandleDeploymentUpdate(ctx context.Context, payload
 		return fmt.Errorf("team mismatch: expected %s, got %s", appDeployment.Spec.TeamID, payload.TeamID)
 	}
 
+	// Optimistic concurrency: the update must apply to the spec the client has seen. The
+	// generation only changes with the spec, so status updates don't conflict.
+	if payload.Generation != 0 && appDeployment.Generation != payload.Generation {
+		err := fmt.Errorf("%w: expected generation %d, got %d", ErrConflict, payload.Generation, appDeployment.Generation)
+		h.conflict(appDeployment, payload, err)
+		return err
+	}
+
 	// Update fields
 	if payload.Version != "" {
 		appDeployment.Spec.ChartVersion = payload.Version
@@ -135,7 +261,14 @@ func (h *DeploymentHandler) HandleDeploymentUpdate(ctx context.Context, payload
 		appDeployment.Spec.Values = &apiextensionsv1.JSON{Raw: valuesBytes}
 	}
 
-	// Update the AppDeployment
+	// The reconciles applying the update continue its trace
+	if appDeployment.Annotations == nil {
+		appDeployment.Annotations = map[string]string{}
+	}
+	tracing.InjectAnnotations(ctx, appDeployment.Annotations)
+
+	// Update the AppDeployment. A conflict with a concurrent write is retried, and checks
+	// the generation again.
 	if err := h.client.Update(ctx, appDeployment); err != nil {
 		return fmt.Errorf("failed to update AppDeployment: %w", err)
 	}
@@ -144,6 +277,16 @@ func (h *DeploymentHandler) HandleDeploymentUpdate(ctx context.Context, payload
 	return nil
 }
 
+// conflict records a warning Event for an update that was rejected by ErrConflict. The
+// backend has already accepted the update, so this is how its client learns about it.
+func (h *DeploymentHandler) conflict(appDeployment *appstore.AppDeployment, payload DeploymentUpdatePayload, err error) {
+	if h.recorder == nil {
+		return
+	}
+	h.recorder.Eventf(appDeployment, corev1.EventTypeWarning, "UpdateConflict",
+		"Update %s was rejected: %v", payload.RequestID, err)
+}
+
 // HandleDeploymentDelete deletes an AppDeployment CR
 func (h *DeploymentHandler) HandleDeploymentDelete(ctx context.Context, payload DeploymentDeletePayload) error {
 	logger := log.FromContext(ctx).WithName("handler").WithValues(
@@ -154,13 +297,17 @@ func (h *DeploymentHandler) HandleDeploymentDelete(ctx context.Context, payload
 
 	logger.Info("Handling deployment delete")
 
+	if err := h.checkNamespace(payload.Namespace); err != nil {
+		return err
+	}
+
 	// Get existing AppDeployment to verify ownership
 	appDeployment := &appstore.AppDeployment{}
 	if err := h.client.Get(ctx, types.NamespacedName{
 		Name:      payload.Name,
 		Namespace: payload.Namespace,
 	}, appDeployment); err != nil {
-		if errors.IsNotFound(err) {
+		if apierrors.IsNotFound(err) {
 			logger.Info("AppDeployment already deleted", "name", payload.Name)
 			return nil
 		}
@@ -174,7 +321,7 @@ func (h *DeploymentHandler) HandleDeploymentDelete(ctx context.Context, payload
 
 	// Delete the AppDeployment
 	if err := h.client.Delete(ctx, appDeployment); err != nil {
-		if errors.IsNotFound(err) {
+		if apierrors.IsNotFound(err) {
 			return nil
 		}
 		return fmt.Errorf("failed to delete AppDeployment: %w", err)
@@ -184,6 +331,57 @@ func (h *DeploymentHandler) HandleDeploymentDelete(ctx context.Context, payload
 	return nil
 }
 
+// HandleDeploymentCancel marks an AppDeployment for the controller to cancel its install
+// or upgrade in progress
+func (h *DeploymentHandler) HandleDeploymentCancel(ctx context.Context, payload DeploymentCancelPayload) error {
+	logger := log.FromContext(ctx).WithName("handler").WithValues(
+		"requestId", payload.RequestID,
+		"name", payload.Name,
+		"namespace", payload.Namespace,
+	)
+
+	logger.Info("Handling deployment cancel")
+
+	if err := h.checkNamespace(payload.Namespace); err != nil {
+		return err
+	}
+
+	appDeployment := &appstore.AppDeployment{}
+	if err := h.client.Get(ctx, types.NamespacedName{
+		Name:      payload.Name,
+		Namespace: payload.Namespace,
+	}, appDeployment); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("AppDeployment not found, nothing to cancel", "name", payload.Name)
+			return nil
+		}
+		return fmt.Errorf("failed to get AppDeployment: %w", err)
+	}
+
+	// Verify team ownership
+	if appDeployment.Spec.TeamID != payload.TeamID {
+		return fmt.Errorf("team mismatch: expected %s, got %s", appDeployment.Spec.TeamID, payload.TeamID)
+	}
+
+	if appDeployment.Annotations == nil {
+		appDeployment.Annotations = map[string]string{}
+	}
+	appDeployment.Annotations[appstore.CancelRequestedAnnotation] = payload.RequestID
+
+	if err := h.client.Update(ctx, appDeployment); err != nil {
+		return fmt.Errorf("failed to update AppDeployment: %w", err)
+	}
+
+	logger.Info("Requested cancel of AppDeployment", "name", payload.Name)
+	return nil
+}
+
+// idempotentName derives a stable AppDeployment name from an idempotency key
+func idempotentName(appName, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("%s-%x", appName, sum[:4])
+}
+
 func (h *DeploymentHandler) ensureNamespace(ctx context.Context, namespace string) error {
 	// For now, we assume namespaces are pre-created
 	// In a production setup, you might want to create team namespaces automatically
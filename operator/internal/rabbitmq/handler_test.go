@@ -0,0 +1,290 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appstore "appstore/operator/api/v1alpha1"
+)
+
+func newTestHandler(t *testing.T, objects ...client.Object) (*DeploymentHandler, client.Client) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := appstore.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() error = %v", err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).
+		WithStatusSubresource(&appstore.AppDeployment{}).Build()
+	return NewDeploymentHandler(c, nil, nil), c
+}
+
+func TestHandleDeploymentRequestRetryWithIdempotencyKey(t *testing.T) {
+	h, c := newTestHandler(t)
+	ctx := context.Background()
+
+	payload := DeploymentRequestPayload{
+		RequestID:      "11111111-aaaa-bbbb-cccc-000000000000",
+		TeamID:         "team-a",
+		UserID:         "alice",
+		AppName:        "postgresql",
+		Namespace:      "team-a",
+		IdempotencyKey: "create-db-1",
+	}
+	if err := h.HandleDeploymentRequest(ctx, payload); err != nil {
+		t.Fatalf("first HandleDeploymentRequest() error = %v", err)
+	}
+
+	// A client retry gets a new request ID from the backend
+	payload.RequestID = "22222222-aaaa-bbbb-cccc-000000000000"
+	if err := h.HandleDeploymentRequest(ctx, payload); err != nil {
+		t.Fatalf("retried HandleDeploymentRequest() error = %v", err)
+	}
+
+	var list appstore.AppDeploymentList
+	if err := c.List(ctx, &list, client.InNamespace("team-a")); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list.Items) != 1 {
+		t.Fatalf("got %d AppDeployments, want 1", len(list.Items))
+	}
+
+	ad := list.Items[0]
+	if want := idempotentName("postgresql", "create-db-1"); ad.Name != want {
+		t.Errorf("name = %q, want %q", ad.Name, want)
+	}
+	if got := ad.Labels[IdempotencyKeyLabel]; got != "create-db-1" {
+		t.Errorf("idempotency label = %q, want %q", got, "create-db-1")
+	}
+	if got := ad.Labels["appstore.bitpipe.no/request-id"]; got != "11111111-aaaa-bbbb-cccc-000000000000" {
+		t.Errorf("request-id label = %q, want the first request", got)
+	}
+}
+
+func TestHandleDeploymentRequestSecretKeyRefs(t *testing.T) {
+	h, c := newTestHandler(t)
+	ctx := context.Background()
+
+	refs := []SecretKeyRef{{Path: "auth.password", Name: "db-credentials", Key: "password", Optional: true}}
+	if err := h.HandleDeploymentRequest(ctx, DeploymentRequestPayload{
+		RequestID:     "11111111-aaaa-bbbb-cccc-000000000000",
+		TeamID:        "team-a",
+		AppName:       "postgresql",
+		Namespace:     "team-a",
+		ReleaseName:   "db",
+		SecretKeyRefs: refs,
+	}); err != nil {
+		t.Fatalf("HandleDeploymentRequest() error = %v", err)
+	}
+
+	var ad appstore.AppDeployment
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "team-a", Name: "db"}, &ad); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	want := []appstore.SecretKeyRef{{Path: "auth.password", Name: "db-credentials", Key: "password", Optional: true}}
+	if !reflect.DeepEqual(ad.Spec.SecretKeyRefs, want) {
+		t.Errorf("secretKeyRefs = %+v, want %+v", ad.Spec.SecretKeyRefs, want)
+	}
+}
+
+func TestHandleDeploymentRequestDifferentKeys(t *testing.T) {
+	h, c := newTestHandler(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"key-a", "key-b"} {
+		payload := DeploymentRequestPayload{
+			RequestID:      "33333333-aaaa-bbbb-cccc-000000000000",
+			TeamID:         "team-a",
+			AppName:        "valkey",
+			Namespace:      "team-a",
+			IdempotencyKey: key,
+		}
+		if err := h.HandleDeploymentRequest(ctx, payload); err != nil {
+			t.Fatalf("HandleDeploymentRequest(%s) error = %v", key, err)
+		}
+	}
+
+	var list appstore.AppDeploymentList
+	if err := c.List(ctx, &list, client.InNamespace("team-a")); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list.Items) != 2 {
+		t.Fatalf("got %d AppDeployments, want 2", len(list.Items))
+	}
+}
+
+func TestHandleDeploymentUpdateGeneration(t *testing.T) {
+	existing := &appstore.AppDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "team-a", Generation: 1},
+		Spec:       appstore.AppDeploymentSpec{AppName: "postgresql", TeamID: "team-a", ChartVersion: "1.0.0"},
+	}
+	h, c := newTestHandler(t, existing)
+	recorder := record.NewFakeRecorder(10)
+	h.recorder = recorder
+	ctx := context.Background()
+
+	// A status update doesn't change the generation, so the update still applies
+	current := &appstore.AppDeployment{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(existing), current); err != nil {
+		t.Fatal(err)
+	}
+	current.Status.Phase = appstore.PhaseDeployed
+	if err := c.Status().Update(ctx, current); err != nil {
+		t.Fatal(err)
+	}
+	err := h.HandleDeploymentUpdate(ctx, DeploymentUpdatePayload{
+		RequestID:  "req-1",
+		TeamID:     "team-a",
+		Name:       "db",
+		Namespace:  "team-a",
+		Version:    "2.0.0",
+		Generation: 1,
+	})
+	if err != nil {
+		t.Fatalf("HandleDeploymentUpdate() error = %v", err)
+	}
+
+	// The API server increments the generation on spec changes, the fake client doesn't
+	if err := c.Get(ctx, client.ObjectKeyFromObject(existing), current); err != nil {
+		t.Fatal(err)
+	}
+	current.Generation = 2
+	if err := c.Update(ctx, current); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second update against the same, now stale, generation conflicts
+	err = h.HandleDeploymentUpdate(ctx, DeploymentUpdatePayload{
+		RequestID:  "req-2",
+		TeamID:     "team-a",
+		Name:       "db",
+		Namespace:  "team-a",
+		Version:    "3.0.0",
+		Generation: 1,
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("HandleDeploymentUpdate() error = %v, want ErrConflict", err)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "UpdateConflict") || !strings.Contains(event, "req-2") {
+			t.Errorf("event = %q, want an UpdateConflict event for req-2", event)
+		}
+	default:
+		t.Error("no event recorded for the conflict")
+	}
+
+	updated := &appstore.AppDeployment{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(existing), updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Spec.ChartVersion != "2.0.0" {
+		t.Errorf("chartVersion = %q, want %q", updated.Spec.ChartVersion, "2.0.0")
+	}
+}
+
+func TestHandleDeploymentRequestWatchNamespaces(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := appstore.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() error = %v", err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	h := NewDeploymentHandler(c, nil, []string{"team-a"})
+	ctx := context.Background()
+
+	payload := DeploymentRequestPayload{
+		RequestID: "11111111-aaaa-bbbb-cccc-000000000000",
+		TeamID:    "team-b",
+		AppName:   "postgresql",
+		Namespace: "team-b",
+	}
+	if err := h.HandleDeploymentRequest(ctx, payload); !errors.Is(err, ErrNamespaceNotWatched) {
+		t.Fatalf("HandleDeploymentRequest() in team-b error = %v, want ErrNamespaceNotWatched", err)
+	}
+	err := h.HandleDeploymentUpdate(ctx, DeploymentUpdatePayload{RequestID: "req-1", Name: "db", Namespace: "team-b"})
+	if !errors.Is(err, ErrNamespaceNotWatched) {
+		t.Fatalf("HandleDeploymentUpdate() in team-b error = %v, want ErrNamespaceNotWatched", err)
+	}
+	err = h.HandleDeploymentDelete(ctx, DeploymentDeletePayload{RequestID: "req-2", Name: "db", Namespace: "team-b"})
+	if !errors.Is(err, ErrNamespaceNotWatched) {
+		t.Fatalf("HandleDeploymentDelete() in team-b error = %v, want ErrNamespaceNotWatched", err)
+	}
+
+	payload.TeamID, payload.Namespace = "team-a", "team-a"
+	if err := h.HandleDeploymentRequest(ctx, payload); err != nil {
+		t.Fatalf("HandleDeploymentRequest() in team-a error = %v", err)
+	}
+
+	var list appstore.AppDeploymentList
+	if err := c.List(ctx, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Namespace != "team-a" {
+		t.Errorf("AppDeployments = %+v, want one in team-a", list.Items)
+	}
+}
+
+func TestDeploymentQueueAndRoutingKeys(t *testing.T) {
+	if got := DeploymentQueueFor(nil); got != "appstore.deployments" {
+		t.Errorf("DeploymentQueueFor(nil) = %q", got)
+	}
+	if got := DeploymentQueueFor([]string{"team-b", "team-a"}); got != "appstore.deployments.ns.team-a.team-b" {
+		t.Errorf("DeploymentQueueFor(team-b, team-a) = %q", got)
+	}
+
+	want := []string{"deployment.request.#", "deployment.update.#", "deployment.delete.#", "deployment.cancel.#"}
+	if got := DeploymentRoutingKeys(nil); !slices.Equal(got, want) {
+		t.Errorf("DeploymentRoutingKeys(nil) = %v, want %v", got, want)
+	}
+	want = []string{
+		"deployment.request.team-a", "deployment.request.team-b",
+		"deployment.update.team-a", "deployment.update.team-b",
+		"deployment.delete.team-a", "deployment.delete.team-b",
+		"deployment.cancel.team-a", "deployment.cancel.team-b",
+	}
+	if got := DeploymentRoutingKeys([]string{"team-a", "team-b"}); !slices.Equal(got, want) {
+		t.Errorf("DeploymentRoutingKeys(team-a, team-b) = %v, want %v", got, want)
+	}
+}
+
+func TestHandleDeploymentCancel(t *testing.T) {
+	existing := &appstore.AppDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "team-a"},
+		Spec:       appstore.AppDeploymentSpec{AppName: "postgresql", TeamID: "team-a"},
+	}
+	h, c := newTestHandler(t, existing)
+	ctx := context.Background()
+
+	// Another team can't cancel it
+	err := h.HandleDeploymentCancel(ctx, DeploymentCancelPayload{RequestID: "req-1", TeamID: "team-b", Name: "db", Namespace: "team-a"})
+	if err == nil {
+		t.Fatal("HandleDeploymentCancel() by another team error = nil")
+	}
+
+	err = h.HandleDeploymentCancel(ctx, DeploymentCancelPayload{RequestID: "req-2", TeamID: "team-a", Name: "db", Namespace: "team-a"})
+	if err != nil {
+		t.Fatalf("HandleDeploymentCancel() error = %v", err)
+	}
+	updated := &appstore.AppDeployment{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(existing), updated); err != nil {
+		t.Fatal(err)
+	}
+	if got := updated.Annotations[appstore.CancelRequestedAnnotation]; got != "req-2" {
+		t.Errorf("cancel annotation = %q, want %q", got, "req-2")
+	}
+
+	// Nothing to cancel for a deployment that is gone
+	err = h.HandleDeploymentCancel(ctx, DeploymentCancelPayload{RequestID: "req-3", TeamID: "team-a", Name: "gone", Namespace: "team-a"})
+	if err != nil {
+		t.Errorf("HandleDeploymentCancel() of a missing AppDeployment error = %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+// fakeLock is an in-memory leader election lock. Another replica holds it until release is
+// called, and renewals fail once failRenewals is called.
+type fakeLock struct {
+	mu       sync.Mutex
+	record   *resourcelock.LeaderElectionRecord
+	released bool
+	failing  bool
+}
+
+func (l *fakeLock) Get(context.Context) (*resourcelock.LeaderElectionRecord, []byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.released {
+		// The other replica keeps renewing
+		now := time.Now()
+		record := resourcelock.LeaderElectionRecord{
+			HolderIdentity:       "other-replica",
+			LeaseDurationSeconds: 1,
+			AcquireTime:          metav1.NewTime(now),
+			RenewTime:            metav1.NewTime(now),
+		}
+		return &record, []byte(now.String()), nil
+	}
+	if l.record == nil {
+		return nil, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "leases"}, "appstore-operator")
+	}
+	record := *l.record
+	return &record, []byte(record.RenewTime.String()), nil
+}
+
+func (l *fakeLock) Create(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	return l.Update(ctx, ler)
+}
+
+func (l *fakeLock) Update(_ context.Context, ler resourcelock.LeaderElectionRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.released {
+		return errors.New("lease is held by another replica")
+	}
+	if l.failing {
+		return errors.New("API server unavailable")
+	}
+	l.record = &ler
+	return nil
+}
+
+func (l *fakeLock) RecordEvent(string) {}
+
+func (l *fakeLock) Identity() string { return "this-replica" }
+
+func (l *fakeLock) Describe() string { return "fake/appstore-operator" }
+
+// release lets this replica acquire the lock
+func (l *fakeLock) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.released = true
+}
+
+// failRenewals makes this replica lose the lock
+func (l *fakeLock) failRenewals() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.failing = true
+}
+
+// fakeBroker accepts connections and closes them right away, reporting each connection
+// attempt of the consumer
+func fakeBroker(t *testing.T) (url string, connects <-chan struct{}) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	ch := make(chan struct{}, 16)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return "amqp://guest:guest@" + listener.Addr().String() + "/", ch
+}
+
+func TestConsumerRunsOnlyOnLeader(t *testing.T) {
+	url, connects := fakeBroker(t)
+	lock := &fakeLock{}
+	leaseDuration, renewDeadline, retryPeriod := time.Second, 500*time.Millisecond, 100*time.Millisecond
+	mgr, err := manager.New(&rest.Config{Host: "http://127.0.0.1:1"}, manager.Options{
+		LeaderElection:                      true,
+		LeaderElectionResourceLockInterface: lock,
+		LeaseDuration:                       &leaseDuration,
+		RenewDeadline:                       &renewDeadline,
+		RetryPeriod:                         &retryPeriod,
+		Metrics:                             metricsserver.Options{BindAddress: "0"},
+	})
+	if err != nil {
+		t.Fatalf("manager.New() error = %v", err)
+	}
+
+	consumer := NewConsumer(ConsumerConfig{URL: url, Queue: "appstore.deployments"}, newTrackingHandler())
+	if err := mgr.Add(consumer); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- mgr.Start(ctx) }()
+
+	// A follower doesn't consume
+	select {
+	case <-connects:
+		t.Fatal("consumer connected while another replica is the leader")
+	case <-time.After(time.Second):
+	}
+
+	// Once elected, it starts consuming
+	lock.release()
+	select {
+	case <-connects:
+	case <-time.After(5 * time.Second):
+		t.Fatal("consumer didn't connect after being elected")
+	}
+
+	// Losing leadership stops the manager, and with it the consumer
+	lock.failRenewals()
+	select {
+	case err := <-stopped:
+		if err == nil || !strings.Contains(err.Error(), "leader election lost") {
+			t.Errorf("manager.Start() error = %v, want leader election lost", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("manager kept running after losing leadership")
+	}
+}
+
+func TestConsumerNeedsLeaderElection(t *testing.T) {
+	var runnable manager.LeaderElectionRunnable = NewConsumer(ConsumerConfig{}, newTrackingHandler())
+	if !runnable.NeedLeaderElection() {
+		t.Error("NeedLeaderElection() = false, want true")
+	}
+}
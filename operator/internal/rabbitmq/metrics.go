@@ -0,0 +1,89 @@
+package rabbitmq
+
+import (
+	"encoding/json"
+
+	"github.com/prometheus/client_golang/prometheus"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Outcomes of a handled message, the values of the outcome label of messagesHandled
+const (
+	outcomeAcked = "acked"
+	// outcomeNacked messages are requeued to be handled again
+	outcomeNacked = "nacked"
+	// outcomeRetried messages are republished to the retry queue to be handled again after
+	// the retry delay
+	outcomeRetried = "retried"
+	// outcomeDeadLettered messages are nacked without requeueing, which dead-letters them
+	// if the queue has a dead letter exchange and drops them otherwise
+	outcomeDeadLettered = "dead_lettered"
+)
+
+// unknownMessageType is the type label of messages that are malformed or of an unknown type
+const unknownMessageType = "unknown"
+
+// messagesReceived counts the messages taken off the queue for handling by type
+var messagesReceived = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "appstore_consumer_messages_received_total",
+		Help: "Number of RabbitMQ messages received for handling, by message type.",
+	},
+	[]string{"type"},
+)
+
+// messagesHandled counts the handled messages by type and outcome
+var messagesHandled = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "appstore_consumer_messages_handled_total",
+		Help: "Number of RabbitMQ messages handled, by message type and outcome (acked, nacked, retried or dead_lettered).",
+	},
+	[]string{"type", "outcome"},
+)
+
+// messagesMalformed counts the messages dropped because they can't be parsed, by type
+var messagesMalformed = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "appstore_consumer_messages_malformed_total",
+		Help: "Number of malformed RabbitMQ messages dead-lettered without being handled, by message type.",
+	},
+	[]string{"type"},
+)
+
+// messagesGivenUp counts the messages dead-lettered after failing MaxAttempts times, by type
+var messagesGivenUp = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "appstore_consumer_messages_given_up_total",
+		Help: "Number of RabbitMQ messages dead-lettered after their handling failed the maximum number of attempts, by message type.",
+	},
+	[]string{"type"},
+)
+
+// messageDuration observes how long handling a message takes by type
+var messageDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "appstore_consumer_message_duration_seconds",
+		Help:    "Duration of handling RabbitMQ messages, by message type.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	},
+	[]string{"type"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(messagesReceived, messagesHandled, messagesMalformed, messagesGivenUp, messageDuration)
+}
+
+// messageTypeLabel returns the type label of a delivery. Labelling only with the known
+// types bounds the metrics to one series per type.
+func messageTypeLabel(msg amqp.Delivery) string {
+	var envelope Message
+	if err := json.Unmarshal(msg.Body, &envelope); err != nil {
+		return unknownMessageType
+	}
+	switch envelope.Type {
+	case MessageTypeDeploymentRequest, MessageTypeDeploymentUpdate, MessageTypeDeploymentDelete, MessageTypeDeploymentCancel:
+		return string(envelope.Type)
+	}
+	return unknownMessageType
+}
@@ -0,0 +1,131 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// errHandlerFailed is returned by failingHandler
+var errHandlerFailed = errors.New("API server unavailable")
+
+// failingHandler fails every deployment request
+type failingHandler struct{ trackingHandler }
+
+func (h *failingHandler) HandleDeploymentRequest(context.Context, DeploymentRequestPayload) error {
+	return errHandlerFailed
+}
+
+// messageMetrics is a snapshot of the metrics of a message type
+type messageMetrics struct {
+	received, acked, nacked, retried, deadLettered float64
+	durations                                      uint64
+}
+
+func snapshotMetrics(t *testing.T, msgType string) messageMetrics {
+	t.Helper()
+	histogram := &dto.Metric{}
+	if err := messageDuration.WithLabelValues(msgType).(prometheus.Histogram).Write(histogram); err != nil {
+		t.Fatal(err)
+	}
+	return messageMetrics{
+		received:     testutil.ToFloat64(messagesReceived.WithLabelValues(msgType)),
+		acked:        testutil.ToFloat64(messagesHandled.WithLabelValues(msgType, outcomeAcked)),
+		nacked:       testutil.ToFloat64(messagesHandled.WithLabelValues(msgType, outcomeNacked)),
+		retried:      testutil.ToFloat64(messagesHandled.WithLabelValues(msgType, outcomeRetried)),
+		deadLettered: testutil.ToFloat64(messagesHandled.WithLabelValues(msgType, outcomeDeadLettered)),
+		durations:    histogram.GetHistogram().GetSampleCount(),
+	}
+}
+
+func TestMessageMetrics(t *testing.T) {
+	request, update := string(MessageTypeDeploymentRequest), string(MessageTypeDeploymentUpdate)
+	tests := []struct {
+		name     string
+		handler  MessageHandler
+		retry    bool
+		delivery func(ack amqp.Acknowledger) amqp.Delivery
+		msgType  string
+		want     messageMetrics
+	}{
+		{
+			name:     "acked",
+			handler:  newTrackingHandler(),
+			delivery: func(ack amqp.Acknowledger) amqp.Delivery { return newDelivery(t, ack, 1) },
+			msgType:  request,
+			want:     messageMetrics{received: 1, acked: 1, durations: 1},
+		},
+		{
+			name:     "nacked",
+			handler:  &failingHandler{},
+			delivery: func(ack amqp.Acknowledger) amqp.Delivery { return newDelivery(t, ack, 1) },
+			msgType:  request,
+			want:     messageMetrics{received: 1, nacked: 1, durations: 1},
+		},
+		{
+			name:     "retried",
+			handler:  &failingHandler{},
+			retry:    true,
+			delivery: func(ack amqp.Acknowledger) amqp.Delivery { return newDelivery(t, ack, 1) },
+			msgType:  request,
+			want:     messageMetrics{received: 1, retried: 1, durations: 1},
+		},
+		{
+			name:     "dead-lettered",
+			handler:  &conflictHandler{},
+			delivery: func(ack amqp.Acknowledger) amqp.Delivery { return newUpdateDelivery(t, ack, 1, "req", "db") },
+			msgType:  update,
+			want:     messageMetrics{received: 1, deadLettered: 1, durations: 1},
+		},
+		{
+			name:    "malformed",
+			handler: newTrackingHandler(),
+			delivery: func(ack amqp.Acknowledger) amqp.Delivery {
+				return amqp.Delivery{Acknowledger: ack, DeliveryTag: 1, Body: []byte("not json")}
+			},
+			msgType: unknownMessageType,
+			want:    messageMetrics{received: 1, deadLettered: 1, durations: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := snapshotMetrics(t, tt.msgType)
+
+			c := NewConsumer(ConsumerConfig{}, tt.handler)
+			if tt.retry {
+				c, _ = newRetryingConsumer(ConsumerConfig{}, tt.handler)
+			}
+			c.processMessage(context.Background(), tt.delivery(&fakeAcknowledger{}))
+
+			after := snapshotMetrics(t, tt.msgType)
+			got := messageMetrics{
+				received:     after.received - before.received,
+				acked:        after.acked - before.acked,
+				nacked:       after.nacked - before.nacked,
+				retried:      after.retried - before.retried,
+				deadLettered: after.deadLettered - before.deadLettered,
+				durations:    after.durations - before.durations,
+			}
+			if got != tt.want {
+				t.Errorf("metrics moved by %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageTypeLabel(t *testing.T) {
+	ack := &fakeAcknowledger{}
+	if got := messageTypeLabel(newDelivery(t, ack, 1)); got != string(MessageTypeDeploymentRequest) {
+		t.Errorf("messageTypeLabel(request) = %q", got)
+	}
+	for _, body := range []string{"not json", `{"type":"deployment.unknown"}`} {
+		if got := messageTypeLabel(amqp.Delivery{Body: []byte(body)}); got != unknownMessageType {
+			t.Errorf("messageTypeLabel(%s) = %q, want %q", body, got, unknownMessageType)
+		}
+	}
+}
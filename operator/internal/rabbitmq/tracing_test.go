@@ -0,0 +1,83 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"k8s.io/apimachinery/pkg/types"
+
+	appstore "appstore/operator/api/v1alpha1"
+	"appstore/operator/internal/tracing"
+)
+
+func TestTraceContextRoundTrip(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	// The backend publishes the message within its publish span
+	_, publishSpan := provider.Tracer("backend").Start(context.Background(), "publish deployment.request")
+	headers := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(trace.ContextWithSpan(context.Background(), publishSpan), headers)
+	publishSpan.End()
+
+	payload, err := json.Marshal(DeploymentRequestPayload{
+		RequestID: "11111111-aaaa-bbbb-cccc-000000000000",
+		TeamID:    "team-a",
+		AppName:   "postgresql",
+		Namespace: "team-a",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(Message{Type: MessageTypeDeploymentRequest, ID: "msg", Payload: payload, Headers: headers})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, c := newTestHandler(t)
+	consumer := NewConsumer(ConsumerConfig{}, h)
+	if err := consumer.handleMessage(context.Background(), amqp.Delivery{Body: body}); err != nil {
+		t.Fatalf("handleMessage() error = %v", err)
+	}
+
+	var consumeSpan sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "process deployment.request" {
+			consumeSpan = span
+		}
+	}
+	if consumeSpan == nil {
+		t.Fatal("no span recorded for processing the message")
+	}
+	if consumeSpan.SpanKind() != trace.SpanKindConsumer {
+		t.Errorf("span kind = %s, want consumer", consumeSpan.SpanKind())
+	}
+	if consumeSpan.Parent().SpanID() != publishSpan.SpanContext().SpanID() || !consumeSpan.Parent().IsRemote() {
+		t.Errorf("consume span parent = %v, want the remote publish span", consumeSpan.Parent().SpanID())
+	}
+
+	// The AppDeployment carries the trace on to its reconciles
+	ad := &appstore.AppDeployment{}
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: "team-a", Name: "postgresql-11111111"}, ad); err != nil {
+		t.Fatal(err)
+	}
+	got := trace.SpanContextFromContext(tracing.ExtractAnnotations(context.Background(), ad.Annotations))
+	if got.TraceID() != publishSpan.SpanContext().TraceID() || got.SpanID() != consumeSpan.SpanContext().SpanID() {
+		t.Errorf("AppDeployment trace context = %v/%v, want %v/%v (annotations %v)", got.TraceID(), got.SpanID(),
+			publishSpan.SpanContext().TraceID(), consumeSpan.SpanContext().SpanID(), ad.Annotations)
+	}
+}
@@ -0,0 +1,67 @@
+package sops
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// Identity is an age X25519 identity, whose secret key is never logged or printed
+type Identity struct {
+	identity *age.X25519Identity
+}
+
+// ParseIdentities parses age identities (AGE-SECRET-KEY-1...) in the format of the key files
+// written by age-keygen: one identity per line, with blank lines and # comments ignored
+func ParseIdentities(data []byte) ([]*Identity, error) {
+	parsed, err := age.ParseIdentities(bytes.NewReader(data))
+	if err != nil {
+		// age's errors name the line, not the secret key on it
+		return nil, fmt.Errorf("invalid age identities: %w", err)
+	}
+	identities := make([]*Identity, 0, len(parsed))
+	for _, identity := range parsed {
+		x25519, ok := identity.(*age.X25519Identity)
+		if !ok {
+			return nil, fmt.Errorf("unsupported age identity type %T", identity)
+		}
+		identities = append(identities, &Identity{identity: x25519})
+	}
+	return identities, nil
+}
+
+// Recipient returns the public age1... recipient of the identity
+func (i *Identity) Recipient() string {
+	return i.identity.Recipient().String()
+}
+
+// String returns the recipient, so that formatting an identity doesn't print its secret key
+func (i *Identity) String() string {
+	return i.Recipient()
+}
+
+// errNoMatchingIdentity is returned when none of the identities is a recipient of an age file
+var errNoMatchingIdentity = errors.New("no identity matches any of the recipients")
+
+// decryptAge decrypts an ASCII-armored age file with the first identity that is one of its
+// recipients
+func decryptAge(armored string, identities []*Identity) ([]byte, error) {
+	ageIdentities := make([]age.Identity, 0, len(identities))
+	for _, identity := range identities {
+		ageIdentities = append(ageIdentities, identity.identity)
+	}
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(armored)), ageIdentities...)
+	var noMatch *age.NoIdentityMatchError
+	if errors.As(err, &noMatch) {
+		return nil, errNoMatchingIdentity
+	}
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
@@ -0,0 +1,400 @@
+// Package sops decrypts Helm values encrypted at rest with SOPS (https://getsops.io) for
+// age recipients.
+//
+// SOPS encrypts each value of a YAML or JSON document separately with AES-256-GCM, using
+// the value's path in the document as additional authenticated data, and stores the data
+// key, encrypted for every recipient, in the document's "sops" metadata. Values are
+// decrypted with an age identity that is one of these recipients. The metadata also holds
+// a MAC: a SHA-512 hash of the document's values in document order, encrypted with the
+// data key. Decrypt verifies it, so values can't be changed, moved, removed or added,
+// whether they are encrypted or not, unless the document was encrypted with
+// mac_only_encrypted, which leaves the unencrypted values unauthenticated.
+//
+// Which values are encrypted follows the unencrypted_suffix, encrypted_suffix,
+// unencrypted_regex and encrypted_regex of the metadata, like in SOPS; a value that should
+// be encrypted but isn't fails to decrypt. Comments aren't authenticated by SOPS and are
+// ignored.
+//
+// Errors never include decrypted values or secret keys.
+package sops
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// MetadataKey is the top-level key of the metadata of SOPS-encrypted documents
+const MetadataKey = "sops"
+
+const dataKeySize = 32
+
+// ErrNoIdentity is returned when none of the identities is a recipient of a document
+var ErrNoIdentity = errors.New("no age identity can decrypt the data key")
+
+// ErrMACMismatch is returned when the values of a document don't match its MAC
+var ErrMACMismatch = errors.New("MAC mismatch, the document was modified after it was encrypted")
+
+// IsEncrypted reports whether values are a SOPS-encrypted document
+func IsEncrypted(values map[string]interface{}) bool {
+	metadata, ok := values[MetadataKey].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, hasMAC := metadata["mac"]
+	_, hasVersion := metadata["version"]
+	return hasMAC || hasVersion
+}
+
+// metadata is the part of the SOPS metadata used for decrypting
+type metadata struct {
+	Age []struct {
+		Recipient string `yaml:"recipient"`
+		Enc       string `yaml:"enc"`
+	} `yaml:"age"`
+	KeyGroups         []interface{} `yaml:"key_groups"`
+	LastModified      string        `yaml:"lastmodified"`
+	MAC               string        `yaml:"mac"`
+	MACOnlyEncrypted  bool          `yaml:"mac_only_encrypted"`
+	UnencryptedSuffix string        `yaml:"unencrypted_suffix"`
+	EncryptedSuffix   string        `yaml:"encrypted_suffix"`
+	UnencryptedRegex  string        `yaml:"unencrypted_regex"`
+	EncryptedRegex    string        `yaml:"encrypted_regex"`
+}
+
+// decrypter decrypts the values of a document and hashes them for its MAC
+type decrypter struct {
+	aead             cipher.AEAD
+	mac              hash.Hash
+	macOnlyEncrypted bool
+
+	unencryptedSuffix, encryptedSuffix string
+	unencryptedRegex, encryptedRegex   *regexp.Regexp
+}
+
+// Decrypt decrypts a SOPS-encrypted YAML or JSON document with the first of the age
+// identities that is one of its recipients and verifies its MAC. It returns the decrypted
+// values without the SOPS metadata; values that weren't encrypted, e.g. because of
+// unencrypted_suffix, are returned as they are.
+func Decrypt(data []byte, identities []*Identity) (map[string]interface{}, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse document: %w", err)
+	}
+	if len(document.Content) != 1 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("document is not a map")
+	}
+	root := document.Content[0]
+
+	var meta *metadata
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == MetadataKey {
+			meta = &metadata{}
+			if err := root.Content[i+1].Decode(meta); err != nil {
+				return nil, fmt.Errorf("invalid sops metadata: %w", err)
+			}
+		}
+	}
+	if meta == nil {
+		return nil, errors.New("missing sops metadata")
+	}
+	if len(meta.KeyGroups) > 0 {
+		return nil, errors.New("key groups are not supported")
+	}
+	if meta.MAC == "" {
+		return nil, errors.New("missing MAC in sops metadata")
+	}
+	lastModified, err := time.Parse(time.RFC3339, meta.LastModified)
+	if err != nil {
+		return nil, errors.New("invalid lastmodified in sops metadata")
+	}
+
+	dataKey, err := decryptDataKey(meta, identities)
+	if err != nil {
+		return nil, err
+	}
+	d, err := newDecrypter(dataKey, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := make(map[string]interface{}, len(root.Content)/2)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value == MetadataKey {
+			continue
+		}
+		if decrypted[key.Value], err = d.decryptNode(value, []string{key.Value}); err != nil {
+			return nil, err
+		}
+	}
+
+	// The MAC is authenticated with the time of the last modification
+	mac, err := d.decryptValue(meta.MAC, lastModified.Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the MAC: %w", err)
+	}
+	macString, ok := mac.(string)
+	computed := fmt.Sprintf("%X", d.mac.Sum(nil))
+	if !ok || subtle.ConstantTimeCompare([]byte(macString), []byte(computed)) != 1 {
+		return nil, ErrMACMismatch
+	}
+	return decrypted, nil
+}
+
+// decryptDataKey decrypts the document's data key from its age recipients
+func decryptDataKey(meta *metadata, identities []*Identity) ([]byte, error) {
+	if len(meta.Age) == 0 {
+		return nil, errors.New("document has no age recipients")
+	}
+
+	var names []string
+	for _, recipient := range meta.Age {
+		names = append(names, recipient.Recipient)
+		if recipient.Enc == "" {
+			continue
+		}
+
+		dataKey, err := decryptAge(recipient.Enc, identities)
+		if errors.Is(err, errNoMatchingIdentity) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt the data key for %s: %w", recipient.Recipient, err)
+		}
+		if len(dataKey) != dataKeySize {
+			return nil, fmt.Errorf("invalid data key for %s", recipient.Recipient)
+		}
+		return dataKey, nil
+	}
+	return nil, fmt.Errorf("%w, recipients are %s", ErrNoIdentity, strings.Join(names, ", "))
+}
+
+func newDecrypter(dataKey []byte, meta *metadata) (*decrypter, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	// SOPS uses 32 byte IVs
+	aead, err := cipher.NewGCMWithNonceSize(block, 32)
+	if err != nil {
+		return nil, err
+	}
+	d := &decrypter{
+		aead:              aead,
+		mac:               sha512.New(),
+		macOnlyEncrypted:  meta.MACOnlyEncrypted,
+		unencryptedSuffix: meta.UnencryptedSuffix,
+		encryptedSuffix:   meta.EncryptedSuffix,
+	}
+	for pattern, dst := range map[string]**regexp.Regexp{meta.UnencryptedRegex: &d.unencryptedRegex, meta.EncryptedRegex: &d.encryptedRegex} {
+		if pattern == "" {
+			continue
+		}
+		if *dst, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid regex in sops metadata: %w", err)
+		}
+	}
+	return d, nil
+}
+
+// encrypted reports whether the value at path is encrypted, following the rules of SOPS:
+// encrypted suffixes and regexes override unencrypted ones, and a key anywhere in the path
+// decides for all values below it
+func (d *decrypter) encrypted(path []string) bool {
+	encrypted := true
+	matches := func(match func(string) bool) bool {
+		for _, key := range path {
+			if match(key) {
+				return true
+			}
+		}
+		return false
+	}
+	if d.unencryptedSuffix != "" && matches(func(key string) bool { return strings.HasSuffix(key, d.unencryptedSuffix) }) {
+		encrypted = false
+	}
+	if d.encryptedSuffix != "" {
+		encrypted = matches(func(key string) bool { return strings.HasSuffix(key, d.encryptedSuffix) })
+	}
+	if d.unencryptedRegex != nil && matches(d.unencryptedRegex.MatchString) {
+		encrypted = false
+	}
+	if d.encryptedRegex != nil {
+		encrypted = matches(d.encryptedRegex.MatchString)
+	}
+	return encrypted
+}
+
+// decryptNode decrypts the values of a node, whose path from the document root is path,
+// in document order, adding them to the MAC
+func (d *decrypter) decryptNode(node *yaml.Node, path []string) (interface{}, error) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return d.decryptNode(node.Alias, path)
+	case yaml.MappingNode:
+		decrypted := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Kind != yaml.ScalarNode || key.ShortTag() != "!!str" {
+				return nil, fmt.Errorf("%s has a non-string key", strings.Join(path, "."))
+			}
+			var err error
+			if decrypted[key.Value], err = d.decryptNode(value, append(path[:len(path):len(path)], key.Value)); err != nil {
+				return nil, err
+			}
+		}
+		return decrypted, nil
+	case yaml.SequenceNode:
+		// Items of lists are authenticated with the path of the list
+		decrypted := make([]interface{}, len(node.Content))
+		for i, item := range node.Content {
+			var err error
+			if decrypted[i], err = d.decryptNode(item, path); err != nil {
+				return nil, err
+			}
+		}
+		return decrypted, nil
+	case yaml.ScalarNode:
+		return d.decryptScalar(node, path)
+	default:
+		return nil, fmt.Errorf("unsupported value at %s", strings.Join(path, "."))
+	}
+}
+
+// decryptScalar decrypts a single value if it should be encrypted and adds it to the MAC
+func (d *decrypter) decryptScalar(node *yaml.Node, path []string) (interface{}, error) {
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid value at %s: %w", strings.Join(path, "."), err)
+	}
+	if value == nil {
+		// SOPS neither encrypts nor authenticates null values
+		return nil, nil
+	}
+
+	encrypted := d.encrypted(path)
+	if encrypted {
+		ciphertext, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s is not encrypted", strings.Join(path, "."))
+		}
+		var err error
+		if value, err = d.decryptValue(ciphertext, strings.Join(path, ":")+":"); err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s: %w", strings.Join(path, "."), err)
+		}
+	}
+
+	if encrypted || !d.macOnlyEncrypted {
+		bytes, err := macBytes(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", strings.Join(path, "."), err)
+		}
+		d.mac.Write(bytes)
+	}
+
+	// Numbers are returned as float64, like those of plaintext values parsed from YAML
+	switch v := value.(type) {
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	default:
+		return v, nil
+	}
+}
+
+// macBytes returns the bytes of a value that SOPS adds to the MAC
+func macBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return []byte(v), nil
+	case int:
+		return []byte(strconv.Itoa(v)), nil
+	case int64:
+		return []byte(strconv.FormatInt(v, 10)), nil
+	case uint64:
+		return []byte(strconv.FormatUint(v, 10)), nil
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	case bool:
+		if v {
+			return []byte("True"), nil
+		}
+		return []byte("False"), nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// decryptValue decrypts an ENC[AES256_GCM,data:...,iv:...,tag:...,type:...] value that was
+// authenticated with additionalData
+func (d *decrypter) decryptValue(value, additionalData string) (interface{}, error) {
+	if value == "" {
+		// SOPS doesn't encrypt empty strings
+		return "", nil
+	}
+	fields, ok := strings.CutPrefix(value, "ENC[AES256_GCM,")
+	if !ok || !strings.HasSuffix(fields, "]") {
+		return nil, errors.New("value is not encrypted")
+	}
+	parts := map[string]string{}
+	for _, field := range strings.Split(strings.TrimSuffix(fields, "]"), ",") {
+		name, part, _ := strings.Cut(field, ":")
+		parts[name] = part
+	}
+
+	var data, iv, tag []byte
+	var err error
+	for name, dst := range map[string]*[]byte{"data": &data, "iv": &iv, "tag": &tag} {
+		if *dst, err = base64.StdEncoding.DecodeString(parts[name]); err != nil {
+			return nil, fmt.Errorf("invalid %s of encrypted value", name)
+		}
+	}
+	if len(iv) != d.aead.NonceSize() || len(tag) != d.aead.Overhead() {
+		return nil, errors.New("invalid iv or tag of encrypted value")
+	}
+
+	plaintext, err := d.aead.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return nil, errors.New("authentication failed")
+	}
+
+	switch parts["type"] {
+	case "str", "bytes":
+		return string(plaintext), nil
+	case "int":
+		number, err := strconv.Atoi(string(plaintext))
+		if err != nil {
+			return nil, errors.New("invalid int value")
+		}
+		return number, nil
+	case "float":
+		number, err := strconv.ParseFloat(string(plaintext), 64)
+		if err != nil {
+			return nil, errors.New("invalid float value")
+		}
+		return number, nil
+	case "bool":
+		b, err := strconv.ParseBool(string(plaintext))
+		if err != nil {
+			return nil, errors.New("invalid bool value")
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %q", parts["type"])
+	}
+}
@@ -0,0 +1,152 @@
+package sops
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+// testOtherIdentity isn't a recipient of testdata/values.enc.yaml, which is encrypted for
+// the identity in testdata/age.key
+const testOtherIdentity = "AGE-SECRET-KEY-19M0JVCD6KKWPH2848FJ6TGJFJSPLLX3WNPNFVT28R07ZNXW0MEWQGAMTEW"
+
+func readTestFile(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func readTestValues(t *testing.T) map[string]interface{} {
+	t.Helper()
+	var values map[string]interface{}
+	if err := yaml.Unmarshal([]byte(readTestFile(t, "values.enc.yaml")), &values); err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func TestParseIdentities(t *testing.T) {
+	identities, err := ParseIdentities([]byte(readTestFile(t, "age.key")))
+	if err != nil {
+		t.Fatalf("ParseIdentities() error = %v", err)
+	}
+	if len(identities) != 1 || identities[0].Recipient() != "age17pw8434mydysudfh3wq34xdg4sf6vem78fygea0krh544p03y4nqwaja0m" {
+		t.Errorf("ParseIdentities() = %v", identities)
+	}
+
+	for name, data := range map[string]string{
+		"empty":        "# no keys\n",
+		"recipient":    identities[0].Recipient(),
+		"mixed case":   strings.ToLower(testOtherIdentity[:20]) + testOtherIdentity[20:],
+		"bad checksum": testOtherIdentity[:len(testOtherIdentity)-1] + "Q",
+	} {
+		_, err := ParseIdentities([]byte(data))
+		if err == nil {
+			t.Errorf("%s: ParseIdentities() error = nil", name)
+			continue
+		}
+		if strings.Contains(strings.ToUpper(err.Error()), "AGE-SECRET-KEY") {
+			t.Errorf("%s: ParseIdentities() error = %q, includes the key", name, err)
+		}
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	identities, err := ParseIdentities([]byte(testOtherIdentity + "\n" + readTestFile(t, "age.key")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := readTestValues(t)
+	if !IsEncrypted(encrypted) {
+		t.Fatal("IsEncrypted() = false")
+	}
+
+	values, err := Decrypt([]byte(readTestFile(t, "values.enc.yaml")), identities)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"replicaCount": float64(2),
+		"auth":         map[string]interface{}{"username": "app", "password": "s3cr3t", "enabled": true},
+		"hosts":        []interface{}{"db-0.example.com", "db-1.example.com"},
+		// Not encrypted because of its suffix
+		"metrics_unencrypted": true,
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Decrypt() = %v, want %v", values, want)
+	}
+	if IsEncrypted(values) || IsEncrypted(map[string]interface{}{"sops": map[string]interface{}{"enabled": true}}) {
+		t.Error("IsEncrypted() = true for plaintext values")
+	}
+}
+
+func TestDecryptFailures(t *testing.T) {
+	identities, err := ParseIdentities([]byte(readTestFile(t, "age.key")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ParseIdentities([]byte(testOtherIdentity))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Decrypt([]byte(readTestFile(t, "values.enc.yaml")), other)
+	if !errors.Is(err, ErrNoIdentity) || !strings.Contains(err.Error(), identities[0].Recipient()) {
+		t.Errorf("Decrypt() with another identity error = %v", err)
+	}
+
+	for name, modify := range map[string]func(string) string{
+		// Values are authenticated with their path, so they can't be swapped
+		"swapped values": func(data string) string {
+			lines := strings.Split(data, "\n")
+			lines[2], lines[3] = strings.Replace(lines[3], "password", "username", 1), strings.Replace(lines[2], "username", "password", 1)
+			return strings.Join(lines, "\n")
+		},
+		"tampered data key": func(data string) string {
+			return strings.Replace(data, "hS7LW4Ra", "hS7LW4Rb", 1)
+		},
+		"plaintext value": func(data string) string {
+			return regexp.MustCompile(`password: ENC\[.*\]`).ReplaceAllString(data, "password: plaintext")
+		},
+		"removed value": func(data string) string {
+			return regexp.MustCompile(`(?m)^    - ENC\[.*\n`).ReplaceAllLiteralString(data, "")
+		},
+		// List items are authenticated with the same path, but in order by the MAC
+		"reordered values": func(data string) string {
+			lines := strings.Split(data, "\n")
+			lines[6], lines[7] = lines[7], lines[6]
+			return strings.Join(lines, "\n")
+		},
+		"changed unencrypted value": func(data string) string {
+			return strings.Replace(data, "metrics_unencrypted: true", "metrics_unencrypted: false", 1)
+		},
+		"added unencrypted value": func(data string) string {
+			return "image_unencrypted: evil\n" + data
+		},
+		"removed MAC": func(data string) string {
+			return regexp.MustCompile(`(?m)^    mac: .*\n`).ReplaceAllLiteralString(data, "")
+		},
+	} {
+		data := modify(readTestFile(t, "values.enc.yaml"))
+		if data == readTestFile(t, "values.enc.yaml") {
+			t.Fatalf("%s: document unchanged", name)
+		}
+		_, err := Decrypt([]byte(data), identities)
+		if err == nil {
+			t.Errorf("%s: Decrypt() error = nil", name)
+			continue
+		}
+		if strings.Contains(err.Error(), "s3cr3t") {
+			t.Errorf("%s: Decrypt() error = %q, includes a decrypted value", name, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Package tracing provides the operator's OpenTelemetry tracer and records the trace
+// context of RabbitMQ messages on the AppDeployments they create or change, so that their
+// reconciles link to it. Tracing is set up with the tracing package of appstore/shared.
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation scope of the spans created by the operator
+const TracerName = "appstore/operator"
+
+// Tracer returns the operator's tracer from the global TracerProvider
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// annotationPrefix namespaces the propagated fields, e.g. traceparent, in annotations
+const annotationPrefix = "appstore.bitpipe.no/"
+
+// annotationCarrier carries trace context in an object's annotations
+type annotationCarrier map[string]string
+
+func (c annotationCarrier) Get(key string) string { return c[annotationPrefix+key] }
+
+func (c annotationCarrier) Set(key, value string) { c[annotationPrefix+key] = value }
+
+func (c annotationCarrier) Keys() []string {
+	var keys []string
+	for key := range c {
+		if field, ok := strings.CutPrefix(key, annotationPrefix); ok {
+			keys = append(keys, field)
+		}
+	}
+	return keys
+}
+
+// InjectAnnotations records the trace context of ctx in annotations, e.g. as
+// appstore.bitpipe.no/traceparent, so that reconciles link to the trace. Fields from an
+// earlier trace are replaced.
+func InjectAnnotations(ctx context.Context, annotations map[string]string) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+	for _, field := range otel.GetTextMapPropagator().Fields() {
+		delete(annotations, annotationPrefix+field)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, annotationCarrier(annotations))
+}
+
+// ExtractAnnotations returns ctx with the trace context recorded in annotations
+func ExtractAnnotations(ctx context.Context, annotations map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, annotationCarrier(annotations))
+}
@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+
+	sharedtracing "appstore/shared/tracing"
+)
+
+func TestAnnotations(t *testing.T) {
+	t.Setenv("OTEL_TRACES_EXPORTER", "none")
+	if _, err := sharedtracing.Setup(context.Background(), "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	annotations := map[string]string{
+		"appstore.bitpipe.no/requested-by": "alice",
+		"appstore.bitpipe.no/tracestate":   "stale=1",
+	}
+	InjectAnnotations(context.Background(), annotations)
+	if len(annotations) != 2 {
+		t.Errorf("InjectAnnotations() without a span changed annotations to %v", annotations)
+	}
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	InjectAnnotations(ctx, annotations)
+
+	want := map[string]string{
+		"appstore.bitpipe.no/requested-by": "alice",
+		"appstore.bitpipe.no/traceparent":  "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+	if len(annotations) != len(want) || annotations["appstore.bitpipe.no/traceparent"] != want["appstore.bitpipe.no/traceparent"] {
+		t.Errorf("annotations = %v, want %v", annotations, want)
+	}
+
+	got := trace.SpanContextFromContext(ExtractAnnotations(context.Background(), annotations))
+	if got.TraceID() != traceID || got.SpanID() != spanID || !got.IsRemote() {
+		t.Errorf("ExtractAnnotations() = %v, want trace %v span %v", got, traceID, spanID)
+	}
+}
@@ -0,0 +1,99 @@
+// Package kubeconfig loads the kubeconfigs of the remote clusters that AppDeployments
+// reference with spec.clusterRef. The operator installs releases with them and the backend
+// reads the releases back, so both accept the same kubeconfigs.
+package kubeconfig
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Load parses a kubeconfig and returns it with the REST config of its current context.
+// Since kubeconfigs come from Secrets of AppDeployment authors, only credentials embedded
+// in the kubeconfig are accepted: exec and auth provider plugins, which run commands or
+// read the caller's credentials, and references to files are rejected. The API server
+// must be one of allowedServers.
+func Load(kubeconfig []byte, allowedServers []string) (*clientcmdapi.Config, *rest.Config, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+	if err := validate(config); err != nil {
+		return nil, nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+	restConfig, err := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+	server, err := NormalizeServer(restConfig.Host)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+	if !slices.Contains(allowedServers, server) {
+		return nil, nil, fmt.Errorf("cluster %s is not allowed", server)
+	}
+	return config, restConfig, nil
+}
+
+// validate rejects the parts of a kubeconfig that make clients run commands or read local
+// files
+func validate(config *clientcmdapi.Config) error {
+	var errs []error
+	for name, user := range config.AuthInfos {
+		switch {
+		case user.Exec != nil:
+			errs = append(errs, fmt.Errorf("user %s: exec plugins are not allowed", name))
+		case user.AuthProvider != nil:
+			errs = append(errs, fmt.Errorf("user %s: auth providers are not allowed", name))
+		case user.TokenFile != "" || user.ClientCertificate != "" || user.ClientKey != "":
+			errs = append(errs, fmt.Errorf("user %s: file references are not allowed, embed the credentials", name))
+		}
+	}
+	for name, cluster := range config.Clusters {
+		switch {
+		case cluster.CertificateAuthority != "":
+			errs = append(errs, fmt.Errorf("cluster %s: file references are not allowed, embed the certificate authority", name))
+		case cluster.ProxyURL != "":
+			errs = append(errs, fmt.Errorf("cluster %s: proxies are not allowed", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NormalizeServer returns an API server URL in the form it is compared with allowed
+// servers: lower case scheme and host, without a trailing slash
+func NormalizeServer(server string) (string, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", fmt.Errorf("invalid server %q: %w", server, err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("invalid server %q, want an http(s) URL", server)
+	}
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host) + strings.TrimSuffix(u.EscapedPath(), "/"), nil
+}
+
+// ParseAllowedServers parses a comma-separated list of API server URLs, normalized with
+// NormalizeServer
+func ParseAllowedServers(s string) ([]string, error) {
+	var servers []string
+	for _, server := range strings.Split(s, ",") {
+		server = strings.TrimSpace(server)
+		if server == "" {
+			continue
+		}
+		normalized, err := NormalizeServer(server)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, normalized)
+	}
+	return servers, nil
+}
@@ -0,0 +1,101 @@
+package kubeconfig
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// remoteKubeConfig returns a kubeconfig for the API server at url
+func remoteKubeConfig(url string) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: remote
+  cluster:
+    server: %s
+contexts:
+- name: remote
+  context:
+    cluster: remote
+    user: remote
+current-context: remote
+users:
+- name: remote
+  user:
+    token: remote-token
+`, url))
+}
+
+func TestLoad(t *testing.T) {
+	allowed := []string{"https://remote.example.com:6443"}
+	_, restConfig, err := Load(remoteKubeConfig("https://Remote.example.com:6443/"), allowed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restConfig.Host != "https://Remote.example.com:6443/" || restConfig.BearerToken != "remote-token" {
+		t.Errorf("rest config = %s, token %q", restConfig.Host, restConfig.BearerToken)
+	}
+
+	for _, kubeconfig := range []string{"not: [yaml", "apiVersion: v1\nkind: Config\n"} {
+		if _, _, err := Load([]byte(kubeconfig), allowed); err == nil {
+			t.Errorf("Load(%q) succeeded", kubeconfig)
+		}
+	}
+}
+
+func TestLoadRejectsUnsafeKubeConfigs(t *testing.T) {
+	allowed := []string{"https://remote.example.com:6443"}
+	base := string(remoteKubeConfig("https://remote.example.com:6443"))
+
+	tests := map[string]struct {
+		kubeconfig string
+		want       string
+	}{
+		"server not allowed": {string(remoteKubeConfig("https://10.0.0.1")), "cluster https://10.0.0.1 is not allowed"},
+		"in-cluster server":  {string(remoteKubeConfig("https://kubernetes.default.svc")), "is not allowed"},
+		"exec plugin": {strings.Replace(base, "    token: remote-token", `    exec:
+      apiVersion: client.authentication.k8s.io/v1
+      command: sh
+      args: ["-c", "id"]`, 1), "exec plugins are not allowed"},
+		"auth provider": {strings.Replace(base, "    token: remote-token", `    auth-provider:
+      name: oidc`, 1), "auth providers are not allowed"},
+		"token file": {strings.Replace(base, "    token: remote-token",
+			"    tokenFile: /var/run/secrets/kubernetes.io/serviceaccount/token", 1), "file references are not allowed"},
+		"client certificate file": {strings.Replace(base, "    token: remote-token",
+			"    client-certificate: /etc/tls/tls.crt\n    client-key: /etc/tls/tls.key", 1), "file references are not allowed"},
+		"certificate authority file": {strings.Replace(base, "    server: https://remote.example.com:6443",
+			"    server: https://remote.example.com:6443\n    certificate-authority: /var/run/secrets/kubernetes.io/serviceaccount/ca.crt", 1),
+			"file references are not allowed"},
+		"proxy": {strings.Replace(base, "    server: https://remote.example.com:6443",
+			"    server: https://remote.example.com:6443\n    proxy-url: http://169.254.169.254", 1), "proxies are not allowed"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := Load([]byte(tt.kubeconfig), allowed)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, _, err := Load(remoteKubeConfig("https://remote.example.com:6443"), nil); err == nil {
+		t.Error("Load() without allowed servers succeeded")
+	}
+}
+
+func TestParseAllowedServers(t *testing.T) {
+	servers, err := ParseAllowedServers(" https://Edge.example.com:6443/ ,,http://10.0.0.1/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"https://edge.example.com:6443", "http://10.0.0.1/api"}; !slices.Equal(servers, want) {
+		t.Errorf("ParseAllowedServers() = %v, want %v", servers, want)
+	}
+	for _, s := range []string{"edge.example.com:6443", "ftp://edge", "https://"} {
+		if _, err := ParseAllowedServers(s); err == nil {
+			t.Errorf("ParseAllowedServers(%q) succeeded", s)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+package rabbitmq
+
+// MaxPriority is the highest priority of deployment requests. The backend rejects higher
+// priorities and the operator declares its queue with it as x-max-priority when priority
+// queues are enabled.
+const MaxPriority = 9
@@ -0,0 +1,77 @@
+// Package rabbitmq holds the RabbitMQ connection settings shared by the backend and the
+// operator.
+package rabbitmq
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// TLSConfig configures the TLS connection used for amqps:// URLs
+type TLSConfig struct {
+	// CAFile contains the CA certificates that verify the server, the system's if empty
+	CAFile string
+	// CertFile and KeyFile are a client certificate for mutual TLS
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the host name verified against the server's certificate
+	ServerName string
+}
+
+// Dial functions, replaced in tests
+var (
+	dialPlain = amqp.Dial
+	dialTLS   = amqp.DialTLS
+)
+
+// Dial connects to RabbitMQ, over TLS configured by tlsConfig for amqps:// URLs
+func Dial(rawURL string, tlsConfig TLSConfig) (*amqp.Connection, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RabbitMQ URL: %w", err)
+	}
+	if u.Scheme != "amqps" {
+		return dialPlain(rawURL)
+	}
+
+	config, err := tlsConfig.build()
+	if err != nil {
+		return nil, err
+	}
+	return dialTLS(rawURL, config)
+}
+
+// build returns the client TLS configuration
+func (c TLSConfig) build() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.ServerName,
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read RabbitMQ CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in RabbitMQ CA file %s", c.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load RabbitMQ client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
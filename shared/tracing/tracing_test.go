@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetupExporters(t *testing.T) {
+	for _, exporter := range []string{"", "none", "console"} {
+		t.Run(exporter, func(t *testing.T) {
+			t.Setenv("OTEL_TRACES_EXPORTER", exporter)
+			shutdown, err := Setup(context.Background(), "test")
+			if err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+			if err := shutdown(context.Background()); err != nil {
+				t.Errorf("shutdown() error = %v", err)
+			}
+		})
+	}
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "zipkin")
+	if _, err := Setup(context.Background(), "test"); err == nil {
+		t.Error("Setup() succeeded with an unsupported exporter")
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	t.Setenv("OTEL_TRACES_EXPORTER", "none")
+	if _, err := Setup(context.Background(), "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if headers := Inject(context.Background()); headers != nil {
+		t.Errorf("Inject() without a span = %v, want nil", headers)
+	}
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	headers := Inject(ctx)
+	if want := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"; headers["traceparent"] != want {
+		t.Errorf("traceparent = %q, want %q", headers["traceparent"], want)
+	}
+
+	got := trace.SpanContextFromContext(Extract(context.Background(), headers))
+	if got.TraceID() != traceID || got.SpanID() != spanID || !got.IsSampled() {
+		t.Errorf("Extract() = %v, want trace %v span %v", got, traceID, spanID)
+	}
+}
@@ -0,0 +1,214 @@
+// Package values merges and edits Helm values maps. The backend and the operator share
+// it, so values computed by the backend match what the operator deploys.
+package values
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Merge recursively merges src into dst and returns dst, which is allocated if nil. Nested
+// maps are merged key by key; any other value in src replaces the value in dst, also when
+// the types differ. Maps from src are copied into dst. A null value in src replaces the
+// value in dst and is kept, as when Helm merges values files, so that higher layers can
+// unset values of lower ones and Helm then also removes the chart's default of the key.
+func Merge(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = make(map[string]interface{}, len(src))
+	}
+	for key, srcVal := range src {
+		srcMap, srcOk := srcVal.(map[string]interface{})
+		if !srcOk {
+			dst[key] = srcVal
+			continue
+		}
+		dstMap, dstOk := dst[key].(map[string]interface{})
+		if !dstOk {
+			dstMap = nil
+		}
+		dst[key] = Merge(dstMap, srcMap)
+	}
+	return dst
+}
+
+// SetAtPath sets a value at a dot-separated path, creating intermediate maps as needed
+func SetAtPath(values map[string]interface{}, path, value string) error {
+	keys := strings.Split(path, ".")
+	current := values
+	for i, key := range keys[:len(keys)-1] {
+		next := current[key]
+		if next == nil {
+			// Missing keys and keys unset with a null get a new map
+			child := make(map[string]interface{})
+			current[key] = child
+			current = child
+			continue
+		}
+		child, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot set values path %s: %s is not a map", path, strings.Join(keys[:i+1], "."))
+		}
+		current = child
+	}
+	current[keys[len(keys)-1]] = value
+	return nil
+}
+
+// GetAtPath returns the value at a dot-separated path and whether it is set. A null
+// value is not set.
+func GetAtPath(values map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = values
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current = m[key]
+	}
+	return current, current != nil
+}
+
+// DeepCopy returns a copy of values that shares no maps or slices with it
+func DeepCopy(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		out[key] = deepCopyValue(value)
+	}
+	return out
+}
+
+func deepCopyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return DeepCopy(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = deepCopyValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// Redact returns a copy of the values with every value other than a map replaced by the
+// marker, keeping only the structure of the keys
+func Redact(values map[string]interface{}, marker string) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if m, ok := value.(map[string]interface{}); ok {
+			out[key] = Redact(m, marker)
+			continue
+		}
+		out[key] = marker
+	}
+	return out
+}
+
+// Hash returns a short hash of the values for change detection. Values are canonicalized
+// first, so semantically equal values have the same hash however they were built: map keys
+// are sorted, typed maps and slices are treated like their generic forms and numbers are
+// compared by value, so 2, int64(2), 2.0 and json.Number("2.0") hash the same. Lists are not
+// sorted since their order is significant to most charts.
+func Hash(values map[string]interface{}) string {
+	data, _ := json.Marshal(canonicalize(values))
+	hash := sha256.Sum256(data)
+	return fmt.Sprintf("%x", hash[:8])
+}
+
+// canonicalize returns value with all maps as map[string]interface{}, all slices as
+// []interface{} and all numbers as json.Number in a canonical format
+func canonicalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, bool, string:
+		return v
+	case json.Number:
+		return canonicalNumber(v)
+	case float64:
+		return canonicalFloat(v)
+	case float32:
+		return canonicalFloat(float64(v))
+	case map[string]interface{}:
+		if v == nil {
+			return nil
+		}
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = canonicalize(item)
+		}
+		return out
+	case []interface{}:
+		if v == nil {
+			return nil
+		}
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = canonicalize(item)
+		}
+		return out
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Number(strconv.FormatInt(rv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return json.Number(strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Map:
+		if rv.IsNil() || rv.Type().Key().Kind() != reflect.String {
+			return value
+		}
+		out := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			out[iter.Key().String()] = canonicalize(iter.Value().Interface())
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = canonicalize(rv.Index(i).Interface())
+		}
+		return out
+	}
+	return value
+}
+
+// canonicalNumber formats a JSON number like the number it represents would be by canonicalFloat
+func canonicalNumber(n json.Number) interface{} {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		return json.Number(strconv.FormatInt(i, 10))
+	}
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return n
+	}
+	return canonicalFloat(f)
+}
+
+// canonicalFloat formats whole numbers as integers, unless they are too large to be exact,
+// and other numbers in their shortest representation
+func canonicalFloat(f float64) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return f
+	}
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return json.Number(strconv.FormatInt(int64(f), 10))
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+}
@@ -0,0 +1,280 @@
+package values
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	dst := map[string]interface{}{
+		"replicas": 1,
+		"image":    map[string]interface{}{"repository": "postgres", "tag": "16"},
+		"auth":     "disabled",
+	}
+	src := map[string]interface{}{
+		"image":     map[string]interface{}{"tag": "17"},
+		"auth":      map[string]interface{}{"enabled": true},
+		"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "1"}},
+	}
+
+	want := map[string]interface{}{
+		"replicas":  1,
+		"image":     map[string]interface{}{"repository": "postgres", "tag": "17"},
+		"auth":      map[string]interface{}{"enabled": true},
+		"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "1"}},
+	}
+	if got := Merge(dst, src); !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() = %v, want %v", got, want)
+	}
+}
+
+func TestSetAtPath(t *testing.T) {
+	values := map[string]interface{}{
+		"auth":     map[string]interface{}{"username": "app"},
+		"replicas": 1,
+	}
+
+	if err := SetAtPath(values, "auth.password", "secret"); err != nil {
+		t.Fatalf("SetAtPath() error = %v", err)
+	}
+	if err := SetAtPath(values, "metrics.serviceMonitor.token", "t"); err != nil {
+		t.Fatalf("SetAtPath() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"auth":     map[string]interface{}{"username": "app", "password": "secret"},
+		"metrics":  map[string]interface{}{"serviceMonitor": map[string]interface{}{"token": "t"}},
+		"replicas": 1,
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %v, want %v", values, want)
+	}
+
+	if err := SetAtPath(values, "replicas.count", "2"); err == nil {
+		t.Error("SetAtPath() through a non-map value succeeded, want an error")
+	}
+}
+
+func TestGetAtPath(t *testing.T) {
+	values := map[string]interface{}{
+		"auth":     map[string]interface{}{"password": "secret", "database": nil},
+		"replicas": 0,
+	}
+
+	for path, want := range map[string]interface{}{
+		"auth.password": "secret",
+		"replicas":      0,
+		"auth":          values["auth"],
+	} {
+		if got, ok := GetAtPath(values, path); !ok || !reflect.DeepEqual(got, want) {
+			t.Errorf("GetAtPath(%q) = %v, %v, want %v, true", path, got, ok, want)
+		}
+	}
+	for _, path := range []string{"auth.database", "auth.username", "replicas.count", "metrics.enabled"} {
+		if got, ok := GetAtPath(values, path); ok {
+			t.Errorf("GetAtPath(%q) = %v, true, want not set", path, got)
+		}
+	}
+}
+
+func TestMergeNil(t *testing.T) {
+	src := map[string]interface{}{"image": map[string]interface{}{"tag": "17"}}
+
+	if got := Merge(nil, src); !reflect.DeepEqual(got, src) {
+		t.Errorf("Merge(nil, src) = %v, want %v", got, src)
+	}
+	if got := Merge(nil, nil); got == nil || len(got) != 0 {
+		t.Errorf("Merge(nil, nil) = %#v, want an empty map", got)
+	}
+
+	dst := map[string]interface{}{"image": map[string]interface{}{"tag": "16"}}
+	if got := Merge(dst, nil); !reflect.DeepEqual(got, map[string]interface{}{"image": map[string]interface{}{"tag": "16"}}) {
+		t.Errorf("Merge(dst, nil) = %v, want dst unchanged", got)
+	}
+}
+
+func TestMergeKeepsNulls(t *testing.T) {
+	dst := map[string]interface{}{
+		"replicas": 1,
+		"image":    map[string]interface{}{"repository": "postgres", "tag": "16"},
+		"metrics":  map[string]interface{}{"enabled": true, "serviceMonitor": map[string]interface{}{"interval": "30s"}},
+		"auth":     map[string]interface{}{"username": "app"},
+	}
+	src := map[string]interface{}{
+		"replicas": nil,
+		"image":    map[string]interface{}{"tag": nil},
+		"metrics":  nil,
+		// Nulls in maps new to dst are kept too
+		"persistence": map[string]interface{}{"size": "10Gi", "storageClass": nil},
+		// Helm also removes keys that only the chart sets
+		"ingress": nil,
+		"auth":    map[string]interface{}{"password": nil, "database": "app"},
+	}
+
+	want := map[string]interface{}{
+		"replicas":    nil,
+		"image":       map[string]interface{}{"repository": "postgres", "tag": nil},
+		"metrics":     nil,
+		"persistence": map[string]interface{}{"size": "10Gi", "storageClass": nil},
+		"ingress":     nil,
+		"auth":        map[string]interface{}{"username": "app", "password": nil, "database": "app"},
+	}
+	if got := Merge(dst, src); !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() = %v, want %v", got, want)
+	}
+	if _, ok := src["persistence"].(map[string]interface{})["storageClass"]; !ok {
+		t.Error("Merge() modified src")
+	}
+}
+
+func TestMergeTypeConflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		dst  interface{}
+		src  interface{}
+	}{
+		{"map replaced by scalar", map[string]interface{}{"enabled": true}, "disabled"},
+		{"scalar replaced by map", "disabled", map[string]interface{}{"enabled": true}},
+		{"map replaced by list", map[string]interface{}{"a": 1}, []interface{}{"a"}},
+		{"list replaced, not appended", []interface{}{"a", "b"}, []interface{}{"c"}},
+		{"number replaced by string", 1, "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Merge(map[string]interface{}{"key": tt.dst}, map[string]interface{}{"key": tt.src})
+			if !reflect.DeepEqual(got["key"], tt.src) {
+				t.Errorf("Merge() key = %v, want %v", got["key"], tt.src)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	values := map[string]interface{}{
+		"auth":     map[string]interface{}{"password": "hunter2", "users": []interface{}{"app"}},
+		"replicas": 2,
+		"empty":    map[string]interface{}{},
+	}
+	want := map[string]interface{}{
+		"auth":     map[string]interface{}{"password": "x", "users": "x"},
+		"replicas": "x",
+		"empty":    map[string]interface{}{},
+	}
+	if got := Redact(values, "x"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Redact() = %v, want %v", got, want)
+	}
+	if values["auth"].(map[string]interface{})["password"] != "hunter2" {
+		t.Error("Redact() modified its input")
+	}
+	if Redact(nil, "x") != nil {
+		t.Error("Redact(nil) != nil")
+	}
+}
+
+func TestHash(t *testing.T) {
+	a := map[string]interface{}{}
+	a["replicas"] = 1
+	a["image"] = map[string]interface{}{"repository": "postgres", "tag": "17"}
+	b := map[string]interface{}{}
+	b["image"] = map[string]interface{}{"tag": "17", "repository": "postgres"}
+	b["replicas"] = 1
+
+	hash := Hash(a)
+	if len(hash) != 16 {
+		t.Errorf("Hash() = %q, want 16 hex characters", hash)
+	}
+	for i := 0; i < 10; i++ {
+		if got := Hash(b); got != hash {
+			t.Fatalf("Hash() of equal values = %q, want %q", got, hash)
+		}
+	}
+
+	b["image"].(map[string]interface{})["tag"] = "16"
+	if Hash(b) == hash {
+		t.Error("Hash() of different values is equal")
+	}
+	if Hash(nil) == Hash(map[string]interface{}{}) {
+		t.Error("Hash() of nil and empty values is equal")
+	}
+}
+
+func TestHashCanonical(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b map[string]interface{}
+	}{
+		{
+			name: "number types",
+			a:    map[string]interface{}{"replicas": 2, "port": int64(5432), "ratio": 0.5},
+			b:    map[string]interface{}{"replicas": float64(2), "port": json.Number("5432"), "ratio": float32(0.5)},
+		},
+		{
+			name: "number formatting",
+			a:    map[string]interface{}{"size": json.Number("1e3"), "ratio": json.Number("0.50")},
+			b:    map[string]interface{}{"size": 1000, "ratio": 0.5},
+		},
+		{
+			name: "typed maps and slices",
+			a: map[string]interface{}{
+				"podLabels": map[string]string{"team": "a", "tier": "db"},
+				"args":      []string{"--verbose", "--port=5432"},
+			},
+			b: map[string]interface{}{
+				"podLabels": map[string]interface{}{"tier": "db", "team": "a"},
+				"args":      []interface{}{"--verbose", "--port=5432"},
+			},
+		},
+		{
+			name: "nested values from different sources",
+			a: map[string]interface{}{"resources": map[string]interface{}{
+				"limits": map[string]interface{}{"cpu": 1, "memory": "512Mi"},
+			}, "hosts": []interface{}{map[string]interface{}{"name": "a", "port": 80}}},
+			b: map[string]interface{}{"hosts": []map[string]interface{}{{"port": float64(80), "name": "a"}},
+				"resources": map[string]map[string]interface{}{
+					"limits": {"memory": "512Mi", "cpu": json.Number("1.0")},
+				}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if a, b := Hash(tt.a), Hash(tt.b); a != b {
+				t.Errorf("Hash() = %s and %s for equal values", a, b)
+			}
+		})
+	}
+
+	different := []map[string]interface{}{
+		{"replicas": 2},
+		{"replicas": 2.5},
+		{"replicas": "2"},
+		{"args": []interface{}{"--port=5432", "--verbose"}},
+		{"args": []interface{}{"--verbose", "--port=5432"}},
+	}
+	seen := map[string]int{}
+	for i, values := range different {
+		hash := Hash(values)
+		if j, ok := seen[hash]; ok {
+			t.Errorf("Hash() of %v equals Hash() of %v", values, different[j])
+		}
+		seen[hash] = i
+	}
+}
+
+func TestDeepCopy(t *testing.T) {
+	original := map[string]interface{}{
+		"image": map[string]interface{}{"tag": "17"},
+		"hosts": []interface{}{map[string]interface{}{"name": "a"}},
+	}
+
+	copied := DeepCopy(original)
+	copied["image"].(map[string]interface{})["tag"] = "18"
+	copied["hosts"].([]interface{})[0].(map[string]interface{})["name"] = "b"
+
+	want := map[string]interface{}{
+		"image": map[string]interface{}{"tag": "17"},
+		"hosts": []interface{}{map[string]interface{}{"name": "a"}},
+	}
+	if !reflect.DeepEqual(original, want) {
+		t.Errorf("original = %v after modifying the copy, want %v", original, want)
+	}
+}